@@ -0,0 +1,375 @@
+// Command openapi-gen collects the annotations of the admin API handlers into a Go table that the
+// OpenAPI document served at /api/openapi.json is built from.
+//
+// It is run by go generate in internal/handler. For every exported handler method taking a
+// *gin.Context it records the doc comment, the type bound by ShouldBindJSON or ShouldBindQuery and
+// the query parameters read by the handler and the helpers it calls.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const openAPIImport = "gpt-load/internal/openapi"
+
+var (
+	bodyBinders  = []string{"ShouldBindJSON", "BindJSON", "ShouldBind", "Bind"}
+	queryBinders = []string{"ShouldBindQuery", "BindQuery"}
+	queryReaders = []string{"Query", "DefaultQuery", "GetQuery", "QueryArray", "GetQueryArray", "QueryMap"}
+
+	// helperParams lists the query parameters read by helpers outside the handler package.
+	helperParams = map[string][]string{
+		"Paginate": {"page", "page_size"},
+	}
+)
+
+// function is what a function of the handler package tells about the request it handles.
+type function struct {
+	name       string
+	key        string
+	handler    bool
+	doc        string
+	body       ast.Expr
+	query      ast.Expr
+	params     []string
+	calls      []string
+	imports    map[string]string
+	usedImport map[string]bool
+}
+
+func main() {
+	output := flag.String("output", "openapi_gen.go", "file to write, relative to the package directory")
+	flag.Parse()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != *output
+	}, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	functions := map[string]*function{}
+	var pkgName string
+	for name, pkg := range pkgs {
+		pkgName = name
+		for _, file := range pkg.Files {
+			collectFunctions(file, functions)
+		}
+	}
+
+	source, err := render(fset, pkgName, functions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, *output), source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func collectFunctions(file *ast.File, functions map[string]*function) {
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		f := &function{
+			name:       fn.Name.Name,
+			key:        fn.Name.Name,
+			handler:    isHandler(fn),
+			doc:        strings.TrimSpace(fn.Doc.Text()),
+			imports:    imports,
+			usedImport: map[string]bool{},
+		}
+		if f.handler {
+			f.key = receiverName(fn) + "." + f.name
+		}
+		inspectBody(fn.Body, f)
+		functions[f.key] = f
+		if _, taken := functions[f.name]; !taken {
+			functions[f.name] = f
+		}
+	}
+}
+
+// isHandler reports whether fn is an exported pointer method with a single *gin.Context parameter.
+func isHandler(fn *ast.FuncDecl) bool {
+	if fn.Recv == nil || !fn.Name.IsExported() || len(fn.Type.Params.List) != 1 || fn.Type.Results != nil {
+		return false
+	}
+	if receiverName(fn) == "" {
+		return false
+	}
+	param, ok := fn.Type.Params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := param.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Context"
+}
+
+func receiverName(fn *ast.FuncDecl) string {
+	recv, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	ident, ok := recv.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	return ident.Name
+}
+
+func inspectBody(body *ast.BlockStmt, f *function) {
+	declared := map[string]ast.Expr{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.ValueSpec:
+			if node.Type != nil {
+				for _, name := range node.Names {
+					declared[name.Name] = node.Type
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range node.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok || i >= len(node.Rhs) {
+					continue
+				}
+				if lit := compositeLit(node.Rhs[i]); lit != nil && lit.Type != nil {
+					declared[ident.Name] = lit.Type
+				}
+			}
+		case *ast.CallExpr:
+			inspectCall(node, f, declared)
+		}
+		return true
+	})
+}
+
+func compositeLit(expr ast.Expr) *ast.CompositeLit {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+	}
+	lit, _ := expr.(*ast.CompositeLit)
+	return lit
+}
+
+func inspectCall(call *ast.CallExpr, f *function, declared map[string]ast.Expr) {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		f.calls = append(f.calls, fun.Name)
+	case *ast.SelectorExpr:
+		method := fun.Sel.Name
+		f.calls = append(f.calls, method)
+
+		switch {
+		case slices.Contains(bodyBinders, method) && f.body == nil:
+			f.body = boundType(call, declared, f)
+		case slices.Contains(queryBinders, method) && f.query == nil:
+			f.query = boundType(call, declared, f)
+		case slices.Contains(queryReaders, method) && len(call.Args) > 0:
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if name, err := strconv.Unquote(lit.Value); err == nil && !slices.Contains(f.params, name) {
+					f.params = append(f.params, name)
+				}
+			}
+		}
+	}
+}
+
+// boundType returns the declared type of the variable whose address is passed to a bind call.
+func boundType(call *ast.CallExpr, declared map[string]ast.Expr, f *function) ast.Expr {
+	if len(call.Args) != 1 {
+		return nil
+	}
+	unary, ok := call.Args[0].(*ast.UnaryExpr)
+	if !ok || unary.Op != token.AND {
+		return nil
+	}
+	ident, ok := unary.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	typ := declared[ident.Name]
+	if typ != nil {
+		ast.Inspect(typ, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if pkg, ok := sel.X.(*ast.Ident); ok {
+					f.usedImport[pkg.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return typ
+}
+
+// queryParams returns the query parameters read by a function and everything it calls in the package.
+func queryParams(name string, functions map[string]*function, seen map[string]bool) []string {
+	f, ok := functions[name]
+	if !ok {
+		return helperParams[name]
+	}
+	if seen[f.key] {
+		return nil
+	}
+	seen[f.key] = true
+	params := slices.Clone(f.params)
+	for _, callee := range f.calls {
+		for _, param := range queryParams(callee, functions, seen) {
+			if !slices.Contains(params, param) {
+				params = append(params, param)
+			}
+		}
+	}
+	return params
+}
+
+func render(fset *token.FileSet, pkgName string, functions map[string]*function) ([]byte, error) {
+	names := make([]string, 0, len(functions))
+	imports := map[string]string{"openapi": openAPIImport}
+	for name, f := range functions {
+		if !f.handler || name != f.key {
+			continue
+		}
+		names = append(names, name)
+		for pkg := range f.usedImport {
+			imports[pkg] = f.imports[pkg]
+		}
+	}
+	slices.Sort(names)
+	if len(names) > 0 {
+		imports["reflect"] = "reflect"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by openapi-gen from the handler annotations. DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n", pkgName)
+	importNames := make([]string, 0, len(imports))
+	for name := range imports {
+		importNames = append(importNames, name)
+	}
+	slices.Sort(importNames)
+	// Standard library imports come first, like in the hand-written files.
+	slices.SortStableFunc(importNames, func(a, b string) int {
+		return boolInt(isModule(imports[a])) - boolInt(isModule(imports[b]))
+	})
+	for i, name := range importNames {
+		path := imports[name]
+		if i > 0 && isModule(path) && !isModule(imports[importNames[i-1]]) {
+			fmt.Fprintln(&buf)
+		}
+		if filepath.Base(path) == name {
+			fmt.Fprintf(&buf, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&buf, "\t%s %q\n", name, path)
+		}
+	}
+	fmt.Fprintln(&buf, ")")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "// openAPIOperations describes the admin API handlers, keyed by receiver and method name.")
+	fmt.Fprintln(&buf, "var openAPIOperations = map[string]openapi.Operation{")
+
+	for _, name := range names {
+		f := functions[name]
+		fmt.Fprintf(&buf, "\t%q: {\n", name)
+		fmt.Fprintf(&buf, "\t\tSummary: %q,\n", summary(f.name))
+		if f.doc != "" {
+			fmt.Fprintf(&buf, "\t\tDescription: %q,\n", strings.Join(strings.Fields(f.doc), " "))
+		}
+		if f.body != nil {
+			fmt.Fprintf(&buf, "\t\tBody: reflect.TypeFor[%s](),\n", exprString(fset, f.body))
+		}
+		if f.query != nil {
+			fmt.Fprintf(&buf, "\t\tQuery: reflect.TypeFor[%s](),\n", exprString(fset, f.query))
+		}
+		if params := queryParams(name, functions, map[string]bool{}); len(params) > 0 {
+			quoted := make([]string, len(params))
+			for i, param := range params {
+				quoted[i] = strconv.Quote(param)
+			}
+			fmt.Fprintf(&buf, "\t\tQueryParams: []string{%s},\n", strings.Join(quoted, ", "))
+		}
+		fmt.Fprintln(&buf, "\t},")
+	}
+	fmt.Fprintln(&buf, "}")
+
+	return format.Source(buf.Bytes())
+}
+
+func isModule(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return strings.Contains(first, ".") || first == "gpt-load"
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// summary turns a handler name such as ListGroupKeys into "List group keys".
+func summary(name string) string {
+	var words []string
+	start := 0
+	runes := []rune(name)
+	for i := 1; i < len(runes); i++ {
+		boundary := unicode.IsUpper(runes[i]) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+		if boundary {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+
+	for i := 1; i < len(words); i++ {
+		if !isAcronym(words[i]) {
+			words[i] = strings.ToLower(words[i])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func isAcronym(word string) bool {
+	return len(word) > 1 && strings.ToUpper(word) == word
+}
+
+func exprString(fset *token.FileSet, expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, expr); err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,282 @@
+// Package accesslog writes one structured JSON line per API or proxy request to configurable sinks,
+// separately from the application logs.
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Sink names accepted in ACCESS_LOG_SINKS.
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+)
+
+// bufferSize is how many lines may wait for the sinks before new lines are dropped.
+const bufferSize = 4096
+
+// contextKey holds the proxy details of the current request.
+const contextKey = "access_log_details"
+
+// Sink receives complete access log lines.
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// Details is what the proxy knows about a request beyond plain HTTP.
+type Details struct {
+	Group            string
+	ParentGroup      string
+	Model            string
+	Upstream         string
+	Key              string
+	Attempts         int
+	Stream           bool
+	CacheHit         bool
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	Cost             *float64
+}
+
+// Entry is one access log record.
+type Entry struct {
+	Time          time.Time
+	RequestID     string
+	Method        string
+	Path          string
+	Status        int
+	DurationMs    int64
+	BytesOut      int
+	ClientIP      string
+	UserAgent     string
+	ClientTokenID string
+	Details
+}
+
+// fields maps the names accepted in ACCESS_LOG_FIELDS to their values.
+var fields = map[string]func(e *Entry) any{
+	"time":              func(e *Entry) any { return e.Time.Format(time.RFC3339Nano) },
+	"request_id":        func(e *Entry) any { return e.RequestID },
+	"method":            func(e *Entry) any { return e.Method },
+	"path":              func(e *Entry) any { return e.Path },
+	"status":            func(e *Entry) any { return e.Status },
+	"duration_ms":       func(e *Entry) any { return e.DurationMs },
+	"bytes_out":         func(e *Entry) any { return e.BytesOut },
+	"client_ip":         func(e *Entry) any { return e.ClientIP },
+	"user_agent":        func(e *Entry) any { return e.UserAgent },
+	"client_token_id":   func(e *Entry) any { return e.ClientTokenID },
+	"group":             func(e *Entry) any { return e.Group },
+	"parent_group":      func(e *Entry) any { return e.ParentGroup },
+	"model":             func(e *Entry) any { return e.Model },
+	"upstream":          func(e *Entry) any { return e.Upstream },
+	"key":               func(e *Entry) any { return e.Key },
+	"attempts":          func(e *Entry) any { return e.Attempts },
+	"stream":            func(e *Entry) any { return e.Stream },
+	"cache_hit":         func(e *Entry) any { return e.CacheHit },
+	"prompt_tokens":     func(e *Entry) any { return e.PromptTokens },
+	"completion_tokens": func(e *Entry) any { return e.CompletionTokens },
+	"total_tokens":      func(e *Entry) any { return e.TotalTokens },
+	"cost":              func(e *Entry) any { return e.Cost },
+}
+
+// defaultFields are logged when ACCESS_LOG_FIELDS is empty.
+var defaultFields = []string{
+	"time", "request_id", "method", "path", "status", "duration_ms", "client_ip", "client_token_id",
+	"group", "model", "upstream", "attempts", "total_tokens", "cost",
+}
+
+// Logger formats entries and hands them to the sinks from a background goroutine, so slow sinks
+// never hold up requests; lines that do not fit in the buffer are dropped.
+type Logger struct {
+	sinks   []Sink
+	fields  []string
+	lines   chan []byte
+	done    chan struct{}
+	dropped atomic.Int64
+	// mu keeps Log from sending on lines once Stop has closed it
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewLogger creates the access logger from ACCESS_LOG_* settings. With no sinks configured it
+// is disabled and Log is a no-op.
+func NewLogger(configManager types.ConfigManager) (*Logger, error) {
+	config := configManager.GetAccessLogConfig()
+	l := &Logger{fields: defaultFields}
+
+	if len(config.Fields) > 0 {
+		if len(config.Fields) == 1 && config.Fields[0] == "all" {
+			l.fields = make([]string, 0, len(fields))
+			for name := range fields {
+				l.fields = append(l.fields, name)
+			}
+			sort.Strings(l.fields)
+		} else {
+			for _, name := range config.Fields {
+				if _, ok := fields[name]; !ok {
+					return nil, fmt.Errorf("unknown access log field %q in ACCESS_LOG_FIELDS", name)
+				}
+			}
+			l.fields = config.Fields
+		}
+	}
+
+	for _, name := range config.Sinks {
+		sink, err := newSink(strings.ToLower(name), config)
+		if err != nil {
+			for _, opened := range l.sinks {
+				_ = opened.Close()
+			}
+			return nil, err
+		}
+		l.sinks = append(l.sinks, sink)
+	}
+
+	if len(l.sinks) > 0 {
+		l.lines = make(chan []byte, bufferSize)
+		l.done = make(chan struct{})
+		go l.run()
+	}
+	return l, nil
+}
+
+func newSink(name string, config types.AccessLogConfig) (Sink, error) {
+	switch name {
+	case SinkStdout:
+		return newStdoutSink(), nil
+	case SinkFile:
+		return newFileSink(config.FilePath, config.FileMaxSizeMB, config.FileMaxBackups)
+	case SinkSyslog:
+		return newSyslogSink(config.SyslogNetwork, config.SyslogAddress, config.SyslogTag)
+	default:
+		return nil, fmt.Errorf("unknown access log sink %q in ACCESS_LOG_SINKS, expected stdout, file or syslog", name)
+	}
+}
+
+// Enabled reports whether any sink is configured.
+func (l *Logger) Enabled() bool {
+	return l != nil && len(l.sinks) > 0
+}
+
+// Log queues an entry for the sinks.
+func (l *Logger) Log(e *Entry) {
+	if !l.Enabled() {
+		return
+	}
+	line := l.format(e)
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.lines <- line:
+	default:
+		if l.dropped.Add(1)%1000 == 1 {
+			logrus.Warnf("Access log buffer is full, %d lines dropped so far", l.dropped.Load())
+		}
+	}
+}
+
+// format renders the configured fields, in their configured order, as a JSON line.
+func (l *Logger) format(e *Entry) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range l.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		value, err := json.Marshal(fields[name](e))
+		if err != nil {
+			value = []byte("null")
+		}
+		buf.WriteByte('"')
+		buf.WriteString(name)
+		buf.WriteString(`":`)
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	for line := range l.lines {
+		for _, sink := range l.sinks {
+			if err := sink.Write(line); err != nil {
+				logrus.WithError(err).Debug("Failed to write access log line")
+			}
+		}
+	}
+}
+
+// Stop writes the queued lines and closes the sinks.
+func (l *Logger) Stop(ctx context.Context) {
+	if !l.Enabled() {
+		return
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.lines)
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		logrus.Warn("Access log stop timed out, some lines may be lost.")
+		return
+	}
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			logrus.WithError(err).Warn("Failed to close access log sink")
+		}
+	}
+	logrus.Info("Access log stopped.")
+}
+
+// SetDetails stores the proxy details of a request for its access log entry.
+func SetDetails(c *gin.Context, details *Details) {
+	c.Set(contextKey, details)
+}
+
+// GetDetails returns the proxy details stored for a request, if any.
+func GetDetails(c *gin.Context) *Details {
+	value, ok := c.Get(contextKey)
+	if !ok {
+		return nil
+	}
+	details, _ := value.(*Details)
+	return details
+}
+
+// ClientTokenID derives a stable identifier from a proxy key that can be logged without exposing
+// the key itself.
+func ClientTokenID(proxyKey string) string {
+	if proxyKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(proxyKey))
+	return "pk_" + hex.EncodeToString(sum[:6])
+}
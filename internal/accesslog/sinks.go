@@ -0,0 +1,123 @@
+package accesslog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// stdoutSink writes lines to standard output.
+type stdoutSink struct {
+	mu sync.Mutex
+}
+
+func newStdoutSink() *stdoutSink {
+	return &stdoutSink{}
+}
+
+func (s *stdoutSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := os.Stdout.Write(line)
+	return err
+}
+
+func (s *stdoutSink) Close() error {
+	return nil
+}
+
+// fileSink appends lines to a file, rotating it to path.1 ... path.N once it exceeds maxBytes.
+type fileSink struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileSink(path string, maxSizeMB, maxBackups int) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create access log directory: %w", err)
+	}
+	s := &fileSink{
+		path:       path,
+		maxBytes:   int64(max(maxSizeMB, 1)) * 1024 * 1024,
+		maxBackups: max(maxBackups, 0),
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open access log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat access log file: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *fileSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// rotate shifts the backups by one, dropping the oldest, and starts a new file.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	if s.maxBackups == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return s.open()
+	}
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", s.path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
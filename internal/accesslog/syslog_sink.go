@@ -0,0 +1,31 @@
+//go:build !windows
+
+package accesslog
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// syslogSink sends lines to the local syslog daemon, or to a remote one when network and address
+// are set (e.g. udp, logs.example.com:514).
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func newSyslogSink(network, address, tag string) (*syslogSink, error) {
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_LOCAL0, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &syslogSink{writer: writer}, nil
+}
+
+func (s *syslogSink) Write(line []byte) error {
+	// syslog frames each message itself, so the trailing newline is dropped
+	return s.writer.Info(string(line[:len(line)-1]))
+}
+
+func (s *syslogSink) Close() error {
+	return s.writer.Close()
+}
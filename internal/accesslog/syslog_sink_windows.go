@@ -0,0 +1,9 @@
+//go:build windows
+
+package accesslog
+
+import "errors"
+
+func newSyslogSink(network, address, tag string) (Sink, error) {
+	return nil, errors.New("the syslog access log sink is not supported on Windows")
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"gpt-load/internal/accesslog"
 	"gpt-load/internal/config"
 	db "gpt-load/internal/db/migrations"
 	"gpt-load/internal/i18n"
@@ -35,8 +36,20 @@ type App struct {
 	logCleanupService *services.LogCleanupService
 	requestLogService *services.RequestLogService
 	cronChecker       *keypool.CronChecker
+	keyTopUpService   *services.KeyTopUpService
+	keyExpiryService  *services.KeyExpiryService
+	encryptionRotator *services.EncryptionRotationService
+	sandboxService    *services.SandboxService
+	usageRollups      *services.UsageRollupService
+	modelRefresher    *services.ModelRefreshService
+	upstreamHealth    *services.UpstreamHealthService
+	webhooks          *services.WebhookService
+	runtimeService    *services.RuntimeService
+	alertService      *services.AlertService
+	declarativeConfig *services.DeclarativeConfigService
 	keyPoolProvider   *keypool.KeyProvider
 	proxyServer       *proxy.ProxyServer
+	accessLogger      *accesslog.Logger
 	storage           store.Store
 	db                *gorm.DB
 	httpServer        *http.Server
@@ -52,8 +65,20 @@ type AppParams struct {
 	LogCleanupService *services.LogCleanupService
 	RequestLogService *services.RequestLogService
 	CronChecker       *keypool.CronChecker
+	KeyTopUpService   *services.KeyTopUpService
+	KeyExpiryService  *services.KeyExpiryService
+	EncryptionRotator *services.EncryptionRotationService
+	SandboxService    *services.SandboxService
+	UsageRollups      *services.UsageRollupService
+	ModelRefresher    *services.ModelRefreshService
+	UpstreamHealth    *services.UpstreamHealthService
+	Webhooks          *services.WebhookService
+	RuntimeService    *services.RuntimeService
+	AlertService      *services.AlertService
+	DeclarativeConfig *services.DeclarativeConfigService
 	KeyPoolProvider   *keypool.KeyProvider
 	ProxyServer       *proxy.ProxyServer
+	AccessLogger      *accesslog.Logger
 	Storage           store.Store
 	DB                *gorm.DB
 }
@@ -68,8 +93,20 @@ func NewApp(params AppParams) *App {
 		logCleanupService: params.LogCleanupService,
 		requestLogService: params.RequestLogService,
 		cronChecker:       params.CronChecker,
+		keyTopUpService:   params.KeyTopUpService,
+		keyExpiryService:  params.KeyExpiryService,
+		encryptionRotator: params.EncryptionRotator,
+		sandboxService:    params.SandboxService,
+		usageRollups:      params.UsageRollups,
+		modelRefresher:    params.ModelRefresher,
+		upstreamHealth:    params.UpstreamHealth,
+		webhooks:          params.Webhooks,
+		runtimeService:    params.RuntimeService,
+		alertService:      params.AlertService,
+		declarativeConfig: params.DeclarativeConfig,
 		keyPoolProvider:   params.KeyPoolProvider,
 		proxyServer:       params.ProxyServer,
+		accessLogger:      params.AccessLogger,
 		storage:           params.Storage,
 		db:                params.DB,
 	}
@@ -87,6 +124,7 @@ func (a *App) Start() error {
 	if err := prommetrics.Init(); err != nil {
 		return fmt.Errorf("failed to initialize Prometheus metrics: %w", err)
 	}
+	prommetrics.SetKeyCounter(a.countKeysByGroup)
 	logrus.Info("Prometheus metrics initialized successfully.")
 	
 	// Master 节点执行初始化
@@ -106,6 +144,20 @@ func (a *App) Start() error {
 			&models.APIKey{},
 			&models.RequestLog{},
 			&models.GroupHourlyStat{},
+			&models.UsageHourlyRollup{},
+			&models.UsageDailyRollup{},
+			&models.AlertRule{},
+			&models.User{},
+			&models.Team{},
+			&models.TeamMember{},
+			&models.ConfigVersion{},
+			&models.Notification{},
+			&models.PlaygroundConversation{},
+			&models.PlaygroundMessage{},
+			&models.StreamTranscript{},
+			&models.KeyHealthEvent{},
+			&models.Job{},
+			&models.WebhookSubscription{},
 		); err != nil {
 			return fmt.Errorf("database auto-migration failed: %w", err)
 		}
@@ -113,6 +165,9 @@ func (a *App) Start() error {
 		if err := db.MigrateDatabase(a.db); err != nil {
 			return fmt.Errorf("database data migration failed: %w", err)
 		}
+		if err := a.webhooks.EncryptPlaintextSecrets(); err != nil {
+			return fmt.Errorf("failed to encrypt webhook secrets: %w", err)
+		}
 		logrus.Info("Database auto-migration completed.")
 
 		// 初始化系统设置
@@ -133,6 +188,13 @@ func (a *App) Start() error {
 		a.requestLogService.Start()
 		a.logCleanupService.Start()
 		a.cronChecker.Start()
+		a.keyTopUpService.Start()
+		a.keyExpiryService.Start()
+		a.encryptionRotator.Start()
+		a.sandboxService.Start()
+		a.usageRollups.Start()
+		a.modelRefresher.Start()
+		a.alertService.Start()
 	} else {
 		logrus.Info("Starting as Slave Node.")
 		a.settingsManager.Initialize(a.storage, a.groupManager, a.configManager.IsMaster())
@@ -143,6 +205,17 @@ func (a *App) Start() error {
 
 	a.groupManager.Initialize()
 
+	// 每个节点投递自己产生的 Webhook 事件
+	a.webhooks.Start()
+
+	// 上游可达性与部署位置有关，每个节点各自探测
+	a.upstreamHealth.Start()
+
+	// 声明式配置文件需要在分组缓存初始化后应用
+	if a.configManager.IsMaster() {
+		a.declarativeConfig.Start()
+	}
+
 	// Create HTTP server
 	serverConfig := a.configManager.GetEffectiveServerConfig()
 	a.httpServer = &http.Server{
@@ -152,6 +225,7 @@ func (a *App) Start() error {
 		WriteTimeout:   time.Duration(serverConfig.WriteTimeout) * time.Second,
 		IdleTimeout:    time.Duration(serverConfig.IdleTimeout) * time.Second,
 		MaxHeaderBytes: 1 << 20,
+		ConnState:      a.runtimeService.TrackConnState,
 	}
 
 	// Start HTTP server in a new goroutine
@@ -174,11 +248,24 @@ func (a *App) Stop(ctx context.Context) {
 	serverConfig := a.configManager.GetEffectiveServerConfig()
 	totalTimeout := time.Duration(serverConfig.GracefulShutdownTimeout) * time.Second
 
-	// 动态计算 HTTP 关机超时时间，为后台服务固定预留 5 秒
+	// 动态计算 HTTP 关机超时时间，为后台服务固定预留 5 秒；配置了 SERVER_DRAIN_TIMEOUT 时以其为准
 	httpShutdownTimeout := totalTimeout - 5*time.Second
+	if serverConfig.DrainTimeout > 0 {
+		httpShutdownTimeout = time.Duration(serverConfig.DrainTimeout) * time.Second
+	}
 	httpShutdownCtx, cancelHttpShutdown := context.WithTimeout(context.Background(), httpShutdownTimeout)
 	defer cancelHttpShutdown()
 
+	// 先停止接收新的代理请求并将就绪探针置为不可用，等待进行中的请求（包括流式响应）结束，
+	// 期间监听端口保持打开，以便负载均衡器通过 /ready 感知下线
+	a.proxyServer.BeginDrain()
+	if inFlight := a.proxyServer.InFlight(); inFlight > 0 {
+		logrus.Infof("Draining %d in-flight proxy requests (max %v)...", inFlight, httpShutdownTimeout)
+	}
+	if !a.proxyServer.WaitForInFlight(httpShutdownCtx) {
+		logrus.Warnf("Drain timed out with %d proxy requests still in flight.", a.proxyServer.InFlight())
+	}
+
 	logrus.Debugf("Attempting to gracefully shut down HTTP server (max %v)...", httpShutdownTimeout)
 	if err := a.httpServer.Shutdown(httpShutdownCtx); err != nil {
 		logrus.Debugf("HTTP server graceful shutdown timed out as expected, forcing remaining connections to close.")
@@ -192,11 +279,22 @@ func (a *App) Stop(ctx context.Context) {
 	stoppableServices := []func(context.Context){
 		a.groupManager.Stop,
 		a.settingsManager.Stop,
+		a.accessLogger.Stop,
+		a.upstreamHealth.Stop,
+		a.webhooks.Stop,
 	}
 
 	if serverConfig.IsMaster {
 		stoppableServices = append(stoppableServices,
 			a.cronChecker.Stop,
+			a.keyTopUpService.Stop,
+			a.keyExpiryService.Stop,
+			a.encryptionRotator.Stop,
+			a.sandboxService.Stop,
+			a.alertService.Stop,
+			a.declarativeConfig.Stop,
+			a.usageRollups.Stop,
+			a.modelRefresher.Stop,
 			a.logCleanupService.Stop,
 			a.requestLogService.Stop,
 		)
@@ -231,3 +329,47 @@ func (a *App) Stop(ctx context.Context) {
 
 	logrus.Info("Server exited gracefully")
 }
+
+// countKeysByGroup returns the number of active and invalid keys of each group for the key gauges.
+func (a *App) countKeysByGroup() (map[string]int64, map[string]int64, error) {
+	var groups []models.Group
+	if err := a.db.Select("id", "name").Find(&groups).Error; err != nil {
+		return nil, nil, err
+	}
+	names := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		names[group.ID] = group.Name
+	}
+
+	var rows []struct {
+		GroupID uint
+		Status  string
+		Count   int64
+	}
+	if err := a.db.Model(&models.APIKey{}).
+		Select("group_id, status, COUNT(*) AS count").
+		Group("group_id, status").
+		Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	active := make(map[string]int64, len(groups))
+	invalid := make(map[string]int64, len(groups))
+	for _, name := range names {
+		active[name] = 0
+		invalid[name] = 0
+	}
+	for _, row := range rows {
+		name, ok := names[row.GroupID]
+		if !ok {
+			continue
+		}
+		switch row.Status {
+		case models.KeyStatusActive:
+			active[name] += row.Count
+		case models.KeyStatusInvalid:
+			invalid[name] += row.Count
+		}
+	}
+	return active, invalid, nil
+}
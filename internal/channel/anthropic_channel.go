@@ -89,7 +89,7 @@ func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIK
 
 	// Build final URL with path and query parameters
 	finalURL := *upstreamURL
-	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + endpointURL.Path
+	finalURL.Path = ch.upstreamPath(upstreamURL, endpointURL.Path)
 	finalURL.RawQuery = endpointURL.RawQuery
 	reqURL := finalURL.String()
 
@@ -152,7 +152,7 @@ func (ch *AnthropicChannel) FetchModels(ctx context.Context, apiKey *models.APIK
 
 	// Build the models list endpoint URL
 	finalURL := *upstreamURL
-	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + "/v1/models"
+	finalURL.Path = ch.upstreamPath(upstreamURL, "/v1/models")
 	reqURL := finalURL.String()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
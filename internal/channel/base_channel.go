@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
@@ -22,6 +23,9 @@ type UpstreamInfo struct {
 	URL           *url.URL
 	Weight        int
 	CurrentWeight int
+	HeaderRules   []models.HeaderRule
+	APIKey        string
+	VersionedBase bool
 }
 
 // BaseChannel provides common functionality for channel proxies.
@@ -34,6 +38,14 @@ type BaseChannel struct {
 	ValidationEndpoint string
 	upstreamLock       sync.Mutex
 
+	// versionPrefix is the API version clients put in front of request paths, e.g. /v1.
+	versionPrefix string
+
+	// groupID, upstreamSelection and latency drive latency-aware upstream selection.
+	groupID           uint
+	upstreamSelection string
+	latency           *LatencyTracker
+
 	// Cached fields from the group for stale check
 	channelType         string
 	groupUpstreams      datatypes.JSON
@@ -42,7 +54,8 @@ type BaseChannel struct {
 	modelRedirectStrict bool
 }
 
-// getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm.
+// getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm. Unhealthy
+// upstreams are skipped unless every upstream is unhealthy.
 func (b *BaseChannel) getUpstreamURL() *url.URL {
 	b.upstreamLock.Lock()
 	defer b.upstreamLock.Unlock()
@@ -54,10 +67,11 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 		return b.Upstreams[0].URL
 	}
 
+	candidates := b.healthyUpstreams()
 	totalWeight := 0
 	var best *UpstreamInfo
 
-	for i := range b.Upstreams {
+	for _, i := range candidates {
 		up := &b.Upstreams[i]
 		totalWeight += up.Weight
 		up.CurrentWeight += up.Weight
@@ -75,23 +89,108 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 	return best.URL
 }
 
+// healthyUpstreams returns the indexes of the upstreams that pass their active probes and whose
+// circuit is not open for every model, or of all upstreams when none does.
+func (b *BaseChannel) healthyUpstreams() []int {
+	all := make([]int, len(b.Upstreams))
+	for i := range all {
+		all[i] = i
+	}
+	if b.latency == nil {
+		return all
+	}
+
+	now := time.Now()
+	b.latency.mu.Lock()
+	defer b.latency.mu.Unlock()
+
+	var healthy []int
+	for i := range b.Upstreams {
+		if b.latency.upstreamHealthy(b.groupID, b.Upstreams[i].URL.String(), now) {
+			healthy = append(healthy, i)
+		}
+	}
+	if len(healthy) == 0 {
+		return all
+	}
+	return healthy
+}
+
+// selectUpstream picks the upstream for a request to model using the group's selection strategy.
+// Latency selection falls back to weighted round-robin when every upstream is unhealthy.
+func (b *BaseChannel) selectUpstream(model string) *url.URL {
+	if b.upstreamSelection == UpstreamSelectionLatency && b.latency != nil && len(b.Upstreams) > 1 {
+		if up := b.latency.pick(b.groupID, b.Upstreams, model); up != nil {
+			return up.URL
+		}
+	}
+	return b.getUpstreamURL()
+}
+
 // BuildUpstreamURL constructs the target URL for the upstream service.
-func (b *BaseChannel) BuildUpstreamURL(originalURL *url.URL, groupName string) (string, error) {
-	base := b.getUpstreamURL()
+func (b *BaseChannel) BuildUpstreamURL(originalURL *url.URL, groupName, model string) (string, error) {
+	base := b.selectUpstream(model)
 	if base == nil {
 		return "", fmt.Errorf("no upstream URL configured for channel %s", b.Name)
 	}
+	return b.buildURL(base, originalURL, groupName), nil
+}
+
+// BuildPinnedUpstreamURL constructs the target URL against the upstream whose base URL is upstream.
+func (b *BaseChannel) BuildPinnedUpstreamURL(originalURL *url.URL, groupName, upstream string) (string, bool) {
+	for i := range b.Upstreams {
+		if b.Upstreams[i].URL.String() == upstream {
+			return b.buildURL(b.Upstreams[i].URL, originalURL, groupName), true
+		}
+	}
+	return "", false
+}
 
+// buildURL maps a proxied request URL onto the given upstream base.
+func (b *BaseChannel) buildURL(base *url.URL, originalURL *url.URL, groupName string) string {
 	finalURL := *base
 	proxyPrefix := "/proxy/" + groupName
 	requestPath := originalURL.Path
 	requestPath = strings.TrimPrefix(requestPath, proxyPrefix)
 
-	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + requestPath
+	finalURL.Path = b.upstreamPath(base, requestPath)
 
 	finalURL.RawQuery = originalURL.RawQuery
 
-	return finalURL.String(), nil
+	return finalURL.String()
+}
+
+// upstreamPath joins an API path onto the base path of the given upstream URL, honouring the
+// upstream's VersionedBase flag.
+func (b *BaseChannel) upstreamPath(base *url.URL, apiPath string) string {
+	versioned := false
+	for i := range b.Upstreams {
+		if b.Upstreams[i].URL == base {
+			versioned = b.Upstreams[i].VersionedBase
+			break
+		}
+	}
+	return utils.JoinUpstreamPath(base.Path, apiPath, b.versionPrefix, versioned)
+}
+
+// MatchUpstream returns the configured upstream that the target URL was built from, if any.
+// When several upstreams match, the one with the longest base path wins.
+func (b *BaseChannel) MatchUpstream(target *url.URL) *UpstreamInfo {
+	var best *UpstreamInfo
+	for i := range b.Upstreams {
+		up := &b.Upstreams[i]
+		if up.URL.Scheme != target.Scheme || up.URL.Host != target.Host {
+			continue
+		}
+		basePath := strings.TrimRight(up.URL.Path, "/")
+		if !strings.HasPrefix(target.Path, basePath) {
+			continue
+		}
+		if best == nil || len(basePath) > len(strings.TrimRight(best.URL.Path, "/")) {
+			best = up
+		}
+	}
+	return best
 }
 
 // IsConfigStale checks if the channel's configuration is stale compared to the provided group.
@@ -152,8 +251,7 @@ func (b *BaseChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, gr
 		return bodyBytes, nil
 	}
 
-	// Direct match without any prefix processing
-	if targetModel, found := group.ModelRedirectMap[model]; found {
+	if targetModel, found := ResolveModelRedirect(group, model); found {
 		requestData["model"] = targetModel
 
 		// Log the redirection for audit
@@ -174,6 +272,31 @@ func (b *BaseChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, gr
 	return bodyBytes, nil
 }
 
+// ResolveModelRedirect returns the upstream model that a requested model (or alias) maps to.
+// Exact rules take precedence; otherwise the longest matching glob pattern wins.
+func ResolveModelRedirect(group *models.Group, model string) (string, bool) {
+	if model == "" || len(group.ModelRedirectMap) == 0 {
+		return "", false
+	}
+	if target, found := group.ModelRedirectMap[model]; found {
+		return target, true
+	}
+
+	bestPattern := ""
+	for pattern := range group.ModelRedirectMap {
+		if !utils.IsModelPattern(pattern) || !utils.MatchModelPattern(pattern, model) {
+			continue
+		}
+		if len(pattern) > len(bestPattern) || (len(pattern) == len(bestPattern) && pattern < bestPattern) {
+			bestPattern = pattern
+		}
+	}
+	if bestPattern == "" {
+		return "", false
+	}
+	return group.ModelRedirectMap[bestPattern], true
+}
+
 // TransformModelList transforms the model list response based on redirect rules.
 func (b *BaseChannel) TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error) {
 	var response map[string]any
@@ -231,6 +354,10 @@ func buildConfiguredModels(redirectMap map[string]string) []any {
 
 	models := make([]any, 0, len(redirectMap))
 	for sourceModel := range redirectMap {
+		// Patterns are rewrite rules rather than concrete model names
+		if utils.IsModelPattern(sourceModel) {
+			continue
+		}
 		models = append(models, map[string]any{
 			"id":       sourceModel,
 			"object":   "model",
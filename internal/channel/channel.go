@@ -5,14 +5,26 @@ import (
 	"gpt-load/internal/models"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ChannelProxy defines the interface for different API channel proxies.
 type ChannelProxy interface {
-	// BuildUpstreamURL constructs the target URL for the upstream service.
-	BuildUpstreamURL(originalURL *url.URL, groupName string) (string, error)
+	// BuildUpstreamURL constructs the target URL for the upstream service, choosing the upstream
+	// for the requested model according to the group's upstream selection strategy.
+	BuildUpstreamURL(originalURL *url.URL, groupName, model string) (string, error)
+
+	// BuildPinnedUpstreamURL constructs the target URL against a specific upstream base URL, as
+	// used by sticky sessions. It returns false when that upstream is no longer configured.
+	BuildPinnedUpstreamURL(originalURL *url.URL, groupName, upstream string) (string, bool)
+
+	// RecordUpstreamResult feeds the outcome of a request into latency-aware upstream selection.
+	RecordUpstreamResult(target *url.URL, model string, latency time.Duration, success bool)
+
+	// ProbeUpstreams actively checks that every upstream answers and returns their combined health.
+	ProbeUpstreams(ctx context.Context) []UpstreamHealth
 
 	// IsConfigStale checks if the channel's configuration is stale compared to the provided group.
 	IsConfigStale(group *models.Group) bool
@@ -43,4 +55,56 @@ type ChannelProxy interface {
 
 	// FetchModels fetches available models from the upstream provider.
 	FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error)
+
+	// MatchUpstream returns the upstream a built URL points at, carrying its per-upstream overrides.
+	MatchUpstream(target *url.URL) *UpstreamInfo
+}
+
+// EmbeddingsTranslator is implemented by channels whose native embeddings API differs from
+// OpenAI's /v1/embeddings, so that OpenAI-format clients can use them unchanged.
+type EmbeddingsTranslator interface {
+	// TranslateEmbeddingsRequest rewrites the upstream URL and body into the native format.
+	TranslateEmbeddingsRequest(upstreamURL string, bodyBytes []byte) (string, []byte, error)
+
+	// TranslateEmbeddingsResponse converts a native response body back to the OpenAI format,
+	// returning the prompt token count recorded for the request.
+	TranslateEmbeddingsResponse(requestBody, responseBody []byte) ([]byte, int, error)
+}
+
+// SafetySettingsApplier is implemented by channels that can enforce group-level safety settings.
+type SafetySettingsApplier interface {
+	// ApplySafetySettings merges the group's safety settings into the request body.
+	ApplySafetySettings(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error)
+}
+
+// Stream usage modes, configured per group via stream_usage_options.
+const (
+	StreamUsageAuto  = "auto"
+	StreamUsageOff   = "off"
+	StreamUsageStrip = "strip"
+)
+
+// StreamUsageReporter is implemented by channels whose chat completion streams only report token
+// usage when the request sets stream_options.include_usage, as the OpenAI API does.
+type StreamUsageReporter interface {
+	// AcceptsStreamOptions reports whether the provider accepts the stream_options field.
+	AcceptsStreamOptions() bool
+}
+
+// Reasoning content modes, configured per group via reasoning_content_mode.
+const (
+	ReasoningModePassthrough = "passthrough"
+	ReasoningModeStrip       = "strip"
+	ReasoningModeInline      = "inline"
+)
+
+// ReasoningTransformer is implemented by channels whose responses carry chain-of-thought in a
+// field separate from the answer, so that groups can strip it or fold it into the content.
+type ReasoningTransformer interface {
+	// TransformReasoning rewrites a complete, uncompressed response body according to mode.
+	TransformReasoning(body []byte, mode string) ([]byte, error)
+
+	// NewReasoningStreamTransformer returns a function that rewrites the data payload of each
+	// stream event. It keeps per-stream state and must not be shared between streams.
+	NewReasoningStreamTransformer(mode string) func(data []byte) []byte
 }
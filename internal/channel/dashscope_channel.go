@@ -0,0 +1,61 @@
+package channel
+
+import (
+	"context"
+	"gpt-load/internal/models"
+	"strings"
+)
+
+func init() {
+	Register("dashscope", newDashScopeChannel)
+}
+
+// DashScopeChannel proxies Alibaba Cloud DashScope (Qwen) through its OpenAI-compatible mode,
+// e.g. https://dashscope.aliyuncs.com/compatible-mode.
+type DashScopeChannel struct {
+	*OpenAIChannel
+}
+
+func newDashScopeChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("dashscope", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DashScopeChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// FetchModels lists models from /v1/models. DashScope reports no capability flags, so they are
+// seeded from the Qwen model naming scheme.
+func (ch *DashScopeChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	capabilities, err := ch.OpenAIChannel.FetchModels(ctx, apiKey, group)
+	if err != nil {
+		return nil, err
+	}
+	for i := range capabilities {
+		seedQwenCapabilities(&capabilities[i])
+	}
+	return capabilities, nil
+}
+
+// seedQwenCapabilities infers capabilities from a DashScope model ID.
+func seedQwenCapabilities(c *models.ModelCapabilities) {
+	id := strings.ToLower(c.ModelID)
+	switch {
+	case strings.Contains(id, "rerank"):
+		c.SupportsStreaming = false
+		c.SupportsRerank = true
+		return
+	case strings.Contains(id, "embedding"), strings.HasPrefix(id, "wanx"), strings.HasPrefix(id, "paraformer"),
+		strings.HasPrefix(id, "cosyvoice"), strings.HasPrefix(id, "sambert"):
+		c.SupportsStreaming = false
+		return
+	}
+
+	c.SupportsStreaming = true
+	c.SupportsVision = strings.Contains(id, "-vl") || strings.HasPrefix(id, "qvq") || strings.Contains(id, "omni")
+	c.SupportsFunctions = !c.SupportsVision && (strings.HasPrefix(id, "qwen-max") || strings.HasPrefix(id, "qwen-plus") ||
+		strings.HasPrefix(id, "qwen-turbo") || strings.HasPrefix(id, "qwen3") || strings.HasPrefix(id, "qwen2.5"))
+}
@@ -0,0 +1,152 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"gpt-load/internal/models"
+	"strings"
+)
+
+func init() {
+	Register("deepseek", newDeepSeekChannel)
+}
+
+// DeepSeekChannel proxies the DeepSeek API. It follows the OpenAI format, with reasoning models
+// returning their chain-of-thought in reasoning_content next to the final content.
+type DeepSeekChannel struct {
+	*OpenAIChannel
+}
+
+func newDeepSeekChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("deepseek", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeepSeekChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// FetchModels lists models from /v1/models. DeepSeek reports no capability flags, so they are
+// inferred: every model streams and only the chat model supports function calling.
+func (ch *DeepSeekChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	capabilities, err := ch.OpenAIChannel.FetchModels(ctx, apiKey, group)
+	if err != nil {
+		return nil, err
+	}
+	for i := range capabilities {
+		capabilities[i].SupportsFunctions = strings.Contains(capabilities[i].ModelID, "deepseek-chat")
+	}
+	return capabilities, nil
+}
+
+const (
+	reasoningOpenTag  = "<think>\n"
+	reasoningCloseTag = "\n</think>\n\n"
+)
+
+// TransformReasoning removes reasoning_content from each choice's message, prepending it to the
+// content in inline mode.
+func (ch *DeepSeekChannel) TransformReasoning(body []byte, mode string) ([]byte, error) {
+	if mode == ReasoningModePassthrough || !bytes.Contains(body, []byte(`"reasoning_content"`)) {
+		return body, nil
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	choices, _ := payload["choices"].([]any)
+	for _, raw := range choices {
+		choice, _ := raw.(map[string]any)
+		message, _ := choice["message"].(map[string]any)
+		if message == nil {
+			continue
+		}
+		reasoning, _ := message["reasoning_content"].(string)
+		delete(message, "reasoning_content")
+		if mode == ReasoningModeInline && reasoning != "" {
+			content, _ := message["content"].(string)
+			message["content"] = reasoningOpenTag + reasoning + reasoningCloseTag + content
+		}
+	}
+
+	return marshalUnescaped(payload)
+}
+
+// NewReasoningStreamTransformer removes reasoning_content from stream deltas. In inline mode the
+// reasoning is sent as content, opened with <think> and closed once the answer starts.
+func (ch *DeepSeekChannel) NewReasoningStreamTransformer(mode string) func(data []byte) []byte {
+	// thinking tracks the choices whose <think> block is still open.
+	thinking := make(map[int]bool)
+
+	return func(data []byte) []byte {
+		if mode == ReasoningModePassthrough {
+			return data
+		}
+		if !bytes.Contains(data, []byte(`"reasoning_content"`)) && len(thinking) == 0 {
+			return data
+		}
+
+		var chunk map[string]any
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return data
+		}
+
+		choices, _ := chunk["choices"].([]any)
+		for i, raw := range choices {
+			choice, _ := raw.(map[string]any)
+			delta, _ := choice["delta"].(map[string]any)
+			if delta == nil {
+				continue
+			}
+			index := i
+			if v, ok := choice["index"].(float64); ok {
+				index = int(v)
+			}
+
+			reasoning, _ := delta["reasoning_content"].(string)
+			delete(delta, "reasoning_content")
+			if mode != ReasoningModeInline {
+				continue
+			}
+
+			content, _ := delta["content"].(string)
+			var sb strings.Builder
+			if reasoning != "" {
+				if !thinking[index] {
+					sb.WriteString(reasoningOpenTag)
+					thinking[index] = true
+				}
+				sb.WriteString(reasoning)
+			}
+			if thinking[index] && (content != "" || choice["finish_reason"] != nil) {
+				sb.WriteString(reasoningCloseTag)
+				delete(thinking, index)
+			}
+			if sb.Len() > 0 {
+				delta["content"] = sb.String() + content
+			}
+		}
+
+		out, err := marshalUnescaped(chunk)
+		if err != nil {
+			return data
+		}
+		return out
+	}
+}
+
+// marshalUnescaped encodes v without escaping <, > and &, keeping inlined <think> tags readable.
+func marshalUnescaped(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
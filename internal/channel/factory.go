@@ -6,10 +6,10 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/models"
+	"gpt-load/internal/store"
 	"gpt-load/internal/utils"
 	"net/url"
 	"sync"
-	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -45,14 +45,17 @@ type Factory struct {
 	clientManager   *httpclient.HTTPClientManager
 	channelCache    map[uint]ChannelProxy
 	cacheLock       sync.Mutex
+	latency         *LatencyTracker
 }
 
-// NewFactory creates a new channel factory.
-func NewFactory(settingsManager *config.SystemSettingsManager, clientManager *httpclient.HTTPClientManager) *Factory {
+// NewFactory creates a new channel factory. Upstream circuit state is shared with the other
+// instances through the store.
+func NewFactory(settingsManager *config.SystemSettingsManager, clientManager *httpclient.HTTPClientManager, store store.Store) *Factory {
 	return &Factory{
 		settingsManager: settingsManager,
 		clientManager:   clientManager,
 		channelCache:    make(map[uint]ChannelProxy),
+		latency:         newSharedLatencyTracker(store),
 	}
 }
 
@@ -83,12 +86,7 @@ func (f *Factory) GetChannel(group *models.Group) (ChannelProxy, error) {
 
 // newBaseChannel is a helper function to create and configure a BaseChannel.
 func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel, error) {
-	type upstreamDef struct {
-		URL    string `json:"url"`
-		Weight int    `json:"weight"`
-	}
-
-	var defs []upstreamDef
+	var defs []models.UpstreamDefinition
 	if err := json.Unmarshal(group.Upstreams, &defs); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal upstreams for %s channel: %w", name, err)
 	}
@@ -106,39 +104,22 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		if def.Weight <= 0 {
 			continue
 		}
-		upstreamInfos = append(upstreamInfos, UpstreamInfo{URL: u, Weight: def.Weight})
-	}
-
-	// Base configuration for regular requests, derived from the group's effective settings.
-	clientConfig := &httpclient.Config{
-		ConnectTimeout:        time.Duration(group.EffectiveConfig.ConnectTimeout) * time.Second,
-		RequestTimeout:        time.Duration(group.EffectiveConfig.RequestTimeout) * time.Second,
-		IdleConnTimeout:       time.Duration(group.EffectiveConfig.IdleConnTimeout) * time.Second,
-		MaxIdleConns:          group.EffectiveConfig.MaxIdleConns,
-		MaxIdleConnsPerHost:   group.EffectiveConfig.MaxIdleConnsPerHost,
-		ResponseHeaderTimeout: time.Duration(group.EffectiveConfig.ResponseHeaderTimeout) * time.Second,
-		ProxyURL:              group.EffectiveConfig.ProxyURL,
-		DisableCompression:    false,
-		WriteBufferSize:       32 * 1024,
-		ReadBufferSize:        32 * 1024,
-		ForceAttemptHTTP2:     true,
-		TLSHandshakeTimeout:   15 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+		upstreamInfos = append(upstreamInfos, UpstreamInfo{
+			URL:           u,
+			Weight:        def.Weight,
+			HeaderRules:   def.HeaderRules,
+			APIKey:        def.APIKey,
+			VersionedBase: def.VersionedBase,
+		})
 	}
 
-	// Create a dedicated configuration for streaming requests.
-	streamConfig := *clientConfig
-	streamConfig.RequestTimeout = 0
-	streamConfig.DisableCompression = true
-	streamConfig.WriteBufferSize = 0
-	streamConfig.ReadBufferSize = 0
-	// Use a larger, independent connection pool for streaming clients to avoid exhaustion.
-	streamConfig.MaxIdleConns = max(group.EffectiveConfig.MaxIdleConns*2, 50)
-	streamConfig.MaxIdleConnsPerHost = max(group.EffectiveConfig.MaxIdleConnsPerHost*2, 20)
+	// Regular and streaming requests use separate clients, derived from the group's effective settings.
+	clientConfig := httpclient.NewConfig(&group.EffectiveConfig)
+	streamConfig := clientConfig.ForStreaming()
 
 	// Get both clients from the manager using their respective configurations.
 	httpClient := f.clientManager.GetClient(clientConfig)
-	streamClient := f.clientManager.GetClient(&streamConfig)
+	streamClient := f.clientManager.GetClient(streamConfig)
 
 	return &BaseChannel{
 		Name:                name,
@@ -147,6 +128,10 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		StreamClient:        streamClient,
 		TestModel:           group.TestModel,
 		ValidationEndpoint:  utils.GetValidationEndpoint(group),
+		versionPrefix:       utils.APIVersionPrefix(group.ChannelType),
+		groupID:             group.ID,
+		upstreamSelection:   group.EffectiveConfig.UpstreamSelection,
+		latency:             f.latency,
 		channelType:         group.ChannelType,
 		groupUpstreams:      group.Upstreams,
 		effectiveConfig:     &group.EffectiveConfig,
@@ -104,12 +104,9 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
 	}
 
-	// Safely join the path segments
-	reqURL, err := url.JoinPath(upstreamURL.String(), "v1beta", "models", ch.TestModel+":generateContent")
-	if err != nil {
-		return false, fmt.Errorf("failed to create gemini validation path: %w", err)
-	}
-	reqURL += "?key=" + apiKey.KeyValue
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, "/v1beta/models/"+ch.TestModel+":generateContent")
+	reqURL := finalURL.String() + "?key=" + apiKey.KeyValue
 
 	payload := gin.H{
 		"contents": []gin.H{
@@ -169,10 +166,9 @@ func (ch *GeminiChannel) FetchModels(ctx context.Context, apiKey *models.APIKey,
 	}
 
 	// Build the models list endpoint URL
-	reqURL, err := url.JoinPath(upstreamURL.String(), "v1beta", "models")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create models path: %w", err)
-	}
+	modelsURL := *upstreamURL
+	modelsURL.Path = ch.upstreamPath(upstreamURL, "/v1beta/models")
+	reqURL := modelsURL.String()
 	
 	// Parse the URL to properly add query parameters
 	parsedURL, err := url.Parse(reqURL)
@@ -295,7 +291,7 @@ func (ch *GeminiChannel) applyNativeFormatRedirect(req *http.Request, bodyBytes
 			modelPart := parts[i+1]
 			originalModel := strings.Split(modelPart, ":")[0]
 
-			if targetModel, found := group.ModelRedirectMap[originalModel]; found {
+			if targetModel, found := ResolveModelRedirect(group, originalModel); found {
 				suffix := ""
 				if colonIndex := strings.Index(modelPart, ":"); colonIndex != -1 {
 					suffix = modelPart[colonIndex:]
@@ -455,3 +451,49 @@ func isFirstPage(req *http.Request) bool {
 	pageToken := req.URL.Query().Get("pageToken")
 	return pageToken == ""
 }
+
+// ApplySafetySettings injects the group's configured safetySettings into native generateContent requests.
+// Group thresholds replace any client-provided entry for the same category.
+func (ch *GeminiChannel) ApplySafetySettings(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	configured := group.EffectiveConfig.GeminiSafetySettings
+	if configured == "" || len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
+	path := req.URL.Path
+	if !strings.HasSuffix(path, ":generateContent") && !strings.HasSuffix(path, ":streamGenerateContent") {
+		return bodyBytes, nil
+	}
+
+	settings, err := utils.ParseGeminiSafetySettings(configured)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gemini safety settings: %w", err)
+	}
+	if len(settings) == 0 {
+		return bodyBytes, nil
+	}
+
+	var requestData map[string]any
+	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
+		return nil, fmt.Errorf("failed to parse request body: %w", err)
+	}
+
+	overridden := make(map[string]bool, len(settings))
+	merged := make([]any, 0, len(settings))
+	for _, s := range settings {
+		overridden[s.Category] = true
+		merged = append(merged, s)
+	}
+	if existing, ok := requestData["safetySettings"].([]any); ok {
+		for _, item := range existing {
+			if entry, ok := item.(map[string]any); ok {
+				if category, _ := entry["category"].(string); overridden[category] {
+					continue
+				}
+			}
+			merged = append(merged, item)
+		}
+	}
+	requestData["safetySettings"] = merged
+
+	return json.Marshal(requestData)
+}
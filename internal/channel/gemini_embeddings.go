@@ -0,0 +1,122 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// openAIEmbeddingsRequest is the subset of the OpenAI embeddings request we translate.
+type openAIEmbeddingsRequest struct {
+	Model      string `json:"model"`
+	Input      any    `json:"input"`
+	Dimensions *int   `json:"dimensions,omitempty"`
+}
+
+// embeddingInputs normalizes the OpenAI "input" field, which may be a string or a list of strings.
+func embeddingInputs(input any) ([]string, error) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, nil
+	case []any:
+		inputs := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("only string inputs are supported for embeddings translation")
+			}
+			inputs = append(inputs, s)
+		}
+		return inputs, nil
+	default:
+		return nil, fmt.Errorf("invalid embeddings input")
+	}
+}
+
+// TranslateEmbeddingsRequest converts an OpenAI /v1/embeddings request into a Gemini
+// batchEmbedContents request against the same upstream.
+func (ch *GeminiChannel) TranslateEmbeddingsRequest(upstreamURL string, bodyBytes []byte) (string, []byte, error) {
+	var req openAIEmbeddingsRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return "", nil, fmt.Errorf("invalid embeddings request: %w", err)
+	}
+	if req.Model == "" {
+		return "", nil, fmt.Errorf("model is required")
+	}
+	inputs, err := embeddingInputs(req.Input)
+	if err != nil {
+		return "", nil, err
+	}
+
+	model := strings.TrimPrefix(req.Model, "models/")
+	requests := make([]map[string]any, 0, len(inputs))
+	for _, text := range inputs {
+		r := map[string]any{
+			"model":   "models/" + model,
+			"content": map[string]any{"parts": []map[string]any{{"text": text}}},
+		}
+		if req.Dimensions != nil {
+			r["outputDimensionality"] = *req.Dimensions
+		}
+		requests = append(requests, r)
+	}
+	newBody, err := json.Marshal(map[string]any{"requests": requests})
+	if err != nil {
+		return "", nil, err
+	}
+
+	u, err := url.Parse(upstreamURL)
+	if err != nil {
+		return "", nil, err
+	}
+	idx := strings.LastIndex(u.Path, "/v1/embeddings")
+	if idx < 0 {
+		return "", nil, fmt.Errorf("not an embeddings path: %s", u.Path)
+	}
+	u.Path = u.Path[:idx] + "/v1beta/models/" + model + ":batchEmbedContents"
+
+	return u.String(), newBody, nil
+}
+
+// TranslateEmbeddingsResponse converts a Gemini batchEmbedContents response into the OpenAI
+// embeddings response shape. Gemini does not report token usage for embeddings, so usage is
+// estimated from the input length (about four characters per token).
+func (ch *GeminiChannel) TranslateEmbeddingsResponse(requestBody, responseBody []byte) ([]byte, int, error) {
+	var gemini struct {
+		Embeddings []struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(responseBody, &gemini); err != nil {
+		return nil, 0, fmt.Errorf("invalid gemini embeddings response: %w", err)
+	}
+
+	var req openAIEmbeddingsRequest
+	_ = json.Unmarshal(requestBody, &req)
+	inputs, _ := embeddingInputs(req.Input)
+	promptTokens := 0
+	for _, text := range inputs {
+		promptTokens += (len([]rune(text)) + 3) / 4
+	}
+
+	data := make([]map[string]any, 0, len(gemini.Embeddings))
+	for i, e := range gemini.Embeddings {
+		data = append(data, map[string]any{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": e.Values,
+		})
+	}
+
+	out, err := json.Marshal(map[string]any{
+		"object": "list",
+		"data":   data,
+		"model":  req.Model,
+		"usage": map[string]int{
+			"prompt_tokens": promptTokens,
+			"total_tokens":  promptTokens,
+		},
+	})
+	return out, promptTokens, err
+}
@@ -0,0 +1,195 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	Register("mistral", newMistralChannel)
+}
+
+// MistralChannel proxies the Mistral AI API, which follows the OpenAI request format.
+type MistralChannel struct {
+	*OpenAIChannel
+}
+
+func newMistralChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("mistral", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MistralChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// AcceptsStreamOptions is false: Mistral rejects unknown request fields and always reports usage
+// in the last chunk of a stream.
+func (ch *MistralChannel) AcceptsStreamOptions() bool {
+	return false
+}
+
+// ValidateKey checks the key with a one-token chat completion.
+func (ch *MistralChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	endpointURL, err := url.Parse(ch.ValidationEndpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse validation endpoint: %w", err)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, endpointURL.Path)
+	finalURL.RawQuery = endpointURL.RawQuery
+	reqURL := finalURL.String()
+
+	payload := gin.H{
+		"model": ch.TestModel,
+		"messages": []gin.H{
+			{"role": "user", "content": "hi"},
+		},
+		"max_tokens": 1,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
+
+// FetchModels fetches available models from /v1/models, using the capability flags Mistral reports
+// and falling back to name-based inference for models that omit them.
+func (ch *MistralChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return nil, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, "/v1/models")
+	reqURL := finalURL.String()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create models request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
+
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send models request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorBody, _ := io.ReadAll(resp.Body)
+		parsedError := app_errors.ParseUpstreamError(errorBody)
+		return nil, fmt.Errorf("failed to fetch models [status %d]: %s", resp.StatusCode, parsedError)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models response: %w", err)
+	}
+
+	var response struct {
+		Data []struct {
+			ID               string `json:"id"`
+			Name             string `json:"name"`
+			MaxContextLength int    `json:"max_context_length"`
+			Capabilities     *struct {
+				CompletionChat  bool `json:"completion_chat"`
+				FunctionCalling bool `json:"function_calling"`
+				Vision          bool `json:"vision"`
+			} `json:"capabilities"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %w", err)
+	}
+
+	capabilities := make([]models.ModelCapabilities, 0, len(response.Data))
+	now := time.Now()
+
+	for _, model := range response.Data {
+		capability := models.ModelCapabilities{
+			GroupID:       group.ID,
+			ModelID:       model.ID,
+			ModelName:     model.ID,
+			IsAutoFetched: true,
+			LastFetchedAt: &now,
+		}
+		if model.MaxContextLength > 0 {
+			maxTokens := model.MaxContextLength
+			capability.MaxTokens = &maxTokens
+		}
+
+		if c := model.Capabilities; c != nil {
+			capability.SupportsStreaming = c.CompletionChat
+			capability.SupportsFunctions = c.FunctionCalling
+			capability.SupportsVision = c.Vision
+		} else {
+			capability.SupportsStreaming = !strings.Contains(model.ID, "embed")
+			capability.SupportsFunctions = strings.Contains(model.ID, "mistral-large") ||
+				strings.Contains(model.ID, "mistral-medium") || strings.Contains(model.ID, "mistral-small")
+			capability.SupportsVision = strings.Contains(model.ID, "pixtral")
+		}
+
+		capabilities = append(capabilities, capability)
+	}
+
+	return capabilities, nil
+}
@@ -0,0 +1,55 @@
+package channel
+
+import (
+	"context"
+	"gpt-load/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("moonshot", newMoonshotChannel)
+}
+
+// MoonshotChannel proxies the Moonshot AI (Kimi) API, which follows the OpenAI request format.
+type MoonshotChannel struct {
+	*OpenAIChannel
+}
+
+func newMoonshotChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("moonshot", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MoonshotChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// moonshotContextPattern matches the context size suffix of models such as moonshot-v1-128k.
+var moonshotContextPattern = regexp.MustCompile(`-(\d+)k(?:-|$)`)
+
+// FetchModels lists models from /v1/models and seeds capabilities from the model IDs: all chat
+// models support tool calls, and the context window is encoded in the moonshot-v1 names.
+func (ch *MoonshotChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	capabilities, err := ch.OpenAIChannel.FetchModels(ctx, apiKey, group)
+	if err != nil {
+		return nil, err
+	}
+	for i := range capabilities {
+		c := &capabilities[i]
+		id := strings.ToLower(c.ModelID)
+		c.SupportsStreaming = true
+		c.SupportsFunctions = true
+		c.SupportsVision = strings.Contains(id, "vision") || strings.Contains(id, "kimi-latest")
+		if m := moonshotContextPattern.FindStringSubmatch(id); m != nil {
+			if size, err := strconv.Atoi(m[1]); err == nil {
+				maxTokens := size * 1024
+				c.MaxTokens = &maxTokens
+			}
+		}
+	}
+	return capabilities, nil
+}
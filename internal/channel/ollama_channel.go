@@ -0,0 +1,227 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	Register("ollama", newOllamaChannel)
+}
+
+// OllamaChannel proxies Ollama and other local OpenAI-compatible servers. It serves both the
+// OpenAI-compatible endpoints (/v1/...) and Ollama's native API (/api/chat, /api/generate, ...).
+// Such servers are usually unauthenticated, so keys only act as pool placeholders.
+type OllamaChannel struct {
+	*BaseChannel
+}
+
+func newOllamaChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("ollama", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OllamaChannel{
+		BaseChannel: base,
+	}, nil
+}
+
+// ModifyRequest removes client credentials; Ollama does not use authentication headers.
+func (ch *OllamaChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	req.Header.Del("Authorization")
+}
+
+// IsStreamRequest checks if the request is for a streaming response using the pre-read body.
+// Ollama's native chat and generate endpoints stream unless "stream": false is sent.
+func (ch *OllamaChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		return true
+	}
+
+	if c.Query("stream") == "true" {
+		return true
+	}
+
+	type streamPayload struct {
+		Stream *bool `json:"stream"`
+	}
+	var p streamPayload
+	if err := json.Unmarshal(bodyBytes, &p); err == nil && p.Stream != nil {
+		return *p.Stream
+	}
+
+	return isOllamaNativeGenerationPath(c.Request.URL.Path)
+}
+
+// isOllamaNativeGenerationPath reports whether path targets a native endpoint that streams by default.
+func isOllamaNativeGenerationPath(path string) bool {
+	return strings.HasSuffix(path, "/api/chat") || strings.HasSuffix(path, "/api/generate")
+}
+
+func (ch *OllamaChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
+	type modelPayload struct {
+		Model string `json:"model"`
+	}
+	var p modelPayload
+	if err := json.Unmarshal(bodyBytes, &p); err == nil {
+		return p.Model
+	}
+	return ""
+}
+
+// ValidateKey checks that the server is reachable and can run the test model.
+// Connection failures are returned as-is so callers can tell an unreachable server from a bad key.
+func (ch *OllamaChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	endpointURL, err := url.Parse(ch.ValidationEndpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse validation endpoint: %w", err)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, endpointURL.Path)
+	finalURL.RawQuery = endpointURL.RawQuery
+	reqURL := finalURL.String()
+
+	payload := gin.H{
+		"model": ch.TestModel,
+		"messages": []gin.H{
+			{"role": "user", "content": "hi"},
+		},
+		"stream": false,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
+
+// FetchModels lists the locally installed models via /api/tags.
+func (ch *OllamaChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return nil, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, "/api/tags")
+	reqURL := finalURL.String()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create models request: %w", err)
+	}
+
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send models request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorBody, _ := io.ReadAll(resp.Body)
+		parsedError := app_errors.ParseUpstreamError(errorBody)
+		return nil, fmt.Errorf("failed to fetch models [status %d]: %s", resp.StatusCode, parsedError)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models response: %w", err)
+	}
+
+	var response struct {
+		Models []struct {
+			Name    string `json:"name"`
+			Model   string `json:"model"`
+			Details struct {
+				Family   string   `json:"family"`
+				Families []string `json:"families"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %w", err)
+	}
+
+	capabilities := make([]models.ModelCapabilities, 0, len(response.Models))
+	now := time.Now()
+
+	for _, model := range response.Models {
+		modelID := model.Model
+		if modelID == "" {
+			modelID = model.Name
+		}
+		capability := models.ModelCapabilities{
+			GroupID:           group.ID,
+			ModelID:           modelID,
+			ModelName:         model.Name,
+			SupportsStreaming: true,
+			IsAutoFetched:     true,
+			LastFetchedAt:     &now,
+		}
+
+		// Multimodal models report a vision encoder ("clip" or "mllama") among their families.
+		for _, family := range model.Details.Families {
+			if family == "clip" || family == "mllama" {
+				capability.SupportsVision = true
+			}
+		}
+
+		capabilities = append(capabilities, capability)
+	}
+
+	return capabilities, nil
+}
@@ -41,6 +41,11 @@ func (ch *OpenAIChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey,
 	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
 }
 
+// AcceptsStreamOptions reports that OpenAI-format providers accept stream_options.
+func (ch *OpenAIChannel) AcceptsStreamOptions() bool {
+	return true
+}
+
 // IsStreamRequest checks if the request is for a streaming response using the pre-read body.
 func (ch *OpenAIChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
 	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
@@ -88,7 +93,7 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 
 	// Build final URL with path and query parameters
 	finalURL := *upstreamURL
-	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + endpointURL.Path
+	finalURL.Path = ch.upstreamPath(upstreamURL, endpointURL.Path)
 	finalURL.RawQuery = endpointURL.RawQuery
 	reqURL := finalURL.String()
 
@@ -149,7 +154,7 @@ func (ch *OpenAIChannel) FetchModels(ctx context.Context, apiKey *models.APIKey,
 
 	// Build the models list endpoint URL
 	finalURL := *upstreamURL
-	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + "/v1/models"
+	finalURL.Path = ch.upstreamPath(upstreamURL, "/v1/models")
 	reqURL := finalURL.String()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
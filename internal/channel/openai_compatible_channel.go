@@ -0,0 +1,254 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	Register("openai-compatible", newOpenAICompatibleChannel)
+}
+
+// OpenAICompatibleChannel proxies third-party providers that implement the OpenAI API, such as
+// Groq, Together AI and Fireworks, as well as the bearer-authenticated rerank APIs of Cohere,
+// Jina AI and Voyage AI. Model capabilities are read from whichever fields the provider reports,
+// then completed with the heuristics of the matching preset.
+type OpenAICompatibleChannel struct {
+	*OpenAIChannel
+}
+
+func newOpenAICompatibleChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("openai-compatible", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAICompatibleChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// compatibleModel covers the model list fields of the supported providers.
+type compatibleModel struct {
+	ID string `json:"id"`
+	// Name and Endpoints are reported by Cohere, which has no id field.
+	Name      string   `json:"name"`
+	Endpoints []string `json:"endpoints"`
+	// Type is reported by Together AI, e.g. chat, language, embedding or image.
+	Type                string `json:"type"`
+	ContextLength       int    `json:"context_length"`
+	ContextWindow       int    `json:"context_window"`
+	MaxCompletionTokens *int   `json:"max_completion_tokens"`
+	// Capability flags reported by Fireworks AI.
+	SupportsChat       *bool `json:"supports_chat"`
+	SupportsImageInput bool  `json:"supports_image_input"`
+	SupportsTools      bool  `json:"supports_tools"`
+	// Pricing in USD per million tokens, as reported by Together AI.
+	Pricing *struct {
+		Input  any `json:"input"`
+		Output any `json:"output"`
+	} `json:"pricing"`
+}
+
+// nonChatModelTypes are model types that cannot serve chat completions.
+var nonChatModelTypes = map[string]bool{
+	"embedding": true, "image": true, "audio": true, "rerank": true, "moderation": true, "transcribe": true,
+}
+
+// ValidateKey checks the key with a one-document rerank when the validation endpoint is a rerank
+// API, and with the OpenAI chat completion check otherwise.
+func (ch *OpenAICompatibleChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	endpointURL, err := url.Parse(ch.ValidationEndpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse validation endpoint: %w", err)
+	}
+	if !isRerankPath(endpointURL.Path) {
+		return ch.OpenAIChannel.ValidateKey(ctx, apiKey, group)
+	}
+
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, endpointURL.Path)
+	finalURL.RawQuery = endpointURL.RawQuery
+	reqURL := finalURL.String()
+
+	payload := gin.H{
+		"model":     ch.TestModel,
+		"query":     "hi",
+		"documents": []string{"hi"},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
+
+// FetchModels fetches /v1/models, accepting the OpenAI list object, a bare array and Cohere's
+// models object. Presets with a static catalog are served from it without a request.
+func (ch *OpenAICompatibleChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return nil, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	preset := presetForUpstream(upstreamURL)
+	if preset != nil && len(preset.models) > 0 {
+		return staticCapabilities(preset.models, group.ID), nil
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, "/v1/models")
+	reqURL := finalURL.String()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create models request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
+
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send models request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorBody, _ := io.ReadAll(resp.Body)
+		parsedError := app_errors.ParseUpstreamError(errorBody)
+		return nil, fmt.Errorf("failed to fetch models [status %d]: %s", resp.StatusCode, parsedError)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models response: %w", err)
+	}
+
+	var list []compatibleModel
+	if trimmed := bytes.TrimSpace(bodyBytes); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &list)
+	} else {
+		var response struct {
+			Data   []compatibleModel `json:"data"`
+			Models []compatibleModel `json:"models"`
+		}
+		err = json.Unmarshal(trimmed, &response)
+		list = append(response.Data, response.Models...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %w", err)
+	}
+
+	capabilities := make([]models.ModelCapabilities, 0, len(list))
+	now := time.Now()
+
+	for _, model := range list {
+		if model.ID == "" {
+			model.ID = model.Name
+		}
+		rerank := model.Type == "rerank" || slices.Contains(model.Endpoints, "rerank") || isRerankModel(model.ID)
+		capability := models.ModelCapabilities{
+			GroupID:           group.ID,
+			ModelID:           model.ID,
+			ModelName:         model.ID,
+			SupportsStreaming: !nonChatModelTypes[model.Type] && !rerank && (model.SupportsChat == nil || *model.SupportsChat),
+			SupportsVision:    model.SupportsImageInput,
+			SupportsFunctions: model.SupportsTools,
+			SupportsRerank:    rerank,
+			MaxOutputTokens:   model.MaxCompletionTokens,
+			IsAutoFetched:     true,
+			LastFetchedAt:     &now,
+		}
+		if contextLength := max(model.ContextLength, model.ContextWindow); contextLength > 0 {
+			capability.MaxTokens = &contextLength
+		}
+		if model.Pricing != nil {
+			capability.InputPricePerMillion = nonNegativePrice(model.Pricing.Input)
+			capability.OutputPricePerMillion = nonNegativePrice(model.Pricing.Output)
+		}
+		if preset != nil && preset.inferCapabilities != nil && capability.SupportsStreaming {
+			preset.inferCapabilities(&capability)
+		}
+
+		capabilities = append(capabilities, capability)
+	}
+
+	return capabilities, nil
+}
+
+// staticCapabilities builds capability records for a preset's static model catalog.
+func staticCapabilities(modelIDs []string, groupID uint) []models.ModelCapabilities {
+	capabilities := make([]models.ModelCapabilities, 0, len(modelIDs))
+	now := time.Now()
+	for _, id := range modelIDs {
+		capabilities = append(capabilities, models.ModelCapabilities{
+			GroupID:        groupID,
+			ModelID:        id,
+			ModelName:      id,
+			SupportsRerank: isRerankModel(id),
+			IsAutoFetched:  true,
+			LastFetchedAt:  &now,
+		})
+	}
+	return capabilities
+}
+
+// nonNegativePrice returns a numeric price, or nil when it is missing or not a valid price.
+func nonNegativePrice(v any) *float64 {
+	price, ok := v.(float64)
+	if !ok || price < 0 {
+		return nil
+	}
+	return &price
+}
@@ -0,0 +1,151 @@
+package channel
+
+import (
+	"gpt-load/internal/models"
+	"net/url"
+	"strings"
+)
+
+// ChannelPreset is a quick-add template for a provider served by the openai-compatible channel.
+type ChannelPreset struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	ChannelType        string `json:"channel_type"`
+	Upstream           string `json:"upstream"`
+	ValidationEndpoint string `json:"validation_endpoint"`
+	TestModel          string `json:"test_model"`
+
+	// host identifies groups using this preset by their upstream URL.
+	host string
+	// inferCapabilities, if set, fills in capabilities the provider's model list does not report.
+	inferCapabilities func(capability *models.ModelCapabilities)
+	// models is a static catalog for providers without a model list endpoint.
+	models []string
+}
+
+var channelPresets = []ChannelPreset{
+	{
+		ID:                 "groq",
+		Name:               "Groq",
+		ChannelType:        "openai-compatible",
+		Upstream:           "https://api.groq.com/openai",
+		ValidationEndpoint: "/v1/chat/completions",
+		TestModel:          "llama-3.1-8b-instant",
+		host:               "api.groq.com",
+		inferCapabilities: func(c *models.ModelCapabilities) {
+			id := strings.ToLower(c.ModelID)
+			if strings.Contains(id, "whisper") || strings.Contains(id, "tts") || strings.Contains(id, "guard") {
+				c.SupportsStreaming = false
+				c.SupportsFunctions = false
+				return
+			}
+			c.SupportsFunctions = true
+			c.SupportsVision = c.SupportsVision || strings.Contains(id, "llama-4") || strings.Contains(id, "vision")
+		},
+	},
+	{
+		ID:                 "together",
+		Name:               "Together AI",
+		ChannelType:        "openai-compatible",
+		Upstream:           "https://api.together.xyz",
+		ValidationEndpoint: "/v1/chat/completions",
+		TestModel:          "meta-llama/Llama-3.2-3B-Instruct-Turbo",
+		host:               "api.together.xyz",
+		inferCapabilities: func(c *models.ModelCapabilities) {
+			id := strings.ToLower(c.ModelID)
+			c.SupportsVision = c.SupportsVision || strings.Contains(id, "vision") || strings.Contains(id, "-vl") || strings.Contains(id, "llama-4")
+			c.SupportsFunctions = c.SupportsFunctions || strings.Contains(id, "llama-3") || strings.Contains(id, "llama-4") ||
+				strings.Contains(id, "qwen") || strings.Contains(id, "mistral") || strings.Contains(id, "deepseek-v3")
+		},
+	},
+	{
+		ID:                 "fireworks",
+		Name:               "Fireworks AI",
+		ChannelType:        "openai-compatible",
+		Upstream:           "https://api.fireworks.ai/inference",
+		ValidationEndpoint: "/v1/chat/completions",
+		TestModel:          "accounts/fireworks/models/llama-v3p1-8b-instruct",
+		host:               "api.fireworks.ai",
+		inferCapabilities: func(c *models.ModelCapabilities) {
+			id := strings.ToLower(c.ModelID)
+			c.SupportsVision = c.SupportsVision || strings.Contains(id, "vision") || strings.Contains(id, "-vl") || strings.Contains(id, "llama4")
+			c.SupportsFunctions = c.SupportsFunctions || strings.Contains(id, "firefunction") || strings.Contains(id, "llama-v3p1") ||
+				strings.Contains(id, "llama4") || strings.Contains(id, "qwen") || strings.Contains(id, "deepseek-v3")
+		},
+	},
+	{
+		ID:                 "cohere-rerank",
+		Name:               "Cohere Rerank",
+		ChannelType:        "openai-compatible",
+		Upstream:           "https://api.cohere.com",
+		ValidationEndpoint: "/v2/rerank",
+		TestModel:          "rerank-v3.5",
+		host:               "api.cohere.com",
+	},
+	{
+		ID:                 "jina-rerank",
+		Name:               "Jina AI Rerank",
+		ChannelType:        "openai-compatible",
+		Upstream:           "https://api.jina.ai",
+		ValidationEndpoint: "/v1/rerank",
+		TestModel:          "jina-reranker-v2-base-multilingual",
+		host:               "api.jina.ai",
+		models: []string{
+			"jina-reranker-m0",
+			"jina-reranker-v2-base-multilingual",
+			"jina-colbert-v2",
+			"jina-embeddings-v3",
+			"jina-clip-v2",
+		},
+	},
+	{
+		ID:                 "voyage-rerank",
+		Name:               "Voyage AI Rerank",
+		ChannelType:        "openai-compatible",
+		Upstream:           "https://api.voyageai.com",
+		ValidationEndpoint: "/v1/rerank",
+		TestModel:          "rerank-2.5-lite",
+		host:               "api.voyageai.com",
+		models: []string{
+			"rerank-2.5",
+			"rerank-2.5-lite",
+			"rerank-2",
+			"rerank-2-lite",
+			"voyage-3.5",
+			"voyage-3.5-lite",
+			"voyage-3-large",
+		},
+	},
+}
+
+// isRerankModel reports whether a model ID names a reranker.
+func isRerankModel(modelID string) bool {
+	id := strings.ToLower(modelID)
+	return strings.Contains(id, "rerank") || strings.Contains(id, "colbert")
+}
+
+// isRerankPath reports whether an upstream path is a rerank endpoint.
+func isRerankPath(path string) bool {
+	return strings.HasSuffix(path, "/rerank")
+}
+
+// GetChannelPresets returns the built-in provider presets.
+func GetChannelPresets() []ChannelPreset {
+	presets := make([]ChannelPreset, len(channelPresets))
+	copy(presets, channelPresets)
+	return presets
+}
+
+// presetForUpstream returns the preset whose host matches the upstream URL, if any.
+func presetForUpstream(u *url.URL) *ChannelPreset {
+	if u == nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for i := range channelPresets {
+		if host == channelPresets[i].host {
+			return &channelPresets[i]
+		}
+	}
+	return nil
+}
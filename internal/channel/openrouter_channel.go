@@ -0,0 +1,163 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("openrouter", newOpenRouterChannel)
+}
+
+// OpenRouterChannel proxies OpenRouter, an OpenAI-compatible router in front of many providers.
+type OpenRouterChannel struct {
+	*OpenAIChannel
+}
+
+func newOpenRouterChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("openrouter", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenRouterChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// ModifyRequest sets the Authorization header and the app attribution headers configured for the group.
+func (ch *OpenRouterChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	ch.OpenAIChannel.ModifyRequest(req, apiKey, group)
+	setOpenRouterAttribution(req, group)
+}
+
+func setOpenRouterAttribution(req *http.Request, group *models.Group) {
+	if referer := strings.TrimSpace(group.EffectiveConfig.OpenRouterReferer); referer != "" {
+		req.Header.Set("HTTP-Referer", referer)
+	}
+	if title := strings.TrimSpace(group.EffectiveConfig.OpenRouterTitle); title != "" {
+		req.Header.Set("X-Title", title)
+	}
+}
+
+// openRouterModel is an entry of OpenRouter's extended model list.
+type openRouterModel struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ContextLength int    `json:"context_length"`
+	Pricing       struct {
+		// Prices are decimal strings in USD per token; "-1" marks variable pricing.
+		Prompt     string `json:"prompt"`
+		Completion string `json:"completion"`
+	} `json:"pricing"`
+	Architecture struct {
+		InputModalities []string `json:"input_modalities"`
+	} `json:"architecture"`
+	TopProvider struct {
+		MaxCompletionTokens *int `json:"max_completion_tokens"`
+	} `json:"top_provider"`
+	SupportedParameters []string `json:"supported_parameters"`
+}
+
+// FetchModels reads OpenRouter's model list, which reports context length, pricing, input
+// modalities and supported parameters for every model.
+func (ch *OpenRouterChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return nil, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = ch.upstreamPath(upstreamURL, "/v1/models")
+	reqURL := finalURL.String()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create models request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
+	setOpenRouterAttribution(req, group)
+
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send models request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorBody, _ := io.ReadAll(resp.Body)
+		parsedError := app_errors.ParseUpstreamError(errorBody)
+		return nil, fmt.Errorf("failed to fetch models [status %d]: %s", resp.StatusCode, parsedError)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models response: %w", err)
+	}
+
+	var response struct {
+		Data []openRouterModel `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %w", err)
+	}
+
+	capabilities := make([]models.ModelCapabilities, 0, len(response.Data))
+	now := time.Now()
+
+	for _, model := range response.Data {
+		name := model.Name
+		if name == "" {
+			name = model.ID
+		}
+		capability := models.ModelCapabilities{
+			GroupID:               group.ID,
+			ModelID:               model.ID,
+			ModelName:             name,
+			SupportsStreaming:     true,
+			SupportsVision:        slices.Contains(model.Architecture.InputModalities, "image"),
+			SupportsFunctions:     slices.Contains(model.SupportedParameters, "tools"),
+			MaxOutputTokens:       model.TopProvider.MaxCompletionTokens,
+			InputPricePerMillion:  perMillionPrice(model.Pricing.Prompt),
+			OutputPricePerMillion: perMillionPrice(model.Pricing.Completion),
+			IsAutoFetched:         true,
+			LastFetchedAt:         &now,
+		}
+		if model.ContextLength > 0 {
+			maxTokens := model.ContextLength
+			capability.MaxTokens = &maxTokens
+		}
+
+		capabilities = append(capabilities, capability)
+	}
+
+	return capabilities, nil
+}
+
+// perMillionPrice converts a per-token price string to USD per million tokens.
+// It returns nil for missing, malformed or variable ("-1") prices.
+func perMillionPrice(perToken string) *float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(perToken), 64)
+	if err != nil || v < 0 {
+		return nil
+	}
+	// Rounded to a millionth of a dollar to drop float noise such as 2.9999999999999996.
+	price := math.Round(v*1e12) / 1e6
+	return &price
+}
@@ -0,0 +1,113 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// upstreamHealthChannel carries changes of upstream circuit state between instances.
+const upstreamHealthChannel = "upstream_health"
+
+// upstreamHealthMessage is the circuit state of one upstream and model after a change.
+type upstreamHealthMessage struct {
+	GroupID        uint      `json:"group_id"`
+	Upstream       string    `json:"upstream"`
+	Model          string    `json:"model"`
+	Failures       int       `json:"failures"`
+	UnhealthyUntil time.Time `json:"unhealthy_until"`
+}
+
+// upstreamFailuresKey returns the shared counter of consecutive failures of an upstream and model.
+func upstreamFailuresKey(key latencyKey) string {
+	return fmt.Sprintf("upstream_failures:%d:%s:%s", key.groupID, key.upstream, key.model)
+}
+
+// countSharedFailure adds a failure to the shared counter and returns the failures counted by all
+// instances, or 0 when the store is unavailable.
+func (t *LatencyTracker) countSharedFailure(key latencyKey) int {
+	failures, err := t.store.HIncrBy(upstreamFailuresKey(key), "failures", 1)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to count upstream failure in store")
+		return 0
+	}
+	return int(failures)
+}
+
+// resetSharedFailures clears the shared counter after a success.
+func (t *LatencyTracker) resetSharedFailures(key latencyKey) {
+	if err := t.store.Delete(upstreamFailuresKey(key)); err != nil {
+		logrus.WithError(err).Debug("Failed to reset upstream failures in store")
+	}
+}
+
+// publishHealth tells the other instances about the circuit state of an upstream and model.
+func (t *LatencyTracker) publishHealth(key latencyKey, failures int, unhealthyUntil time.Time) {
+	payload, err := json.Marshal(upstreamHealthMessage{
+		GroupID:        key.groupID,
+		Upstream:       key.upstream,
+		Model:          key.model,
+		Failures:       failures,
+		UnhealthyUntil: unhealthyUntil,
+	})
+	if err != nil {
+		return
+	}
+	if err := t.store.Publish(upstreamHealthChannel, payload); err != nil {
+		logrus.WithError(err).Debug("Failed to publish upstream health")
+	}
+}
+
+// listenHealth applies the circuit state published by the instances, re-subscribing when the
+// subscription is lost.
+func (t *LatencyTracker) listenHealth() {
+	for {
+		subscription, err := t.store.Subscribe(upstreamHealthChannel)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to subscribe to upstream health, retrying in 5s")
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for msg := range subscription.Channel() {
+			var health upstreamHealthMessage
+			if err := json.Unmarshal(msg.Payload, &health); err != nil {
+				continue
+			}
+			t.applyHealth(health)
+		}
+		subscription.Close()
+	}
+}
+
+// applyHealth takes over the circuit state published by an instance.
+func (t *LatencyTracker) applyHealth(health upstreamHealthMessage) {
+	key := latencyKey{groupID: health.GroupID, upstream: health.Upstream, model: health.Model}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.stats[key]
+	if !ok {
+		if health.Failures == 0 {
+			return
+		}
+		s = &upstreamLatency{}
+		t.stats[key] = s
+	}
+	s.consecutiveFailures = health.Failures
+	s.unhealthyUntil = health.UnhealthyUntil
+}
+
+// newSharedLatencyTracker creates a LatencyTracker whose circuit state is shared through the
+// store, so that every instance takes a failing upstream out of rotation at the same time.
+// Latency samples stay per instance.
+func newSharedLatencyTracker(s store.Store) *LatencyTracker {
+	t := newLatencyTracker()
+	t.store = s
+	go t.listenHealth()
+	return t
+}
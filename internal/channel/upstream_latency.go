@@ -0,0 +1,319 @@
+package channel
+
+import (
+	"math"
+	"math/rand"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+// Upstream selection strategies, configured per group via upstream_selection.
+const (
+	// UpstreamSelectionWeighted uses smooth weighted round-robin over the static weights.
+	UpstreamSelectionWeighted = "weighted"
+	// UpstreamSelectionLatency prefers the healthy upstream with the lowest recent latency for the model.
+	UpstreamSelectionLatency = "latency"
+)
+
+const (
+	// latencyWindow is how long a sample counts; older samples expire, so an upstream that
+	// recovered from a slow period is judged on fresh measurements again.
+	latencyWindow = 5 * time.Minute
+	// latencyMaxSamples bounds the samples kept per upstream and model.
+	latencyMaxSamples = 200
+	// latencyMinSamples is the number of recent samples an upstream needs before it is ranked.
+	latencyMinSamples = 5
+	// latencyExplorationRate is the percentage of requests spread by weight across healthy
+	// upstreams, keeping measurements of slower upstreams current.
+	latencyExplorationRate = 5
+	// upstreamFailureThreshold consecutive failures take an upstream out of latency selection
+	// for upstreamCooldown.
+	upstreamFailureThreshold = 3
+	upstreamCooldown         = 30 * time.Second
+	// latencySweepInterval limits how often statistics without recent data are removed.
+	latencySweepInterval = time.Minute
+)
+
+// latencyKey identifies the measurements of one upstream of a group for one model.
+type latencyKey struct {
+	groupID  uint
+	upstream string
+	model    string
+}
+
+// latencySample is the time until an upstream answered a request.
+type latencySample struct {
+	at time.Time
+	ms float64
+}
+
+// upstreamLatency holds the recent samples and health of one upstream and model.
+type upstreamLatency struct {
+	samples             []latencySample
+	consecutiveFailures int
+	unhealthyUntil      time.Time
+}
+
+// LatencyTracker keeps rolling latency statistics per upstream and model. It is owned by the
+// Factory so that statistics survive channels being rebuilt after config changes.
+type LatencyTracker struct {
+	mu        sync.Mutex
+	stats     map[latencyKey]*upstreamLatency
+	lastSweep time.Time
+	// probes holds the active probe results per upstream, independent of the model.
+	probes map[probeKey]*upstreamProbe
+	// store shares consecutive failures and open circuits between instances when set.
+	store store.Store
+}
+
+// UpstreamLatencyStats reports the recent latency of one upstream for one model.
+type UpstreamLatencyStats struct {
+	Upstream            string  `json:"upstream"`
+	Model               string  `json:"model"`
+	Samples             int     `json:"samples"`
+	P50Ms               float64 `json:"p50_ms"`
+	P95Ms               float64 `json:"p95_ms"`
+	Healthy             bool    `json:"healthy"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	// CircuitOpen is set while the upstream keeps failing, from the failure that took it out of
+	// rotation until its next success
+	CircuitOpen bool `json:"circuit_open"`
+}
+
+func newLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{
+		stats:  make(map[latencyKey]*upstreamLatency),
+		probes: make(map[probeKey]*upstreamProbe),
+	}
+}
+
+// sweep removes statistics whose samples have all expired and whose upstream is healthy.
+// Callers must hold mu.
+func (t *LatencyTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < latencySweepInterval {
+		return
+	}
+	t.lastSweep = now
+	for key, s := range t.stats {
+		s.expire(now)
+		if len(s.samples) == 0 && s.healthy(now) {
+			delete(t.stats, key)
+		}
+	}
+}
+
+// record adds the outcome of a request. Failures count toward taking the upstream out of rotation
+// but add no latency sample.
+func (t *LatencyTracker) record(key latencyKey, latency time.Duration, success bool) {
+	if t.store == nil {
+		t.update(key, latency, success, 0)
+		return
+	}
+
+	// Consecutive failures are counted across instances, and every change of circuit state is
+	// published so that the other instances open and close the circuit together.
+	sharedFailures := 0
+	if !success {
+		sharedFailures = t.countSharedFailure(key)
+	}
+	failures, unhealthyUntil, changed := t.update(key, latency, success, sharedFailures)
+	if !changed {
+		return
+	}
+	if success {
+		t.resetSharedFailures(key)
+	}
+	t.publishHealth(key, failures, unhealthyUntil)
+}
+
+// update applies the outcome of a request to the local statistics, taking sharedFailures as the
+// consecutive failure count when it is set. It returns the resulting circuit state and whether it
+// changed.
+func (t *LatencyTracker) update(key latencyKey, latency time.Duration, success bool, sharedFailures int) (int, time.Time, bool) {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep(now)
+	s, ok := t.stats[key]
+	if !ok {
+		s = &upstreamLatency{}
+		t.stats[key] = s
+	}
+	if !success {
+		s.consecutiveFailures = max(s.consecutiveFailures+1, sharedFailures)
+		if s.consecutiveFailures >= upstreamFailureThreshold {
+			s.unhealthyUntil = now.Add(upstreamCooldown)
+		}
+		return s.consecutiveFailures, s.unhealthyUntil, true
+	}
+
+	changed := s.consecutiveFailures > 0 || !s.unhealthyUntil.IsZero()
+	s.consecutiveFailures = 0
+	s.unhealthyUntil = time.Time{}
+	s.expire(now)
+	if len(s.samples) >= latencyMaxSamples {
+		s.samples = s.samples[1:]
+	}
+	s.samples = append(s.samples, latencySample{at: now, ms: float64(latency.Microseconds()) / 1000})
+	return 0, time.Time{}, changed
+}
+
+// expire drops samples older than latencyWindow. Samples are kept in arrival order.
+func (s *upstreamLatency) expire(now time.Time) {
+	i := 0
+	for i < len(s.samples) && now.Sub(s.samples[i].at) > latencyWindow {
+		i++
+	}
+	if i > 0 {
+		s.samples = append(s.samples[:0], s.samples[i:]...)
+	}
+}
+
+func (s *upstreamLatency) healthy(now time.Time) bool {
+	return !now.Before(s.unhealthyUntil)
+}
+
+// percentiles returns the p50 and p95 of the current samples.
+func (s *upstreamLatency) percentiles() (p50, p95 float64) {
+	if len(s.samples) == 0 {
+		return 0, 0
+	}
+	values := make([]float64, len(s.samples))
+	for i, sample := range s.samples {
+		values[i] = sample.ms
+	}
+	sort.Float64s(values)
+	return percentile(values, 0.5), percentile(values, 0.95)
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, q float64) float64 {
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// pick chooses an upstream for a model: an under-sampled healthy upstream first, then a
+// weighted-random healthy one with the exploration probability, otherwise the healthy upstream
+// with the lowest p50 (p95 breaks ties). Upstreams failing their active probes are left out. It
+// returns nil when every upstream is unhealthy.
+func (t *LatencyTracker) pick(groupID uint, upstreams []UpstreamInfo, model string) *UpstreamInfo {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	type candidate struct {
+		upstream *UpstreamInfo
+		stats    *upstreamLatency
+	}
+	var healthy []candidate
+	for i := range upstreams {
+		s, ok := t.stats[latencyKey{groupID: groupID, upstream: upstreams[i].URL.String(), model: model}]
+		if !ok {
+			s = &upstreamLatency{}
+		}
+		if !s.healthy(now) || !t.probeHealthy(groupID, upstreams[i].URL.String()) {
+			continue
+		}
+		s.expire(now)
+		healthy = append(healthy, candidate{upstream: &upstreams[i], stats: s})
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	var leastSampled *candidate
+	for i := range healthy {
+		if n := len(healthy[i].stats.samples); n < latencyMinSamples && (leastSampled == nil || n < len(leastSampled.stats.samples)) {
+			leastSampled = &healthy[i]
+		}
+	}
+	if leastSampled != nil {
+		return leastSampled.upstream
+	}
+
+	if rand.Intn(100) < latencyExplorationRate {
+		total := 0
+		for _, c := range healthy {
+			total += c.upstream.Weight
+		}
+		n := rand.Intn(max(total, 1))
+		for _, c := range healthy {
+			n -= c.upstream.Weight
+			if n < 0 {
+				return c.upstream
+			}
+		}
+	}
+
+	best := healthy[0]
+	bestP50, bestP95 := best.stats.percentiles()
+	for _, c := range healthy[1:] {
+		p50, p95 := c.stats.percentiles()
+		if p50 < bestP50 || (p50 == bestP50 && p95 < bestP95) {
+			best, bestP50, bestP95 = c, p50, p95
+		}
+	}
+	return best.upstream
+}
+
+// report returns the statistics of a group's upstreams, slowest p95 first.
+func (t *LatencyTracker) report(groupID uint) []UpstreamLatencyStats {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var report []UpstreamLatencyStats
+	for key, s := range t.stats {
+		if key.groupID != groupID {
+			continue
+		}
+		s.expire(now)
+		if len(s.samples) == 0 && s.consecutiveFailures == 0 {
+			continue
+		}
+		p50, p95 := s.percentiles()
+		report = append(report, UpstreamLatencyStats{
+			Upstream:            key.upstream,
+			Model:               key.model,
+			Samples:             len(s.samples),
+			P50Ms:               p50,
+			P95Ms:               p95,
+			Healthy:             s.healthy(now),
+			ConsecutiveFailures: s.consecutiveFailures,
+			CircuitOpen:         s.consecutiveFailures >= upstreamFailureThreshold,
+		})
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].P95Ms != report[j].P95Ms {
+			return report[i].P95Ms > report[j].P95Ms
+		}
+		return report[i].Upstream+report[i].Model < report[j].Upstream+report[j].Model
+	})
+	return report
+}
+
+// UpstreamLatency returns the rolling latency statistics of a group's upstreams.
+func (f *Factory) UpstreamLatency(groupID uint) []UpstreamLatencyStats {
+	return f.latency.report(groupID)
+}
+
+// RecordUpstreamResult records how long the upstream that target points at took to answer.
+// Unsuccessful requests count toward the upstream's health instead of its latency.
+func (b *BaseChannel) RecordUpstreamResult(target *url.URL, model string, latency time.Duration, success bool) {
+	if b.latency == nil {
+		return
+	}
+	upstream := b.MatchUpstream(target)
+	if upstream == nil {
+		return
+	}
+	b.latency.record(latencyKey{groupID: b.groupID, upstream: upstream.URL.String(), model: model}, latency, success)
+}
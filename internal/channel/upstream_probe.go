@@ -0,0 +1,167 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// upstreamProbeFailureThreshold consecutive failed probes mark an upstream unhealthy until a
+	// probe succeeds again.
+	upstreamProbeFailureThreshold = 2
+	// upstreamProbeTimeout bounds each probe.
+	upstreamProbeTimeout = 5 * time.Second
+)
+
+// probeKey identifies the active probes of one upstream of a group.
+type probeKey struct {
+	groupID  uint
+	upstream string
+}
+
+// upstreamProbe holds the outcome of the recent active probes of an upstream.
+type upstreamProbe struct {
+	checkedAt           time.Time
+	consecutiveFailures int
+	lastError           string
+}
+
+// UpstreamHealth reports the combined active and passive health of one upstream of a group.
+type UpstreamHealth struct {
+	Upstream string `json:"upstream"`
+	Healthy  bool   `json:"healthy"`
+	// ProbeHealthy is false once upstreamProbeFailureThreshold probes in a row failed.
+	ProbeHealthy bool `json:"probe_healthy"`
+	// CircuitOpen is set while requests to the upstream keep failing for every model it serves.
+	CircuitOpen    bool       `json:"circuit_open"`
+	LastProbeAt    *time.Time `json:"last_probe_at,omitempty"`
+	LastProbeError string     `json:"last_probe_error,omitempty"`
+}
+
+// recordProbe stores the outcome of an active probe.
+func (t *LatencyTracker) recordProbe(key probeKey, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.probes[key]
+	if !ok {
+		p = &upstreamProbe{}
+		t.probes[key] = p
+	}
+	p.checkedAt = time.Now()
+	if err != nil {
+		p.consecutiveFailures++
+		p.lastError = err.Error()
+		return
+	}
+	p.consecutiveFailures = 0
+	p.lastError = ""
+}
+
+// probeHealthy reports whether the active probes of an upstream pass. Upstreams not probed yet
+// count as healthy. Callers must hold mu.
+func (t *LatencyTracker) probeHealthy(groupID uint, upstream string) bool {
+	p, ok := t.probes[probeKey{groupID: groupID, upstream: upstream}]
+	return !ok || p.consecutiveFailures < upstreamProbeFailureThreshold
+}
+
+// circuitOpen reports whether the circuit of an upstream is open for every model it was used for.
+// Callers must hold mu.
+func (t *LatencyTracker) circuitOpen(groupID uint, upstream string, now time.Time) bool {
+	open := false
+	for key, s := range t.stats {
+		if key.groupID != groupID || key.upstream != upstream {
+			continue
+		}
+		if s.healthy(now) {
+			return false
+		}
+		open = true
+	}
+	return open
+}
+
+// upstreamHealthy combines the active probes and the passive circuit state of an upstream.
+// Callers must hold mu.
+func (t *LatencyTracker) upstreamHealthy(groupID uint, upstream string, now time.Time) bool {
+	return t.probeHealthy(groupID, upstream) && !t.circuitOpen(groupID, upstream, now)
+}
+
+// health reports the combined health of a group's upstreams.
+func (t *LatencyTracker) health(groupID uint, upstreams []UpstreamInfo) []UpstreamHealth {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := make([]UpstreamHealth, 0, len(upstreams))
+	for i := range upstreams {
+		upstream := upstreams[i].URL.String()
+		health := UpstreamHealth{
+			Upstream:     upstream,
+			ProbeHealthy: t.probeHealthy(groupID, upstream),
+			CircuitOpen:  t.circuitOpen(groupID, upstream, now),
+		}
+		health.Healthy = health.ProbeHealthy && !health.CircuitOpen
+		if p, ok := t.probes[probeKey{groupID: groupID, upstream: upstream}]; ok {
+			checkedAt := p.checkedAt
+			health.LastProbeAt = &checkedAt
+			health.LastProbeError = p.lastError
+		}
+		report = append(report, health)
+	}
+	return report
+}
+
+// ProbeUpstreams requests the model list of every upstream without credentials and records
+// whether it answered. Any answer below 500, including 401 and 404, shows the upstream is up, and
+// so does 501 from upstreams that do not serve GET.
+func (b *BaseChannel) ProbeUpstreams(ctx context.Context) []UpstreamHealth {
+	if b.latency == nil {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := range b.Upstreams {
+		wg.Add(1)
+		go func(base *url.URL) {
+			defer wg.Done()
+			err := b.probeUpstream(ctx, base)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{"upstream": base.String(), "error": err}).Debug("Upstream probe failed")
+			}
+			b.latency.recordProbe(probeKey{groupID: b.groupID, upstream: base.String()}, err)
+		}(b.Upstreams[i].URL)
+	}
+	wg.Wait()
+
+	return b.latency.health(b.groupID, b.Upstreams)
+}
+
+// probeUpstream sends one probe to an upstream.
+func (b *BaseChannel) probeUpstream(ctx context.Context, base *url.URL) error {
+	ctx, cancel := context.WithTimeout(ctx, upstreamProbeTimeout)
+	defer cancel()
+
+	target := *base
+	target.Path = b.upstreamPath(base, b.versionPrefix+"/models")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package channel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"gpt-load/internal/models"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("zhipu", newZhipuChannel)
+}
+
+// zhipuTokenTTL is the lifetime of the signed tokens sent to Zhipu; they are reused until
+// shortly before they expire.
+const zhipuTokenTTL = 30 * time.Minute
+
+// ZhipuChannel proxies the Zhipu AI (GLM) open platform, whose upstream base is the versioned
+// https://open.bigmodel.cn/api/paas/v4. Keys of the form {id}.{secret} are exchanged for a
+// short-lived HS256 token, as the official SDKs do.
+type ZhipuChannel struct {
+	*OpenAIChannel
+
+	tokenMu sync.Mutex
+	tokens  map[string]zhipuToken
+}
+
+type zhipuToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+func newZhipuChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("zhipu", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ZhipuChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+		tokens:        make(map[string]zhipuToken),
+	}, nil
+}
+
+// ModifyRequest sets the Authorization header to a signed token for the key.
+func (ch *ZhipuChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	req.Header.Set("Authorization", "Bearer "+ch.authToken(apiKey.KeyValue))
+}
+
+// ValidateKey runs the OpenAI chat completion check with the signed token in place of the key.
+func (ch *ZhipuChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	signed := *apiKey
+	signed.KeyValue = ch.authToken(apiKey.KeyValue)
+	return ch.OpenAIChannel.ValidateKey(ctx, &signed, group)
+}
+
+// authToken returns a cached or freshly signed token for a key. Keys that are not in the
+// {id}.{secret} format are sent unchanged.
+func (ch *ZhipuChannel) authToken(key string) string {
+	id, secret, ok := strings.Cut(key, ".")
+	if !ok || id == "" || secret == "" {
+		return key
+	}
+
+	ch.tokenMu.Lock()
+	defer ch.tokenMu.Unlock()
+
+	now := time.Now()
+	if cached, ok := ch.tokens[key]; ok && now.Add(time.Minute).Before(cached.expiresAt) {
+		return cached.value
+	}
+
+	expiresAt := now.Add(zhipuTokenTTL)
+	token, err := signZhipuToken(id, secret, now, expiresAt)
+	if err != nil {
+		return key
+	}
+	ch.tokens[key] = zhipuToken{value: token, expiresAt: expiresAt}
+	return token
+}
+
+// signZhipuToken builds the HS256 JWT Zhipu expects, with millisecond timestamps.
+func signZhipuToken(id, secret string, issuedAt, expiresAt time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "sign_type": "SIGN"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]any{
+		"api_key":   id,
+		"exp":       expiresAt.UnixMilli(),
+		"timestamp": issuedAt.UnixMilli(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// zhipuModels seeds the GLM catalog, since the platform has no model list endpoint.
+var zhipuModels = []struct {
+	id        string
+	maxTokens int
+	vision    bool
+	functions bool
+	embedding bool
+}{
+	{id: "glm-4.6", maxTokens: 200000, functions: true},
+	{id: "glm-4.5", maxTokens: 128000, functions: true},
+	{id: "glm-4.5-air", maxTokens: 128000, functions: true},
+	{id: "glm-4.5-flash", maxTokens: 128000, functions: true},
+	{id: "glm-4-plus", maxTokens: 128000, functions: true},
+	{id: "glm-4-air-250414", maxTokens: 128000, functions: true},
+	{id: "glm-4-flash", maxTokens: 128000, functions: true},
+	{id: "glm-4-long", maxTokens: 1000000, functions: true},
+	{id: "glm-4.5v", vision: true},
+	{id: "glm-4v-plus-0111", vision: true},
+	{id: "glm-4v-flash", vision: true},
+	{id: "embedding-3", embedding: true},
+}
+
+// FetchModels returns the seeded GLM catalog.
+func (ch *ZhipuChannel) FetchModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]models.ModelCapabilities, error) {
+	capabilities := make([]models.ModelCapabilities, 0, len(zhipuModels))
+	now := time.Now()
+
+	for _, model := range zhipuModels {
+		capability := models.ModelCapabilities{
+			GroupID:           group.ID,
+			ModelID:           model.id,
+			ModelName:         model.id,
+			SupportsStreaming: !model.embedding,
+			SupportsVision:    model.vision,
+			SupportsFunctions: model.functions,
+			IsAutoFetched:     true,
+			LastFetchedAt:     &now,
+		}
+		if model.maxTokens > 0 {
+			maxTokens := model.maxTokens
+			capability.MaxTokens = &maxTokens
+		}
+		capabilities = append(capabilities, capability)
+	}
+
+	return capabilities, nil
+}
@@ -0,0 +1,510 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/handler"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/sirupsen/logrus"
+)
+
+// taskPollInterval is how often the status of a background task is polled.
+const taskPollInterval = time.Second
+
+// stringList is a flag that can be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// adminCommand parses "<command> <subcommand> [flags]" and returns the subcommand, or prints the
+// usage and exits when it is missing or not one of subcommands.
+func adminCommand(fs *flag.FlagSet, args []string, subcommands ...string) string {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fs.Usage()
+		os.Exit(0)
+	}
+	subcommand := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	for _, name := range subcommands {
+		if name == subcommand {
+			return subcommand
+		}
+	}
+	fmt.Printf("Unknown %s subcommand: %s\n\n", fs.Name(), subcommand)
+	fs.Usage()
+	os.Exit(1)
+	return ""
+}
+
+func printAdminUsage(fs *flag.FlagSet, lines ...string) func() {
+	return func() {
+		fmt.Println("Usage:")
+		for _, line := range lines {
+			fmt.Println("  " + line)
+		}
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs.PrintDefaults()
+	}
+}
+
+func printJSON(v any) {
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(data))
+}
+
+// RunGroups handles the groups command entry point
+func RunGroups(args []string) {
+	fs := flag.NewFlagSet("groups", flag.ExitOnError)
+	newClient := adminClientFlags(fs)
+	jsonOutput := fs.Bool("json", false, "Print JSON instead of a table")
+	fromFile := fs.String("file", "", "create: read the group as JSON from this file ('-' for stdin)")
+	name := fs.String("name", "", "create: group name")
+	displayName := fs.String("display-name", "", "create: display name")
+	channelType := fs.String("channel", "openai", "create: channel type")
+	testModel := fs.String("test-model", "", "create: model used to validate keys")
+	var upstreams stringList
+	fs.Var(&upstreams, "upstream", "create: upstream URL, may be repeated")
+	fs.Usage = printAdminUsage(fs,
+		"gpt-load groups list [flags]      List groups",
+		"gpt-load groups create [flags]    Create a group from flags or from a JSON file",
+	)
+
+	subcommand := adminCommand(fs, args, "list", "create")
+	client := newClient()
+
+	switch subcommand {
+	case "list":
+		var groups []handler.GroupResponse
+		if err := client.Call(http.MethodGet, "/groups", nil, nil, &groups); err != nil {
+			logrus.Fatalf("Failed to list groups: %v", err)
+		}
+		if *jsonOutput {
+			printJSON(groups)
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTYPE\tCHANNEL\tENDPOINT")
+		for _, group := range groups {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", group.ID, group.Name, group.GroupType, group.ChannelType, group.Endpoint)
+		}
+		w.Flush()
+
+	case "create":
+		var req handler.GroupCreateRequest
+		if *fromFile != "" {
+			data, err := readInput(*fromFile)
+			if err != nil {
+				logrus.Fatalf("Failed to read group: %v", err)
+			}
+			if err := json.Unmarshal(data, &req); err != nil {
+				logrus.Fatalf("Invalid group JSON: %v", err)
+			}
+		} else {
+			if *name == "" || len(upstreams) == 0 {
+				logrus.Fatal("create needs --name and at least one --upstream, or --file")
+			}
+			req = handler.GroupCreateRequest{
+				Name:        *name,
+				DisplayName: *displayName,
+				ChannelType: *channelType,
+				TestModel:   *testModel,
+			}
+			list := make([]map[string]any, 0, len(upstreams))
+			for _, upstream := range upstreams {
+				list = append(list, map[string]any{"url": upstream, "weight": 1})
+			}
+			req.Upstreams, _ = json.Marshal(list)
+		}
+
+		var group handler.GroupResponse
+		if err := client.Call(http.MethodPost, "/groups", nil, req, &group); err != nil {
+			logrus.Fatalf("Failed to create group: %v", err)
+		}
+		if *jsonOutput {
+			printJSON(group)
+			return
+		}
+		fmt.Printf("Created group %s (id %d), endpoint %s\n", group.Name, group.ID, group.Endpoint)
+	}
+}
+
+// RunKeys handles the keys command entry point
+func RunKeys(args []string) {
+	fs := flag.NewFlagSet("keys", flag.ExitOnError)
+	newClient := adminClientFlags(fs)
+	groupRef := fs.String("group", "", "Group name or ID")
+	file := fs.String("file", "-", "import: file with keys separated by newlines, commas or spaces ('-' for stdin)")
+	status := fs.String("status", "", "validate: only validate keys with this status (active or invalid)")
+	noWait := fs.Bool("no-wait", false, "Return once the task is started instead of waiting for its result")
+	fs.Usage = printAdminUsage(fs,
+		"gpt-load keys import --group <name> [flags]     Add keys to a group",
+		"gpt-load keys validate --group <name> [flags]   Validate the keys of a group",
+	)
+
+	subcommand := adminCommand(fs, args, "import", "validate")
+	if *groupRef == "" {
+		logrus.Fatal("--group is required")
+	}
+	client := newClient()
+	group, err := findGroup(client, *groupRef)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	var task services.TaskStatus
+	switch subcommand {
+	case "import":
+		data, err := readInput(*file)
+		if err != nil {
+			logrus.Fatalf("Failed to read keys: %v", err)
+		}
+		req := handler.KeyTextRequest{GroupID: group.ID, KeysText: string(data)}
+		if err := client.Call(http.MethodPost, "/keys/add-async", nil, req, &task); err != nil {
+			logrus.Fatalf("Failed to import keys: %v", err)
+		}
+	case "validate":
+		req := handler.ValidateGroupKeysRequest{GroupID: group.ID, Status: *status}
+		if err := client.Call(http.MethodPost, "/keys/validate-group", nil, req, &task); err != nil {
+			logrus.Fatalf("Failed to validate keys: %v", err)
+		}
+	}
+
+	if *noWait {
+		fmt.Printf("Task %s started for group %s\n", task.TaskType, group.Name)
+		return
+	}
+	finished, err := waitForTask(client)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if finished.Error != "" {
+		logrus.Fatalf("Task %s failed: %s", finished.TaskType, finished.Error)
+	}
+
+	result, _ := json.Marshal(finished.Result)
+	switch subcommand {
+	case "import":
+		var imported services.KeyImportResult
+		_ = json.Unmarshal(result, &imported)
+		fmt.Printf("Added %d keys to %s, %d ignored\n", imported.AddedCount, group.Name, imported.IgnoredCount)
+	case "validate":
+		var validated services.ManualValidationResult
+		_ = json.Unmarshal(result, &validated)
+		fmt.Printf("Validated %d keys of %s: %d valid, %d invalid\n",
+			validated.TotalKeys, group.Name, validated.ValidKeys, validated.InvalidKeys)
+	}
+}
+
+// RunConfig handles the config command entry point
+func RunConfig(args []string) {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	newClient := adminClientFlags(fs)
+	file := fs.String("file", "-", "File to write the export to or read the import from ('-' for stdout/stdin)")
+	includeKeys := fs.Bool("include-keys", false, "export: include the keys, encrypted with --passphrase")
+	noSettings := fs.Bool("no-settings", false, "export: leave out the system settings")
+	passphraseFlag := fs.String("passphrase", "", "Passphrase of the keys in the bundle (env GPT_LOAD_BACKUP_PASSPHRASE)")
+	fs.Usage = printAdminUsage(fs,
+		"gpt-load config export [flags]   Export groups and settings as a backup bundle",
+		"gpt-load config import [flags]   Import a backup bundle",
+	)
+
+	subcommand := adminCommand(fs, args, "export", "import")
+	client := newClient()
+	passphrase := firstNonEmpty(*passphraseFlag, os.Getenv("GPT_LOAD_BACKUP_PASSPHRASE"))
+
+	switch subcommand {
+	case "export":
+		req := handler.ConfigExportRequest{
+			IncludeSettings: !*noSettings,
+			IncludeKeys:     *includeKeys,
+			Passphrase:      passphrase,
+		}
+		data, err := client.Raw(http.MethodPost, "/backup/export", nil, req)
+		if err != nil {
+			logrus.Fatalf("Failed to export config: %v", err)
+		}
+		if *file == "-" {
+			os.Stdout.Write(data)
+			fmt.Println()
+			return
+		}
+		if err := os.WriteFile(*file, data, 0o600); err != nil {
+			logrus.Fatalf("Failed to write bundle: %v", err)
+		}
+		fmt.Printf("Config exported to %s\n", *file)
+
+	case "import":
+		data, err := readInput(*file)
+		if err != nil {
+			logrus.Fatalf("Failed to read bundle: %v", err)
+		}
+		req := struct {
+			Bundle     json.RawMessage `json:"bundle"`
+			Passphrase string          `json:"passphrase"`
+		}{Bundle: data, Passphrase: passphrase}
+		if !json.Valid(req.Bundle) {
+			logrus.Fatal("The bundle is not valid JSON")
+		}
+
+		var result services.ConfigImportResult
+		if err := client.Call(http.MethodPost, "/backup/import", nil, req, &result); err != nil {
+			logrus.Fatalf("Failed to import config: %v", err)
+		}
+		fmt.Printf("Groups created: %d, updated: %d, models imported: %d, keys added: %d, ignored: %d, settings updated: %t\n",
+			result.GroupsCreated, result.GroupsUpdated, result.ModelsImported, result.KeysAdded, result.KeysIgnored, result.SettingsUpdated)
+	}
+}
+
+// RunLogs handles the logs command entry point
+func RunLogs(args []string) {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	newClient := adminClientFlags(fs)
+	group := fs.String("group", "", "Only logs of this group")
+	parentGroup := fs.String("parent-group", "", "Only logs of this aggregate group")
+	model := fs.String("model", "", "Only logs of this model")
+	requestType := fs.String("type", "", "Only logs of this request type (final or retry)")
+	statusClass := fs.String("status", "", "Only logs with this status class, e.g. 4xx or 5xx")
+	jsonOutput := fs.Bool("json", false, "Print every log as a JSON line")
+	fs.Usage = printAdminUsage(fs,
+		"gpt-load logs tail [flags]   Follow request logs as they are recorded",
+	)
+
+	adminCommand(fs, args, "tail")
+	client := newClient()
+
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"group_name":        *group,
+		"parent_group_name": *parentGroup,
+		"model":             *model,
+		"request_type":      *requestType,
+		"status_class":      *statusClass,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := client.Stream(ctx, "/logs/stream", query, func(data []byte) error {
+		if *jsonOutput {
+			fmt.Println(string(data))
+			return nil
+		}
+		var entry models.RequestLog
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil
+		}
+		fmt.Println(formatLogLine(&entry))
+		return nil
+	})
+	if err != nil {
+		logrus.Fatalf("Log stream failed: %v", err)
+	}
+}
+
+func formatLogLine(entry *models.RequestLog) string {
+	groupName := entry.GroupName
+	if entry.ParentGroupName != "" {
+		groupName = entry.ParentGroupName + "/" + groupName
+	}
+	line := fmt.Sprintf("%s %d %-5s %s %s %dms %d tokens",
+		entry.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		entry.StatusCode, entry.RequestType, groupName, entry.Model, entry.Duration, entry.TotalTokens)
+	if entry.ErrorMessage != "" {
+		line += " " + strconv.Quote(entry.ErrorMessage)
+	}
+	return line
+}
+
+// RunEncryption handles the encryption command entry point
+func RunEncryption(args []string) {
+	fs := flag.NewFlagSet("encryption", flag.ExitOnError)
+	newClient := adminClientFlags(fs)
+	noWait := fs.Bool("no-wait", false, "rotate: return once the rotation is started instead of waiting for it to finish")
+	jsonOutput := fs.Bool("json", false, "status: print the status as JSON")
+	showKey := fs.Bool("show-key", false, "generate-key: also print the plain data key, to keep offline as ENCRYPTION_KEY for when the KMS is unreachable")
+	fs.Usage = printAdminUsage(fs,
+		"gpt-load encryption rotate [flags]   Re-encrypt stored keys with the current encryption key version",
+		"gpt-load encryption status [flags]   Show the progress of the encryption key rotation",
+		"gpt-load encryption generate-key     Create a data key wrapped by ENCRYPTION_KMS_PROVIDER",
+		"gpt-load encryption wrap-key         Wrap the current ENCRYPTION_KEY with ENCRYPTION_KMS_PROVIDER",
+	)
+
+	subcommand := adminCommand(fs, args, "rotate", "status", "generate-key", "wrap-key")
+	switch subcommand {
+	case "generate-key", "wrap-key":
+		runWrapKey(subcommand, *showKey)
+		return
+	}
+	client := newClient()
+
+	var status services.EncryptionRotationStatus
+	switch subcommand {
+	case "status":
+		if err := client.Call(http.MethodGet, "/encryption/rotation", nil, nil, &status); err != nil {
+			logrus.Fatalf("Failed to get the rotation status: %v", err)
+		}
+		if *jsonOutput {
+			printJSON(status)
+			return
+		}
+		printRotationStatus(&status)
+		return
+	case "rotate":
+		if err := client.Call(http.MethodPost, "/encryption/rotation", nil, nil, &status); err != nil {
+			logrus.Fatalf("Failed to start the rotation: %v", err)
+		}
+	}
+
+	if *noWait {
+		fmt.Printf("Rotation to encryption key version %d started\n", status.Version)
+		return
+	}
+	for status.Running {
+		fmt.Fprintf(os.Stderr, "\rkeys: %d/%d, request logs: %d/%d, users: %d/%d, transcripts: %d/%d, webhooks: %d/%d",
+			status.Keys.Scanned, status.Keys.Total, status.RequestLogs.Scanned, status.RequestLogs.Total,
+			status.Users.Scanned, status.Users.Total, status.Transcripts.Scanned, status.Transcripts.Total,
+			status.Webhooks.Scanned, status.Webhooks.Total)
+		time.Sleep(taskPollInterval)
+		if err := client.Call(http.MethodGet, "/encryption/rotation", nil, nil, &status); err != nil {
+			logrus.Fatalf("Failed to get the rotation status: %v", err)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	printRotationStatus(&status)
+	if status.Error != "" || status.Keys.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runWrapKey talks to the KMS directly, so it needs the KMS settings of the server rather than its URL.
+func runWrapKey(subcommand string, showKey bool) {
+	kms := config.LoadKMSConfig()
+	if kms.Provider == "" {
+		logrus.Fatal("ENCRYPTION_KMS_PROVIDER and ENCRYPTION_KMS_KEY_ID must be set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if subcommand == "wrap-key" {
+		key := os.Getenv("ENCRYPTION_KEY")
+		if key == "" {
+			logrus.Fatal("ENCRYPTION_KEY must be set to the key to wrap")
+		}
+		wrapped, err := encryption.WrapDataKey(ctx, kms, key)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		fmt.Printf("ENCRYPTION_WRAPPED_KEY=%s\n", wrapped)
+		return
+	}
+
+	key, wrapped, err := encryption.GenerateDataKey(ctx, kms)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	fmt.Printf("ENCRYPTION_WRAPPED_KEY=%s\n", wrapped)
+	if showKey {
+		fmt.Printf("ENCRYPTION_KEY=%s\n", key)
+	}
+}
+
+func printRotationStatus(status *services.EncryptionRotationStatus) {
+	if !status.Enabled {
+		fmt.Println("No previous encryption keys are configured, there is nothing to rotate")
+	}
+	if status.StartedAt == nil {
+		fmt.Printf("No rotation to encryption key version %d has run since the server started\n", status.Version)
+		return
+	}
+	state := "finished"
+	if status.Running {
+		state = "running"
+	}
+	fmt.Printf("Rotation to encryption key version %d %s (started %s)\n",
+		status.Version, state, status.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  API keys:     %d/%d scanned, %d migrated, %d failed\n",
+		status.Keys.Scanned, status.Keys.Total, status.Keys.Migrated, status.Keys.Failed)
+	fmt.Printf("  request logs: %d/%d scanned, %d migrated, %d failed\n",
+		status.RequestLogs.Scanned, status.RequestLogs.Total, status.RequestLogs.Migrated, status.RequestLogs.Failed)
+	fmt.Printf("  users:        %d/%d scanned, %d migrated, %d failed\n",
+		status.Users.Scanned, status.Users.Total, status.Users.Migrated, status.Users.Failed)
+	fmt.Printf("  transcripts:  %d/%d scanned, %d migrated, %d failed\n",
+		status.Transcripts.Scanned, status.Transcripts.Total, status.Transcripts.Migrated, status.Transcripts.Failed)
+	fmt.Printf("  webhooks:     %d/%d scanned, %d migrated, %d failed\n",
+		status.Webhooks.Scanned, status.Webhooks.Total, status.Webhooks.Migrated, status.Webhooks.Failed)
+	if status.Error != "" {
+		fmt.Printf("  stopped: %s\n", status.Error)
+	} else if !status.Running && status.Keys.Failed > 0 {
+		fmt.Println("  some API keys cannot be decrypted, keep ENCRYPTION_PREVIOUS_KEYS and run 'gpt-load db check'")
+	}
+}
+
+// findGroup resolves a group by name, or by ID when ref is a number.
+func findGroup(client *AdminClient, ref string) (*handler.GroupResponse, error) {
+	var groups []handler.GroupResponse
+	if err := client.Call(http.MethodGet, "/groups", nil, nil, &groups); err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	id, _ := strconv.ParseUint(ref, 10, 64)
+	for i := range groups {
+		if groups[i].Name == ref || (id != 0 && uint64(groups[i].ID) == id) {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("group %s not found", ref)
+}
+
+// waitForTask polls the global task until it finishes, printing its progress to stderr.
+func waitForTask(client *AdminClient) (*services.TaskStatus, error) {
+	for {
+		var task services.TaskStatus
+		if err := client.Call(http.MethodGet, "/tasks/status", nil, nil, &task); err != nil {
+			return nil, fmt.Errorf("failed to get task status: %w", err)
+		}
+		if !task.IsRunning {
+			fmt.Fprintln(os.Stderr)
+			return &task, nil
+		}
+		fmt.Fprintf(os.Stderr, "\r%s: %d/%d", task.TaskType, task.Processed, task.Total)
+		time.Sleep(taskPollInterval)
+	}
+}
+
+// readInput reads a file, or stdin when path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
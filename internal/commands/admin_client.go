@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultAdminURL is used when neither --url nor GPT_LOAD_URL is set.
+const defaultAdminURL = "http://localhost:3001"
+
+// AdminClient calls the admin API of a running GPT-Load server.
+type AdminClient struct {
+	baseURL    string
+	token      string
+	totpCode   string
+	httpClient *http.Client
+}
+
+// adminResponse is the envelope of every admin API response. The code is 0 on success and an error
+// code string otherwise.
+type adminResponse struct {
+	Code    json.RawMessage `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// adminClientFlags registers the connection flags shared by the admin commands. The token defaults
+// to GPT_LOAD_TOKEN, then AUTH_KEY, which the server accepts as the built-in administrator.
+func adminClientFlags(fs *flag.FlagSet) func() *AdminClient {
+	baseURL := fs.String("url", firstNonEmpty(os.Getenv("GPT_LOAD_URL"), defaultAdminURL), "Server URL (env GPT_LOAD_URL)")
+	token := fs.String("token", "", "Session token or AUTH_KEY (env GPT_LOAD_TOKEN, then AUTH_KEY)")
+	totpCode := fs.String("totp", "", "Current 2FA code, for operations that require a second factor")
+	timeout := fs.Duration("timeout", 60*time.Second, "Request timeout")
+
+	return func() *AdminClient {
+		return &AdminClient{
+			baseURL:    strings.TrimRight(*baseURL, "/"),
+			token:      firstNonEmpty(*token, os.Getenv("GPT_LOAD_TOKEN"), os.Getenv("AUTH_KEY")),
+			totpCode:   *totpCode,
+			httpClient: &http.Client{Timeout: *timeout},
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Call sends a JSON request and decodes the data of the response envelope into out, if not nil.
+func (c *AdminClient) Call(method, path string, query url.Values, body, out any) error {
+	data, err := c.Raw(method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	var envelope adminResponse
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// Raw sends a JSON request and returns the response body as is, for endpoints that download files.
+func (c *AdminClient) Raw(method, path string, query url.Values, body any) ([]byte, error) {
+	resp, err := c.do(context.Background(), c.httpClient, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Stream reads a server-sent events endpoint and calls handle with the data of every event until
+// the stream ends or ctx is done.
+func (c *AdminClient) Stream(ctx context.Context, path string, query url.Values, handle func(data []byte) error) error {
+	// Streams stay open indefinitely, so only the connection attempt is bounded by the timeout
+	streamClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := c.do(ctx, streamClient, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if err := handle([]byte(data)); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+func (c *AdminClient) do(ctx context.Context, client *http.Client, method, path string, query url.Values, body any) (*http.Response, error) {
+	if c.token == "" {
+		return nil, fmt.Errorf("no token given, set --token, GPT_LOAD_TOKEN or AUTH_KEY")
+	}
+
+	endpoint := c.baseURL + "/api" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.totpCode != "" {
+		req.Header.Set("X-TOTP-Code", c.totpCode)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", c.baseURL, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		var envelope adminResponse
+		if json.Unmarshal(data, &envelope) == nil && envelope.Message != "" {
+			return nil, fmt.Errorf("%s %s: %s (%s)", method, path, envelope.Message, resp.Status)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
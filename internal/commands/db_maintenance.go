@@ -0,0 +1,600 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gpt-load/internal/container"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// maxReportSampleIDs limits how many affected row IDs are listed per check.
+const maxReportSampleIDs = 20
+
+// maintenanceModels are the tables covered by reindex and optimize.
+var maintenanceModels = []any{
+	&models.SystemSetting{},
+	&models.Group{},
+	&models.GroupSubGroup{},
+	&models.APIKey{},
+	&models.RequestLog{},
+	&models.GroupHourlyStat{},
+	&models.ConfigVersion{},
+	&models.ModelCapabilities{},
+}
+
+// MaintenanceReport is the result of a db maintenance run.
+type MaintenanceReport struct {
+	Command    string              `json:"command"`
+	Database   string              `json:"database"`
+	DryRun     bool                `json:"dry_run,omitempty"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	Checks     []IntegrityCheck    `json:"checks,omitempty"`
+	Actions    []MaintenanceAction `json:"actions,omitempty"`
+	SizeBefore int64               `json:"size_before_bytes,omitempty"`
+	SizeAfter  int64               `json:"size_after_bytes,omitempty"`
+	Healthy    bool                `json:"healthy"`
+}
+
+// IntegrityCheck is the outcome of a single integrity check.
+type IntegrityCheck struct {
+	Name        string `json:"name"`
+	Table       string `json:"table"`
+	Description string `json:"description"`
+	Count       int64  `json:"count"`
+	SampleIDs   []uint `json:"sample_ids,omitempty"`
+	Repaired    int64  `json:"repaired,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// MaintenanceAction is a reindex or optimize statement that was executed.
+type MaintenanceAction struct {
+	Statement  string `json:"statement"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RunDBMaintenance handles the db command entry point
+func RunDBMaintenance(args []string) {
+	dbCmd := flag.NewFlagSet("db", flag.ExitOnError)
+	reportPath := dbCmd.String("report", "", "Write the report as JSON to this file")
+	jsonOutput := dbCmd.Bool("json", false, "Print the report as JSON instead of text")
+	dryRun := dbCmd.Bool("dry-run", false, "repair: report what would be changed without writing")
+
+	dbCmd.Usage = func() {
+		fmt.Println("GPT-Load Database Maintenance Tool")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gpt-load db check [flags]      Verify referential integrity and key encryption")
+		fmt.Println("  gpt-load db repair [flags]     Remove orphaned rows and re-encrypt or re-hash damaged keys")
+		fmt.Println("  gpt-load db reindex [flags]    Rebuild table indexes")
+		fmt.Println("  gpt-load db optimize [flags]   Vacuum and refresh planner statistics")
+		fmt.Println()
+		fmt.Println("Flags:")
+		dbCmd.PrintDefaults()
+		fmt.Println()
+		fmt.Println("⚠️  Important Notes:")
+		fmt.Println("  1. Always backup database before repair, reindex or optimize")
+		fmt.Println("  2. Stop service before repair; optimize may lock tables while it runs")
+		fmt.Println("  3. check exits with status 1 when problems are found")
+	}
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		dbCmd.Usage()
+		os.Exit(0)
+	}
+	subcommand := args[0]
+	if err := dbCmd.Parse(args[1:]); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	switch subcommand {
+	case "check", "repair", "reindex", "optimize":
+	default:
+		fmt.Printf("Unknown db subcommand: %s\n\n", subcommand)
+		dbCmd.Usage()
+		os.Exit(1)
+	}
+
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	if err := cont.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+
+	var report *MaintenanceReport
+	if err := cont.Invoke(func(db *gorm.DB, encryptionSvc encryption.Service, cacheStore store.Store) {
+		maintenanceCmd := NewDBMaintenanceCommand(db, encryptionSvc, cacheStore)
+		var runErr error
+		switch subcommand {
+		case "check":
+			report, runErr = maintenanceCmd.Check()
+		case "repair":
+			report, runErr = maintenanceCmd.Repair(*dryRun)
+		case "reindex":
+			report, runErr = maintenanceCmd.Reindex()
+		case "optimize":
+			report, runErr = maintenanceCmd.Optimize()
+		}
+		if runErr != nil {
+			logrus.Fatalf("Database %s failed: %v", subcommand, runErr)
+		}
+	}); err != nil {
+		logrus.Fatalf("Failed to execute db %s: %v", subcommand, err)
+	}
+
+	if *reportPath != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*reportPath, data, 0o644); err != nil {
+			logrus.Fatalf("Failed to write report: %v", err)
+		}
+		logrus.Infof("Report written to %s", *reportPath)
+	}
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printMaintenanceReport(report)
+	}
+
+	if !report.Healthy {
+		os.Exit(1)
+	}
+}
+
+// DBMaintenanceCommand checks and repairs the database
+type DBMaintenanceCommand struct {
+	db            *gorm.DB
+	encryptionSvc encryption.Service
+	cacheStore    store.Store
+}
+
+// NewDBMaintenanceCommand creates a new maintenance command
+func NewDBMaintenanceCommand(db *gorm.DB, encryptionSvc encryption.Service, cacheStore store.Store) *DBMaintenanceCommand {
+	return &DBMaintenanceCommand{
+		db:            db,
+		encryptionSvc: encryptionSvc,
+		cacheStore:    cacheStore,
+	}
+}
+
+func (cmd *DBMaintenanceCommand) newReport(command string) *MaintenanceReport {
+	return &MaintenanceReport{
+		Command:   command,
+		Database:  cmd.db.Dialector.Name(),
+		StartedAt: time.Now(),
+		Healthy:   true,
+	}
+}
+
+// Check runs all integrity checks without changing any data.
+func (cmd *DBMaintenanceCommand) Check() (*MaintenanceReport, error) {
+	report := cmd.newReport("check")
+	checks, _, err := cmd.runChecks(false)
+	if err != nil {
+		return nil, err
+	}
+	keyChecks, _, err := cmd.inspectKeys(false)
+	if err != nil {
+		return nil, err
+	}
+	rotationChecks, err := cmd.inspectEncryptedTables()
+	if err != nil {
+		return nil, err
+	}
+	report.Checks = append(append(checks, keyChecks...), rotationChecks...)
+	for _, check := range report.Checks {
+		if check.Count > 0 {
+			report.Healthy = false
+		}
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Repair fixes what the integrity checks find: orphaned rows are deleted, plaintext keys are
+// re-encrypted, stale key hashes are recomputed and duplicate keys are removed.
+// Keys that cannot be decrypted with the current ENCRYPTION_KEY are only reported.
+func (cmd *DBMaintenanceCommand) Repair(dryRun bool) (*MaintenanceReport, error) {
+	report := cmd.newReport("repair")
+	report.DryRun = dryRun
+
+	// Keys are fixed first so that duplicates are detected on correct hashes.
+	keyChecks, keysChanged, err := cmd.inspectKeys(!dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	checks, keysDeleted, err := cmd.runChecks(!dryRun)
+	if err != nil {
+		return nil, err
+	}
+	report.Checks = append(checks, keyChecks...)
+	keysChanged = keysChanged || keysDeleted
+
+	for _, check := range report.Checks {
+		if check.Count > check.Repaired {
+			report.Healthy = false
+		}
+	}
+
+	if keysChanged && cmd.cacheStore != nil {
+		logrus.Info("Key data changed, clearing cache...")
+		if err := cmd.cacheStore.Clear(); err != nil {
+			logrus.Warnf("Cache cleanup failed, recommend manual service restart: %v", err)
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// runChecks returns the result of the database and row-level integrity checks. With fix set, the
+// rows found by each check are deleted before the next check runs, so counts never overlap.
+func (cmd *DBMaintenanceCommand) runChecks(fix bool) ([]IntegrityCheck, bool, error) {
+	var checks []IntegrityCheck
+	keysDeleted := false
+
+	if cmd.db.Dialector.Name() == "sqlite" {
+		var result string
+		if err := cmd.db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+			return nil, false, fmt.Errorf("sqlite integrity check failed: %w", err)
+		}
+		check := IntegrityCheck{Name: "sqlite_integrity", Table: "*", Description: "SQLite page and index consistency"}
+		if result != "ok" {
+			check.Count = 1
+			check.Detail = result
+		}
+		checks = append(checks, check)
+	}
+
+	for _, name := range []string{"orphaned_api_keys", "orphaned_model_capabilities", "orphaned_sub_group_links", "duplicate_keys"} {
+		check, err := cmd.countRows(name)
+		if err != nil {
+			return nil, false, err
+		}
+		if check == nil {
+			continue
+		}
+		if fix && check.Count > 0 {
+			check.Repaired, err = cmd.deleteRows(name)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to repair %s: %w", name, err)
+			}
+			keysDeleted = keysDeleted || (check.Table == "api_keys" && check.Repaired > 0)
+		}
+		checks = append(checks, *check)
+	}
+	return checks, keysDeleted, nil
+}
+
+// rowQuery returns the query selecting the rows a row-level check reports, or nil if its table does not exist.
+func (cmd *DBMaintenanceCommand) rowQuery(name string) (*gorm.DB, IntegrityCheck) {
+	groupIDs := cmd.db.Model(&models.Group{}).Select("id")
+	switch name {
+	case "orphaned_api_keys":
+		return cmd.db.Model(&models.APIKey{}).Where("group_id NOT IN (?)", groupIDs),
+			IntegrityCheck{Name: name, Table: "api_keys", Description: "Keys whose group no longer exists"}
+	case "orphaned_model_capabilities":
+		if !cmd.db.Migrator().HasTable(&models.ModelCapabilities{}) {
+			return nil, IntegrityCheck{}
+		}
+		return cmd.db.Model(&models.ModelCapabilities{}).Where("group_id NOT IN (?)", groupIDs),
+			IntegrityCheck{Name: name, Table: "model_capabilities", Description: "Model capabilities whose group no longer exists"}
+	case "orphaned_sub_group_links":
+		return cmd.db.Model(&models.GroupSubGroup{}).Where("group_id NOT IN (?) OR sub_group_id NOT IN (?)", groupIDs, groupIDs),
+			IntegrityCheck{Name: name, Table: "group_sub_groups", Description: "Aggregate group links to a missing group"}
+	case "duplicate_keys":
+		// Every copy except the oldest of a key within the same group.
+		firstIDs := cmd.db.Model(&models.APIKey{}).Select("MIN(id)").Group("group_id, key_hash")
+		return cmd.db.Model(&models.APIKey{}).Where("key_hash <> '' AND id NOT IN (?)", firstIDs),
+			IntegrityCheck{Name: name, Table: "api_keys", Description: "Keys stored more than once in the same group"}
+	}
+	return nil, IntegrityCheck{}
+}
+
+func (cmd *DBMaintenanceCommand) countRows(name string) (*IntegrityCheck, error) {
+	query, check := cmd.rowQuery(name)
+	if query == nil {
+		return nil, nil
+	}
+	if err := query.Session(&gorm.Session{}).Count(&check.Count).Error; err != nil {
+		return nil, fmt.Errorf("check %s failed: %w", name, err)
+	}
+	if check.Count > 0 {
+		if err := query.Session(&gorm.Session{}).Order("id").Limit(maxReportSampleIDs).Pluck("id", &check.SampleIDs).Error; err != nil {
+			return nil, fmt.Errorf("check %s failed: %w", name, err)
+		}
+	}
+	return &check, nil
+}
+
+func (cmd *DBMaintenanceCommand) deleteRows(name string) (int64, error) {
+	query, _ := cmd.rowQuery(name)
+	if query == nil {
+		return 0, nil
+	}
+	var ids []uint
+	if err := query.Session(&gorm.Session{}).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for start := 0; start < len(ids); start += migrationBatchSize {
+		end := min(start+migrationBatchSize, len(ids))
+		result := cmd.db.Where("id IN ?", ids[start:end]).Delete(query.Statement.Model)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	logrus.Infof("Deleted %d rows for %s", deleted, name)
+	return deleted, nil
+}
+
+// inspectKeys verifies that every key decrypts with the configured encryption keys and matches its hash.
+// With fix set, plaintext keys and keys on a previous key version are encrypted with the current key
+// and stale hashes are recomputed.
+func (cmd *DBMaintenanceCommand) inspectKeys(fix bool) ([]IntegrityCheck, bool, error) {
+	undecryptable := IntegrityCheck{Name: "undecryptable_keys", Table: "api_keys", Description: "Keys that cannot be decrypted with ENCRYPTION_KEY or ENCRYPTION_PREVIOUS_KEYS"}
+	mismatched := IntegrityCheck{Name: "key_hash_mismatch", Table: "api_keys", Description: "Keys whose key_hash does not match the key"}
+	pending := IntegrityCheck{Name: "pending_key_rotation", Table: "api_keys", Description: "Keys still encrypted with a previous ENCRYPTION_KEY_VERSION"}
+	noop, _ := encryption.NewService("")
+	encrypted := cmd.encryptionSvc.Hash("probe") != noop.Hash("probe")
+	changed := false
+
+	lastID := uint(0)
+	for {
+		var keys []models.APIKey
+		if err := cmd.db.Select("id, key_value, key_hash").Where("id > ?", lastID).Order("id").Limit(migrationBatchSize).Find(&keys).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to get key data: %w", err)
+		}
+		if len(keys) == 0 {
+			break
+		}
+		lastID = keys[len(keys)-1].ID
+
+		for _, key := range keys {
+			plaintext, err := cmd.encryptionSvc.Decrypt(key.KeyValue)
+			recoverable := true
+			rotate := err == nil && cmd.encryptionSvc.NeedsRotation(key.KeyValue)
+			switch {
+			case err != nil && encrypted && !encryption.LooksEncrypted(key.KeyValue):
+				// A plaintext key written while encryption was disabled.
+				plaintext = key.KeyValue
+			case err != nil, !encrypted && encryption.LooksEncrypted(key.KeyValue) && noop.Hash(key.KeyValue) != key.KeyHash:
+				// Encrypted with another key, or ENCRYPTION_KEY is missing: only migrate-keys can fix it.
+				recoverable = false
+			case rotate:
+				// Readable with a previous key version; the server migrates these in the background.
+			case cmd.encryptionSvc.Hash(plaintext) == key.KeyHash:
+				continue
+			}
+
+			check := &mismatched
+			switch {
+			case err != nil || !recoverable:
+				check = &undecryptable
+			case rotate:
+				check = &pending
+			}
+			check.Count++
+			if len(check.SampleIDs) < maxReportSampleIDs {
+				check.SampleIDs = append(check.SampleIDs, key.ID)
+			}
+			if !fix || !recoverable {
+				continue
+			}
+
+			updates := map[string]any{"key_hash": cmd.encryptionSvc.Hash(plaintext)}
+			if err != nil || rotate {
+				ciphertext, encErr := cmd.encryptionSvc.Encrypt(plaintext)
+				if encErr != nil {
+					return nil, false, fmt.Errorf("key ID %d encryption failed: %w", key.ID, encErr)
+				}
+				updates["key_value"] = ciphertext
+			}
+			if err := cmd.db.Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(updates).Error; err != nil {
+				return nil, false, fmt.Errorf("failed to repair key ID %d: %w", key.ID, err)
+			}
+			check.Repaired++
+			changed = true
+		}
+	}
+
+	if undecryptable.Count > undecryptable.Repaired {
+		undecryptable.Detail = "use 'gpt-load migrate-keys' with the key the data was encrypted with, or delete these keys"
+	}
+	return []IntegrityCheck{undecryptable, mismatched, pending}, changed, nil
+}
+
+// inspectEncryptedTables counts the rows of the other encrypted tables that still hold values
+// encrypted with a previous key version. The server migrates them in the background.
+func (cmd *DBMaintenanceCommand) inspectEncryptedTables() ([]IntegrityCheck, error) {
+	var checks []IntegrityCheck
+	for _, table := range services.EncryptedTables {
+		pending := IntegrityCheck{Name: "pending_key_rotation", Table: table.Table, Description: "Rows with values still encrypted with a previous ENCRYPTION_KEY_VERSION"}
+		err := table.EachBatch(cmd.db, migrationBatchSize, func(rows []services.EncryptedRow) error {
+			for _, row := range rows {
+				if !slices.ContainsFunc(row.Values, cmd.pendingRotation) {
+					continue
+				}
+				pending.Count++
+				if len(pending.SampleIDs) < maxReportSampleIDs {
+					pending.SampleIDs = append(pending.SampleIDs, row.ID)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", table.Table, err)
+		}
+		checks = append(checks, pending)
+	}
+	return checks, nil
+}
+
+// pendingRotation reports whether a value is readable with a previous key version only.
+func (cmd *DBMaintenanceCommand) pendingRotation(value string) bool {
+	if value == "" || !cmd.encryptionSvc.NeedsRotation(value) {
+		return false
+	}
+	_, err := cmd.encryptionSvc.Decrypt(value)
+	return err == nil
+}
+
+// Reindex rebuilds the indexes of all application tables.
+func (cmd *DBMaintenanceCommand) Reindex() (*MaintenanceReport, error) {
+	report := cmd.newReport("reindex")
+	var statements []string
+	switch report.Database {
+	case "sqlite":
+		statements = []string{"REINDEX"}
+	case "postgres":
+		for _, table := range cmd.tables() {
+			statements = append(statements, fmt.Sprintf("REINDEX TABLE %q", table))
+		}
+	case "mysql":
+		for _, table := range cmd.tables() {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE `%s` FORCE", table))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", report.Database)
+	}
+	cmd.execStatements(report, statements)
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Optimize reclaims free space and refreshes query planner statistics.
+func (cmd *DBMaintenanceCommand) Optimize() (*MaintenanceReport, error) {
+	report := cmd.newReport("optimize")
+	var statements []string
+	switch report.Database {
+	case "sqlite":
+		statements = []string{"VACUUM", "ANALYZE", "PRAGMA optimize"}
+		report.SizeBefore = cmd.sqliteSize()
+	case "postgres":
+		for _, table := range cmd.tables() {
+			statements = append(statements, fmt.Sprintf("VACUUM ANALYZE %q", table))
+		}
+	case "mysql":
+		for _, table := range cmd.tables() {
+			statements = append(statements, fmt.Sprintf("OPTIMIZE TABLE `%s`", table))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", report.Database)
+	}
+	cmd.execStatements(report, statements)
+	if report.Database == "sqlite" {
+		report.SizeAfter = cmd.sqliteSize()
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// tables returns the names of the application tables that exist in the database.
+func (cmd *DBMaintenanceCommand) tables() []string {
+	var tables []string
+	for _, model := range maintenanceModels {
+		if !cmd.db.Migrator().HasTable(model) {
+			continue
+		}
+		stmt := &gorm.Statement{DB: cmd.db}
+		if err := stmt.Parse(model); err == nil {
+			tables = append(tables, stmt.Schema.Table)
+		}
+	}
+	return tables
+}
+
+func (cmd *DBMaintenanceCommand) execStatements(report *MaintenanceReport, statements []string) {
+	for _, statement := range statements {
+		logrus.Infof("Executing %s...", statement)
+		start := time.Now()
+		action := MaintenanceAction{Statement: statement}
+		if err := cmd.db.Exec(statement).Error; err != nil {
+			action.Error = err.Error()
+			report.Healthy = false
+			logrus.Errorf("%s failed: %v", statement, err)
+		}
+		action.DurationMs = time.Since(start).Milliseconds()
+		report.Actions = append(report.Actions, action)
+	}
+}
+
+// sqliteSize returns the size of the SQLite database file in bytes.
+func (cmd *DBMaintenanceCommand) sqliteSize() int64 {
+	var pageCount, pageSize int64
+	cmd.db.Raw("PRAGMA page_count").Scan(&pageCount)
+	cmd.db.Raw("PRAGMA page_size").Scan(&pageSize)
+	return pageCount * pageSize
+}
+
+// printMaintenanceReport prints a human readable summary of report.
+func printMaintenanceReport(report *MaintenanceReport) {
+	fmt.Println()
+	title := fmt.Sprintf("Database %s report (%s)", report.Command, report.Database)
+	if report.DryRun {
+		title += " - dry run"
+	}
+	fmt.Println(title)
+	fmt.Println(strings.Repeat("=", len(title)))
+
+	for _, check := range report.Checks {
+		status := "OK   "
+		if check.Count > check.Repaired {
+			status = "FAIL "
+		} else if check.Count > 0 {
+			status = "FIXED"
+		}
+		fmt.Printf("[%s] %-28s %-20s %d found", status, check.Name, check.Table, check.Count)
+		if check.Repaired > 0 {
+			fmt.Printf(", %d repaired", check.Repaired)
+		}
+		fmt.Println()
+		if check.Count > 0 {
+			fmt.Printf("        %s\n", check.Description)
+			if len(check.SampleIDs) > 0 {
+				fmt.Printf("        IDs: %v\n", check.SampleIDs)
+			}
+		}
+		if check.Detail != "" {
+			fmt.Printf("        %s\n", check.Detail)
+		}
+	}
+
+	for _, action := range report.Actions {
+		if action.Error != "" {
+			fmt.Printf("[FAIL ] %s: %s\n", action.Statement, action.Error)
+		} else {
+			fmt.Printf("[OK   ] %s (%d ms)\n", action.Statement, action.DurationMs)
+		}
+	}
+	if report.SizeBefore > 0 {
+		fmt.Printf("Database size: %d -> %d bytes\n", report.SizeBefore, report.SizeAfter)
+	}
+
+	fmt.Println()
+	if report.Healthy {
+		fmt.Println("Result: healthy")
+	} else {
+		fmt.Println("Result: problems found")
+	}
+}
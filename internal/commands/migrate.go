@@ -39,6 +39,7 @@ func RunMigrateKeys(args []string) {
 		fmt.Println("  1. Always backup database before migration")
 		fmt.Println("  2. Stop service during migration")
 		fmt.Println("  3. Restart service after migration completes")
+		fmt.Println("  4. To change the key without downtime, use ENCRYPTION_KEY_VERSION and ENCRYPTION_PREVIOUS_KEYS instead")
 	}
 
 	// Parse parameters
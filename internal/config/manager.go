@@ -2,10 +2,13 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/errors"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
@@ -47,9 +50,18 @@ type Config struct {
 	CORS          types.CORSConfig
 	Performance   types.PerformanceConfig
 	Log           types.LogConfig
+	AccessLog     types.AccessLogConfig
 	Database      types.DatabaseConfig
 	RedisDSN      string
 	EncryptionKey string
+	// EncryptionKeyVersion and PreviousEncryptionKeys allow rotating ENCRYPTION_KEY while rows are migrated in the background
+	EncryptionKeyVersion   int
+	PreviousEncryptionKeys map[int]string
+	// EncryptionKMSProvider is set when EncryptionKey is a data key unwrapped by a KMS
+	EncryptionKMSProvider string
+	HookScriptDir         string
+	ConfigFilePath        string
+	Plugins               []string
 }
 
 // NewManager creates a new configuration manager
@@ -78,6 +90,9 @@ func (m *Manager) ReloadConfig() error {
 			WriteTimeout:            utils.ParseInteger(os.Getenv("SERVER_WRITE_TIMEOUT"), 600),
 			IdleTimeout:             utils.ParseInteger(os.Getenv("SERVER_IDLE_TIMEOUT"), 120),
 			GracefulShutdownTimeout: utils.ParseInteger(os.Getenv("SERVER_GRACEFUL_SHUTDOWN_TIMEOUT"), 10),
+			DrainTimeout:            utils.ParseInteger(os.Getenv("SERVER_DRAIN_TIMEOUT"), 0),
+			EnablePprof:             utils.ParseBoolean(os.Getenv("ENABLE_PPROF"), false),
+			TrustedProxies:          utils.ParseArray(os.Getenv("TRUSTED_PROXIES"), []string{}),
 		},
 		Auth: types.AuthConfig{
 			Key: os.Getenv("AUTH_KEY"),
@@ -85,7 +100,7 @@ func (m *Manager) ReloadConfig() error {
 		CORS: types.CORSConfig{
 			Enabled:          utils.ParseBoolean(os.Getenv("ENABLE_CORS"), false),
 			AllowedOrigins:   utils.ParseArray(os.Getenv("ALLOWED_ORIGINS"), []string{}),
-			AllowedMethods:   utils.ParseArray(os.Getenv("ALLOWED_METHODS"), []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			AllowedMethods:   utils.ParseArray(os.Getenv("ALLOWED_METHODS"), []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
 			AllowedHeaders:   utils.ParseArray(os.Getenv("ALLOWED_HEADERS"), []string{"*"}),
 			AllowCredentials: utils.ParseBoolean(os.Getenv("ALLOW_CREDENTIALS"), false),
 		},
@@ -98,11 +113,33 @@ func (m *Manager) ReloadConfig() error {
 			EnableFile: utils.ParseBoolean(os.Getenv("LOG_ENABLE_FILE"), false),
 			FilePath:   utils.GetEnvOrDefault("LOG_FILE_PATH", "./data/logs/app.log"),
 		},
+		AccessLog: types.AccessLogConfig{
+			Sinks:          utils.ParseArray(os.Getenv("ACCESS_LOG_SINKS"), []string{}),
+			Fields:         utils.ParseArray(os.Getenv("ACCESS_LOG_FIELDS"), []string{}),
+			FilePath:       utils.GetEnvOrDefault("ACCESS_LOG_FILE_PATH", "./data/logs/access.log"),
+			FileMaxSizeMB:  utils.ParseInteger(os.Getenv("ACCESS_LOG_FILE_MAX_SIZE_MB"), 100),
+			FileMaxBackups: utils.ParseInteger(os.Getenv("ACCESS_LOG_FILE_MAX_BACKUPS"), 5),
+			SyslogNetwork:  os.Getenv("ACCESS_LOG_SYSLOG_NETWORK"),
+			SyslogAddress:  os.Getenv("ACCESS_LOG_SYSLOG_ADDRESS"),
+			SyslogTag:      utils.GetEnvOrDefault("ACCESS_LOG_SYSLOG_TAG", "gpt-load"),
+		},
 		Database: types.DatabaseConfig{
 			DSN: utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
 		},
-		RedisDSN:      os.Getenv("REDIS_DSN"),
-		EncryptionKey: os.Getenv("ENCRYPTION_KEY"),
+		RedisDSN:             os.Getenv("REDIS_DSN"),
+		EncryptionKey:        os.Getenv("ENCRYPTION_KEY"),
+		EncryptionKeyVersion: utils.ParseInteger(os.Getenv("ENCRYPTION_KEY_VERSION"), 1),
+		HookScriptDir:        utils.GetEnvOrDefault("HOOK_SCRIPT_DIR", "./data/hooks"),
+		ConfigFilePath:       os.Getenv("CONFIG_FILE"),
+		Plugins:              utils.ParseArray(os.Getenv("PLUGINS"), []string{}),
+	}
+	previousKeys, err := parsePreviousEncryptionKeys(os.Getenv("ENCRYPTION_PREVIOUS_KEYS"))
+	if err != nil {
+		return errors.NewAPIError(errors.ErrValidation, err.Error())
+	}
+	config.PreviousEncryptionKeys = previousKeys
+	if err := resolveKMSDataKey(config); err != nil {
+		return err
 	}
 	m.config = config
 
@@ -144,6 +181,26 @@ func (m *Manager) GetRedisDSN() string {
 	return m.config.RedisDSN
 }
 
+// GetHookScriptDir returns the directory that group hook scripts are run from.
+func (m *Manager) GetHookScriptDir() string {
+	return m.config.HookScriptDir
+}
+
+// GetConfigFilePath returns the declarative config file that groups are reconciled with, if any.
+func (m *Manager) GetConfigFilePath() string {
+	return m.config.ConfigFilePath
+}
+
+// GetPlugins returns the names of the enabled proxy plugins, in the order their hooks run.
+func (m *Manager) GetPlugins() []string {
+	return m.config.Plugins
+}
+
+// GetAccessLogConfig returns access log configuration
+func (m *Manager) GetAccessLogConfig() types.AccessLogConfig {
+	return m.config.AccessLog
+}
+
 // GetDatabaseConfig returns the database configuration.
 func (m *Manager) GetDatabaseConfig() types.DatabaseConfig {
 	return m.config.Database
@@ -154,6 +211,73 @@ func (m *Manager) GetEncryptionKey() string {
 	return m.config.EncryptionKey
 }
 
+// GetEncryptionConfig returns the current encryption key with its version and the previous keys.
+func (m *Manager) GetEncryptionConfig() types.EncryptionConfig {
+	return types.EncryptionConfig{
+		Key:          m.config.EncryptionKey,
+		KeyVersion:   m.config.EncryptionKeyVersion,
+		PreviousKeys: m.config.PreviousEncryptionKeys,
+		KMSProvider:  m.config.EncryptionKMSProvider,
+	}
+}
+
+// LoadKMSConfig reads the KMS that wraps the data encryption key from the environment.
+func LoadKMSConfig() types.KMSConfig {
+	return types.KMSConfig{
+		Provider:   strings.ToLower(strings.TrimSpace(os.Getenv("ENCRYPTION_KMS_PROVIDER"))),
+		KeyID:      strings.TrimSpace(os.Getenv("ENCRYPTION_KMS_KEY_ID")),
+		WrappedKey: strings.TrimSpace(os.Getenv("ENCRYPTION_WRAPPED_KEY")),
+	}
+}
+
+// resolveKMSDataKey unwraps the data key when a KMS is configured and uses it as the encryption key.
+// ENCRYPTION_KEY, when also set, is a copy of the data key that is used if the KMS cannot be reached;
+// it is checked against the unwrapped key on every start so that it cannot drift unnoticed. Such a
+// copy keeps the plaintext data key in the environment, which envelope encryption otherwise avoids.
+func resolveKMSDataKey(config *Config) error {
+	kms := LoadKMSConfig()
+	if kms.Provider == "" {
+		return nil
+	}
+
+	dataKey, err := encryption.UnwrapDataKey(context.Background(), kms)
+	if err != nil {
+		if config.EncryptionKey == "" {
+			return errors.NewAPIError(errors.ErrValidation, err.Error())
+		}
+		logrus.WithError(err).Error("KMS unavailable, falling back to ENCRYPTION_KEY as the data key")
+		return nil
+	}
+	if config.EncryptionKey != "" && config.EncryptionKey != dataKey {
+		return errors.NewAPIError(errors.ErrValidation, "ENCRYPTION_KEY does not match the data key unwrapped by the KMS, remove it or set it to the same key")
+	}
+	config.EncryptionKey = dataKey
+	config.EncryptionKMSProvider = kms.Provider
+	return nil
+}
+
+// parsePreviousEncryptionKeys parses ENCRYPTION_PREVIOUS_KEYS, a comma-separated list of version:key pairs.
+func parsePreviousEncryptionKeys(value string) (map[int]string, error) {
+	keys := make(map[int]string)
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		versionStr, key, found := strings.Cut(entry, ":")
+		version, err := strconv.Atoi(strings.TrimSpace(versionStr))
+		if !found || err != nil || version < 1 || key == "" {
+			// Never echo the entry, it contains key material
+			return nil, fmt.Errorf("invalid ENCRYPTION_PREVIOUS_KEYS entry #%d, expected version:key", i+1)
+		}
+		if _, exists := keys[version]; exists {
+			return nil, fmt.Errorf("duplicate version %d in ENCRYPTION_PREVIOUS_KEYS", version)
+		}
+		keys[version] = key
+	}
+	return keys, nil
+}
+
 // GetEffectiveServerConfig returns server configuration merged with system settings
 func (m *Manager) GetEffectiveServerConfig() types.ServerConfig {
 	return m.config.Server
@@ -179,11 +303,28 @@ func (m *Manager) Validate() error {
 		utils.ValidatePasswordStrength(m.config.Auth.Key, "AUTH_KEY")
 	}
 
+	// Validate encryption key versions
+	if m.config.EncryptionKeyVersion < 1 {
+		validationErrors = append(validationErrors, "ENCRYPTION_KEY_VERSION must be at least 1")
+	}
+	if len(m.config.PreviousEncryptionKeys) > 0 {
+		if m.config.EncryptionKey == "" {
+			validationErrors = append(validationErrors, "ENCRYPTION_PREVIOUS_KEYS requires ENCRYPTION_KEY to be set")
+		}
+		if _, exists := m.config.PreviousEncryptionKeys[m.config.EncryptionKeyVersion]; exists {
+			validationErrors = append(validationErrors, "ENCRYPTION_PREVIOUS_KEYS must not contain the current ENCRYPTION_KEY_VERSION")
+		}
+	}
+
 	// Validate GracefulShutdownTimeout and reset if necessary
 	if m.config.Server.GracefulShutdownTimeout < 10 {
 		logrus.Warnf("SERVER_GRACEFUL_SHUTDOWN_TIMEOUT value %ds is too short, resetting to minimum 10s.", m.config.Server.GracefulShutdownTimeout)
 		m.config.Server.GracefulShutdownTimeout = 10
 	}
+	if m.config.Server.DrainTimeout < 0 {
+		logrus.Warnf("SERVER_DRAIN_TIMEOUT value %ds is negative, resetting to 0.", m.config.Server.DrainTimeout)
+		m.config.Server.DrainTimeout = 0
+	}
 
 	if m.config.CORS.Enabled {
 		if len(m.config.CORS.AllowedOrigins) == 0 {
@@ -219,9 +360,20 @@ func (m *Manager) DisplayServerConfig() {
 	logrus.Info("  --- Server ---")
 	logrus.Infof("    Listen Address: %s:%d", serverConfig.Host, serverConfig.Port)
 	logrus.Infof("    Graceful Shutdown Timeout: %d seconds", serverConfig.GracefulShutdownTimeout)
+	if serverConfig.DrainTimeout > 0 {
+		logrus.Infof("    Drain Timeout: %d seconds", serverConfig.DrainTimeout)
+	}
 	logrus.Infof("    Read Timeout: %d seconds", serverConfig.ReadTimeout)
 	logrus.Infof("    Write Timeout: %d seconds", serverConfig.WriteTimeout)
 	logrus.Infof("    Idle Timeout: %d seconds", serverConfig.IdleTimeout)
+	if serverConfig.EnablePprof {
+		logrus.Info("    pprof: enabled on /debug/pprof")
+	}
+	if len(serverConfig.TrustedProxies) > 0 {
+		logrus.Infof("    Trusted Proxies: %s", strings.Join(serverConfig.TrustedProxies, ", "))
+	} else {
+		logrus.Info("    Trusted Proxies: none, forwarding headers are ignored")
+	}
 
 	logrus.Info("  --- Performance ---")
 	logrus.Infof("    Max Concurrent Requests: %d", perfConfig.MaxConcurrentRequests)
@@ -229,7 +381,14 @@ func (m *Manager) DisplayServerConfig() {
 	logrus.Info("  --- Security ---")
 	logrus.Infof("    Authentication: enabled (key loaded)")
 	if encryptionKey != "" {
-		logrus.Info("    Encryption: enabled")
+		if m.config.EncryptionKMSProvider != "" {
+			logrus.Infof("    Encryption: enabled (key version %d, data key unwrapped by the %s KMS)", m.config.EncryptionKeyVersion, m.config.EncryptionKMSProvider)
+		} else {
+			logrus.Infof("    Encryption: enabled (key version %d)", m.config.EncryptionKeyVersion)
+		}
+		if len(m.config.PreviousEncryptionKeys) > 0 {
+			logrus.Infof("    Previous Encryption Keys: %d (rows are migrated in the background)", len(m.config.PreviousEncryptionKeys))
+		}
 	} else {
 		logrus.Warn("    Encryption: disabled - WARNING: Sensitive data may be stored unencrypted, which poses security risks including potential key exposure")
 	}
@@ -246,6 +405,21 @@ func (m *Manager) DisplayServerConfig() {
 	if logConfig.EnableFile {
 		logrus.Infof("    Log File Path: %s", logConfig.FilePath)
 	}
+	if accessLogConfig := m.GetAccessLogConfig(); len(accessLogConfig.Sinks) > 0 {
+		logrus.Infof("    Access Log Sinks: %s", strings.Join(accessLogConfig.Sinks, ", "))
+	} else {
+		logrus.Info("    Access Log: disabled")
+	}
+
+	if m.config.ConfigFilePath != "" {
+		logrus.Info("  --- Declarative Config ---")
+		logrus.Infof("    Config File: %s (reloaded on SIGHUP)", m.config.ConfigFilePath)
+	}
+
+	if len(m.config.Plugins) > 0 {
+		logrus.Info("  --- Plugins ---")
+		logrus.Infof("    Enabled: %s", strings.Join(m.config.Plugins, ", "))
+	}
 
 	logrus.Info("  --- Dependencies ---")
 	if dbConfig.DSN != "" {
@@ -2,6 +2,7 @@
 package container
 
 import (
+	"gpt-load/internal/accesslog"
 	"gpt-load/internal/app"
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
@@ -10,6 +11,8 @@ import (
 	"gpt-load/internal/handler"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/plugin"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/router"
 	"gpt-load/internal/services"
@@ -28,16 +31,23 @@ func BuildContainer() (*dig.Container, error) {
 		return nil, err
 	}
 	if err := container.Provide(func(configManager types.ConfigManager) (encryption.Service, error) {
-		return encryption.NewService(configManager.GetEncryptionKey())
+		cfg := configManager.GetEncryptionConfig()
+		return encryption.NewVersionedService(cfg.Key, cfg.KeyVersion, cfg.PreviousKeys)
 	}); err != nil {
 		return nil, err
 	}
 	if err := container.Provide(db.NewDB); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(accesslog.NewLogger); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(config.NewSystemSettingsManager); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(notification.NewService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(store.NewStore); err != nil {
 		return nil, err
 	}
@@ -49,6 +59,12 @@ func BuildContainer() (*dig.Container, error) {
 	}
 
 	// Business Services
+	if err := container.Provide(services.NewJobService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewWebhookService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewTaskService); err != nil {
 		return nil, err
 	}
@@ -64,12 +80,45 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewKeyDeleteService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewKeyTopUpService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewKeyExpiryService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewEncryptionRotationService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewUsageRollupService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewModelRefreshService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewUpstreamHealthService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogCleanupService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewAlertService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewUserService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewOIDCService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewTeamService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewLogStreamService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewRequestLogService); err != nil {
 		return nil, err
 	}
@@ -79,9 +128,45 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewGroupManager); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewConfigVersionService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewAdvisorService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewPlaygroundConversationService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewStreamTranscriptService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewQuotaService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewKeyLimitService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewKeyHealthService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewDependencyCheckService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewRuntimeService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewGroupService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewSandboxService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewConfigBackupService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewDeclarativeConfigService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewAggregateGroupService); err != nil {
 		return nil, err
 	}
@@ -107,6 +192,9 @@ func BuildContainer() (*dig.Container, error) {
 	}
 
 	// Proxy & Router
+	if err := container.Provide(plugin.NewManager); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(proxy.NewProxyServer); err != nil {
 		return nil, err
 	}
@@ -78,5 +78,9 @@ func NewDB(configManager types.ConfigManager) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(500)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
+	if err := CheckCapabilities(DB); err != nil {
+		return nil, err
+	}
+
 	return DB, nil
 }
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Supported database dialects, as reported by gorm.Dialector.Name().
+const (
+	DialectSQLite   = "sqlite"
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+)
+
+// minimumVersions are the oldest server versions supported per dialect. Queries must stay portable
+// across all of them: no RANDOM()/RAND() or other dialect-specific functions, and window
+// functions, which the log export needs, are available from these versions on.
+var minimumVersions = map[string][2]int{
+	DialectSQLite:   {3, 25},
+	DialectMySQL:    {8, 0},
+	DialectPostgres: {10, 0},
+}
+
+// mariaDBMinimumVersion is the oldest supported MariaDB, which reports itself as MySQL.
+var mariaDBMinimumVersion = [2]int{10, 2}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// Dialect returns the name of the database dialect.
+func Dialect(db *gorm.DB) string {
+	return db.Dialector.Name()
+}
+
+// CheckCapabilities logs the database server and its version at startup. It fails for dialects
+// that are not supported and warns when the server is older than supported.
+func CheckCapabilities(db *gorm.DB) error {
+	dialect := Dialect(db)
+	minimum, ok := minimumVersions[dialect]
+	if !ok {
+		return fmt.Errorf("unsupported database dialect %q, use SQLite, MySQL or PostgreSQL", dialect)
+	}
+
+	serverVersion, err := queryServerVersion(db, dialect)
+	if err != nil {
+		logrus.Warnf("Failed to read the %s server version: %v", dialect, err)
+		return nil
+	}
+	if dialect == DialectMySQL && strings.Contains(strings.ToLower(serverVersion), "mariadb") {
+		minimum = mariaDBMinimumVersion
+	}
+	logrus.Infof("Database: %s %s", dialect, serverVersion)
+
+	major, minor, ok := parseVersion(serverVersion)
+	if ok && (major < minimum[0] || (major == minimum[0] && minor < minimum[1])) {
+		logrus.Warnf("Database %s %s is older than the supported %d.%d; some queries, such as the log key export, may fail",
+			dialect, serverVersion, minimum[0], minimum[1])
+	}
+	return nil
+}
+
+func queryServerVersion(db *gorm.DB, dialect string) (string, error) {
+	query := "SELECT version()"
+	if dialect == DialectSQLite {
+		query = "SELECT sqlite_version()"
+	}
+	var serverVersion string
+	if err := db.Raw(query).Scan(&serverVersion).Error; err != nil {
+		return "", err
+	}
+	return serverVersion, nil
+}
+
+// parseVersion extracts the major and minor version, e.g. from "8.0.36" or
+// "PostgreSQL 16.2 on x86_64-pc-linux-gnu".
+func parseVersion(serverVersion string) (int, int, bool) {
+	match := versionPattern.FindStringSubmatch(serverVersion)
+	if match == nil {
+		return 0, 0, false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return major, minor, true
+}
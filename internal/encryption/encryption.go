@@ -10,6 +10,9 @@ import (
 	"fmt"
 	"gpt-load/internal/utils"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // Service defines the encryption interface
@@ -17,17 +20,99 @@ type Service interface {
 	Encrypt(plaintext string) (string, error)
 	Decrypt(ciphertext string) (string, error)
 	Hash(plaintext string) string
+	// LookupHashes returns the hashes of plaintext under every readable key version, current first,
+	// so rows that have not been migrated to the current key yet can still be matched.
+	LookupHashes(plaintext string) []string
+	// CurrentVersion returns the key version new data is encrypted with, or 0 when encryption is disabled.
+	CurrentVersion() int
+	// NeedsRotation reports whether a stored value was encrypted with a key other than the current one.
+	NeedsRotation(ciphertext string) bool
 }
 
 // NewService creates encryption service
 func NewService(encryptionKey string) (Service, error) {
-	if encryptionKey == "" {
+	return NewVersionedService(encryptionKey, 1, nil)
+}
+
+// NewVersionedService creates an encryption service that encrypts with currentKey under currentVersion
+// and can still decrypt values written with any of previousKeys (keyed by version).
+func NewVersionedService(currentKey string, currentVersion int, previousKeys map[int]string) (Service, error) {
+	if currentKey == "" {
+		if len(previousKeys) > 0 {
+			return nil, fmt.Errorf("previous encryption keys require a current encryption key")
+		}
 		return &noopService{}, nil
 	}
+	if currentVersion < 1 {
+		return nil, fmt.Errorf("invalid encryption key version %d", currentVersion)
+	}
 
 	// Derive AES-256 key from user input and validate strength
+	utils.ValidatePasswordStrength(currentKey, "ENCRYPTION_KEY")
+	current, err := newVersionedKey(currentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &aesService{
+		current: currentVersion,
+		keys:    map[int]*versionedKey{currentVersion: current},
+		order:   []int{currentVersion},
+	}
+
+	previousVersions := make([]int, 0, len(previousKeys))
+	for version := range previousKeys {
+		previousVersions = append(previousVersions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(previousVersions)))
+
+	for _, version := range previousVersions {
+		if version < 1 || version == currentVersion {
+			return nil, fmt.Errorf("invalid previous encryption key version %d", version)
+		}
+		key, err := newVersionedKey(previousKeys[version])
+		if err != nil {
+			return nil, err
+		}
+		s.keys[version] = key
+		s.order = append(s.order, version)
+	}
+
+	return s, nil
+}
+
+// SplitVersion separates the key version from a stored ciphertext. Version 1 is stored without a
+// prefix, which is also the format of everything written before key versioning existed.
+func SplitVersion(ciphertext string) (int, string) {
+	if rest, ok := strings.CutPrefix(ciphertext, "v"); ok {
+		if digits, payload, found := strings.Cut(rest, ":"); found {
+			if version, err := strconv.Atoi(digits); err == nil && version > 0 {
+				return version, payload
+			}
+		}
+	}
+	return 1, ciphertext
+}
+
+// LooksEncrypted reports whether a stored value has the shape of AES-GCM ciphertext.
+func LooksEncrypted(value string) bool {
+	_, value = SplitVersion(value)
+	// 12-byte nonce + 16-byte tag, hex encoded
+	if len(value) < 56 || len(value)%2 != 0 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// versionedKey holds the derived key material of one key version.
+type versionedKey struct {
+	key []byte
+	gcm cipher.AEAD
+}
+
+func newVersionedKey(encryptionKey string) (*versionedKey, error) {
 	aesKey := utils.DeriveAESKey(encryptionKey)
-	utils.ValidatePasswordStrength(encryptionKey, "ENCRYPTION_KEY")
 
 	// Initialize cipher and GCM once for reuse
 	block, err := aes.NewCipher(aesKey)
@@ -40,38 +125,55 @@ func NewService(encryptionKey string) (Service, error) {
 		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
 
-	return &aesService{key: aesKey, gcm: gcm}, nil
+	return &versionedKey{key: aesKey, gcm: gcm}, nil
 }
 
-// aesService implements AES-256-GCM encryption
+func (k *versionedKey) hash(plaintext string) string {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte(plaintext))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// aesService implements AES-256-GCM encryption with versioned keys
 type aesService struct {
-	key []byte
-	gcm cipher.AEAD
+	current int
+	keys    map[int]*versionedKey
+	order   []int // current version first, then previous versions newest first
 }
 
 func (s *aesService) Encrypt(plaintext string) (string, error) {
-	nonce := make([]byte, s.gcm.NonceSize())
+	gcm := s.keys[s.current].gcm
+	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	ciphertext := s.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return hex.EncodeToString(ciphertext), nil
+	ciphertext := hex.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+	if s.current == 1 {
+		return ciphertext, nil
+	}
+	return fmt.Sprintf("v%d:%s", s.current, ciphertext), nil
 }
 
 func (s *aesService) Decrypt(ciphertext string) (string, error) {
-	data, err := hex.DecodeString(ciphertext)
+	version, payload := SplitVersion(ciphertext)
+	key, ok := s.keys[version]
+	if !ok {
+		return "", fmt.Errorf("no encryption key configured for version %d", version)
+	}
+
+	data, err := hex.DecodeString(payload)
 	if err != nil {
 		return "", fmt.Errorf("invalid hex data: %w", err)
 	}
 
-	nonceSize := s.gcm.NonceSize()
+	nonceSize := key.gcm.NonceSize()
 	if len(data) < nonceSize {
 		return "", fmt.Errorf("ciphertext too short")
 	}
 
 	nonce, encrypted := data[:nonceSize], data[nonceSize:]
-	plaintext, err := s.gcm.Open(nil, nonce, encrypted, nil)
+	plaintext, err := key.gcm.Open(nil, nonce, encrypted, nil)
 	if err != nil {
 		return "", fmt.Errorf("decryption failed: %w", err)
 	}
@@ -84,9 +186,27 @@ func (s *aesService) Hash(plaintext string) string {
 	if plaintext == "" {
 		return ""
 	}
-	mac := hmac.New(sha256.New, s.key)
-	mac.Write([]byte(plaintext))
-	return hex.EncodeToString(mac.Sum(nil))
+	return s.keys[s.current].hash(plaintext)
+}
+
+func (s *aesService) LookupHashes(plaintext string) []string {
+	if plaintext == "" {
+		return nil
+	}
+	hashes := make([]string, 0, len(s.order))
+	for _, version := range s.order {
+		hashes = append(hashes, s.keys[version].hash(plaintext))
+	}
+	return hashes
+}
+
+func (s *aesService) CurrentVersion() int {
+	return s.current
+}
+
+func (s *aesService) NeedsRotation(ciphertext string) bool {
+	version, _ := SplitVersion(ciphertext)
+	return version != s.current
 }
 
 // noopService disables encryption
@@ -108,3 +228,18 @@ func (s *noopService) Hash(plaintext string) string {
 	hash := sha256.Sum256([]byte(plaintext))
 	return hex.EncodeToString(hash[:])
 }
+
+func (s *noopService) LookupHashes(plaintext string) []string {
+	if plaintext == "" {
+		return nil
+	}
+	return []string{s.Hash(plaintext)}
+}
+
+func (s *noopService) CurrentVersion() int {
+	return 0
+}
+
+func (s *noopService) NeedsRotation(string) bool {
+	return false
+}
@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// KMS providers for ENCRYPTION_KMS_PROVIDER.
+const (
+	KMSProviderAWS   = "aws"
+	KMSProviderGCP   = "gcp"
+	KMSProviderVault = "vault"
+)
+
+const (
+	// kmsRequestTimeout bounds one call to a KMS.
+	kmsRequestTimeout = 10 * time.Second
+	// kmsUnwrapAttempts is how often unwrapping the data key is tried before giving up.
+	kmsUnwrapAttempts = 4
+)
+
+var kmsClient = &http.Client{Timeout: kmsRequestTimeout}
+
+// KeyWrapper encrypts and decrypts a data encryption key with a key held by a KMS.
+type KeyWrapper interface {
+	Wrap(ctx context.Context, plaintext []byte) (string, error)
+	Unwrap(ctx context.Context, wrapped string) ([]byte, error)
+}
+
+// NewKeyWrapper returns the wrapper of a KMS provider. Credentials are read from the provider's
+// usual environment variables.
+func NewKeyWrapper(cfg types.KMSConfig) (KeyWrapper, error) {
+	if cfg.KeyID == "" {
+		return nil, fmt.Errorf("ENCRYPTION_KMS_KEY_ID is required for the %s KMS provider", cfg.Provider)
+	}
+	switch cfg.Provider {
+	case KMSProviderAWS:
+		return newAWSKeyWrapper(cfg.KeyID)
+	case KMSProviderGCP:
+		return newGCPKeyWrapper(cfg.KeyID)
+	case KMSProviderVault:
+		return newVaultKeyWrapper(cfg.KeyID)
+	default:
+		return nil, fmt.Errorf("unknown KMS provider %q, expected %s, %s or %s", cfg.Provider, KMSProviderAWS, KMSProviderGCP, KMSProviderVault)
+	}
+}
+
+// UnwrapDataKey decrypts the wrapped data key of cfg, retrying with backoff while the KMS cannot be
+// reached.
+func UnwrapDataKey(ctx context.Context, cfg types.KMSConfig) (string, error) {
+	if cfg.WrappedKey == "" {
+		return "", fmt.Errorf("ENCRYPTION_WRAPPED_KEY is required for the %s KMS provider", cfg.Provider)
+	}
+	wrapper, err := NewKeyWrapper(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		plaintext, err := wrapper.Unwrap(ctx, cfg.WrappedKey)
+		if err == nil {
+			if len(plaintext) == 0 {
+				return "", fmt.Errorf("%s KMS returned an empty data key", cfg.Provider)
+			}
+			return string(plaintext), nil
+		}
+		if attempt == kmsUnwrapAttempts {
+			return "", fmt.Errorf("failed to unwrap the data key with the %s KMS: %w", cfg.Provider, err)
+		}
+		logrus.WithError(err).Warnf("Failed to unwrap the data key with the %s KMS, retrying in %s", cfg.Provider, backoff)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// GenerateDataKey returns a new random data key and its wrapped form.
+func GenerateDataKey(ctx context.Context, cfg types.KMSConfig) (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	key := base64.RawURLEncoding.EncodeToString(raw)
+	wrapped, err := WrapDataKey(ctx, cfg, key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, wrapped, nil
+}
+
+// WrapDataKey encrypts an existing key, such as a static ENCRYPTION_KEY, with the KMS of cfg.
+func WrapDataKey(ctx context.Context, cfg types.KMSConfig, key string) (string, error) {
+	wrapper, err := NewKeyWrapper(cfg)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := wrapper.Wrap(ctx, []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap the data key with the %s KMS: %w", cfg.Provider, err)
+	}
+	return wrapped, nil
+}
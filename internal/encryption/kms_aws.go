@@ -0,0 +1,348 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gpt-load/internal/utils"
+)
+
+const (
+	awsIMDSEndpoint = "http://169.254.169.254"
+	awsECSEndpoint  = "http://169.254.170.2"
+	// awsIMDSTimeout keeps startup quick outside EC2, where the metadata service does not answer.
+	awsIMDSTimeout = 2 * time.Second
+)
+
+// awsKeyWrapper calls the Encrypt and Decrypt actions of AWS KMS, signing requests with Signature
+// Version 4. It is configured by AWS_REGION (or AWS_DEFAULT_REGION) and, for KMS-compatible services,
+// AWS_ENDPOINT_URL_KMS; credentials are looked up as described at awsLoadCredentials.
+type awsKeyWrapper struct {
+	keyID    string
+	region   string
+	endpoint string
+}
+
+// awsCredentials are the keys requests are signed with.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+func newAWSKeyWrapper(keyID string) (*awsKeyWrapper, error) {
+	region := utils.GetEnvOrDefault("AWS_REGION", os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" {
+		return nil, fmt.Errorf("AWS_REGION is required for the aws KMS provider")
+	}
+	w := &awsKeyWrapper{
+		keyID:    keyID,
+		region:   region,
+		endpoint: utils.GetEnvOrDefault("AWS_ENDPOINT_URL_KMS", os.Getenv("AWS_ENDPOINT_URL")),
+	}
+	if w.endpoint == "" {
+		w.endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", region)
+	}
+	return w, nil
+}
+
+func (w *awsKeyWrapper) Wrap(ctx context.Context, plaintext []byte) (string, error) {
+	var resp struct {
+		CiphertextBlob string `json:"CiphertextBlob"`
+	}
+	body := map[string]string{"KeyId": w.keyID, "Plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := w.call(ctx, "Encrypt", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.CiphertextBlob, nil
+}
+
+func (w *awsKeyWrapper) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"Plaintext"`
+	}
+	body := map[string]string{"KeyId": w.keyID, "CiphertextBlob": wrapped}
+	if err := w.call(ctx, "Decrypt", body, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+func (w *awsKeyWrapper) call(ctx context.Context, action string, body any, out any) error {
+	creds, err := awsLoadCredentials(ctx, w.region)
+	if err != nil {
+		return fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	w.sign(req, payload, creds, time.Now().UTC())
+
+	resp, err := kmsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &awsErr) == nil && awsErr.Type != "" {
+			return fmt.Errorf("aws kms returned %d: %s %s", resp.StatusCode, awsErr.Type, awsErr.Message)
+		}
+		return fmt.Errorf("aws kms returned %d: %s", resp.StatusCode, utils.TruncateString(string(respBody), 200))
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// sign adds the Signature Version 4 headers to a KMS request.
+func (w *awsKeyWrapper) sign(req *http.Request, payload []byte, creds *awsCredentials, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         req.URL.Host,
+		"x-amz-date":   amzDate,
+		"x-amz-target": req.Header.Get("X-Amz-Target"),
+	}
+	names := []string{"content-type", "host", "x-amz-date"}
+	if creds.SessionToken != "" {
+		headers["x-amz-security-token"] = creds.SessionToken
+		names = append(names, "x-amz-security-token")
+	}
+	names = append(names, "x-amz-target")
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + w.region + "/kms/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, w.region)
+	signingKey = hmacSHA256(signingKey, "kms")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsLoadCredentials returns credentials from the first configured source, in the order of the AWS
+// SDKs: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (with AWS_SESSION_TOKEN), a web identity token
+// in AWS_WEB_IDENTITY_TOKEN_FILE exchanged for AWS_ROLE_ARN (EKS service accounts), the ECS
+// container credentials endpoint and the EC2 instance metadata service.
+func awsLoadCredentials(ctx context.Context, region string) (*awsCredentials, error) {
+	if accessKeyID, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); accessKeyID != "" && secretKey != "" {
+		return &awsCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretKey, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if tokenFile, roleARN := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"); tokenFile != "" && roleARN != "" {
+		return awsWebIdentityCredentials(ctx, region, tokenFile, roleARN)
+	}
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return awsContainerCredentials(ctx)
+	}
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return nil, fmt.Errorf("no credentials configured and the EC2 metadata service is disabled")
+	}
+	creds, err := awsInstanceCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no credentials configured and the EC2 metadata service is unavailable: %w", err)
+	}
+	return creds, nil
+}
+
+// awsWebIdentityCredentials exchanges a web identity token for temporary credentials of a role.
+func awsWebIdentityCredentials(ctx context.Context, region, tokenFile, roleARN string) (*awsCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := utils.GetEnvOrDefault("AWS_ENDPOINT_URL_STS", fmt.Sprintf("https://sts.%s.amazonaws.com", region))
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {utils.GetEnvOrDefault("AWS_ROLE_SESSION_NAME", "gpt-load")},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := doAWSCredentialsRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("sts AssumeRoleWithWebIdentity failed: %w", err)
+	}
+	var resp struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string `xml:"SecretAccessKey"`
+			SessionToken    string `xml:"SessionToken"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid sts response: %w", err)
+	}
+	return &awsCredentials{
+		AccessKeyID:     resp.Credentials.AccessKeyID,
+		SecretAccessKey: resp.Credentials.SecretAccessKey,
+		SessionToken:    resp.Credentials.SessionToken,
+	}, nil
+}
+
+// awsContainerCredentials reads the credentials of the ECS task role, or of another container
+// credentials provider given by AWS_CONTAINER_CREDENTIALS_FULL_URI.
+func awsContainerCredentials(ctx context.Context) (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		endpoint = awsECSEndpoint + relative
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	authorization := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if path := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		authorization = strings.TrimSpace(string(data))
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	body, err := doAWSCredentialsRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("container credentials endpoint failed: %w", err)
+	}
+	return parseAWSCredentialsJSON(body)
+}
+
+// awsInstanceCredentials reads the credentials of the EC2 instance profile with IMDSv2.
+func awsInstanceCredentials(ctx context.Context) (*awsCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, awsIMDSTimeout)
+	defer cancel()
+	endpoint := strings.TrimRight(utils.GetEnvOrDefault("AWS_EC2_METADATA_SERVICE_ENDPOINT", awsIMDSEndpoint), "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	token, err := doAWSCredentialsRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+		return doAWSCredentialsRequest(req)
+	}
+	roles, err := get("/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return nil, fmt.Errorf("no instance profile: %w", err)
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	if role == "" {
+		return nil, fmt.Errorf("no instance profile")
+	}
+	body, err := get("/latest/meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return nil, err
+	}
+	return parseAWSCredentialsJSON(body)
+}
+
+// parseAWSCredentialsJSON reads the credentials document of the ECS and EC2 metadata endpoints.
+func parseAWSCredentialsJSON(body []byte) (*awsCredentials, error) {
+	var resp struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid credentials response: %w", err)
+	}
+	if resp.AccessKeyID == "" || resp.SecretAccessKey == "" {
+		return nil, fmt.Errorf("credentials response has no keys")
+	}
+	return &awsCredentials{AccessKeyID: resp.AccessKeyID, SecretAccessKey: resp.SecretAccessKey, SessionToken: resp.Token}, nil
+}
+
+func doAWSCredentialsRequest(req *http.Request) ([]byte, error) {
+	resp, err := kmsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, utils.TruncateString(string(body), 200))
+	}
+	return body, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
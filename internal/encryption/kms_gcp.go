@@ -0,0 +1,197 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gpt-load/internal/utils"
+)
+
+const (
+	gcpKMSEndpoint        = "https://cloudkms.googleapis.com"
+	gcpMetadataTokenURL   = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpCloudKMSScope      = "https://www.googleapis.com/auth/cloudkms"
+	gcpDefaultTokenURL    = "https://oauth2.googleapis.com/token"
+	gcpJWTBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+// gcpKeyWrapper calls the encrypt and decrypt methods of a Cloud KMS CryptoKey. It authenticates with
+// GOOGLE_OAUTH_ACCESS_TOKEN, the service account key file in GOOGLE_APPLICATION_CREDENTIALS or, on
+// Google Cloud, the instance's service account.
+type gcpKeyWrapper struct {
+	keyName  string
+	endpoint string
+}
+
+func newGCPKeyWrapper(keyName string) (*gcpKeyWrapper, error) {
+	if !strings.HasPrefix(keyName, "projects/") {
+		return nil, fmt.Errorf("the gcp KMS key must be a resource name like projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>")
+	}
+	return &gcpKeyWrapper{
+		keyName:  keyName,
+		endpoint: strings.TrimRight(utils.GetEnvOrDefault("GCP_KMS_ENDPOINT", gcpKMSEndpoint), "/"),
+	}, nil
+}
+
+func (w *gcpKeyWrapper) Wrap(ctx context.Context, plaintext []byte) (string, error) {
+	var resp struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := w.call(ctx, "encrypt", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Ciphertext, nil
+}
+
+func (w *gcpKeyWrapper) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := w.call(ctx, "decrypt", map[string]string{"ciphertext": wrapped}, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+func (w *gcpKeyWrapper) call(ctx context.Context, method string, body any, out any) error {
+	token, err := gcpAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a Google access token: %w", err)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s:%s", w.endpoint, w.keyName, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return doGCPRequest(req, out)
+}
+
+// gcpAccessToken returns an OAuth access token from the first configured source.
+func gcpAccessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return gcpServiceAccountToken(ctx, path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doGCPRequest(req, &resp); err != nil {
+		return "", fmt.Errorf("no credentials configured and the metadata server is unavailable: %w", err)
+	}
+	return resp.AccessToken, nil
+}
+
+// gcpServiceAccountToken exchanges a JWT signed with a service account key for an access token.
+func gcpServiceAccountToken(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var account struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return "", fmt.Errorf("invalid service account key file: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = gcpDefaultTokenURL
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("service account key file has no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("invalid service account private key: %w", err)
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("service account private key is not an RSA key")
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   account.ClientEmail,
+		"scope": gcpCloudKMSScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	assertion := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	form := url.Values{"grant_type": {gcpJWTBearerGrantType}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doGCPRequest(req, &resp); err != nil {
+		return "", err
+	}
+	return resp.AccessToken, nil
+}
+
+func doGCPRequest(req *http.Request, out any) error {
+	resp, err := kmsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var gcpErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &gcpErr) == nil && gcpErr.Error.Message != "" {
+			return fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, gcpErr.Error.Message)
+		}
+		return fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, utils.TruncateString(string(respBody), 200))
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,106 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"gpt-load/internal/utils"
+)
+
+// vaultKeyWrapper uses the encrypt and decrypt endpoints of HashiCorp Vault's Transit secrets engine.
+// It is configured by VAULT_ADDR, VAULT_TOKEN, VAULT_NAMESPACE and VAULT_TRANSIT_MOUNT.
+type vaultKeyWrapper struct {
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	keyName   string
+}
+
+func newVaultKeyWrapper(keyName string) (*vaultKeyWrapper, error) {
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is required for the vault KMS provider")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN is required for the vault KMS provider")
+	}
+	return &vaultKeyWrapper{
+		addr:      addr,
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		mount:     strings.Trim(utils.GetEnvOrDefault("VAULT_TRANSIT_MOUNT", "transit"), "/"),
+		keyName:   keyName,
+	}, nil
+}
+
+func (w *vaultKeyWrapper) Wrap(ctx context.Context, plaintext []byte) (string, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := w.call(ctx, "encrypt", body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+func (w *vaultKeyWrapper) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := w.call(ctx, "decrypt", map[string]string{"ciphertext": wrapped}, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+func (w *vaultKeyWrapper) call(ctx context.Context, operation string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", w.addr, w.mount, operation, url.PathEscape(w.keyName))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", w.token)
+	if w.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", w.namespace)
+	}
+
+	resp, err := kmsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("vault returned %d: %s", resp.StatusCode, utils.TruncateString(string(respBody), 200))
+	}
+	return json.Unmarshal(respBody, out)
+}
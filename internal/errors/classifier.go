@@ -0,0 +1,115 @@
+package errors
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Upstream error categories.
+const (
+	// UpstreamErrorAuthInvalid means the key was rejected: revoked, mistyped or without access.
+	UpstreamErrorAuthInvalid = "auth_invalid"
+	// UpstreamErrorQuotaExhausted means the key's balance or billing quota is used up.
+	UpstreamErrorQuotaExhausted = "quota_exhausted"
+	// UpstreamErrorRateLimited means the key is sending too much for now.
+	UpstreamErrorRateLimited = "rate_limited"
+	// UpstreamErrorContentFiltered means the provider refused the content of the request.
+	UpstreamErrorContentFiltered = "content_filtered"
+	// UpstreamErrorBadRequest means the request itself is invalid and would fail with any key.
+	UpstreamErrorBadRequest = "bad_request"
+	// UpstreamErrorServerError means the provider failed or is overloaded.
+	UpstreamErrorServerError = "server_error"
+	// UpstreamErrorNetwork means the upstream could not be reached or the connection failed.
+	UpstreamErrorNetwork = "network"
+	// UpstreamErrorUnknown covers failures none of the rules recognize.
+	UpstreamErrorUnknown = "unknown"
+)
+
+// upstreamErrorRule maps the upstream errors of a channel type (or of every channel type when
+// channelType is empty) to a category. A rule matches when the status code is one of statusCodes
+// (any status when empty) and the lowercased body contains one of substrings (any body when empty).
+type upstreamErrorRule struct {
+	channelType string
+	statusCodes []int
+	substrings  []string
+	category    string
+}
+
+// upstreamErrorRules are checked in order; provider rules come first so that they can override the
+// generic status code rules, such as Gemini answering 400 for an invalid key.
+var upstreamErrorRules = []upstreamErrorRule{
+	// OpenAI and OpenAI-compatible providers
+	{statusCodes: []int{http.StatusTooManyRequests}, substrings: []string{"insufficient_quota", "exceeded your current quota", "billing"}, category: UpstreamErrorQuotaExhausted},
+	{statusCodes: []int{http.StatusBadRequest}, substrings: []string{"content_policy_violation", "content management policy", "content_filter"}, category: UpstreamErrorContentFiltered},
+
+	// Gemini
+	{channelType: "gemini", statusCodes: []int{http.StatusBadRequest, http.StatusForbidden}, substrings: []string{"api key not valid", "api_key_invalid", "api key expired", "permission_denied"}, category: UpstreamErrorAuthInvalid},
+	{channelType: "gemini", statusCodes: []int{http.StatusBadRequest}, substrings: []string{"safety", "prohibited_content", "blocked"}, category: UpstreamErrorContentFiltered},
+	{channelType: "gemini", statusCodes: []int{http.StatusTooManyRequests}, substrings: []string{"per day", "perday", "billing"}, category: UpstreamErrorQuotaExhausted},
+
+	// Anthropic
+	{channelType: "anthropic", statusCodes: []int{http.StatusBadRequest}, substrings: []string{"credit balance is too low"}, category: UpstreamErrorQuotaExhausted},
+	{channelType: "anthropic", statusCodes: []int{529}, category: UpstreamErrorServerError},
+
+	// Vendors that report an invalid key or an empty balance with other status codes
+	{substrings: []string{"invalid api key", "incorrect api key", "invalid_api_key", "api key is invalid", "invalid x-api-key"}, category: UpstreamErrorAuthInvalid},
+	{substrings: []string{"insufficient balance", "insufficient_balance", "account balance is insufficient", "arrearage", "余额不足", "欠费"}, category: UpstreamErrorQuotaExhausted},
+
+	// Generic status codes
+	{statusCodes: []int{http.StatusUnauthorized, http.StatusForbidden}, category: UpstreamErrorAuthInvalid},
+	{statusCodes: []int{http.StatusPaymentRequired}, category: UpstreamErrorQuotaExhausted},
+	{statusCodes: []int{http.StatusTooManyRequests}, category: UpstreamErrorRateLimited},
+	{statusCodes: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity}, category: UpstreamErrorBadRequest},
+}
+
+// ClassifyUpstreamError maps a failed upstream request of a channel type to an error category.
+// err is the transport error, if the request got no response; otherwise statusCode and body are
+// those of the response.
+func ClassifyUpstreamError(channelType string, statusCode int, err error, body []byte) string {
+	if err != nil {
+		return UpstreamErrorNetwork
+	}
+
+	bodyLower := strings.ToLower(string(body))
+	for _, rule := range upstreamErrorRules {
+		if rule.channelType != "" && rule.channelType != channelType {
+			continue
+		}
+		if len(rule.statusCodes) > 0 && !containsStatus(rule.statusCodes, statusCode) {
+			continue
+		}
+		if len(rule.substrings) > 0 && !containsAny(bodyLower, rule.substrings) {
+			continue
+		}
+		return rule.category
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		return UpstreamErrorServerError
+	}
+	return UpstreamErrorUnknown
+}
+
+// IsRetryableCategory reports whether a request that failed with the category may succeed when
+// repeated with another key. Bad requests fail the same way with any key.
+func IsRetryableCategory(category string) bool {
+	return category != UpstreamErrorBadRequest
+}
+
+func containsStatus(statusCodes []int, statusCode int) bool {
+	for _, code := range statusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
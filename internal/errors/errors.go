@@ -33,11 +33,29 @@ var (
 	ErrDatabase           = &APIError{HTTPStatus: http.StatusInternalServerError, Code: "DATABASE_ERROR", Message: "Database operation failed"}
 	ErrUnauthorized       = &APIError{HTTPStatus: http.StatusUnauthorized, Code: "UNAUTHORIZED", Message: "Authentication failed"}
 	ErrForbidden          = &APIError{HTTPStatus: http.StatusForbidden, Code: "FORBIDDEN", Message: "You do not have permission to access this resource"}
+	ErrTOTPRequired       = &APIError{HTTPStatus: http.StatusForbidden, Code: "TOTP_REQUIRED", Message: "A valid two-factor code is required"}
 	ErrTaskInProgress     = &APIError{HTTPStatus: http.StatusConflict, Code: "TASK_IN_PROGRESS", Message: "A task is already in progress"}
+	ErrModifiedConflict   = &APIError{HTTPStatus: http.StatusConflict, Code: "MODIFIED_CONFLICT", Message: "The resource was modified by another request"}
+	ErrPreconditionNeeded = &APIError{HTTPStatus: http.StatusPreconditionRequired, Code: "PRECONDITION_REQUIRED", Message: "The request must state the version it is based on"}
 	ErrBadGateway         = &APIError{HTTPStatus: http.StatusBadGateway, Code: "BAD_GATEWAY", Message: "Upstream service error"}
 	ErrNoActiveKeys       = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_ACTIVE_KEYS", Message: "No active API keys available for this group"}
 	ErrMaxRetriesExceeded = &APIError{HTTPStatus: http.StatusBadGateway, Code: "MAX_RETRIES_EXCEEDED", Message: "Request failed after maximum retries"}
 	ErrNoKeysAvailable    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_KEYS_AVAILABLE", Message: "No API keys available to process the request"}
+	ErrOutsideSchedule    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "OUTSIDE_TRAFFIC_SCHEDULE", Message: "The group is not accepting traffic at this time"}
+	ErrQueueFull          = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "QUEUE_FULL", Message: "Too many requests are waiting for this group"}
+	ErrQueueTimeout       = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "QUEUE_TIMEOUT", Message: "Timed out waiting for a free request slot in this group"}
+	ErrDuplicateRequest   = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "DUPLICATE_REQUEST_THROTTLED", Message: "Too many identical requests in a short time, please retry later"}
+	ErrGroupExpired       = &APIError{HTTPStatus: http.StatusGone, Code: "GROUP_EXPIRED", Message: "This sandbox group has expired"}
+	ErrContextWindow      = &APIError{HTTPStatus: http.StatusBadRequest, Code: "CONTEXT_WINDOW_EXCEEDED", Message: "The request does not fit the context window of the model"}
+	ErrQuotaPaced         = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "QUOTA_PACED", Message: "Request held back so that the provider quota lasts until it resets, please retry later"}
+	ErrKeyLimitReached    = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "KEY_LIMIT_REACHED", Message: "Every key tried has reached its rate or spend limit, please retry later"}
+	ErrKeysCoolingDown    = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "KEYS_COOLING_DOWN", Message: "Every key tried is cooling down after being rate limited upstream, please retry later"}
+	ErrServerDraining     = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "SERVER_DRAINING", Message: "The server is shutting down and no longer accepts new requests, please retry"}
+	ErrPIIDetected        = &APIError{HTTPStatus: http.StatusBadRequest, Code: "PII_DETECTED", Message: "The request contains personal data that this group does not allow"}
+	ErrContentModerated   = &APIError{HTTPStatus: http.StatusBadRequest, Code: "CONTENT_MODERATED", Message: "The request was blocked by content moderation"}
+	ErrIPNotAllowed       = &APIError{HTTPStatus: http.StatusForbidden, Code: "IP_NOT_ALLOWED", Message: "Requests from this address are not allowed"}
+	ErrPluginRejected     = &APIError{HTTPStatus: http.StatusForbidden, Code: "PLUGIN_REJECTED", Message: "The request was rejected by a plugin"}
+	ErrRequestTooLarge    = &APIError{HTTPStatus: http.StatusRequestEntityTooLarge, Code: "REQUEST_TOO_LARGE", Message: "The request body exceeds the size limit of this group"}
 )
 
 // NewAPIError creates a new APIError with a custom message.
@@ -0,0 +1,29 @@
+package errors
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// IsUpstreamUnreachableError reports whether err means the upstream server could not be reached
+// at all (connection refused, DNS failure, dial timeout). Such failures say nothing about the key
+// used for the request, so keys must not be penalized for them.
+func IsUpstreamUnreachableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return strings.Contains(err.Error(), "connection refused")
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/i18n"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AdvisorFindingResponse is an advisor finding with its translated message and fix hint.
+type AdvisorFindingResponse struct {
+	services.AdvisorFinding
+	Message string `json:"message"`
+	FixHint string `json:"fix_hint"`
+}
+
+// AdvisorReportResponse is the configuration advisor report.
+type AdvisorReportResponse struct {
+	Summary  map[string]int           `json:"summary"`
+	Findings []AdvisorFindingResponse `json:"findings"`
+}
+
+// GetAdvisorReport handles GET /api/admin/advisor.
+// It inspects the current groups and settings and reports actionable findings ordered by severity.
+// Callers restricted to some teams only get the findings of their groups and the system settings.
+func (s *Server) GetAdvisorReport(c *gin.Context) {
+	findings, err := s.AdvisorService.Analyze(c.Request.Context())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to analyze configuration")
+		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "advisor.analyze_failed")
+		return
+	}
+	if services.GroupScopeFromContext(c) != nil {
+		groupIDs := make([]uint, 0, len(findings))
+		for _, finding := range findings {
+			if finding.GroupID != 0 {
+				groupIDs = append(groupIDs, finding.GroupID)
+			}
+		}
+		visible, err := s.visibleGroupIDs(c, groupIDs)
+		if err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return
+		}
+		scoped := findings[:0]
+		for _, finding := range findings {
+			if finding.GroupID == 0 || visible[finding.GroupID] {
+				scoped = append(scoped, finding)
+			}
+		}
+		findings = scoped
+	}
+
+	report := AdvisorReportResponse{
+		Summary: map[string]int{
+			services.AdvisorSeverityCritical: 0,
+			services.AdvisorSeverityWarning:  0,
+			services.AdvisorSeverityInfo:     0,
+		},
+		Findings: make([]AdvisorFindingResponse, 0, len(findings)),
+	}
+	for _, finding := range findings {
+		report.Summary[finding.Severity]++
+		report.Findings = append(report.Findings, AdvisorFindingResponse{
+			AdvisorFinding: finding,
+			Message:        i18n.Message(c, "advisor."+finding.Code, finding.Params),
+			FixHint:        i18n.Message(c, "advisor."+finding.Code+"_fix", finding.Params),
+		})
+	}
+
+	response.Success(c, report)
+}
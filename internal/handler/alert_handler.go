@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertRuleRequest is the full state of an alert rule. Saving replaces every field.
+type AlertRuleRequest struct {
+	Name             string  `json:"name"`
+	Type             string  `json:"type"`
+	GroupID          *uint   `json:"group_id"`
+	Threshold        float64 `json:"threshold"`
+	WindowMinutes    int     `json:"window_minutes"`
+	MinRequests      int     `json:"min_requests"`
+	RepeatMinutes    int     `json:"repeat_minutes"`
+	Channel          string  `json:"channel"`
+	WebhookURL       string  `json:"webhook_url"`
+	TelegramBotToken string  `json:"telegram_bot_token"`
+	TelegramChatID   string  `json:"telegram_chat_id"`
+	PayloadTemplate  string  `json:"payload_template"`
+	Enabled          bool    `json:"enabled"`
+}
+
+func (r AlertRuleRequest) params() services.AlertRuleParams {
+	return services.AlertRuleParams{
+		Name:             r.Name,
+		Type:             r.Type,
+		GroupID:          r.GroupID,
+		Threshold:        r.Threshold,
+		WindowMinutes:    r.WindowMinutes,
+		MinRequests:      r.MinRequests,
+		RepeatMinutes:    r.RepeatMinutes,
+		Channel:          r.Channel,
+		WebhookURL:       r.WebhookURL,
+		TelegramBotToken: r.TelegramBotToken,
+		TelegramChatID:   r.TelegramChatID,
+		PayloadTemplate:  r.PayloadTemplate,
+		Enabled:          r.Enabled,
+	}
+}
+
+// ListAlertRules handles GET /api/alerts.
+func (s *Server) ListAlertRules(c *gin.Context) {
+	rules, err := s.AlertService.ListRules()
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, gin.H{
+		"rules":            rules,
+		"types":            services.AlertRuleTypes,
+		"channels":         services.AlertChannels,
+		"default_template": services.DefaultAlertPayloadTemplate,
+	})
+}
+
+// CreateAlertRule handles POST /api/alerts.
+func (s *Server) CreateAlertRule(c *gin.Context) {
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	rule, err := s.AlertService.CreateRule(req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, rule)
+}
+
+// UpdateAlertRule handles PUT /api/alerts/:id.
+func (s *Server) UpdateAlertRule(c *gin.Context) {
+	id, ok := parseAlertRuleID(c)
+	if !ok {
+		return
+	}
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	rule, err := s.AlertService.UpdateRule(id, req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, rule)
+}
+
+// DeleteAlertRule handles DELETE /api/alerts/:id.
+func (s *Server) DeleteAlertRule(c *gin.Context) {
+	id, ok := parseAlertRuleID(c)
+	if !ok {
+		return
+	}
+	if s.handleGroupError(c, s.AlertService.DeleteRule(id)) {
+		return
+	}
+	response.Success(c, nil)
+}
+
+// TestAlertRule handles POST /api/alerts/:id/test, sending a test payload to the rule's webhook.
+func (s *Server) TestAlertRule(c *gin.Context) {
+	id, ok := parseAlertRuleID(c)
+	if !ok {
+		return
+	}
+	if s.handleGroupError(c, s.AlertService.TestRule(c.Request.Context(), id)) {
+		return
+	}
+	response.SuccessI18n(c, "success.alert_test_sent", nil)
+}
+
+// parseAlertRuleID parses the :id path parameter, writing an error response on failure.
+func parseAlertRuleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_alert_rule_id")
+		return 0, false
+	}
+	return uint(id), true
+}
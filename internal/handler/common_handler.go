@@ -20,3 +20,8 @@ func (h *CommonHandler) GetChannelTypes(c *gin.Context) {
 	channelTypes := channel.GetChannels()
 	response.Success(c, channelTypes)
 }
+
+// GetChannelPresets returns the quick-add provider presets for the group form.
+func (h *CommonHandler) GetChannelPresets(c *gin.Context) {
+	response.Success(c, channel.GetChannelPresets())
+}
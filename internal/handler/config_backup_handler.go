@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ConfigExportRequest selects what a configuration export contains. The passphrase encrypts the
+// exported keys and is required to import them again.
+type ConfigExportRequest struct {
+	IncludeSettings bool   `json:"include_settings"`
+	IncludeKeys     bool   `json:"include_keys"`
+	Passphrase      string `json:"passphrase"`
+}
+
+// ConfigImportRequest is a bundle produced by an export, with the passphrase of its keys.
+type ConfigImportRequest struct {
+	Bundle     *services.ConfigBundle `json:"bundle"`
+	Passphrase string                 `json:"passphrase"`
+}
+
+// ExportConfig handles POST /api/backup/export, downloading the configuration as a JSON bundle.
+func (s *Server) ExportConfig(c *gin.Context) {
+	var req ConfigExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	bundle, err := s.ConfigBackupService.Export(c.Request.Context(), services.ConfigExportOptions{
+		IncludeSettings: req.IncludeSettings,
+		IncludeKeys:     req.IncludeKeys,
+		Passphrase:      req.Passphrase,
+	})
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"client_ip":        c.ClientIP(),
+		"groups":           len(bundle.Groups),
+		"include_settings": req.IncludeSettings,
+		"include_keys":     req.IncludeKeys,
+	}).Info("Config exported")
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	filename := fmt.Sprintf("gpt-load-backup-%s.json", bundle.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// ImportConfig handles POST /api/backup/import, applying a bundle produced by ExportConfig.
+func (s *Server) ImportConfig(c *gin.Context) {
+	var req ConfigImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if req.Bundle == nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.backup_bundle_required")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"client_ip": c.ClientIP(),
+		"version":   req.Bundle.Version,
+		"groups":    len(req.Bundle.Groups),
+	}).Info("Config import")
+
+	result, err := s.ConfigBackupService.Import(c.Request.Context(), req.Bundle, req.Passphrase)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	if result.SettingsUpdated {
+		time.Sleep(100 * time.Millisecond) // 等待异步更新配置
+	}
+	response.Success(c, result)
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ConfigVersionDetail is a stored version together with its changes relative to a base version.
+type ConfigVersionDetail struct {
+	Version     *models.ConfigVersion        `json:"version"`
+	BaseVersion *models.ConfigVersion        `json:"base_version"`
+	Changes     []services.ConfigFieldChange `json:"changes"`
+}
+
+// ListConfigVersions handles GET /api/config-versions?resource_type=group&resource_id=1.
+func (s *Server) ListConfigVersions(c *gin.Context) {
+	resourceType := c.Query("resource_type")
+	var resourceID uint
+	switch resourceType {
+	case services.ConfigResourceGroup:
+		id, err := strconv.Atoi(c.Query("resource_id"))
+		if err != nil || id <= 0 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+			return
+		}
+		resourceID = uint(id)
+		if !s.authorizeGroup(c, resourceID) {
+			return
+		}
+	case services.ConfigResourceSettings:
+	default:
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_config_resource_type")
+		return
+	}
+
+	versions, err := s.ConfigVersionService.List(resourceType, resourceID)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	if !canViewSecrets(c) {
+		for i := range versions {
+			maskConfigVersion(&versions[i])
+		}
+	}
+	response.Success(c, versions)
+}
+
+// GetConfigVersion handles GET /api/config-versions/:id, returning the version and its changes from the previous one.
+func (s *Server) GetConfigVersion(c *gin.Context) {
+	version, ok := s.loadConfigVersion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	previous, err := s.ConfigVersionService.Previous(version)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	s.respondConfigDiff(c, previous, version)
+}
+
+// DiffConfigVersions handles GET /api/config-versions/:id/diff?against=<id>.
+func (s *Server) DiffConfigVersions(c *gin.Context) {
+	version, ok := s.loadConfigVersion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	base, ok := s.loadConfigVersion(c, c.Query("against"))
+	if !ok {
+		return
+	}
+	if base.ResourceType != version.ResourceType || base.ResourceID != version.ResourceID {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.config_version_resource_mismatch")
+		return
+	}
+	s.respondConfigDiff(c, base, version)
+}
+
+// RollbackConfigVersion handles POST /api/config-versions/:id/rollback, restoring the stored state.
+func (s *Server) RollbackConfigVersion(c *gin.Context) {
+	version, ok := s.loadConfigVersion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"client_ip":     c.ClientIP(),
+		"resource_type": version.ResourceType,
+		"resource_id":   version.ResourceID,
+		"version":       version.Version,
+	}).Info("Config rollback")
+
+	switch version.ResourceType {
+	case services.ConfigResourceGroup:
+		group, err := s.GroupService.RollbackGroup(c.Request.Context(), version)
+		if s.handleGroupError(c, err) {
+			return
+		}
+		response.Success(c, s.newGroupResponse(group))
+	case services.ConfigResourceSettings:
+		settingsMap, err := services.SettingsFromVersion(version)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+			return
+		}
+		previous := s.SettingsManager.GetSettings()
+		if err := s.SettingsManager.UpdateSettings(settingsMap); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+		if err := s.ConfigVersionService.RecordSettings(previous, settingsMap, services.ConfigActionRollback); err != nil {
+			logrus.WithError(err).Warn("Failed to record settings version")
+		}
+
+		time.Sleep(100 * time.Millisecond) // 等待异步更新配置
+
+		response.SuccessI18n(c, "settings.update_success", nil)
+	default:
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_config_resource_type")
+	}
+}
+
+// loadConfigVersion parses a version ID and loads the version, writing an error response on failure.
+func (s *Server) loadConfigVersion(c *gin.Context, rawID string) (*models.ConfigVersion, bool) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil || id <= 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_config_version_id")
+		return nil, false
+	}
+	version, err := s.ConfigVersionService.Get(uint(id))
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return nil, false
+	}
+	if version.ResourceType == services.ConfigResourceGroup && !s.authorizeGroup(c, version.ResourceID) {
+		return nil, false
+	}
+	return version, true
+}
+
+func (s *Server) respondConfigDiff(c *gin.Context, base, version *models.ConfigVersion) {
+	changes, err := s.ConfigVersionService.Diff(base, version)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	if !canViewSecrets(c) {
+		maskConfigVersion(base)
+		maskConfigVersion(version)
+		maskConfigChanges(version.ResourceType, changes)
+	}
+	response.Success(c, ConfigVersionDetail{
+		Version:     version,
+		BaseVersion: base,
+		Changes:     changes,
+	})
+}
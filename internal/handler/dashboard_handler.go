@@ -7,6 +7,9 @@ import (
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,12 +19,13 @@ import (
 
 // Stats Get dashboard statistics
 func (s *Server) Stats(c *gin.Context) {
+	scope := services.GroupScopeFromContext(c)
 	var activeKeys, invalidKeys int64
-	s.DB.Model(&models.APIKey{}).Where("status = ?", models.KeyStatusActive).Count(&activeKeys)
-	s.DB.Model(&models.APIKey{}).Where("status = ?", models.KeyStatusInvalid).Count(&invalidKeys)
+	s.DB.Model(&models.APIKey{}).Scopes(scope.ByGroupID("group_id")).Where("status = ?", models.KeyStatusActive).Count(&activeKeys)
+	s.DB.Model(&models.APIKey{}).Scopes(scope.ByGroupID("group_id")).Where("status = ?", models.KeyStatusInvalid).Count(&invalidKeys)
 
 	now := time.Now()
-	rpmStats, err := s.getRPMStats(now)
+	rpmStats, err := s.getRPMStats(scope, now)
 	if err != nil {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.rpm_stats_failed")
 		return
@@ -29,12 +33,12 @@ func (s *Server) Stats(c *gin.Context) {
 	twentyFourHoursAgo := now.Add(-24 * time.Hour)
 	fortyEightHoursAgo := now.Add(-48 * time.Hour)
 
-	currentPeriod, err := s.getHourlyStats(twentyFourHoursAgo, now)
+	currentPeriod, err := s.getHourlyStats(scope, twentyFourHoursAgo, now)
 	if err != nil {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.current_stats_failed")
 		return
 	}
-	previousPeriod, err := s.getHourlyStats(fortyEightHoursAgo, twentyFourHoursAgo)
+	previousPeriod, err := s.getHourlyStats(scope, fortyEightHoursAgo, twentyFourHoursAgo)
 	if err != nil {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.previous_stats_failed")
 		return
@@ -124,6 +128,7 @@ func (s *Server) Chart(c *gin.Context) {
 
 	var hourlyStats []models.GroupHourlyStat
 	query := s.DB.Table("group_hourly_stats").
+		Scopes(services.GroupScopeFromContext(c).ByGroupID("group_id")).
 		Where("time >= ? AND time < ?", startHour, endHour.Add(time.Hour))
 	if groupID != "" {
 		query = query.Where("group_id = ?", groupID)
@@ -181,14 +186,85 @@ func (s *Server) Chart(c *gin.Context) {
 	response.Success(c, chartData)
 }
 
+// maxHourlyUsageRange bounds hourly usage queries; longer ranges should use daily rollups.
+const maxHourlyUsageRange = 31 * 24 * time.Hour
+
+// UsageResponse is the result of a usage rollup query.
+type UsageResponse struct {
+	Granularity string `json:"granularity"`
+	// RolledUpUntil is the time up to which requests are included; later requests are not yet rolled up
+	RolledUpUntil *time.Time           `json:"rolled_up_until"`
+	Items         []models.UsageRollup `json:"items"`
+}
+
+// Usage handles GET /api/dashboard/usage, returning request and token usage per group and model
+// from the hourly or daily rollups. It accepts granularity (hour or day, default day), start_time
+// and end_time (RFC3339, default the last 24 hours or 30 days), group_id and model.
+func (s *Server) Usage(c *gin.Context) {
+	granularity := c.DefaultQuery("granularity", services.UsageGranularityDay)
+	if granularity != services.UsageGranularityHour && granularity != services.UsageGranularityDay {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_usage_granularity")
+		return
+	}
+
+	end := time.Now()
+	if endStr := c.Query("end_time"); endStr != "" {
+		parsed, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_usage_range")
+			return
+		}
+		end = parsed
+	}
+	start := end.Add(-24 * time.Hour)
+	if granularity == services.UsageGranularityDay {
+		start = end.AddDate(0, 0, -30)
+	}
+	if startStr := c.Query("start_time"); startStr != "" {
+		parsed, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_usage_range")
+			return
+		}
+		start = parsed
+	}
+	if !start.Before(end) || (granularity == services.UsageGranularityHour && end.Sub(start) > maxHourlyUsageRange) {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_usage_range")
+		return
+	}
+
+	var groupID uint
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		id, err := strconv.ParseUint(groupIDStr, 10, 32)
+		if err != nil {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_group_id_format")
+			return
+		}
+		groupID = uint(id)
+	}
+
+	scope := services.GroupScopeFromContext(c)
+	items, err := s.UsageRollupService.QueryUsage(scope, granularity, start, end, groupID, c.Query("model"))
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	result := UsageResponse{Granularity: granularity, Items: items}
+	if rolledUpUntil, err := s.UsageRollupService.RolledUpUntil(); err == nil && !rolledUpUntil.IsZero() {
+		result.RolledUpUntil = &rolledUpUntil
+	}
+	response.Success(c, result)
+}
+
 type hourlyStatResult struct {
 	TotalRequests int64
 	TotalFailures int64
 }
 
-func (s *Server) getHourlyStats(startTime, endTime time.Time) (hourlyStatResult, error) {
+func (s *Server) getHourlyStats(scope *services.GroupScope, startTime, endTime time.Time) (hourlyStatResult, error) {
 	var result hourlyStatResult
 	err := s.DB.Table("group_hourly_stats").
+		Scopes(scope.ByGroupID("group_id")).
 		Where("time >= ? AND time < ?", startTime, endTime).
 		Where("group_id NOT IN (?)",
 			s.DB.Table("groups").Select("id").Where("group_type = ?", "aggregate")).
@@ -202,12 +278,13 @@ type rpmStatResult struct {
 	PreviousRequests int64
 }
 
-func (s *Server) getRPMStats(now time.Time) (models.StatCard, error) {
+func (s *Server) getRPMStats(scope *services.GroupScope, now time.Time) (models.StatCard, error) {
 	tenMinutesAgo := now.Add(-10 * time.Minute)
 	twentyMinutesAgo := now.Add(-20 * time.Minute)
 
 	var result rpmStatResult
 	err := s.DB.Model(&models.RequestLog{}).
+		Scopes(scope.ByGroupID("group_id")).
 		Select("count(case when timestamp >= ? then 1 end) as current_requests, count(case when timestamp >= ? and timestamp < ? then 1 end) as previous_requests", tenMinutesAgo, twentyMinutesAgo, tenMinutesAgo).
 		Where("timestamp >= ? AND request_type = ?", twentyMinutesAgo, models.RequestTypeFinal).
 		Scan(&result).Error
@@ -448,20 +525,17 @@ func (s *Server) checkEncryptionMismatch(c *gin.Context) (bool, string, string,
 
 	unencryptedConsistencyRate := float64(unencryptedHashMatchCount) / float64(len(sampleKeys))
 
-	// If ENCRYPTION_KEY is configured, also check if current key can decrypt the data
+	// If ENCRYPTION_KEY is configured, also check if the configured keys can decrypt the data.
+	// Keys still on a previous key version count as matching while they are being rotated.
 	var currentKeyHashMatchCount int
 	if encryptionKey != "" {
-		currentService, err := encryption.NewService(encryptionKey)
-		if err == nil {
-			for _, key := range sampleKeys {
-				// Try to decrypt and re-hash to check if current key matches
-				decrypted, err := currentService.Decrypt(key.KeyValue)
-				if err == nil {
-					// Successfully decrypted, check if hash matches
-					expectedHash := currentService.Hash(decrypted)
-					if expectedHash == key.KeyHash {
-						currentKeyHashMatchCount++
-					}
+		for _, key := range sampleKeys {
+			// Try to decrypt and re-hash to check if current key matches
+			decrypted, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+			if err == nil {
+				// Successfully decrypted, check if hash matches
+				if slices.Contains(s.EncryptionSvc.LookupHashes(decrypted), key.KeyHash) {
+					currentKeyHashMatchCount++
 				}
 			}
 		}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetEncryptionRotation handles requests for the progress of the encryption key rotation.
+func (s *Server) GetEncryptionRotation(c *gin.Context) {
+	response.Success(c, s.EncryptionRotator.Status())
+}
+
+// StartEncryptionRotation handles starting or resuming the re-encryption of stored keys with the
+// current encryption key version.
+func (s *Server) StartEncryptionRotation(c *gin.Context) {
+	if s.handleGroupError(c, s.EncryptionRotator.Trigger()) {
+		return
+	}
+	logrus.WithField("client_ip", c.ClientIP()).Info("Encryption key rotation started")
+	response.Success(c, s.EncryptionRotator.Status())
+}
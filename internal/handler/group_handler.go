@@ -33,6 +33,14 @@ func (s *Server) handleGroupError(c *gin.Context, err error) bool {
 		return true
 	}
 
+	if conflict, ok := err.(*services.GroupConflictError); ok {
+		response.ErrorI18nWithData(c, app_errors.ErrModifiedConflict, "group.modified_concurrently", gin.H{
+			"current": s.newGroupResponse(conflict.Current),
+			"changes": conflict.Changes,
+		})
+		return true
+	}
+
 	if apiErr, ok := err.(*app_errors.APIError); ok {
 		response.Error(c, apiErr)
 		return true
@@ -45,21 +53,25 @@ func (s *Server) handleGroupError(c *gin.Context, err error) bool {
 
 // GroupCreateRequest defines the payload for creating a group.
 type GroupCreateRequest struct {
-	Name                string              `json:"name"`
-	DisplayName         string              `json:"display_name"`
-	Description         string              `json:"description"`
-	GroupType           string              `json:"group_type"` // 'standard' or 'aggregate'
-	Upstreams           json.RawMessage     `json:"upstreams"`
-	ChannelType         string              `json:"channel_type"`
-	Sort                int                 `json:"sort"`
-	TestModel           string              `json:"test_model"`
-	ValidationEndpoint  string              `json:"validation_endpoint"`
-	ParamOverrides      map[string]any      `json:"param_overrides"`
-	ModelRedirectRules  map[string]string   `json:"model_redirect_rules"`
-	ModelRedirectStrict bool                `json:"model_redirect_strict"`
-	Config              map[string]any      `json:"config"`
-	HeaderRules         []models.HeaderRule `json:"header_rules"`
-	ProxyKeys           string              `json:"proxy_keys"`
+	Name                string                    `json:"name"`
+	DisplayName         string                    `json:"display_name"`
+	Description         string                    `json:"description"`
+	GroupType           string                    `json:"group_type"` // 'standard' or 'aggregate'
+	Upstreams           json.RawMessage           `json:"upstreams"`
+	ChannelType         string                    `json:"channel_type"`
+	Sort                int                       `json:"sort"`
+	TestModel           string                    `json:"test_model"`
+	ValidationEndpoint  string                    `json:"validation_endpoint"`
+	ParamOverrides      map[string]any            `json:"param_overrides"`
+	ModelRedirectRules  map[string]string         `json:"model_redirect_rules"`
+	ModelRedirectStrict bool                      `json:"model_redirect_strict"`
+	ModelRoutingRules   []models.ModelRoutingRule `json:"model_routing_rules"`
+	Config              map[string]any            `json:"config"`
+	HeaderRules         []models.HeaderRule       `json:"header_rules"`
+	BodyRules           []models.BodyRule         `json:"body_rules"`
+	ScriptRules         []models.ScriptRule       `json:"script_rules"`
+	ProxyKeys           string                    `json:"proxy_keys"`
+	TTLHours            int                       `json:"ttl_hours"`
 }
 
 // CreateGroup handles the creation of a new group.
@@ -83,9 +95,13 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		ParamOverrides:      req.ParamOverrides,
 		ModelRedirectRules:  req.ModelRedirectRules,
 		ModelRedirectStrict: req.ModelRedirectStrict,
+		ModelRoutingRules:   req.ModelRoutingRules,
 		Config:              req.Config,
 		HeaderRules:         req.HeaderRules,
+		BodyRules:           req.BodyRules,
+		ScriptRules:         req.ScriptRules,
 		ProxyKeys:           req.ProxyKeys,
+		TTLHours:            req.TTLHours,
 	}
 
 	group, err := s.GroupService.CreateGroup(c.Request.Context(), params)
@@ -93,6 +109,14 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		return
 	}
 
+	// Groups created by team members belong to their team, so that they stay out of sight of others
+	if teamID := services.GroupScopeFromContext(c).DefaultTeamID(); teamID != nil {
+		if s.handleGroupError(c, s.TeamService.AssignGroup(group.ID, teamID)) {
+			return
+		}
+		group.TeamID = teamID
+	}
+
 	response.Success(c, s.newGroupResponse(group))
 }
 
@@ -103,9 +127,18 @@ func (s *Server) ListGroups(c *gin.Context) {
 		return
 	}
 
+	scope := services.GroupScopeFromContext(c)
+	showSecrets := canViewSecrets(c)
 	groupResponses := make([]GroupResponse, 0, len(groups))
 	for i := range groups {
-		groupResponses = append(groupResponses, *s.newGroupResponse(&groups[i]))
+		if !scope.Allows(&groups[i]) {
+			continue
+		}
+		groupResponse := s.newGroupResponse(&groups[i])
+		if !showSecrets {
+			groupResponse.maskProxyKeys()
+		}
+		groupResponses = append(groupResponses, *groupResponse)
 	}
 
 	response.Success(c, groupResponses)
@@ -114,21 +147,25 @@ func (s *Server) ListGroups(c *gin.Context) {
 // GroupUpdateRequest defines the payload for updating a group.
 // Using a dedicated struct avoids issues with zero values being ignored by GORM's Update.
 type GroupUpdateRequest struct {
-	Name                *string             `json:"name,omitempty"`
-	DisplayName         *string             `json:"display_name,omitempty"`
-	Description         *string             `json:"description,omitempty"`
-	GroupType           *string             `json:"group_type,omitempty"`
-	Upstreams           json.RawMessage     `json:"upstreams"`
-	ChannelType         *string             `json:"channel_type,omitempty"`
-	Sort                *int                `json:"sort"`
-	TestModel           string              `json:"test_model"`
-	ValidationEndpoint  *string             `json:"validation_endpoint,omitempty"`
-	ParamOverrides      map[string]any      `json:"param_overrides"`
-	ModelRedirectRules  map[string]string   `json:"model_redirect_rules"`
-	ModelRedirectStrict *bool               `json:"model_redirect_strict"`
-	Config              map[string]any      `json:"config"`
-	HeaderRules         []models.HeaderRule `json:"header_rules"`
-	ProxyKeys           *string             `json:"proxy_keys,omitempty"`
+	Name                *string                   `json:"name,omitempty"`
+	DisplayName         *string                   `json:"display_name,omitempty"`
+	Description         *string                   `json:"description,omitempty"`
+	GroupType           *string                   `json:"group_type,omitempty"`
+	Upstreams           json.RawMessage           `json:"upstreams"`
+	ChannelType         *string                   `json:"channel_type,omitempty"`
+	Sort                *int                      `json:"sort"`
+	TestModel           string                    `json:"test_model"`
+	ValidationEndpoint  *string                   `json:"validation_endpoint,omitempty"`
+	ParamOverrides      map[string]any            `json:"param_overrides"`
+	ModelRedirectRules  map[string]string         `json:"model_redirect_rules"`
+	ModelRedirectStrict *bool                     `json:"model_redirect_strict"`
+	ModelRoutingRules   []models.ModelRoutingRule `json:"model_routing_rules"`
+	Config              map[string]any            `json:"config"`
+	HeaderRules         []models.HeaderRule       `json:"header_rules"`
+	BodyRules           []models.BodyRule         `json:"body_rules"`
+	ScriptRules         []models.ScriptRule       `json:"script_rules"`
+	ProxyKeys           *string                   `json:"proxy_keys,omitempty"`
+	TTLHours            *int                      `json:"ttl_hours,omitempty"`
 }
 
 // UpdateGroup handles updating an existing group.
@@ -138,6 +175,9 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	var req GroupUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -145,6 +185,58 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		return
 	}
 
+	group, err := s.GroupService.UpdateGroup(c.Request.Context(), uint(id), req.toParams())
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, s.newGroupResponse(group))
+}
+
+// GroupPatchRequest is a partial group update. Omitted fields are left unchanged and config is merged
+// into the existing overrides, a null value removing one. updated_at or version must state the
+// state of the group the change is based on; if the group has changed since, 409 is returned.
+type GroupPatchRequest struct {
+	GroupUpdateRequest
+	UpdatedAt *time.Time `json:"updated_at"`
+	Version   *int       `json:"version"`
+}
+
+// PatchGroup handles PATCH /api/groups/:id with optimistic locking.
+func (s *Server) PatchGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
+
+	var req GroupPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if req.UpdatedAt == nil && req.Version == nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrPreconditionNeeded, "validation.group_precondition_required")
+		return
+	}
+
+	params := req.toParams()
+	params.MergeConfig = true
+	params.Precondition = &services.GroupPrecondition{UpdatedAt: req.UpdatedAt, Version: req.Version}
+
+	group, err := s.GroupService.UpdateGroup(c.Request.Context(), uint(id), params)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, s.newGroupResponse(group))
+}
+
+// toParams converts the request into service parameters.
+func (req *GroupUpdateRequest) toParams() services.GroupUpdateParams {
 	params := services.GroupUpdateParams{
 		Name:                req.Name,
 		DisplayName:         req.DisplayName,
@@ -158,6 +250,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		ModelRedirectStrict: req.ModelRedirectStrict,
 		Config:              req.Config,
 		ProxyKeys:           req.ProxyKeys,
+		TTLHours:            req.TTLHours,
 	}
 
 	if req.Upstreams != nil {
@@ -175,36 +268,57 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		params.HeaderRules = &rules
 	}
 
-	group, err := s.GroupService.UpdateGroup(c.Request.Context(), uint(id), params)
-	if s.handleGroupError(c, err) {
-		return
+	if req.BodyRules != nil {
+		rules := req.BodyRules
+		params.BodyRules = &rules
 	}
 
-	response.Success(c, s.newGroupResponse(group))
+	if req.ScriptRules != nil {
+		rules := req.ScriptRules
+		params.ScriptRules = &rules
+	}
+
+	if req.ModelRoutingRules != nil {
+		rules := req.ModelRoutingRules
+		params.ModelRoutingRules = &rules
+	}
+
+	return params
 }
 
 // GroupResponse defines the structure for a group response, excluding sensitive or large fields.
 type GroupResponse struct {
-	ID                  uint                `json:"id"`
-	Name                string              `json:"name"`
-	Endpoint            string              `json:"endpoint"`
-	DisplayName         string              `json:"display_name"`
-	Description         string              `json:"description"`
-	GroupType           string              `json:"group_type"`
-	Upstreams           datatypes.JSON      `json:"upstreams"`
-	ChannelType         string              `json:"channel_type"`
-	Sort                int                 `json:"sort"`
-	TestModel           string              `json:"test_model"`
-	ValidationEndpoint  string              `json:"validation_endpoint"`
-	ParamOverrides      datatypes.JSONMap   `json:"param_overrides"`
-	ModelRedirectRules  datatypes.JSONMap   `json:"model_redirect_rules"`
-	ModelRedirectStrict bool                `json:"model_redirect_strict"`
-	Config              datatypes.JSONMap   `json:"config"`
-	HeaderRules         []models.HeaderRule `json:"header_rules"`
-	ProxyKeys           string              `json:"proxy_keys"`
-	LastValidatedAt     *time.Time          `json:"last_validated_at"`
-	CreatedAt           time.Time           `json:"created_at"`
-	UpdatedAt           time.Time           `json:"updated_at"`
+	ID                  uint                      `json:"id"`
+	Name                string                    `json:"name"`
+	Endpoint            string                    `json:"endpoint"`
+	DisplayName         string                    `json:"display_name"`
+	Description         string                    `json:"description"`
+	GroupType           string                    `json:"group_type"`
+	Upstreams           datatypes.JSON            `json:"upstreams"`
+	ChannelType         string                    `json:"channel_type"`
+	Sort                int                       `json:"sort"`
+	TestModel           string                    `json:"test_model"`
+	ValidationEndpoint  string                    `json:"validation_endpoint"`
+	ParamOverrides      datatypes.JSONMap         `json:"param_overrides"`
+	ModelRedirectRules  datatypes.JSONMap         `json:"model_redirect_rules"`
+	ModelRedirectStrict bool                      `json:"model_redirect_strict"`
+	ModelRoutingRules   []models.ModelRoutingRule `json:"model_routing_rules"`
+	ModelAccess         datatypes.JSON            `json:"model_access"`
+	IPAccess            datatypes.JSON            `json:"ip_access"`
+	Config              datatypes.JSONMap         `json:"config"`
+	HeaderRules         []models.HeaderRule       `json:"header_rules"`
+	BodyRules           []models.BodyRule         `json:"body_rules"`
+	ScriptRules         []models.ScriptRule       `json:"script_rules"`
+	ProxyKeys           string                    `json:"proxy_keys"`
+	ProxyKeyExpiry      datatypes.JSONMap         `json:"proxy_key_expiry"`
+	ExpiresAt           *time.Time                `json:"expires_at"`
+	TeamID              *uint                     `json:"team_id"`
+	LastValidatedAt     *time.Time                `json:"last_validated_at"`
+	ModelWarmupStatus   string                    `json:"model_warmup_status"`
+	ModelWarmupError    string                    `json:"model_warmup_error,omitempty"`
+	ModelWarmupAt       *time.Time                `json:"model_warmup_at"`
+	CreatedAt           time.Time                 `json:"created_at"`
+	UpdatedAt           time.Time                 `json:"updated_at"`
 }
 
 // newGroupResponse creates a new GroupResponse from a models.Group.
@@ -228,6 +342,27 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		}
 	}
 
+	bodyRules := make([]models.BodyRule, 0)
+	if len(group.BodyRules) > 0 {
+		if err := json.Unmarshal(group.BodyRules, &bodyRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal body rules")
+		}
+	}
+
+	scriptRules := make([]models.ScriptRule, 0)
+	if len(group.ScriptRules) > 0 {
+		if err := json.Unmarshal(group.ScriptRules, &scriptRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal script rules")
+		}
+	}
+
+	routingRules := make([]models.ModelRoutingRule, 0)
+	if len(group.ModelRoutingRules) > 0 {
+		if err := json.Unmarshal(group.ModelRoutingRules, &routingRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal model routing rules")
+		}
+	}
+
 	return &GroupResponse{
 		ID:                  group.ID,
 		Name:                group.Name,
@@ -243,10 +378,21 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		ParamOverrides:      group.ParamOverrides,
 		ModelRedirectRules:  group.ModelRedirectRules,
 		ModelRedirectStrict: group.ModelRedirectStrict,
+		ModelRoutingRules:   routingRules,
+		ModelAccess:         group.ModelAccess,
+		IPAccess:            group.IPAccess,
 		Config:              group.Config,
 		HeaderRules:         headerRules,
+		BodyRules:           bodyRules,
+		ScriptRules:         scriptRules,
 		ProxyKeys:           group.ProxyKeys,
+		ProxyKeyExpiry:      group.ProxyKeyExpiry,
+		ExpiresAt:           group.ExpiresAt,
+		TeamID:              group.TeamID,
 		LastValidatedAt:     group.LastValidatedAt,
+		ModelWarmupStatus:   group.ModelWarmupStatus,
+		ModelWarmupError:    group.ModelWarmupError,
+		ModelWarmupAt:       group.ModelWarmupAt,
 		CreatedAt:           group.CreatedAt,
 		UpdatedAt:           group.UpdatedAt,
 	}
@@ -259,6 +405,9 @@ func (s *Server) DeleteGroup(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	if s.handleGroupError(c, s.GroupService.DeleteGroup(c.Request.Context(), uint(id))) {
 		return
@@ -266,6 +415,50 @@ func (s *Server) DeleteGroup(c *gin.Context) {
 	response.SuccessI18n(c, "success.group_deleted", nil)
 }
 
+// BulkGroupConfigRequest defines the payload for updating the config overrides of several groups.
+type BulkGroupConfigRequest struct {
+	GroupIDs []uint `json:"group_ids" binding:"required"`
+	// Config is merged into each group's overrides; a null value removes that override.
+	Config        map[string]any `json:"config" binding:"required"`
+	Transactional bool           `json:"transactional"`
+}
+
+// BulkUpdateGroupConfig handles PUT /api/groups/bulk-config with per-group results.
+func (s *Server) BulkUpdateGroupConfig(c *gin.Context) {
+	var req BulkGroupConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if !s.authorizeGroupIDs(c, req.GroupIDs) {
+		return
+	}
+
+	result, err := s.GroupService.BulkUpdateGroupConfig(c.Request.Context(), req.GroupIDs, req.Config, req.Transactional)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	translateBulkReasons(c, &result.BulkResult)
+	response.Success(c, result)
+}
+
+// translateBulkReasons replaces the reasons of failed bulk items with localized messages.
+func translateBulkReasons(c *gin.Context, result *services.BulkResult) {
+	for i := range result.Items {
+		switch err := result.Items[i].Err.(type) {
+		case *services.I18nError:
+			if err.Template != nil {
+				result.Items[i].Reason = i18n.Message(c, err.MessageID, err.Template)
+			} else {
+				result.Items[i].Reason = i18n.Message(c, err.MessageID)
+			}
+		case *app_errors.APIError:
+			result.Items[i].Reason = err.Message
+		}
+	}
+}
+
 // ConfigOption represents a single configurable option for a group.
 type ConfigOption struct {
 	Key          string `json:"key"`
@@ -310,6 +503,9 @@ func (s *Server) GetGroupStats(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	stats, err := s.GroupService.GetGroupStats(c.Request.Context(), uint(id))
 	if s.handleGroupError(c, err) {
@@ -337,6 +533,9 @@ func (s *Server) CopyGroup(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	var req GroupCopyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -357,10 +556,159 @@ func (s *Server) CopyGroup(c *gin.Context) {
 	response.Success(c, copyResponse)
 }
 
+// SandboxKeyRequest defines the payload for creating a sandbox proxy key.
+type SandboxKeyRequest struct {
+	TTLHours int `json:"ttl_hours"`
+}
+
+// CreateSandboxKey handles generating a proxy key for a group that expires after ttl_hours.
+func (s *Server) CreateSandboxKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
+
+	var req SandboxKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	key, err := s.GroupService.CreateSandboxProxyKey(c.Request.Context(), uint(id), req.TTLHours)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, key)
+}
+
+// CheckGroupProxy handles checking that the upstreams of a group can be reached through its outbound proxy
+func (s *Server) CheckGroupProxy(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
+
+	result, err := s.GroupService.CheckProxy(c.Request.Context(), uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetIPAccess handles reading the client address allowlist and denylist of a group
+func (s *Server) GetIPAccess(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
+
+	var group models.Group
+	if err := s.DB.First(&group, id).Error; err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ParseDBError(err), "group.group_not_found")
+		return
+	}
+
+	policy := newIPAccessResponse(&group)
+	if !canViewSecrets(c) {
+		maskIPAccessKeys(&policy)
+	}
+	response.Success(c, policy)
+}
+
+// UpdateIPAccess handles replacing the client address allowlist and denylist of a group
+func (s *Server) UpdateIPAccess(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
+
+	var req models.IPAccessPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	group, err := s.GroupService.UpdateGroup(c.Request.Context(), uint(id), services.GroupUpdateParams{
+		IPAccess: &req,
+	})
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, newIPAccessResponse(group))
+}
+
+// DeleteIPAccess handles removing every client address restriction of a group
+func (s *Server) DeleteIPAccess(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
+
+	group, err := s.GroupService.UpdateGroup(c.Request.Context(), uint(id), services.GroupUpdateParams{
+		IPAccess: &models.IPAccessPolicy{},
+	})
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, newIPAccessResponse(group))
+}
+
+// newIPAccessResponse decodes a group's IP access policy, using empty lists when none is set.
+func newIPAccessResponse(group *models.Group) models.IPAccessPolicy {
+	var policy models.IPAccessPolicy
+	if len(group.IPAccess) > 0 {
+		if err := json.Unmarshal(group.IPAccess, &policy); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal IP access policy")
+		}
+	}
+	if policy.Allowed == nil {
+		policy.Allowed = []string{}
+	}
+	if policy.Denied == nil {
+		policy.Denied = []string{}
+	}
+	if policy.ProxyKeys == nil {
+		policy.ProxyKeys = []models.ProxyKeyIPAccess{}
+	}
+	for i := range policy.ProxyKeys {
+		if policy.ProxyKeys[i].Allowed == nil {
+			policy.ProxyKeys[i].Allowed = []string{}
+		}
+		if policy.ProxyKeys[i].Denied == nil {
+			policy.ProxyKeys[i].Denied = []string{}
+		}
+	}
+	return policy
+}
+
 // List godoc
 func (s *Server) List(c *gin.Context) {
 	var groups []models.Group
-	if err := s.DB.Select("id, name,display_name").Find(&groups).Error; err != nil {
+	if err := s.DB.Select("id, name,display_name").Scopes(services.GroupScopeFromContext(c).Groups).Find(&groups).Error; err != nil {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.cannot_get_groups")
 		return
 	}
@@ -384,6 +732,9 @@ func (s *Server) GetSubGroups(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	subGroups, err := s.AggregateGroupService.GetSubGroups(c.Request.Context(), uint(id))
 	if s.handleGroupError(c, err) {
@@ -400,6 +751,9 @@ func (s *Server) AddSubGroups(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	var req AddSubGroupsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -407,6 +761,14 @@ func (s *Server) AddSubGroups(c *gin.Context) {
 		return
 	}
 
+	subGroupIDs := make([]uint, 0, len(req.SubGroups))
+	for _, subGroup := range req.SubGroups {
+		subGroupIDs = append(subGroupIDs, subGroup.GroupID)
+	}
+	if !s.authorizeGroupIDs(c, subGroupIDs) {
+		return
+	}
+
 	if err := s.AggregateGroupService.AddSubGroups(c.Request.Context(), uint(id), req.SubGroups); s.handleGroupError(c, err) {
 		return
 	}
@@ -421,6 +783,9 @@ func (s *Server) UpdateSubGroupWeight(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	subGroupID, err := strconv.Atoi(c.Param("subGroupId"))
 	if err != nil {
@@ -448,6 +813,9 @@ func (s *Server) DeleteSubGroup(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	subGroupID, err := strconv.Atoi(c.Param("subGroupId"))
 	if err != nil {
@@ -469,11 +837,30 @@ func (s *Server) GetParentAggregateGroups(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
 		return
 	}
+	if !s.authorizeGroup(c, uint(id)) {
+		return
+	}
 
 	parentGroups, err := s.AggregateGroupService.GetParentAggregateGroups(c.Request.Context(), uint(id))
 	if s.handleGroupError(c, err) {
 		return
 	}
 
-	response.Success(c, parentGroups)
+	parentIDs := make([]uint, 0, len(parentGroups))
+	for _, parent := range parentGroups {
+		parentIDs = append(parentIDs, parent.GroupID)
+	}
+	visible, err := s.visibleGroupIDs(c, parentIDs)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	scoped := make([]models.ParentAggregateGroupInfo, 0, len(parentGroups))
+	for _, parent := range parentGroups {
+		if visible[parent.GroupID] {
+			scoped = append(scoped, parent)
+		}
+	}
+
+	response.Success(c, scoped)
 }
@@ -2,96 +2,172 @@
 package handler
 
 import (
-	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
+	"gpt-load/internal/httpclient"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"go.uber.org/dig"
 	"gorm.io/gorm"
 )
 
 // Server contains dependencies for HTTP handlers
 type Server struct {
-	DB                         *gorm.DB
-	config                     types.ConfigManager
-	SettingsManager            *config.SystemSettingsManager
-	GroupManager               *services.GroupManager
-	GroupService               *services.GroupService
-	AggregateGroupService      *services.AggregateGroupService
-	KeyManualValidationService *services.KeyManualValidationService
-	TaskService                *services.TaskService
-	KeyService                 *services.KeyService
-	KeyImportService           *services.KeyImportService
-	KeyDeleteService           *services.KeyDeleteService
-	LogService                 *services.LogService
-	ModelService               *services.ModelService
-	CommonHandler              *CommonHandler
-	EncryptionSvc              encryption.Service
+	DB                            *gorm.DB
+	config                        types.ConfigManager
+	SettingsManager               *config.SystemSettingsManager
+	GroupManager                  *services.GroupManager
+	GroupService                  *services.GroupService
+	AggregateGroupService         *services.AggregateGroupService
+	KeyManualValidationService    *services.KeyManualValidationService
+	TaskService                   *services.TaskService
+	JobService                    *services.JobService
+	KeyService                    *services.KeyService
+	KeyImportService              *services.KeyImportService
+	KeyDeleteService              *services.KeyDeleteService
+	LogService                    *services.LogService
+	LogStreamService              *services.LogStreamService
+	UsageRollupService            *services.UsageRollupService
+	AlertService                  *services.AlertService
+	WebhookService                *services.WebhookService
+	UserService                   *services.UserService
+	OIDCService                   *services.OIDCService
+	TeamService                   *services.TeamService
+	ModelService                  *services.ModelService
+	ConfigVersionService          *services.ConfigVersionService
+	ConfigBackupService           *services.ConfigBackupService
+	AdvisorService                *services.AdvisorService
+	PlaygroundConversationService *services.PlaygroundConversationService
+	StreamTranscriptService       *services.StreamTranscriptService
+	KeyHealthService              *services.KeyHealthService
+	DependencyCheckService        *services.DependencyCheckService
+	RuntimeService                *services.RuntimeService
+	ProxyServer                   *proxy.ProxyServer
+	NotificationService           *notification.Service
+	EncryptionRotator             *services.EncryptionRotationService
+	ModelRefresher                *services.ModelRefreshService
+	HTTPClientManager             *httpclient.HTTPClientManager
+	CommonHandler                 *CommonHandler
+	EncryptionSvc                 encryption.Service
 }
 
 // NewServerParams defines the dependencies for the NewServer constructor.
 type NewServerParams struct {
 	dig.In
-	DB                         *gorm.DB
-	Config                     types.ConfigManager
-	SettingsManager            *config.SystemSettingsManager
-	GroupManager               *services.GroupManager
-	GroupService               *services.GroupService
-	AggregateGroupService      *services.AggregateGroupService
-	KeyManualValidationService *services.KeyManualValidationService
-	TaskService                *services.TaskService
-	KeyService                 *services.KeyService
-	KeyImportService           *services.KeyImportService
-	KeyDeleteService           *services.KeyDeleteService
-	LogService                 *services.LogService
-	ModelService               *services.ModelService
-	CommonHandler              *CommonHandler
-	EncryptionSvc              encryption.Service
+	DB                            *gorm.DB
+	Config                        types.ConfigManager
+	SettingsManager               *config.SystemSettingsManager
+	GroupManager                  *services.GroupManager
+	GroupService                  *services.GroupService
+	AggregateGroupService         *services.AggregateGroupService
+	KeyManualValidationService    *services.KeyManualValidationService
+	TaskService                   *services.TaskService
+	JobService                    *services.JobService
+	KeyService                    *services.KeyService
+	KeyImportService              *services.KeyImportService
+	KeyDeleteService              *services.KeyDeleteService
+	LogService                    *services.LogService
+	LogStreamService              *services.LogStreamService
+	UsageRollupService            *services.UsageRollupService
+	AlertService                  *services.AlertService
+	WebhookService                *services.WebhookService
+	UserService                   *services.UserService
+	OIDCService                   *services.OIDCService
+	TeamService                   *services.TeamService
+	ModelService                  *services.ModelService
+	ConfigVersionService          *services.ConfigVersionService
+	ConfigBackupService           *services.ConfigBackupService
+	AdvisorService                *services.AdvisorService
+	PlaygroundConversationService *services.PlaygroundConversationService
+	StreamTranscriptService       *services.StreamTranscriptService
+	KeyHealthService              *services.KeyHealthService
+	DependencyCheckService        *services.DependencyCheckService
+	RuntimeService                *services.RuntimeService
+	ProxyServer                   *proxy.ProxyServer
+	NotificationService           *notification.Service
+	EncryptionRotator             *services.EncryptionRotationService
+	ModelRefresher                *services.ModelRefreshService
+	HTTPClientManager             *httpclient.HTTPClientManager
+	CommonHandler                 *CommonHandler
+	EncryptionSvc                 encryption.Service
 }
 
 // NewServer creates a new handler instance with dependencies injected by dig.
 func NewServer(params NewServerParams) *Server {
 	return &Server{
-		DB:                         params.DB,
-		config:                     params.Config,
-		SettingsManager:            params.SettingsManager,
-		GroupManager:               params.GroupManager,
-		GroupService:               params.GroupService,
-		AggregateGroupService:      params.AggregateGroupService,
-		KeyManualValidationService: params.KeyManualValidationService,
-		TaskService:                params.TaskService,
-		KeyService:                 params.KeyService,
-		KeyImportService:           params.KeyImportService,
-		KeyDeleteService:           params.KeyDeleteService,
-		LogService:                 params.LogService,
-		ModelService:               params.ModelService,
-		CommonHandler:              params.CommonHandler,
-		EncryptionSvc:              params.EncryptionSvc,
+		DB:                            params.DB,
+		config:                        params.Config,
+		SettingsManager:               params.SettingsManager,
+		GroupManager:                  params.GroupManager,
+		GroupService:                  params.GroupService,
+		AggregateGroupService:         params.AggregateGroupService,
+		KeyManualValidationService:    params.KeyManualValidationService,
+		TaskService:                   params.TaskService,
+		JobService:                    params.JobService,
+		KeyService:                    params.KeyService,
+		KeyImportService:              params.KeyImportService,
+		KeyDeleteService:              params.KeyDeleteService,
+		LogService:                    params.LogService,
+		LogStreamService:              params.LogStreamService,
+		UsageRollupService:            params.UsageRollupService,
+		AlertService:                  params.AlertService,
+		WebhookService:                params.WebhookService,
+		UserService:                   params.UserService,
+		OIDCService:                   params.OIDCService,
+		TeamService:                   params.TeamService,
+		ModelService:                  params.ModelService,
+		ConfigVersionService:          params.ConfigVersionService,
+		ConfigBackupService:           params.ConfigBackupService,
+		AdvisorService:                params.AdvisorService,
+		PlaygroundConversationService: params.PlaygroundConversationService,
+		StreamTranscriptService:       params.StreamTranscriptService,
+		KeyHealthService:              params.KeyHealthService,
+		DependencyCheckService:        params.DependencyCheckService,
+		RuntimeService:                params.RuntimeService,
+		ProxyServer:                   params.ProxyServer,
+		NotificationService:           params.NotificationService,
+		EncryptionRotator:             params.EncryptionRotator,
+		ModelRefresher:                params.ModelRefresher,
+		HTTPClientManager:             params.HTTPClientManager,
+		CommonHandler:                 params.CommonHandler,
+		EncryptionSvc:                 params.EncryptionSvc,
 	}
 }
 
-// LoginRequest represents the login request payload
+// LoginRequest represents the login request payload. An empty username logs in with AUTH_KEY as the
+// password; auth_key is still accepted for clients of the single-key login. TOTPCode is the TOTP or
+// recovery code of users with two-factor authentication.
 type LoginRequest struct {
-	AuthKey string `json:"auth_key" binding:"required"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	AuthKey  string `json:"auth_key"`
+	TOTPCode string `json:"totp_code"`
 }
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success      bool                `json:"success"`
+	Message      string              `json:"message"`
+	Token        string              `json:"token,omitempty"`
+	User         *services.Principal `json:"user,omitempty"`
+	TOTPRequired bool                `json:"totp_required,omitempty"`
 }
 
-// Login handles authentication verification
+// Login handles authentication verification, issuing the token to send as a bearer token
 func (s *Server) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Password == "" && req.AuthKey == "") {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": i18n.Message(c, "auth.invalid_request"),
@@ -99,16 +175,37 @@ func (s *Server) Login(c *gin.Context) {
 		return
 	}
 
-	authConfig := s.config.GetAuthConfig()
-
-	isValid := subtle.ConstantTimeCompare([]byte(req.AuthKey), []byte(authConfig.Key)) == 1
+	password := req.Password
+	if req.Username == "" && password == "" {
+		password = req.AuthKey
+	}
+	token, principal, err := s.UserService.Login(strings.TrimSpace(req.Username), password, req.TOTPCode)
 
-	if isValid {
+	var i18nErr *services.I18nError
+	switch {
+	case err == nil:
 		c.JSON(http.StatusOK, LoginResponse{
 			Success: true,
 			Message: i18n.Message(c, "auth.authentication_successful"),
+			Token:   token,
+			User:    principal,
+		})
+	case errors.Is(err, services.ErrTOTPRequired):
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Success:      false,
+			Message:      i18n.Message(c, "auth.two_factor_required"),
+			TOTPRequired: true,
 		})
-	} else {
+	case errors.As(err, &i18nErr):
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Success:      false,
+			Message:      i18n.Message(c, i18nErr.MessageID, i18nErr.Template),
+			TOTPRequired: true,
+		})
+	default:
+		if !errors.Is(err, services.ErrLoginFailed) {
+			logrus.Errorf("Login failed: %v", err)
+		}
 		c.JSON(http.StatusUnauthorized, LoginResponse{
 			Success: false,
 			Message: i18n.Message(c, "auth.authentication_failed"),
@@ -131,3 +228,31 @@ func (s *Server) Health(c *gin.Context) {
 		"uptime":    uptime,
 	})
 }
+
+// Healthz checks the database, Redis (when configured) and the encryption service, answering 503
+// with the status of each when one of them fails.
+func (s *Server) Healthz(c *gin.Context) {
+	report := s.DependencyCheckService.Health(c.Request.Context())
+	c.JSON(dependencyReportStatus(report), report)
+}
+
+// Readyz runs the health checks, also checks that every group in use has a resolvable upstream,
+// and fails while the server is draining on shutdown.
+func (s *Server) Readyz(c *gin.Context) {
+	report := s.DependencyCheckService.Readiness(c.Request.Context())
+	drain := services.DependencyCheck{Name: "proxy", Status: services.DependencyStatusOK}
+	if s.ProxyServer.Draining() {
+		drain.Status = services.DependencyStatusFailed
+		drain.Error = "draining in-flight requests for shutdown"
+		report.Status = services.DependencyStatusFailed
+	}
+	report.Checks = append(report.Checks, drain)
+	c.JSON(dependencyReportStatus(report), report)
+}
+
+func dependencyReportStatus(report *services.DependencyReport) int {
+	if report.Status == services.DependencyStatusFailed {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
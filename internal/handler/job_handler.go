@@ -0,0 +1,62 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListJobs handles GET /api/jobs?type=KEY_IMPORT&status=partial&group_name=x with pagination,
+// newest first.
+func (s *Server) ListJobs(c *gin.Context) {
+	query := s.JobService.Query(services.JobListParams{
+		Type:      c.Query("type"),
+		Status:    c.Query("status"),
+		GroupName: c.Query("group_name"),
+	}).Scopes(s.jobScope(c))
+
+	var jobs []models.Job
+	pagination, err := response.Paginate(c, query, &jobs)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, pagination)
+}
+
+// GetJob handles GET /api/jobs/:id, reporting the progress of a job and, once it has finished,
+// its result including the items that failed.
+func (s *Server) GetJob(c *gin.Context) {
+	job, err := s.JobService.Get(c.Param("id"))
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	// Jobs without a group, such as the encryption key rotation, are not shown to restricted requests.
+	if services.GroupScopeFromContext(c) != nil {
+		if job.GroupName == "" {
+			response.Error(c, app_errors.ErrResourceNotFound)
+			return
+		}
+		if !s.authorizeGroupName(c, job.GroupName) {
+			return
+		}
+	}
+	response.Success(c, job)
+}
+
+// jobScope limits requests restricted to some groups to the jobs of those groups.
+func (s *Server) jobScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		scope := services.GroupScopeFromContext(c)
+		if scope == nil {
+			return db
+		}
+		visible := s.DB.Model(&models.Group{}).Select("name").Scopes(scope.Groups)
+		return db.Where("group_name IN (?)", visible)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"log"
 	"strconv"
 	"strings"
@@ -45,7 +46,8 @@ func validateKeysText(c *gin.Context, keysText string) bool {
 	return true
 }
 
-// findGroupByID is a helper function to find a group by its ID.
+// findGroupByID is a helper function to find a group by its ID. Groups outside the request's
+// group scope are not found.
 func (s *Server) findGroupByID(c *gin.Context, groupID uint) (*models.Group, bool) {
 	var group models.Group
 	if err := s.DB.First(&group, groupID).Error; err != nil {
@@ -56,6 +58,10 @@ func (s *Server) findGroupByID(c *gin.Context, groupID uint) (*models.Group, boo
 		}
 		return nil, false
 	}
+	if !services.GroupScopeFromContext(c).Allows(&group) {
+		response.Error(c, app_errors.ErrResourceNotFound)
+		return nil, false
+	}
 	return &group, true
 }
 
@@ -63,6 +69,8 @@ func (s *Server) findGroupByID(c *gin.Context, groupID uint) (*models.Group, boo
 type KeyTextRequest struct {
 	GroupID  uint   `json:"group_id" binding:"required"`
 	KeysText string `json:"keys_text" binding:"required"`
+	// Transactional makes bulk add, delete and restore all-or-nothing instead of reporting partial results.
+	Transactional bool `json:"transactional"`
 }
 
 // GroupIDRequest defines a generic payload for operations requiring only a group ID.
@@ -92,7 +100,7 @@ func (s *Server) AddMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	result, err := s.KeyService.AddMultipleKeys(req.GroupID, req.KeysText)
+	result, err := s.KeyService.AddMultipleKeys(req.GroupID, req.KeysText, req.Transactional)
 	if err != nil {
 		if strings.Contains(err.Error(), "batch size exceeds the limit") {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
@@ -104,6 +112,10 @@ func (s *Server) AddMultipleKeys(c *gin.Context) {
 		return
 	}
 
+	if result.AddedCount > 0 {
+		s.ModelService.WarmUpModels(req.GroupID)
+	}
+
 	response.Success(c, result)
 }
 
@@ -124,7 +136,7 @@ func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
 		return
 	}
 
-	taskStatus, err := s.KeyImportService.StartImportTask(group, req.KeysText)
+	taskStatus, err := s.KeyImportService.StartImportTask(group, req.KeysText, req.Transactional)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
 		return
@@ -133,7 +145,24 @@ func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
 	response.Success(c, taskStatus)
 }
 
-// ListKeysInGroup handles listing all keys within a specific group with pagination.
+// keySortOptions are the orders of the key list selected with the sort parameter.
+var keySortOptions = response.SortOptions{
+	Fields: map[string]string{
+		"id":            "id",
+		"created_at":    "created_at",
+		"last_used_at":  "last_used_at",
+		"expires_at":    "expires_at",
+		"request_count": "request_count",
+		"failure_count": "failure_count",
+	},
+	Nullable:    map[string]bool{"last_used_at": true, "expires_at": true},
+	Default:     "created_at",
+	DefaultDesc: true,
+}
+
+// ListKeysInGroup handles listing all keys within a specific group with filtering (status,
+// key_value, tier, notes, expires_within_days), sorting (sort, order) and pagination, by page or
+// by cursor.
 func (s *Server) ListKeysInGroup(c *gin.Context) {
 	groupID, ok := validateGroupIDFromQuery(c)
 	if !ok {
@@ -151,32 +180,67 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 	}
 
 	searchKeyword := c.Query("key_value")
-	searchHash := ""
+	var searchHashes []string
 	if searchKeyword != "" {
-		searchHash = s.EncryptionSvc.Hash(searchKeyword)
+		searchHashes = s.EncryptionSvc.LookupHashes(searchKeyword)
 	}
 
-	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHash)
+	// expires_within_days lists keys expiring within that many days, including already expired ones.
+	var expiresBefore *time.Time
+	if days := c.Query("expires_within_days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "expires_within_days must be a non-negative integer"))
+			return
+		}
+		before := time.Now().AddDate(0, 0, n)
+		expiresBefore = &before
+	}
+
+	tierFilter := c.Query("tier")
+	if tierFilter != "" && !isValidKeyTier(tierFilter) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "tier must be primary or backup"))
+		return
+	}
+
+	// Without sort the default order of ListKeysInGroupQuery applies; cursors need an explicit sort.
+	_, sorted := c.GetQuery("sort")
+	var order response.Sort
+	if sorted || response.UseCursor(c) {
+		var ok bool
+		if order, ok = parseSort(c, keySortOptions); !ok {
+			return
+		}
+		sorted = true
+	}
+
+	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHashes, expiresBefore, sorted)
+	if tierFilter != "" {
+		query = query.Where("tier = ?", tierFilter)
+	}
+	if notes := c.Query("notes"); notes != "" {
+		query = query.Where("notes LIKE ?", "%"+notes+"%")
+	}
 
 	var keys []models.APIKey
-	paginatedResult, err := response.Paginate(c, query, &keys)
-	if err != nil {
-		response.Error(c, app_errors.ParseDBError(err))
+	paginatedResult, ok := paginate(c, query, &keys, order)
+	if !ok {
 		return
 	}
 
-	// Decrypt all keys for display
+	// Decrypt all keys for display; viewers only see them masked
+	showSecrets := canViewSecrets(c)
 	for i := range keys {
 		decryptedValue, err := s.EncryptionSvc.Decrypt(keys[i].KeyValue)
 		if err != nil {
 			logrus.WithError(err).WithField("key_id", keys[i].ID).Error("Failed to decrypt key value for listing")
 			keys[i].KeyValue = "failed-to-decrypt"
-		} else {
+		} else if showSecrets {
 			keys[i].KeyValue = decryptedValue
+		} else {
+			keys[i].KeyValue = maskSecret(decryptedValue)
 		}
 	}
-	paginatedResult.Items = keys
-
 	response.Success(c, paginatedResult)
 }
 
@@ -196,7 +260,7 @@ func (s *Server) DeleteMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	result, err := s.KeyService.DeleteMultipleKeys(req.GroupID, req.KeysText)
+	result, err := s.KeyService.DeleteMultipleKeys(req.GroupID, req.KeysText, req.Transactional)
 	if err != nil {
 		if strings.Contains(err.Error(), "batch size exceeds the limit") {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
@@ -228,7 +292,7 @@ func (s *Server) DeleteMultipleKeysAsync(c *gin.Context) {
 		return
 	}
 
-	taskStatus, err := s.KeyDeleteService.StartDeleteTask(group, req.KeysText)
+	taskStatus, err := s.KeyDeleteService.StartDeleteTask(group, req.KeysText, req.Transactional)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
 		return
@@ -253,7 +317,53 @@ func (s *Server) RestoreMultipleKeys(c *gin.Context) {
 		return
 	}
 
-	result, err := s.KeyService.RestoreMultipleKeys(req.GroupID, req.KeysText)
+	result, err := s.KeyService.RestoreMultipleKeys(req.GroupID, req.KeysText, req.Transactional)
+	if err != nil {
+		if strings.Contains(err.Error(), "batch size exceeds the limit") {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		} else if err.Error() == "no valid keys found in the input text" {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// SetKeysTierRequest defines the payload for moving keys from a text block to a tier.
+type SetKeysTierRequest struct {
+	KeyTextRequest
+	Tier string `json:"tier" binding:"required"`
+}
+
+// isValidKeyTier reports whether tier is one of the key tiers.
+func isValidKeyTier(tier string) bool {
+	return tier == models.KeyTierPrimary || tier == models.KeyTierBackup
+}
+
+// SetKeysTier handles moving keys from a text block to the primary or backup tier of their group.
+func (s *Server) SetKeysTier(c *gin.Context) {
+	var req SetKeysTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if !isValidKeyTier(req.Tier) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "tier must be primary or backup"))
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+		return
+	}
+
+	if !validateKeysText(c, req.KeysText) {
+		return
+	}
+
+	result, err := s.KeyService.SetKeysTier(req.GroupID, req.KeysText, req.Tier, req.Transactional)
 	if err != nil {
 		if strings.Contains(err.Error(), "batch size exceeds the limit") {
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
@@ -478,6 +588,9 @@ func (s *Server) UpdateKeyNotes(c *gin.Context) {
 		}
 		return
 	}
+	if !s.authorizeGroup(c, key.GroupID) {
+		return
+	}
 
 	// Update notes
 	if err := s.DB.Model(&key).Update("notes", req.Notes).Error; err != nil {
@@ -487,3 +600,173 @@ func (s *Server) UpdateKeyNotes(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// UpdateKeyExpiryRequest defines the payload for setting a key's expiry date; null clears it.
+type UpdateKeyExpiryRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// UpdateKeyExpiry handles setting or clearing the expiry date of a specific API key.
+func (s *Server) UpdateKeyExpiry(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+	if !s.authorizeGroup(c, key.GroupID) {
+		return
+	}
+
+	if err := s.KeyService.SetKeyExpiry(&key, req.ExpiresAt); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, gin.H{"expires_at": key.ExpiresAt, "status": key.Status})
+}
+
+// UpdateKeyLimitsRequest defines the payload for setting a key's own limits; 0 removes a limit.
+type UpdateKeyLimitsRequest struct {
+	RPMLimit        int     `json:"rpm_limit"`
+	TPMLimit        int     `json:"tpm_limit"`
+	DailySpendLimit float64 `json:"daily_spend_limit"`
+}
+
+// UpdateKeyLimits handles setting the rate and spend limits of a specific API key.
+func (s *Server) UpdateKeyLimits(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if req.RPMLimit < 0 || req.TPMLimit < 0 || req.DailySpendLimit < 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "limits must not be negative"))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+	if !s.authorizeGroup(c, key.GroupID) {
+		return
+	}
+
+	if err := s.KeyService.SetKeyLimits(&key, req.RPMLimit, req.TPMLimit, req.DailySpendLimit); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// UpdateKeyTierRequest defines the payload for moving a key to a tier.
+type UpdateKeyTierRequest struct {
+	Tier string `json:"tier" binding:"required"`
+}
+
+// UpdateKeyTier handles moving a specific API key to the primary or backup tier of its group.
+func (s *Server) UpdateKeyTier(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if !isValidKeyTier(req.Tier) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "tier must be primary or backup"))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+	if !s.authorizeGroup(c, key.GroupID) {
+		return
+	}
+
+	if err := s.KeyService.SetKeyTier(&key, req.Tier); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// GetKeyHistory handles GET /api/keys/:id/history, returning the key's validation results, proxy
+// failures and success rate over the last days (1-30, default 7).
+func (s *Server) GetKeyHistory(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	days := 7
+	if value := c.Query("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > 30 {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "days must be an integer between 1 and 30"))
+			return
+		}
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+	if !s.authorizeGroup(c, key.GroupID) {
+		return
+	}
+
+	history, err := s.KeyHealthService.History(&key, days)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, history)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// parseSort reads the sort and order query parameters of a list endpoint. It returns false if an
+// error response has been sent.
+func parseSort(c *gin.Context, options response.SortOptions) (response.Sort, bool) {
+	order, err := response.ParseSort(c, options)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return response.Sort{}, false
+	}
+	return order, true
+}
+
+// paginate pages a list query with a cursor when the request passes one and with page numbers
+// otherwise. The returned page refers to dest, so rows changed after the call are sent as
+// changed. It returns false if an error response has been sent.
+func paginate(c *gin.Context, query *gorm.DB, dest any, order response.Sort) (any, bool) {
+	if response.UseCursor(c) {
+		page, err := response.PaginateCursor(c, query, dest, order)
+		if errors.Is(err, response.ErrInvalidCursor) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return nil, false
+		}
+		if err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return nil, false
+		}
+		return page, true
+	}
+
+	page, err := response.Paginate(c, query.Scopes(order.Scope), dest)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return nil, false
+	}
+	return page, true
+}
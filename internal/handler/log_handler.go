@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"encoding/json"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"log"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,33 +22,50 @@ type LogResponse struct {
 	models.RequestLog
 }
 
-// GetLogs handles fetching request logs with filtering and pagination.
+// logSortOptions are the orders of the log list, newest first by default.
+var logSortOptions = response.SortOptions{
+	Fields: map[string]string{
+		"timestamp":    "timestamp",
+		"duration_ms":  "duration",
+		"status_code":  "status_code",
+		"total_tokens": "total_tokens",
+	},
+	Default:     "timestamp",
+	DefaultDesc: true,
+}
+
+// GetLogs handles fetching request logs with filtering, sorting (sort, order) and pagination,
+// by page or by cursor.
 func (s *Server) GetLogs(c *gin.Context) {
+	order, ok := parseSort(c, logSortOptions)
+	if !ok {
+		return
+	}
 	query := s.LogService.GetLogsQuery(c)
 
 	var logs []models.RequestLog
-	query = query.Order("timestamp desc")
-	pagination, err := response.Paginate(c, query, &logs)
-	if err != nil {
-		response.Error(c, app_errors.ParseDBError(err))
+	page, ok := paginate(c, query, &logs, order)
+	if !ok {
 		return
 	}
 
-	// 解密所有日志中的密钥用于前端显示
+	// 解密所有日志中的密钥用于前端显示，viewer 只能看到掩码后的密钥
+	showSecrets := canViewSecrets(c)
 	for i := range logs {
 		if logs[i].KeyValue != "" {
 			decryptedValue, err := s.EncryptionSvc.Decrypt(logs[i].KeyValue)
 			if err != nil {
 				logrus.WithError(err).WithField("log_id", logs[i].ID).Error("Failed to decrypt log key value")
 				logs[i].KeyValue = "failed-to-decrypt"
-			} else {
+			} else if showSecrets {
 				logs[i].KeyValue = decryptedValue
+			} else {
+				logs[i].KeyValue = maskSecret(decryptedValue)
 			}
 		}
 	}
 
-	pagination.Items = logs
-	response.Success(c, pagination)
+	response.Success(c, page)
 }
 
 // ExportLogs handles exporting filtered log keys to a CSV file.
@@ -54,10 +75,208 @@ func (s *Server) ExportLogs(c *gin.Context) {
 	c.Header("Content-Type", "text/csv; charset=utf-8")
 
 	// Stream the response
-	err := s.LogService.StreamLogKeysToCSV(c, c.Writer)
+	var maskKey func(string) string
+	if !canViewSecrets(c) {
+		maskKey = maskSecret
+	}
+	err := s.LogService.StreamLogKeysToCSV(c, c.Writer, maskKey)
 	if err != nil {
 		log.Printf("Failed to stream log keys to CSV: %v", err)
 		c.JSON(500, gin.H{"error": i18n.Message(c, "error.export_logs")})
 		return
 	}
 }
+
+// ExportUsage handles exporting the token usage of filtered requests, with proxy key metadata, to a CSV file.
+func (s *Server) ExportUsage(c *gin.Context) {
+	filename := fmt.Sprintf("usage_export_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+
+	if err := s.LogService.StreamUsageToCSV(c, c.Writer); err != nil {
+		log.Printf("Failed to stream usage to CSV: %v", err)
+		c.JSON(500, gin.H{"error": i18n.Message(c, "error.export_logs")})
+		return
+	}
+}
+
+// GetRequestTrace reveals which keys and upstreams served a request, looked up either by
+// request_id or by group_name + timestamp (RFC3339) with an optional window_seconds (default 5).
+func (s *Server) GetRequestTrace(c *gin.Context) {
+	logrus.WithFields(logrus.Fields{
+		"client_ip":  c.ClientIP(),
+		"request_id": c.Query("request_id"),
+		"group_name": c.Query("group_name"),
+		"timestamp":  c.Query("timestamp"),
+	}).Info("Request trace lookup")
+
+	if requestID := c.Query("request_id"); requestID != "" {
+		trace, err := s.LogService.TraceRequestByID(requestID)
+		if err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return
+		}
+		if !s.authorizeGroupName(c, trace.GroupName) {
+			return
+		}
+		traces := []*services.RequestTrace{trace}
+		maskTraceKeys(c, traces)
+		response.Success(c, traces)
+		return
+	}
+
+	groupName := c.Query("group_name")
+	timestamp, err := time.Parse(time.RFC3339, c.Query("timestamp"))
+	if groupName == "" || err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.trace_params_required")
+		return
+	}
+
+	if !s.authorizeGroupName(c, groupName) {
+		return
+	}
+
+	window := 5 * time.Second
+	if windowStr := c.Query("window_seconds"); windowStr != "" {
+		seconds, err := strconv.Atoi(windowStr)
+		if err != nil || seconds < 0 || seconds > 3600 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_trace_window")
+			return
+		}
+		window = time.Duration(seconds) * time.Second
+	}
+
+	traces, err := s.LogService.TraceRequestsAt(groupName, timestamp, window, 50)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	maskTraceKeys(c, traces)
+	response.Success(c, traces)
+}
+
+// maskTraceKeys masks the keys of trace attempts for callers who may not view secrets.
+func maskTraceKeys(c *gin.Context, traces []*services.RequestTrace) {
+	if canViewSecrets(c) {
+		return
+	}
+	for _, trace := range traces {
+		for i := range trace.Attempts {
+			if key := trace.Attempts[i].KeyValue; key != "" && key != "failed-to-decrypt" {
+				trace.Attempts[i].KeyValue = maskSecret(key)
+			}
+		}
+	}
+}
+
+// ListStreamTranscripts handles GET /api/logs/transcripts, listing captured streaming transcripts
+// without their content. It accepts optional group_name and request_id filters.
+func (s *Server) ListStreamTranscripts(c *gin.Context) {
+	var transcripts []models.StreamTranscript
+	query := s.StreamTranscriptService.Query(c.Query("group_name"), c.Query("request_id")).
+		Scopes(services.GroupScopeFromContext(c).ByGroupID("group_id"))
+	pagination, err := response.Paginate(c, query, &transcripts)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, pagination)
+}
+
+// GetStreamTranscript handles GET /api/logs/transcripts/:id, returning the decrypted request and
+// assembled response of a captured stream.
+func (s *Server) GetStreamTranscript(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_transcript_id")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"client_ip":     c.ClientIP(),
+		"transcript_id": id,
+	}).Info("Stream transcript viewed")
+
+	transcript, err := s.StreamTranscriptService.Get(uint(id))
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	if !s.authorizeGroup(c, transcript.GroupID) {
+		return
+	}
+	response.Success(c, transcript)
+}
+
+// logStreamHeartbeat keeps idle streams from being closed by proxies in front of the server.
+const logStreamHeartbeat = 15 * time.Second
+
+// StreamLogs handles GET /api/logs/stream, pushing request logs as server-sent events as they are
+// recorded. It accepts optional parent_group_name, group_name, model, request_type and status_class
+// (e.g. 4xx) filters, matched like the log list filters.
+func (s *Server) StreamLogs(c *gin.Context) {
+	filter := services.LogStreamFilter{
+		ParentGroupName: c.Query("parent_group_name"),
+		GroupName:       c.Query("group_name"),
+		Model:           c.Query("model"),
+		RequestType:     c.Query("request_type"),
+	}
+	statusClass, ok := services.ParseStatusClass(c.Query("status_class"))
+	if !ok {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_status_class")
+		return
+	}
+	filter.StatusClass = statusClass
+	if scope := services.GroupScopeFromContext(c); scope != nil {
+		var groupIDs []uint
+		if err := s.DB.Model(&models.Group{}).Scopes(scope.Groups).Pluck("id", &groupIDs).Error; err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return
+		}
+		filter.GroupIDs = make(map[uint]bool, len(groupIDs))
+		for _, id := range groupIDs {
+			filter.GroupIDs[id] = true
+		}
+	}
+
+	logs, err := s.LogStreamService.Subscribe(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+
+	// The stream lives longer than the server's write timeout allows for regular responses
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logrus.WithError(err).Debug("Failed to clear write deadline for log stream")
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	showSecrets := canViewSecrets(c)
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case entry, ok := <-logs:
+			if !ok {
+				return
+			}
+			if !showSecrets && entry.KeyValue != "" && entry.KeyValue != "failed-to-decrypt" {
+				entry.KeyValue = maskSecret(entry.KeyValue)
+			}
+			data, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
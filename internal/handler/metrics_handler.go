@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"slices"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetMetricsSnapshot handles GET /api/metrics/snapshot?group=a,b&prefix=gpt_load_.
+// It returns the metrics served on /metrics as JSON for clients that cannot scrape Prometheus.
+// Callers restricted to some teams only get the series of their groups.
+func (s *Server) GetMetricsSnapshot(c *gin.Context) {
+	var groups []string
+	for _, value := range c.QueryArray("group") {
+		for _, group := range strings.Split(value, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	if scope := services.GroupScopeFromContext(c); scope != nil {
+		var visible []string
+		if err := s.DB.Model(&models.Group{}).Scopes(scope.Groups).Pluck("name", &visible).Error; err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return
+		}
+		scoped := make([]string, 0, len(visible))
+		for _, name := range visible {
+			if groups == nil || slices.Contains(groups, name) {
+				scoped = append(scoped, name)
+			}
+		}
+		groups = scoped
+	}
+
+	snapshot, err := prometheus.TakeSnapshot(prometheus.SnapshotOptions{
+		Groups: groups,
+		Prefix: c.Query("prefix"),
+	})
+	if err != nil {
+		logrus.Errorf("Failed to take metrics snapshot: %v", err)
+		response.ErrorI18nFromAPIError(c, app_errors.ErrInternalServer, "metrics.snapshot_failed")
+		return
+	}
+	response.Success(c, snapshot)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MarkNotificationsReadRequest defines the payload for marking notifications as read.
+type MarkNotificationsReadRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// ListNotifications handles GET /api/notifications?unread=true&category=key&severity=warning with pagination.
+func (s *Server) ListNotifications(c *gin.Context) {
+	query := s.NotificationService.Query(notification.ListParams{
+		Category:   c.Query("category"),
+		Severity:   c.Query("severity"),
+		UnreadOnly: c.Query("unread") == "true",
+	})
+
+	var notifications []models.Notification
+	pagination, err := response.Paginate(c, query, &notifications)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, pagination)
+}
+
+// GetUnreadNotificationCount handles GET /api/notifications/unread-count for the bell badge.
+func (s *Server) GetUnreadNotificationCount(c *gin.Context) {
+	counts, err := s.NotificationService.UnreadCounts()
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, counts)
+}
+
+// MarkNotificationsRead handles POST /api/notifications/read.
+func (s *Server) MarkNotificationsRead(c *gin.Context) {
+	var req MarkNotificationsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	updated, err := s.NotificationService.MarkRead(req.IDs)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, gin.H{"updated": updated})
+}
+
+// MarkAllNotificationsRead handles POST /api/notifications/read-all.
+func (s *Server) MarkAllNotificationsRead(c *gin.Context) {
+	updated, err := s.NotificationService.MarkAllRead()
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, gin.H{"updated": updated})
+}
+
+// DeleteNotification handles DELETE /api/notifications/:id.
+func (s *Server) DeleteNotification(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_notification_id")
+		return
+	}
+
+	if err := s.NotificationService.Delete(uint(id)); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ClearReadNotifications handles DELETE /api/notifications/read, removing every read notification.
+func (s *Server) ClearReadNotifications(c *gin.Context) {
+	deleted, err := s.NotificationService.DeleteRead()
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, gin.H{"deleted": deleted})
+}
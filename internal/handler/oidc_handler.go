@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"gpt-load/internal/i18n"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// oidcLoginPage is the web page that finishes a single sign-on login. The result is passed in the
+// URL fragment, which browsers never send to servers or in Referer headers.
+const oidcLoginPage = "/login"
+
+// GetOIDCConfig handles GET /api/auth/oidc/config, telling the login page whether to offer single
+// sign-on.
+func (s *Server) GetOIDCConfig(c *gin.Context) {
+	response.Success(c, gin.H{"enabled": s.OIDCService.Enabled()})
+}
+
+// OIDCLogin handles GET /api/auth/oidc/login, redirecting the browser to the identity provider.
+func (s *Server) OIDCLogin(c *gin.Context) {
+	authURL, err := s.OIDCService.AuthorizationURL(c.Request.Context())
+	if err != nil {
+		s.redirectOIDCError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback handles GET /api/auth/oidc/callback, where the identity provider sends the browser
+// back after the user signed in.
+func (s *Server) OIDCCallback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		message := c.Query("error_description")
+		if message == "" {
+			message = providerErr
+		}
+		redirectOIDCResult(c, url.Values{"sso_error": {message}})
+		return
+	}
+
+	token, principal, err := s.OIDCService.Callback(c.Request.Context(), c.Query("code"), c.Query("state"))
+	if err != nil {
+		s.redirectOIDCError(c, err)
+		return
+	}
+	logrus.Infof("User %s signed in through OIDC as %s", principal.Username, principal.Role)
+	redirectOIDCResult(c, url.Values{"sso_token": {token}})
+}
+
+// redirectOIDCError sends the browser back to the login page with a translated error.
+func (s *Server) redirectOIDCError(c *gin.Context, err error) {
+	var i18nErr *services.I18nError
+	message := i18n.Message(c, "auth.oidc_failed")
+	if errors.As(err, &i18nErr) {
+		message = i18n.Message(c, i18nErr.MessageID, i18nErr.Template)
+	} else {
+		logrus.Warnf("OIDC login failed: %v", err)
+	}
+	redirectOIDCResult(c, url.Values{"sso_error": {message}})
+}
+
+func redirectOIDCResult(c *gin.Context, values url.Values) {
+	c.Redirect(http.StatusFound, oidcLoginPage+"#"+values.Encode())
+}
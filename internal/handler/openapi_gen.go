@@ -0,0 +1,593 @@
+// Code generated by openapi-gen from the handler annotations. DO NOT EDIT.
+
+package handler
+
+import (
+	"reflect"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/openapi"
+)
+
+// openAPIOperations describes the admin API handlers, keyed by receiver and method name.
+var openAPIOperations = map[string]openapi.Operation{
+	"CommonHandler.GetChannelPresets": {
+		Summary:     "Get channel presets",
+		Description: "GetChannelPresets returns the quick-add provider presets for the group form.",
+	},
+	"CommonHandler.GetChannelTypes": {
+		Summary:     "Get channel types",
+		Description: "GetChannelTypes returns a list of available channel types.",
+	},
+	"Server.AddMultipleKeys": {
+		Summary:     "Add multiple keys",
+		Description: "AddMultipleKeys handles creating new keys from a text block within a specific group.",
+		Body:        reflect.TypeFor[KeyTextRequest](),
+	},
+	"Server.AddMultipleKeysAsync": {
+		Summary:     "Add multiple keys async",
+		Description: "AddMultipleKeysAsync handles creating new keys from a text block within a specific group.",
+		Body:        reflect.TypeFor[KeyTextRequest](),
+	},
+	"Server.AddSubGroups": {
+		Summary:     "Add sub groups",
+		Description: "AddSubGroups handles adding sub groups to an aggregate group",
+		Body:        reflect.TypeFor[AddSubGroupsRequest](),
+	},
+	"Server.AssignGroupTeam": {
+		Summary:     "Assign group team",
+		Description: "AssignGroupTeam handles PUT /api/groups/:id/team.",
+		Body:        reflect.TypeFor[GroupTeamRequest](),
+	},
+	"Server.BulkUpdateGroupConfig": {
+		Summary:     "Bulk update group config",
+		Description: "BulkUpdateGroupConfig handles PUT /api/groups/bulk-config with per-group results.",
+		Body:        reflect.TypeFor[BulkGroupConfigRequest](),
+	},
+	"Server.BulkUpdateModels": {
+		Summary:     "Bulk update models",
+		Description: "BulkUpdateModels handles PUT /api/models/group/:groupId/bulk, applying the same capabilities to the selected models of a group in one transaction.",
+		Body:        reflect.TypeFor[BulkUpdateModelsRequest](),
+	},
+	"Server.ChangePassword": {
+		Summary:     "Change password",
+		Description: "ChangePassword handles PUT /api/auth/password for the signed-in user.",
+		Body:        reflect.TypeFor[ChangePasswordRequest](),
+	},
+	"Server.Chart": {
+		Summary:     "Chart",
+		Description: "Chart Get dashboard chart data",
+		QueryParams: []string{"groupId"},
+	},
+	"Server.CheckGroupProxy": {
+		Summary:     "Check group proxy",
+		Description: "CheckGroupProxy handles checking that the upstreams of a group can be reached through its outbound proxy",
+	},
+	"Server.ClearAllInvalidKeys": {
+		Summary:     "Clear all invalid keys",
+		Description: "ClearAllInvalidKeys deletes all 'inactive' keys from a group.",
+		Body:        reflect.TypeFor[GroupIDRequest](),
+	},
+	"Server.ClearAllKeys": {
+		Summary:     "Clear all keys",
+		Description: "ClearAllKeys deletes all keys from a group.",
+		Body:        reflect.TypeFor[GroupIDRequest](),
+	},
+	"Server.ClearReadNotifications": {
+		Summary:     "Clear read notifications",
+		Description: "ClearReadNotifications handles DELETE /api/notifications/read, removing every read notification.",
+	},
+	"Server.CopyGroup": {
+		Summary: "Copy group",
+		Body:    reflect.TypeFor[GroupCopyRequest](),
+	},
+	"Server.CreateAlertRule": {
+		Summary:     "Create alert rule",
+		Description: "CreateAlertRule handles POST /api/alerts.",
+		Body:        reflect.TypeFor[AlertRuleRequest](),
+	},
+	"Server.CreateGroup": {
+		Summary:     "Create group",
+		Description: "CreateGroup handles the creation of a new group.",
+		Body:        reflect.TypeFor[GroupCreateRequest](),
+	},
+	"Server.CreateModel": {
+		Summary:     "Create model",
+		Description: "CreateModel handles POST /api/models/group/:groupId, adding a model by hand.",
+		Body:        reflect.TypeFor[CreateModelRequest](),
+	},
+	"Server.CreatePlaygroundConversation": {
+		Summary:     "Create playground conversation",
+		Description: "CreatePlaygroundConversation handles POST /api/playground/conversations.",
+	},
+	"Server.CreateSandboxKey": {
+		Summary:     "Create sandbox key",
+		Description: "CreateSandboxKey handles generating a proxy key for a group that expires after ttl_hours.",
+		Body:        reflect.TypeFor[SandboxKeyRequest](),
+	},
+	"Server.CreateTeam": {
+		Summary:     "Create team",
+		Description: "CreateTeam handles POST /api/teams.",
+		Body:        reflect.TypeFor[TeamRequest](),
+	},
+	"Server.CreateUser": {
+		Summary:     "Create user",
+		Description: "CreateUser handles POST /api/users.",
+		Body:        reflect.TypeFor[UserRequest](),
+	},
+	"Server.CreateWebhook": {
+		Summary:     "Create webhook",
+		Description: "CreateWebhook handles POST /api/webhooks.",
+		Body:        reflect.TypeFor[WebhookSubscriptionRequest](),
+	},
+	"Server.DeleteAlertRule": {
+		Summary:     "Delete alert rule",
+		Description: "DeleteAlertRule handles DELETE /api/alerts/:id.",
+	},
+	"Server.DeleteGroup": {
+		Summary:     "Delete group",
+		Description: "DeleteGroup handles deleting a group.",
+	},
+	"Server.DeleteIPAccess": {
+		Summary:     "Delete IP access",
+		Description: "DeleteIPAccess handles removing every client address restriction of a group",
+	},
+	"Server.DeleteModel": {
+		Summary:     "Delete model",
+		Description: "DeleteModel handles deleting a model",
+	},
+	"Server.DeleteModelAccess": {
+		Summary:     "Delete model access",
+		Description: "DeleteModelAccess handles removing every model restriction of a group",
+	},
+	"Server.DeleteMultipleKeys": {
+		Summary:     "Delete multiple keys",
+		Description: "DeleteMultipleKeys handles deleting keys from a text block within a specific group.",
+		Body:        reflect.TypeFor[KeyTextRequest](),
+	},
+	"Server.DeleteMultipleKeysAsync": {
+		Summary:     "Delete multiple keys async",
+		Description: "DeleteMultipleKeysAsync handles deleting keys from a text block within a specific group using async task.",
+		Body:        reflect.TypeFor[KeyTextRequest](),
+	},
+	"Server.DeleteNotification": {
+		Summary:     "Delete notification",
+		Description: "DeleteNotification handles DELETE /api/notifications/:id.",
+	},
+	"Server.DeletePlaygroundConversation": {
+		Summary:     "Delete playground conversation",
+		Description: "DeletePlaygroundConversation handles DELETE /api/playground/conversations/:id.",
+	},
+	"Server.DeleteSubGroup": {
+		Summary:     "Delete sub group",
+		Description: "DeleteSubGroup handles deleting a sub group from an aggregate group",
+	},
+	"Server.DeleteTeam": {
+		Summary:     "Delete team",
+		Description: "DeleteTeam handles DELETE /api/teams/:id.",
+	},
+	"Server.DeleteUser": {
+		Summary:     "Delete user",
+		Description: "DeleteUser handles DELETE /api/users/:id.",
+	},
+	"Server.DeleteWebhook": {
+		Summary:     "Delete webhook",
+		Description: "DeleteWebhook handles DELETE /api/webhooks/:id.",
+	},
+	"Server.DiffConfigVersions": {
+		Summary:     "Diff config versions",
+		Description: "DiffConfigVersions handles GET /api/config-versions/:id/diff?against=<id>.",
+		QueryParams: []string{"against"},
+	},
+	"Server.DisableTOTP": {
+		Summary:     "Disable TOTP",
+		Description: "DisableTOTP handles POST /api/auth/2fa/disable for the signed-in user.",
+		Body:        reflect.TypeFor[TOTPCodeRequest](),
+	},
+	"Server.EnableTOTP": {
+		Summary:     "Enable TOTP",
+		Description: "EnableTOTP handles POST /api/auth/2fa/enable, confirming enrollment and returning the recovery codes.",
+		Body:        reflect.TypeFor[TOTPCodeRequest](),
+	},
+	"Server.EncryptionStatus": {
+		Summary:     "Encryption status",
+		Description: "EncryptionStatus checks if ENCRYPTION_KEY is configured but keys are not encrypted",
+	},
+	"Server.ExportConfig": {
+		Summary:     "Export config",
+		Description: "ExportConfig handles POST /api/backup/export, downloading the configuration as a JSON bundle.",
+		Body:        reflect.TypeFor[ConfigExportRequest](),
+	},
+	"Server.ExportKeys": {
+		Summary:     "Export keys",
+		Description: "ExportKeys handles exporting keys to a text file.",
+		QueryParams: []string{"status", "group_id"},
+	},
+	"Server.ExportLogs": {
+		Summary:     "Export logs",
+		Description: "ExportLogs handles exporting filtered log keys to a CSV file.",
+	},
+	"Server.ExportUsage": {
+		Summary:     "Export usage",
+		Description: "ExportUsage handles exporting the token usage of filtered requests, with proxy key metadata, to a CSV file.",
+	},
+	"Server.FetchModels": {
+		Summary:     "Fetch models",
+		Description: "FetchModels handles fetching models from the provider",
+		Body:        reflect.TypeFor[FetchModelsRequest](),
+	},
+	"Server.GetAdvisorReport": {
+		Summary:     "Get advisor report",
+		Description: "GetAdvisorReport handles GET /api/admin/advisor. It inspects the current groups and settings and reports actionable findings ordered by severity. Callers restricted to some teams only get the findings of their groups and the system settings.",
+	},
+	"Server.GetConfigVersion": {
+		Summary:     "Get config version",
+		Description: "GetConfigVersion handles GET /api/config-versions/:id, returning the version and its changes from the previous one.",
+	},
+	"Server.GetCurrentUser": {
+		Summary:     "Get current user",
+		Description: "GetCurrentUser handles GET /api/auth/me, returning who the request is authenticated as.",
+	},
+	"Server.GetEncryptionRotation": {
+		Summary:     "Get encryption rotation",
+		Description: "GetEncryptionRotation handles requests for the progress of the encryption key rotation.",
+	},
+	"Server.GetGroupConfigOptions": {
+		Summary:     "Get group config options",
+		Description: "GetGroupConfigOptions returns a list of available configuration options for groups.",
+	},
+	"Server.GetGroupStats": {
+		Summary:     "Get group stats",
+		Description: "calculateRequestStats is a helper to compute request statistics.",
+	},
+	"Server.GetIPAccess": {
+		Summary:     "Get IP access",
+		Description: "GetIPAccess handles reading the client address allowlist and denylist of a group",
+	},
+	"Server.GetIntegrationInfo": {
+		Summary:     "Get integration info",
+		Description: "GetIntegrationInfo handles the integration info request",
+		QueryParams: []string{"key"},
+	},
+	"Server.GetJob": {
+		Summary:     "Get job",
+		Description: "GetJob handles GET /api/jobs/:id, reporting the progress of a job and, once it has finished, its result including the items that failed.",
+	},
+	"Server.GetKeyHistory": {
+		Summary:     "Get key history",
+		Description: "GetKeyHistory handles GET /api/keys/:id/history, returning the key's validation results, proxy failures and success rate over the last days (1-30, default 7).",
+		QueryParams: []string{"days"},
+	},
+	"Server.GetLogs": {
+		Summary:     "Get logs",
+		Description: "GetLogs handles fetching request logs with filtering, sorting (sort, order) and pagination, by page or by cursor.",
+		QueryParams: []string{"page", "page_size"},
+	},
+	"Server.GetMetricsSnapshot": {
+		Summary:     "Get metrics snapshot",
+		Description: "GetMetricsSnapshot handles GET /api/metrics/snapshot?group=a,b&prefix=gpt_load_. It returns the metrics served on /metrics as JSON for clients that cannot scrape Prometheus. Callers restricted to some teams only get the series of their groups.",
+		QueryParams: []string{"group", "prefix"},
+	},
+	"Server.GetModel": {
+		Summary:     "Get model",
+		Description: "GetModel handles retrieving a specific model",
+	},
+	"Server.GetModelAccess": {
+		Summary:     "Get model access",
+		Description: "GetModelAccess handles reading the model allowlist and denylist of a group",
+	},
+	"Server.GetOIDCConfig": {
+		Summary:     "Get OIDC config",
+		Description: "GetOIDCConfig handles GET /api/auth/oidc/config, telling the login page whether to offer single sign-on.",
+	},
+	"Server.GetParentAggregateGroups": {
+		Summary:     "Get parent aggregate groups",
+		Description: "GetParentAggregateGroups handles getting parent aggregate groups that reference a group",
+	},
+	"Server.GetPlaygroundConversation": {
+		Summary:     "Get playground conversation",
+		Description: "GetPlaygroundConversation handles GET /api/playground/conversations/:id, returning the conversation with its messages so that the playground can resume it.",
+	},
+	"Server.GetRequestTrace": {
+		Summary:     "Get request trace",
+		Description: "GetRequestTrace reveals which keys and upstreams served a request, looked up either by request_id or by group_name + timestamp (RFC3339) with an optional window_seconds (default 5).",
+		QueryParams: []string{"request_id", "group_name", "timestamp", "window_seconds"},
+	},
+	"Server.GetRuntimeInfo": {
+		Summary:     "Get runtime info",
+		Description: "GetRuntimeInfo handles GET /api/system/runtime. It reports goroutines, heap and GC statistics, HTTP connections and build information.",
+	},
+	"Server.GetSettings": {
+		Summary:     "Get settings",
+		Description: "GetSettings handles the GET /api/settings request. It retrieves all system settings, groups them by category, and returns them.",
+	},
+	"Server.GetStreamTranscript": {
+		Summary:     "Get stream transcript",
+		Description: "GetStreamTranscript handles GET /api/logs/transcripts/:id, returning the decrypted request and assembled response of a captured stream.",
+	},
+	"Server.GetSubGroups": {
+		Summary:     "Get sub groups",
+		Description: "GetSubGroups handles getting sub groups of an aggregate group",
+	},
+	"Server.GetTaskStatus": {
+		Summary:     "Get task status",
+		Description: "GetTaskStatus handles requests for the status of the global long-running task.",
+	},
+	"Server.GetUnreadNotificationCount": {
+		Summary:     "Get unread notification count",
+		Description: "GetUnreadNotificationCount handles GET /api/notifications/unread-count for the bell badge.",
+	},
+	"Server.Health": {
+		Summary:     "Health",
+		Description: "Health handles health check requests",
+	},
+	"Server.Healthz": {
+		Summary:     "Healthz",
+		Description: "Healthz checks the database, Redis (when configured) and the encryption service, answering 503 with the status of each when one of them fails.",
+	},
+	"Server.ImportConfig": {
+		Summary:     "Import config",
+		Description: "ImportConfig handles POST /api/backup/import, applying a bundle produced by ExportConfig.",
+		Body:        reflect.TypeFor[ConfigImportRequest](),
+	},
+	"Server.List": {
+		Summary:     "List",
+		Description: "List godoc",
+	},
+	"Server.ListAlertRules": {
+		Summary:     "List alert rules",
+		Description: "ListAlertRules handles GET /api/alerts.",
+	},
+	"Server.ListConfigVersions": {
+		Summary:     "List config versions",
+		Description: "ListConfigVersions handles GET /api/config-versions?resource_type=group&resource_id=1.",
+		QueryParams: []string{"resource_type", "resource_id"},
+	},
+	"Server.ListGroups": {
+		Summary:     "List groups",
+		Description: "ListGroups handles listing all groups.",
+	},
+	"Server.ListJobs": {
+		Summary:     "List jobs",
+		Description: "ListJobs handles GET /api/jobs?type=KEY_IMPORT&status=partial&group_name=x with pagination, newest first.",
+		QueryParams: []string{"type", "status", "group_name", "page", "page_size"},
+	},
+	"Server.ListKeysInGroup": {
+		Summary:     "List keys in group",
+		Description: "ListKeysInGroup handles listing all keys within a specific group with filtering (status, key_value, tier, notes, expires_within_days), sorting (sort, order) and pagination, by page or by cursor.",
+		QueryParams: []string{"status", "key_value", "expires_within_days", "tier", "sort", "notes", "group_id", "page", "page_size"},
+	},
+	"Server.ListModelAliases": {
+		Summary:     "List model aliases",
+		Description: "ListModelAliases handles listing the model aliases of a group",
+	},
+	"Server.ListModels": {
+		Summary:     "List models",
+		Description: "ListModels handles listing the models of a group, filtered by q (model ID or name contains) and capability flags and sorted with sort and order. With page, page_size or cursor the result is paginated; otherwise every matching model is returned.",
+		QueryParams: []string{"q", "page", "page_size"},
+	},
+	"Server.ListNotifications": {
+		Summary:     "List notifications",
+		Description: "ListNotifications handles GET /api/notifications?unread=true&category=key&severity=warning with pagination.",
+		QueryParams: []string{"category", "severity", "unread", "page", "page_size"},
+	},
+	"Server.ListPlaygroundConversations": {
+		Summary:     "List playground conversations",
+		Description: "ListPlaygroundConversations handles GET /api/playground/conversations with pagination.",
+		QueryParams: []string{"page", "page_size"},
+	},
+	"Server.ListStreamTranscripts": {
+		Summary:     "List stream transcripts",
+		Description: "ListStreamTranscripts handles GET /api/logs/transcripts, listing captured streaming transcripts without their content. It accepts optional group_name and request_id filters.",
+		QueryParams: []string{"group_name", "request_id", "page", "page_size"},
+	},
+	"Server.ListTeams": {
+		Summary:     "List teams",
+		Description: "ListTeams handles GET /api/teams.",
+	},
+	"Server.ListUsers": {
+		Summary:     "List users",
+		Description: "ListUsers handles GET /api/users.",
+	},
+	"Server.ListWebhooks": {
+		Summary:     "List webhooks",
+		Description: "ListWebhooks handles GET /api/webhooks.",
+	},
+	"Server.Login": {
+		Summary:     "Login",
+		Description: "Login handles authentication verification, issuing the token to send as a bearer token",
+		Body:        reflect.TypeFor[LoginRequest](),
+	},
+	"Server.Logout": {
+		Summary:     "Logout",
+		Description: "Logout handles POST /api/auth/logout, ending the session of the request's token.",
+	},
+	"Server.MarkAllNotificationsRead": {
+		Summary:     "Mark all notifications read",
+		Description: "MarkAllNotificationsRead handles POST /api/notifications/read-all.",
+	},
+	"Server.MarkNotificationsRead": {
+		Summary:     "Mark notifications read",
+		Description: "MarkNotificationsRead handles POST /api/notifications/read.",
+		Body:        reflect.TypeFor[MarkNotificationsReadRequest](),
+	},
+	"Server.OIDCCallback": {
+		Summary:     "OIDC callback",
+		Description: "OIDCCallback handles GET /api/auth/oidc/callback, where the identity provider sends the browser back after the user signed in.",
+		QueryParams: []string{"error", "error_description", "code", "state"},
+	},
+	"Server.OIDCLogin": {
+		Summary:     "OIDC login",
+		Description: "OIDCLogin handles GET /api/auth/oidc/login, redirecting the browser to the identity provider.",
+	},
+	"Server.PatchGroup": {
+		Summary:     "Patch group",
+		Description: "PatchGroup handles PATCH /api/groups/:id with optimistic locking.",
+		Body:        reflect.TypeFor[GroupPatchRequest](),
+	},
+	"Server.PlaygroundChat": {
+		Summary:     "Playground chat",
+		Description: "PlaygroundChat handles chat requests from the playground",
+		Body:        reflect.TypeFor[PlaygroundChatRequest](),
+	},
+	"Server.PlaygroundEmbeddings": {
+		Summary:     "Playground embeddings",
+		Description: "PlaygroundEmbeddings handles embeddings requests from the playground",
+		Body:        reflect.TypeFor[PlaygroundEmbeddingsRequest](),
+	},
+	"Server.Readyz": {
+		Summary:     "Readyz",
+		Description: "Readyz runs the health checks, also checks that every group in use has a resolvable upstream, and fails while the server is draining on shutdown.",
+	},
+	"Server.RefreshModels": {
+		Summary:     "Refresh models",
+		Description: "RefreshModels handles refreshing stale models for a group",
+		QueryParams: []string{"stale_hours"},
+	},
+	"Server.RefreshModelsAsync": {
+		Summary:     "Refresh models async",
+		Description: "RefreshModelsAsync handles POST /api/models/group/:groupId/refresh-async. It syncs the group's model list with the provider in the background, adding and removing auto-fetched models, and returns the job to poll at /api/jobs/:id.",
+	},
+	"Server.ResetUserTOTP": {
+		Summary:     "Reset user TOTP",
+		Description: "ResetUserTOTP handles DELETE /api/users/:id/2fa, removing the second factor of a user who lost it.",
+	},
+	"Server.RestoreAllInvalidKeys": {
+		Summary:     "Restore all invalid keys",
+		Description: "RestoreAllInvalidKeys sets the status of all 'inactive' keys in a group to 'active'.",
+		Body:        reflect.TypeFor[GroupIDRequest](),
+	},
+	"Server.RestoreMultipleKeys": {
+		Summary:     "Restore multiple keys",
+		Description: "RestoreMultipleKeys handles restoring keys from a text block within a specific group.",
+		Body:        reflect.TypeFor[KeyTextRequest](),
+	},
+	"Server.RollbackConfigVersion": {
+		Summary:     "Rollback config version",
+		Description: "RollbackConfigVersion handles POST /api/config-versions/:id/rollback, restoring the stored state.",
+	},
+	"Server.SetKeysTier": {
+		Summary:     "Set keys tier",
+		Description: "SetKeysTier handles moving keys from a text block to the primary or backup tier of their group.",
+		Body:        reflect.TypeFor[SetKeysTierRequest](),
+	},
+	"Server.SetupTOTP": {
+		Summary:     "Setup TOTP",
+		Description: "SetupTOTP handles POST /api/auth/2fa/setup, returning a new secret for the authenticator app.",
+	},
+	"Server.StartEncryptionRotation": {
+		Summary:     "Start encryption rotation",
+		Description: "StartEncryptionRotation handles starting or resuming the re-encryption of stored keys with the current encryption key version.",
+	},
+	"Server.Stats": {
+		Summary:     "Stats",
+		Description: "Stats Get dashboard statistics",
+	},
+	"Server.StreamLogs": {
+		Summary:     "Stream logs",
+		Description: "StreamLogs handles GET /api/logs/stream, pushing request logs as server-sent events as they are recorded. It accepts optional parent_group_name, group_name, model, request_type and status_class (e.g. 4xx) filters, matched like the log list filters.",
+		QueryParams: []string{"parent_group_name", "group_name", "model", "request_type", "status_class"},
+	},
+	"Server.TestAlertRule": {
+		Summary:     "Test alert rule",
+		Description: "TestAlertRule handles POST /api/alerts/:id/test, sending a test payload to the rule's webhook.",
+	},
+	"Server.TestMultipleKeys": {
+		Summary:     "Test multiple keys",
+		Description: "TestMultipleKeys handles a one-off validation test for multiple keys.",
+		Body:        reflect.TypeFor[KeyTextRequest](),
+	},
+	"Server.TestWebhook": {
+		Summary:     "Test webhook",
+		Description: "TestWebhook handles POST /api/webhooks/:id/test, delivering a ping event to the subscription.",
+	},
+	"Server.UpdateAlertRule": {
+		Summary:     "Update alert rule",
+		Description: "UpdateAlertRule handles PUT /api/alerts/:id.",
+		Body:        reflect.TypeFor[AlertRuleRequest](),
+	},
+	"Server.UpdateGroup": {
+		Summary:     "Update group",
+		Description: "UpdateGroup handles updating an existing group.",
+		Body:        reflect.TypeFor[GroupUpdateRequest](),
+	},
+	"Server.UpdateIPAccess": {
+		Summary:     "Update IP access",
+		Description: "UpdateIPAccess handles replacing the client address allowlist and denylist of a group",
+		Body:        reflect.TypeFor[models.IPAccessPolicy](),
+	},
+	"Server.UpdateKeyExpiry": {
+		Summary:     "Update key expiry",
+		Description: "UpdateKeyExpiry handles setting or clearing the expiry date of a specific API key.",
+		Body:        reflect.TypeFor[UpdateKeyExpiryRequest](),
+	},
+	"Server.UpdateKeyLimits": {
+		Summary:     "Update key limits",
+		Description: "UpdateKeyLimits handles setting the rate and spend limits of a specific API key.",
+		Body:        reflect.TypeFor[UpdateKeyLimitsRequest](),
+	},
+	"Server.UpdateKeyNotes": {
+		Summary:     "Update key notes",
+		Description: "UpdateKeyNotes handles updating the notes of a specific API key.",
+		Body:        reflect.TypeFor[UpdateKeyNotesRequest](),
+	},
+	"Server.UpdateKeyTier": {
+		Summary:     "Update key tier",
+		Description: "UpdateKeyTier handles moving a specific API key to the primary or backup tier of its group.",
+		Body:        reflect.TypeFor[UpdateKeyTierRequest](),
+	},
+	"Server.UpdateModel": {
+		Summary:     "Update model",
+		Description: "UpdateModel handles updating a model's custom capabilities",
+		Body:        reflect.TypeFor[UpdateModelRequest](),
+	},
+	"Server.UpdateModelAccess": {
+		Summary:     "Update model access",
+		Description: "UpdateModelAccess handles replacing the model allowlist and denylist of a group",
+		Body:        reflect.TypeFor[models.ModelAccessPolicy](),
+	},
+	"Server.UpdateModelAliases": {
+		Summary:     "Update model aliases",
+		Description: "UpdateModelAliases handles replacing the model aliases of a group. Aliases are stored as the group's model redirect rules.",
+		Body:        reflect.TypeFor[UpdateModelAliasesRequest](),
+	},
+	"Server.UpdatePlaygroundConversation": {
+		Summary:     "Update playground conversation",
+		Description: "UpdatePlaygroundConversation handles PUT /api/playground/conversations/:id.",
+	},
+	"Server.UpdateSettings": {
+		Summary:     "Update settings",
+		Description: "UpdateSettings handles the PUT /api/settings request.",
+		Body:        reflect.TypeFor[map[string]any](),
+	},
+	"Server.UpdateSubGroupWeight": {
+		Summary:     "Update sub group weight",
+		Description: "UpdateSubGroupWeight handles updating the weight of a sub group",
+		Body:        reflect.TypeFor[UpdateSubGroupWeightRequest](),
+	},
+	"Server.UpdateTeam": {
+		Summary:     "Update team",
+		Description: "UpdateTeam handles PUT /api/teams/:id.",
+		Body:        reflect.TypeFor[TeamRequest](),
+	},
+	"Server.UpdateUser": {
+		Summary:     "Update user",
+		Description: "UpdateUser handles PUT /api/users/:id.",
+		Body:        reflect.TypeFor[UserRequest](),
+	},
+	"Server.UpdateWebhook": {
+		Summary:     "Update webhook",
+		Description: "UpdateWebhook handles PUT /api/webhooks/:id.",
+		Body:        reflect.TypeFor[WebhookSubscriptionRequest](),
+	},
+	"Server.Usage": {
+		Summary:     "Usage",
+		Description: "Usage handles GET /api/dashboard/usage, returning request and token usage per group and model from the hourly or daily rollups. It accepts granularity (hour or day, default day), start_time and end_time (RFC3339, default the last 24 hours or 30 days), group_id and model.",
+		QueryParams: []string{"granularity", "end_time", "start_time", "group_id", "model"},
+	},
+	"Server.ValidateGroupKeys": {
+		Summary:     "Validate group keys",
+		Description: "ValidateGroupKeys initiates a manual validation task for all keys in a group.",
+		Body:        reflect.TypeFor[ValidateGroupKeysRequest](),
+	},
+}
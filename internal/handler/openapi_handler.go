@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+
+	"gpt-load/internal/openapi"
+	"gpt-load/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ../../cmd/openapi-gen
+
+// OpenAPISpec serves the OpenAPI document of the admin API. The document is built on the first
+// request, once all routes are registered; public lists the routes that need no credentials. The
+// route serving the document is left out of it.
+func (s *Server) OpenAPISpec(routes func() gin.RoutesInfo, public ...string) gin.HandlerFunc {
+	var (
+		once sync.Once
+		doc  *openapi.Document
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			documented := slices.DeleteFunc(routes(), func(route gin.RouteInfo) bool {
+				return route.Path == c.FullPath()
+			})
+			info := openapi.Info{Title: "GPT-Load Admin API", Version: version.Version}
+			doc = openapi.Build(info, "/api", documented, openAPIOperations, public)
+		})
+		c.JSON(http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"gorm.io/gorm"
+)
+
+// maxPlaygroundImageBytes bounds a single decoded inline image.
+const maxPlaygroundImageBytes = 20 << 20
+
+// playgroundPart is a text or image part of a playground message. Inline images carry their
+// MIME type and base64 data (from a data: URL); remote images carry their URL.
+type playgroundPart struct {
+	Text     string
+	IsImage  bool
+	MimeType string
+	Data     string
+	URL      string
+}
+
+// parsePlaygroundContent parses OpenAI style message content: a plain string, or a list of
+// {"type":"text","text":...} and {"type":"image_url","image_url":{"url":...}} parts, where the
+// URL is either http(s) or a base64 data: URL.
+func parsePlaygroundContent(content any) ([]playgroundPart, error) {
+	switch value := content.(type) {
+	case string:
+		return []playgroundPart{{Text: value}}, nil
+	case []interface{}:
+		parts := make([]playgroundPart, 0, len(value))
+		for _, raw := range value {
+			item, ok := raw.(map[string]interface{})
+			if !ok {
+				return nil, services.NewI18nError(app_errors.ErrValidation, "playground.invalid_content", nil)
+			}
+			switch stringField(item, "type") {
+			case "text":
+				parts = append(parts, playgroundPart{Text: stringField(item, "text")})
+			case "image_url":
+				rawURL := stringField(item, "image_url")
+				if imageURL, ok := item["image_url"].(map[string]interface{}); ok {
+					rawURL = stringField(imageURL, "url")
+				}
+				part, err := parsePlaygroundImage(rawURL)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, part)
+			default:
+				return nil, services.NewI18nError(app_errors.ErrValidation, "playground.invalid_content", nil)
+			}
+		}
+		return parts, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, services.NewI18nError(app_errors.ErrValidation, "playground.invalid_content", nil)
+	}
+}
+
+// parsePlaygroundImage validates an image URL or a base64 image data: URL.
+func parsePlaygroundImage(rawURL string) (playgroundPart, error) {
+	invalid := services.NewI18nError(app_errors.ErrValidation, "playground.invalid_image", nil)
+
+	if strings.HasPrefix(rawURL, "https://") || strings.HasPrefix(rawURL, "http://") {
+		return playgroundPart{IsImage: true, URL: rawURL}, nil
+	}
+
+	header, data, ok := strings.Cut(strings.TrimPrefix(rawURL, "data:"), ",")
+	mimeType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !strings.HasPrefix(rawURL, "data:") || !ok || !isBase64 || !strings.HasPrefix(mimeType, "image/") {
+		return playgroundPart{}, invalid
+	}
+	if base64.StdEncoding.DecodedLen(len(data)) > maxPlaygroundImageBytes {
+		return playgroundPart{}, services.NewI18nError(app_errors.ErrValidation, "playground.image_too_large",
+			map[string]any{"max_mb": maxPlaygroundImageBytes >> 20})
+	}
+	if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+		return playgroundPart{}, invalid
+	}
+	return playgroundPart{IsImage: true, MimeType: mimeType, Data: data}, nil
+}
+
+// validatePlaygroundMessages checks the content of every message and, when images are attached,
+// that the model is known to support vision.
+func (s *Server) validatePlaygroundMessages(group *models.Group, req PlaygroundChatRequest) error {
+	hasImages := false
+	for _, msg := range req.Messages {
+		parts, err := parsePlaygroundContent(msg["content"])
+		if err != nil {
+			return err
+		}
+		for _, part := range parts {
+			if !part.IsImage {
+				continue
+			}
+			hasImages = true
+			// Gemini only accepts inline image data or its own file URIs.
+			if group.ChannelType == "gemini" && part.URL != "" {
+				return services.NewI18nError(app_errors.ErrValidation, "playground.gemini_image_url", nil)
+			}
+		}
+	}
+	if !hasImages {
+		return nil
+	}
+
+	capability, err := s.ModelService.FindGroupModel(group.ID, req.Model)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return app_errors.ParseDBError(err)
+	}
+	if capability == nil || !capability.SupportsVision {
+		return services.NewI18nError(app_errors.ErrValidation, "playground.model_no_vision", map[string]any{"model": req.Model})
+	}
+	return nil
+}
+
+// geminiParts converts message content to Gemini parts, with images as inlineData.
+func geminiParts(content any) []map[string]interface{} {
+	parts, _ := parsePlaygroundContent(content)
+	out := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.IsImage && part.Data != "":
+			out = append(out, map[string]interface{}{
+				"inlineData": map[string]interface{}{"mimeType": part.MimeType, "data": part.Data},
+			})
+		case !part.IsImage && part.Text != "":
+			out = append(out, map[string]interface{}{"text": part.Text})
+		}
+	}
+	return out
+}
+
+// anthropicContent keeps plain text content as is and converts parts to Anthropic content blocks.
+func anthropicContent(content any) (any, bool) {
+	if text, ok := content.(string); ok {
+		return text, true
+	}
+	parts, _ := parsePlaygroundContent(content)
+	blocks := make([]map[string]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.IsImage && part.Data != "":
+			blocks = append(blocks, map[string]interface{}{
+				"type":   "image",
+				"source": map[string]interface{}{"type": "base64", "media_type": part.MimeType, "data": part.Data},
+			})
+		case part.IsImage:
+			blocks = append(blocks, map[string]interface{}{
+				"type":   "image",
+				"source": map[string]interface{}{"type": "url", "url": part.URL},
+			})
+		case part.Text != "":
+			blocks = append(blocks, map[string]interface{}{"type": "text", "text": part.Text})
+		}
+	}
+	return blocks, len(blocks) > 0
+}
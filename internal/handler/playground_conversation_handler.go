@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// maxPlaygroundTitleLength bounds conversation titles, including ones derived from the first message.
+const maxPlaygroundTitleLength = 80
+
+// PlaygroundConversationMessage is a single message of a saved conversation. Assistant messages may
+// carry the tool calls they requested, and tool messages the ID of the call they answer.
+type PlaygroundConversationMessage struct {
+	Role             string         `json:"role" binding:"required,oneof=system user assistant tool error"`
+	Content          string         `json:"content"`
+	ReasoningContent string         `json:"reasoning_content"`
+	ToolCalls        datatypes.JSON `json:"tool_calls"`
+	ToolCallID       string         `json:"tool_call_id" binding:"max=100"`
+}
+
+// SavePlaygroundConversationRequest is the full state of a conversation. Saving replaces the stored messages.
+type SavePlaygroundConversationRequest struct {
+	Title       string                          `json:"title"`
+	GroupName   string                          `json:"group_name"`
+	Model       string                          `json:"model"`
+	Temperature float64                         `json:"temperature"`
+	Messages    []PlaygroundConversationMessage `json:"messages" binding:"dive"`
+}
+
+// ListPlaygroundConversations handles GET /api/playground/conversations with pagination.
+func (s *Server) ListPlaygroundConversations(c *gin.Context) {
+	var conversations []models.PlaygroundConversation
+	pagination, err := response.Paginate(c, s.PlaygroundConversationService.Query(), &conversations)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, pagination)
+}
+
+// GetPlaygroundConversation handles GET /api/playground/conversations/:id, returning the conversation
+// with its messages so that the playground can resume it.
+func (s *Server) GetPlaygroundConversation(c *gin.Context) {
+	id, ok := parsePlaygroundConversationID(c)
+	if !ok {
+		return
+	}
+
+	conversation, err := s.PlaygroundConversationService.Get(id)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, conversation)
+}
+
+// CreatePlaygroundConversation handles POST /api/playground/conversations.
+func (s *Server) CreatePlaygroundConversation(c *gin.Context) {
+	s.savePlaygroundConversation(c, 0)
+}
+
+// UpdatePlaygroundConversation handles PUT /api/playground/conversations/:id.
+func (s *Server) UpdatePlaygroundConversation(c *gin.Context) {
+	id, ok := parsePlaygroundConversationID(c)
+	if !ok {
+		return
+	}
+	s.savePlaygroundConversation(c, id)
+}
+
+// DeletePlaygroundConversation handles DELETE /api/playground/conversations/:id.
+func (s *Server) DeletePlaygroundConversation(c *gin.Context) {
+	id, ok := parsePlaygroundConversationID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.PlaygroundConversationService.Delete(id); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (s *Server) savePlaygroundConversation(c *gin.Context, id uint) {
+	var req SavePlaygroundConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	conversation := &models.PlaygroundConversation{
+		ID:          id,
+		Title:       playgroundConversationTitle(req),
+		GroupName:   req.GroupName,
+		Model:       req.Model,
+		Temperature: req.Temperature,
+	}
+	messages := make([]models.PlaygroundMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		messages = append(messages, models.PlaygroundMessage{
+			Role:             msg.Role,
+			Content:          msg.Content,
+			ReasoningContent: msg.ReasoningContent,
+			ToolCalls:        msg.ToolCalls,
+			ToolCallID:       msg.ToolCallID,
+		})
+	}
+
+	if err := s.PlaygroundConversationService.Save(conversation, messages); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, conversation)
+}
+
+// playgroundConversationTitle returns the requested title, or the start of the first user message.
+func playgroundConversationTitle(req SavePlaygroundConversationRequest) string {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		for _, msg := range req.Messages {
+			if msg.Role == "user" {
+				title = strings.Join(strings.Fields(msg.Content), " ")
+				break
+			}
+		}
+	}
+	if runes := []rune(title); len(runes) > maxPlaygroundTitleLength {
+		title = string(runes[:maxPlaygroundTitleLength-1]) + "…"
+	}
+	return title
+}
+
+// parsePlaygroundConversationID parses the :id path parameter, writing an error response on failure.
+func parsePlaygroundConversationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_conversation_id")
+		return 0, false
+	}
+	return uint(id), true
+}
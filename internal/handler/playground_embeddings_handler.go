@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlaygroundEmbeddingsRequest represents an embeddings request from playground
+type PlaygroundEmbeddingsRequest struct {
+	GroupName string   `json:"group_name" binding:"required"`
+	Model     string   `json:"model" binding:"required"`
+	Input     []string `json:"input" binding:"required,min=1,max=32"`
+}
+
+// PlaygroundEmbedding is the vector returned for a single input
+type PlaygroundEmbedding struct {
+	Index      int       `json:"index"`
+	Dimensions int       `json:"dimensions"`
+	Values     []float64 `json:"values"`
+}
+
+// PlaygroundEmbeddingsResponse represents the embeddings response to playground
+type PlaygroundEmbeddingsResponse struct {
+	Model      string                `json:"model"`
+	Embeddings []PlaygroundEmbedding `json:"embeddings"`
+	Usage      PlaygroundUsage       `json:"usage"`
+}
+
+// PlaygroundEmbeddings handles embeddings requests from the playground
+func (s *Server) PlaygroundEmbeddings(c *gin.Context) {
+	var req PlaygroundEmbeddingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_request")
+		return
+	}
+
+	group, upstream, decryptedKey, ok := s.resolvePlaygroundTarget(c, req.GroupName)
+	if !ok {
+		return
+	}
+
+	client := s.playgroundClient(group, false)
+	var result *PlaygroundEmbeddingsResponse
+	var apiErr error
+
+	switch group.ChannelType {
+	case "gemini":
+		result, apiErr = s.callGeminiEmbeddings(client, upstream.URL, decryptedKey, req)
+	case "anthropic":
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Embeddings are not supported for anthropic channels"))
+		return
+	default:
+		result, apiErr = s.callOpenAIEmbeddings(client, upstream.URL, decryptedKey, req)
+	}
+
+	if apiErr != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadGateway, "api.call_failed")
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// postPlaygroundJSON sends a JSON request and returns the body of a 200 response.
+func postPlaygroundJSON(client *http.Client, url string, payload any, headers map[string]string) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (s *Server) callOpenAIEmbeddings(client *http.Client, baseURL, apiKey string, req PlaygroundEmbeddingsRequest) (*PlaygroundEmbeddingsResponse, error) {
+	body, err := postPlaygroundJSON(client, baseURL+"/v1/embeddings", map[string]any{
+		"model": req.Model,
+		"input": req.Input,
+	}, map[string]string{"Authorization": "Bearer " + apiKey})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	out := &PlaygroundEmbeddingsResponse{
+		Model: req.Model,
+		Usage: PlaygroundUsage{
+			PromptTokens: result.Usage.PromptTokens,
+			TotalTokens:  result.Usage.TotalTokens,
+		},
+	}
+	for _, d := range result.Data {
+		out.Embeddings = append(out.Embeddings, PlaygroundEmbedding{
+			Index:      d.Index,
+			Dimensions: len(d.Embedding),
+			Values:     d.Embedding,
+		})
+	}
+	if len(out.Embeddings) == 0 {
+		return nil, fmt.Errorf("unexpected response format")
+	}
+	return out, nil
+}
+
+func (s *Server) callGeminiEmbeddings(client *http.Client, baseURL, apiKey string, req PlaygroundEmbeddingsRequest) (*PlaygroundEmbeddingsResponse, error) {
+	requests := make([]map[string]any, 0, len(req.Input))
+	for _, text := range req.Input {
+		requests = append(requests, map[string]any{
+			"model":   "models/" + req.Model,
+			"content": map[string]any{"parts": []map[string]any{{"text": text}}},
+		})
+	}
+
+	url := fmt.Sprintf("%s/v1beta/models/%s:batchEmbedContents?key=%s", baseURL, req.Model, apiKey)
+	body, err := postPlaygroundJSON(client, url, map[string]any{"requests": requests}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Embeddings []struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	out := &PlaygroundEmbeddingsResponse{Model: req.Model}
+	for i, e := range result.Embeddings {
+		out.Embeddings = append(out.Embeddings, PlaygroundEmbedding{
+			Index:      i,
+			Dimensions: len(e.Values),
+			Values:     e.Values,
+		})
+	}
+	if len(out.Embeddings) == 0 {
+		return nil, fmt.Errorf("unexpected response format")
+	}
+	return out, nil
+}
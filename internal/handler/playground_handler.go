@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/httpclient"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
 	"io"
+	"math/rand/v2"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // Upstream represents an upstream server configuration
@@ -21,18 +25,54 @@ type Upstream struct {
 	Weight int    `json:"weight"`
 }
 
-// PlaygroundChatRequest represents the chat request from playground
+// PlaygroundChatRequest represents the chat request from playground. Message content is a string or
+// a list of OpenAI style text and image_url parts; images require a model with supports_vision.
+// Tools, tool_choice, assistant tool_calls and tool result messages use the OpenAI format and are
+// translated for Gemini and Anthropic groups.
 type PlaygroundChatRequest struct {
 	GroupName   string                   `json:"group_name" binding:"required"`
 	Model       string                   `json:"model" binding:"required"`
 	Messages    []map[string]interface{} `json:"messages" binding:"required"`
 	Temperature float64                  `json:"temperature"`
+	N           int                      `json:"n" binding:"omitempty,min=1,max=8"`
+	Tools       []PlaygroundTool         `json:"tools" binding:"omitempty,dive"`
+	// ToolChoice is "auto", "none", "required" or {"type":"function","function":{"name":...}}.
+	ToolChoice any `json:"tool_choice"`
+	// Stream answers with server-sent events carrying content, reasoning and partial tool call deltas.
+	Stream bool `json:"stream"`
+}
+
+// PlaygroundChoice is a single candidate completion. FinishReason is normalized to
+// stop, length, tool_calls, content_filter or other regardless of provider.
+// ReasoningContent holds the chain-of-thought of reasoning models that return it separately.
+// ToolCalls holds the function calls requested by the model, in the same shape for every provider.
+type PlaygroundChoice struct {
+	Index            int                  `json:"index"`
+	Content          string               `json:"content"`
+	ReasoningContent string               `json:"reasoning_content,omitempty"`
+	ToolCalls        []PlaygroundToolCall `json:"tool_calls,omitempty"`
+	FinishReason     string               `json:"finish_reason,omitempty"`
+}
+
+// PlaygroundUsage is the token usage reported by the upstream, summed across calls
+type PlaygroundUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // PlaygroundChatResponse represents the response to playground
 type PlaygroundChatResponse struct {
-	Content string `json:"content"`
-	Model   string `json:"model"`
+	Content string             `json:"content"`
+	Model   string             `json:"model"`
+	Choices []PlaygroundChoice `json:"choices"`
+	Usage   PlaygroundUsage    `json:"usage"`
+}
+
+// playgroundResult is what each provider call returns
+type playgroundResult struct {
+	Choices []PlaygroundChoice
+	Usage   PlaygroundUsage
 }
 
 // PlaygroundChat handles chat requests from the playground
@@ -42,56 +82,39 @@ func (s *Server) PlaygroundChat(c *gin.Context) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_request")
 		return
 	}
-
-	// Find the group
-	var group models.Group
-	if err := s.DB.Where("name = ?", req.GroupName).First(&group).Error; err != nil {
-		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "group.not_found")
-		return
+	if req.N <= 0 {
+		req.N = 1
 	}
 
-	// Parse upstreams from JSON
-	var upstreams []Upstream
-	if err := json.Unmarshal(group.Upstreams, &upstreams); err != nil || len(upstreams) == 0 {
-		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "group.no_upstreams")
+	group, upstream, decryptedKey, ok := s.resolvePlaygroundTarget(c, req.GroupName)
+	if !ok {
 		return
 	}
 
-	upstream := upstreams[0]
-
-	// Get an API key from the group - use a portable approach
-	var apiKeys []models.APIKey
-	if err := s.DB.Where("group_id = ? AND status = ?", group.ID, models.KeyStatusActive).
-		Limit(10).Find(&apiKeys).Error; err != nil || len(apiKeys) == 0 {
-		response.ErrorI18nFromAPIError(c, app_errors.ErrNoActiveKeys, "keys.no_active_keys")
+	if s.handleGroupError(c, s.validatePlaygroundMessages(group, req)) {
 		return
 	}
 
-	// Randomly select one key from the result
-	apiKey := apiKeys[time.Now().UnixNano()%int64(len(apiKeys))]
-
-	// Decrypt the API key
-	decryptedKey, err := s.EncryptionSvc.Decrypt(apiKey.KeyValue)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to decrypt API key")
-		response.ErrorI18nFromAPIError(c, app_errors.ErrInternalServer, "encryption.decrypt_failed")
+	if req.Stream {
+		s.streamPlayground(c, group, upstream.URL, decryptedKey, req)
 		return
 	}
 
 	// Build the request based on channel type
-	var upstreamResp string
+	client := s.playgroundClient(group, false)
+	var upstreamResp *playgroundResult
 	var apiErr error
 
 	switch group.ChannelType {
 	case "openai":
-		upstreamResp, apiErr = s.callOpenAI(upstream.URL, decryptedKey, req)
+		upstreamResp, apiErr = s.callOpenAI(client, upstream.URL, decryptedKey, req)
 	case "gemini":
-		upstreamResp, apiErr = s.callGemini(upstream.URL, decryptedKey, req)
+		upstreamResp, apiErr = s.callGemini(client, upstream.URL, decryptedKey, req)
 	case "anthropic":
-		upstreamResp, apiErr = s.callAnthropic(upstream.URL, decryptedKey, req)
+		upstreamResp, apiErr = s.callAnthropic(client, upstream.URL, decryptedKey, req)
 	default:
 		// Default to OpenAI format
-		upstreamResp, apiErr = s.callOpenAI(upstream.URL, decryptedKey, req)
+		upstreamResp, apiErr = s.callOpenAI(client, upstream.URL, decryptedKey, req)
 	}
 
 	if apiErr != nil {
@@ -100,72 +123,169 @@ func (s *Server) PlaygroundChat(c *gin.Context) {
 	}
 
 	response.Success(c, PlaygroundChatResponse{
-		Content: upstreamResp,
+		Content: upstreamResp.Choices[0].Content,
 		Model:   req.Model,
+		Choices: upstreamResp.Choices,
+		Usage:   upstreamResp.Usage,
 	})
 }
 
-func (s *Server) callOpenAI(baseURL, apiKey string, req PlaygroundChatRequest) (string, error) {
-	// Build OpenAI chat completion request
-	reqBody := map[string]interface{}{
-		"model":       req.Model,
-		"messages":    req.Messages,
-		"temperature": req.Temperature,
+// resolvePlaygroundTarget loads the group and picks its first upstream and a random active key.
+// It returns false if a response has already been sent to the client.
+func (s *Server) resolvePlaygroundTarget(c *gin.Context, groupName string) (*models.Group, Upstream, string, bool) {
+	// Find the group
+	var group models.Group
+	err := s.DB.Where("name = ?", groupName).First(&group).Error
+	if err != nil || !services.GroupScopeFromContext(c).Allows(&group) {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "group.not_found")
+		return nil, Upstream{}, "", false
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	// Parse upstreams from JSON
+	var upstreams []Upstream
+	if err := json.Unmarshal(group.Upstreams, &upstreams); err != nil || len(upstreams) == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "group.no_upstreams")
+		return nil, Upstream{}, "", false
+	}
+
+	upstream := upstreams[0]
+
+	// Pick a random active key; the offset is chosen in Go because RANDOM()/RAND() differ between
+	// SQLite, PostgreSQL and MySQL
+	activeKeys := s.DB.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", group.ID, models.KeyStatusActive).Session(&gorm.Session{})
+	var count int64
+	if err := activeKeys.Count(&count).Error; err != nil || count == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrNoActiveKeys, "keys.no_active_keys")
+		return nil, Upstream{}, "", false
+	}
+	var apiKey models.APIKey
+	if err := activeKeys.Order("id").Offset(rand.IntN(int(count))).Limit(1).Take(&apiKey).Error; err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrNoActiveKeys, "keys.no_active_keys")
+		return nil, Upstream{}, "", false
+	}
+
+	// Decrypt the API key
+	decryptedKey, err := s.EncryptionSvc.Decrypt(apiKey.KeyValue)
 	if err != nil {
-		return "", err
+		logrus.WithError(err).Error("Failed to decrypt API key")
+		response.ErrorI18nFromAPIError(c, app_errors.ErrInternalServer, "encryption.decrypt_failed")
+		return nil, Upstream{}, "", false
+	}
+
+	group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
+	return &group, upstream, decryptedKey, true
+}
+
+// playgroundClient returns the upstream client for a group, so that playground calls follow the
+// group's connect, response header and request timeouts and its transport settings.
+func (s *Server) playgroundClient(group *models.Group, stream bool) *http.Client {
+	config := httpclient.NewConfig(&group.EffectiveConfig)
+	if stream {
+		config = config.ForStreaming()
+	}
+	return s.HTTPClientManager.GetClient(config)
+}
+
+// newPlaygroundRequest builds the provider request of a playground chat for the group's channel
+// type, translating messages, tools and tool_choice from the OpenAI format.
+func newPlaygroundRequest(channelType, baseURL, apiKey string, req PlaygroundChatRequest, stream bool) (*http.Request, error) {
+	var url string
+	var reqBody map[string]interface{}
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	switch channelType {
+	case "gemini":
+		reqBody = geminiPlaygroundBody(req)
+		method := "generateContent"
+		if stream {
+			method = "streamGenerateContent"
+		}
+		url = fmt.Sprintf("%s/v1beta/models/%s:%s?key=%s", baseURL, req.Model, method, apiKey)
+		if stream {
+			url += "&alt=sse"
+		}
+	case "anthropic":
+		reqBody = anthropicPlaygroundBody(req, stream)
+		url = baseURL + "/v1/messages"
+		headers["x-api-key"] = apiKey
+		headers["anthropic-version"] = "2023-06-01"
+	default:
+		// Default to OpenAI format
+		reqBody = openAIPlaygroundBody(req, stream)
+		url = baseURL + "/v1/chat/completions"
+		headers["Authorization"] = "Bearer " + apiKey
 	}
 
-	url := baseURL + "/v1/chat/completions"
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
 	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+	return httpReq, nil
+}
 
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
+// doPlaygroundRequest sends a non-streaming playground request and returns the decoded JSON body.
+func doPlaygroundRequest(client *http.Client, httpReq *http.Request) (map[string]interface{}, error) {
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
+		return nil, err
 	}
+	return result, nil
+}
 
-	// Extract the message content
-	if choices, ok := result["choices"].([]interface{}); ok && len(choices) > 0 {
-		if choice, ok := choices[0].(map[string]interface{}); ok {
-			if message, ok := choice["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"].(string); ok {
-					return content, nil
-				}
-			}
+func openAIPlaygroundBody(req PlaygroundChatRequest, stream bool) map[string]interface{} {
+	// Build OpenAI chat completion request
+	reqBody := map[string]interface{}{
+		"model":       req.Model,
+		"messages":    req.Messages,
+		"temperature": req.Temperature,
+	}
+	if req.N > 1 {
+		reqBody["n"] = req.N
+	}
+	if len(req.Tools) > 0 {
+		reqBody["tools"] = req.Tools
+		if req.ToolChoice != nil {
+			reqBody["tool_choice"] = req.ToolChoice
 		}
 	}
-
-	return "", fmt.Errorf("unexpected response format")
+	if stream {
+		reqBody["stream"] = true
+		reqBody["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+	return reqBody
 }
 
-func (s *Server) callGemini(baseURL, apiKey string, req PlaygroundChatRequest) (string, error) {
+func geminiPlaygroundBody(req PlaygroundChatRequest) map[string]interface{} {
 	// Convert OpenAI messages format to Gemini format
+	names := toolCallNames(req.Messages)
 	var contents []map[string]interface{}
 	for _, msg := range req.Messages {
+		if content, ok := geminiToolMessage(msg, names); ok {
+			contents = append(contents, content)
+			continue
+		}
 		role := "user"
 		if msgRole, ok := msg["role"].(string); ok {
 			switch msgRole {
@@ -178,80 +298,41 @@ func (s *Server) callGemini(baseURL, apiKey string, req PlaygroundChatRequest) (
 				role = "user"
 			}
 		}
-		if content, ok := msg["content"].(string); ok {
+		if parts := geminiParts(msg["content"]); len(parts) > 0 {
 			contents = append(contents, map[string]interface{}{
-				"role": role,
-				"parts": []map[string]interface{}{
-					{"text": content},
-				},
+				"role":  role,
+				"parts": parts,
 			})
 		}
 	}
 
-	reqBody := map[string]interface{}{
-		"contents": contents,
-		"generationConfig": map[string]interface{}{
-			"temperature": req.Temperature,
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
-	}
-
-	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", baseURL, req.Model, apiKey)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
+	generationConfig := map[string]interface{}{
+		"temperature": req.Temperature,
 	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", err
+	if req.N > 1 {
+		generationConfig["candidateCount"] = req.N
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	reqBody := map[string]interface{}{
+		"contents":         contents,
+		"generationConfig": generationConfig,
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	if tools, toolConfig := geminiTools(req); tools != nil {
+		reqBody["tools"] = tools
+		reqBody["toolConfig"] = toolConfig
 	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
-	}
-
-	// Extract the message content from Gemini response
-	if candidates, ok := result["candidates"].([]interface{}); ok && len(candidates) > 0 {
-		if candidate, ok := candidates[0].(map[string]interface{}); ok {
-			if content, ok := candidate["content"].(map[string]interface{}); ok {
-				if parts, ok := content["parts"].([]interface{}); ok && len(parts) > 0 {
-					if part, ok := parts[0].(map[string]interface{}); ok {
-						if text, ok := part["text"].(string); ok {
-							return text, nil
-						}
-					}
-				}
-			}
-		}
-	}
-
-	return "", fmt.Errorf("unexpected response format")
+	return reqBody
 }
 
-func (s *Server) callAnthropic(baseURL, apiKey string, req PlaygroundChatRequest) (string, error) {
+func anthropicPlaygroundBody(req PlaygroundChatRequest, stream bool) map[string]interface{} {
 	// Convert OpenAI messages to Anthropic format
 	var messages []map[string]interface{}
 	for _, msg := range req.Messages {
-		if content, ok := msg["content"].(string); ok {
+		if message, ok := anthropicToolMessage(msg); ok {
+			messages = append(messages, message)
+			continue
+		}
+		if content, ok := anthropicContent(msg["content"]); ok {
 			messages = append(messages, map[string]interface{}{
 				"role":    msg["role"],
 				"content": content,
@@ -265,51 +346,202 @@ func (s *Server) callAnthropic(baseURL, apiKey string, req PlaygroundChatRequest
 		"max_tokens":  1024,
 		"temperature": req.Temperature,
 	}
+	if tools, toolChoice := anthropicTools(req); tools != nil {
+		reqBody["tools"] = tools
+		reqBody["tool_choice"] = toolChoice
+	}
+	if stream {
+		reqBody["stream"] = true
+	}
+	return reqBody
+}
 
-	jsonData, err := json.Marshal(reqBody)
+func (s *Server) callOpenAI(client *http.Client, baseURL, apiKey string, req PlaygroundChatRequest) (*playgroundResult, error) {
+	httpReq, err := newPlaygroundRequest("openai", baseURL, apiKey, req, false)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	url := baseURL + "/v1/messages"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	result, err := doPlaygroundRequest(client, httpReq)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	httpReq.Header.Set("x-api-key", apiKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
-	httpReq.Header.Set("Content-Type", "application/json")
+	// Extract every choice, not just the first one
+	out := &playgroundResult{}
+	if choices, ok := result["choices"].([]interface{}); ok {
+		for i, raw := range choices {
+			choice, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			message, ok := choice["message"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			content, hasContent := message["content"].(string)
+			toolCalls := openAIToolCalls(message)
+			if !hasContent && len(toolCalls) == 0 {
+				continue
+			}
+			out.Choices = append(out.Choices, PlaygroundChoice{
+				Index:            intField(choice, "index", i),
+				Content:          content,
+				ReasoningContent: stringField(message, "reasoning_content"),
+				ToolCalls:        toolCalls,
+				FinishReason:     utils.NormalizeFinishReason(stringField(choice, "finish_reason")),
+			})
+		}
+	}
+	if usage, ok := result["usage"].(map[string]interface{}); ok {
+		out.Usage = PlaygroundUsage{
+			PromptTokens:     intField(usage, "prompt_tokens", 0),
+			CompletionTokens: intField(usage, "completion_tokens", 0),
+			TotalTokens:      intField(usage, "total_tokens", 0),
+		}
+	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", err
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("unexpected response format")
 	}
-	defer resp.Body.Close()
+	return out, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (s *Server) callGemini(client *http.Client, baseURL, apiKey string, req PlaygroundChatRequest) (*playgroundResult, error) {
+	httpReq, err := newPlaygroundRequest("gemini", baseURL, apiKey, req, false)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	result, err := doPlaygroundRequest(client, httpReq)
+	if err != nil {
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	// Extract every candidate from Gemini response
+	out := &playgroundResult{}
+	if candidates, ok := result["candidates"].([]interface{}); ok {
+		for i, raw := range candidates {
+			candidate, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			content, ok := candidate["content"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			parts, ok := content["parts"].([]interface{})
+			if !ok {
+				continue
+			}
+			choice := PlaygroundChoice{
+				Index:        intField(candidate, "index", i),
+				FinishReason: utils.NormalizeFinishReason(stringField(candidate, "finishReason")),
+			}
+			for _, p := range parts {
+				if part, ok := p.(map[string]interface{}); ok {
+					appendGeminiPart(&choice, part)
+				}
+			}
+			out.Choices = append(out.Choices, choice)
+		}
+	}
+	if usage, ok := result["usageMetadata"].(map[string]interface{}); ok {
+		out.Usage = PlaygroundUsage{
+			PromptTokens:     intField(usage, "promptTokenCount", 0),
+			CompletionTokens: intField(usage, "candidatesTokenCount", 0),
+			TotalTokens:      intField(usage, "totalTokenCount", 0),
+		}
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("unexpected response format")
 	}
+	return out, nil
+}
 
-	// Extract the message content from Anthropic response
-	if content, ok := result["content"].([]interface{}); ok && len(content) > 0 {
-		if textBlock, ok := content[0].(map[string]interface{}); ok {
-			if text, ok := textBlock["text"].(string); ok {
-				return text, nil
-			}
+// appendGeminiPart adds the text, thought or function call of a Gemini part to a choice.
+func appendGeminiPart(choice *PlaygroundChoice, part map[string]interface{}) {
+	if call, ok := part["functionCall"].(map[string]interface{}); ok {
+		args, _ := json.Marshal(call["args"])
+		choice.ToolCalls = append(choice.ToolCalls,
+			newPlaygroundToolCall(stringField(call, "id"), stringField(call, "name"), string(args), len(choice.ToolCalls)))
+		return
+	}
+	if thought, _ := part["thought"].(bool); thought {
+		choice.ReasoningContent += stringField(part, "text")
+		return
+	}
+	choice.Content += stringField(part, "text")
+}
+
+// callAnthropic issues one request per requested choice, since the Messages API has no n parameter.
+func (s *Server) callAnthropic(client *http.Client, baseURL, apiKey string, req PlaygroundChatRequest) (*playgroundResult, error) {
+	out := &playgroundResult{}
+	for i := 0; i < req.N; i++ {
+		single, err := s.callAnthropicOnce(client, baseURL, apiKey, req)
+		if err != nil {
+			return nil, err
 		}
+		single.Choices[0].Index = i
+		out.Choices = append(out.Choices, single.Choices[0])
+		out.Usage.PromptTokens += single.Usage.PromptTokens
+		out.Usage.CompletionTokens += single.Usage.CompletionTokens
+		out.Usage.TotalTokens += single.Usage.TotalTokens
 	}
+	return out, nil
+}
+
+func (s *Server) callAnthropicOnce(client *http.Client, baseURL, apiKey string, req PlaygroundChatRequest) (*playgroundResult, error) {
+	httpReq, err := newPlaygroundRequest("anthropic", baseURL, apiKey, req, false)
+	if err != nil {
+		return nil, err
+	}
+	result, err := doPlaygroundRequest(client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract text, thinking and tool_use blocks from Anthropic response
+	content, ok := result["content"].([]interface{})
+	if !ok || len(content) == 0 {
+		return nil, fmt.Errorf("unexpected response format")
+	}
+	choice := PlaygroundChoice{FinishReason: utils.NormalizeFinishReason(stringField(result, "stop_reason"))}
+	for _, raw := range content {
+		block, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch stringField(block, "type") {
+		case "text":
+			choice.Content += stringField(block, "text")
+		case "thinking":
+			choice.ReasoningContent += stringField(block, "thinking")
+		case "tool_use":
+			input, _ := json.Marshal(block["input"])
+			choice.ToolCalls = append(choice.ToolCalls,
+				newPlaygroundToolCall(stringField(block, "id"), stringField(block, "name"), string(input), len(choice.ToolCalls)))
+		}
+	}
+
+	out := &playgroundResult{Choices: []PlaygroundChoice{choice}}
+	if usage, ok := result["usage"].(map[string]interface{}); ok {
+		out.Usage.PromptTokens = intField(usage, "input_tokens", 0)
+		out.Usage.CompletionTokens = intField(usage, "output_tokens", 0)
+		out.Usage.TotalTokens = out.Usage.PromptTokens + out.Usage.CompletionTokens
+	}
+	return out, nil
+}
+
+// intField reads a JSON number from a decoded object, returning def when absent.
+func intField(m map[string]interface{}, key string, def int) int {
+	if v, ok := m[key].(float64); ok {
+		return int(v)
+	}
+	return def
+}
 
-	return "", fmt.Errorf("unexpected response format")
+// stringField reads a JSON string from a decoded object.
+func stringField(m map[string]interface{}, key string) string {
+	v, _ := m[key].(string)
+	return v
 }
@@ -0,0 +1,343 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/i18n"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxPlaygroundStreamLine bounds a single line of an upstream stream.
+const maxPlaygroundStreamLine = 1024 * 1024
+
+// playgroundStreamEvent is a server-sent event of a streaming playground chat:
+//   - delta: content or reasoning_content appended to choice index
+//   - tool_call_delta: a fragment of the arguments of tool call tool_index, with id and name on its
+//     first fragment, as generated by the model
+//   - done: the assembled response, in the same shape as the non-streaming endpoint
+//   - error: the upstream call failed
+type playgroundStreamEvent struct {
+	Type             string                  `json:"type"`
+	Index            int                     `json:"index"`
+	Content          string                  `json:"content,omitempty"`
+	ReasoningContent string                  `json:"reasoning_content,omitempty"`
+	ToolIndex        int                     `json:"tool_index"`
+	ID               string                  `json:"id,omitempty"`
+	Name             string                  `json:"name,omitempty"`
+	Arguments        string                  `json:"arguments,omitempty"`
+	Response         *PlaygroundChatResponse `json:"response,omitempty"`
+	Message          string                  `json:"message,omitempty"`
+}
+
+// playgroundStream forwards provider deltas to the client as playground events and assembles the
+// final response from them.
+type playgroundStream struct {
+	c      *gin.Context
+	client *http.Client
+	// idleTimeout aborts an upstream stream that stops sending data.
+	idleTimeout time.Duration
+	started     bool
+	offset      int
+	choices     []PlaygroundChoice
+	usage       PlaygroundUsage
+}
+
+// streamPlayground relays a playground chat as server-sent events. Anthropic has no n parameter,
+// so its choices are streamed one after another.
+func (s *Server) streamPlayground(c *gin.Context, group *models.Group, baseURL, apiKey string, req PlaygroundChatRequest) {
+	stream := &playgroundStream{
+		c:           c,
+		client:      s.playgroundClient(group, true),
+		idleTimeout: time.Duration(group.EffectiveConfig.StreamIdleTimeout) * time.Second,
+	}
+	channelType := group.ChannelType
+	calls := 1
+	if channelType == "anthropic" {
+		calls = req.N
+	}
+
+	for i := 0; i < calls; i++ {
+		stream.offset = i
+		if err := stream.relay(channelType, baseURL, apiKey, req); err != nil {
+			logrus.WithError(err).WithField("group", req.GroupName).Debug("Playground stream failed")
+			stream.fail()
+			return
+		}
+	}
+	if len(stream.choices) == 0 {
+		stream.fail()
+		return
+	}
+
+	for i := range stream.choices {
+		choice := &stream.choices[i]
+		choice.Index = i
+		choice.FinishReason = utils.NormalizeFinishReason(choice.FinishReason)
+		for j, call := range choice.ToolCalls {
+			// Calls without parameters stream no argument fragments at all
+			if call.Arguments == "" {
+				call.Arguments = "{}"
+			}
+			choice.ToolCalls[j] = newPlaygroundToolCall(call.ID, call.Name, call.Arguments, j)
+		}
+	}
+	stream.emit(playgroundStreamEvent{
+		Type: "done",
+		Response: &PlaygroundChatResponse{
+			Content: stream.choices[0].Content,
+			Model:   req.Model,
+			Choices: stream.choices,
+			Usage:   stream.usage,
+		},
+	})
+}
+
+// relay performs one streaming upstream call and forwards its events.
+func (p *playgroundStream) relay(channelType, baseURL, apiKey string, req PlaygroundChatRequest) error {
+	httpReq, err := newPlaygroundRequest(channelType, baseURL, apiKey, req, true)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(httpReq.WithContext(p.c.Request.Context()))
+	if err != nil {
+		return err
+	}
+	resp.Body = httpclient.WithIdleTimeout(resp.Body, p.idleTimeout)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, body.String())
+	}
+
+	// Anthropic identifies tool_use blocks by content block index
+	toolBlocks := make(map[int]int)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPlaygroundStreamLine)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(bytes.TrimSpace(scanner.Bytes()), []byte("data:"))
+		data = bytes.TrimSpace(data)
+		if !ok || len(data) == 0 || data[0] != '{' {
+			continue
+		}
+		var chunk map[string]interface{}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			continue
+		}
+
+		switch channelType {
+		case "gemini":
+			p.geminiChunk(chunk)
+		case "anthropic":
+			if err := p.anthropicEvent(chunk, toolBlocks); err != nil {
+				return err
+			}
+		default:
+			p.openAIChunk(chunk)
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *playgroundStream) openAIChunk(chunk map[string]interface{}) {
+	choices, _ := chunk["choices"].([]interface{})
+	for _, raw := range choices {
+		choice, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index := intField(choice, "index", 0)
+		if reason := stringField(choice, "finish_reason"); reason != "" {
+			p.choice(index).FinishReason = reason
+		}
+		delta, ok := choice["delta"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p.text(index, stringField(delta, "content"), stringField(delta, "reasoning_content"))
+
+		toolCalls, _ := delta["tool_calls"].([]interface{})
+		for _, rawCall := range toolCalls {
+			call, ok := rawCall.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			function, _ := call["function"].(map[string]interface{})
+			p.toolCall(index, intField(call, "index", 0), stringField(call, "id"), stringField(function, "name"), stringField(function, "arguments"))
+		}
+	}
+	if usage, ok := chunk["usage"].(map[string]interface{}); ok {
+		p.usage = PlaygroundUsage{
+			PromptTokens:     intField(usage, "prompt_tokens", 0),
+			CompletionTokens: intField(usage, "completion_tokens", 0),
+			TotalTokens:      intField(usage, "total_tokens", 0),
+		}
+	}
+}
+
+// geminiChunk handles a streamGenerateContent chunk. Gemini sends each function call whole, so it
+// arrives as a single tool_call_delta.
+func (p *playgroundStream) geminiChunk(chunk map[string]interface{}) {
+	candidates, _ := chunk["candidates"].([]interface{})
+	for i, raw := range candidates {
+		candidate, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		index := intField(candidate, "index", i)
+		if reason := stringField(candidate, "finishReason"); reason != "" {
+			p.choice(index).FinishReason = reason
+		}
+		content, _ := candidate["content"].(map[string]interface{})
+		parts, _ := content["parts"].([]interface{})
+		for _, rawPart := range parts {
+			part, ok := rawPart.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if call, ok := part["functionCall"].(map[string]interface{}); ok {
+				args, _ := json.Marshal(call["args"])
+				toolIndex := len(p.choice(index).ToolCalls)
+				p.toolCall(index, toolIndex, stringField(call, "id"), stringField(call, "name"), string(args))
+				continue
+			}
+			if thought, _ := part["thought"].(bool); thought {
+				p.text(index, "", stringField(part, "text"))
+			} else {
+				p.text(index, stringField(part, "text"), "")
+			}
+		}
+	}
+	if usage, ok := chunk["usageMetadata"].(map[string]interface{}); ok {
+		p.usage = PlaygroundUsage{
+			PromptTokens:     intField(usage, "promptTokenCount", 0),
+			CompletionTokens: intField(usage, "candidatesTokenCount", 0),
+			TotalTokens:      intField(usage, "totalTokenCount", 0),
+		}
+	}
+}
+
+// anthropicEvent handles a Messages API stream event, forwarding input_json_delta fragments as
+// partial tool call arguments. Usage is summed across the sequential calls of n>1.
+func (p *playgroundStream) anthropicEvent(event map[string]interface{}, toolBlocks map[int]int) error {
+	index := p.offset
+	switch stringField(event, "type") {
+	case "message_start":
+		p.choice(index)
+		message, _ := event["message"].(map[string]interface{})
+		if usage, ok := message["usage"].(map[string]interface{}); ok {
+			p.usage.PromptTokens += intField(usage, "input_tokens", 0)
+			p.usage.TotalTokens += intField(usage, "input_tokens", 0)
+		}
+	case "content_block_start":
+		block, _ := event["content_block"].(map[string]interface{})
+		if stringField(block, "type") == "tool_use" {
+			toolIndex := len(p.choice(index).ToolCalls)
+			toolBlocks[intField(event, "index", 0)] = toolIndex
+			p.toolCall(index, toolIndex, stringField(block, "id"), stringField(block, "name"), "")
+		}
+	case "content_block_delta":
+		delta, _ := event["delta"].(map[string]interface{})
+		switch stringField(delta, "type") {
+		case "text_delta":
+			p.text(index, stringField(delta, "text"), "")
+		case "thinking_delta":
+			p.text(index, "", stringField(delta, "thinking"))
+		case "input_json_delta":
+			if toolIndex, ok := toolBlocks[intField(event, "index", 0)]; ok {
+				p.toolCall(index, toolIndex, "", "", stringField(delta, "partial_json"))
+			}
+		}
+	case "message_delta":
+		delta, _ := event["delta"].(map[string]interface{})
+		if reason := stringField(delta, "stop_reason"); reason != "" {
+			p.choice(index).FinishReason = reason
+		}
+		if usage, ok := event["usage"].(map[string]interface{}); ok {
+			p.usage.CompletionTokens += intField(usage, "output_tokens", 0)
+			p.usage.TotalTokens += intField(usage, "output_tokens", 0)
+		}
+	case "error":
+		errInfo, _ := event["error"].(map[string]interface{})
+		return fmt.Errorf("stream error: %s", stringField(errInfo, "message"))
+	}
+	return nil
+}
+
+// choice returns the choice at index, growing the list as needed.
+func (p *playgroundStream) choice(index int) *PlaygroundChoice {
+	for len(p.choices) <= index {
+		p.choices = append(p.choices, PlaygroundChoice{Index: len(p.choices)})
+	}
+	return &p.choices[index]
+}
+
+// text appends a content or reasoning delta to a choice and forwards it.
+func (p *playgroundStream) text(index int, content, reasoning string) {
+	if content == "" && reasoning == "" {
+		return
+	}
+	choice := p.choice(index)
+	choice.Content += content
+	choice.ReasoningContent += reasoning
+	p.emit(playgroundStreamEvent{Type: "delta", Index: index, Content: content, ReasoningContent: reasoning})
+}
+
+// toolCall appends a tool call fragment to a choice and forwards it. id and name are only set on
+// the first fragment of a call.
+func (p *playgroundStream) toolCall(index, toolIndex int, id, name, arguments string) {
+	choice := p.choice(index)
+	for len(choice.ToolCalls) <= toolIndex {
+		choice.ToolCalls = append(choice.ToolCalls, PlaygroundToolCall{})
+	}
+	call := &choice.ToolCalls[toolIndex]
+	if id != "" {
+		call.ID = id
+	}
+	if name != "" {
+		call.Name = name
+	}
+	call.Arguments += arguments
+	p.emit(playgroundStreamEvent{Type: "tool_call_delta", Index: index, ToolIndex: toolIndex, ID: id, Name: name, Arguments: arguments})
+}
+
+// emit writes an event, sending the event stream headers first.
+func (p *playgroundStream) emit(event playgroundStreamEvent) {
+	if !p.started {
+		p.started = true
+		p.c.Header("Content-Type", "text/event-stream")
+		p.c.Header("Cache-Control", "no-cache")
+		p.c.Header("Connection", "keep-alive")
+		p.c.Header("X-Accel-Buffering", "no")
+		p.c.Status(http.StatusOK)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(p.c.Writer, "data: %s\n\n", data)
+	p.c.Writer.Flush()
+}
+
+// fail reports a failed call: as a regular error response when nothing has been streamed yet, and
+// as an error event otherwise.
+func (p *playgroundStream) fail() {
+	if !p.started {
+		response.ErrorI18nFromAPIError(p.c, app_errors.ErrBadGateway, "api.call_failed")
+		return
+	}
+	p.emit(playgroundStreamEvent{Type: "error", Message: i18n.Message(p.c, "api.call_failed")})
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PlaygroundTool is an OpenAI style function tool definition. It is translated to Gemini function
+// declarations and Anthropic tools for the other channel types.
+type PlaygroundTool struct {
+	Type     string             `json:"type" binding:"omitempty,eq=function"`
+	Function PlaygroundFunction `json:"function"`
+}
+
+// PlaygroundFunction describes a callable function with a JSON schema for its arguments.
+type PlaygroundFunction struct {
+	Name        string         `json:"name" binding:"required"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// PlaygroundToolCall is a tool call requested by the model. Arguments is the raw JSON text as
+// generated; ValidJSON reports whether it parses as a JSON object, which is the usual symptom of
+// a schema the model struggles with.
+type PlaygroundToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	ValidJSON bool   `json:"valid_json"`
+}
+
+// newPlaygroundToolCall builds a tool call, generating an ID for providers that do not return one.
+func newPlaygroundToolCall(id, name, arguments string, position int) PlaygroundToolCall {
+	if id == "" {
+		id = fmt.Sprintf("call_%d", position)
+	}
+	var parsed map[string]any
+	return PlaygroundToolCall{
+		ID:        id,
+		Name:      name,
+		Arguments: arguments,
+		ValidJSON: json.Unmarshal([]byte(arguments), &parsed) == nil,
+	}
+}
+
+// openAIToolCalls reads the tool_calls of an OpenAI style message.
+func openAIToolCalls(message map[string]interface{}) []PlaygroundToolCall {
+	raw, _ := message["tool_calls"].([]interface{})
+	calls := make([]PlaygroundToolCall, 0, len(raw))
+	for i, item := range raw {
+		call, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		function, _ := call["function"].(map[string]interface{})
+		calls = append(calls, newPlaygroundToolCall(stringField(call, "id"), stringField(function, "name"), stringField(function, "arguments"), i))
+	}
+	return calls
+}
+
+// toolArguments decodes the JSON arguments of a previous tool call, as Gemini and Anthropic
+// expect an object rather than a string.
+func toolArguments(arguments string) map[string]any {
+	args := map[string]any{}
+	_ = json.Unmarshal([]byte(arguments), &args)
+	return args
+}
+
+// toolCallNames maps the tool call IDs of the conversation to function names, since Gemini
+// identifies function responses by name.
+func toolCallNames(messages []map[string]interface{}) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range openAIToolCalls(msg) {
+			names[call.ID] = call.Name
+		}
+	}
+	return names
+}
+
+// geminiTools converts the tool definitions and tool_choice to Gemini tools and toolConfig.
+func geminiTools(req PlaygroundChatRequest) ([]map[string]interface{}, map[string]interface{}) {
+	if len(req.Tools) == 0 {
+		return nil, nil
+	}
+	declarations := make([]map[string]interface{}, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		declaration := map[string]interface{}{"name": tool.Function.Name, "description": tool.Function.Description}
+		if len(tool.Function.Parameters) > 0 {
+			declaration["parameters"] = tool.Function.Parameters
+		}
+		declarations = append(declarations, declaration)
+	}
+	tools := []map[string]interface{}{{"functionDeclarations": declarations}}
+
+	callingConfig := map[string]interface{}{"mode": "AUTO"}
+	switch choice := req.ToolChoice.(type) {
+	case string:
+		switch choice {
+		case "none":
+			callingConfig["mode"] = "NONE"
+		case "required":
+			callingConfig["mode"] = "ANY"
+		}
+	case map[string]interface{}:
+		if function, ok := choice["function"].(map[string]interface{}); ok {
+			callingConfig["mode"] = "ANY"
+			callingConfig["allowedFunctionNames"] = []string{stringField(function, "name")}
+		}
+	}
+	return tools, map[string]interface{}{"functionCallingConfig": callingConfig}
+}
+
+// anthropicTools converts the tool definitions and tool_choice to Anthropic tools and tool_choice.
+func anthropicTools(req PlaygroundChatRequest) ([]map[string]interface{}, map[string]interface{}) {
+	if len(req.Tools) == 0 {
+		return nil, nil
+	}
+	tools := make([]map[string]interface{}, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		schema := tool.Function.Parameters
+		if len(schema) == 0 {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, map[string]interface{}{
+			"name":         tool.Function.Name,
+			"description":  tool.Function.Description,
+			"input_schema": schema,
+		})
+	}
+
+	toolChoice := map[string]interface{}{"type": "auto"}
+	switch choice := req.ToolChoice.(type) {
+	case string:
+		switch choice {
+		case "none":
+			toolChoice["type"] = "none"
+		case "required":
+			toolChoice["type"] = "any"
+		}
+	case map[string]interface{}:
+		if function, ok := choice["function"].(map[string]interface{}); ok {
+			toolChoice = map[string]interface{}{"type": "tool", "name": stringField(function, "name")}
+		}
+	}
+	return tools, toolChoice
+}
+
+// geminiToolMessage converts an assistant message with tool calls or a tool result to Gemini
+// functionCall or functionResponse parts. It returns false for other messages.
+func geminiToolMessage(msg map[string]interface{}, names map[string]string) (map[string]interface{}, bool) {
+	switch role, _ := msg["role"].(string); {
+	case role == "assistant" && len(openAIToolCalls(msg)) > 0:
+		parts := geminiParts(msg["content"])
+		for _, call := range openAIToolCalls(msg) {
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{"name": call.Name, "args": toolArguments(call.Arguments)},
+			})
+		}
+		return map[string]interface{}{"role": "model", "parts": parts}, true
+	case role == "tool":
+		name := names[stringField(msg, "tool_call_id")]
+		if name == "" {
+			name = stringField(msg, "name")
+		}
+		return map[string]interface{}{
+			"role": "user",
+			"parts": []map[string]interface{}{{
+				"functionResponse": map[string]interface{}{
+					"name":     name,
+					"response": map[string]interface{}{"content": msg["content"]},
+				},
+			}},
+		}, true
+	}
+	return nil, false
+}
+
+// anthropicToolMessage converts an assistant message with tool calls or a tool result to
+// Anthropic tool_use or tool_result blocks. It returns false for other messages.
+func anthropicToolMessage(msg map[string]interface{}) (map[string]interface{}, bool) {
+	switch role, _ := msg["role"].(string); {
+	case role == "assistant" && len(openAIToolCalls(msg)) > 0:
+		var blocks []map[string]interface{}
+		if text, ok := msg["content"].(string); ok && text != "" {
+			blocks = append(blocks, map[string]interface{}{"type": "text", "text": text})
+		}
+		for _, call := range openAIToolCalls(msg) {
+			blocks = append(blocks, map[string]interface{}{
+				"type":  "tool_use",
+				"id":    call.ID,
+				"name":  call.Name,
+				"input": toolArguments(call.Arguments),
+			})
+		}
+		return map[string]interface{}{"role": "assistant", "content": blocks}, true
+	case role == "tool":
+		return map[string]interface{}{
+			"role": "user",
+			"content": []map[string]interface{}{{
+				"type":        "tool_result",
+				"tool_use_id": stringField(msg, "tool_call_id"),
+				"content":     stringField(msg, "content"),
+			}},
+		}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// canViewSecrets reports whether the caller may read upstream and proxy keys in full. Viewers only
+// see masked keys, as they may not export keys either.
+func canViewSecrets(c *gin.Context) bool {
+	return services.RoleAllows(middleware.GetPrincipal(c).Role, models.RoleOperator)
+}
+
+// maskSecret masks a key for display, hiding short keys entirely.
+func maskSecret(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return utils.MaskAPIKey(key)
+}
+
+// maskKeyList masks every key of a comma-separated list.
+func maskKeyList(list string) string {
+	if list == "" {
+		return list
+	}
+	keys := strings.Split(list, ",")
+	for i, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			keys[i] = maskSecret(key)
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// maskProxyKeys hides the proxy keys of a group response.
+func (r *GroupResponse) maskProxyKeys() {
+	r.ProxyKeys = maskKeyList(r.ProxyKeys)
+
+	if len(r.ProxyKeyExpiry) > 0 {
+		expiry := make(datatypes.JSONMap, len(r.ProxyKeyExpiry))
+		for key, value := range r.ProxyKeyExpiry {
+			expiry[maskSecret(key)] = value
+		}
+		r.ProxyKeyExpiry = expiry
+	}
+
+	if keys, ok := r.Config["transcript_proxy_keys"].(string); ok {
+		config := make(datatypes.JSONMap, len(r.Config))
+		for key, value := range r.Config {
+			config[key] = value
+		}
+		config["transcript_proxy_keys"] = maskKeyList(keys)
+		r.Config = config
+	}
+
+	if len(r.ModelAccess) > 0 {
+		var policy models.ModelAccessPolicy
+		if json.Unmarshal(r.ModelAccess, &policy) == nil {
+			maskModelAccessKeys(&policy)
+			r.ModelAccess, _ = json.Marshal(policy)
+		}
+	}
+	if len(r.IPAccess) > 0 {
+		var policy models.IPAccessPolicy
+		if json.Unmarshal(r.IPAccess, &policy) == nil {
+			maskIPAccessKeys(&policy)
+			r.IPAccess, _ = json.Marshal(policy)
+		}
+	}
+}
+
+func maskModelAccessKeys(policy *models.ModelAccessPolicy) {
+	for i := range policy.ProxyKeys {
+		policy.ProxyKeys[i].ProxyKey = maskSecret(policy.ProxyKeys[i].ProxyKey)
+	}
+}
+
+func maskIPAccessKeys(policy *models.IPAccessPolicy) {
+	for i := range policy.ProxyKeys {
+		policy.ProxyKeys[i].ProxyKey = maskSecret(policy.ProxyKeys[i].ProxyKey)
+	}
+}
+
+// maskSettingSecrets hides the proxy keys held in system settings.
+func maskSettingSecrets(setting *models.SystemSettingInfo) {
+	value, ok := setting.Value.(string)
+	if !ok {
+		return
+	}
+	switch setting.Key {
+	case "proxy_keys", "transcript_proxy_keys":
+		setting.Value = maskKeyList(value)
+	case "proxy_key_metadata":
+		var metadata map[string]map[string]string
+		if value == "" || json.Unmarshal([]byte(value), &metadata) != nil {
+			return
+		}
+		masked := make(map[string]map[string]string, len(metadata))
+		for key, fields := range metadata {
+			masked[maskSecret(key)] = fields
+		}
+		if data, err := json.Marshal(masked); err == nil {
+			setting.Value = string(data)
+		}
+	}
+}
+
+// maskConfigVersion hides the proxy keys held in the snapshot of a group or settings version.
+func maskConfigVersion(version *models.ConfigVersion) {
+	if version == nil {
+		return
+	}
+	var fields map[string]any
+	if json.Unmarshal(version.Snapshot, &fields) != nil {
+		return
+	}
+	maskSnapshotFields(version.ResourceType, fields)
+	if data, err := json.Marshal(fields); err == nil {
+		version.Snapshot = data
+	}
+}
+
+// maskConfigChanges hides the proxy keys in the old and new values of version changes.
+func maskConfigChanges(resourceType string, changes []services.ConfigFieldChange) {
+	for i := range changes {
+		fields := map[string]any{changes[i].Field: changes[i].Old}
+		maskSnapshotFields(resourceType, fields)
+		changes[i].Old = fields[changes[i].Field]
+
+		fields = map[string]any{changes[i].Field: changes[i].New}
+		maskSnapshotFields(resourceType, fields)
+		changes[i].New = fields[changes[i].Field]
+	}
+}
+
+// maskSnapshotFields masks the proxy keys among the top-level fields of a snapshot.
+func maskSnapshotFields(resourceType string, fields map[string]any) {
+	switch resourceType {
+	case services.ConfigResourceGroup:
+		if keys, ok := fields["proxy_keys"].(string); ok {
+			fields["proxy_keys"] = maskKeyList(keys)
+		}
+		if config, ok := fields["config"].(map[string]any); ok {
+			if keys, ok := config["transcript_proxy_keys"].(string); ok {
+				config["transcript_proxy_keys"] = maskKeyList(keys)
+			}
+		}
+		for _, field := range []string{"model_access", "ip_access"} {
+			policy, ok := fields[field].(map[string]any)
+			if !ok {
+				continue
+			}
+			entries, _ := policy["proxy_keys"].([]any)
+			for _, entry := range entries {
+				if entry, ok := entry.(map[string]any); ok {
+					if key, ok := entry["proxy_key"].(string); ok {
+						entry["proxy_key"] = maskSecret(key)
+					}
+				}
+			}
+		}
+	case services.ConfigResourceSettings:
+		for key, value := range fields {
+			setting := models.SystemSettingInfo{Key: key, Value: value}
+			maskSettingSecrets(&setting)
+			fields[key] = setting.Value
+		}
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"gpt-load/internal/utils"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 // GetSettings handles the GET /api/settings request.
@@ -19,7 +21,11 @@ func (s *Server) GetSettings(c *gin.Context) {
 	settingsInfo := utils.GenerateSettingsMetadata(&currentSettings)
 
 	// Translate settings info
+	showSecrets := canViewSecrets(c)
 	for i := range settingsInfo {
+		if !showSecrets {
+			maskSettingSecrets(&settingsInfo[i])
+		}
 		// Translate name if it's an i18n key
 		if strings.HasPrefix(settingsInfo[i].Name, "config.") {
 			settingsInfo[i].Name = i18n.Message(c, settingsInfo[i].Name)
@@ -77,12 +83,18 @@ func (s *Server) UpdateSettings(c *gin.Context) {
 		}
 	}
 
+	previous := s.SettingsManager.GetSettings()
+
 	// 更新配置
 	if err := s.SettingsManager.UpdateSettings(settingsMap); err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrDatabase, err.Error()))
 		return
 	}
 
+	if err := s.ConfigVersionService.RecordSettings(previous, settingsMap, services.ConfigActionUpdate); err != nil {
+		logrus.WithError(err).Warn("Failed to record settings version")
+	}
+
 	time.Sleep(100 * time.Millisecond) // 等待异步更新配置
 
 	response.SuccessI18n(c, "settings.update_success", nil)
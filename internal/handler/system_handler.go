@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runtimeResponse adds the state of the proxy to the runtime snapshot.
+type runtimeResponse struct {
+	*services.RuntimeInfo
+	IsMaster              bool  `json:"is_master"`
+	PprofEnabled          bool  `json:"pprof_enabled"`
+	Draining              bool  `json:"draining"`
+	InFlightProxyRequests int64 `json:"in_flight_proxy_requests"`
+}
+
+// GetRuntimeInfo handles GET /api/system/runtime.
+// It reports goroutines, heap and GC statistics, HTTP connections and build information.
+func (s *Server) GetRuntimeInfo(c *gin.Context) {
+	serverConfig := s.config.GetEffectiveServerConfig()
+	response.Success(c, runtimeResponse{
+		RuntimeInfo:           s.RuntimeService.Info(),
+		IsMaster:              serverConfig.IsMaster,
+		PprofEnabled:          serverConfig.EnablePprof,
+		Draining:              s.ProxyServer.Draining(),
+		InFlightProxyRequests: s.ProxyServer.InFlight(),
+	})
+}
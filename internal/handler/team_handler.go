@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TeamRequest is the full state of a team, including its members.
+type TeamRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	UserIDs     []uint `json:"user_ids"`
+}
+
+func (r TeamRequest) params() services.TeamParams {
+	return services.TeamParams{
+		Name:        r.Name,
+		Description: r.Description,
+		UserIDs:     r.UserIDs,
+	}
+}
+
+// GroupTeamRequest assigns a group to a team; a null team_id shares the group with everyone.
+type GroupTeamRequest struct {
+	TeamID *uint `json:"team_id"`
+}
+
+// ListTeams handles GET /api/teams.
+func (s *Server) ListTeams(c *gin.Context) {
+	teams, err := s.TeamService.ListTeams()
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, teams)
+}
+
+// CreateTeam handles POST /api/teams.
+func (s *Server) CreateTeam(c *gin.Context) {
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	team, err := s.TeamService.CreateTeam(req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, team)
+}
+
+// UpdateTeam handles PUT /api/teams/:id.
+func (s *Server) UpdateTeam(c *gin.Context) {
+	id, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	team, err := s.TeamService.UpdateTeam(id, req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, team)
+}
+
+// DeleteTeam handles DELETE /api/teams/:id.
+func (s *Server) DeleteTeam(c *gin.Context) {
+	id, ok := parseTeamID(c)
+	if !ok {
+		return
+	}
+	if s.handleGroupError(c, s.TeamService.DeleteTeam(id)) {
+		return
+	}
+	response.Success(c, nil)
+}
+
+// AssignGroupTeam handles PUT /api/groups/:id/team.
+func (s *Server) AssignGroupTeam(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	var req GroupTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if s.handleGroupError(c, s.TeamService.AssignGroup(uint(id), req.TeamID)) {
+		return
+	}
+	response.SuccessI18n(c, "success.group_team_assigned", nil)
+}
+
+// parseTeamID parses the :id path parameter, writing an error response on failure.
+func parseTeamID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_team_id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// authorizeGroup checks that the group is in the request's group scope. Groups out of scope are
+// reported as not found, so their existence is not revealed. Unrestricted requests pass unchecked.
+func (s *Server) authorizeGroup(c *gin.Context, groupID uint) bool {
+	if services.GroupScopeFromContext(c) == nil {
+		return true
+	}
+	_, ok := s.findGroupByID(c, groupID)
+	return ok
+}
+
+// authorizeGroupName is authorizeGroup for handlers addressing groups by name.
+func (s *Server) authorizeGroupName(c *gin.Context, name string) bool {
+	if services.GroupScopeFromContext(c) == nil {
+		return true
+	}
+	var group models.Group
+	if err := s.DB.Select("id", "team_id").Where("name = ?", name).First(&group).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return false
+	}
+	if !services.GroupScopeFromContext(c).Allows(&group) {
+		response.Error(c, app_errors.ErrResourceNotFound)
+		return false
+	}
+	return true
+}
+
+// visibleGroupIDs returns which of ids are in the request's group scope.
+func (s *Server) visibleGroupIDs(c *gin.Context, ids []uint) (map[uint]bool, error) {
+	var visible []uint
+	err := s.DB.Model(&models.Group{}).
+		Scopes(services.GroupScopeFromContext(c).Groups).
+		Where("id IN ?", ids).
+		Pluck("id", &visible).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uint]bool, len(visible))
+	for _, id := range visible {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// authorizeGroupIDs is authorizeGroup for requests addressing several groups at once.
+func (s *Server) authorizeGroupIDs(c *gin.Context, ids []uint) bool {
+	visible, err := s.visibleGroupIDs(c, ids)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return false
+	}
+	for _, id := range ids {
+		if !visible[id] {
+			response.Error(c, app_errors.ErrResourceNotFound)
+			return false
+		}
+	}
+	return true
+}
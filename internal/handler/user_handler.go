@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserRequest is the full state of a user. An empty password keeps the current one on update.
+type UserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+func (r UserRequest) params() services.UserParams {
+	return services.UserParams{
+		Username: r.Username,
+		Password: r.Password,
+		Role:     r.Role,
+		Disabled: r.Disabled,
+	}
+}
+
+// ChangePasswordRequest is the payload of PUT /api/auth/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// TOTPCodeRequest carries a TOTP code, or a recovery code where those are accepted.
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// currentUserResponse is the signed-in principal plus whether destructive operations ask for a
+// two-factor code.
+type currentUserResponse struct {
+	*services.Principal
+	TwoFactorEnforced bool `json:"two_factor_enforced"`
+}
+
+// GetCurrentUser handles GET /api/auth/me, returning who the request is authenticated as.
+func (s *Server) GetCurrentUser(c *gin.Context) {
+	response.Success(c, currentUserResponse{
+		Principal:         middleware.GetPrincipal(c),
+		TwoFactorEnforced: s.UserService.SecondFactorEnforced(),
+	})
+}
+
+// Logout handles POST /api/auth/logout, ending the session of the request's token.
+func (s *Server) Logout(c *gin.Context) {
+	if err := s.UserService.Logout(c.GetString(middleware.ContextKeyAuthToken)); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.SuccessI18n(c, "auth.logout_success", nil)
+}
+
+// ChangePassword handles PUT /api/auth/password for the signed-in user.
+func (s *Server) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	err := s.UserService.ChangePassword(middleware.GetPrincipal(c), c.GetString(middleware.ContextKeyAuthToken), req.CurrentPassword, req.NewPassword)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.SuccessI18n(c, "success.password_changed", nil)
+}
+
+// SetupTOTP handles POST /api/auth/2fa/setup, returning a new secret for the authenticator app.
+func (s *Server) SetupTOTP(c *gin.Context) {
+	enrollment, err := s.UserService.SetupTOTP(middleware.GetPrincipal(c))
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, enrollment)
+}
+
+// EnableTOTP handles POST /api/auth/2fa/enable, confirming enrollment and returning the recovery
+// codes.
+func (s *Server) EnableTOTP(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	codes, err := s.UserService.EnableTOTP(middleware.GetPrincipal(c), req.Code)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.SuccessI18n(c, "success.two_factor_enabled", gin.H{"recovery_codes": codes})
+}
+
+// DisableTOTP handles POST /api/auth/2fa/disable for the signed-in user.
+func (s *Server) DisableTOTP(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if s.handleGroupError(c, s.UserService.DisableTOTP(middleware.GetPrincipal(c), req.Code)) {
+		return
+	}
+	response.SuccessI18n(c, "success.two_factor_disabled", nil)
+}
+
+// ResetUserTOTP handles DELETE /api/users/:id/2fa, removing the second factor of a user who lost it.
+func (s *Server) ResetUserTOTP(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	if s.handleGroupError(c, s.UserService.ResetTOTP(id)) {
+		return
+	}
+	response.SuccessI18n(c, "success.two_factor_reset", nil)
+}
+
+// ListUsers handles GET /api/users.
+func (s *Server) ListUsers(c *gin.Context) {
+	users, err := s.UserService.ListUsers()
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, gin.H{
+		"users": users,
+		"roles": services.Roles,
+	})
+}
+
+// CreateUser handles POST /api/users.
+func (s *Server) CreateUser(c *gin.Context) {
+	var req UserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	user, err := s.UserService.CreateUser(req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, user)
+}
+
+// UpdateUser handles PUT /api/users/:id.
+func (s *Server) UpdateUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	var req UserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	user, err := s.UserService.UpdateUser(id, req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, user)
+}
+
+// DeleteUser handles DELETE /api/users/:id.
+func (s *Server) DeleteUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	if s.handleGroupError(c, s.UserService.DeleteUser(id)) {
+		return
+	}
+	if s.handleGroupError(c, s.TeamService.RemoveUser(id)) {
+		return
+	}
+	response.Success(c, nil)
+}
+
+// parseUserID parses the :id path parameter, writing an error response on failure.
+func parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_user_id")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookSubscriptionRequest is the full state of a webhook subscription. Saving replaces every
+// field except the secret, which is kept when left empty unless clear_secret is set.
+type WebhookSubscriptionRequest struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	ClearSecret bool     `json:"clear_secret"`
+	Events      []string `json:"events"`
+	GroupID     *uint    `json:"group_id"`
+	Enabled     bool     `json:"enabled"`
+}
+
+func (r WebhookSubscriptionRequest) params() services.WebhookSubscriptionParams {
+	return services.WebhookSubscriptionParams{
+		Name:        r.Name,
+		URL:         r.URL,
+		Secret:      r.Secret,
+		ClearSecret: r.ClearSecret,
+		Events:      r.Events,
+		GroupID:     r.GroupID,
+		Enabled:     r.Enabled,
+	}
+}
+
+// ListWebhooks handles GET /api/webhooks.
+func (s *Server) ListWebhooks(c *gin.Context) {
+	subscriptions, err := s.WebhookService.ListSubscriptions()
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, gin.H{
+		"webhooks": subscriptions,
+		"events":   services.WebhookEventTypes,
+	})
+}
+
+// CreateWebhook handles POST /api/webhooks.
+func (s *Server) CreateWebhook(c *gin.Context) {
+	var req WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	subscription, err := s.WebhookService.CreateSubscription(req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, subscription)
+}
+
+// UpdateWebhook handles PUT /api/webhooks/:id.
+func (s *Server) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	var req WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	subscription, err := s.WebhookService.UpdateSubscription(id, req.params())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, subscription)
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/:id.
+func (s *Server) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	if s.handleGroupError(c, s.WebhookService.DeleteSubscription(id)) {
+		return
+	}
+	response.Success(c, nil)
+}
+
+// TestWebhook handles POST /api/webhooks/:id/test, delivering a ping event to the subscription.
+func (s *Server) TestWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	if s.handleGroupError(c, s.WebhookService.TestSubscription(c.Request.Context(), id)) {
+		return
+	}
+	response.SuccessI18n(c, "success.webhook_test_sent", nil)
+}
+
+// parseWebhookID parses the :id path parameter, writing an error response on failure.
+func parseWebhookID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_webhook_id")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"gpt-load/internal/utils"
+)
+
+// IP family preferences for upstream connections.
+const (
+	IPFamilyAuto       = "auto"
+	IPFamilyIPv4       = "ipv4"
+	IPFamilyIPv6       = "ipv6"
+	IPFamilyPreferIPv4 = "prefer_ipv4"
+	IPFamilyPreferIPv6 = "prefer_ipv6"
+)
+
+// egressDialer dials upstream connections with an IP family preference, an optional source
+// address so that multi-homed hosts use a deterministic egress, and an optional DNS server.
+type egressDialer struct {
+	dialer   *net.Dialer
+	resolver *net.Resolver
+	family   string
+	sourceIP net.IP
+}
+
+func newEgressDialer(config *Config) (*egressDialer, error) {
+	d := &egressDialer{
+		dialer: &net.Dialer{
+			Timeout:   config.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		},
+		resolver: net.DefaultResolver,
+		family:   config.IPFamily,
+	}
+	if d.family == "" {
+		d.family = IPFamilyAuto
+	}
+
+	if config.SourceAddress != "" {
+		d.sourceIP = net.ParseIP(config.SourceAddress)
+		if d.sourceIP == nil {
+			return nil, fmt.Errorf("invalid source address '%s'", config.SourceAddress)
+		}
+		d.dialer.LocalAddr = &net.TCPAddr{IP: d.sourceIP}
+		// A source address can only reach destinations of its own family, so it decides the family.
+		d.family = IPFamilyIPv6
+		if d.sourceIP.To4() != nil {
+			d.family = IPFamilyIPv4
+		}
+	}
+
+	if config.DNSResolver != "" {
+		server, err := utils.ParseDNSResolver(config.DNSResolver)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNS resolver: %w", err)
+		}
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: config.ConnectTimeout}).DialContext(ctx, network, server)
+			},
+		}
+		d.dialer.Resolver = d.resolver
+	}
+	return d, nil
+}
+
+// DialContext connects to address following the family preference.
+func (d *egressDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	switch d.family {
+	case IPFamilyIPv4:
+		return d.dialer.DialContext(ctx, restrictNetwork(network, "4"), address)
+	case IPFamilyIPv6:
+		return d.dialer.DialContext(ctx, restrictNetwork(network, "6"), address)
+	case IPFamilyPreferIPv4, IPFamilyPreferIPv6:
+		return d.dialPreferred(ctx, network, address)
+	default:
+		return d.dialer.DialContext(ctx, network, address)
+	}
+}
+
+// dialPreferred resolves the host and tries the addresses of the preferred family before the others.
+func (d *egressDialer) dialPreferred(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	wantIPv4 := d.family == IPFamilyPreferIPv4
+	var preferred, fallback []net.IPAddr
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == wantIPv4 {
+			preferred = append(preferred, addr)
+		} else {
+			fallback = append(fallback, addr)
+		}
+	}
+
+	var errs []error
+	for _, addr := range append(preferred, fallback...) {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// restrictNetwork turns "tcp" into "tcp4" or "tcp6"; other networks are left alone.
+func restrictNetwork(network, version string) string {
+	if network == "tcp" || network == "udp" {
+		return network + version
+	}
+	return network
+}
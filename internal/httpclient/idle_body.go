@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrStreamIdleTimeout is returned when a streamed response body stays silent for longer than
+// its idle timeout.
+var ErrStreamIdleTimeout = errors.New("upstream stream idle timeout exceeded")
+
+// WithIdleTimeout wraps a streamed response body so that it is closed when no data arrives for
+// timeout, unblocking readers with ErrStreamIdleTimeout. A zero timeout returns body unchanged.
+func WithIdleTimeout(body io.ReadCloser, timeout time.Duration) io.ReadCloser {
+	if timeout <= 0 {
+		return body
+	}
+	b := &idleTimeoutBody{body: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, b.expire)
+	return b
+}
+
+// idleTimeoutBody closes the wrapped body when its timer fires; every read that returns data
+// restarts the timer.
+type idleTimeoutBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	mu      sync.Mutex
+	expired bool
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	expired := b.expired
+	b.mu.Unlock()
+	if expired {
+		return n, ErrStreamIdleTimeout
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.body.Close()
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -8,6 +9,8 @@ import (
 	"sync"
 	"time"
 
+	"gpt-load/internal/types"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -27,6 +30,50 @@ type Config struct {
 	TLSHandshakeTimeout   time.Duration
 	ExpectContinueTimeout time.Duration
 	ProxyURL              string
+	// IPFamily is one of the IPFamily* preferences; empty means auto.
+	IPFamily string
+	// SourceAddress binds upstream connections to a local IP when set.
+	SourceAddress string
+	// DNSResolver is a DNS server (IP or IP:port) used instead of the system resolver when set.
+	DNSResolver string
+}
+
+// NewConfig returns the client configuration for regular upstream requests of a group with the
+// given effective settings.
+func NewConfig(settings *types.SystemSettings) *Config {
+	return &Config{
+		ConnectTimeout:        time.Duration(settings.ConnectTimeout) * time.Second,
+		RequestTimeout:        time.Duration(settings.RequestTimeout) * time.Second,
+		IdleConnTimeout:       time.Duration(settings.IdleConnTimeout) * time.Second,
+		MaxIdleConns:          settings.MaxIdleConns,
+		MaxIdleConnsPerHost:   settings.MaxIdleConnsPerHost,
+		ResponseHeaderTimeout: time.Duration(settings.ResponseHeaderTimeout) * time.Second,
+		ProxyURL:              settings.ProxyURL,
+		IPFamily:              settings.EgressIPFamily,
+		SourceAddress:         settings.EgressSourceAddress,
+		DNSResolver:           settings.DNSResolver,
+		DisableCompression:    false,
+		WriteBufferSize:       32 * 1024,
+		ReadBufferSize:        32 * 1024,
+		ForceAttemptHTTP2:     !settings.DisableHTTP2,
+		TLSHandshakeTimeout:   time.Duration(settings.TLSHandshakeTimeout) * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// ForStreaming derives the configuration for streaming requests. Streams have no overall timeout,
+// as long completions may run for many minutes; stalled streams are cut off by the stream idle
+// timeout instead.
+func (c *Config) ForStreaming() *Config {
+	stream := *c
+	stream.RequestTimeout = 0
+	stream.DisableCompression = true
+	stream.WriteBufferSize = 0
+	stream.ReadBufferSize = 0
+	// Use a larger, independent connection pool for streaming clients to avoid exhaustion.
+	stream.MaxIdleConns = max(c.MaxIdleConns*2, 50)
+	stream.MaxIdleConnsPerHost = max(c.MaxIdleConnsPerHost*2, 20)
+	return &stream
 }
 
 // HTTPClientManager manages the lifecycle of HTTP clients.
@@ -68,11 +115,18 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 	}
 
 	// Create a new transport and client with the specified configuration.
+	dialContext := (&net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	if dialer, err := newEgressDialer(config); err != nil {
+		logrus.Warnf("Invalid egress settings, falling back to the default dialer: %v", err)
+	} else {
+		dialContext = dialer.DialContext
+	}
+
 	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   config.ConnectTimeout,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		DialContext:           dialContext,
 		ForceAttemptHTTP2:     config.ForceAttemptHTTP2,
 		MaxIdleConns:          config.MaxIdleConns,
 		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
@@ -84,6 +138,11 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 		WriteBufferSize:       config.WriteBufferSize,
 		ReadBufferSize:        config.ReadBufferSize,
 	}
+	// Without ForceAttemptHTTP2 a custom dialer already disables HTTP/2; an empty TLSNextProto
+	// keeps it off regardless of GODEBUG settings.
+	if !config.ForceAttemptHTTP2 {
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
 
 	// Set http proxy.
 	if config.ProxyURL != "" {
@@ -110,7 +169,7 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 // getFingerprint generates a unique string representation of the client configuration.
 func (c *Config) getFingerprint() string {
 	return fmt.Sprintf(
-		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s",
+		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s|ipf:%s|src:%s|dns:%s",
 		c.ConnectTimeout.Seconds(),
 		c.RequestTimeout.Seconds(),
 		c.IdleConnTimeout.Seconds(),
@@ -124,5 +183,8 @@ func (c *Config) getFingerprint() string {
 		c.TLSHandshakeTimeout.Seconds(),
 		c.ExpectContinueTimeout.Seconds(),
 		c.ProxyURL,
+		c.IPFamily,
+		c.SourceAddress,
+		c.DNSResolver,
 	)
 }
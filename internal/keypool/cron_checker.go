@@ -118,14 +118,16 @@ func (s *CronChecker) validateGroupKeys(group *models.Group) {
 	groupProcessStart := time.Now()
 
 	var invalidKeys []models.APIKey
-	err := s.DB.Where("group_id = ? AND status = ?", group.ID, models.KeyStatusInvalid).Find(&invalidKeys).Error
+	// Expired keys are left out: they stay disabled whether or not they still work.
+	err := s.DB.Where("group_id = ? AND status = ?", group.ID, models.KeyStatusInvalid).
+		Scopes(notExpired(groupProcessStart)).Find(&invalidKeys).Error
 	if err != nil {
 		logrus.Errorf("CronChecker: Failed to get invalid keys for group %s: %v", group.Name, err)
 		return
 	}
 
 	if len(invalidKeys) == 0 {
-		if err := s.DB.Model(group).Update("last_validated_at", time.Now()).Error; err != nil {
+		if err := s.DB.Model(group).UpdateColumn("last_validated_at", time.Now()).Error; err != nil {
 			logrus.Errorf("CronChecker: Failed to update last_validated_at for group %s: %v", group.Name, err)
 		}
 		logrus.Infof("CronChecker: Group '%s' has no invalid keys to check.", group.Name)
@@ -182,7 +184,7 @@ DistributeLoop:
 
 	keyWg.Wait()
 
-	if err := s.DB.Model(group).Update("last_validated_at", time.Now()).Error; err != nil {
+	if err := s.DB.Model(group).UpdateColumn("last_validated_at", time.Now()).Error; err != nil {
 		logrus.Errorf("CronChecker: Failed to update last_validated_at for group %s: %v", group.Name, err)
 	}
 
@@ -7,8 +7,11 @@ import (
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
 	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
 	"math/rand"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,29 +20,36 @@ import (
 	"gorm.io/gorm"
 )
 
+// keyBatchSize 限制单条 SQL 语句中处理的 Key 数量，避免超出数据库的参数上限。
+const keyBatchSize = 500
+
 type KeyProvider struct {
 	db              *gorm.DB
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	encryptionSvc   encryption.Service
+	notifier        *notification.Service
 }
 
 // NewProvider 创建一个新的 KeyProvider 实例。
-func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service) *KeyProvider {
+func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service, notifier *notification.Service) *KeyProvider {
 	return &KeyProvider{
 		db:              db,
 		store:           store,
 		settingsManager: settingsManager,
 		encryptionSvc:   encryptionSvc,
+		notifier:        notifier,
 	}
 }
 
 // SelectKey 为指定的分组原子性地选择并轮换一个可用的 APIKey。
 func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-
-	// 1. Atomically rotate the key ID from the list
-	keyIDStr, err := p.store.Rotate(activeKeysListKey)
+	// 1. Atomically rotate the key ID from the primary list, falling back to the backup list once
+	// the primary tier has no active keys left
+	keyIDStr, err := p.store.Rotate(tierListKey(groupID, models.KeyTierPrimary))
+	if errors.Is(err, store.ErrNotFound) {
+		keyIDStr, err = p.store.Rotate(tierListKey(groupID, models.KeyTierBackup))
+	}
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, app_errors.ErrNoActiveKeys
@@ -59,9 +69,31 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
 	}
 
-	// 3. Manually unmarshal the map into an APIKey struct
+	return p.keyFromDetails(groupID, keyID, keyDetails), nil
+}
+
+// GetActiveKey returns the given key when it still belongs to the group and is active, so that a
+// pinned key can be reused; it returns ErrNotFound otherwise.
+func (p *KeyProvider) GetActiveKey(groupID, keyID uint) (*models.APIKey, error) {
+	keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+	}
+	if keyDetails["group_id"] != strconv.FormatUint(uint64(groupID), 10) || keyDetails["status"] != models.KeyStatusActive {
+		return nil, store.ErrNotFound
+	}
+	return p.keyFromDetails(groupID, uint64(keyID), keyDetails), nil
+}
+
+// keyFromDetails builds an APIKey from its store hash, decrypting the key value.
+func (p *KeyProvider) keyFromDetails(groupID uint, keyID uint64, keyDetails map[string]string) *models.APIKey {
+	// Manually unmarshal the map into an APIKey struct
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	createdAt, _ := strconv.ParseInt(keyDetails["created_at"], 10, 64)
+	rpmLimit, _ := strconv.Atoi(keyDetails["rpm_limit"])
+	tpmLimit, _ := strconv.Atoi(keyDetails["tpm_limit"])
+	dailySpendLimit, _ := strconv.ParseFloat(keyDetails["daily_spend_limit"], 64)
+	cooldownUntil, _ := strconv.ParseInt(keyDetails["cooldown_until"], 10, 64)
 
 	// Decrypt the key value for use by channels
 	encryptedKeyValue := keyDetails["key_string"]
@@ -76,27 +108,40 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 	}
 
 	apiKey := &models.APIKey{
-		ID:           uint(keyID),
-		KeyValue:     decryptedKeyValue,
-		Status:       keyDetails["status"],
-		FailureCount: failureCount,
-		GroupID:      groupID,
-		CreatedAt:    time.Unix(createdAt, 0),
+		ID:              uint(keyID),
+		KeyValue:        decryptedKeyValue,
+		Status:          keyDetails["status"],
+		Tier:            keyDetails["tier"],
+		FailureCount:    failureCount,
+		GroupID:         groupID,
+		RPMLimit:        rpmLimit,
+		TPMLimit:        tpmLimit,
+		DailySpendLimit: dailySpendLimit,
+		CreatedAt:       time.Unix(createdAt, 0),
 	}
 
-	return apiKey, nil
+	if apiKey.Tier == "" {
+		apiKey.Tier = models.KeyTierPrimary
+	}
+	if cooldownUntil > 0 {
+		until := time.Unix(cooldownUntil, 0)
+		apiKey.CooldownUntil = &until
+	}
+
+	return apiKey
 }
 
-// UpdateStatus 异步地提交一个 Key 状态更新任务。
-func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, errorMessage string) {
+// UpdateStatus 异步地提交一个 Key 状态更新任务，并将结果记录为 Key 的健康事件。
+// source 为 models.KeyHealthSourceValidation 或 models.KeyHealthSourceProxy。
+func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, source string, isSuccess bool, errorMessage string) {
 	go func() {
 		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
-		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
 
 		if isSuccess {
-			if err := p.handleSuccess(apiKey.ID, keyHashKey, activeKeysListKey); err != nil {
+			if err := p.handleSuccess(apiKey, group, keyHashKey); err != nil {
 				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key success")
 			}
+			p.recordHealthEvent(apiKey, group, source, true, "", false)
 		} else {
 			if app_errors.IsUnCounted(errorMessage) {
 				logrus.WithFields(logrus.Fields{
@@ -104,14 +149,57 @@ func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, i
 					"error": errorMessage,
 				}).Debug("Uncounted error, skipping failure handling")
 			} else {
-				if err := p.handleFailure(apiKey, group, keyHashKey, activeKeysListKey); err != nil {
+				blacklisted, err := p.handleFailure(apiKey, group, keyHashKey, "")
+				if err != nil {
 					logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key failure")
 				}
+				p.recordHealthEvent(apiKey, group, source, false, errorMessage, blacklisted)
 			}
 		}
 	}()
 }
 
+// DisableKey 在上游错误表明 Key 本身已失效（认证失败、额度耗尽）时异步地立即禁用该 Key，
+// 不等待达到拉黑阈值。reason 为错误分类，写入通知；被禁用的 Key 仍会由定时验证恢复。
+func (p *KeyProvider) DisableKey(apiKey *models.APIKey, group *models.Group, reason string, errorMessage string) {
+	go func() {
+		blacklisted, err := p.handleFailure(apiKey, group, fmt.Sprintf("key:%d", apiKey.ID), reason)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to disable key")
+		}
+		p.recordHealthEvent(apiKey, group, models.KeyHealthSourceProxy, false, errorMessage, blacklisted)
+	}()
+}
+
+// CooldownKey 在上游返回 429 并给出重试时间后，让选 Key 时跳过该 Key 直到 until，而不计为失败。
+// Store 中的状态同步更新，以便本次请求的重试立即换用其他 Key；数据库和健康事件异步写入。
+func (p *KeyProvider) CooldownKey(apiKey *models.APIKey, group *models.Group, until time.Time, errorMessage string) {
+	if err := p.store.HSet(fmt.Sprintf("key:%d", apiKey.ID), map[string]any{"cooldown_until": until.Unix()}); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to set key cooldown in store")
+	}
+	go func() {
+		if err := p.db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("cooldown_until", until).Error; err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to save key cooldown")
+		}
+		p.recordHealthEvent(apiKey, group, models.KeyHealthSourceProxy, false, errorMessage, false)
+	}()
+}
+
+// recordHealthEvent 保存一次验证结果或代理失败，供 Key 健康历史查询使用。
+func (p *KeyProvider) recordHealthEvent(apiKey *models.APIKey, group *models.Group, source string, isSuccess bool, errorMessage string, disabled bool) {
+	event := models.KeyHealthEvent{
+		KeyID:        apiKey.ID,
+		GroupID:      group.ID,
+		Source:       source,
+		IsSuccess:    isSuccess,
+		ErrorMessage: strings.ToValidUTF8(utils.TruncateString(errorMessage, 500), ""),
+		Disabled:     disabled,
+	}
+	if err := p.db.Create(&event).Error; err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Warn("Failed to record key health event")
+	}
+}
+
 // executeTransactionWithRetry wraps a database transaction with a retry mechanism.
 func (p *KeyProvider) executeTransactionWithRetry(operation func(tx *gorm.DB) error) error {
 	const maxRetries = 3
@@ -139,7 +227,8 @@ func (p *KeyProvider) executeTransactionWithRetry(operation func(tx *gorm.DB) er
 	return err
 }
 
-func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey string) error {
+func (p *KeyProvider) handleSuccess(apiKey *models.APIKey, group *models.Group, keyHashKey string) error {
+	keyID := apiKey.ID
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
@@ -152,14 +241,17 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 		return nil
 	}
 
-	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+	recovered := false
+	err = p.executeTransactionWithRetry(func(tx *gorm.DB) error {
 		var key models.APIKey
 		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, keyID).Error; err != nil {
 			return fmt.Errorf("failed to lock key %d for update: %w", keyID, err)
 		}
 
+		// An expired key stays disabled even though it still works upstream.
+		recovered = !isActive && (key.ExpiresAt == nil || time.Now().Before(*key.ExpiresAt))
 		updates := map[string]any{"failure_count": 0}
-		if !isActive {
+		if recovered {
 			updates["status"] = models.KeyStatusActive
 		}
 
@@ -171,28 +263,44 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 			return fmt.Errorf("failed to update key details in store: %w", err)
 		}
 
-		if !isActive {
+		if recovered {
 			logrus.WithField("keyID", keyID).Debug("Key has recovered and is being restored to active pool.")
-			if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+			if err := p.removeFromActiveLists(key.GroupID, keyID); err != nil {
 				return fmt.Errorf("failed to LRem key before LPush on recovery: %w", err)
 			}
-			if err := p.store.LPush(activeKeysListKey, keyID); err != nil {
+			if err := p.store.LPush(tierListKey(key.GroupID, key.Tier), keyID); err != nil {
 				return fmt.Errorf("failed to LPush key back to active list: %w", err)
 			}
 		}
 
 		return nil
 	})
+
+	if err == nil && recovered {
+		p.notifier.Notify(notification.Event{
+			Category:  models.NotificationCategoryKey,
+			Severity:  models.NotificationSeveritySuccess,
+			Event:     notification.EventKeyRecovered,
+			Message:   fmt.Sprintf("Key %s in group '%s' recovered and is active again", utils.MaskAPIKey(apiKey.KeyValue), group.Name),
+			Params:    map[string]any{"key_id": apiKey.ID, "key": utils.MaskAPIKey(apiKey.KeyValue)},
+			GroupID:   group.ID,
+			GroupName: group.Name,
+			Merge:     true,
+		})
+	}
+	return err
 }
 
-func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string) error {
+// handleFailure 记录一次失败，返回 Key 是否因达到黑名单阈值而被停用。
+// disableReason 非空时无论阈值都立即停用。
+func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey string, disableReason string) (bool, error) {
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
-		return fmt.Errorf("failed to get key details from store: %w", err)
+		return false, fmt.Errorf("failed to get key details from store: %w", err)
 	}
 
 	if keyDetails["status"] == models.KeyStatusInvalid {
-		return nil
+		return false, nil
 	}
 
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
@@ -200,7 +308,9 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 	// 获取该分组的有效配置
 	blacklistThreshold := group.EffectiveConfig.BlacklistThreshold
 
-	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+	blacklisted := false
+	err = p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		blacklisted = false
 		var key models.APIKey
 		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, apiKey.ID).Error; err != nil {
 			return fmt.Errorf("failed to lock key %d for update: %w", apiKey.ID, err)
@@ -209,7 +319,7 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 		newFailureCount := failureCount + 1
 
 		updates := map[string]any{"failure_count": newFailureCount}
-		shouldBlacklist := blacklistThreshold > 0 && newFailureCount >= int64(blacklistThreshold)
+		shouldBlacklist := disableReason != "" || (blacklistThreshold > 0 && newFailureCount >= int64(blacklistThreshold))
 		if shouldBlacklist {
 			updates["status"] = models.KeyStatusInvalid
 		}
@@ -223,17 +333,40 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 		}
 
 		if shouldBlacklist {
-			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "threshold": blacklistThreshold}).Warn("Key has reached blacklist threshold, disabling.")
-			if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
+			if disableReason != "" {
+				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "reason": disableReason}).Warn("Upstream error shows the key is unusable, disabling.")
+			} else {
+				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "threshold": blacklistThreshold}).Warn("Key has reached blacklist threshold, disabling.")
+			}
+			if err := p.removeFromActiveLists(key.GroupID, apiKey.ID); err != nil {
 				return fmt.Errorf("failed to LRem key from active list: %w", err)
 			}
 			if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusInvalid}); err != nil {
 				return fmt.Errorf("failed to update key status to invalid in store: %w", err)
 			}
+			blacklisted = true
 		}
 
 		return nil
 	})
+
+	if err == nil && blacklisted {
+		message := fmt.Sprintf("Key %s in group '%s' was disabled after %d failures", utils.MaskAPIKey(apiKey.KeyValue), group.Name, failureCount+1)
+		if disableReason != "" {
+			message = fmt.Sprintf("Key %s in group '%s' was disabled after an upstream %s error", utils.MaskAPIKey(apiKey.KeyValue), group.Name, disableReason)
+		}
+		p.notifier.Notify(notification.Event{
+			Category:  models.NotificationCategoryKey,
+			Severity:  models.NotificationSeverityWarning,
+			Event:     notification.EventKeyDisabled,
+			Message:   message,
+			Params:    map[string]any{"key_id": apiKey.ID, "key": utils.MaskAPIKey(apiKey.KeyValue), "failures": failureCount + 1},
+			GroupID:   group.ID,
+			GroupName: group.Name,
+			Merge:     true,
+		})
+	}
+	return err == nil && blacklisted, err
 }
 
 // LoadKeysFromDB 从数据库加载所有分组和密钥，并填充到 Store 中。
@@ -241,7 +374,7 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 	logrus.Debug("First time startup, loading keys from DB...")
 
 	// 1. 分批从数据库加载并使用 Pipeline 写入 Redis
-	allActiveKeyIDs := make(map[uint][]any)
+	allActiveKeyIDs := make(map[string][]any)
 	batchSize := 1000
 	var batchKeys []*models.APIKey
 
@@ -266,7 +399,8 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 			}
 
 			if key.Status == models.KeyStatusActive {
-				allActiveKeyIDs[key.GroupID] = append(allActiveKeyIDs[key.GroupID], key.ID)
+				listKey := tierListKey(key.GroupID, key.Tier)
+				allActiveKeyIDs[listKey] = append(allActiveKeyIDs[listKey], key.ID)
 			}
 		}
 
@@ -284,12 +418,11 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 
 	// 2. 更新所有分组的 active_keys 列表
 	logrus.Info("Updating active key lists for all groups...")
-	for groupID, activeIDs := range allActiveKeyIDs {
+	for listKey, activeIDs := range allActiveKeyIDs {
 		if len(activeIDs) > 0 {
-			activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-			p.store.Delete(activeKeysListKey)
-			if err := p.store.LPush(activeKeysListKey, activeIDs...); err != nil {
-				logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to LPush active keys for group")
+			p.store.Delete(listKey)
+			if err := p.store.LPush(listKey, activeIDs...); err != nil {
+				logrus.WithFields(logrus.Fields{"list": listKey, "error": err}).Error("Failed to LPush active keys for group")
 			}
 		}
 	}
@@ -297,14 +430,14 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 	return nil
 }
 
-// AddKeys 批量添加新的 Key 到池和数据库中。
+// AddKeys 批量添加新的 Key 到池和数据库中，所有 Key 在同一个事务中写入。
 func (p *KeyProvider) AddKeys(groupID uint, keys []models.APIKey) error {
 	if len(keys) == 0 {
 		return nil
 	}
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&keys).Error; err != nil {
+		if err := tx.CreateInBatches(&keys, keyBatchSize).Error; err != nil {
 			return err
 		}
 
@@ -320,43 +453,28 @@ func (p *KeyProvider) AddKeys(groupID uint, keys []models.APIKey) error {
 	return err
 }
 
-// RemoveKeys 批量从池和数据库中移除 Key。
-func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) (int64, error) {
+// RemoveKeys 批量从池和数据库中移除 Key，返回被删除的 Key。
+func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) ([]models.APIKey, error) {
 	if len(keyValues) == 0 {
-		return 0, nil
+		return nil, nil
 	}
 
 	var keysToDelete []models.APIKey
-	var deletedCount int64
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
-		var keyHashes []string
-		for _, keyValue := range keyValues {
-			keyHash := p.encryptionSvc.Hash(keyValue)
-			if keyHash != "" {
-				keyHashes = append(keyHashes, keyHash)
-			}
-		}
-
-		if len(keyHashes) == 0 {
-			return nil
-		}
-
-		if err := tx.Where("group_id = ? AND key_hash IN ?", groupID, keyHashes).Find(&keysToDelete).Error; err != nil {
+		var err error
+		keysToDelete, err = p.findKeysByValues(tx, groupID, keyValues, "")
+		if err != nil || len(keysToDelete) == 0 {
 			return err
 		}
 
-		if len(keysToDelete) == 0 {
-			return nil
-		}
-
-		keyIDsToDelete := pluckIDs(keysToDelete)
-
-		result := tx.Where("id IN ?", keyIDsToDelete).Delete(&models.APIKey{})
-		if result.Error != nil {
-			return result.Error
+		ids := pluckIDs(keysToDelete)
+		for i := 0; i < len(ids); i += keyBatchSize {
+			end := min(i+keyBatchSize, len(ids))
+			if err := tx.Where("id IN ?", ids[i:end]).Delete(&models.APIKey{}).Error; err != nil {
+				return err
+			}
 		}
-		deletedCount = result.RowsAffected
 
 		for _, key := range keysToDelete {
 			if err := p.removeKeyFromStore(key.ID, key.GroupID); err != nil {
@@ -367,8 +485,11 @@ func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) (int64, error
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return deletedCount, err
+	return keysToDelete, nil
 }
 
 // RestoreKeys 恢复组内所有无效的 Key。
@@ -377,7 +498,8 @@ func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
 	var restoredCount int64
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("group_id = ? AND status = ?", groupID, models.KeyStatusInvalid).Find(&invalidKeys).Error; err != nil {
+		if err := tx.Where("group_id = ? AND status = ?", groupID, models.KeyStatusInvalid).
+			Scopes(notExpired(time.Now())).Find(&invalidKeys).Error; err != nil {
 			return err
 		}
 
@@ -389,11 +511,15 @@ func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
 			"status":        models.KeyStatusActive,
 			"failure_count": 0,
 		}
-		result := tx.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusInvalid).Updates(updates)
-		if result.Error != nil {
-			return result.Error
+		ids := pluckIDs(invalidKeys)
+		for i := 0; i < len(ids); i += keyBatchSize {
+			end := min(i+keyBatchSize, len(ids))
+			result := tx.Model(&models.APIKey{}).Where("id IN ?", ids[i:end]).Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			restoredCount += result.RowsAffected
 		}
-		restoredCount = result.RowsAffected
 
 		for _, key := range invalidKeys {
 			key.Status = models.KeyStatusActive
@@ -409,61 +535,186 @@ func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
 	return restoredCount, err
 }
 
-// RestoreMultipleKeys 恢复指定的 Key。
-func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int64, error) {
+// RestoreMultipleKeys 恢复指定的无效 Key，返回被恢复的 Key。
+func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) ([]models.APIKey, error) {
 	if len(keyValues) == 0 {
-		return 0, nil
+		return nil, nil
 	}
 
 	var keysToRestore []models.APIKey
-	var restoredCount int64
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
-		var keyHashes []string
-		for _, keyValue := range keyValues {
-			keyHash := p.encryptionSvc.Hash(keyValue)
-			if keyHash != "" {
-				keyHashes = append(keyHashes, keyHash)
-			}
-		}
-
-		if len(keyHashes) == 0 {
-			return nil
-		}
-
-		if err := tx.Where("group_id = ? AND key_hash IN ? AND status = ?", groupID, keyHashes, models.KeyStatusInvalid).Find(&keysToRestore).Error; err != nil {
+		var err error
+		keysToRestore, err = p.findKeysByValues(tx, groupID, keyValues, models.KeyStatusInvalid)
+		if err != nil {
 			return err
 		}
-
+		now := time.Now()
+		keysToRestore = slices.DeleteFunc(keysToRestore, func(key models.APIKey) bool {
+			return key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)
+		})
 		if len(keysToRestore) == 0 {
 			return nil
 		}
 
-		keyIDsToRestore := pluckIDs(keysToRestore)
-
 		updates := map[string]any{
 			"status":        models.KeyStatusActive,
 			"failure_count": 0,
 		}
-		result := tx.Model(&models.APIKey{}).Where("id IN ?", keyIDsToRestore).Updates(updates)
-		if result.Error != nil {
-			return result.Error
+		ids := pluckIDs(keysToRestore)
+		for i := 0; i < len(ids); i += keyBatchSize {
+			end := min(i+keyBatchSize, len(ids))
+			if err := tx.Model(&models.APIKey{}).Where("id IN ?", ids[i:end]).Updates(updates).Error; err != nil {
+				return err
+			}
 		}
-		restoredCount = result.RowsAffected
 
-		for _, key := range keysToRestore {
-			key.Status = models.KeyStatusActive
-			key.FailureCount = 0
-			if err := p.addKeyToStore(&key); err != nil {
-				logrus.WithFields(logrus.Fields{"keyID": key.ID, "error": err}).Error("Failed to restore key in store after DB update")
+		for i := range keysToRestore {
+			keysToRestore[i].Status = models.KeyStatusActive
+			keysToRestore[i].FailureCount = 0
+			if err := p.addKeyToStore(&keysToRestore[i]); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": keysToRestore[i].ID, "error": err}).Error("Failed to restore key in store after DB update")
 				return err
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keysToRestore, nil
+}
+
+// findKeysByValues 按明文查找组内的 Key（包括尚未迁移到当前加密密钥的 Key），status 为空时不过滤状态。
+func (p *KeyProvider) findKeysByValues(tx *gorm.DB, groupID uint, keyValues []string, status string) ([]models.APIKey, error) {
+	var keyHashes []string
+	for _, keyValue := range keyValues {
+		keyHashes = append(keyHashes, p.encryptionSvc.LookupHashes(keyValue)...)
+	}
+
+	var keys []models.APIKey
+	for i := 0; i < len(keyHashes); i += keyBatchSize {
+		end := min(i+keyBatchSize, len(keyHashes))
+		query := tx.Where("group_id = ? AND key_hash IN ?", groupID, keyHashes[i:end])
+		if status != "" {
+			query = query.Where("status = ?", status)
+		}
+		var batch []models.APIKey
+		if err := query.Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+	}
+	return keys, nil
+}
+
+// ExpireKeys 将已到期的活跃 Key 标记为无效并移出活跃列表，返回实际被停用的 Key。
+func (p *KeyProvider) ExpireKeys(keys []models.APIKey) ([]models.APIKey, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	var expired []models.APIKey
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		expired = nil
+		for _, key := range keys {
+			// The status guard skips keys that were disabled or deleted since they were read.
+			result := tx.Model(&models.APIKey{}).Where("id = ? AND status = ?", key.ID, models.KeyStatusActive).
+				Update("status", models.KeyStatusInvalid)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				expired = append(expired, key)
+			}
+		}
+
+		for _, key := range expired {
+			if err := p.removeFromActiveLists(key.GroupID, key.ID); err != nil {
+				return fmt.Errorf("failed to LRem expired key %d from active list: %w", key.ID, err)
+			}
+			if err := p.store.HSet(fmt.Sprintf("key:%d", key.ID), map[string]any{"status": models.KeyStatusInvalid}); err != nil {
+				return fmt.Errorf("failed to update expired key %d in store: %w", key.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// UpdateKeyLimits 将 Key 的 RPM/TPM/每日消费上限同步到 Store，供选 Key 时使用。
+func (p *KeyProvider) UpdateKeyLimits(key *models.APIKey) error {
+	return p.store.HSet(fmt.Sprintf("key:%d", key.ID), map[string]any{
+		"rpm_limit":         key.RPMLimit,
+		"tpm_limit":         key.TPMLimit,
+		"daily_spend_limit": key.DailySpendLimit,
+	})
+}
 
-	return restoredCount, err
+// SetKeysTier 将组内指定的 Key 归入 primary 或 backup 层级，返回匹配到的 Key。
+func (p *KeyProvider) SetKeysTier(groupID uint, keyValues []string, tier string) ([]models.APIKey, error) {
+	if len(keyValues) == 0 {
+		return nil, nil
+	}
+
+	var keys []models.APIKey
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		keys, err = p.findKeysByValues(tx, groupID, keyValues, "")
+		if err != nil {
+			return err
+		}
+		return p.applyKeysTier(tx, keys, tier)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// SetKeyTier 将单个 Key 归入 primary 或 backup 层级。
+func (p *KeyProvider) SetKeyTier(key *models.APIKey, tier string) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		keys := []models.APIKey{*key}
+		if err := p.applyKeysTier(tx, keys, tier); err != nil {
+			return err
+		}
+		key.Tier = tier
+		return nil
+	})
+}
+
+// applyKeysTier 更新 Key 的层级，并将有效的 Key 移到对应层级的列表中。
+func (p *KeyProvider) applyKeysTier(tx *gorm.DB, keys []models.APIKey, tier string) error {
+	ids := pluckIDs(keys)
+	for i := 0; i < len(ids); i += keyBatchSize {
+		end := min(i+keyBatchSize, len(ids))
+		if err := tx.Model(&models.APIKey{}).Where("id IN ?", ids[i:end]).Update("tier", tier).Error; err != nil {
+			return err
+		}
+	}
+
+	for i := range keys {
+		keys[i].Tier = tier
+		if err := p.store.HSet(fmt.Sprintf("key:%d", keys[i].ID), map[string]any{"tier": tier}); err != nil {
+			return fmt.Errorf("failed to update tier of key %d in store: %w", keys[i].ID, err)
+		}
+		if keys[i].Status != models.KeyStatusActive {
+			continue
+		}
+		if err := p.removeFromActiveLists(keys[i].GroupID, keys[i].ID); err != nil {
+			return fmt.Errorf("failed to LRem key %d before moving it to the %s tier: %w", keys[i].ID, tier, err)
+		}
+		if err := p.store.LPush(tierListKey(keys[i].GroupID, tier), keys[i].ID); err != nil {
+			return fmt.Errorf("failed to LPush key %d to the %s tier: %w", keys[i].ID, tier, err)
+		}
+	}
+	return nil
 }
 
 // RemoveInvalidKeys 移除组内所有无效的 Key。
@@ -525,10 +776,8 @@ func (p *KeyProvider) RemoveKeysFromStore(groupID uint, keyIDs []uint) error {
 		return nil
 	}
 
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-
-	// 第一步：直接删除整个 active_keys 列表
-	if err := p.store.Delete(activeKeysListKey); err != nil {
+	// 第一步：直接删除各层级的有效 Key 列表
+	if err := p.store.Del(tierListKey(groupID, models.KeyTierPrimary), tierListKey(groupID, models.KeyTierBackup)); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"groupID": groupID,
 			"error":   err,
@@ -566,11 +815,10 @@ func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 
 	// 2. If active, add to the active LIST
 	if key.Status == models.KeyStatusActive {
-		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
-		if err := p.store.LRem(activeKeysListKey, 0, key.ID); err != nil {
+		if err := p.removeFromActiveLists(key.GroupID, key.ID); err != nil {
 			return fmt.Errorf("failed to LRem key %d before LPush for group %d: %w", key.ID, key.GroupID, err)
 		}
-		if err := p.store.LPush(activeKeysListKey, key.ID); err != nil {
+		if err := p.store.LPush(tierListKey(key.GroupID, key.Tier), key.ID); err != nil {
 			return fmt.Errorf("failed to LPush key %d to group %d: %w", key.ID, key.GroupID, err)
 		}
 	}
@@ -579,8 +827,7 @@ func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 
 // removeKeyFromStore is a helper to remove a single key from the cache.
 func (p *KeyProvider) removeKeyFromStore(keyID, groupID uint) error {
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-	if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+	if err := p.removeFromActiveLists(groupID, keyID); err != nil {
 		logrus.WithFields(logrus.Fields{"keyID": keyID, "groupID": groupID, "error": err}).Error("Failed to LRem key from active list")
 	}
 
@@ -593,13 +840,47 @@ func (p *KeyProvider) removeKeyFromStore(keyID, groupID uint) error {
 
 // apiKeyToMap converts an APIKey model to a map for HSET.
 func (p *KeyProvider) apiKeyToMap(key *models.APIKey) map[string]any {
+	var cooldownUntil int64
+	if key.CooldownUntil != nil {
+		cooldownUntil = key.CooldownUntil.Unix()
+	}
 	return map[string]any{
-		"id":            fmt.Sprint(key.ID),
-		"key_string":    key.KeyValue,
-		"status":        key.Status,
-		"failure_count": key.FailureCount,
-		"group_id":      key.GroupID,
-		"created_at":    key.CreatedAt.Unix(),
+		"id":                fmt.Sprint(key.ID),
+		"key_string":        key.KeyValue,
+		"status":            key.Status,
+		"tier":              key.Tier,
+		"failure_count":     key.FailureCount,
+		"group_id":          key.GroupID,
+		"rpm_limit":         key.RPMLimit,
+		"tpm_limit":         key.TPMLimit,
+		"daily_spend_limit": key.DailySpendLimit,
+		"cooldown_until":    cooldownUntil,
+		"created_at":        key.CreatedAt.Unix(),
+	}
+}
+
+// tierListKey 返回分组某一层级的有效 Key 列表，primary 层沿用原有的 active_keys 列表。
+func tierListKey(groupID uint, tier string) string {
+	if tier == models.KeyTierBackup {
+		return fmt.Sprintf("group:%d:backup_keys", groupID)
+	}
+	return fmt.Sprintf("group:%d:active_keys", groupID)
+}
+
+// removeFromActiveLists 将 Key 从分组所有层级的有效 Key 列表中移除。
+func (p *KeyProvider) removeFromActiveLists(groupID, keyID uint) error {
+	for _, tier := range []string{models.KeyTierPrimary, models.KeyTierBackup} {
+		if err := p.store.LRem(tierListKey(groupID, tier), 0, keyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notExpired limits a key query to keys without an expiry date or that have not expired at now.
+func notExpired(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at IS NULL OR expires_at > ?", now)
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"time"
 
@@ -65,11 +66,21 @@ func (s *KeyValidator) ValidateSingleKey(key *models.APIKey, group *models.Group
 
 	isValid, validationErr := ch.ValidateKey(ctx, key, group)
 
+	// An unreachable server is not the key's fault; keep its current status.
+	if app_errors.IsUpstreamUnreachableError(validationErr) {
+		logrus.WithFields(logrus.Fields{
+			"error":    validationErr,
+			"key_id":   key.ID,
+			"group_id": group.ID,
+		}).Debug("Key validation skipped, upstream unreachable")
+		return false, validationErr
+	}
+
 	var errorMsg string
 	if !isValid && validationErr != nil {
 		errorMsg = validationErr.Error()
 	}
-	s.keypoolProvider.UpdateStatus(key, group, isValid, errorMsg)
+	s.keypoolProvider.UpdateStatus(key, group, models.KeyHealthSourceValidation, isValid, errorMsg)
 
 	if !isValid {
 		logrus.WithFields(logrus.Fields{
@@ -95,11 +106,7 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 	// Generate hashes for all key values
 	var keyHashes []string
 	for _, keyValue := range keyValues {
-		keyHash := s.encryptionSvc.Hash(keyValue)
-		if keyHash == "" {
-			continue
-		}
-		keyHashes = append(keyHashes, keyHash)
+		keyHashes = append(keyHashes, s.encryptionSvc.LookupHashes(keyValue)...)
 	}
 
 	// Find which of the provided keys actually exist in the database for this group
@@ -117,8 +124,13 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 	}
 
 	for i, kv := range keyValues {
-		keyHash := s.encryptionSvc.Hash(kv)
-		apiKey, exists := existingKeyMap[keyHash]
+		var apiKey models.APIKey
+		exists := false
+		for _, keyHash := range s.encryptionSvc.LookupHashes(kv) {
+			if apiKey, exists = existingKeyMap[keyHash]; exists {
+				break
+			}
+		}
 		if !exists {
 			results[i] = KeyTestResult{
 				KeyValue: kv,
@@ -2,15 +2,21 @@
 package middleware
 
 import (
-	"crypto/subtle"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
 	"time"
 
+	"gpt-load/internal/accesslog"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/plugin"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -74,6 +80,41 @@ func Logger(config types.LogConfig) gin.HandlerFunc {
 	}
 }
 
+// AccessLog records API and proxy requests to the access log, together with the group, model,
+// tokens and cost the proxy attached to the request.
+func AccessLog(logger *accesslog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !logger.Enabled() {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		if !strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/proxy/") {
+			return
+		}
+		entry := &accesslog.Entry{
+			Time:          start,
+			RequestID:     c.Writer.Header().Get("X-Request-Id"),
+			Method:        c.Request.Method,
+			Path:          path,
+			Status:        c.Writer.Status(),
+			DurationMs:    time.Since(start).Milliseconds(),
+			BytesOut:      max(c.Writer.Size(), 0),
+			ClientIP:      c.ClientIP(),
+			UserAgent:     c.Request.UserAgent(),
+			ClientTokenID: accesslog.ClientTokenID(c.GetString(ContextKeyProxyKey)),
+		}
+		if details := accesslog.GetDetails(c); details != nil {
+			entry.Details = *details
+		}
+		logger.Log(entry)
+	}
+}
+
 // CORS creates a CORS middleware
 func CORS(config types.CORSConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -115,8 +156,15 @@ func CORS(config types.CORSConfig) gin.HandlerFunc {
 	}
 }
 
-// Auth creates an authentication middleware
-func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
+// Context keys set by Auth: the user an admin API request was authenticated as, and the token used.
+const (
+	ContextKeyPrincipal = "principal"
+	ContextKeyAuthToken = "auth_token"
+)
+
+// Auth creates an authentication middleware accepting AUTH_KEY, a user session token or an ID token
+// of the configured OIDC provider
+func Auth(users *services.UserService, oidc *services.OIDCService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 
@@ -125,20 +173,111 @@ func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
 			return
 		}
 
-		key := extractAuthKey(c)
+		token := extractAuthKey(c)
+		principal, ok := users.Authenticate(token)
+		if !ok {
+			principal, ok = oidc.Authenticate(c.Request.Context(), token)
+		}
+		if !ok {
+			response.Error(c, app_errors.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextKeyPrincipal, principal)
+		c.Set(ContextKeyAuthToken, token)
+		c.Next()
+	}
+}
 
-		isValid := key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.Key)) == 1
+// HeaderTOTPCode carries the two-factor code of destructive operations.
+const HeaderTOTPCode = "X-TOTP-Code"
 
-		if !isValid {
-			response.Error(c, app_errors.ErrUnauthorized)
+// RequireSecondFactor asks for a current TOTP code when destructive operations are set to require
+// one. Downloads opened as links, which cannot set headers, send the code as totp_code.
+func RequireSecondFactor(users *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code := c.GetHeader(HeaderTOTPCode)
+		if code == "" {
+			code = c.Query("totp_code")
+		}
+
+		err := users.CheckSecondFactor(GetPrincipal(c), code)
+		if err != nil {
+			var i18nErr *services.I18nError
+			var apiErr *app_errors.APIError
+			switch {
+			case errors.As(err, &i18nErr):
+				response.ErrorI18nFromAPIError(c, i18nErr.APIError, i18nErr.MessageID, i18nErr.Template)
+			case errors.As(err, &apiErr):
+				response.Error(c, apiErr)
+			default:
+				response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+			}
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
+// RequireRole rejects requests from users whose role is below role.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !services.RoleAllows(GetPrincipal(c).Role, role) {
+			response.Error(c, app_errors.ErrForbidden)
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
+// RequireRoleForWrites applies RequireRole to every request that is not a GET or HEAD, leaving
+// reads open to every signed-in user.
+func RequireRoleForWrites(role string) gin.HandlerFunc {
+	requireRole := RequireRole(role)
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		requireRole(c)
+	}
+}
+
+// GroupScope limits the groups, keys, models and logs a request can reach to those of the
+// principal's teams. Handlers read the scope with services.GroupScopeFromContext.
+func GroupScope(teams *services.TeamService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope, err := teams.ScopeFor(GetPrincipal(c))
+		if err != nil {
+			var apiErr *app_errors.APIError
+			if !errors.As(err, &apiErr) {
+				apiErr = app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+			}
+			response.Error(c, apiErr)
+			c.Abort()
+			return
+		}
+		c.Set(services.ContextKeyGroupScope, scope)
+		c.Next()
+	}
+}
+
+// GetPrincipal returns the user a request was authenticated as, or an empty principal.
+func GetPrincipal(c *gin.Context) *services.Principal {
+	if value, ok := c.Get(ContextKeyPrincipal); ok {
+		if principal, ok := value.(*services.Principal); ok {
+			return principal
+		}
+	}
+	return &services.Principal{}
+}
+
+// ContextKeyProxyKey holds the proxy key a request was authenticated with.
+const ContextKeyProxyKey = "proxy_key"
+
 // ProxyAuth
 func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -160,8 +299,22 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 		// Check both key collections to prevent timing attacks
 		_, existsInEffective := group.EffectiveConfig.ProxyKeysMap[key]
 		_, existsInGroup := group.ProxyKeysMap[key]
+		if expiresAt, ok := group.ProxyKeyExpiryMap[key]; ok && !time.Now().Before(expiresAt) {
+			existsInGroup = false
+		}
 
 		if existsInEffective || existsInGroup {
+			if group.ExpiresAt != nil && !time.Now().Before(*group.ExpiresAt) {
+				response.Error(c, app_errors.ErrGroupExpired)
+				c.Abort()
+				return
+			}
+			if !clientIPAllowed(group.IPAccessPolicy, key, c.ClientIP()) {
+				response.Error(c, app_errors.ErrIPNotAllowed)
+				c.Abort()
+				return
+			}
+			c.Set(ContextKeyProxyKey, key)
 			c.Next()
 			return
 		}
@@ -171,6 +324,48 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 	}
 }
 
+// clientIPAllowed checks a client address against a group's IP access policy, both for the whole
+// group and for the proxy key the request was authenticated with.
+func clientIPAllowed(policy *models.IPAccessPolicy, proxyKey, clientIP string) bool {
+	if policy == nil {
+		return true
+	}
+	var keyRule *models.ProxyKeyIPAccess
+	for i := range policy.ProxyKeys {
+		if policy.ProxyKeys[i].ProxyKey == proxyKey {
+			keyRule = &policy.ProxyKeys[i]
+			break
+		}
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		// An unknown address can only pass policies without an allowlist
+		return len(policy.Allowed) == 0 && (keyRule == nil || len(keyRule.Allowed) == 0)
+	}
+	if !utils.IPAllowed(ip, policy.Allowed, policy.Denied) {
+		return false
+	}
+	return keyRule == nil || utils.IPAllowed(ip, keyRule.Allowed, keyRule.Denied)
+}
+
+// PluginPreAuth runs the pre-auth hooks of the enabled plugins before the proxy key is checked
+func PluginPreAuth(plugins *plugin.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !plugins.Enabled() {
+			c.Next()
+			return
+		}
+
+		if apiErr := plugins.PreAuth(plugins.Context(c)); apiErr != nil {
+			response.Error(c, apiErr)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // ProxyRouteDispatcher dispatches special routes before proxy authentication
 func ProxyRouteDispatcher(serverHandler interface{ GetIntegrationInfo(*gin.Context) }) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -234,7 +429,7 @@ func ErrorHandler() gin.HandlerFunc {
 
 // isMonitoringEndpoint checks if the path is a monitoring endpoint
 func isMonitoringEndpoint(path string) bool {
-	monitoringPaths := []string{"/health"}
+	monitoringPaths := []string{"/health", "/healthz", "/ready", "/readyz"}
 	for _, monitoringPath := range monitoringPaths {
 		if path == monitoringPath {
 			return true
@@ -13,6 +13,19 @@ const (
 	KeyStatusInvalid = "invalid"
 )
 
+// Key 层级：分组内先用完 primary 层的有效 Key，才会使用 backup 层
+const (
+	KeyTierPrimary = "primary"
+	KeyTierBackup  = "backup"
+)
+
+// 模型列表预热状态
+const (
+	ModelWarmupRunning = "running"
+	ModelWarmupSuccess = "success"
+	ModelWarmupFailed  = "failed"
+)
+
 // SystemSetting 对应 system_settings 表
 type SystemSetting struct {
 	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -30,14 +43,75 @@ type GroupConfig struct {
 	ConnectTimeout               *int    `json:"connect_timeout,omitempty"`
 	MaxIdleConns                 *int    `json:"max_idle_conns,omitempty"`
 	MaxIdleConnsPerHost          *int    `json:"max_idle_conns_per_host,omitempty"`
+	TLSHandshakeTimeout          *int    `json:"tls_handshake_timeout,omitempty"`
+	DisableHTTP2                 *bool   `json:"disable_http2,omitempty"`
+	DNSResolver                  *string `json:"dns_resolver,omitempty"`
 	ResponseHeaderTimeout        *int    `json:"response_header_timeout,omitempty"`
+	StreamIdleTimeout            *int    `json:"stream_idle_timeout,omitempty"`
 	ProxyURL                     *string `json:"proxy_url,omitempty"`
+	EgressIPFamily               *string `json:"egress_ip_family,omitempty"`
+	EgressSourceAddress          *string `json:"egress_source_address,omitempty"`
 	MaxRetries                   *int    `json:"max_retries,omitempty"`
 	BlacklistThreshold           *int    `json:"blacklist_threshold,omitempty"`
 	KeyValidationIntervalMinutes *int    `json:"key_validation_interval_minutes,omitempty"`
 	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
 	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
+	ModelRefreshIntervalHours    *int    `json:"model_refresh_interval_hours,omitempty"`
 	EnableRequestBodyLogging     *bool   `json:"enable_request_body_logging,omitempty"`
+	TranscriptProxyKeys          *string `json:"transcript_proxy_keys,omitempty"`
+	TranscriptSamplePercent      *int    `json:"transcript_sample_percent,omitempty"`
+	EnableResponseCache          *bool   `json:"enable_response_cache,omitempty"`
+	ResponseCacheTTLSeconds      *int    `json:"response_cache_ttl_seconds,omitempty"`
+	ContentFilterPolicy          *string `json:"content_filter_policy,omitempty"`
+	GeminiSafetySettings         *string `json:"gemini_safety_settings,omitempty"`
+	TrafficSchedule              *string `json:"traffic_schedule,omitempty"`
+	TrafficScheduleFallbackGroup *string `json:"traffic_schedule_fallback_group,omitempty"`
+	CanaryGroup                  *string `json:"canary_group,omitempty"`
+	CanaryPercent                *int    `json:"canary_percent,omitempty"`
+	CanaryStickyHeader           *string `json:"canary_sticky_header,omitempty"`
+	MaxConcurrency               *int    `json:"max_concurrency,omitempty"`
+	MaxQueueDepth                *int    `json:"max_queue_depth,omitempty"`
+	QueueTimeoutSeconds          *int    `json:"queue_timeout_seconds,omitempty"`
+	DuplicateRequestProtection   *string `json:"duplicate_request_protection,omitempty"`
+	DuplicateRequestWindow       *int    `json:"duplicate_request_window_seconds,omitempty"`
+	DuplicateRequestLimit        *int    `json:"duplicate_request_limit,omitempty"`
+	StickySessionMode            *string `json:"sticky_session_mode,omitempty"`
+	StickySessionHeader          *string `json:"sticky_session_header,omitempty"`
+	StickySessionTTLSeconds      *int    `json:"sticky_session_ttl_seconds,omitempty"`
+	ContextWindowPolicy          *string `json:"context_window_policy,omitempty"`
+	AutoMaxTokens                *bool   `json:"auto_max_tokens,omitempty"`
+	PIIRedaction                 *string `json:"pii_redaction,omitempty"`
+	PIIRedactionTypes            *string `json:"pii_redaction_types,omitempty"`
+	PIIRedactionPatterns         *string `json:"pii_redaction_patterns,omitempty"`
+	Moderation                   *string `json:"moderation,omitempty"`
+	ModerationEndpoint           *string `json:"moderation_endpoint,omitempty"`
+	ModerationAPIKey             *string `json:"moderation_api_key,omitempty"`
+	ModerationModel              *string `json:"moderation_model,omitempty"`
+	SubGroupRouting              *string `json:"sub_group_routing,omitempty"`
+	BanditExplorationRate        *int    `json:"bandit_exploration_rate,omitempty"`
+	UpstreamSelection            *string `json:"upstream_selection,omitempty"`
+	ReasoningContentMode         *string `json:"reasoning_content_mode,omitempty"`
+	OpenRouterReferer            *string `json:"openrouter_referer,omitempty"`
+	OpenRouterTitle              *string `json:"openrouter_title,omitempty"`
+	StreamUsageOptions           *string `json:"stream_usage_options,omitempty"`
+	TranslateLegacyCompletions   *bool   `json:"translate_legacy_completions,omitempty"`
+	ResponseMetadataEnabled      *bool   `json:"response_metadata_enabled,omitempty"`
+	ResponseMetadataKey          *string `json:"response_metadata_key,omitempty"`
+	MaxRequestBodyKB             *int    `json:"max_request_body_kb,omitempty"`
+	ResponseCompressionMinKB     *int    `json:"response_compression_min_kb,omitempty"`
+	ResponseBufferMaxKB          *int    `json:"response_buffer_max_kb,omitempty"`
+	DiagnosticsHeaders           *bool   `json:"diagnostics_headers,omitempty"`
+	KeyTopUpThreshold            *int    `json:"key_topup_threshold,omitempty"`
+	KeyTopUpWebhookURL           *string `json:"key_topup_webhook_url,omitempty"`
+	KeyTopUpScript               *string `json:"key_topup_script,omitempty"`
+	KeyTopUpCooldownMinutes      *int    `json:"key_topup_cooldown_minutes,omitempty"`
+	QuotaCycle                   *string `json:"quota_cycle,omitempty"`
+	QuotaResetTime               *string `json:"quota_reset_time,omitempty"`
+	QuotaResetDay                *int    `json:"quota_reset_day,omitempty"`
+	QuotaScope                   *string `json:"quota_scope,omitempty"`
+	QuotaUnit                    *string `json:"quota_unit,omitempty"`
+	QuotaLimit                   *int    `json:"quota_limit,omitempty"`
+	QuotaPacing                  *bool   `json:"quota_pacing,omitempty"`
 }
 
 // HeaderRule defines a single rule for header manipulation.
@@ -47,6 +121,69 @@ type HeaderRule struct {
 	Action string `json:"action"` // "set" or "remove"
 }
 
+// BodyRule defines a single rule applied to the JSON body of requests before they are forwarded.
+type BodyRule struct {
+	Path   string `json:"path"`   // dot-separated field path, e.g. "metadata.user"
+	Action string `json:"action"` // "set", "default", "remove" or "max"
+	Value  any    `json:"value,omitempty"`
+}
+
+// ScriptRule applies Action to requests for which the expression When evaluates to true.
+type ScriptRule struct {
+	When   string `json:"when"`            // expr expression, e.g. model startsWith "gpt-4" && prompt_tokens > 8000
+	Action string `json:"action"`          // "reject", "route" or "model"
+	Value  string `json:"value,omitempty"` // rejection message, target group or replacement model
+}
+
+// ModelRoutingRule sends requests for models matching Pattern to the group named Group.
+type ModelRoutingRule struct {
+	Pattern string `json:"pattern"` // exact model name or glob, e.g. "claude-*"
+	Group   string `json:"group"`
+}
+
+// ModelAccessPolicy restricts which models a group may call. Entries are exact model names or globs;
+// an empty Allowed list permits every model that is not denied.
+type ModelAccessPolicy struct {
+	Allowed   []string              `json:"allowed"`
+	Denied    []string              `json:"denied"`
+	ProxyKeys []ProxyKeyModelAccess `json:"proxy_keys"`
+}
+
+// ProxyKeyModelAccess further restricts the models a single proxy key may call in the group.
+type ProxyKeyModelAccess struct {
+	ProxyKey string   `json:"proxy_key"`
+	Allowed  []string `json:"allowed"`
+	Denied   []string `json:"denied"`
+}
+
+// IPAccessPolicy restricts the client addresses that may call a group. Entries are IP addresses or
+// CIDR ranges; an empty Allowed list permits every address that is not denied.
+type IPAccessPolicy struct {
+	Allowed   []string           `json:"allowed"`
+	Denied    []string           `json:"denied"`
+	ProxyKeys []ProxyKeyIPAccess `json:"proxy_keys"`
+}
+
+// ProxyKeyIPAccess further restricts the client addresses a single proxy key may be used from.
+type ProxyKeyIPAccess struct {
+	ProxyKey string   `json:"proxy_key"`
+	Allowed  []string `json:"allowed"`
+	Denied   []string `json:"denied"`
+}
+
+// UpstreamDefinition is a single entry of Group.Upstreams.
+type UpstreamDefinition struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	// HeaderRules are applied after the group's header rules for requests sent to this upstream.
+	HeaderRules []HeaderRule `json:"header_rules,omitempty"`
+	// APIKey, when set, is sent to this upstream instead of the key selected from the pool.
+	APIKey string `json:"api_key,omitempty"`
+	// VersionedBase marks a URL that ends in the provider's own API version (e.g. /api/paas/v4),
+	// so the client's version prefix is dropped from request paths.
+	VersionedBase bool `json:"versioned_base,omitempty"`
+}
+
 // GroupSubGroup 聚合分组和子分组的关联表
 type GroupSubGroup struct {
 	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -79,34 +216,52 @@ type ParentAggregateGroupInfo struct {
 
 // Group 对应 groups 表
 type Group struct {
-	ID                   uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
-	EffectiveConfig      types.SystemSettings `gorm:"-" json:"effective_config,omitempty"`
-	Name                 string               `gorm:"type:varchar(255);not null;unique" json:"name"`
-	Endpoint             string               `gorm:"-" json:"endpoint"`
-	DisplayName          string               `gorm:"type:varchar(255)" json:"display_name"`
-	ProxyKeys            string               `gorm:"type:text" json:"proxy_keys"`
-	Description          string               `gorm:"type:varchar(512)" json:"description"`
-	GroupType            string               `gorm:"type:varchar(50);default:'standard'" json:"group_type"` // 'standard' or 'aggregate'
-	Upstreams            datatypes.JSON       `gorm:"type:json;not null" json:"upstreams"`
-	ValidationEndpoint   string               `gorm:"type:varchar(255)" json:"validation_endpoint"`
-	ChannelType          string               `gorm:"type:varchar(50);not null" json:"channel_type"`
-	Sort                 int                  `gorm:"default:0" json:"sort"`
-	TestModel            string               `gorm:"type:varchar(255);not null" json:"test_model"`
-	ParamOverrides       datatypes.JSONMap    `gorm:"type:json" json:"param_overrides"`
-	Config               datatypes.JSONMap    `gorm:"type:json" json:"config"`
-	HeaderRules          datatypes.JSON       `gorm:"type:json" json:"header_rules"`
-	ModelRedirectRules   datatypes.JSONMap    `gorm:"type:json" json:"model_redirect_rules"`
-	ModelRedirectStrict  bool                 `gorm:"default:false" json:"model_redirect_strict"`
-	APIKeys              []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
-	SubGroups            []GroupSubGroup      `gorm:"-" json:"sub_groups,omitempty"`
-	LastValidatedAt      *time.Time           `json:"last_validated_at"`
-	CreatedAt            time.Time            `json:"created_at"`
-	UpdatedAt            time.Time            `json:"updated_at"`
+	ID                  uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
+	EffectiveConfig     types.SystemSettings `gorm:"-" json:"effective_config,omitempty"`
+	Name                string               `gorm:"type:varchar(255);not null;unique" json:"name"`
+	Endpoint            string               `gorm:"-" json:"endpoint"`
+	DisplayName         string               `gorm:"type:varchar(255)" json:"display_name"`
+	ProxyKeys           string               `gorm:"type:text" json:"proxy_keys"`
+	Description         string               `gorm:"type:varchar(512)" json:"description"`
+	GroupType           string               `gorm:"type:varchar(50);default:'standard'" json:"group_type"` // 'standard' or 'aggregate'
+	Upstreams           datatypes.JSON       `gorm:"type:json;not null" json:"upstreams"`
+	ValidationEndpoint  string               `gorm:"type:varchar(255)" json:"validation_endpoint"`
+	ChannelType         string               `gorm:"type:varchar(50);not null" json:"channel_type"`
+	Sort                int                  `gorm:"default:0" json:"sort"`
+	TestModel           string               `gorm:"type:varchar(255);not null" json:"test_model"`
+	ParamOverrides      datatypes.JSONMap    `gorm:"type:json" json:"param_overrides"`
+	Config              datatypes.JSONMap    `gorm:"type:json" json:"config"`
+	HeaderRules         datatypes.JSON       `gorm:"type:json" json:"header_rules"`
+	BodyRules           datatypes.JSON       `gorm:"type:json" json:"body_rules"`
+	ScriptRules         datatypes.JSON       `gorm:"type:json" json:"script_rules"`
+	ModelRedirectRules  datatypes.JSONMap    `gorm:"type:json" json:"model_redirect_rules"`
+	ModelRedirectStrict bool                 `gorm:"default:false" json:"model_redirect_strict"`
+	ModelRoutingRules   datatypes.JSON       `gorm:"type:json" json:"model_routing_rules"`
+	ModelAccess         datatypes.JSON       `gorm:"type:json" json:"model_access"`
+	IPAccess            datatypes.JSON       `gorm:"type:json" json:"ip_access"`
+	APIKeys             []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
+	SubGroups           []GroupSubGroup      `gorm:"-" json:"sub_groups,omitempty"`
+	LastValidatedAt     *time.Time           `json:"last_validated_at"`
+	ModelWarmupStatus   string               `gorm:"type:varchar(20);default:''" json:"model_warmup_status"`
+	ModelWarmupError    string               `gorm:"type:varchar(512);default:''" json:"model_warmup_error"`
+	ModelWarmupAt       *time.Time           `json:"model_warmup_at"`
+	ModelsRefreshedAt   *time.Time           `json:"models_refreshed_at"`               // 上次定时刷新模型列表的时间
+	ExpiresAt           *time.Time           `gorm:"index" json:"expires_at"`           // 沙盒分组的到期时间，为空表示长期有效
+	TeamID              *uint                `gorm:"index" json:"team_id"`              // 所属团队，为空表示所有用户可见
+	ProxyKeyExpiry      datatypes.JSONMap    `gorm:"type:json" json:"proxy_key_expiry"` // 沙盒代理密钥 -> 到期时间（RFC 3339）
+	CreatedAt           time.Time            `json:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at"`
 
 	// For cache
-	ProxyKeysMap      map[string]struct{} `gorm:"-" json:"-"`
-	HeaderRuleList    []HeaderRule        `gorm:"-" json:"-"`
-	ModelRedirectMap  map[string]string   `gorm:"-" json:"-"`
+	ProxyKeysMap      map[string]struct{}  `gorm:"-" json:"-"`
+	ProxyKeyExpiryMap map[string]time.Time `gorm:"-" json:"-"`
+	HeaderRuleList    []HeaderRule         `gorm:"-" json:"-"`
+	BodyRuleList      []BodyRule           `gorm:"-" json:"-"`
+	ScriptRuleList    []ScriptRule         `gorm:"-" json:"-"`
+	ModelRedirectMap  map[string]string    `gorm:"-" json:"-"`
+	ModelRoutingList  []ModelRoutingRule   `gorm:"-" json:"-"`
+	ModelAccessPolicy *ModelAccessPolicy   `gorm:"-" json:"-"`
+	IPAccessPolicy    *IPAccessPolicy      `gorm:"-" json:"-"`
 }
 
 // APIKey 对应 api_keys 表
@@ -116,12 +271,24 @@ type APIKey struct {
 	KeyHash      string     `gorm:"type:varchar(128);index" json:"key_hash"`
 	GroupID      uint       `gorm:"not null;index" json:"group_id"`
 	Status       string     `gorm:"type:varchar(50);not null;default:'active'" json:"status"`
+	Tier         string     `gorm:"type:varchar(20);not null;default:'primary'" json:"tier"`
 	Notes        string     `gorm:"type:varchar(255);default:''" json:"notes"`
 	RequestCount int64      `gorm:"not null;default:0" json:"request_count"`
 	FailureCount int64      `gorm:"not null;default:0" json:"failure_count"`
 	LastUsedAt   *time.Time `json:"last_used_at"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	// ExpiresAt deactivates the key once reached; ExpiryWarnedAt records the advance warning so it is sent once.
+	ExpiresAt      *time.Time `gorm:"index" json:"expires_at"`
+	ExpiryWarnedAt *time.Time `json:"-"`
+	// RPMLimit, TPMLimit and DailySpendLimit (USD) are the key's own tier limits, 0 meaning none. The
+	// key selector passes over a key that has reached one of them.
+	RPMLimit        int     `gorm:"not null;default:0" json:"rpm_limit"`
+	TPMLimit        int     `gorm:"not null;default:0" json:"tpm_limit"`
+	DailySpendLimit float64 `gorm:"not null;default:0" json:"daily_spend_limit"`
+	// CooldownUntil is set when the upstream answered 429 with a Retry-After or rate-limit reset;
+	// the key selector passes over the key until then.
+	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 // RequestType 请求类型常量
@@ -132,26 +299,39 @@ const (
 
 // RequestLog 对应 request_logs 表
 type RequestLog struct {
-	ID              string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	Timestamp       time.Time `gorm:"not null;index" json:"timestamp"`
-	GroupID         uint      `gorm:"not null;index" json:"group_id"`
-	GroupName       string    `gorm:"type:varchar(255);index" json:"group_name"`
-	ParentGroupID   uint      `gorm:"index" json:"parent_group_id"`
-	ParentGroupName string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
-	KeyValue        string    `gorm:"type:text" json:"key_value"`
-	KeyHash         string    `gorm:"type:varchar(128);index" json:"key_hash"`
-	Model           string    `gorm:"type:varchar(255);index" json:"model"`
-	IsSuccess       bool      `gorm:"not null" json:"is_success"`
-	SourceIP        string    `gorm:"type:varchar(64)" json:"source_ip"`
-	StatusCode      int       `gorm:"not null" json:"status_code"`
-	RequestPath     string    `gorm:"type:varchar(500)" json:"request_path"`
-	Duration        int64     `gorm:"not null" json:"duration_ms"`
-	ErrorMessage    string    `gorm:"type:text" json:"error_message"`
-	UserAgent       string    `gorm:"type:varchar(512)" json:"user_agent"`
-	RequestType     string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
-	UpstreamAddr    string    `gorm:"type:varchar(500)" json:"upstream_addr"`
-	IsStream        bool      `gorm:"not null" json:"is_stream"`
-	RequestBody     string    `gorm:"type:text" json:"request_body"`
+	ID                  string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Timestamp           time.Time `gorm:"not null;index" json:"timestamp"`
+	GroupID             uint      `gorm:"not null;index" json:"group_id"`
+	GroupName           string    `gorm:"type:varchar(255);index" json:"group_name"`
+	ParentGroupID       uint      `gorm:"index" json:"parent_group_id"`
+	ParentGroupName     string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
+	KeyValue            string    `gorm:"type:text" json:"key_value"`
+	KeyHash             string    `gorm:"type:varchar(128);index" json:"key_hash"`
+	Model               string    `gorm:"type:varchar(255);index" json:"model"`
+	IsSuccess           bool      `gorm:"not null" json:"is_success"`
+	SourceIP            string    `gorm:"type:varchar(64)" json:"source_ip"`
+	StatusCode          int       `gorm:"not null" json:"status_code"`
+	RequestPath         string    `gorm:"type:varchar(500)" json:"request_path"`
+	Duration            int64     `gorm:"not null" json:"duration_ms"`
+	ErrorMessage        string    `gorm:"type:text" json:"error_message"`
+	UserAgent           string    `gorm:"type:varchar(512)" json:"user_agent"`
+	RequestType         string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
+	UpstreamAddr        string    `gorm:"type:varchar(500)" json:"upstream_addr"`
+	IsStream            bool      `gorm:"not null" json:"is_stream"`
+	RequestBody         string    `gorm:"type:text" json:"request_body"`
+	ChoiceCount         int       `gorm:"not null;default:0" json:"choice_count"`
+	PromptTokens        int       `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens    int       `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens         int       `gorm:"not null;default:0" json:"total_tokens"`
+	CacheCreationTokens int       `gorm:"not null;default:0" json:"cache_creation_tokens"`
+	CacheReadTokens     int       `gorm:"not null;default:0" json:"cache_read_tokens"`
+	SearchUnits         int       `gorm:"not null;default:0" json:"search_units"`
+	CacheHit            bool      `gorm:"not null;default:false" json:"cache_hit"`
+	FinishReason        string    `gorm:"type:varchar(32);index" json:"finish_reason"`
+	ModerationFlags     string    `gorm:"type:varchar(255)" json:"moderation_flags,omitempty"`
+	RequestID           string    `gorm:"type:varchar(36);index" json:"request_id"`
+	// Metadata is the proxy key's custom metadata as a JSON object, stored as text so it can be filtered with LIKE.
+	Metadata datatypes.JSON `gorm:"type:text" json:"metadata,omitempty"`
 }
 
 // StatCard 用于仪表盘的单个统计卡片数据
@@ -204,21 +384,309 @@ type GroupHourlyStat struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// UsageRollup 保存某个时间段内某分组某模型的最终请求用量汇总，Time 为 UTC 整点或零点；
+// 经聚合分组转发的请求记录在子分组上，并带有父分组 ID
+type UsageRollup struct {
+	ID                  uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	Time                time.Time `gorm:"not null;uniqueIndex:,composite:bucket" json:"time"`
+	GroupID             uint      `gorm:"not null;uniqueIndex:,composite:bucket" json:"group_id"`
+	ParentGroupID       uint      `gorm:"not null;default:0;uniqueIndex:,composite:bucket" json:"parent_group_id"`
+	Model               string    `gorm:"type:varchar(255);not null;default:'';uniqueIndex:,composite:bucket" json:"model"`
+	RequestCount        int64     `gorm:"not null;default:0" json:"request_count"`
+	SuccessCount        int64     `gorm:"not null;default:0" json:"success_count"`
+	FailureCount        int64     `gorm:"not null;default:0" json:"failure_count"`
+	PromptTokens        int64     `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens    int64     `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens         int64     `gorm:"not null;default:0" json:"total_tokens"`
+	CacheCreationTokens int64     `gorm:"not null;default:0" json:"cache_creation_tokens"`
+	CacheReadTokens     int64     `gorm:"not null;default:0" json:"cache_read_tokens"`
+	DurationMs          int64     `gorm:"not null;default:0" json:"duration_ms"` // 总耗时，除以 request_count 即平均耗时
+}
+
+// UsageHourlyRollup 对应 usage_hourly_rollups 表
+type UsageHourlyRollup struct {
+	UsageRollup
+}
+
+// UsageDailyRollup 对应 usage_daily_rollups 表，由小时汇总累加得到
+type UsageDailyRollup struct {
+	UsageRollup
+}
+
+// ConfigVersion 对应 config_versions 表，保存分组和系统设置的历史快照
+type ConfigVersion struct {
+	ID           uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	ResourceType string         `gorm:"type:varchar(20);not null;index:idx_config_resource" json:"resource_type"` // 'group' or 'settings'
+	ResourceID   uint           `gorm:"not null;default:0;index:idx_config_resource" json:"resource_id"`
+	Version      int            `gorm:"not null" json:"version"`
+	Action       string         `gorm:"type:varchar(20);not null" json:"action"`
+	Snapshot     datatypes.JSON `gorm:"type:json;not null" json:"snapshot"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
 // ModelCapabilities represents the capabilities table for storing model information and features
 type ModelCapabilities struct {
-	ID                  uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	GroupID             uint           `gorm:"not null;index" json:"group_id"`
-	ModelID             string         `gorm:"type:varchar(255);not null;index" json:"model_id"`
-	ModelName           string         `gorm:"type:varchar(255);not null" json:"model_name"`
-	SupportsStreaming   bool           `gorm:"default:false" json:"supports_streaming"`
-	SupportsVision      bool           `gorm:"default:false" json:"supports_vision"`
-	SupportsFunctions   bool           `gorm:"default:false" json:"supports_functions"`
-	MaxTokens           *int           `json:"max_tokens"`
-	MaxInputTokens      *int           `json:"max_input_tokens"`
-	MaxOutputTokens     *int           `json:"max_output_tokens"`
-	CustomCapabilities  datatypes.JSON `gorm:"type:json" json:"custom_capabilities"`
-	IsAutoFetched       bool           `gorm:"default:false" json:"is_auto_fetched"`
-	LastFetchedAt       *time.Time     `json:"last_fetched_at"`
-	CreatedAt           time.Time      `json:"created_at"`
-	UpdatedAt           time.Time      `json:"updated_at"`
+	ID                uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID           uint   `gorm:"not null;index" json:"group_id"`
+	ModelID           string `gorm:"type:varchar(255);not null;index" json:"model_id"`
+	ModelName         string `gorm:"type:varchar(255);not null" json:"model_name"`
+	SupportsStreaming bool   `gorm:"default:false" json:"supports_streaming"`
+	SupportsVision    bool   `gorm:"default:false" json:"supports_vision"`
+	SupportsFunctions bool   `gorm:"default:false" json:"supports_functions"`
+	SupportsRerank    bool   `gorm:"default:false" json:"supports_rerank"`
+	MaxTokens         *int   `json:"max_tokens"`
+	MaxInputTokens    *int   `json:"max_input_tokens"`
+	MaxOutputTokens   *int   `json:"max_output_tokens"`
+	// Prices in USD per million tokens, used for cost estimates. Nil means unknown.
+	InputPricePerMillion  *float64       `json:"input_price_per_million"`
+	OutputPricePerMillion *float64       `json:"output_price_per_million"`
+	CustomCapabilities    datatypes.JSON `gorm:"type:json" json:"custom_capabilities"`
+	IsAutoFetched         bool           `gorm:"default:false" json:"is_auto_fetched"`
+	LastFetchedAt         *time.Time     `json:"last_fetched_at"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+}
+
+// 通知分类
+const (
+	NotificationCategoryKey   = "key"
+	NotificationCategoryJob   = "job"
+	NotificationCategoryAlert = "alert"
+	NotificationCategoryModel = "model"
+)
+
+// 通知级别
+const (
+	NotificationSeverityInfo    = "info"
+	NotificationSeveritySuccess = "success"
+	NotificationSeverityWarning = "warning"
+	NotificationSeverityError   = "error"
+)
+
+// Notification 对应 notifications 表，是管理界面通知中心的一条消息
+type Notification struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Category string `gorm:"type:varchar(20);not null;index" json:"category"`
+	Severity string `gorm:"type:varchar(20);not null" json:"severity"`
+	Event    string `gorm:"type:varchar(64);not null" json:"event"`
+	Message  string `gorm:"type:text;not null" json:"message"`
+	// Params holds the values the web UI interpolates into the translated event text.
+	Params    datatypes.JSON `gorm:"type:json" json:"params"`
+	GroupID   *uint          `gorm:"index" json:"group_id"`
+	GroupName string         `gorm:"type:varchar(255)" json:"group_name"`
+	// Count is how many occurrences of the same event were merged into this notification.
+	Count     int        `gorm:"not null;default:1" json:"count"`
+	ReadAt    *time.Time `gorm:"index" json:"read_at"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// 告警规则类型
+const (
+	AlertTypeErrorRate           = "error_rate"
+	AlertTypeAllKeysInvalid      = "all_keys_invalid"
+	AlertTypeQuotaExceeded       = "quota_exceeded"
+	AlertTypeUpstreamCircuitOpen = "upstream_circuit_open"
+	// Key events are delivered as they happen rather than evaluated periodically
+	AlertTypeKeyInvalidated = "key_invalidated"
+	AlertTypeKeyRecovered   = "key_recovered"
+)
+
+// 告警通知渠道
+const (
+	AlertChannelWebhook  = "webhook"
+	AlertChannelSlack    = "slack"
+	AlertChannelDiscord  = "discord"
+	AlertChannelTelegram = "telegram"
+)
+
+// AlertRule 对应 alert_rules 表，定义告警条件及触发和恢复时推送的通知渠道
+type AlertRule struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name string `gorm:"type:varchar(255);not null" json:"name"`
+	Type string `gorm:"type:varchar(32);not null" json:"type"`
+	// GroupID limits the rule to one group; nil evaluates it for every group.
+	GroupID *uint `gorm:"index" json:"group_id"`
+	// Threshold is the error rate in percent for error_rate, and the share of the quota used in
+	// percent for quota_exceeded.
+	Threshold     float64 `gorm:"not null;default:0" json:"threshold"`
+	WindowMinutes int     `gorm:"not null;default:5" json:"window_minutes"`
+	// MinRequests keeps error_rate from firing on a handful of requests.
+	MinRequests int `gorm:"not null;default:0" json:"min_requests"`
+	// RepeatMinutes re-sends a firing alert at this interval, 0 sends it once.
+	RepeatMinutes int `gorm:"not null;default:0" json:"repeat_minutes"`
+	// Channel selects how alerts are delivered. Webhook, Slack and Discord post to WebhookURL;
+	// Telegram sends through the bot API to TelegramChatID.
+	Channel          string `gorm:"type:varchar(32);not null;default:'webhook'" json:"channel"`
+	WebhookURL       string `gorm:"type:varchar(1024)" json:"webhook_url"`
+	TelegramBotToken string `gorm:"type:varchar(255)" json:"telegram_bot_token"`
+	TelegramChatID   string `gorm:"type:varchar(64)" json:"telegram_chat_id"`
+	// PayloadTemplate is a Go text/template rendering the JSON body posted to WebhookURL; empty uses
+	// the default payload of the channel.
+	PayloadTemplate string    `gorm:"type:text" json:"payload_template"`
+	Enabled         bool      `gorm:"not null;default:false" json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// 用户角色，权限依次递增
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// User 对应 users 表，管理界面的登录用户
+type User struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username     string `gorm:"type:varchar(64);not null;uniqueIndex" json:"username"`
+	PasswordHash string `gorm:"type:varchar(255);not null" json:"-"`
+	Role         string `gorm:"type:varchar(16);not null" json:"role"`
+	Disabled     bool   `gorm:"not null;default:false" json:"disabled"`
+	// SessionVersion is part of every session issued to the user; bumping it signs them out everywhere.
+	SessionVersion int `gorm:"not null;default:0" json:"-"`
+	// TOTPSecret is encrypted; it is set on enrollment and only used for logins once TOTPEnabled is
+	// set. RecoveryCodes holds the unused one-time recovery codes as an encrypted JSON array.
+	TOTPSecret    string     `gorm:"type:text" json:"-"`
+	TOTPEnabled   bool       `gorm:"not null;default:false" json:"totp_enabled"`
+	RecoveryCodes string     `gorm:"type:text" json:"-"`
+	LastLoginAt   *time.Time `json:"last_login_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Team 对应 teams 表，非管理员只能看到和管理所属团队的分组以及不属于任何团队的分组
+type Team struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	UserIDs     []uint    `gorm:"-" json:"user_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TeamMember 对应 team_members 表，记录用户所属的团队
+type TeamMember struct {
+	TeamID uint `gorm:"primaryKey" json:"team_id"`
+	UserID uint `gorm:"primaryKey;index" json:"user_id"`
+}
+
+// PlaygroundConversation 对应 playground_conversations 表，保存测试环境中的一次多轮对话
+type PlaygroundConversation struct {
+	ID          uint                `gorm:"primaryKey;autoIncrement" json:"id"`
+	Title       string              `gorm:"type:varchar(255);not null" json:"title"`
+	GroupName   string              `gorm:"type:varchar(255)" json:"group_name"`
+	Model       string              `gorm:"type:varchar(255)" json:"model"`
+	Temperature float64             `json:"temperature"`
+	Messages    []PlaygroundMessage `gorm:"foreignKey:ConversationID" json:"messages,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `gorm:"index" json:"updated_at"`
+}
+
+// PlaygroundMessage 对应 playground_messages 表，是对话中的一条消息，按 Position 排序。
+// 助手消息可携带 ToolCalls，tool 角色的消息通过 ToolCallID 关联其回应的工具调用
+type PlaygroundMessage struct {
+	ID               uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConversationID   uint           `gorm:"not null;index" json:"conversation_id"`
+	Position         int            `gorm:"not null" json:"position"`
+	Role             string         `gorm:"type:varchar(20);not null" json:"role"`
+	Content          string         `gorm:"type:text;not null" json:"content"`
+	ReasoningContent string         `gorm:"type:text" json:"reasoning_content,omitempty"`
+	ToolCalls        datatypes.JSON `gorm:"type:text" json:"tool_calls,omitempty"`
+	ToolCallID       string         `gorm:"type:varchar(100)" json:"tool_call_id,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// Key health event sources
+const (
+	KeyHealthSourceValidation = "validation"
+	KeyHealthSourceProxy      = "proxy"
+)
+
+// KeyHealthEvent 对应 key_health_events 表，记录 Key 的每次验证结果和每次代理失败，用于观察 Key 是否在逐渐失效
+type KeyHealthEvent struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	KeyID        uint      `gorm:"not null;index:idx_key_health_key_time" json:"key_id"`
+	GroupID      uint      `gorm:"not null;index" json:"group_id"`
+	Source       string    `gorm:"type:varchar(20);not null" json:"source"`
+	IsSuccess    bool      `gorm:"not null" json:"is_success"`
+	ErrorMessage string    `gorm:"type:varchar(500)" json:"error_message,omitempty"`
+	Disabled     bool      `gorm:"not null;default:false" json:"disabled"` // 该次失败使 Key 达到黑名单阈值而被停用
+	CreatedAt    time.Time `gorm:"index;index:idx_key_health_key_time" json:"created_at"`
+}
+
+// Transcript capture reasons
+const (
+	TranscriptReasonProxyKey = "proxy_key"
+	TranscriptReasonSampled  = "sampled"
+)
+
+// StreamTranscript 对应 stream_transcripts 表，保存由流式增量拼接出的完整回复，用于排查仅在流式模式下出现的问题。
+// RequestBody、Content 和 ReasoningContent 加密存储
+type StreamTranscript struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RequestID        string    `gorm:"type:varchar(36);index" json:"request_id"`
+	GroupID          uint      `gorm:"not null;index" json:"group_id"`
+	GroupName        string    `gorm:"type:varchar(255);index" json:"group_name"`
+	Model            string    `gorm:"type:varchar(255)" json:"model"`
+	Reason           string    `gorm:"type:varchar(20);not null" json:"reason"`
+	ChunkCount       int       `gorm:"not null;default:0" json:"chunk_count"`
+	Truncated        bool      `gorm:"not null;default:false" json:"truncated"`
+	FinishReason     string    `gorm:"type:varchar(32)" json:"finish_reason"`
+	RequestBody      string    `gorm:"type:text" json:"request_body,omitempty"`
+	Content          string    `gorm:"type:text" json:"content,omitempty"`
+	ReasoningContent string    `gorm:"type:text" json:"reasoning_content,omitempty"`
+	CreatedAt        time.Time `gorm:"index" json:"created_at"`
+}
+
+// 后台任务状态
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusPartial   = "partial" // 已完成，但部分条目失败
+	JobStatusFailed    = "failed"
+)
+
+// Job 对应 jobs 表，记录一次后台长任务（批量验证、导入、重新加密、模型探测）的进度和结果。
+// Result 保存任务结束时的结果，其中包含失败条目的详情
+type Job struct {
+	ID         string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Type       string         `gorm:"type:varchar(32);not null;index" json:"type"`
+	Status     string         `gorm:"type:varchar(20);not null;index" json:"status"`
+	GroupName  string         `gorm:"type:varchar(255);index" json:"group_name,omitempty"`
+	Total      int            `gorm:"not null;default:0" json:"total"`
+	Processed  int            `gorm:"not null;default:0" json:"processed"`
+	Failed     int            `gorm:"not null;default:0" json:"failed"`
+	Result     datatypes.JSON `gorm:"type:json" json:"result,omitempty"`
+	Error      string         `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  time.Time      `gorm:"index" json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription 对应 webhook_subscriptions 表，把订阅的生命周期事件推送到 URL。
+// 设置了 Secret 时请求体使用 HMAC-SHA256 签名
+type WebhookSubscription struct {
+	ID     uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name   string `gorm:"type:varchar(255);not null" json:"name"`
+	URL    string `gorm:"type:varchar(1024);not null" json:"url"`
+	Secret string `gorm:"type:text" json:"-"`
+	// SecretSet tells clients whether a secret is configured without returning it.
+	SecretSet bool `gorm:"-" json:"secret_set"`
+	// Events lists the subscribed event types; empty subscribes to every event.
+	Events datatypes.JSON `gorm:"type:json" json:"events"`
+	// GroupID limits the subscription to the events of one group; nil also receives events without a group.
+	GroupID            *uint      `gorm:"index" json:"group_id"`
+	Enabled            bool       `gorm:"not null;default:false" json:"enabled"`
+	LastDeliveryAt     *time.Time `json:"last_delivery_at"`
+	LastDeliveryStatus string     `gorm:"type:varchar(20)" json:"last_delivery_status"`
+	LastDeliveryError  string     `gorm:"type:varchar(500)" json:"last_delivery_error"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
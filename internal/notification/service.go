@@ -0,0 +1,239 @@
+// Package notification stores the in-app notifications shown in the web UI's notification center.
+package notification
+
+import (
+	"encoding/json"
+	"gpt-load/internal/models"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Event codes. The web UI translates them, filling in the notification's params.
+const (
+	EventKeyDisabled       = "key_disabled"
+	EventKeyRecovered      = "key_recovered"
+	EventKeyExpired        = "key_expired"
+	EventKeyExpiring       = "key_expiring"
+	EventTaskCompleted     = "task_completed"
+	EventTaskFailed        = "task_failed"
+	EventKeysLow           = "keys_low"
+	EventKeyRotationFailed = "key_rotation_failed"
+	EventModelsDetected    = "models_detected"
+	EventModelsRemoved     = "models_removed"
+	EventSandboxDeleted    = "sandbox_deleted"
+	EventAlertFiring       = "alert_firing"
+	EventAlertResolved     = "alert_resolved"
+)
+
+const (
+	// mergeWindow is how long an unread notification keeps absorbing repeats of its event.
+	mergeWindow = 10 * time.Minute
+	// retention is how long notifications are kept.
+	retention = 30 * 24 * time.Hour
+	// pruneInterval limits how often expired notifications are deleted.
+	pruneInterval = time.Hour
+)
+
+// Event describes something worth telling the administrator about.
+type Event struct {
+	Category  string
+	Severity  string
+	Event     string
+	Message   string
+	Params    map[string]any
+	GroupID   uint
+	GroupName string
+	// Merge folds the event into an unread notification of the same event and group created within
+	// the merge window instead of adding a new one, so bursts (e.g. many keys failing) stay readable.
+	Merge bool
+}
+
+// ListParams filters the notification list.
+type ListParams struct {
+	Category   string
+	Severity   string
+	UnreadOnly bool
+}
+
+// UnreadCounts summarizes the unread notifications.
+type UnreadCounts struct {
+	Total      int64            `json:"total"`
+	BySeverity map[string]int64 `json:"by_severity"`
+}
+
+// Listener is called with every event after it has been recorded. It runs on the producer's
+// goroutine, so it must return quickly.
+type Listener func(Event)
+
+// Service records and manages notifications.
+type Service struct {
+	db        *gorm.DB
+	mu        sync.Mutex
+	lastPrune time.Time
+	listeners []Listener
+}
+
+// NewService creates a new notification service.
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// AddListener registers a listener for the events recorded from now on.
+func (s *Service) AddListener(listener Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Notify records an event and passes it to the listeners. Errors are logged rather than returned
+// so that producers never fail because of a notification.
+func (s *Service) Notify(e Event) {
+	s.mu.Lock()
+	s.recordLocked(e)
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(e)
+	}
+}
+
+// recordLocked saves an event, merging it into a recent unread notification when requested.
+func (s *Service) recordLocked(e Event) {
+
+	params, err := json.Marshal(e.Params)
+	if err != nil {
+		logrus.WithError(err).WithField("event", e.Event).Error("Failed to encode notification params")
+		params = nil
+	}
+
+	var groupID *uint
+	if e.GroupID != 0 {
+		groupID = &e.GroupID
+	}
+
+	if e.Merge {
+		query := s.db.Model(&models.Notification{}).
+			Where("event = ? AND read_at IS NULL AND created_at > ?", e.Event, time.Now().Add(-mergeWindow))
+		if groupID != nil {
+			query = query.Where("group_id = ?", *groupID)
+		} else {
+			query = query.Where("group_id IS NULL")
+		}
+		result := query.Order("id desc").Limit(1).UpdateColumns(map[string]any{
+			"count":      gorm.Expr("count + 1"),
+			"message":    e.Message,
+			"params":     datatypes.JSON(params),
+			"updated_at": time.Now(),
+		})
+		if result.Error == nil && result.RowsAffected > 0 {
+			return
+		}
+	}
+
+	notification := models.Notification{
+		Category:  e.Category,
+		Severity:  e.Severity,
+		Event:     e.Event,
+		Message:   e.Message,
+		Params:    datatypes.JSON(params),
+		GroupID:   groupID,
+		GroupName: e.GroupName,
+		Count:     1,
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		logrus.WithError(err).WithField("event", e.Event).Error("Failed to save notification")
+		return
+	}
+
+	s.pruneLocked()
+}
+
+// pruneLocked deletes notifications past the retention period, at most once per prune interval.
+func (s *Service) pruneLocked() {
+	if time.Since(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = time.Now()
+	if err := s.db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.Notification{}).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to prune old notifications")
+	}
+}
+
+// Query returns the filtered notifications, newest first.
+func (s *Service) Query(params ListParams) *gorm.DB {
+	query := s.db.Model(&models.Notification{})
+	if params.Category != "" {
+		query = query.Where("category = ?", params.Category)
+	}
+	if params.Severity != "" {
+		query = query.Where("severity = ?", params.Severity)
+	}
+	if params.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	return query.Order("updated_at desc, id desc")
+}
+
+// UnreadCounts counts unread notifications in total and per severity.
+func (s *Service) UnreadCounts() (*UnreadCounts, error) {
+	var rows []struct {
+		Severity string
+		Count    int64
+	}
+	if err := s.db.Model(&models.Notification{}).
+		Select("severity, COUNT(*) as count").
+		Where("read_at IS NULL").
+		Group("severity").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := &UnreadCounts{BySeverity: make(map[string]int64)}
+	for _, row := range rows {
+		counts.BySeverity[row.Severity] = row.Count
+		counts.Total += row.Count
+	}
+	return counts, nil
+}
+
+// MarkRead marks the given notifications as read and returns how many changed.
+func (s *Service) MarkRead(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := s.db.Model(&models.Notification{}).
+		Where("id IN ? AND read_at IS NULL", ids).
+		UpdateColumn("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// MarkAllRead marks every unread notification as read and returns how many changed.
+func (s *Service) MarkAllRead() (int64, error) {
+	result := s.db.Model(&models.Notification{}).
+		Where("read_at IS NULL").
+		UpdateColumn("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// Delete removes a single notification.
+func (s *Service) Delete(id uint) error {
+	result := s.db.Delete(&models.Notification{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteRead removes every notification that has been read and returns how many were removed.
+func (s *Service) DeleteRead() (int64, error) {
+	result := s.db.Where("read_at IS NOT NULL").Delete(&models.Notification{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,373 @@
+// Package openapi builds the OpenAPI 3 document of the admin API from the registered gin routes and
+// the handler annotations collected by cmd/openapi-gen.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI version of the generated document.
+const Version = "3.0.3"
+
+// Operation is what the annotations of a handler say about the request it handles.
+type Operation struct {
+	Summary     string
+	Description string
+	// Body is the type bound from the JSON request body.
+	Body reflect.Type
+	// Query is the struct bound from the query string.
+	Query reflect.Type
+	// QueryParams are the query parameters the handler reads one by one.
+	QueryParams []string
+}
+
+// Info describes the API in the document header.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Document is a subset of the OpenAPI 3 document object.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []map[string]string             `json:"servers"`
+	Paths      map[string]map[string]*PathItem `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security"`
+}
+
+// PathItem is an operation of the document.
+type PathItem struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is a JSON request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas and security schemes.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how requests are authenticated.
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Schema is a subset of the JSON schema dialect of OpenAPI 3.0.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Build returns the document of the routes under prefix. Routes are matched with their operation by
+// the receiver and method name of the handler, e.g. "Server.ListGroups"; routes listed in public
+// need no credentials.
+func Build(info Info, prefix string, routes gin.RoutesInfo, operations map[string]Operation, public []string) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: []map[string]string{{"url": "/"}},
+		Paths:   map[string]map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer"},
+				"apiKey":     {Type: "apiKey", In: "header", Name: "X-Api-Key"},
+			},
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}},
+	}
+	schemas := &schemaBuilder{components: doc.Components.Schemas}
+	operationIDs := map[string]int{}
+
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, prefix+"/") {
+			continue
+		}
+		name := handlerName(route.Handler)
+		op := operations[name]
+
+		// A handler mounted on several routes gets a numbered operation ID on each further route.
+		id := operationID(name, route.Method)
+		if n := operationIDs[id]; n > 0 {
+			operationIDs[id]++
+			id = fmt.Sprintf("%s%d", id, n+1)
+		} else {
+			operationIDs[id] = 1
+		}
+
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		item := &PathItem{
+			Tags:        []string{tag(route.Path, prefix)},
+			Summary:     op.Summary,
+			Description: op.Description,
+			OperationID: id,
+			Responses: map[string]Response{
+				"200":     {Description: "Success", Content: jsonContent(&Schema{Ref: "#/components/schemas/Response"})},
+				"default": {Description: "Error", Content: jsonContent(&Schema{Ref: "#/components/schemas/Response"})},
+			},
+		}
+		if slices.Contains(public, route.Path) {
+			item.Security = []map[string][]string{{}}
+		}
+
+		for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			item.Parameters = append(item.Parameters, Parameter{
+				Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+		item.Parameters = append(item.Parameters, queryParameters(op, schemas)...)
+
+		if op.Body != nil && route.Method != http.MethodGet {
+			item.RequestBody = &RequestBody{Required: true, Content: jsonContent(schemas.schema(op.Body))}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = item
+	}
+
+	doc.Components.Schemas["Response"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer"},
+			"message": {Type: "string"},
+			"data":    {},
+		},
+		Required: []string{"code", "message"},
+	}
+	return doc
+}
+
+// handlerName turns the function name gin reports, such as
+// "gpt-load/internal/handler.(*Server).ListGroups-fm", into "Server.ListGroups".
+func handlerName(handler string) string {
+	handler = strings.TrimSuffix(handler, "-fm")
+	if i := strings.LastIndex(handler, "/"); i >= 0 {
+		handler = handler[i+1:]
+	}
+	if i := strings.Index(handler, "."); i >= 0 {
+		handler = handler[i+1:]
+	}
+	return strings.NewReplacer("(", "", ")", "", "*", "").Replace(handler)
+}
+
+func operationID(name, method string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || strings.HasPrefix(name, "func") {
+		return strings.ToLower(method)
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// tag groups the operations by the first path segment after the prefix.
+func tag(path, prefix string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, prefix+"/"), "/")
+	return segment
+}
+
+func queryParameters(op Operation, schemas *schemaBuilder) []Parameter {
+	var params []Parameter
+	seen := map[string]bool{}
+	if op.Query != nil {
+		t := op.Query
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		for _, field := range fields(t) {
+			name := field.Tag.Get("form")
+			if name == "" || name == "-" {
+				continue
+			}
+			seen[name] = true
+			params = append(params, Parameter{
+				Name:     name,
+				In:       "query",
+				Required: strings.Contains(field.Tag.Get("binding"), "required"),
+				Schema:   schemas.schema(field.Type),
+			})
+		}
+	}
+	for _, name := range op.QueryParams {
+		if !seen[name] {
+			seen[name] = true
+			params = append(params, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+		}
+	}
+	return params
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// fields returns the fields of a struct with the fields of embedded structs promoted.
+func fields(t reflect.Type) []reflect.StructField {
+	var result []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				result = append(result, fields(embedded)...)
+				continue
+			}
+		}
+		if field.IsExported() {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schemaBuilder converts Go types to schemas, registering named structs as components.
+type schemaBuilder struct {
+	components map[string]*Schema
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Nullable: nullable}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Nullable: nullable}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem()), Nullable: nullable}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem()), Nullable: nullable}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := componentName(t)
+		if _, ok := b.components[name]; !ok {
+			// Registered before the fields are walked so that recursive types terminate.
+			b.components[name] = &Schema{}
+			*b.components[name] = *b.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (b *schemaBuilder) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, field := range fields(t) {
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(options, "string") {
+			schema.Properties[name] = &Schema{Type: "string"}
+		} else {
+			schema.Properties[name] = b.schema(field.Type)
+		}
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// componentName qualifies a type name with its package, e.g. the Group type of internal/models
+// becomes models.Group.
+func componentName(t reflect.Type) string {
+	name := strings.NewReplacer("[", "_", "]", "", "*", "", "/", "_").Replace(t.Name())
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ctxKeyPluginContext holds the plugin context of a request between the middleware and the proxy.
+const ctxKeyPluginContext = "plugin_context"
+
+// Manager runs the hooks of the enabled plugins.
+type Manager struct {
+	preAuth      []PreAuthHook
+	preRoute     []PreRouteHook
+	preUpstream  []PreUpstreamHook
+	postResponse []PostResponseHook
+	onError      []OnErrorHook
+	names        []string
+}
+
+// NewManager enables the plugins listed in PLUGINS. An unknown name or a failing Init stops startup,
+// so a policy plugin can never be silently missing.
+func NewManager(configManager types.ConfigManager) (*Manager, error) {
+	m := &Manager{}
+	seen := make(map[string]bool)
+	for _, name := range configManager.GetPlugins() {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		p, ok := lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown plugin %q, registered plugins: %s", name, strings.Join(Registered(), ", "))
+		}
+		if initializer, ok := p.(Initializer); ok {
+			if err := initializer.Init(); err != nil {
+				return nil, fmt.Errorf("failed to initialize plugin %q: %w", name, err)
+			}
+		}
+		m.add(p)
+	}
+
+	if len(m.names) > 0 {
+		logrus.Infof("Plugins enabled: %s", strings.Join(m.names, ", "))
+	}
+	return m, nil
+}
+
+func (m *Manager) add(p Plugin) {
+	m.names = append(m.names, p.Name())
+	if h, ok := p.(PreAuthHook); ok {
+		m.preAuth = append(m.preAuth, h)
+	}
+	if h, ok := p.(PreRouteHook); ok {
+		m.preRoute = append(m.preRoute, h)
+	}
+	if h, ok := p.(PreUpstreamHook); ok {
+		m.preUpstream = append(m.preUpstream, h)
+	}
+	if h, ok := p.(PostResponseHook); ok {
+		m.postResponse = append(m.postResponse, h)
+	}
+	if h, ok := p.(OnErrorHook); ok {
+		m.onError = append(m.onError, h)
+	}
+}
+
+// Enabled reports whether any plugin is enabled.
+func (m *Manager) Enabled() bool {
+	return len(m.names) > 0
+}
+
+// Context returns the plugin context of a request, creating it on first use.
+func (m *Manager) Context(c *gin.Context) *Context {
+	if v, ok := c.Get(ctxKeyPluginContext); ok {
+		return v.(*Context)
+	}
+	ctx := &Context{Gin: c, GroupName: c.Param("group_name"), Values: make(map[string]any)}
+	c.Set(ctxKeyPluginContext, ctx)
+	return ctx
+}
+
+// PreAuth runs the pre-auth hooks, stopping at the first rejection.
+func (m *Manager) PreAuth(ctx *Context) *app_errors.APIError {
+	for _, h := range m.preAuth {
+		if err := callHook(h, func() error { return h.PreAuth(ctx) }); err != nil {
+			return rejection(h, "pre-auth", err)
+		}
+	}
+	return nil
+}
+
+// PreRoute runs the pre-route hooks, stopping at the first rejection.
+func (m *Manager) PreRoute(ctx *Context) *app_errors.APIError {
+	for _, h := range m.preRoute {
+		if err := callHook(h, func() error { return h.PreRoute(ctx) }); err != nil {
+			return rejection(h, "pre-route", err)
+		}
+	}
+	return nil
+}
+
+// PreUpstream runs the pre-upstream hooks, stopping at the first rejection.
+func (m *Manager) PreUpstream(ctx *Context) *app_errors.APIError {
+	for _, h := range m.preUpstream {
+		if err := callHook(h, func() error { return h.PreUpstream(ctx) }); err != nil {
+			return rejection(h, "pre-upstream", err)
+		}
+	}
+	return nil
+}
+
+// Finish runs the post-response or on-error hooks depending on the status written to the client.
+// err is the error that failed the request, if known.
+func (m *Manager) Finish(ctx *Context, err error) {
+	if len(m.postResponse) == 0 && len(m.onError) == 0 {
+		return
+	}
+
+	ctx.StatusCode = ctx.Gin.Writer.Status()
+	if err == nil && ctx.StatusCode < 400 {
+		for _, h := range m.postResponse {
+			callHook(h, func() error { h.PostResponse(ctx); return nil })
+		}
+		return
+	}
+
+	if err == nil {
+		err = fmt.Errorf("request failed with status %d", ctx.StatusCode)
+	}
+	ctx.Err = err
+	for _, h := range m.onError {
+		callHook(h, func() error { h.OnError(ctx); return nil })
+	}
+}
+
+// callHook runs a hook and turns a panic into an error so a faulty plugin fails the request rather
+// than the server.
+func callHook(p any, hook func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logrus.Errorf("Plugin %s panicked: %v", p.(Plugin).Name(), recovered)
+			err = fmt.Errorf("plugin %s failed", p.(Plugin).Name())
+		}
+	}()
+	return hook()
+}
+
+// rejection converts a hook error into the response sent to the client. Plugins return an
+// *errors.APIError to choose the status and code, any other error is a 403 PLUGIN_REJECTED.
+func rejection(p any, hook string, err error) *app_errors.APIError {
+	logrus.WithFields(logrus.Fields{
+		"plugin": p.(Plugin).Name(),
+		"hook":   hook,
+	}).Debugf("Request rejected by plugin: %v", err)
+
+	var apiErr *app_errors.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return app_errors.NewAPIError(app_errors.ErrPluginRejected, err.Error())
+}
@@ -0,0 +1,125 @@
+// Package plugin lets custom policy logic hook into the proxy without forking it.
+//
+// A plugin is any value with a Name that implements one or more of the hook interfaces below.
+// Plugins register themselves from an init function, and only the ones listed in the PLUGINS
+// environment variable are enabled, in that order:
+//
+//	func init() {
+//		plugin.Register(&myPlugin{})
+//	}
+//
+// In-tree plugins live under the top-level plugins directory. User plugins are compiled in by
+// adding their package there and importing it from plugins/plugins.go.
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Plugin is implemented by every plugin. Name is the identifier used in PLUGINS.
+type Plugin interface {
+	Name() string
+}
+
+// Initializer is implemented by plugins that read their configuration or set up resources once
+// they are enabled. It runs after the environment and .env file have been loaded, and an error
+// stops the server from starting.
+type Initializer interface {
+	Init() error
+}
+
+// PreAuthHook runs before the proxy key is checked. Only GroupName is set on the context.
+type PreAuthHook interface {
+	PreAuth(ctx *Context) error
+}
+
+// PreRouteHook runs once the request is authenticated and its body has been read, before model
+// routing, canary splits and sub-group selection. Body may be replaced.
+type PreRouteHook interface {
+	PreRoute(ctx *Context) error
+}
+
+// PreUpstreamHook runs right before each attempt is sent upstream, after keys, header rules and
+// body rewrites have been applied. Upstream may be modified.
+type PreUpstreamHook interface {
+	PreUpstream(ctx *Context) error
+}
+
+// PostResponseHook runs after a request has been answered successfully. The response has already
+// been written, so it is suited to auditing, metrics and billing.
+type PostResponseHook interface {
+	PostResponse(ctx *Context)
+}
+
+// OnErrorHook runs after a request has been answered with an error, whether it came from the
+// proxy, the upstream or a plugin.
+type OnErrorHook interface {
+	OnError(ctx *Context)
+}
+
+// Context carries the request through the hooks. Fields are filled as the request progresses.
+type Context struct {
+	Gin       *gin.Context
+	GroupName string
+	// Group is the group the request was sent to, and from PreUpstream on the group that serves it.
+	Group *models.Group
+	// Body is the request body. Changes made in PreRoute are what the proxy sends.
+	Body []byte
+	// Upstream is the outgoing request in PreUpstream.
+	Upstream *http.Request
+	// Attempt is the zero-based attempt number in PreUpstream.
+	Attempt    int
+	StatusCode int
+	// Err is the error that failed the request, set for OnError.
+	Err error
+	// Values is shared by the hooks of one request, for plugins that pass state between hooks.
+	Values map[string]any
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Plugin)
+)
+
+// Register makes a plugin available to the PLUGINS setting. It panics when the name is empty or
+// already taken, as it is meant to be called from init.
+func Register(p Plugin) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := p.Name()
+	if name == "" {
+		panic("plugin: Register called with an empty plugin name")
+	}
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("plugin: plugin %q registered twice", name))
+	}
+	registry[name] = p
+}
+
+// Registered returns the names of all registered plugins, sorted.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (Plugin, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	p, ok := registry[name]
+	return p, ok
+}
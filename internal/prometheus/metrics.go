@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -68,20 +69,198 @@ var (
 	proxyRequestsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "gpt_load_proxy_requests_total",
-			Help: "Total number of proxy requests per group",
+			Help: "Total number of proxy requests per group and model",
 		},
-		[]string{"group", "status"},
+		[]string{"group", "model", "status"},
 	)
 
 	proxyRequestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "gpt_load_proxy_request_duration_seconds",
-			Help:    "Proxy request duration in seconds",
+			Help:    "Proxy request duration in seconds per group and model",
 			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120},
 		},
+		[]string{"group", "model"},
+	)
+
+	streamTimeToFirstToken = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gpt_load_stream_time_to_first_token_seconds",
+			Help:    "Time from sending a streamed request upstream to the first token, in seconds",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30},
+		},
+		[]string{"group", "model"},
+	)
+
+	streamTokensPerSecond = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gpt_load_stream_tokens_per_second",
+			Help:    "Output throughput of streamed responses after the first token",
+			Buckets: []float64{1, 5, 10, 20, 40, 60, 80, 100, 150, 200, 400},
+		},
+		[]string{"group", "model"},
+	)
+
+	responseCacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_response_cache_requests_total",
+			Help: "Total number of response cache lookups per group and model",
+		},
+		[]string{"group", "model", "result"},
+	)
+
+	promptCacheTokensTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_prompt_cache_tokens_total",
+			Help: "Total number of input tokens of prompt-cached requests by cache usage",
+		},
+		[]string{"group", "type"},
+	)
+
+	contentFilteredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_content_filtered_requests_total",
+			Help: "Total number of upstream responses blocked by a content filter",
+		},
+		[]string{"group", "action"},
+	)
+
+	scheduleDivertedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_schedule_diverted_requests_total",
+			Help: "Total number of requests arriving outside a group's traffic schedule",
+		},
+		[]string{"group", "action"},
+	)
+
+	canarySplitTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_canary_split_requests_total",
+			Help: "Total number of requests split between a group and its canary group",
+		},
+		[]string{"group", "target"},
+	)
+
+	groupQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gpt_load_group_queue_depth",
+			Help: "Number of requests waiting for a free concurrency slot per group",
+		},
 		[]string{"group"},
 	)
 
+	upstreamHealthy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gpt_load_upstream_healthy",
+			Help: "Whether an upstream of a group passes its active probes and its circuit is not open (1) or not (0)",
+		},
+		[]string{"group", "upstream"},
+	)
+
+	duplicateRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_duplicate_requests_total",
+			Help: "Total number of duplicate requests collapsed or throttled per group",
+		},
+		[]string{"group", "action"},
+	)
+
+	stickySessionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_sticky_session_requests_total",
+			Help: "Total number of conversational requests by sticky session outcome per group",
+		},
+		[]string{"group", "result"},
+	)
+
+	quotaPacedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_quota_paced_total",
+			Help: "Total number of keys skipped or requests rejected to pace quota usage per group",
+		},
+		[]string{"group", "action"},
+	)
+
+	keyLimitedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_key_limited_total",
+			Help: "Total number of keys skipped or requests rejected because keys reached their own rate or spend limits per group",
+		},
+		[]string{"group", "action"},
+	)
+
+	keyCooldownTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_key_cooldown_total",
+			Help: "Total number of keys put on cooldown after an upstream 429, keys skipped while cooling down and requests rejected because every key tried was cooling down per group and model",
+		},
+		[]string{"group", "model", "action"},
+	)
+
+	upstreamErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_upstream_errors_total",
+			Help: "Total number of failed upstream attempts per group, model and error category",
+		},
+		[]string{"group", "model", "category"},
+	)
+
+	upstreamRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_upstream_retries_total",
+			Help: "Total number of requests retried with another key after a failed upstream attempt per group, model and error category",
+		},
+		[]string{"group", "model", "category"},
+	)
+
+	tokensTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_tokens_total",
+			Help: "Total number of prompt and completion tokens reported by upstreams per group and model",
+		},
+		[]string{"group", "model", "type"},
+	)
+
+	costMicroDollarsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_cost_microdollars_total",
+			Help: "Total cost in micro-dollars of requests to models with configured pricing per group and model",
+		},
+		[]string{"group", "model"},
+	)
+
+	contextWindowTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_context_window_total",
+			Help: "Total number of requests rejected, truncated or given an output limit to fit the model context window per group",
+		},
+		[]string{"group", "action"},
+	)
+
+	piiRedactionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_pii_redactions_total",
+			Help: "Total number of personal data matches masked or rejected in requests per group",
+		},
+		[]string{"group", "type", "action"},
+	)
+
+	moderationTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_moderation_total",
+			Help: "Total number of requests checked by content moderation per group",
+		},
+		[]string{"group", "result"},
+	)
+
+	scriptRulesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gpt_load_script_rules_total",
+			Help: "Total number of script rules that matched or failed per group",
+		},
+		[]string{"group", "action"},
+	)
+
 	keyRotationsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "gpt_load_key_rotations_total",
@@ -111,6 +290,28 @@ func Init() error {
 		invalidKeysTotal,
 		proxyRequestsTotal,
 		proxyRequestDuration,
+		streamTimeToFirstToken,
+		streamTokensPerSecond,
+		responseCacheRequestsTotal,
+		promptCacheTokensTotal,
+		contentFilteredTotal,
+		scheduleDivertedTotal,
+		canarySplitTotal,
+		groupQueueDepth,
+		upstreamHealthy,
+		duplicateRequestsTotal,
+		stickySessionsTotal,
+		quotaPacedTotal,
+		keyLimitedTotal,
+		keyCooldownTotal,
+		upstreamErrorsTotal,
+		upstreamRetriesTotal,
+		tokensTotal,
+		costMicroDollarsTotal,
+		contextWindowTotal,
+		piiRedactionsTotal,
+		moderationTotal,
+		scriptRulesTotal,
 		keyRotationsTotal,
 		keyValidationTotal,
 	}
@@ -128,10 +329,17 @@ func Init() error {
 	return nil
 }
 
-// Handler returns a Gin handler for the Prometheus metrics endpoint
+// Handler returns a Gin handler for the Prometheus metrics endpoint. Scrapers that accept the
+// OpenMetrics format also receive the trace ID exemplars of the latency histograms.
 func Handler() gin.HandlerFunc {
-	h := promhttp.Handler()
+	h := promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
 	return func(c *gin.Context) {
+		if err := refreshKeyGauges(); err != nil {
+			logrus.Warnf("Failed to refresh key gauges: %v", err)
+		}
 		h.ServeHTTP(c.Writer, c.Request)
 	}
 }
@@ -163,10 +371,143 @@ func SetInvalidKeys(group string, count float64) {
 	invalidKeysTotal.WithLabelValues(group).Set(count)
 }
 
-// RecordProxyRequest records a proxy request
-func RecordProxyRequest(group, status string, duration float64) {
-	proxyRequestsTotal.WithLabelValues(group, status).Inc()
-	proxyRequestDuration.WithLabelValues(group).Observe(duration)
+// RecordProxyRequest records a proxy request, with traceID as exemplar when it is not empty
+func RecordProxyRequest(group, model, status string, duration float64, traceID string) {
+	label := modelLabel(model)
+	proxyRequestsTotal.WithLabelValues(group, label, status).Inc()
+	observeWithTraceID(proxyRequestDuration.WithLabelValues(group, label), duration, traceID)
+}
+
+// RecordStreamTTFT records the time to first token of a streamed response, with traceID as
+// exemplar when it is not empty
+func RecordStreamTTFT(group, model string, seconds float64, traceID string) {
+	observeWithTraceID(streamTimeToFirstToken.WithLabelValues(group, modelLabel(model)), seconds, traceID)
+}
+
+// observeWithTraceID observes a value and attaches the trace it was measured in as exemplar.
+func observeWithTraceID(observer prometheus.Observer, value float64, traceID string) {
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && traceID != "" {
+		exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	observer.Observe(value)
+}
+
+// RecordStreamThroughput records the tokens per second of a streamed response
+func RecordStreamThroughput(group, model string, tokensPerSecond float64) {
+	streamTokensPerSecond.WithLabelValues(group, modelLabel(model)).Observe(tokensPerSecond)
+}
+
+// RecordResponseCache records a response cache lookup, result is "hit" or "miss"
+func RecordResponseCache(group, model, result string) {
+	responseCacheRequestsTotal.WithLabelValues(group, modelLabel(model), result).Inc()
+}
+
+// RecordPromptCacheTokens records the input tokens of a request that used provider prompt caching
+func RecordPromptCacheTokens(group string, uncached, creation, read int) {
+	promptCacheTokensTotal.WithLabelValues(group, "uncached").Add(float64(uncached))
+	promptCacheTokensTotal.WithLabelValues(group, "creation").Add(float64(creation))
+	promptCacheTokensTotal.WithLabelValues(group, "read").Add(float64(read))
+}
+
+// RecordContentFiltered records a content-filter block and the action taken for it
+func RecordContentFiltered(group, action string) {
+	contentFilteredTotal.WithLabelValues(group, action).Inc()
+}
+
+// RecordScheduleDiverted records a request that arrived outside a group's traffic schedule
+func RecordScheduleDiverted(group, action string) {
+	scheduleDivertedTotal.WithLabelValues(group, action).Inc()
+}
+
+// RecordCanarySplit records which side of a canary split served a request
+func RecordCanarySplit(group, target string) {
+	canarySplitTotal.WithLabelValues(group, target).Inc()
+}
+
+// SetGroupQueueDepth sets the number of requests queued for a group
+func SetGroupQueueDepth(group string, depth int) {
+	groupQueueDepth.WithLabelValues(group).Set(float64(depth))
+}
+
+// SetUpstreamHealth replaces the health of every probed upstream, keyed by group and upstream URL,
+// so that upstreams no longer configured disappear from the gauge
+func SetUpstreamHealth(health map[[2]string]bool) {
+	upstreamHealthy.Reset()
+	for key, healthy := range health {
+		value := 0.0
+		if healthy {
+			value = 1
+		}
+		upstreamHealthy.WithLabelValues(key[0], key[1]).Set(value)
+	}
+}
+
+// RecordDuplicateRequest records a duplicate request that was collapsed or throttled
+func RecordDuplicateRequest(group, action string) {
+	duplicateRequestsTotal.WithLabelValues(group, action).Inc()
+}
+
+// RecordStickySession records whether a conversational request reused its pinned key and upstream
+func RecordStickySession(group, result string) {
+	stickySessionsTotal.WithLabelValues(group, result).Inc()
+}
+
+// RecordQuotaPaced records a key skipped or a request rejected because it was ahead of the quota pace
+func RecordQuotaPaced(group, action string) {
+	quotaPacedTotal.WithLabelValues(group, action).Inc()
+}
+
+// RecordKeyLimited records a key skipped or a request rejected because keys reached their rpm_limit, tpm_limit or daily_spend_limit
+func RecordKeyLimited(group, action string) {
+	keyLimitedTotal.WithLabelValues(group, action).Inc()
+}
+
+// RecordKeyCooldown records a key put on cooldown after an upstream 429, skipped while cooling down, or a request rejected because every key tried was cooling down
+func RecordKeyCooldown(group, model, action string) {
+	keyCooldownTotal.WithLabelValues(group, modelLabel(model), action).Inc()
+}
+
+// RecordUpstreamError records a failed upstream attempt under its error category
+func RecordUpstreamError(group, model, category string) {
+	upstreamErrorsTotal.WithLabelValues(group, modelLabel(model), category).Inc()
+}
+
+// RecordUpstreamRetry records a request retried with another key after a failed attempt of the given error category
+func RecordUpstreamRetry(group, model, category string) {
+	upstreamRetriesTotal.WithLabelValues(group, modelLabel(model), category).Inc()
+}
+
+// RecordTokenUsage records the prompt and completion tokens of a request
+func RecordTokenUsage(group, model string, promptTokens, completionTokens int) {
+	label := modelLabel(model)
+	tokensTotal.WithLabelValues(group, label, "prompt").Add(float64(promptTokens))
+	tokensTotal.WithLabelValues(group, label, "completion").Add(float64(completionTokens))
+}
+
+// RecordCost records the cost in USD of a request, counted in micro-dollars
+func RecordCost(group, model string, cost float64) {
+	costMicroDollarsTotal.WithLabelValues(group, modelLabel(model)).Add(cost * 1e6)
+}
+
+// RecordContextWindow records a request rejected, truncated or given an output limit to fit the context window
+func RecordContextWindow(group, action string) {
+	contextWindowTotal.WithLabelValues(group, action).Inc()
+}
+
+// RecordPIIRedactions records the personal data matches of a request that were masked or rejected
+func RecordPIIRedactions(group, piiType, action string, count int) {
+	piiRedactionsTotal.WithLabelValues(group, piiType, action).Add(float64(count))
+}
+
+// RecordModeration records the outcome of a content moderation check
+func RecordModeration(group, result string) {
+	moderationTotal.WithLabelValues(group, result).Inc()
+}
+
+// RecordScriptRule records a script rule that matched, by its action, or that failed with "error"
+func RecordScriptRule(group, action string) {
+	scriptRulesTotal.WithLabelValues(group, action).Inc()
 }
 
 // RecordKeyRotation records a key rotation event
@@ -0,0 +1,58 @@
+package prometheus
+
+import "sync"
+
+const (
+	// maxModelLabels bounds the number of distinct model label values. Model names come from
+	// client requests, so without a bound every made-up name would add new series.
+	maxModelLabels = 200
+	// maxModelLabelLength bounds the length of a model label value.
+	maxModelLabelLength = 100
+	// modelLabelOther replaces models seen after maxModelLabels was reached.
+	modelLabelOther = "other"
+	// modelLabelUnknown replaces an empty model name.
+	modelLabelUnknown = "unknown"
+)
+
+var (
+	modelLabelsMu  sync.Mutex
+	modelLabels    = make(map[string]struct{})
+	modelAllowlist map[string]struct{}
+)
+
+// SetModelAllowlist sets the models that keep their name in model labels. An empty allowlist
+// lets the first maxModelLabels models keep their name.
+func SetModelAllowlist(models map[string]struct{}) {
+	modelLabelsMu.Lock()
+	defer modelLabelsMu.Unlock()
+	modelAllowlist = models
+}
+
+// modelLabel returns the label value for a model. Models on the allowlist keep their name and
+// others are counted under "other"; without an allowlist the first maxModelLabels models keep
+// their name.
+func modelLabel(model string) string {
+	if model == "" {
+		return modelLabelUnknown
+	}
+
+	modelLabelsMu.Lock()
+	defer modelLabelsMu.Unlock()
+	if modelAllowlist != nil {
+		if _, ok := modelAllowlist[model]; ok {
+			return model
+		}
+		return modelLabelOther
+	}
+	if len(model) > maxModelLabelLength {
+		return modelLabelOther
+	}
+	if _, ok := modelLabels[model]; ok {
+		return model
+	}
+	if len(modelLabels) >= maxModelLabels {
+		return modelLabelOther
+	}
+	modelLabels[model] = struct{}{}
+	return model
+}
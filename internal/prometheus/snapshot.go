@@ -0,0 +1,172 @@
+package prometheus
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// keyGaugeRefreshInterval limits how often the key gauges are recounted.
+const keyGaugeRefreshInterval = 15 * time.Second
+
+// KeyCounter returns the number of active and invalid keys per group name.
+type KeyCounter func() (active, invalid map[string]int64, err error)
+
+var (
+	keyCounterMu     sync.Mutex
+	keyCounter       KeyCounter
+	keyGaugesUpdated time.Time
+)
+
+// SetKeyCounter registers the function that feeds the per-group key gauges. The gauges are
+// refreshed when metrics are collected rather than on every key status change.
+func SetKeyCounter(counter KeyCounter) {
+	keyCounterMu.Lock()
+	defer keyCounterMu.Unlock()
+	keyCounter = counter
+	keyGaugesUpdated = time.Time{}
+}
+
+// refreshKeyGauges recounts the keys of every group, at most once per keyGaugeRefreshInterval.
+func refreshKeyGauges() error {
+	keyCounterMu.Lock()
+	defer keyCounterMu.Unlock()
+
+	if keyCounter == nil || time.Since(keyGaugesUpdated) < keyGaugeRefreshInterval {
+		return nil
+	}
+	active, invalid, err := keyCounter()
+	if err != nil {
+		return err
+	}
+	keyGaugesUpdated = time.Now()
+
+	// Reset so that deleted groups disappear from the gauges
+	activeKeysTotal.Reset()
+	invalidKeysTotal.Reset()
+	for group, count := range active {
+		SetActiveKeys(group, float64(count))
+	}
+	for group, count := range invalid {
+		SetInvalidKeys(group, float64(count))
+	}
+	return nil
+}
+
+// SnapshotOptions narrows a metrics snapshot.
+type SnapshotOptions struct {
+	// Groups, when not nil, keeps only series whose group label is one of these groups.
+	Groups []string
+	// Prefix keeps only metric families whose name starts with it.
+	Prefix string
+}
+
+// MetricSample is one series of a metric family. Counters and gauges carry Value, histograms
+// carry Count, Sum and cumulative Buckets keyed by upper bound.
+type MetricSample struct {
+	Labels  map[string]string `json:"labels"`
+	Value   *float64          `json:"value,omitempty"`
+	Count   *uint64           `json:"count,omitempty"`
+	Sum     *float64          `json:"sum,omitempty"`
+	Buckets map[string]uint64 `json:"buckets,omitempty"`
+}
+
+// MetricFamily is a metric with all of its series.
+type MetricFamily struct {
+	Name    string         `json:"name"`
+	Help    string         `json:"help"`
+	Type    string         `json:"type"`
+	Samples []MetricSample `json:"samples"`
+}
+
+// Snapshot is the current state of the registered metrics.
+type Snapshot struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Metrics   []MetricFamily `json:"metrics"`
+}
+
+// TakeSnapshot gathers the metrics served on /metrics in a JSON-friendly form.
+func TakeSnapshot(opts SnapshotOptions) (*Snapshot, error) {
+	if err := refreshKeyGauges(); err != nil {
+		return nil, err
+	}
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]bool, len(opts.Groups))
+	for _, group := range opts.Groups {
+		groups[group] = true
+	}
+
+	snapshot := &Snapshot{Timestamp: time.Now(), Metrics: []MetricFamily{}}
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), opts.Prefix) {
+			continue
+		}
+
+		samples := make([]MetricSample, 0, len(family.GetMetric()))
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if opts.Groups != nil && !groups[labels["group"]] {
+				continue
+			}
+			samples = append(samples, newMetricSample(family.GetType(), metric, labels))
+		}
+		if len(samples) == 0 && opts.Groups != nil {
+			continue
+		}
+
+		snapshot.Metrics = append(snapshot.Metrics, MetricFamily{
+			Name:    family.GetName(),
+			Help:    family.GetHelp(),
+			Type:    strings.ToLower(family.GetType().String()),
+			Samples: samples,
+		})
+	}
+	return snapshot, nil
+}
+
+func newMetricSample(metricType dto.MetricType, metric *dto.Metric, labels map[string]string) MetricSample {
+	sample := MetricSample{Labels: labels}
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		sample.Value = finite(metric.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		sample.Value = finite(metric.GetGauge().GetValue())
+	case dto.MetricType_UNTYPED:
+		sample.Value = finite(metric.GetUntyped().GetValue())
+	case dto.MetricType_HISTOGRAM:
+		h := metric.GetHistogram()
+		count := h.GetSampleCount()
+		sample.Count = &count
+		sample.Sum = finite(h.GetSampleSum())
+		sample.Buckets = make(map[string]uint64, len(h.GetBucket()))
+		for _, bucket := range h.GetBucket() {
+			sample.Buckets[strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)] = bucket.GetCumulativeCount()
+		}
+	case dto.MetricType_SUMMARY:
+		s := metric.GetSummary()
+		count := s.GetSampleCount()
+		sample.Count = &count
+		sample.Sum = finite(s.GetSampleSum())
+	}
+	return sample
+}
+
+// finite returns a pointer to v, or nil for values JSON cannot represent.
+func finite(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
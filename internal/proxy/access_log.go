@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"net/url"
+
+	"gpt-load/internal/accesslog"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAccessDetails attaches what the proxy knows about a request to its access log entry. Each
+// upstream attempt is counted; the final one fills in the group, model, usage and cost.
+func (ps *ProxyServer) recordAccessDetails(c *gin.Context, entry *models.RequestLog, apiKey *models.APIKey, usage *usageStats) {
+	if !ps.accessLogger.Enabled() {
+		return
+	}
+	details := accesslog.GetDetails(c)
+	if details == nil {
+		details = &accesslog.Details{}
+		accesslog.SetDetails(c, details)
+	}
+	if apiKey != nil {
+		details.Attempts++
+	}
+	if entry.RequestType != models.RequestTypeFinal {
+		return
+	}
+
+	details.Group = entry.GroupName
+	details.ParentGroup = entry.ParentGroupName
+	details.Model = entry.Model
+	details.Stream = entry.IsStream
+	details.CacheHit = entry.CacheHit
+	if upstream, err := url.Parse(entry.UpstreamAddr); err == nil && upstream.Host != "" {
+		details.Upstream = upstream.Scheme + "://" + upstream.Host + upstream.Path
+	}
+	if apiKey != nil {
+		details.Key = maskDiagnosticsKey(apiKey.KeyValue)
+	}
+	if usage != nil {
+		details.PromptTokens = usage.PromptTokens
+		details.CompletionTokens = usage.CompletionTokens
+		details.TotalTokens = usage.TotalTokens
+		if pricing := pricingOf(ps.modelInfo.get(entry.GroupID, entry.Model)); pricing != nil && usage.TotalTokens > 0 {
+			cost := roundCost(pricing.cost(usage.PromptTokens, usage.CompletionTokens))
+			details.Cost = &cost
+		}
+	}
+}
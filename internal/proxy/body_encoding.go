@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDecodedRequestBytes bounds decompressed request bodies of groups without max_request_body_kb,
+// so that a small compressed body cannot expand into an unbounded allocation.
+const maxDecodedRequestBytes = 64 << 20
+
+// requestTooLargeError is returned when a request body exceeds Limit bytes.
+type requestTooLargeError struct {
+	Limit int64
+}
+
+func (e *requestTooLargeError) Error() string {
+	return fmt.Sprintf("request body exceeds %d bytes", e.Limit)
+}
+
+// readRequestBody reads the client request body. Gzip and deflate bodies are decompressed, so that
+// the model can be read from them and rules can rewrite them, and are forwarded uncompressed. A
+// positive limit bounds the body both as sent and once decompressed; decompressed bodies are
+// always bounded, by maxDecodedRequestBytes when there is no limit.
+func readRequestBody(c *gin.Context, limit int64) ([]byte, error) {
+	body := c.Request.Body
+	defer body.Close()
+	if limit > 0 {
+		if c.Request.ContentLength > limit {
+			return nil, &requestTooLargeError{Limit: limit}
+		}
+		body = http.MaxBytesReader(c.Writer, body, limit)
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+	var reader io.Reader = body
+	decoded := true
+	switch encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, requestBodyError(fmt.Errorf("invalid gzip request body: %w", err), limit)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		reader = newDeflateReader(body)
+	default:
+		decoded = false
+	}
+	readLimit := limit
+	if decoded && readLimit <= 0 {
+		readLimit = maxDecodedRequestBytes
+	}
+	if readLimit > 0 {
+		reader = io.LimitReader(reader, readLimit+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, requestBodyError(err, limit)
+	}
+	if readLimit > 0 && int64(len(data)) > readLimit {
+		return nil, &requestTooLargeError{Limit: readLimit}
+	}
+
+	if decoded {
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+	}
+	return data, nil
+}
+
+// requestBodyError reports bodies cut off by http.MaxBytesReader as a requestTooLargeError.
+func requestBodyError(err error, limit int64) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &requestTooLargeError{Limit: limit}
+	}
+	return err
+}
+
+// newDeflateReader decodes a deflate body. HTTP deflate is zlib-wrapped, but some clients send raw
+// DEFLATE data, so the zlib header is checked first.
+func newDeflateReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(2)
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(br)
+}
+
+// compressedResponseWriter returns the writer for a non-stream response body. Bodies of at least
+// minSize bytes, or of unknown size, are gzip-compressed for clients that accept gzip unless the
+// upstream already encoded them. The returned function flushes the compressor.
+func compressedResponseWriter(c *gin.Context, resp *http.Response, minSize int) (io.Writer, func() error) {
+	noop := func() error { return nil }
+	if minSize <= 0 || c.Request.Method == http.MethodHead ||
+		resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified ||
+		resp.Header.Get("Content-Encoding") != "" || !acceptsGzip(c.Request.Header.Get("Accept-Encoding")) {
+		return c.Writer, noop
+	}
+	if length, err := strconv.Atoi(resp.Header.Get("Content-Length")); err == nil && length < minSize {
+		return c.Writer, noop
+	}
+
+	header := c.Writer.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", "gzip")
+	header.Add("Vary", "Accept-Encoding")
+	gz := gzip.NewWriter(c.Writer)
+	return gz, gz.Close
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
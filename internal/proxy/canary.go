@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"hash/fnv"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// applyCanarySplit sends canary_percent of a group's clients to its canary group. Clients are
+// bucketed by a hash of their identifier, so a client stays on the same side while the percentage
+// is unchanged and raising it only moves clients from the stable group to the canary.
+func (ps *ProxyServer) applyCanarySplit(c *gin.Context, group *models.Group) *models.Group {
+	cfg := group.EffectiveConfig
+	if cfg.CanaryGroup == "" || cfg.CanaryPercent <= 0 || cfg.CanaryGroup == group.Name {
+		return group
+	}
+
+	if canaryBucket(group.Name, canaryClientID(c, cfg.CanaryStickyHeader)) >= cfg.CanaryPercent {
+		prometheus.RecordCanarySplit(group.Name, "stable")
+		return group
+	}
+
+	canary, err := ps.groupManager.GetGroupByName(cfg.CanaryGroup)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"group":  group.Name,
+			"canary": cfg.CanaryGroup,
+		}).Warn("Canary group not found, serving from the original group")
+		prometheus.RecordCanarySplit(group.Name, "stable")
+		return group
+	}
+
+	prometheus.RecordCanarySplit(group.Name, "canary")
+	logrus.WithFields(logrus.Fields{
+		"group":  group.Name,
+		"canary": canary.Name,
+	}).Debug("Routing request to canary group")
+	return canary
+}
+
+// canaryClientID identifies the client for sticky assignment: the configured header when the
+// request carries it, otherwise the proxy key combined with the client IP.
+func canaryClientID(c *gin.Context, header string) string {
+	if header != "" {
+		if value := c.GetHeader(header); value != "" {
+			return value
+		}
+	}
+	return c.GetString(middleware.ContextKeyProxyKey) + "|" + c.ClientIP()
+}
+
+// canaryBucket maps a client to a bucket in [0, 100). The group name is part of the hash so that
+// the same client lands in independent buckets for different groups.
+func canaryBucket(groupName, clientID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(groupName))
+	h.Write([]byte{0})
+	h.Write([]byte(clientID))
+	return int(h.Sum32() % 100)
+}
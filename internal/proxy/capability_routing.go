@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// capabilityModelPrefix marks a pseudo-model that names a capability profile, e.g.
+	// "capability:vision+functions+128k+cheapest".
+	capabilityModelPrefix = "capability:"
+	// capabilityHeader requests a capability profile regardless of the model in the body.
+	capabilityHeader = "X-Model-Capabilities"
+	// capabilityMatchHeader tells the client which group and model a capability request was sent to.
+	capabilityMatchHeader = "X-Capability-Match"
+)
+
+// Orders in which matching models are ranked.
+const (
+	capabilityOrderCheapest = "cheapest"
+	capabilityOrderLargest  = "largest"
+)
+
+// capabilityProfile is what a client requires of a model.
+type capabilityProfile struct {
+	vision     bool
+	functions  bool
+	streaming  bool
+	rerank     bool
+	minContext int
+	order      string
+}
+
+// capabilityCandidate is a model of a group that satisfies a profile.
+type capabilityCandidate struct {
+	group *models.Group
+	model *models.ModelCapabilities
+}
+
+// applyCapabilityRouting resolves a capability profile, requested through the X-Model-Capabilities
+// header or a "capability:" pseudo-model, to the best matching model among the group, its
+// sub-groups and its model routing targets, and rewrites the request to that model. It returns a
+// nil group when the request does not ask for a capability profile.
+func (ps *ProxyServer) applyCapabilityRouting(c *gin.Context, group *models.Group, bodyBytes []byte) (*models.Group, []byte, *app_errors.APIError) {
+	var requestData map[string]any
+	isJSON := json.Unmarshal(bodyBytes, &requestData) == nil && requestData != nil
+
+	spec := strings.TrimSpace(c.GetHeader(capabilityHeader))
+	if spec == "" && isJSON {
+		if model, ok := requestData["model"].(string); ok && strings.HasPrefix(model, capabilityModelPrefix) {
+			spec = strings.TrimPrefix(model, capabilityModelPrefix)
+		}
+	}
+	if spec == "" {
+		return nil, bodyBytes, nil
+	}
+
+	if !isJSON || group.ChannelType == "gemini" {
+		return nil, nil, app_errors.NewAPIError(app_errors.ErrBadRequest, "Capability routing requires a JSON request body with a model field")
+	}
+
+	profile, err := parseCapabilityProfile(spec)
+	if err != nil {
+		return nil, nil, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error())
+	}
+
+	var candidates []capabilityCandidate
+	for _, candidateGroup := range ps.capabilityCandidateGroups(group) {
+		groupModels := ps.modelInfo.list(candidateGroup.ID)
+		for i := range groupModels {
+			model := &groupModels[i]
+			if !profile.matches(model) ||
+				modelAccessError(c, group, model.ModelID) != nil ||
+				modelAccessError(c, candidateGroup, model.ModelID) != nil {
+				continue
+			}
+			candidates = append(candidates, capabilityCandidate{group: candidateGroup, model: model})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil, app_errors.NewAPIError(app_errors.ErrResourceNotFound, fmt.Sprintf("No model available in group '%s' matches capabilities '%s'", group.Name, spec))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return profile.less(candidates[i].model, candidates[j].model)
+	})
+	chosen := candidates[0]
+
+	requestData["model"] = chosen.model.ModelID
+	rewritten, err := json.Marshal(requestData)
+	if err != nil {
+		return nil, nil, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to rewrite request model: %v", err))
+	}
+
+	c.Header(capabilityMatchHeader, chosen.group.Name+"/"+chosen.model.ModelID)
+	logrus.WithFields(logrus.Fields{
+		"group":        group.Name,
+		"target":       chosen.group.Name,
+		"model":        chosen.model.ModelID,
+		"capabilities": spec,
+	}).Debug("Routing request by capability profile")
+
+	return chosen.group, rewritten, nil
+}
+
+// capabilityCandidateGroups returns the groups whose models may serve a capability request sent to
+// group: the group itself, its enabled sub-groups and its model routing targets. Only groups speaking
+// the same API format as the entry group are considered.
+func (ps *ProxyServer) capabilityCandidateGroups(group *models.Group) []*models.Group {
+	candidates := []*models.Group{group}
+	seen := map[uint]bool{group.ID: true}
+	add := func(name string) {
+		target, err := ps.groupManager.GetGroupByName(name)
+		if err != nil || seen[target.ID] || target.ChannelType != group.ChannelType {
+			return
+		}
+		seen[target.ID] = true
+		candidates = append(candidates, target)
+	}
+
+	for _, sub := range group.SubGroups {
+		if sub.Weight > 0 {
+			add(sub.SubGroupName)
+		}
+	}
+	for _, rule := range group.ModelRoutingList {
+		add(rule.Group)
+	}
+	return candidates
+}
+
+// parseCapabilityProfile parses tokens separated by '+', ',' or spaces: vision, functions (or
+// tools), streaming, rerank, a minimum context window such as 128k or 1m, and the ranking order
+// cheapest (default) or largest.
+func parseCapabilityProfile(spec string) (*capabilityProfile, error) {
+	profile := &capabilityProfile{order: capabilityOrderCheapest}
+	tokens := strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool {
+		return r == '+' || r == ',' || r == ';' || r == ' '
+	})
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty capability profile")
+	}
+
+	for _, token := range tokens {
+		switch token {
+		case "vision":
+			profile.vision = true
+		case "functions", "tools":
+			profile.functions = true
+		case "streaming":
+			profile.streaming = true
+		case "rerank":
+			profile.rerank = true
+		case capabilityOrderCheapest, capabilityOrderLargest:
+			profile.order = token
+		default:
+			count, ok := parseTokenCount(token)
+			if !ok {
+				return nil, fmt.Errorf("unknown capability '%s'", token)
+			}
+			profile.minContext = count
+		}
+	}
+	return profile, nil
+}
+
+// parseTokenCount parses a token count such as 8192, 128k or 1m.
+func parseTokenCount(value string) (int, bool) {
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1e3
+		value = strings.TrimSuffix(value, "k")
+	case strings.HasSuffix(value, "m"):
+		multiplier = 1e6
+		value = strings.TrimSuffix(value, "m")
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return int(n * multiplier), true
+}
+
+// matches reports whether a model provides every required capability.
+func (p *capabilityProfile) matches(model *models.ModelCapabilities) bool {
+	return (!p.vision || model.SupportsVision) &&
+		(!p.functions || model.SupportsFunctions) &&
+		(!p.streaming || model.SupportsStreaming) &&
+		(!p.rerank || model.SupportsRerank) &&
+		contextWindow(model) >= p.minContext
+}
+
+// less ranks a before b. Models without pricing rank after priced ones when ordering by price.
+func (p *capabilityProfile) less(a, b *models.ModelCapabilities) bool {
+	priceA, knownA := totalPrice(a)
+	priceB, knownB := totalPrice(b)
+	contextA, contextB := contextWindow(a), contextWindow(b)
+
+	if p.order == capabilityOrderLargest && contextA != contextB {
+		return contextA > contextB
+	}
+	if knownA != knownB {
+		return knownA
+	}
+	if priceA != priceB {
+		return priceA < priceB
+	}
+	return contextA > contextB
+}
+
+// contextWindow returns the input context size of a model, or 0 when unknown.
+func contextWindow(model *models.ModelCapabilities) int {
+	switch {
+	case model.MaxInputTokens != nil:
+		return *model.MaxInputTokens
+	case model.MaxTokens != nil:
+		return *model.MaxTokens
+	}
+	return 0
+}
+
+// totalPrice returns the sum of a model's input and output prices per million tokens.
+func totalPrice(model *models.ModelCapabilities) (float64, bool) {
+	pricing := pricingOf(model)
+	if pricing == nil {
+		return 0, false
+	}
+	return pricing.InputPerMillion + pricing.OutputPerMillion, true
+}
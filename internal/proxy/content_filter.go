@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Content filter policies, configured per group via content_filter_policy.
+const (
+	ContentFilterPassthrough = "passthrough"
+	ContentFilterNormalize   = "normalize"
+	ContentFilterRetry       = "retry"
+)
+
+// contentFilterPayload covers the places providers report content-filter blocks.
+type contentFilterPayload struct {
+	// OpenAI / Azure OpenAI
+	Choices []struct {
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Code       any    `json:"code"`
+		Message    string `json:"message"`
+		InnerError *struct {
+			Code string `json:"code"`
+		} `json:"innererror"`
+	} `json:"error"`
+
+	// Gemini
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	Candidates []struct {
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+
+	// Anthropic
+	StopReason string `json:"stop_reason"`
+}
+
+// detectContentFilter reports whether a (decompressed) response body is a content-filter block,
+// returning the provider's own reason string.
+func detectContentFilter(body []byte) (string, bool) {
+	var payload contentFilterPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", false
+	}
+
+	if e := payload.Error; e != nil {
+		if code, ok := e.Code.(string); ok && strings.EqualFold(code, "content_filter") {
+			return code, true
+		}
+		if e.InnerError != nil && strings.Contains(strings.ToLower(e.InnerError.Code), "contentfilter") {
+			return e.InnerError.Code, true
+		}
+	}
+
+	if payload.PromptFeedback != nil && payload.PromptFeedback.BlockReason != "" {
+		return payload.PromptFeedback.BlockReason, true
+	}
+
+	// A response is only considered blocked when every choice was filtered.
+	reasons := make([]string, 0, len(payload.Choices)+len(payload.Candidates)+1)
+	for _, choice := range payload.Choices {
+		reasons = append(reasons, choice.FinishReason)
+	}
+	for _, candidate := range payload.Candidates {
+		reasons = append(reasons, candidate.FinishReason)
+	}
+	if payload.StopReason != "" {
+		reasons = append(reasons, payload.StopReason)
+	}
+	if len(reasons) == 0 {
+		return "", false
+	}
+	for _, r := range reasons {
+		if utils.NormalizeFinishReason(r) != utils.FinishReasonContentFilter {
+			return "", false
+		}
+	}
+	return reasons[0], true
+}
+
+// contentFilterAction resolves the action for a blocked response. The retry policy falls back
+// to the normalized error once no attempts remain.
+func contentFilterAction(policy string, isLastAttempt bool) string {
+	switch policy {
+	case ContentFilterRetry:
+		if isLastAttempt {
+			return ContentFilterNormalize
+		}
+		return ContentFilterRetry
+	case ContentFilterNormalize:
+		return ContentFilterNormalize
+	default:
+		return ContentFilterPassthrough
+	}
+}
+
+// writeContentFilterError sends the normalized content-filter error shape.
+func writeContentFilterError(c *gin.Context, providerReason string) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error": gin.H{
+			"type":            "content_filter",
+			"code":            "content_filter",
+			"message":         "The request or response was blocked by the upstream provider's content filter.",
+			"provider_reason": providerReason,
+		},
+	})
+}
@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Context window policies, configured per group via context_window_policy.
+const (
+	ContextWindowOff      = "off"
+	ContextWindowReject   = "reject"
+	ContextWindowTruncate = "truncate"
+)
+
+// applyContextWindow estimates the prompt tokens of a request and compares them with the context
+// window of the model, its max_input_tokens or else max_tokens. Depending on the group's policy, a
+// prompt that does not fit is rejected or has its oldest turns dropped. With auto_max_tokens the
+// output limit is lowered, or set when missing, to what is left of the window. Models without a
+// known window are passed through.
+func (ps *ProxyServer) applyContextWindow(c *gin.Context, channelHandler channel.ChannelProxy, group *models.Group, bodyBytes []byte) ([]byte, *app_errors.APIError) {
+	cfg := group.EffectiveConfig
+	policy := cfg.ContextWindowPolicy
+	if (policy == "" || policy == ContextWindowOff) && !cfg.AutoMaxTokens {
+		return bodyBytes, nil
+	}
+
+	model := upstreamModel(c, channelHandler, group, bodyBytes)
+	capability := ps.modelInfo.get(group.ID, model)
+	if capability == nil {
+		return bodyBytes, nil
+	}
+	window := capability.MaxInputTokens
+	if window == nil {
+		window = capability.MaxTokens
+	}
+	if window == nil || *window <= 0 {
+		return bodyBytes, nil
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return bodyBytes, nil
+	}
+
+	changed := false
+	promptTokens := utils.EstimatePromptTokens(body)
+	if promptTokens > *window {
+		switch policy {
+		case ContextWindowReject:
+			prometheus.RecordContextWindow(group.Name, "rejected")
+			return nil, contextWindowError(model, promptTokens, *window)
+		case ContextWindowTruncate:
+			dropped := 0
+			promptTokens, dropped = truncateConversation(body, promptTokens, *window)
+			if promptTokens > *window {
+				prometheus.RecordContextWindow(group.Name, "rejected")
+				return nil, contextWindowError(model, promptTokens, *window)
+			}
+			prometheus.RecordContextWindow(group.Name, "truncated")
+			logrus.WithFields(logrus.Fields{
+				"group":    group.Name,
+				"model":    model,
+				"dropped":  dropped,
+				"estimate": promptTokens,
+			}).Debug("Dropped oldest messages to fit the context window")
+			changed = true
+		}
+	}
+
+	if cfg.AutoMaxTokens && promptTokens < *window {
+		remaining := *window - promptTokens
+		if capability.MaxOutputTokens != nil && *capability.MaxOutputTokens > 0 && *capability.MaxOutputTokens < remaining {
+			remaining = *capability.MaxOutputTokens
+		}
+		if requested := requestedMaxTokens(body); requested == nil || *requested > remaining {
+			setMaxTokens(c, group, body, remaining)
+			prometheus.RecordContextWindow(group.Name, "max_tokens_set")
+			changed = true
+		}
+	}
+
+	if !changed {
+		return bodyBytes, nil
+	}
+	newBody, err := json.Marshal(body)
+	if err != nil {
+		return bodyBytes, nil
+	}
+	return newBody, nil
+}
+
+func contextWindowError(model string, promptTokens, window int) *app_errors.APIError {
+	return app_errors.NewAPIError(app_errors.ErrContextWindow, fmt.Sprintf(
+		"The request is estimated at %d prompt tokens, more than the %d token context window of model '%s'",
+		promptTokens, window, model))
+}
+
+// truncateConversation drops the oldest turns of the messages (OpenAI, Anthropic) or contents
+// (Gemini) list until the prompt fits the window. System messages and the last message are kept,
+// and the list always restarts at a user message so tool results are not left without their call.
+// It returns the new estimate and the number of dropped messages.
+func truncateConversation(body map[string]any, promptTokens, window int) (int, int) {
+	key := "messages"
+	list, ok := body[key].([]any)
+	if !ok {
+		key = "contents"
+		if list, ok = body[key].([]any); !ok {
+			return promptTokens, 0
+		}
+	}
+
+	var system, conversation []any
+	for _, message := range list {
+		if role := messageRole(message); role == "system" || role == "developer" {
+			system = append(system, message)
+		} else {
+			conversation = append(conversation, message)
+		}
+	}
+
+	dropped := 0
+	for len(conversation) > 1 && (promptTokens > window || !startsTurn(conversation[0])) {
+		promptTokens -= utils.EstimateMessageTokens(conversation[0])
+		conversation = conversation[1:]
+		dropped++
+	}
+	if dropped == 0 {
+		return promptTokens, 0
+	}
+
+	// System messages keep their place at the start of the list
+	kept := make([]any, 0, len(system)+len(conversation))
+	kept = append(kept, system...)
+	kept = append(kept, conversation...)
+	body[key] = kept
+	return promptTokens, dropped
+}
+
+func messageRole(message any) string {
+	if m, ok := message.(map[string]any); ok {
+		role, _ := m["role"].(string)
+		return role
+	}
+	return ""
+}
+
+// startsTurn reports whether a conversation can start with message: a user message that does not
+// answer a tool call.
+func startsTurn(message any) bool {
+	if messageRole(message) != "user" {
+		return false
+	}
+	m := message.(map[string]any)
+	parts, _ := m["content"].([]any)
+	if geminiParts, ok := m["parts"].([]any); ok {
+		parts = geminiParts
+	}
+	for _, part := range parts {
+		p, ok := part.(map[string]any)
+		if !ok {
+			continue
+		}
+		if p["type"] == "tool_result" || p["functionResponse"] != nil || p["function_response"] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// setMaxTokens writes the output token limit in the field the request format uses, replacing the
+// one the client sent if any.
+func setMaxTokens(c *gin.Context, group *models.Group, body map[string]any, tokens int) {
+	switch {
+	case group.ChannelType == "gemini":
+		cfg, _ := body["generationConfig"].(map[string]any)
+		if cfg == nil {
+			cfg = make(map[string]any)
+			body["generationConfig"] = cfg
+		}
+		cfg["maxOutputTokens"] = tokens
+		return
+	case group.ChannelType == "ollama":
+		opts, _ := body["options"].(map[string]any)
+		if opts == nil {
+			opts = make(map[string]any)
+			body["options"] = opts
+		}
+		opts["num_predict"] = tokens
+		return
+	}
+
+	for _, key := range []string{"max_completion_tokens", "max_output_tokens", "max_tokens"} {
+		if _, ok := body[key]; ok {
+			body[key] = tokens
+			return
+		}
+	}
+	switch {
+	case strings.HasSuffix(c.Param("path"), "/responses"):
+		body["max_output_tokens"] = tokens
+	case group.ChannelType == "openai":
+		// Reasoning models reject max_tokens
+		body["max_completion_tokens"] = tokens
+	default:
+		body["max_tokens"] = tokens
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// costEstimatePath is the proxy sub-path answered locally with a pre-flight cost estimate.
+const costEstimatePath = "/api/estimate"
+
+// CostEstimate is the pre-flight estimate for a request body.
+type CostEstimate struct {
+	Model               string        `json:"model"`
+	UpstreamModel       string        `json:"upstream_model"`
+	PromptTokens        int           `json:"prompt_tokens"`
+	MaxCompletionTokens *int          `json:"max_completion_tokens"`
+	Pricing             *ModelPricing `json:"pricing"`
+	// EstimatedCost is nil when no pricing is configured for the model.
+	EstimatedCost *CostRange `json:"estimated_cost"`
+}
+
+// ModelPricing holds prices in USD per million tokens.
+type ModelPricing struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// CostRange spans from a response with no output tokens to one that uses the full output budget.
+// Max is nil when neither the request nor the model defines an output limit.
+type CostRange struct {
+	Currency string   `json:"currency"`
+	Min      float64  `json:"min"`
+	Max      *float64 `json:"max"`
+}
+
+// isCostEstimateRequest reports whether the request targets the local estimate endpoint.
+func isCostEstimateRequest(c *gin.Context) bool {
+	return c.Request.Method == http.MethodPost && c.Param("path") == costEstimatePath
+}
+
+// handleCostEstimate estimates prompt tokens and cost for a request body without sending it upstream.
+func (ps *ProxyServer) handleCostEstimate(c *gin.Context, group *models.Group, bodyBytes []byte) {
+	var body map[string]any
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	model, _ := body["model"].(string)
+	if model == "" {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "model is required"))
+		return
+	}
+
+	upstreamModel := model
+	if target, ok := channel.ResolveModelRedirect(group, model); ok {
+		upstreamModel = target
+	} else if group.ModelRedirectStrict && len(group.ModelRedirectMap) > 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "model '"+model+"' is not configured in redirect rules"))
+		return
+	}
+
+	estimate := CostEstimate{
+		Model:               model,
+		UpstreamModel:       upstreamModel,
+		PromptTokens:        utils.EstimatePromptTokens(body),
+		MaxCompletionTokens: requestedMaxTokens(body),
+	}
+
+	if capability := ps.modelInfo.get(group.ID, upstreamModel); capability != nil {
+		if estimate.MaxCompletionTokens == nil {
+			estimate.MaxCompletionTokens = capability.MaxOutputTokens
+		}
+		if pricing := pricingOf(capability); pricing != nil {
+			estimate.Pricing = pricing
+			estimate.EstimatedCost = estimateCostRange(pricing, estimate.PromptTokens, estimate.MaxCompletionTokens)
+		}
+	}
+
+	response.Success(c, estimate)
+}
+
+// requestedMaxTokens reads the output token limit in any of the supported request formats.
+func requestedMaxTokens(body map[string]any) *int {
+	candidates := []any{body["max_completion_tokens"], body["max_tokens"], body["max_output_tokens"]}
+	if cfg, ok := body["generationConfig"].(map[string]any); ok {
+		candidates = append(candidates, cfg["maxOutputTokens"])
+	}
+	if opts, ok := body["options"].(map[string]any); ok {
+		candidates = append(candidates, opts["num_predict"])
+	}
+	for _, candidate := range candidates {
+		if n, ok := candidate.(float64); ok && n > 0 {
+			tokens := int(n)
+			return &tokens
+		}
+	}
+	return nil
+}
+
+func estimateCostRange(pricing *ModelPricing, promptTokens int, maxCompletionTokens *int) *CostRange {
+	costRange := &CostRange{Currency: "USD", Min: roundCost(pricing.cost(promptTokens, 0))}
+	if maxCompletionTokens != nil {
+		maxCost := roundCost(pricing.cost(promptTokens, *maxCompletionTokens))
+		costRange.Max = &maxCost
+	}
+	return costRange
+}
+
+// roundCost rounds to a millionth of a dollar to avoid float noise in responses.
+func roundCost(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
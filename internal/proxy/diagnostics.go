@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Diagnostics headers describe how a request was served, for groups with diagnostics_headers.
+const (
+	diagnosticsGroupHeader    = "X-Gateway-Group"
+	diagnosticsUpstreamHeader = "X-Gateway-Upstream"
+	diagnosticsKeyHeader      = "X-Gateway-Key"
+	diagnosticsAttemptsHeader = "X-Gateway-Attempts"
+	diagnosticsRetriesHeader  = "X-Gateway-Retry-Reasons"
+)
+
+// ctxKeyRetryReasons holds the reasons of the failed attempts of a request.
+const ctxKeyRetryReasons = "diagnostics_retry_reasons"
+
+// maxRetryReasonLength bounds the error text kept per failed attempt.
+const maxRetryReasonLength = 120
+
+// diagnosticsEnabled reports whether the addressed or the serving group exposes diagnostics headers.
+func diagnosticsEnabled(originalGroup, group *models.Group) bool {
+	return group.EffectiveConfig.DiagnosticsHeaders || (originalGroup != nil && originalGroup.EffectiveConfig.DiagnosticsHeaders)
+}
+
+// setDiagnosticsHeaders describes the attempt about to be sent. The upstream is reduced to its
+// scheme and host, since some channels pass the key in the query string.
+func setDiagnosticsHeaders(c *gin.Context, originalGroup, group *models.Group, apiKey *models.APIKey, upstreamURL *url.URL, retryCount int) {
+	if !diagnosticsEnabled(originalGroup, group) {
+		return
+	}
+	groupName := group.Name
+	if originalGroup != nil && originalGroup.ID != group.ID {
+		groupName = originalGroup.Name + "/" + group.Name
+	}
+	c.Header(diagnosticsGroupHeader, groupName)
+	c.Header(diagnosticsUpstreamHeader, upstreamURL.Scheme+"://"+upstreamURL.Host)
+	c.Header(diagnosticsKeyHeader, maskDiagnosticsKey(apiKey.KeyValue))
+	c.Header(diagnosticsAttemptsHeader, strconv.Itoa(retryCount+1))
+}
+
+// recordRetryReason adds a failed attempt to the retry reasons header, e.g.
+// "1:429 rate limit exceeded; 2:502 connection refused".
+func recordRetryReason(c *gin.Context, originalGroup, group *models.Group, statusCode int, err error) {
+	if !diagnosticsEnabled(originalGroup, group) {
+		return
+	}
+	reason := strconv.Itoa(statusCode)
+	if err != nil {
+		reason += " " + utils.TruncateString(strings.Join(strings.Fields(err.Error()), " "), maxRetryReasonLength)
+	}
+	reasons, _ := c.Get(ctxKeyRetryReasons)
+	list, _ := reasons.([]string)
+	list = append(list, fmt.Sprintf("%d:%s", len(list)+1, strings.ReplaceAll(reason, ";", ",")))
+	c.Set(ctxKeyRetryReasons, list)
+	c.Header(diagnosticsRetriesHeader, strings.Join(list, "; "))
+}
+
+// maskDiagnosticsKey masks a key for a response header, hiding short keys completely.
+func maskDiagnosticsKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return utils.MaskAPIKey(key)
+}
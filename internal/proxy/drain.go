@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// drainPollInterval is how often in-flight requests are counted while draining.
+const drainPollInterval = 100 * time.Millisecond
+
+// DrainGuard counts in-flight proxy requests, including streams until their last chunk, and
+// rejects new ones with 503 once the server is draining so that load balancers send them to
+// another instance.
+func (ps *ProxyServer) DrainGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Count before checking, so that a request admitted while draining starts is waited for.
+		ps.inFlight.Add(1)
+		defer ps.inFlight.Add(-1)
+
+		if ps.draining.Load() {
+			c.Header("Connection", "close")
+			response.Error(c, app_errors.ErrServerDraining)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Ready reports whether the instance accepts proxy requests, for load balancer readiness probes.
+// It answers 503 from the moment the server starts draining.
+func (ps *ProxyServer) Ready(c *gin.Context) {
+	if ps.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining", "in_flight": ps.inFlight.Load()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// BeginDrain stops accepting new proxy requests and flips readiness.
+func (ps *ProxyServer) BeginDrain() {
+	ps.draining.Store(true)
+}
+
+// Draining reports whether the server has started draining.
+func (ps *ProxyServer) Draining() bool {
+	return ps.draining.Load()
+}
+
+// InFlight returns the number of proxy requests being handled.
+func (ps *ProxyServer) InFlight() int64 {
+	return ps.inFlight.Load()
+}
+
+// WaitForInFlight waits until every in-flight proxy request has finished or ctx is done, and
+// reports whether they all finished.
+func (ps *ProxyServer) WaitForInFlight(ctx context.Context) bool {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for ps.inFlight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
@@ -0,0 +1,263 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Duplicate request protection modes.
+const (
+	duplicateProtectionOff      = "off"
+	duplicateProtectionCollapse = "collapse"
+	duplicateProtectionThrottle = "throttle"
+)
+
+const (
+	// duplicateHeader marks responses that were shared with, rather than fetched for, a duplicate request.
+	duplicateHeader = "X-Duplicate-Request"
+	// maxCollapsedBodySize bounds the response kept for sharing with duplicates.
+	maxCollapsedBodySize = 8 << 20
+	// duplicateSweepInterval limits how often expired entries are removed.
+	duplicateSweepInterval = 30 * time.Second
+)
+
+// duplicateDecision is what to do with an incoming request.
+type duplicateDecision int
+
+const (
+	duplicateProceed duplicateDecision = iota
+	duplicateCollapsed
+	duplicateThrottled
+)
+
+// duplicateGuard detects bursts of identical requests sent with the same proxy key, such as client
+// retry storms during an incident. Once more than duplicate_request_limit identical requests arrive
+// within the window, further ones either share the response of the latest identical request
+// (collapse) or are rejected with 429 (throttle), so they do not use upstream quota.
+// State is kept per instance.
+type duplicateGuard struct {
+	mu        sync.Mutex
+	entries   map[string]*duplicateEntry
+	lastSweep time.Time
+}
+
+// duplicateEntry tracks the identical requests of one key within the current window.
+type duplicateEntry struct {
+	windowStart time.Time
+	window      time.Duration
+	count       int
+	inflight    *duplicateCall
+	last        *sharedResponse
+}
+
+// duplicateCall is an in-flight request whose response duplicates may share.
+type duplicateCall struct {
+	done     chan struct{}
+	response *sharedResponse
+}
+
+// sharedResponse is a captured response that can be replayed to duplicates.
+type sharedResponse struct {
+	statusCode      int
+	contentType     string
+	contentEncoding string
+	body            []byte
+}
+
+// duplicateTicket is the outcome of duplicateGuard.begin.
+type duplicateTicket struct {
+	decision   duplicateDecision
+	response   *sharedResponse
+	retryAfter time.Duration
+
+	guard   *duplicateGuard
+	key     string
+	call    *duplicateCall
+	capture *capturingWriter
+}
+
+func newDuplicateGuard() *duplicateGuard {
+	return &duplicateGuard{entries: make(map[string]*duplicateEntry)}
+}
+
+// duplicateKey hashes the proxy key together with the request identity used by the response cache.
+func (ps *ProxyServer) duplicateKey(c *gin.Context, group *models.Group, bodyBytes []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.GetString(middleware.ContextKeyProxyKey)))
+	h.Write([]byte{0})
+	h.Write([]byte(ps.responseCache.cacheKey(group, c, bodyBytes)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// begin registers a request and decides whether it proceeds, shares another request's response or
+// is throttled. Proceeding requests must call finish once the response has been written.
+func (g *duplicateGuard) begin(c *gin.Context, key string, cfg duplicateConfig, isStream bool) *duplicateTicket {
+	now := time.Now()
+
+	g.mu.Lock()
+	g.sweepLocked(now)
+
+	entry, ok := g.entries[key]
+	if !ok || now.Sub(entry.windowStart) >= entry.window {
+		if !ok {
+			entry = &duplicateEntry{}
+			g.entries[key] = entry
+		}
+		entry.windowStart = now
+		entry.count = 0
+		entry.last = nil
+	}
+	entry.window = cfg.window
+	entry.count++
+	retryAfter := entry.windowStart.Add(entry.window).Sub(now)
+
+	if entry.count > cfg.limit {
+		if cfg.mode == duplicateProtectionCollapse && !isStream {
+			if entry.last != nil {
+				response := entry.last
+				g.mu.Unlock()
+				return &duplicateTicket{decision: duplicateCollapsed, response: response}
+			}
+			if call := entry.inflight; call != nil {
+				g.mu.Unlock()
+				select {
+				case <-call.done:
+					if call.response != nil {
+						return &duplicateTicket{decision: duplicateCollapsed, response: call.response}
+					}
+				case <-c.Request.Context().Done():
+				}
+				return &duplicateTicket{decision: duplicateThrottled, retryAfter: retryAfter}
+			}
+		}
+		g.mu.Unlock()
+		return &duplicateTicket{decision: duplicateThrottled, retryAfter: retryAfter}
+	}
+
+	ticket := &duplicateTicket{decision: duplicateProceed, guard: g, key: key}
+	if cfg.mode == duplicateProtectionCollapse && !isStream {
+		ticket.call = &duplicateCall{done: make(chan struct{})}
+		entry.inflight = ticket.call
+		ticket.capture = &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = ticket.capture
+	}
+	g.mu.Unlock()
+	return ticket
+}
+
+// finish publishes the captured response of a proceeding request to waiting and later duplicates.
+func (t *duplicateTicket) finish() {
+	if t.call == nil {
+		return
+	}
+
+	var response *sharedResponse
+	if !t.capture.overflow && t.capture.Written() {
+		header := t.capture.Header()
+		response = &sharedResponse{
+			statusCode:      t.capture.Status(),
+			contentType:     header.Get("Content-Type"),
+			contentEncoding: header.Get("Content-Encoding"),
+			body:            t.capture.buf.Bytes(),
+		}
+	}
+
+	t.guard.mu.Lock()
+	t.call.response = response
+	if entry, ok := t.guard.entries[t.key]; ok {
+		if entry.inflight == t.call {
+			entry.inflight = nil
+		}
+		if response != nil {
+			entry.last = response
+		}
+	}
+	t.guard.mu.Unlock()
+	close(t.call.done)
+}
+
+// sweepLocked removes entries whose window has passed. The caller holds the lock.
+func (g *duplicateGuard) sweepLocked(now time.Time) {
+	if now.Sub(g.lastSweep) < duplicateSweepInterval {
+		return
+	}
+	g.lastSweep = now
+	for key, entry := range g.entries {
+		if entry.inflight == nil && now.Sub(entry.windowStart) >= entry.window {
+			delete(g.entries, key)
+		}
+	}
+}
+
+// write replays a shared response to a duplicate request.
+func (r *sharedResponse) write(c *gin.Context) {
+	if r.contentEncoding != "" {
+		c.Header("Content-Encoding", r.contentEncoding)
+	}
+	c.Header(duplicateHeader, "collapsed")
+	c.Data(r.statusCode, r.contentType, r.body)
+}
+
+// retryAfterSeconds formats the Retry-After value of a throttled duplicate.
+func (t *duplicateTicket) retryAfterSeconds() string {
+	return strconv.Itoa(max(1, int(math.Ceil(t.retryAfter.Seconds()))))
+}
+
+// duplicateConfig is the effective duplicate protection of a group.
+type duplicateConfig struct {
+	mode   string
+	window time.Duration
+	limit  int
+}
+
+// duplicateConfigOf returns the group's duplicate protection settings, and false when it is off.
+func duplicateConfigOf(group *models.Group) (duplicateConfig, bool) {
+	cfg := group.EffectiveConfig
+	if cfg.DuplicateRequestProtection == "" || cfg.DuplicateRequestProtection == duplicateProtectionOff {
+		return duplicateConfig{}, false
+	}
+	return duplicateConfig{
+		mode:   cfg.DuplicateRequestProtection,
+		window: time.Duration(cfg.DuplicateRequestWindow) * time.Second,
+		limit:  cfg.DuplicateRequestLimit,
+	}, true
+}
+
+// capturingWriter copies a non-streaming response body so that it can be shared with duplicates.
+type capturingWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *capturingWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(data) > maxCollapsedBodySize {
+		w.overflow = true
+		w.buf.Reset()
+		return
+	}
+	w.buf.Write(data)
+}
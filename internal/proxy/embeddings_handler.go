@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// isOpenAIEmbeddingsPath checks if an upstream path is an OpenAI-format embeddings call
+func isOpenAIEmbeddingsPath(path string) bool {
+	return strings.HasSuffix(path, "/v1/embeddings")
+}
+
+// handleEmbeddingsResponse converts a translated channel's native embeddings response back to
+// the OpenAI format. Non-200 responses are passed through unchanged. The whole body is read, as
+// the translation needs it; embeddings responses are bounded by the size of the request.
+func (ps *ProxyServer) handleEmbeddingsResponse(c *gin.Context, resp *http.Response, translator channel.EmbeddingsTranslator, requestBody []byte) *usageStats {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading embeddings response", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read response"})
+		return nil
+	}
+
+	decompressed, err := utils.DecompressResponse(resp.Header.Get("Content-Encoding"), bodyBytes)
+	if err != nil {
+		decompressed = bodyBytes
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), decompressed)
+		return nil
+	}
+
+	translated, promptTokens, err := translator.TranslateEmbeddingsResponse(requestBody, decompressed)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to translate embeddings response")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to process response"})
+		return nil
+	}
+
+	c.Data(http.StatusOK, "application/json", translated)
+	return &usageStats{PromptTokens: promptTokens, TotalTokens: promptTokens}
+}
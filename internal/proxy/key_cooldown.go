@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+)
+
+// maxKeyCooldown bounds how long a key is passed over after an upstream 429, so that a bogus
+// reset header cannot take a key out of rotation for good.
+const maxKeyCooldown = 24 * time.Hour
+
+// defaultRateLimitCooldown is how long a rate limited key is passed over when the upstream does
+// not say when to retry.
+const defaultRateLimitCooldown = 30 * time.Second
+
+// rateLimitResetHeaders pairs the vendor headers giving when a rate limit resets with the header
+// giving what is left of it. OpenAI-style resets are durations like "6m0s", Anthropic ones are
+// RFC 3339 timestamps.
+var rateLimitResetHeaders = [][2]string{
+	{"x-ratelimit-reset-requests", "x-ratelimit-remaining-requests"},
+	{"x-ratelimit-reset-tokens", "x-ratelimit-remaining-tokens"},
+	{"anthropic-ratelimit-requests-reset", "anthropic-ratelimit-requests-remaining"},
+	{"anthropic-ratelimit-tokens-reset", "anthropic-ratelimit-tokens-remaining"},
+	{"anthropic-ratelimit-input-tokens-reset", "anthropic-ratelimit-input-tokens-remaining"},
+	{"anthropic-ratelimit-output-tokens-reset", "anthropic-ratelimit-output-tokens-remaining"},
+}
+
+// keyCooldownError is returned when every key tried was cooling down after an upstream 429.
+type keyCooldownError struct {
+	retryAfter time.Duration
+}
+
+func (e *keyCooldownError) Error() string {
+	return fmt.Sprintf("all keys tried are cooling down after being rate limited upstream, retry in %s", e.retryAfter.Round(time.Second))
+}
+
+// upstreamCooldown returns how long the key of a 429 response should be passed over, taken from
+// Retry-After (or retry-after-ms) or else from the latest reset of the rate limits the response
+// reports as exhausted. It reports false when the response says nothing about when to retry.
+func upstreamCooldown(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	var cooldown time.Duration
+	if ms, err := strconv.ParseFloat(resp.Header.Get("retry-after-ms"), 64); err == nil {
+		cooldown = time.Duration(ms * float64(time.Millisecond))
+	} else if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			cooldown = time.Duration(seconds * float64(time.Second))
+		} else if at, err := http.ParseTime(value); err == nil {
+			cooldown = at.Sub(now)
+		}
+	} else {
+		for _, header := range rateLimitResetHeaders {
+			if strings.TrimSpace(resp.Header.Get(header[1])) != "0" {
+				continue
+			}
+			if wait, ok := parseRateLimitReset(resp.Header.Get(header[0]), now); ok {
+				cooldown = max(cooldown, wait)
+			}
+		}
+	}
+
+	if cooldown <= 0 {
+		return 0, false
+	}
+	return min(max(cooldown, time.Second), maxKeyCooldown), true
+}
+
+// parseRateLimitReset parses a reset given as a duration, a number of seconds or a timestamp.
+func parseRateLimitReset(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d, true
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at.Sub(now), true
+	}
+	return 0, false
+}
+
+// applyKeyErrorPolicy changes the state of the key a request failed with according to the
+// category of the upstream error. Errors that are not the key's fault leave it untouched.
+func (ps *ProxyServer) applyKeyErrorPolicy(apiKey *models.APIKey, group *models.Group, model string, resp *http.Response, category, parsedError string) {
+	switch category {
+	case app_errors.UpstreamErrorRateLimited, app_errors.UpstreamErrorQuotaExhausted:
+		cooldown, ok := upstreamCooldown(resp, time.Now())
+		if !ok && category == app_errors.UpstreamErrorQuotaExhausted {
+			// 额度耗尽且上游未说明何时恢复：停用该密钥，等待定时验证恢复
+			ps.keyProvider.DisableKey(apiKey, group, category, parsedError)
+			return
+		}
+		if !ok {
+			cooldown = defaultRateLimitCooldown
+		}
+		// 上游限流：让该密钥冷却，而不是计为失败或立即再次使用
+		ps.keyProvider.CooldownKey(apiKey, group, time.Now().Add(cooldown), parsedError)
+		prometheus.RecordKeyCooldown(group.Name, model, "cooled_down")
+	case app_errors.UpstreamErrorAuthInvalid:
+		ps.keyProvider.DisableKey(apiKey, group, category, parsedError)
+	case app_errors.UpstreamErrorUnknown:
+		// 无法判断原因的错误计为密钥失败，达到拉黑阈值后停用
+		ps.keyProvider.UpdateStatus(apiKey, group, models.KeyHealthSourceProxy, false, parsedError)
+	}
+}
@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keyLimitedError is returned when every key tried had reached its rpm_limit, tpm_limit or
+// daily_spend_limit.
+type keyLimitedError struct {
+	retryAfter time.Duration
+}
+
+func (e *keyLimitedError) Error() string {
+	return fmt.Sprintf("all keys tried have reached their rate or spend limit, retry in %s", e.retryAfter.Round(time.Second))
+}
+
+// recordKeyUsage counts the tokens and cost of a successful request against the tpm_limit and
+// daily_spend_limit of its key. Models without pricing count no spend.
+func (ps *ProxyServer) recordKeyUsage(c *gin.Context, group *models.Group, apiKey *models.APIKey, channelHandler channel.ChannelProxy, bodyBytes []byte, usage *usageStats) {
+	if usage == nil || (apiKey.TPMLimit <= 0 && apiKey.DailySpendLimit <= 0) {
+		return
+	}
+	var cost float64
+	if apiKey.DailySpendLimit > 0 && channelHandler != nil && bodyBytes != nil && usage.TotalTokens > 0 {
+		if pricing := pricingOf(ps.modelInfo.get(group.ID, upstreamModel(c, channelHandler, group, bodyBytes))); pricing != nil {
+			cost = pricing.cost(usage.PromptTokens, usage.CompletionTokens)
+		}
+	}
+	ps.keyLimits.RecordUsage(apiKey, usage.TotalTokens, cost)
+}
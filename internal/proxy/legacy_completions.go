@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// legacyCompletionsParams are /v1/completions parameters with no chat-completions equivalent.
+var legacyCompletionsParams = []string{"prompt", "suffix", "echo", "best_of", "logprobs"}
+
+// isLegacyCompletionsPath checks if a client path is the legacy OpenAI text completions endpoint.
+func isLegacyCompletionsPath(path string) bool {
+	return strings.HasSuffix(path, "/v1/completions")
+}
+
+// toChatCompletionsPath points an upstream completions path at chat completions.
+func toChatCompletionsPath(path string) string {
+	return strings.TrimSuffix(path, "/completions") + "/chat/completions"
+}
+
+// translateLegacyCompletionsRequest converts a /v1/completions body into a chat completions body
+// with the prompt as a single user message. Sampling parameters are kept as they are.
+func translateLegacyCompletionsRequest(body []byte) ([]byte, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid completions request: %w", err)
+	}
+
+	prompt, err := legacyPromptText(req["prompt"])
+	if err != nil {
+		return nil, err
+	}
+	for _, param := range legacyCompletionsParams {
+		delete(req, param)
+	}
+	req["messages"] = []map[string]any{{"role": "user", "content": prompt}}
+
+	return json.Marshal(req)
+}
+
+// legacyPromptText returns the prompt as text. Batched and token-array prompts need one chat
+// request per prompt and are rejected.
+func legacyPromptText(prompt any) (string, error) {
+	switch p := prompt.(type) {
+	case string:
+		return p, nil
+	case []any:
+		if len(p) == 1 {
+			if text, ok := p[0].(string); ok {
+				return text, nil
+			}
+		}
+		return "", fmt.Errorf("only a single text prompt can be translated to chat completions")
+	default:
+		return "", fmt.Errorf("prompt is required")
+	}
+}
+
+// applyLegacyCompletionsResponse converts a chat completions response back to the text
+// completions format the client asked for.
+func applyLegacyCompletionsResponse(resp *http.Response, isStream bool, limit int) {
+	if isStream {
+		if resp.Header.Get("Content-Encoding") != "" {
+			return
+		}
+		rewriteStreamEvents(resp, func(data []byte) []byte {
+			out, err := chatToLegacyCompletion(data)
+			if err != nil {
+				return data
+			}
+			return out
+		})
+		return
+	}
+	rewriteResponseBody(resp, limit, chatToLegacyCompletion)
+}
+
+// chatToLegacyCompletion converts a chat completion or chat completion chunk into a
+// text_completion object, keeping id, model, usage and other top-level fields.
+func chatToLegacyCompletion(body []byte) ([]byte, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	if _, ok := payload["choices"]; !ok {
+		return body, nil
+	}
+
+	choices, _ := payload["choices"].([]any)
+	converted := make([]any, 0, len(choices))
+	for i, raw := range choices {
+		choice, _ := raw.(map[string]any)
+		var text string
+		for _, key := range []string{"message", "delta"} {
+			if m, ok := choice[key].(map[string]any); ok {
+				text, _ = m["content"].(string)
+			}
+		}
+		index := any(i)
+		if v, ok := choice["index"]; ok {
+			index = v
+		}
+		converted = append(converted, map[string]any{
+			"text":          text,
+			"index":         index,
+			"logprobs":      nil,
+			"finish_reason": choice["finish_reason"],
+		})
+	}
+
+	payload["object"] = "text_completion"
+	payload["choices"] = converted
+	return json.Marshal(payload)
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"fmt"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkModelAccess returns an error when group's model access policy forbids the requested model,
+// either for the whole group or for the proxy key the request was authenticated with.
+// Requests that do not name a model are not restricted.
+func (ps *ProxyServer) checkModelAccess(c *gin.Context, group *models.Group, bodyBytes []byte) error {
+	policy := group.ModelAccessPolicy
+	if policy == nil {
+		return nil
+	}
+
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil
+	}
+	model := channelHandler.ExtractModel(c, bodyBytes)
+	if model == "" {
+		return nil
+	}
+
+	return modelAccessError(c, group, model)
+}
+
+// modelAccessError checks a model name against group's model access policy.
+func modelAccessError(c *gin.Context, group *models.Group, model string) error {
+	policy := group.ModelAccessPolicy
+	if policy == nil {
+		return nil
+	}
+
+	if !modelAllowed(policy.Allowed, policy.Denied, model) {
+		return fmt.Errorf("model '%s' is not allowed in group '%s'", model, group.Name)
+	}
+
+	proxyKey := c.GetString(middleware.ContextKeyProxyKey)
+	for _, rule := range policy.ProxyKeys {
+		if rule.ProxyKey != proxyKey {
+			continue
+		}
+		if !modelAllowed(rule.Allowed, rule.Denied, model) {
+			return fmt.Errorf("model '%s' is not allowed for this proxy key in group '%s'", model, group.Name)
+		}
+		break
+	}
+	return nil
+}
+
+// modelAllowed applies a denylist and then, when it is not empty, an allowlist to model.
+func modelAllowed(allowed, denied []string, model string) bool {
+	for _, pattern := range denied {
+		if utils.MatchModelPattern(pattern, model) {
+			return false
+		}
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, pattern := range allowed {
+		if utils.MatchModelPattern(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
@@ -26,7 +26,7 @@ func shouldInterceptModelList(path string, method string) bool {
 
 // handleModelListResponse processes the model list response and applies filtering based on redirect rules
 func (ps *ProxyServer) handleModelListResponse(c *gin.Context, resp *http.Response, group *models.Group, channelHandler channel.ChannelProxy) {
-	// Read the upstream response body
+	// Read the upstream response body; model lists are small and filtering needs the whole list
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to read model list response body")
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/sirupsen/logrus"
+)
+
+// modelInfoCacheTTL bounds how long pricing edits take to reach the proxy.
+const modelInfoCacheTTL = time.Minute
+
+// modelInfoCache caches model capability lookups, including misses, so that per-request
+// cost accounting does not hit the database.
+type modelInfoCache struct {
+	modelService *services.ModelService
+	mu           sync.Mutex
+	entries      map[string]modelInfoCacheEntry
+	lists        map[uint]modelListCacheEntry
+}
+
+type modelInfoCacheEntry struct {
+	capability *models.ModelCapabilities
+	expiresAt  time.Time
+}
+
+type modelListCacheEntry struct {
+	models    []models.ModelCapabilities
+	expiresAt time.Time
+}
+
+func newModelInfoCache(modelService *services.ModelService) *modelInfoCache {
+	return &modelInfoCache{
+		modelService: modelService,
+		entries:      make(map[string]modelInfoCacheEntry),
+		lists:        make(map[uint]modelListCacheEntry),
+	}
+}
+
+// get returns the capability record of a group's model, or nil if none is known.
+func (m *modelInfoCache) get(groupID uint, model string) *models.ModelCapabilities {
+	if model == "" {
+		return nil
+	}
+	key := fmt.Sprintf("%d:%s", groupID, model)
+	now := time.Now()
+
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	m.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.capability
+	}
+
+	capability, err := m.modelService.FindGroupModel(groupID, model)
+	if err != nil {
+		capability = nil
+	}
+
+	m.mu.Lock()
+	m.entries[key] = modelInfoCacheEntry{capability: capability, expiresAt: now.Add(modelInfoCacheTTL)}
+	for k, e := range m.entries {
+		if now.After(e.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+	m.mu.Unlock()
+
+	return capability
+}
+
+// list returns all known models of a group. The returned slice must not be modified.
+func (m *modelInfoCache) list(groupID uint) []models.ModelCapabilities {
+	now := time.Now()
+
+	m.mu.Lock()
+	entry, ok := m.lists[groupID]
+	m.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.models
+	}
+
+	groupModels, err := m.modelService.GetModels(groupID)
+	if err != nil {
+		logrus.WithError(err).WithField("groupID", groupID).Warn("Failed to load group models")
+		return nil
+	}
+
+	m.mu.Lock()
+	m.lists[groupID] = modelListCacheEntry{models: groupModels, expiresAt: now.Add(modelInfoCacheTTL)}
+	m.mu.Unlock()
+
+	return groupModels
+}
+
+// pricingOf returns the configured pricing of a model, or nil if it has none.
+func pricingOf(capability *models.ModelCapabilities) *ModelPricing {
+	if capability == nil || (capability.InputPricePerMillion == nil && capability.OutputPricePerMillion == nil) {
+		return nil
+	}
+	pricing := &ModelPricing{}
+	if capability.InputPricePerMillion != nil {
+		pricing.InputPerMillion = *capability.InputPricePerMillion
+	}
+	if capability.OutputPricePerMillion != nil {
+		pricing.OutputPerMillion = *capability.OutputPricePerMillion
+	}
+	return pricing
+}
+
+// cost returns the price of the given usage in USD.
+func (p *ModelPricing) cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.InputPerMillion + float64(completionTokens)*p.OutputPerMillion) / 1e6
+}
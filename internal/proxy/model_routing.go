@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// applyModelRouting returns the group named by the first routing rule of group that matches the
+// requested model, or group itself when no rule matches. Rules of the target group are not followed.
+func (ps *ProxyServer) applyModelRouting(c *gin.Context, group *models.Group, bodyBytes []byte) *models.Group {
+	if len(group.ModelRoutingList) == 0 {
+		return group
+	}
+
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		return group
+	}
+	model := channelHandler.ExtractModel(c, bodyBytes)
+	if model == "" {
+		return group
+	}
+
+	for _, rule := range group.ModelRoutingList {
+		if !utils.MatchModelPattern(rule.Pattern, model) {
+			continue
+		}
+		target, err := ps.groupManager.GetGroupByName(rule.Group)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"group":  group.Name,
+				"target": rule.Group,
+				"model":  model,
+			}).Warn("Model routing target group not found, serving from the original group")
+			return group
+		}
+		logrus.WithFields(logrus.Fields{
+			"group":   group.Name,
+			"target":  target.Name,
+			"model":   model,
+			"pattern": rule.Pattern,
+		}).Debug("Routing request to group by model")
+		return target
+	}
+	return group
+}
+
+// upstreamModel returns the model a request is forwarded with, i.e. the requested model after the
+// group's alias and redirect rules are applied. bodyBytes must be the body before redirection.
+func upstreamModel(c *gin.Context, channelHandler channel.ChannelProxy, group *models.Group, bodyBytes []byte) string {
+	model := channelHandler.ExtractModel(c, bodyBytes)
+	if target, ok := channel.ResolveModelRedirect(group, model); ok {
+		return target
+	}
+	return model
+}
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Moderation policies, configured per group via moderation.
+const (
+	ModerationOff   = "off"
+	ModerationFlag  = "flag"
+	ModerationBlock = "block"
+)
+
+// moderationTimeout bounds a call to the moderation endpoint.
+const moderationTimeout = 10 * time.Second
+
+// ctxKeyModerationFlags holds the comma-separated categories a request was flagged for.
+const ctxKeyModerationFlags = "moderation_flags"
+
+var moderationClient = &http.Client{Timeout: moderationTimeout}
+
+// moderationResponse is the response of OpenAI's moderation endpoint, which other classifiers can
+// mimic to be used in its place.
+type moderationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// applyModeration sends the prompt of a request to the group's moderation endpoint. A flagged
+// prompt is refused under the block policy; under the flag policy it goes ahead and its categories
+// are recorded in the request log. If the classifier cannot be reached the request goes ahead.
+func (ps *ProxyServer) applyModeration(c *gin.Context, group *models.Group, bodyBytes []byte) *app_errors.APIError {
+	cfg := group.EffectiveConfig
+	if cfg.Moderation != ModerationFlag && cfg.Moderation != ModerationBlock {
+		return nil
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(bodyBytes, &body); err != nil || body == nil {
+		return nil
+	}
+	text := utils.PromptText(body)
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	result, err := moderate(c, cfg.ModerationEndpoint, cfg.ModerationAPIKey, cfg.ModerationModel, text)
+	if err != nil {
+		prometheus.RecordModeration(group.Name, "error")
+		logrus.WithError(err).WithField("group", group.Name).Warn("Content moderation failed, letting the request through")
+		return nil
+	}
+
+	categories, scores := flaggedCategories(result)
+	if len(categories) == 0 {
+		prometheus.RecordModeration(group.Name, "passed")
+		return nil
+	}
+
+	fields := logrus.Fields{
+		"group":      group.Name,
+		"categories": categories,
+		"scores":     scores,
+		"policy":     cfg.Moderation,
+	}
+	if cfg.Moderation == ModerationBlock {
+		prometheus.RecordModeration(group.Name, "blocked")
+		logrus.WithFields(fields).Warn("Request blocked by content moderation")
+		return app_errors.NewAPIError(app_errors.ErrContentModerated, fmt.Sprintf(
+			"The request was blocked by content moderation (%s)", strings.Join(categories, ", ")))
+	}
+
+	prometheus.RecordModeration(group.Name, "flagged")
+	logrus.WithFields(fields).Warn("Request flagged by content moderation")
+	c.Set(ctxKeyModerationFlags, utils.TruncateString(strings.Join(categories, ","), 255))
+	return nil
+}
+
+// moderate calls a moderation endpoint in the OpenAI format.
+func moderate(c *gin.Context, endpoint, apiKey, model, text string) (*moderationResponse, error) {
+	payload := map[string]any{"input": text}
+	if model != "" {
+		payload["model"] = model
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := moderationClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation endpoint returned %d: %s", resp.StatusCode, utils.TruncateString(string(respBody), 200))
+	}
+
+	var result moderationResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid moderation response: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("moderation response has no results")
+	}
+	return &result, nil
+}
+
+// flaggedCategories returns the sorted categories a moderation result flagged, with their scores.
+// A result that is flagged without naming a category is reported as "flagged".
+func flaggedCategories(result *moderationResponse) ([]string, map[string]float64) {
+	scores := make(map[string]float64)
+	for _, r := range result.Results {
+		for category, flagged := range r.Categories {
+			if flagged && r.CategoryScores[category] >= scores[category] {
+				scores[category] = r.CategoryScores[category]
+			}
+		}
+		if r.Flagged && len(scores) == 0 {
+			scores["flagged"] = 1
+		}
+	}
+
+	categories := make([]string, 0, len(scores))
+	for category := range scores {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories, scores
+}
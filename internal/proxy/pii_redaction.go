@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PII redaction policies, configured per group via pii_redaction.
+const (
+	PIIRedactionOff    = "off"
+	PIIRedactionMask   = "mask"
+	PIIRedactionReject = "reject"
+)
+
+// applyPIIRedaction looks for emails, phone numbers, card numbers and the group's custom patterns in
+// the prompt fields of a request. Depending on the group's policy, matches are replaced by a
+// placeholder such as [EMAIL] before the request goes upstream, or the request is rejected.
+func (ps *ProxyServer) applyPIIRedaction(group *models.Group, bodyBytes []byte) ([]byte, *app_errors.APIError) {
+	cfg := group.EffectiveConfig
+	if cfg.PIIRedaction != PIIRedactionMask && cfg.PIIRedaction != PIIRedactionReject {
+		return bodyBytes, nil
+	}
+
+	detector, err := utils.NewPIIDetector(cfg.PIIRedactionTypes, cfg.PIIRedactionPatterns)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Invalid PII redaction settings, skipping redaction")
+		return bodyBytes, nil
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(bodyBytes, &body); err != nil || body == nil {
+		return bodyBytes, nil
+	}
+
+	counts := detector.RedactBody(body)
+	if len(counts) == 0 {
+		return bodyBytes, nil
+	}
+
+	action := "masked"
+	if cfg.PIIRedaction == PIIRedactionReject {
+		action = "rejected"
+	}
+	types := make([]string, 0, len(counts))
+	for piiType, count := range counts {
+		prometheus.RecordPIIRedactions(group.Name, piiType, action, count)
+		types = append(types, piiType)
+	}
+	sort.Strings(types)
+	logrus.WithFields(logrus.Fields{
+		"group":  group.Name,
+		"types":  types,
+		"action": action,
+	}).Debug("Personal data found in request")
+
+	if cfg.PIIRedaction == PIIRedactionReject {
+		return nil, app_errors.NewAPIError(app_errors.ErrPIIDetected, fmt.Sprintf(
+			"The request contains personal data (%s) that group '%s' does not allow", strings.Join(types, ", "), group.Name))
+	}
+
+	newBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to redact request body: %v", err))
+	}
+	return newBody, nil
+}
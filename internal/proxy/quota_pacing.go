@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+)
+
+// maxHeldKeySkips bounds how many further keys are tried when the selected key is ahead of its
+// quota pace, at one of its own limits or cooling down after an upstream 429.
+const maxHeldKeySkips = 10
+
+// Reasons for passing over a key.
+const (
+	keyHeldPaced    = "paced"
+	keyHeldLimited  = "limited"
+	keyHeldCooldown = "cooldown"
+)
+
+// quotaPacedError is returned when every key tried was ahead of its quota pace.
+type quotaPacedError struct {
+	retryAfter time.Duration
+}
+
+func (e *quotaPacedError) Error() string {
+	return fmt.Sprintf("all keys tried are ahead of their quota pace, retry in %s", e.retryAfter.Round(time.Second))
+}
+
+// retryAfterSeconds formats the Retry-After value of a paced request.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// skipHeldBackKeys passes over keys cooling down after an upstream 429, keys that have reached
+// their own rpm_limit, tpm_limit or daily_spend_limit, and keys that have used more than their share of the current quota cycle in
+// groups with quota_scope=key and quota_pacing, so that every key lasts until it resets. The
+// request is counted against the rpm_limit of the key it returns.
+func (ps *ProxyServer) skipHeldBackKeys(group *models.Group, model string, apiKey *models.APIKey) (*models.APIKey, error) {
+	reason, retryAfter := ps.keyHeldBack(group, apiKey)
+	for i := 0; reason != "" && i < maxHeldKeySkips; i++ {
+		recordKeyHeldBack(group.Name, model, reason, "key_skipped")
+		next, err := ps.keyProvider.SelectKey(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		nextReason, wait := ps.keyHeldBack(group, next)
+		if nextReason != "" {
+			retryAfter = min(retryAfter, wait)
+		}
+		apiKey, reason = next, nextReason
+	}
+	switch reason {
+	case keyHeldPaced:
+		recordKeyHeldBack(group.Name, model, reason, "rejected")
+		return nil, &quotaPacedError{retryAfter: retryAfter}
+	case keyHeldLimited:
+		recordKeyHeldBack(group.Name, model, reason, "rejected")
+		return nil, &keyLimitedError{retryAfter: retryAfter}
+	case keyHeldCooldown:
+		recordKeyHeldBack(group.Name, model, reason, "rejected")
+		return nil, &keyCooldownError{retryAfter: retryAfter}
+	}
+	ps.keyLimits.CountRequest(apiKey)
+	return apiKey, nil
+}
+
+// keyHeldBack returns why a key must be passed over for now, if at all, and how long until it
+// could be used again.
+func (ps *ProxyServer) keyHeldBack(group *models.Group, apiKey *models.APIKey) (string, time.Duration) {
+	if apiKey.CooldownUntil != nil {
+		if wait := time.Until(*apiKey.CooldownUntil); wait > 0 {
+			return keyHeldCooldown, wait
+		}
+	}
+	if wait, limited := ps.keyLimits.Limited(apiKey); limited {
+		return keyHeldLimited, wait
+	}
+	if wait, paced := ps.quotaService.KeyPaced(group, apiKey.ID); paced {
+		return keyHeldPaced, wait
+	}
+	return "", 0
+}
+
+func recordKeyHeldBack(group, model, reason, action string) {
+	switch reason {
+	case keyHeldLimited:
+		prometheus.RecordKeyLimited(group, action)
+	case keyHeldCooldown:
+		prometheus.RecordKeyCooldown(group, model, action)
+	default:
+		prometheus.RecordQuotaPaced(group, action)
+	}
+}
@@ -0,0 +1,28 @@
+package proxy
+
+import (
+	"net/http"
+
+	"gpt-load/internal/channel"
+)
+
+// applyReasoningMode rewrites the reasoning content of a successful upstream response according
+// to the group's reasoning_content_mode. Streams are rewritten event by event as they are read.
+func applyReasoningMode(resp *http.Response, transformer channel.ReasoningTransformer, mode string, isStream bool, limit int) {
+	if mode == channel.ReasoningModePassthrough {
+		return
+	}
+
+	if isStream {
+		// Compressed streams are passed through untouched, as for usage tracking.
+		if resp.Header.Get("Content-Encoding") != "" {
+			return
+		}
+		rewriteStreamEvents(resp, transformer.NewReasoningStreamTransformer(mode))
+		return
+	}
+
+	rewriteResponseBody(resp, limit, func(body []byte) ([]byte, error) {
+		return transformer.TransformReasoning(body, mode)
+	})
+}
@@ -5,10 +5,13 @@ import (
 	"compress/gzip"
 	"encoding/json"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/middleware"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 	"io"
 	"net/http"
 
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,6 +33,25 @@ func (ps *ProxyServer) applyParamOverrides(bodyBytes []byte, group *models.Group
 	return json.Marshal(requestData)
 }
 
+// applyBodyRules applies the group's body rules to the request body. Bodies that are not JSON
+// objects are passed through unchanged.
+func (ps *ProxyServer) applyBodyRules(c *gin.Context, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	if len(group.BodyRuleList) == 0 || len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
+
+	var requestData map[string]any
+	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
+		logrus.Warnf("failed to unmarshal request body for body rules, passing through: %v", err)
+		return bodyBytes, nil
+	}
+
+	if !utils.ApplyBodyRules(requestData, group.BodyRuleList, utils.NewHeaderVariableContextFromGin(c, group, nil)) {
+		return bodyBytes, nil
+	}
+	return json.Marshal(requestData)
+}
+
 // logUpstreamError provides a centralized way to log errors from upstream interactions.
 func logUpstreamError(context string, err error) {
 	if err == nil {
@@ -61,3 +83,17 @@ func handleGzipCompression(resp *http.Response, bodyBytes []byte) []byte {
 	}
 	return bodyBytes
 }
+
+// proxyKeyMetadata returns the custom metadata configured for the request's proxy key as JSON,
+// or nil when the key has none.
+func (ps *ProxyServer) proxyKeyMetadata(c *gin.Context) []byte {
+	metadata := ps.settingsManager.GetSettings().ProxyKeyMetadataMap[c.GetString(middleware.ContextKeyProxyKey)]
+	if len(metadata) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil
+	}
+	return data
+}
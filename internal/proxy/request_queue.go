@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+)
+
+var (
+	errQueueFull    = errors.New("request queue is full")
+	errQueueTimeout = errors.New("timed out waiting in the request queue")
+)
+
+// requestQueue limits how many requests each group sends upstream at once. Requests over the
+// limit wait in a FIFO queue, so bursts are smoothed out instead of hitting rate-limited upstreams.
+type requestQueue struct {
+	mu     sync.Mutex
+	groups map[uint]*groupQueue
+}
+
+// groupQueue tracks the in-flight and waiting requests of one group.
+type groupQueue struct {
+	name    string
+	limit   int
+	active  int
+	waiters *list.List // of chan struct{}, closed when the waiter is granted a slot
+}
+
+func newRequestQueue() *requestQueue {
+	return &requestQueue{groups: make(map[uint]*groupQueue)}
+}
+
+// acquire waits for a free slot in the group and returns the function releasing it.
+// Groups without max_concurrency are not limited.
+func (q *requestQueue) acquire(ctx context.Context, group *models.Group) (func(), error) {
+	cfg := group.EffectiveConfig
+	if cfg.MaxConcurrency <= 0 {
+		return func() {}, nil
+	}
+
+	q.mu.Lock()
+	gq, ok := q.groups[group.ID]
+	if !ok {
+		gq = &groupQueue{waiters: list.New()}
+		q.groups[group.ID] = gq
+	}
+	gq.name = group.Name
+	// The limit follows config changes; a raised limit lets waiting requests through right away.
+	gq.limit = cfg.MaxConcurrency
+	gq.dispatch()
+
+	if gq.active < gq.limit {
+		gq.active++
+		q.mu.Unlock()
+		return func() { q.release(gq) }, nil
+	}
+	if gq.waiters.Len() >= cfg.MaxQueueDepth {
+		q.mu.Unlock()
+		return nil, errQueueFull
+	}
+
+	ready := make(chan struct{})
+	elem := gq.waiters.PushBack(ready)
+	prometheus.SetGroupQueueDepth(gq.name, gq.waiters.Len())
+	q.mu.Unlock()
+
+	timer := time.NewTimer(time.Duration(cfg.QueueTimeoutSeconds) * time.Second)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ready:
+		return func() { q.release(gq) }, nil
+	case <-timer.C:
+		err = errQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	q.mu.Lock()
+	select {
+	case <-ready:
+		// Granted a slot while giving up; hand it on.
+		gq.active--
+		gq.dispatch()
+	default:
+		gq.waiters.Remove(elem)
+	}
+	prometheus.SetGroupQueueDepth(gq.name, gq.waiters.Len())
+	q.mu.Unlock()
+	return nil, err
+}
+
+// release frees a slot and wakes the next waiting request.
+func (q *requestQueue) release(gq *groupQueue) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	gq.active--
+	gq.dispatch()
+	prometheus.SetGroupQueueDepth(gq.name, gq.waiters.Len())
+}
+
+// dispatch grants free slots to waiting requests in arrival order. The caller holds the lock.
+func (gq *groupQueue) dispatch() {
+	for gq.active < gq.limit && gq.waiters.Len() > 0 {
+		ready := gq.waiters.Remove(gq.waiters.Front()).(chan struct{})
+		gq.active++
+		close(ready)
+	}
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"gpt-load/internal/types"
+)
+
+// responseBufferLimit returns the largest non-stream response body, in bytes, that may be held
+// in memory for rewriting, content-filter inspection, usage capture or caching.
+func responseBufferLimit(cfg types.SystemSettings) int {
+	return cfg.ResponseBufferMaxKB << 10
+}
+
+// bufferResponseBody reads the response body into memory if it fits within limit bytes. When it
+// does not, the bytes already read are put back in front of the rest of the body so the response
+// can still be streamed to the client unchanged, and ok is false.
+func bufferResponseBody(resp *http.Response, limit int) (body []byte, ok bool, err error) {
+	body, err = io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return body, false, err
+	}
+	if len(body) <= limit {
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return body, true, nil
+	}
+
+	resp.Body = &prefixedBody{
+		Reader: io.MultiReader(bytes.NewReader(body), resp.Body),
+		closer: resp.Body,
+	}
+	return nil, false, nil
+}
+
+// prefixedBody replays a prefix that was read ahead before the rest of the upstream body.
+type prefixedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b *prefixedBody) Close() error {
+	return b.closer.Close()
+}
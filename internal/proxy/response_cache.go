@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const responseCachePrefix = "response_cache:"
+
+// cachedResponse is the serialized form of an upstream response kept in the store.
+type cachedResponse struct {
+	StatusCode      int    `json:"status_code"`
+	ContentType     string `json:"content_type"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	Body            []byte `json:"body"`
+}
+
+// responseCache serves identical non-streaming requests from the shared store.
+// The store is Redis when REDIS_DSN is set and in-memory otherwise.
+type responseCache struct {
+	store store.Store
+}
+
+func newResponseCache(s store.Store) *responseCache {
+	return &responseCache{store: s}
+}
+
+// cacheKey hashes the request path and a normalized form of the body.
+// Re-marshalling through a generic value sorts object keys, so field order and
+// whitespace differences do not produce distinct entries.
+func (rc *responseCache) cacheKey(group *models.Group, c *gin.Context, bodyBytes []byte) string {
+	normalized := bodyBytes
+	var parsed any
+	if err := json.Unmarshal(bodyBytes, &parsed); err == nil {
+		if b, err := json.Marshal(parsed); err == nil {
+			normalized = b
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Request.URL.Path))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Request.URL.RawQuery))
+	h.Write([]byte{0})
+	h.Write(normalized)
+	return fmt.Sprintf("%s%d:%s", responseCachePrefix, group.ID, hex.EncodeToString(h.Sum(nil)))
+}
+
+// get returns the cached response for key, or nil on a miss.
+func (rc *responseCache) get(key string) *cachedResponse {
+	data, err := rc.store.Get(key)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logrus.WithError(err).Warn("Failed to read response cache")
+		}
+		return nil
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		logrus.WithError(err).Warn("Failed to decode cached response")
+		return nil
+	}
+	return &cached
+}
+
+// set stores a successful upstream response under key.
+func (rc *responseCache) set(key string, resp *http.Response, body []byte, ttl time.Duration) {
+	data, err := json.Marshal(cachedResponse{
+		StatusCode:      resp.StatusCode,
+		ContentType:     resp.Header.Get("Content-Type"),
+		ContentEncoding: resp.Header.Get("Content-Encoding"),
+		Body:            body,
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to encode response for cache")
+		return
+	}
+	if err := rc.store.Set(key, data, ttl); err != nil {
+		logrus.WithError(err).Warn("Failed to write response cache")
+	}
+}
+
+// write sends a cached response to the client.
+func (cached *cachedResponse) write(c *gin.Context) {
+	if cached.ContentEncoding != "" {
+		c.Header("Content-Encoding", cached.ContentEncoding)
+	}
+	c.Header("X-Cache", "HIT")
+	c.Data(cached.StatusCode, cached.ContentType, cached.Body)
+}
+
+// usage parses token usage from the cached body so that hits are accounted like upstream calls.
+func (cached *cachedResponse) usage() *usageStats {
+	body, err := utils.DecompressResponse(cached.ContentEncoding, cached.Body)
+	if err != nil {
+		return nil
+	}
+	return parseUsage(body)
+}
+
+// isCacheableRequest reports whether a request may be served from or stored in the cache.
+func isCacheableRequest(c *gin.Context, group *models.Group, isStream bool) bool {
+	return group.EffectiveConfig.EnableResponseCache &&
+		!isStream &&
+		c.Request.Method == http.MethodPost &&
+		!shouldInterceptModelList(c.Request.URL.Path, c.Request.Method)
+}
@@ -1,15 +1,29 @@
 package proxy
 
 import (
+	"bytes"
 	"io"
 	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response) {
-	c.Header("Content-Type", "text/event-stream")
+// handleStreamingResponse copies the upstream stream to the client. sentAt is when the
+// upstream request was issued and is the reference point for time-to-first-token. When
+// transcript is not nil, the stream is also fed into it to assemble the full response.
+func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, groupName, model string, sentAt time.Time, transcript *transcriptAssembler) *usageStats {
+	// Ollama's native API streams newline-delimited JSON rather than SSE.
+	contentType := "text/event-stream"
+	if upstreamType := resp.Header.Get("Content-Type"); strings.HasPrefix(upstreamType, "application/x-ndjson") {
+		contentType = upstreamType
+	}
+	c.Header("Content-Type", contentType)
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
@@ -17,32 +31,105 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
-		ps.handleNormalResponse(c, resp)
-		return
+		usage, _ := ps.handleNormalResponse(c, resp, maxUsageCaptureBytes, 0)
+		return usage
+	}
+
+	// Send the headers right away so the client sees the response start before the first chunk.
+	c.Writer.WriteHeaderNow()
+	flusher.Flush()
+
+	// Compressed streams are passed through untouched and not inspected for usage or transcripts.
+	var tracker *streamUsageTracker
+	if resp.Header.Get("Content-Encoding") == "" {
+		tracker = newStreamUsageTracker()
 	}
 
+	var firstChunkAt time.Time
 	buf := make([]byte, 4*1024)
 	for {
 		n, err := resp.Body.Read(buf)
 		if n > 0 {
+			if firstChunkAt.IsZero() {
+				firstChunkAt = time.Now()
+				prometheus.RecordStreamTTFT(groupName, model, firstChunkAt.Sub(sentAt).Seconds(), sampledTraceID(c.Request))
+			}
 			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
 				logUpstreamError("writing stream to client", writeErr)
-				return
+				break
 			}
 			flusher.Flush()
+			if tracker != nil {
+				tracker.Write(buf[:n])
+				if transcript != nil {
+					transcript.Write(buf[:n])
+				}
+			}
 		}
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			logUpstreamError("reading from upstream", err)
-			return
+			break
+		}
+	}
+
+	if tracker == nil {
+		return nil
+	}
+
+	// Result first processes a final event that was not terminated by a newline, which often
+	// carries the usage.
+	usage := tracker.Result()
+	if !firstChunkAt.IsZero() {
+		elapsed := time.Since(firstChunkAt).Seconds()
+		if tokens := tracker.OutputTokens(); tokens > 0 && elapsed > 0 {
+			prometheus.RecordStreamThroughput(groupName, model, float64(tokens)/elapsed)
 		}
 	}
+	return usage
 }
 
-func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response) {
-	if _, err := io.Copy(c.Writer, resp.Body); err != nil {
+// handleNormalResponse streams the upstream body to the client and returns the parsed usage
+// together with the raw body as received. At most limit bytes are kept in memory; the body is
+// nil if it was larger. Bodies of at least compressMin bytes are gzip-compressed for clients
+// that accept it; 0 disables compression.
+func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response, limit, compressMin int) (*usageStats, []byte) {
+	capture := &limitedBuffer{limit: limit}
+	dst, closeDst := compressedResponseWriter(c, resp, compressMin)
+	_, err := io.Copy(dst, io.TeeReader(resp.Body, capture))
+	if closeErr := closeDst(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		logUpstreamError("copying response body", err)
+		return nil, nil
+	}
+	if capture.overflow {
+		return nil, nil
+	}
+
+	raw := capture.Bytes()
+	body, err := utils.DecompressResponse(resp.Header.Get("Content-Encoding"), raw)
+	if err != nil {
+		return nil, raw
+	}
+	return parseUsage(body), raw
+}
+
+// limitedBuffer keeps up to limit bytes and silently drops the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.overflow || b.Len()+len(p) > b.limit {
+		b.overflow = true
+		b.Reset()
+		return len(p), nil
 	}
+	return b.Buffer.Write(p)
 }
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"gpt-load/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gatewayName identifies this proxy in injected response metadata.
+const gatewayName = "gpt-load"
+
+// responseMetadata is the attribution block injected into non-streaming JSON responses.
+type responseMetadata struct {
+	RequestID string `json:"request_id"`
+	Model     string `json:"model,omitempty"`
+	Group     string `json:"group"`
+	Gateway   string `json:"gateway"`
+	Version   string `json:"version"`
+	Cached    bool   `json:"cached,omitempty"`
+}
+
+// newResponseMetadata describes the current request. requestModel is used when the response
+// body does not name the model that served it.
+func newResponseMetadata(c *gin.Context, group *models.Group, requestModel string) responseMetadata {
+	return responseMetadata{
+		RequestID: c.GetString(ctxKeyRequestID),
+		Model:     requestModel,
+		Group:     group.Name,
+		Gateway:   gatewayName,
+		Version:   version.Version,
+	}
+}
+
+// applyResponseMetadata injects meta under key into a successful JSON response body.
+func applyResponseMetadata(resp *http.Response, key string, meta responseMetadata, limit int) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return
+	}
+	rewriteResponseBody(resp, limit, func(body []byte) ([]byte, error) {
+		return injectResponseMetadata(body, key, meta)
+	})
+}
+
+// withResponseMetadata returns a copy of a cached response carrying metadata for the current request,
+// replacing the block stored with the original response.
+func (cached *cachedResponse) withResponseMetadata(key string, meta responseMetadata) *cachedResponse {
+	if !strings.Contains(cached.ContentType, "json") {
+		return cached
+	}
+	body, err := utils.DecompressResponse(cached.ContentEncoding, cached.Body)
+	if err != nil {
+		return cached
+	}
+	meta.Cached = true
+	injected, err := injectResponseMetadata(body, key, meta)
+	if err != nil {
+		return cached
+	}
+	return &cachedResponse{StatusCode: cached.StatusCode, ContentType: cached.ContentType, Body: injected}
+}
+
+// injectResponseMetadata sets key on a top-level JSON object, keeping the order of the other fields.
+// The model named in the body, if any, takes precedence over meta.Model.
+func injectResponseMetadata(body []byte, key string, meta responseMetadata) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("response body is not a JSON object")
+	}
+
+	type field struct {
+		name  string
+		value json.RawMessage
+	}
+	var fields []field
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if name == key {
+			continue
+		}
+		if name == "model" {
+			var model string
+			if json.Unmarshal(value, &model) == nil && model != "" {
+				meta.Model = model
+			}
+		}
+		fields = append(fields, field{name: name, value: value})
+	}
+
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	fields = append(fields, field{name: key, value: metaJSON})
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(f.value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
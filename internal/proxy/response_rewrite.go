@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rewriteResponseBody buffers and decompresses a non-stream response body and replaces it with
+// the output of transform. Bodies larger than limit bytes are streamed through unchanged, and on
+// any error the original body is kept.
+func rewriteResponseBody(resp *http.Response, limit int, transform func(body []byte) ([]byte, error)) {
+	raw, ok, err := bufferResponseBody(resp, limit)
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return
+	}
+	if !ok {
+		logrus.Debugf("Response body exceeds the %d byte buffer limit, passing it through without rewriting", limit)
+		return
+	}
+
+	body, err := utils.DecompressResponse(resp.Header.Get("Content-Encoding"), raw)
+	if err != nil {
+		return
+	}
+	transformed, err := transform(body)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to rewrite response body, passing it through")
+		return
+	}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(transformed)))
+	resp.Body = io.NopCloser(bytes.NewReader(transformed))
+}
+
+// rewriteStreamEvents wraps a server-sent event stream so that each event's data payload is
+// passed through transform as it is read. A nil result drops the data line.
+func rewriteStreamEvents(resp *http.Response, transform func(data []byte) []byte) {
+	resp.Body = &sseTransformReader{
+		src:       bufio.NewReader(resp.Body),
+		closer:    resp.Body,
+		transform: transform,
+	}
+}
+
+// sseTransformReader rewrites the payload of each "data:" line of a server-sent event stream.
+type sseTransformReader struct {
+	src       *bufio.Reader
+	closer    io.Closer
+	transform func(data []byte) []byte
+	pending   []byte
+}
+
+func (r *sseTransformReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		line, err := r.src.ReadBytes('\n')
+		if len(line) > 0 {
+			r.pending = r.rewriteLine(line)
+		}
+		if err != nil {
+			if len(r.pending) == 0 {
+				return 0, err
+			}
+			break
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *sseTransformReader) rewriteLine(line []byte) []byte {
+	content := bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(content, []byte("data:")) {
+		return line
+	}
+	data := bytes.TrimSpace(content[len("data:"):])
+	if len(data) == 0 || bytes.Equal(data, []byte("[DONE]")) {
+		return line
+	}
+
+	transformed := r.transform(data)
+	if transformed == nil {
+		return nil
+	}
+	out := make([]byte, 0, len(line)+16)
+	out = append(out, "data: "...)
+	out = append(out, transformed...)
+	return append(out, line[len(content):]...)
+}
+
+func (r *sseTransformReader) Close() error {
+	return r.closer.Close()
+}
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+// recordRoutingOutcome reports the final result of a request that an aggregate group routed to a
+// sub-group, so that bandit routing can compare sub-groups by success rate, latency and cost.
+func (ps *ProxyServer) recordRoutingOutcome(entry *models.RequestLog, usage *usageStats) {
+	var cost *float64
+	if usage != nil && usage.TotalTokens > 0 {
+		if pricing := pricingOf(ps.modelInfo.get(entry.GroupID, entry.Model)); pricing != nil {
+			c := pricing.cost(usage.PromptTokens, usage.CompletionTokens)
+			cost = &c
+		}
+	}
+
+	ps.subGroupManager.RecordOutcome(
+		entry.ParentGroupID,
+		entry.GroupID,
+		entry.IsSuccess,
+		time.Duration(entry.Duration)*time.Millisecond,
+		cost,
+	)
+}
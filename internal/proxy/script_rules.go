@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Script rule actions.
+const (
+	scriptActionReject = "reject"
+	scriptActionRoute  = "route"
+	scriptActionModel  = "model"
+)
+
+// applyScriptRules evaluates the group's script rules in order. A matching model rule rewrites the
+// requested model and evaluation goes on with the new model; a matching reject or route rule stops
+// it, rejecting the request or returning the group to send it to. Rules that fail or run out of time
+// are skipped. A nil group means the request stays in group.
+func (ps *ProxyServer) applyScriptRules(c *gin.Context, group *models.Group, bodyBytes []byte) (*models.Group, []byte, *app_errors.APIError) {
+	if len(group.ScriptRuleList) == 0 {
+		return nil, bodyBytes, nil
+	}
+
+	var body map[string]any
+	_ = json.Unmarshal(bodyBytes, &body)
+	env := newScriptEnv(c, group, body)
+	if channelHandler, err := ps.channelFactory.GetChannel(group); err == nil {
+		env.Model = channelHandler.ExtractModel(c, bodyBytes)
+	}
+
+	for _, rule := range group.ScriptRuleList {
+		matched, err := utils.EvalScriptRule(rule.When, env)
+		if err != nil {
+			prometheus.RecordScriptRule(group.Name, "error")
+			logrus.WithFields(logrus.Fields{
+				"group": group.Name,
+				"rule":  rule.When,
+			}).WithError(err).Warn("Script rule failed, skipping it")
+			continue
+		}
+		if !matched {
+			continue
+		}
+		prometheus.RecordScriptRule(group.Name, rule.Action)
+
+		switch rule.Action {
+		case scriptActionReject:
+			message := rule.Value
+			if message == "" {
+				message = "Request rejected by a script rule of this group"
+			}
+			return nil, nil, app_errors.NewAPIError(app_errors.ErrForbidden, message)
+		case scriptActionRoute:
+			target, err := ps.groupManager.GetGroupByName(rule.Value)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group":  group.Name,
+					"target": rule.Value,
+				}).Warn("Script rule target group not found, serving from the original group")
+				return nil, bodyBytes, nil
+			}
+			logrus.WithFields(logrus.Fields{
+				"group":  group.Name,
+				"target": target.Name,
+				"rule":   rule.When,
+			}).Debug("Routing request to group by script rule")
+			return target, bodyBytes, nil
+		case scriptActionModel:
+			if _, ok := body["model"].(string); !ok {
+				logrus.WithField("group", group.Name).Debug("Script rule cannot rewrite a request without a model field")
+				continue
+			}
+			body["model"] = rule.Value
+			rewritten, err := json.Marshal(body)
+			if err != nil {
+				return nil, nil, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to rewrite request model: %v", err))
+			}
+			bodyBytes = rewritten
+			env.Model = rule.Value
+		}
+	}
+	return nil, bodyBytes, nil
+}
+
+// newScriptEnv collects what script rules can inspect about a request.
+func newScriptEnv(c *gin.Context, group *models.Group, body map[string]any) *utils.ScriptEnv {
+	headers := make(map[string]string, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+	env := &utils.ScriptEnv{
+		ProxyKey: c.GetString(middleware.ContextKeyProxyKey),
+		Headers:  headers,
+		Body:     body,
+		Path:     c.Param("path"),
+		Method:   c.Request.Method,
+		ClientIP: c.ClientIP(),
+		Group:    group.Name,
+	}
+	if body != nil {
+		env.PromptTokens = utils.EstimatePromptTokens(body)
+	}
+	return env
+}
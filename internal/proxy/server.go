@@ -9,19 +9,29 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"gpt-load/internal/accesslog"
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/httpclient"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
+	"gpt-load/internal/plugin"
+	"gpt-load/internal/prometheus"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
+	"gpt-load/internal/store"
 	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -33,9 +43,36 @@ type ProxyServer struct {
 	settingsManager   *config.SystemSettingsManager
 	channelFactory    *channel.Factory
 	requestLogService *services.RequestLogService
+	transcriptService *services.StreamTranscriptService
+	quotaService      *services.QuotaService
+	keyLimits         *services.KeyLimitService
+	modelInfo         *modelInfoCache
 	encryptionSvc     encryption.Service
+	responseCache     *responseCache
+	requestQueue      *requestQueue
+	duplicateGuard    *duplicateGuard
+	store             store.Store
+	accessLogger      *accesslog.Logger
+	plugins           *plugin.Manager
+	// streamOptionsRejected holds the upstreams that answered 400 to stream_options.
+	streamOptionsRejected sync.Map
+	// draining is set on shutdown; inFlight counts the proxy requests being handled.
+	draining atomic.Bool
+	inFlight atomic.Int64
 }
 
+// ctxKeyCacheHit marks a request that was answered from the response cache.
+const ctxKeyCacheHit = "response_cache_hit"
+
+// ctxKeyRequestID holds the ID shared by all log entries (retries and final) of one proxied request.
+const ctxKeyRequestID = "proxy_request_id"
+
+// ctxKeyFinalError holds the error of the final attempt, passed to the on-error plugin hooks.
+const ctxKeyFinalError = "proxy_final_error"
+
+// requestIDHeader returns the request ID to clients so it can be traced later.
+const requestIDHeader = "X-Request-Id"
+
 // NewProxyServer creates a new proxy server
 func NewProxyServer(
 	keyProvider *keypool.KeyProvider,
@@ -44,7 +81,14 @@ func NewProxyServer(
 	settingsManager *config.SystemSettingsManager,
 	channelFactory *channel.Factory,
 	requestLogService *services.RequestLogService,
+	transcriptService *services.StreamTranscriptService,
+	quotaService *services.QuotaService,
+	keyLimits *services.KeyLimitService,
+	modelService *services.ModelService,
 	encryptionSvc encryption.Service,
+	store store.Store,
+	accessLogger *accesslog.Logger,
+	plugins *plugin.Manager,
 ) (*ProxyServer, error) {
 	return &ProxyServer{
 		keyProvider:       keyProvider,
@@ -53,7 +97,17 @@ func NewProxyServer(
 		settingsManager:   settingsManager,
 		channelFactory:    channelFactory,
 		requestLogService: requestLogService,
+		transcriptService: transcriptService,
+		quotaService:      quotaService,
+		keyLimits:         keyLimits,
+		modelInfo:         newModelInfoCache(modelService),
 		encryptionSvc:     encryptionSvc,
+		responseCache:     newResponseCache(store),
+		requestQueue:      newRequestQueue(),
+		duplicateGuard:    newDuplicateGuard(),
+		store:             store,
+		accessLogger:      accessLogger,
+		plugins:           plugins,
 	}, nil
 }
 
@@ -62,30 +116,118 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 	startTime := time.Now()
 	groupName := c.Param("group_name")
 
+	requestID := uuid.NewString()
+	c.Set(ctxKeyRequestID, requestID)
+	c.Header(requestIDHeader, requestID)
+
 	originalGroup, err := ps.groupManager.GetGroupByName(groupName)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
 	}
 
-	// Select sub-group if this is an aggregate group
-	subGroupName, err := ps.subGroupManager.SelectSubGroup(originalGroup)
+	bodyBytes, err := readRequestBody(c, int64(originalGroup.EffectiveConfig.MaxRequestBodyKB)<<10)
+	var tooLarge *requestTooLargeError
+	if errors.As(err, &tooLarge) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrRequestTooLarge, fmt.Sprintf("The request body exceeds the %d KB limit", tooLarge.Limit>>10)))
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to read request body: %v", err)
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
+		return
+	}
+
+	// Let plugins inspect or rewrite the request before it is routed
+	if ps.plugins.Enabled() {
+		pluginCtx := ps.plugins.Context(c)
+		defer func() { ps.plugins.Finish(pluginCtx, finalErrorOf(c)) }()
+		pluginCtx.Group = originalGroup
+		pluginCtx.Body = bodyBytes
+		if apiErr := ps.plugins.PreRoute(pluginCtx); apiErr != nil {
+			response.Error(c, apiErr)
+			return
+		}
+		bodyBytes = pluginCtx.Body
+	}
+
+	// Resolve a requested capability profile to a concrete model and group
+	entryGroup := originalGroup
+	capabilityGroup, bodyBytes, apiErr := ps.applyCapabilityRouting(c, entryGroup, bodyBytes)
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+
+	// Let the group's script rules reject the request, rewrite its model or pick another group
+	var scriptGroup *models.Group
+	if capabilityGroup == nil {
+		scriptGroup, bodyBytes, apiErr = ps.applyScriptRules(c, entryGroup, bodyBytes)
+		if apiErr != nil {
+			response.Error(c, apiErr)
+			return
+		}
+	}
+
+	// Reject disallowed models before routing so the entry group's restrictions cannot be bypassed
+	if err := ps.checkModelAccess(c, entryGroup, bodyBytes); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrForbidden, err.Error()))
+		return
+	}
+
+	// Hand the request to another group when one of this group's model routing rules matches
+	if capabilityGroup != nil {
+		originalGroup = capabilityGroup
+	} else if scriptGroup != nil {
+		originalGroup = scriptGroup
+	} else {
+		originalGroup = ps.applyModelRouting(c, originalGroup, bodyBytes)
+	}
+
+	// Send the configured share of clients to the canary group
+	originalGroup = ps.applyCanarySplit(c, originalGroup)
+
+	// Divert to the fallback group outside the traffic schedule
+	originalGroup, err = ps.applyTrafficSchedule(originalGroup)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"aggregate_group": originalGroup.Name,
-			"error":           err,
-		}).Error("Failed to select sub-group from aggregate")
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, "No available sub-groups"))
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrOutsideSchedule, err.Error()))
 		return
 	}
 
+	// Pin conversations to the sub-group, key and upstream that served their earlier turns
+	if session := ps.resolveStickySession(c, originalGroup, bodyBytes); session != nil {
+		c.Set(ctxKeyStickySession, session)
+	}
+
+	// Select sub-group if this is an aggregate group, skipping sub-groups outside their schedule
 	group := originalGroup
-	if subGroupName != "" {
+	for attempt := 0; attempt <= len(originalGroup.SubGroups); attempt++ {
+		subGroupName, err := ps.selectSubGroup(c, originalGroup, attempt)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"aggregate_group": originalGroup.Name,
+				"error":           err,
+			}).Error("Failed to select sub-group from aggregate")
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, "No available sub-groups"))
+			return
+		}
+		if subGroupName == "" {
+			break
+		}
+
 		group, err = ps.groupManager.GetGroupByName(subGroupName)
 		if err != nil {
 			response.Error(c, app_errors.ParseDBError(err))
 			return
 		}
+		if isWithinTrafficSchedule(group, time.Now()) {
+			break
+		}
+		if attempt == len(originalGroup.SubGroups) {
+			prometheus.RecordScheduleDiverted(originalGroup.Name, "rejected")
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrOutsideSchedule, "All sub-groups are outside their traffic schedules"))
+			return
+		}
 	}
 
 	channelHandler, err := ps.channelFactory.GetChannel(group)
@@ -94,13 +236,16 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
-	bodyBytes, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		logrus.Errorf("Failed to read request body: %v", err)
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
-		return
+	// The routed and selected groups enforce their own restrictions too
+	for i, g := range []*models.Group{originalGroup, group} {
+		if g == entryGroup || (i == 1 && g == originalGroup) {
+			continue
+		}
+		if err := ps.checkModelAccess(c, g, bodyBytes); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrForbidden, err.Error()))
+			return
+		}
 	}
-	c.Request.Body.Close()
 
 	finalBodyBytes, err := ps.applyParamOverrides(bodyBytes, group)
 	if err != nil {
@@ -108,9 +253,99 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
+	finalBodyBytes, err = ps.applyBodyRules(c, finalBodyBytes, group)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply body rules: %v", err)))
+		return
+	}
+
+	// Mask or refuse personal data before the prompt leaves the proxy
+	finalBodyBytes, apiErr = ps.applyPIIRedaction(group, finalBodyBytes)
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+
+	if isCostEstimateRequest(c) {
+		ps.handleCostEstimate(c, group, finalBodyBytes)
+		return
+	}
+
+	// Fit the prompt and output limit into the context window of the model
+	finalBodyBytes, apiErr = ps.applyContextWindow(c, channelHandler, group, finalBodyBytes)
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+
+	// Run the prompt through the group's moderation endpoint
+	if apiErr := ps.applyModeration(c, group, finalBodyBytes); apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+
 	isStream := channelHandler.IsStreamRequest(c, bodyBytes)
 
-	ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, finalBodyBytes, isStream, startTime, 0)
+	var cacheKey string
+	if isCacheableRequest(c, group, isStream) {
+		cacheKey = ps.responseCache.cacheKey(group, c, finalBodyBytes)
+		model := upstreamModel(c, channelHandler, group, finalBodyBytes)
+		if cached := ps.responseCache.get(cacheKey); cached != nil {
+			prometheus.RecordResponseCache(group.Name, model, "hit")
+			c.Set(ctxKeyCacheHit, true)
+			if group.EffectiveConfig.ResponseMetadataEnabled {
+				meta := newResponseMetadata(c, group, model)
+				cached = cached.withResponseMetadata(group.EffectiveConfig.ResponseMetadataKey, meta)
+			}
+			cached.write(c)
+			ps.logRequest(c, originalGroup, group, nil, startTime, cached.StatusCode, nil, isStream, "", channelHandler, finalBodyBytes, models.RequestTypeFinal, cached.usage())
+			return
+		}
+		prometheus.RecordResponseCache(group.Name, model, "miss")
+	}
+
+	// Collapse or throttle bursts of identical requests from the same key
+	if cfg, ok := duplicateConfigOf(group); ok {
+		ticket := ps.duplicateGuard.begin(c, ps.duplicateKey(c, group, finalBodyBytes), cfg, isStream)
+		switch ticket.decision {
+		case duplicateCollapsed:
+			prometheus.RecordDuplicateRequest(group.Name, "collapsed")
+			ticket.response.write(c)
+			ps.logRequest(c, originalGroup, group, nil, startTime, ticket.response.statusCode, nil, isStream, "", channelHandler, finalBodyBytes, models.RequestTypeFinal, nil)
+			return
+		case duplicateThrottled:
+			prometheus.RecordDuplicateRequest(group.Name, "throttled")
+			c.Header("Retry-After", ticket.retryAfterSeconds())
+			response.Error(c, app_errors.ErrDuplicateRequest)
+			return
+		}
+		defer ticket.finish()
+	}
+
+	// Hold back requests that would use up the group's quota before its cycle resets
+	if retryAfter, paced := ps.quotaService.GroupPaced(group); paced {
+		prometheus.RecordQuotaPaced(group.Name, "rejected")
+		c.Header("Retry-After", retryAfterSeconds(retryAfter))
+		response.Error(c, app_errors.ErrQuotaPaced)
+		return
+	}
+
+	// Wait for a free slot when the group limits its concurrent upstream requests
+	release, err := ps.requestQueue.acquire(c.Request.Context(), group)
+	if err != nil {
+		switch {
+		case errors.Is(err, errQueueFull):
+			response.Error(c, app_errors.ErrQueueFull)
+		case errors.Is(err, errQueueTimeout):
+			response.Error(c, app_errors.ErrQueueTimeout)
+		default:
+			logrus.Debugf("Client left while queued for group %s: %v", group.Name, err)
+		}
+		return
+	}
+	defer release()
+
+	ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, finalBodyBytes, isStream, startTime, 0, cacheKey)
 }
 
 // executeRequestWithRetry is the core recursive function for handling requests and retries.
@@ -123,18 +358,44 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	isStream bool,
 	startTime time.Time,
 	retryCount int,
+	cacheKey string,
 ) {
 	cfg := group.EffectiveConfig
+	model := upstreamModel(c, channelHandler, group, bodyBytes)
 
-	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	apiKey, err := ps.selectKey(c, group, retryCount)
+	if err == nil {
+		apiKey, err = ps.skipHeldBackKeys(group, model, apiKey)
+	}
+	var pacedErr *quotaPacedError
+	if errors.As(err, &pacedErr) {
+		c.Header("Retry-After", retryAfterSeconds(pacedErr.retryAfter))
+		response.Error(c, app_errors.ErrQuotaPaced)
+		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusTooManyRequests, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+		return
+	}
+	var limitedErr *keyLimitedError
+	if errors.As(err, &limitedErr) {
+		c.Header("Retry-After", retryAfterSeconds(limitedErr.retryAfter))
+		response.Error(c, app_errors.ErrKeyLimitReached)
+		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusTooManyRequests, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+		return
+	}
+	var cooldownErr *keyCooldownError
+	if errors.As(err, &cooldownErr) {
+		c.Header("Retry-After", retryAfterSeconds(cooldownErr.retryAfter))
+		response.Error(c, app_errors.ErrKeysCoolingDown)
+		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusTooManyRequests, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+		return
+	}
 	if err != nil {
 		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
-		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal, nil)
 		return
 	}
 
-	upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
+	upstreamURL, err := ps.buildUpstreamURL(c, channelHandler, group, model, retryCount)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to build upstream URL: %v", err)))
 		return
@@ -169,23 +430,96 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	finalBodyBytes, err := channelHandler.ApplyModelRedirect(req, bodyBytes, group)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
-		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
 		return
 	}
 
-	// Update request body if it was modified by redirection
+	// Enforce group-level provider safety settings
+	if applier, ok := channelHandler.(channel.SafetySettingsApplier); ok {
+		finalBodyBytes, err = applier.ApplySafetySettings(req, finalBodyBytes, group)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+			return
+		}
+	}
+
+	// Have OpenAI-format streams report usage, or strip stream_options where it is rejected
+	var injectedUsage, sentStreamOptions bool
+	finalBodyBytes, injectedUsage, sentStreamOptions = ps.applyStreamUsageOptions(channelHandler, group, req.URL, finalBodyBytes, isStream)
+
+	// Update request body if it was modified by redirection, safety settings or stream options
 	if !bytes.Equal(finalBodyBytes, bodyBytes) {
 		req.Body = io.NopCloser(bytes.NewReader(finalBodyBytes))
 		req.ContentLength = int64(len(finalBodyBytes))
 	}
 
-	channelHandler.ModifyRequest(req, apiKey, group)
+	// Translate OpenAI-format embeddings for channels with a different native API
+	var embeddingsTranslator channel.EmbeddingsTranslator
+	if translator, ok := channelHandler.(channel.EmbeddingsTranslator); ok && isOpenAIEmbeddingsPath(req.URL.Path) {
+		translatedURL, translatedBody, err := translator.TranslateEmbeddingsRequest(req.URL.String(), finalBodyBytes)
+		if err == nil {
+			req.URL, err = url.Parse(translatedURL)
+		}
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+			return
+		}
+		req.Host = req.URL.Host
+		req.Body = io.NopCloser(bytes.NewReader(translatedBody))
+		req.ContentLength = int64(len(translatedBody))
+		embeddingsTranslator = translator
+	}
+
+	// Serve the legacy completions API through chat completions when the group asks for it
+	legacyCompletions := cfg.TranslateLegacyCompletions && isLegacyCompletionsPath(c.Request.URL.Path)
+	if legacyCompletions {
+		translatedBody, err := translateLegacyCompletionsRequest(finalBodyBytes)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+			return
+		}
+		req.URL.Path = toChatCompletionsPath(req.URL.Path)
+		req.URL.RawPath = ""
+		req.Body = io.NopCloser(bytes.NewReader(translatedBody))
+		req.ContentLength = int64(len(translatedBody))
+	}
+
+	// Per-upstream credential override replaces the pooled key for this upstream only
+	upstream := channelHandler.MatchUpstream(req.URL)
+	requestKey := apiKey
+	if upstream != nil && upstream.APIKey != "" {
+		overrideKey := *apiKey
+		overrideKey.KeyValue = upstream.APIKey
+		requestKey = &overrideKey
+	}
+
+	channelHandler.ModifyRequest(req, requestKey, group)
+	setDiagnosticsHeaders(c, originalGroup, group, requestKey, req.URL, retryCount)
 
 	// Apply custom header rules
 	if len(group.HeaderRuleList) > 0 {
-		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
+		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, requestKey)
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
+	if upstream != nil && len(upstream.HeaderRules) > 0 {
+		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, requestKey)
+		utils.ApplyHeaderRules(req, upstream.HeaderRules, headerCtx)
+	}
+
+	if ps.plugins.Enabled() {
+		pluginCtx := ps.plugins.Context(c)
+		pluginCtx.Group = group
+		pluginCtx.Upstream = req
+		pluginCtx.Attempt = retryCount
+		if apiErr := ps.plugins.PreUpstream(pluginCtx); apiErr != nil {
+			response.Error(c, apiErr)
+			ps.logRequest(c, originalGroup, group, requestKey, startTime, apiErr.HTTPStatus, apiErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+			return
+		}
+	}
 
 	var client *http.Client
 	if isStream {
@@ -195,32 +529,52 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		client = channelHandler.GetHTTPClient()
 	}
 
+	sentAt := time.Now()
 	resp, err := client.Do(req)
 	if resp != nil {
+		// Streams have no overall timeout; a stream that stops sending data is cut off instead.
+		if isStream {
+			resp.Body = httpclient.WithIdleTimeout(resp.Body, time.Duration(cfg.StreamIdleTimeout)*time.Second)
+		}
 		defer resp.Body.Close()
 	}
 
+	// Feed latency-aware upstream selection; client cancellations and 4xx say nothing about the upstream
+	switch {
+	case err != nil && !app_errors.IsIgnorableError(err):
+		channelHandler.RecordUpstreamResult(req.URL, model, 0, false)
+	case err == nil && resp.StatusCode >= http.StatusInternalServerError:
+		channelHandler.RecordUpstreamResult(req.URL, model, 0, false)
+	case err == nil && resp.StatusCode < http.StatusBadRequest:
+		channelHandler.RecordUpstreamResult(req.URL, model, time.Since(sentAt), true)
+	}
+
 	// Unified error handling for retries. Exclude 404 from being a retryable error.
 	if err != nil || (resp != nil && resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
 		if err != nil && app_errors.IsIgnorableError(err) {
 			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
-			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			ps.logRequest(c, originalGroup, group, requestKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
 			return
 		}
 
 		var statusCode int
 		var errorMessage string
 		var parsedError string
+		var errorBody []byte
 
 		if err != nil {
 			statusCode = 500
+			if app_errors.IsUpstreamUnreachableError(err) {
+				statusCode = http.StatusBadGateway
+			}
 			errorMessage = err.Error()
 			parsedError = errorMessage
 			logrus.Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
 		} else {
 			// HTTP-level error (status >= 400)
 			statusCode = resp.StatusCode
-			errorBody, readErr := io.ReadAll(resp.Body)
+			var readErr error
+			errorBody, readErr = io.ReadAll(io.LimitReader(resp.Body, int64(responseBufferLimit(cfg))))
 			if readErr != nil {
 				logrus.Errorf("Failed to read error body: %v", readErr)
 				errorBody = []byte("Failed to read error body")
@@ -232,17 +586,38 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
 		}
 
-		// 使用解析后的错误信息更新密钥状态
-		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
+		// The upstream rejects stream_options: remember it and repeat the attempt without the field.
+		if sentStreamOptions && rejectsStreamOptions(statusCode, errorBody) {
+			ps.rememberStreamOptionsRejected(group, req.URL)
+			ps.logRequest(c, originalGroup, group, requestKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeRetry, nil)
+			ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount, cacheKey)
+			return
+		}
+
+		category := app_errors.ClassifyUpstreamError(group.ChannelType, statusCode, err, errorBody)
+		prometheus.RecordUpstreamError(group.Name, model, category)
+
+		// 判断是否为最后一次尝试；请求本身有误时换用其他密钥也不会成功，不再重试
+		isLastAttempt := retryCount >= cfg.MaxRetries || !app_errors.IsRetryableCategory(category)
+
+		// Content-filter blocks are not the key's fault and are handled per group policy.
+		if filterReason, filtered := detectContentFilter(errorBody); filtered {
+			action := contentFilterAction(cfg.ContentFilterPolicy, isLastAttempt)
+			prometheus.RecordContentFiltered(group.Name, action)
+			if action != ContentFilterPassthrough {
+				ps.handleContentFilterBlock(c, channelHandler, originalGroup, group, apiKey, bodyBytes, isStream, startTime, retryCount, cacheKey, upstreamURL, statusCode, action, filterReason)
+				return
+			}
+		} else if requestKey == apiKey {
+			ps.applyKeyErrorPolicy(apiKey, group, model, resp, category, parsedError)
+		}
 
-		// 判断是否为最后一次尝试
-		isLastAttempt := retryCount >= cfg.MaxRetries
 		requestType := models.RequestTypeRetry
 		if isLastAttempt {
 			requestType = models.RequestTypeFinal
 		}
 
-		ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, requestType)
+		ps.logRequest(c, originalGroup, group, requestKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, requestType, nil)
 
 		// 如果是最后一次尝试，直接返回错误，不再递归
 		if isLastAttempt {
@@ -255,15 +630,55 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 
-		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
+		prometheus.RecordUpstreamRetry(group.Name, model, category)
+		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1, cacheKey)
 		return
 	}
 
-	// ps.keyProvider.UpdateStatus(apiKey, group, true) // 请求成功不再重置成功次数，减少IO消耗
+	// ps.keyProvider.UpdateStatus(apiKey, group, models.KeyHealthSourceProxy, true) // 请求成功不再重置成功次数，减少IO消耗
 	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
+	ps.pinStickySession(c, group, apiKey, upstream)
+
+	// Non-stream bodies are buffered so that content-filter blocks can be replaced or retried
+	// before anything is written to the client. Bodies over the buffer limit are not inspected.
+	bufferLimit := responseBufferLimit(cfg)
+	if !isStream && cfg.ContentFilterPolicy != ContentFilterPassthrough && !shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
+		respBody, buffered, readErr := bufferResponseBody(resp, bufferLimit)
+		if readErr != nil {
+			logUpstreamError("reading response body", readErr)
+		}
+
+		if buffered {
+			decoded, _ := utils.DecompressResponse(resp.Header.Get("Content-Encoding"), respBody)
+			if filterReason, filtered := detectContentFilter(decoded); filtered {
+				action := contentFilterAction(cfg.ContentFilterPolicy, retryCount >= cfg.MaxRetries)
+				prometheus.RecordContentFiltered(group.Name, action)
+				ps.handleContentFilterBlock(c, channelHandler, originalGroup, group, apiKey, bodyBytes, isStream, startTime, retryCount, cacheKey, upstreamURL, resp.StatusCode, action, filterReason)
+				return
+			}
+		}
+	}
+
+	var hiddenUsage *injectedStreamUsage
+	if injectedUsage {
+		hiddenUsage = hideInjectedStreamUsage(resp)
+	}
+	if transformer, ok := channelHandler.(channel.ReasoningTransformer); ok && embeddingsTranslator == nil && !shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
+		applyReasoningMode(resp, transformer, cfg.ReasoningContentMode, isStream, bufferLimit)
+	}
+	if legacyCompletions {
+		applyLegacyCompletionsResponse(resp, isStream, bufferLimit)
+	}
+	if cfg.ResponseMetadataEnabled && !isStream && embeddingsTranslator == nil && !shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
+		applyResponseMetadata(resp, cfg.ResponseMetadataKey, newResponseMetadata(c, group, upstreamModel(c, channelHandler, group, bodyBytes)), bufferLimit)
+	}
+
+	var usage *usageStats
 
 	// Check if this is a model list request (needs special handling)
-	if shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
+	if embeddingsTranslator != nil {
+		usage = ps.handleEmbeddingsResponse(c, resp, embeddingsTranslator, finalBodyBytes)
+	} else if shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
 		ps.handleModelListResponse(c, resp, group, channelHandler)
 	} else {
 		for key, values := range resp.Header {
@@ -274,13 +689,51 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		c.Status(resp.StatusCode)
 
 		if isStream {
-			ps.handleStreamingResponse(c, resp)
+			transcript := newTranscriptAssembler(c, group)
+			usage = ps.handleStreamingResponse(c, resp, group.Name, model, sentAt, transcript)
+			hiddenUsage.merge(usage)
+			ps.saveTranscript(c, group, transcript, bodyBytes, model, usage)
 		} else {
-			ps.handleNormalResponse(c, resp)
+			var rawBody []byte
+			usage, rawBody = ps.handleNormalResponse(c, resp, bufferLimit, cfg.ResponseCompressionMinKB<<10)
+			if cacheKey != "" && resp.StatusCode == http.StatusOK && rawBody != nil {
+				ps.responseCache.set(cacheKey, resp, rawBody, time.Duration(cfg.ResponseCacheTTLSeconds)*time.Second)
+			}
 		}
 	}
 
-	ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+	ps.logRequest(c, originalGroup, group, requestKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, usage)
+}
+
+// handleContentFilterBlock either retries a content-filtered request with another key or
+// answers with the normalized content_filter error, depending on action.
+func (ps *ProxyServer) handleContentFilterBlock(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	apiKey *models.APIKey,
+	bodyBytes []byte,
+	isStream bool,
+	startTime time.Time,
+	retryCount int,
+	cacheKey string,
+	upstreamURL string,
+	statusCode int,
+	action string,
+	filterReason string,
+) {
+	filterErr := fmt.Errorf("content filter: %s", filterReason)
+
+	if action == ContentFilterRetry {
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, filterErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeRetry, nil)
+		prometheus.RecordUpstreamRetry(group.Name, upstreamModel(c, channelHandler, group, bodyBytes), app_errors.UpstreamErrorContentFiltered)
+		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1, cacheKey)
+		return
+	}
+
+	ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusUnprocessableEntity, filterErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+	writeContentFilterError(c, filterReason)
 }
 
 // logRequest is a helper function to create and record a request log.
@@ -297,7 +750,24 @@ func (ps *ProxyServer) logRequest(
 	channelHandler channel.ChannelProxy,
 	bodyBytes []byte,
 	requestType string,
+	usage *usageStats,
 ) {
+	// Successful upstream requests count against the group's quota cycle
+	if apiKey != nil && finalError == nil && statusCode < 400 {
+		totalTokens := 0
+		if usage != nil {
+			totalTokens = usage.TotalTokens
+		}
+		ps.quotaService.Record(group, apiKey.ID, totalTokens)
+		ps.recordKeyUsage(c, group, apiKey, channelHandler, bodyBytes, usage)
+	}
+
+	if requestType == models.RequestTypeRetry {
+		recordRetryReason(c, originalGroup, group, statusCode, finalError)
+	} else if finalError != nil {
+		c.Set(ctxKeyFinalError, finalError)
+	}
+
 	if ps.requestLogService == nil {
 		return
 	}
@@ -312,18 +782,22 @@ func (ps *ProxyServer) logRequest(
 	duration := time.Since(startTime).Milliseconds()
 
 	logEntry := &models.RequestLog{
-		GroupID:      group.ID,
-		GroupName:    group.Name,
-		IsSuccess:    finalError == nil && statusCode < 400,
-		SourceIP:     c.ClientIP(),
-		StatusCode:   statusCode,
-		RequestPath:  utils.TruncateString(c.Request.URL.String(), 500),
-		Duration:     duration,
-		UserAgent:    userAgent,
-		RequestType:  requestType,
-		IsStream:     isStream,
-		UpstreamAddr: utils.TruncateString(upstreamAddr, 500),
-		RequestBody:  requestBodyToLog,
+		GroupID:         group.ID,
+		GroupName:       group.Name,
+		IsSuccess:       finalError == nil && statusCode < 400,
+		SourceIP:        c.ClientIP(),
+		StatusCode:      statusCode,
+		RequestPath:     utils.TruncateString(c.Request.URL.String(), 500),
+		Duration:        duration,
+		UserAgent:       userAgent,
+		RequestType:     requestType,
+		IsStream:        isStream,
+		UpstreamAddr:    utils.TruncateString(upstreamAddr, 500),
+		RequestBody:     requestBodyToLog,
+		CacheHit:        c.GetBool(ctxKeyCacheHit),
+		RequestID:       c.GetString(ctxKeyRequestID),
+		Metadata:        ps.proxyKeyMetadata(c),
+		ModerationFlags: c.GetString(ctxKeyModerationFlags),
 	}
 
 	// Set parent group
@@ -333,7 +807,11 @@ func (ps *ProxyServer) logRequest(
 	}
 
 	if channelHandler != nil && bodyBytes != nil {
-		logEntry.Model = channelHandler.ExtractModel(c, bodyBytes)
+		logEntry.Model = upstreamModel(c, channelHandler, group, bodyBytes)
+	}
+
+	if requestType == models.RequestTypeFinal {
+		prometheus.RecordProxyRequest(group.Name, logEntry.Model, strconv.Itoa(statusCode), time.Since(startTime).Seconds(), sampledTraceID(c.Request))
 	}
 
 	if apiKey != nil {
@@ -353,7 +831,44 @@ func (ps *ProxyServer) logRequest(
 		logEntry.ErrorMessage = finalError.Error()
 	}
 
+	if usage != nil {
+		logEntry.ChoiceCount = usage.ChoiceCount
+		logEntry.PromptTokens = usage.PromptTokens
+		logEntry.CompletionTokens = usage.CompletionTokens
+		logEntry.TotalTokens = usage.TotalTokens
+		logEntry.CacheCreationTokens = usage.CacheCreationTokens
+		logEntry.CacheReadTokens = usage.CacheReadTokens
+		logEntry.SearchUnits = usage.SearchUnits
+		logEntry.FinishReason = usage.FinishReason
+	}
+
+	if usage != nil && requestType == models.RequestTypeFinal && (usage.CacheCreationTokens > 0 || usage.CacheReadTokens > 0) {
+		prometheus.RecordPromptCacheTokens(group.Name, usage.PromptTokens, usage.CacheCreationTokens, usage.CacheReadTokens)
+	}
+
+	// 缓存命中的响应没有消耗上游用量，不计入 token 与费用
+	if usage != nil && requestType == models.RequestTypeFinal && !logEntry.CacheHit {
+		prometheus.RecordTokenUsage(group.Name, logEntry.Model, usage.PromptTokens, usage.CompletionTokens)
+		if pricing := pricingOf(ps.modelInfo.get(group.ID, logEntry.Model)); pricing != nil {
+			prometheus.RecordCost(group.Name, logEntry.Model, pricing.cost(usage.PromptTokens, usage.CompletionTokens))
+		}
+	}
+
+	ps.recordAccessDetails(c, logEntry, apiKey, usage)
+
+	if logEntry.ParentGroupID != 0 && requestType == models.RequestTypeFinal && !logEntry.CacheHit && statusCode != 499 {
+		ps.recordRoutingOutcome(logEntry, usage)
+	}
+
 	if err := ps.requestLogService.Record(logEntry); err != nil {
 		logrus.Errorf("Failed to record request log: %v", err)
 	}
 }
+
+// finalErrorOf returns the error logged for the final attempt of a request, if any.
+func finalErrorOf(c *gin.Context) error {
+	if v, ok := c.Get(ctxKeyFinalError); ok {
+		return v.(error)
+	}
+	return nil
+}
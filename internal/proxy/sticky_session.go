@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Sticky session modes, configured per group via sticky_session_mode.
+const (
+	StickySessionOff          = "off"
+	StickySessionHeader       = "header"
+	StickySessionFirstMessage = "first_message"
+)
+
+// ctxKeyStickySession holds the *stickySession of a conversational request.
+const ctxKeyStickySession = "sticky_session"
+
+// stickySession is a conversation pinned to the sub-group, key and upstream that served it, so that
+// providers with server-side prompt caching see every turn on the same account and endpoint.
+// Pins live in the shared store and expire sticky_session_ttl_seconds after the last request.
+type stickySession struct {
+	storeKey string
+	ttl      time.Duration
+	pin      *stickyPin
+}
+
+// stickyPin is the stored target of a conversation.
+type stickyPin struct {
+	Group    string `json:"group"`
+	KeyID    uint   `json:"key_id"`
+	Upstream string `json:"upstream,omitempty"`
+}
+
+// resolveStickySession identifies the conversation of a request and loads its pin. It returns nil
+// when the group has sticky sessions off or the request cannot be tied to a conversation.
+func (ps *ProxyServer) resolveStickySession(c *gin.Context, group *models.Group, bodyBytes []byte) *stickySession {
+	cfg := group.EffectiveConfig
+	if cfg.StickySessionMode == "" || cfg.StickySessionMode == StickySessionOff {
+		return nil
+	}
+
+	conversation := c.GetHeader(cfg.StickySessionHeader)
+	if conversation == "" && cfg.StickySessionMode == StickySessionFirstMessage {
+		conversation = firstUserMessage(bodyBytes)
+	}
+	if conversation == "" {
+		return nil
+	}
+
+	h := sha256.New()
+	h.Write([]byte(c.GetString(middleware.ContextKeyProxyKey)))
+	h.Write([]byte{0})
+	h.Write([]byte(conversation))
+	session := &stickySession{
+		storeKey: fmt.Sprintf("sticky_session:%d:%s", group.ID, hex.EncodeToString(h.Sum(nil))),
+		ttl:      time.Duration(cfg.StickySessionTTLSeconds) * time.Second,
+	}
+
+	data, err := ps.store.Get(session.storeKey)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logrus.WithError(err).Warn("Failed to load sticky session")
+		}
+		return session
+	}
+	var pin stickyPin
+	if err := json.Unmarshal(data, &pin); err == nil {
+		session.pin = &pin
+	}
+	return session
+}
+
+// firstUserMessage returns the first user message of an OpenAI, Anthropic or Gemini style request
+// body in compact JSON form, or "" when there is none.
+func firstUserMessage(bodyBytes []byte) string {
+	var body struct {
+		Messages []json.RawMessage `json:"messages"`
+		Contents []json.RawMessage `json:"contents"`
+		Input    json.RawMessage   `json:"input"`
+	}
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return ""
+	}
+
+	messages := body.Messages
+	if len(messages) == 0 {
+		messages = body.Contents
+	}
+	if len(messages) == 0 && len(body.Input) > 0 {
+		// The Responses API accepts either a plain string or a list of input items.
+		if body.Input[0] == '"' {
+			return string(body.Input)
+		}
+		_ = json.Unmarshal(body.Input, &messages)
+	}
+
+	for _, raw := range messages {
+		var message struct {
+			Role string `json:"role"`
+		}
+		if json.Unmarshal(raw, &message) != nil || message.Role != "user" {
+			continue
+		}
+		var compact bytes.Buffer
+		if json.Compact(&compact, raw) != nil {
+			return string(raw)
+		}
+		return compact.String()
+	}
+	return ""
+}
+
+// stickySessionOf returns the sticky session of the current request, if any.
+func stickySessionOf(c *gin.Context) *stickySession {
+	if value, ok := c.Get(ctxKeyStickySession); ok {
+		return value.(*stickySession)
+	}
+	return nil
+}
+
+// selectSubGroup picks the sub-group of an aggregate group, preferring the one the conversation is
+// pinned to on the first attempt.
+func (ps *ProxyServer) selectSubGroup(c *gin.Context, group *models.Group, attempt int) (string, error) {
+	if session := stickySessionOf(c); attempt == 0 && session != nil && session.pin != nil && group.GroupType == "aggregate" {
+		for _, sg := range group.SubGroups {
+			if sg.SubGroupName == session.pin.Group && sg.Weight > 0 {
+				return sg.SubGroupName, nil
+			}
+		}
+	}
+	return ps.subGroupManager.SelectSubGroup(group)
+}
+
+// selectKey returns the pinned key on the first attempt while it is still active, and rotates to
+// the next key otherwise. Retries always rotate so that a failing pinned key is replaced.
+func (ps *ProxyServer) selectKey(c *gin.Context, group *models.Group, retryCount int) (*models.APIKey, error) {
+	session := stickySessionOf(c)
+	if session == nil || retryCount > 0 {
+		return ps.keyProvider.SelectKey(group.ID)
+	}
+
+	if session.pin != nil && session.pin.Group == group.Name {
+		if apiKey, err := ps.keyProvider.GetActiveKey(group.ID, session.pin.KeyID); err == nil {
+			prometheus.RecordStickySession(group.Name, "pinned")
+			return apiKey, nil
+		}
+	}
+	prometheus.RecordStickySession(group.Name, "new")
+	return ps.keyProvider.SelectKey(group.ID)
+}
+
+// buildUpstreamURL targets the pinned upstream on the first attempt while it is still configured,
+// and uses the group's upstream selection otherwise.
+func (ps *ProxyServer) buildUpstreamURL(c *gin.Context, channelHandler channel.ChannelProxy, group *models.Group, model string, retryCount int) (string, error) {
+	if session := stickySessionOf(c); session != nil && session.pin != nil && retryCount == 0 &&
+		session.pin.Group == group.Name && session.pin.Upstream != "" {
+		if upstreamURL, ok := channelHandler.BuildPinnedUpstreamURL(c.Request.URL, c.Param("group_name"), session.pin.Upstream); ok {
+			return upstreamURL, nil
+		}
+	}
+	return channelHandler.BuildUpstreamURL(c.Request.URL, c.Param("group_name"), model)
+}
+
+// pinStickySession stores the sub-group, key and upstream that successfully served a conversation
+// and extends its lifetime.
+func (ps *ProxyServer) pinStickySession(c *gin.Context, group *models.Group, apiKey *models.APIKey, upstream *channel.UpstreamInfo) {
+	session := stickySessionOf(c)
+	if session == nil {
+		return
+	}
+
+	pin := stickyPin{Group: group.Name, KeyID: apiKey.ID}
+	if upstream != nil {
+		pin.Upstream = upstream.URL.String()
+	}
+	data, err := json.Marshal(pin)
+	if err != nil {
+		return
+	}
+	if err := ps.store.Set(session.storeKey, data, session.ttl); err != nil {
+		logrus.WithError(err).Warn("Failed to save sticky session")
+	}
+}
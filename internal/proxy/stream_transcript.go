@@ -0,0 +1,209 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxTranscriptBytes bounds the assembled content and reasoning of a single transcript.
+const maxTranscriptBytes = 256 * 1024
+
+// transcriptAssembler rebuilds the full response of a stream from its deltas. It understands
+// OpenAI chat and Responses API events, Anthropic content block deltas, Gemini candidates and
+// Ollama NDJSON lines; for n>1 streams only the first choice is assembled.
+type transcriptAssembler struct {
+	reason    string
+	pending   []byte
+	content   strings.Builder
+	reasoning strings.Builder
+	chunks    int
+	truncated bool
+}
+
+// transcriptPayload holds the delta fields of the supported stream formats.
+type transcriptPayload struct {
+	Type    string          `json:"type"`
+	Delta   json.RawMessage `json:"delta"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Candidates []struct {
+		Index   int `json:"index"`
+		Content struct {
+			Parts []struct {
+				Text    string `json:"text"`
+				Thought bool   `json:"thought"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	Message *struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Response string `json:"response"`
+}
+
+// newTranscriptAssembler returns an assembler when the stream of this request should be captured,
+// either because its proxy key is listed in transcript_proxy_keys or because it falls into
+// transcript_sample_percent. Sampling is per sticky session when the request has one, so that
+// every turn of a sampled conversation is captured, and per request otherwise.
+func newTranscriptAssembler(c *gin.Context, group *models.Group) *transcriptAssembler {
+	cfg := group.EffectiveConfig
+	if proxyKey := c.GetString(middleware.ContextKeyProxyKey); proxyKey != "" && cfg.TranscriptProxyKeys != "" &&
+		slices.Contains(utils.SplitAndTrim(cfg.TranscriptProxyKeys, ","), proxyKey) {
+		return &transcriptAssembler{reason: models.TranscriptReasonProxyKey}
+	}
+	if cfg.TranscriptSamplePercent <= 0 {
+		return nil
+	}
+
+	sampleID := c.GetString(ctxKeyRequestID)
+	if session := stickySessionOf(c); session != nil {
+		sampleID = session.storeKey
+	}
+	if canaryBucket(group.Name, sampleID) >= cfg.TranscriptSamplePercent {
+		return nil
+	}
+	return &transcriptAssembler{reason: models.TranscriptReasonSampled}
+}
+
+// Write feeds a chunk of the stream into the assembler.
+func (a *transcriptAssembler) Write(p []byte) {
+	a.pending = append(a.pending, p...)
+	for {
+		i := bytes.IndexByte(a.pending, '\n')
+		if i < 0 {
+			break
+		}
+		a.processLine(a.pending[:i])
+		a.pending = a.pending[i+1:]
+	}
+	if len(a.pending) > maxUsageCaptureBytes {
+		a.pending = a.pending[:0]
+	}
+}
+
+func (a *transcriptAssembler) processLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	data := line
+	if bytes.HasPrefix(line, []byte("data:")) {
+		data = bytes.TrimSpace(line[len("data:"):])
+	}
+	if len(data) == 0 || data[0] != '{' {
+		return
+	}
+
+	var payload transcriptPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return
+	}
+	a.chunks++
+
+	for _, choice := range payload.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		a.append(&a.content, choice.Delta.Content)
+		a.append(&a.reasoning, choice.Delta.ReasoningContent)
+		a.append(&a.reasoning, choice.Delta.Reasoning)
+	}
+	for _, candidate := range payload.Candidates {
+		if candidate.Index != 0 {
+			continue
+		}
+		for _, part := range candidate.Content.Parts {
+			if part.Thought {
+				a.append(&a.reasoning, part.Text)
+			} else {
+				a.append(&a.content, part.Text)
+			}
+		}
+	}
+	if payload.Message != nil {
+		a.append(&a.content, payload.Message.Content)
+		a.append(&a.reasoning, payload.Message.Thinking)
+	}
+	a.append(&a.content, payload.Response)
+
+	if len(payload.Delta) == 0 {
+		return
+	}
+	switch {
+	case payload.Type == "response.output_text.delta":
+		var text string
+		if json.Unmarshal(payload.Delta, &text) == nil {
+			a.append(&a.content, text)
+		}
+	case strings.HasPrefix(payload.Type, "response.reasoning") && strings.HasSuffix(payload.Type, ".delta"):
+		var text string
+		if json.Unmarshal(payload.Delta, &text) == nil {
+			a.append(&a.reasoning, text)
+		}
+	case payload.Type == "content_block_delta":
+		var delta struct {
+			Text     string `json:"text"`
+			Thinking string `json:"thinking"`
+		}
+		if json.Unmarshal(payload.Delta, &delta) == nil {
+			a.append(&a.content, delta.Text)
+			a.append(&a.reasoning, delta.Thinking)
+		}
+	}
+}
+
+// append adds text to b unless the transcript has reached maxTranscriptBytes.
+func (a *transcriptAssembler) append(b *strings.Builder, text string) {
+	if text == "" || a.truncated {
+		return
+	}
+	if a.content.Len()+a.reasoning.Len()+len(text) > maxTranscriptBytes {
+		a.truncated = true
+		return
+	}
+	b.WriteString(text)
+}
+
+// saveTranscript stores the assembled transcript of a finished stream together with the client's
+// request body. Failures are logged and never affect the response.
+func (ps *ProxyServer) saveTranscript(c *gin.Context, group *models.Group, assembler *transcriptAssembler, bodyBytes []byte, model string, usage *usageStats) {
+	if assembler == nil || ps.transcriptService == nil {
+		return
+	}
+	if len(assembler.pending) > 0 {
+		assembler.processLine(assembler.pending)
+		assembler.pending = nil
+	}
+
+	transcript := &models.StreamTranscript{
+		RequestID:        c.GetString(ctxKeyRequestID),
+		GroupID:          group.ID,
+		GroupName:        group.Name,
+		Model:            utils.TruncateString(model, 255),
+		Reason:           assembler.reason,
+		ChunkCount:       assembler.chunks,
+		Truncated:        assembler.truncated,
+		RequestBody:      utils.TruncateString(string(bodyBytes), maxTranscriptBytes),
+		Content:          assembler.content.String(),
+		ReasoningContent: assembler.reasoning.String(),
+	}
+	if usage != nil {
+		transcript.FinishReason = usage.FinishReason
+	}
+	if err := ps.transcriptService.Save(transcript); err != nil {
+		logrus.WithError(err).WithField("request_id", transcript.RequestID).Error("Failed to save stream transcript")
+	}
+}
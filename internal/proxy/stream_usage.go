@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// streamOptionsUpstream identifies an upstream of a group in streamOptionsRejected.
+func streamOptionsUpstream(group *models.Group, upstreamURL *url.URL) string {
+	return fmt.Sprintf("%d|%s://%s", group.ID, upstreamURL.Scheme, upstreamURL.Host)
+}
+
+// applyStreamUsageOptions rewrites stream_options of a streaming chat or text completion request
+// according to the group's stream_usage_options. In auto mode include_usage is injected so that
+// usage is reported without clients opting in, unless the provider or this upstream rejects
+// the field, in which case it is stripped as in strip mode. It returns the new body, whether
+// include_usage was added on behalf of the client and whether stream_options is sent at all.
+func (ps *ProxyServer) applyStreamUsageOptions(channelHandler channel.ChannelProxy, group *models.Group, upstreamURL *url.URL, body []byte, isStream bool) ([]byte, bool, bool) {
+	mode := group.EffectiveConfig.StreamUsageOptions
+	reporter, ok := channelHandler.(channel.StreamUsageReporter)
+	if !isStream || !ok || mode == channel.StreamUsageOff || !strings.HasSuffix(upstreamURL.Path, "/completions") {
+		return body, false, false
+	}
+
+	var requestData map[string]any
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		return body, false, false
+	}
+	options, present := requestData["stream_options"]
+
+	_, rejected := ps.streamOptionsRejected.Load(streamOptionsUpstream(group, upstreamURL))
+	if mode == channel.StreamUsageStrip || !reporter.AcceptsStreamOptions() || rejected {
+		if !present {
+			return body, false, false
+		}
+		delete(requestData, "stream_options")
+		out, err := json.Marshal(requestData)
+		if err != nil {
+			return body, false, true
+		}
+		return out, false, false
+	}
+
+	optionsMap, _ := options.(map[string]any)
+	if includeUsage, _ := optionsMap["include_usage"].(bool); includeUsage {
+		return body, false, true
+	}
+	if optionsMap == nil {
+		optionsMap = make(map[string]any)
+	}
+	optionsMap["include_usage"] = true
+	requestData["stream_options"] = optionsMap
+	out, err := json.Marshal(requestData)
+	if err != nil {
+		return body, false, present
+	}
+	return out, true, true
+}
+
+// rejectsStreamOptions reports whether an error response blames the stream_options field.
+func rejectsStreamOptions(statusCode int, errorBody []byte) bool {
+	if statusCode != http.StatusBadRequest && statusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(errorBody), []byte("stream_options"))
+}
+
+// rememberStreamOptionsRejected makes later requests to the upstream strip stream_options. It is
+// kept in memory, so the upstream is probed again after a restart.
+func (ps *ProxyServer) rememberStreamOptionsRejected(group *models.Group, upstreamURL *url.URL) {
+	if _, loaded := ps.streamOptionsRejected.LoadOrStore(streamOptionsUpstream(group, upstreamURL), true); !loaded {
+		logrus.WithFields(logrus.Fields{"group": group.Name, "upstream": upstreamURL.Host}).
+			Info("Upstream rejected stream_options, it will be stripped from further requests")
+	}
+}
+
+// injectedStreamUsage holds the usage-only chunk of a stream whose include_usage was injected by
+// the gateway. The chunk is withheld from the client, whose SDK may not expect a chunk without
+// choices, and its usage is merged into the request's usage afterwards.
+type injectedStreamUsage struct {
+	payload []byte
+}
+
+// hideInjectedStreamUsage removes the usage-only chunk from the stream as it is read.
+func hideInjectedStreamUsage(resp *http.Response) *injectedStreamUsage {
+	hidden := &injectedStreamUsage{}
+	if resp.Header.Get("Content-Encoding") != "" {
+		return hidden
+	}
+	rewriteStreamEvents(resp, func(data []byte) []byte {
+		var chunk struct {
+			Choices []json.RawMessage `json:"choices"`
+			Usage   json.RawMessage   `json:"usage"`
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil || len(chunk.Choices) > 0 || len(chunk.Usage) == 0 || string(chunk.Usage) == "null" {
+			return data
+		}
+		hidden.payload = append([]byte(nil), data...)
+		return nil
+	})
+	return hidden
+}
+
+// merge adds the withheld usage to the usage tracked from the rest of the stream.
+func (h *injectedStreamUsage) merge(usage *usageStats) {
+	if h == nil || usage == nil || h.payload == nil {
+		return
+	}
+	var payload usagePayload
+	if err := json.Unmarshal(h.payload, &payload); err != nil {
+		return
+	}
+	applyUsage(usage, &payload)
+}
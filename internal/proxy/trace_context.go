@@ -0,0 +1,30 @@
+package proxy
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// traceparentHeader carries the W3C trace context of a request traced by the client or a gateway
+// in front of the proxy.
+const traceparentHeader = "traceparent"
+
+// sampledTraceID returns the trace ID of the request's W3C trace context, or "" when the request
+// has none or its trace was not sampled, since an unsampled trace cannot be looked up.
+func sampledTraceID(r *http.Request) string {
+	// version-traceid-parentid-flags, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	parts := strings.Split(strings.TrimSpace(r.Header.Get(traceparentHeader)), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[3]) != 2 {
+		return ""
+	}
+	traceID := strings.ToLower(parts[1])
+	if _, err := hex.DecodeString(traceID); err != nil || traceID == strings.Repeat("0", 32) {
+		return ""
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || flags[0]&0x01 == 0 {
+		return ""
+	}
+	return traceID
+}
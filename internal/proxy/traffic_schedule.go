@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// isWithinTrafficSchedule reports whether the group accepts traffic at the given time.
+// Groups without a schedule, or with one that no longer parses, always accept traffic.
+func isWithinTrafficSchedule(group *models.Group, now time.Time) bool {
+	expr := group.EffectiveConfig.TrafficSchedule
+	if expr == "" {
+		return true
+	}
+	schedule, err := utils.ParseTrafficSchedule(expr)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Ignoring invalid traffic schedule")
+		return true
+	}
+	return schedule.Contains(now)
+}
+
+// applyTrafficSchedule returns the group that should serve a request arriving now: the group itself
+// while inside its schedule, otherwise its fallback group. Fallbacks are followed only one level deep.
+func (ps *ProxyServer) applyTrafficSchedule(group *models.Group) (*models.Group, error) {
+	now := time.Now()
+	if isWithinTrafficSchedule(group, now) {
+		return group, nil
+	}
+
+	fallbackName := group.EffectiveConfig.TrafficScheduleFallbackGroup
+	if fallbackName == "" || fallbackName == group.Name {
+		prometheus.RecordScheduleDiverted(group.Name, "rejected")
+		return nil, fmt.Errorf("group '%s' is outside its traffic schedule", group.Name)
+	}
+
+	fallback, err := ps.groupManager.GetGroupByName(fallbackName)
+	if err != nil {
+		prometheus.RecordScheduleDiverted(group.Name, "rejected")
+		return nil, fmt.Errorf("fallback group '%s' for '%s' not found", fallbackName, group.Name)
+	}
+	if !isWithinTrafficSchedule(fallback, now) {
+		prometheus.RecordScheduleDiverted(group.Name, "rejected")
+		return nil, fmt.Errorf("group '%s' and its fallback '%s' are both outside their traffic schedules", group.Name, fallbackName)
+	}
+
+	prometheus.RecordScheduleDiverted(group.Name, "fallback")
+	logrus.WithFields(logrus.Fields{
+		"group":    group.Name,
+		"fallback": fallback.Name,
+	}).Debug("Routing request to fallback group outside traffic schedule")
+	return fallback, nil
+}
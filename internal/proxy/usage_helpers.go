@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gpt-load/internal/utils"
+)
+
+// maxUsageCaptureBytes bounds how much of a non-stream response body is kept for usage parsing.
+const maxUsageCaptureBytes = 8 << 20
+
+// usageStats holds the choice count and token usage reported by an upstream response.
+type usageStats struct {
+	ChoiceCount      int
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// CacheCreationTokens and CacheReadTokens are prompt-cache input tokens (Anthropic cache_control),
+	// reported separately from PromptTokens.
+	CacheCreationTokens int
+	CacheReadTokens     int
+	// SearchUnits are the rerank search units billed by Cohere.
+	SearchUnits int
+	// FinishReason is the normalized finish reason of the first choice.
+	FinishReason string
+}
+
+// usagePayload covers the usage shapes of the OpenAI, Anthropic, Gemini and Ollama native formats,
+// and of the Cohere rerank API.
+type usagePayload struct {
+	Choices []struct {
+		Index        int    `json:"index"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Candidates []struct {
+		Index        int    `json:"index"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	StopReason string            `json:"stop_reason"`
+	Content    []json.RawMessage `json:"content"`
+	Usage      *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+		InputTokens      int `json:"input_tokens"`
+		OutputTokens     int `json:"output_tokens"`
+		promptCacheUsage
+	} `json:"usage"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	// Anthropic streaming events
+	Type  string `json:"type"`
+	Delta *struct {
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Message *struct {
+		Usage *struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+			promptCacheUsage
+		} `json:"usage"`
+	} `json:"message"`
+	// Ollama native API (/api/chat, /api/generate)
+	Done            *bool  `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	// Cohere API (/v2/rerank)
+	Meta *struct {
+		BilledUnits *struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+			SearchUnits  int `json:"search_units"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// promptCacheUsage holds Anthropic prompt caching counters.
+type promptCacheUsage struct {
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// parseUsage extracts usage from a complete (non-stream) response body.
+func parseUsage(body []byte) *usageStats {
+	var payload usagePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+
+	stats := &usageStats{}
+	switch {
+	case len(payload.Choices) > 0:
+		stats.ChoiceCount = len(payload.Choices)
+	case len(payload.Candidates) > 0:
+		stats.ChoiceCount = len(payload.Candidates)
+	case len(payload.Content) > 0, payload.Done != nil:
+		stats.ChoiceCount = 1
+	}
+	applyUsage(stats, &payload)
+	applyFinishReason(stats, &payload)
+
+	return stats
+}
+
+// applyUsage copies any token counts present in payload into stats.
+func applyUsage(stats *usageStats, payload *usagePayload) {
+	reportedTotal := 0
+	if u := payload.Usage; u != nil {
+		if u.PromptTokens > 0 || u.CompletionTokens > 0 || u.TotalTokens > 0 {
+			stats.PromptTokens = u.PromptTokens
+			stats.CompletionTokens = u.CompletionTokens
+			reportedTotal = u.TotalTokens
+		} else {
+			if u.InputTokens > 0 {
+				stats.PromptTokens = u.InputTokens
+			}
+			if u.OutputTokens > 0 {
+				stats.CompletionTokens = u.OutputTokens
+			}
+		}
+		applyPromptCacheUsage(stats, u.promptCacheUsage)
+	}
+	if payload.Message != nil && payload.Message.Usage != nil {
+		stats.PromptTokens = payload.Message.Usage.InputTokens
+		stats.CompletionTokens = payload.Message.Usage.OutputTokens
+		applyPromptCacheUsage(stats, payload.Message.Usage.promptCacheUsage)
+	}
+	if payload.PromptEvalCount > 0 || payload.EvalCount > 0 {
+		stats.PromptTokens = payload.PromptEvalCount
+		stats.CompletionTokens = payload.EvalCount
+	}
+	if m := payload.UsageMetadata; m != nil {
+		stats.PromptTokens = m.PromptTokenCount
+		stats.CompletionTokens = m.CandidatesTokenCount
+		reportedTotal = m.TotalTokenCount
+	}
+	if m := payload.Meta; m != nil && m.BilledUnits != nil {
+		stats.PromptTokens = m.BilledUnits.InputTokens
+		stats.CompletionTokens = m.BilledUnits.OutputTokens
+		stats.SearchUnits = m.BilledUnits.SearchUnits
+	}
+	// Embeddings and rerank APIs such as Voyage's only report a total, which is all input.
+	if stats.PromptTokens == 0 && stats.CompletionTokens == 0 && reportedTotal > 0 {
+		stats.PromptTokens = reportedTotal
+	}
+	if reportedTotal > 0 {
+		stats.TotalTokens = reportedTotal
+	} else {
+		stats.TotalTokens = stats.PromptTokens + stats.CompletionTokens
+	}
+}
+
+// applyPromptCacheUsage copies non-zero prompt cache counters, so that later stream events
+// without them don't reset the values from message_start.
+func applyPromptCacheUsage(stats *usageStats, u promptCacheUsage) {
+	if u.CacheCreationInputTokens > 0 {
+		stats.CacheCreationTokens = u.CacheCreationInputTokens
+	}
+	if u.CacheReadInputTokens > 0 {
+		stats.CacheReadTokens = u.CacheReadInputTokens
+	}
+}
+
+// applyFinishReason records the normalized finish reason of choice 0, if present in payload.
+func applyFinishReason(stats *usageStats, payload *usagePayload) {
+	raw := payload.StopReason
+	if payload.DoneReason != "" {
+		raw = payload.DoneReason
+	}
+	if payload.Delta != nil && payload.Delta.StopReason != "" {
+		raw = payload.Delta.StopReason
+	}
+	for _, choice := range payload.Choices {
+		if choice.Index == 0 && choice.FinishReason != "" {
+			raw = choice.FinishReason
+		}
+	}
+	for _, candidate := range payload.Candidates {
+		if candidate.Index == 0 && candidate.FinishReason != "" {
+			raw = candidate.FinishReason
+		}
+	}
+	if normalized := utils.NormalizeFinishReason(raw); normalized != "" {
+		stats.FinishReason = normalized
+	}
+}
+
+// streamUsageTracker inspects SSE data lines as they pass through the proxy.
+// Choices are counted by distinct index so that n>1 streams are reported correctly.
+type streamUsageTracker struct {
+	pending []byte
+	indexes map[int]struct{}
+	stats   usageStats
+	// deltas counts content-bearing events, used as a token estimate when no usage is reported.
+	deltas int
+}
+
+func newStreamUsageTracker() *streamUsageTracker {
+	return &streamUsageTracker{indexes: make(map[int]struct{})}
+}
+
+// Write feeds a chunk of the upstream stream into the tracker.
+func (t *streamUsageTracker) Write(p []byte) {
+	t.pending = append(t.pending, p...)
+	for {
+		i := bytes.IndexByte(t.pending, '\n')
+		if i < 0 {
+			break
+		}
+		t.processLine(t.pending[:i])
+		t.pending = t.pending[i+1:]
+	}
+	if len(t.pending) > maxUsageCaptureBytes {
+		t.pending = t.pending[:0]
+	}
+}
+
+func (t *streamUsageTracker) processLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	// SSE data lines, or bare JSON lines of an NDJSON stream (Ollama native API).
+	data := line
+	if bytes.HasPrefix(line, []byte("data:")) {
+		data = bytes.TrimSpace(line[len("data:"):])
+	}
+	if len(data) == 0 || data[0] != '{' {
+		return
+	}
+
+	var payload usagePayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return
+	}
+
+	for _, choice := range payload.Choices {
+		t.indexes[choice.Index] = struct{}{}
+	}
+	for _, candidate := range payload.Candidates {
+		t.indexes[candidate.Index] = struct{}{}
+	}
+	if payload.Type == "message_start" || payload.Done != nil {
+		t.indexes[0] = struct{}{}
+	}
+	isOllamaDelta := payload.Done != nil && !*payload.Done
+	if len(payload.Choices) > 0 || len(payload.Candidates) > 0 || payload.Type == "content_block_delta" || isOllamaDelta {
+		t.deltas++
+	}
+	applyUsage(&t.stats, &payload)
+	applyFinishReason(&t.stats, &payload)
+}
+
+// OutputTokens returns the reported completion tokens, or the number of deltas if none were reported.
+// It only covers the whole stream once Result has been called.
+func (t *streamUsageTracker) OutputTokens() int {
+	if t.stats.CompletionTokens > 0 {
+		return t.stats.CompletionTokens
+	}
+	return t.deltas
+}
+
+// Result returns the usage collected so far.
+func (t *streamUsageTracker) Result() *usageStats {
+	if len(t.pending) > 0 {
+		t.processLine(t.pending)
+		t.pending = nil
+	}
+	stats := t.stats
+	stats.ChoiceCount = len(t.indexes)
+	return &stats
+}
@@ -1,11 +1,20 @@
 package response
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -36,13 +45,7 @@ func Paginate(c *gin.Context, query *gorm.DB, dest any) (*PaginatedResponse, err
 		page = 1
 	}
 
-	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(DefaultPageSize)))
-	if err != nil || pageSize <= 0 {
-		pageSize = DefaultPageSize
-	}
-	if pageSize > MaxPageSize {
-		pageSize = MaxPageSize
-	}
+	pageSize := parsePageSize(c)
 
 	// 2. Get total count of items
 	var totalItems int64
@@ -72,3 +75,172 @@ func Paginate(c *gin.Context, query *gorm.DB, dest any) (*PaginatedResponse, err
 
 	return paginatedData, nil
 }
+
+// parsePageSize reads the "page_size" query parameter, bounded by MaxPageSize.
+func parsePageSize(c *gin.Context) int {
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(DefaultPageSize)))
+	if err != nil || pageSize <= 0 {
+		return DefaultPageSize
+	}
+	return min(pageSize, MaxPageSize)
+}
+
+// SortOptions lists the columns a list endpoint can be sorted by, keyed by the value of the
+// "sort" query parameter. Nullable columns cannot be paged with a cursor.
+type SortOptions struct {
+	Fields      map[string]string
+	Nullable    map[string]bool
+	Default     string
+	DefaultDesc bool
+}
+
+// Sort is the order requested with the "sort" and "order" query parameters. Rows with equal
+// values are ordered by id in the same direction, so that pages do not overlap.
+type Sort struct {
+	Column   string
+	Desc     bool
+	Nullable bool
+}
+
+// ParseSort reads the "sort" and "order" (asc or desc) query parameters.
+func ParseSort(c *gin.Context, options SortOptions) (Sort, error) {
+	field := c.DefaultQuery("sort", options.Default)
+	column, ok := options.Fields[field]
+	if !ok {
+		fields := make([]string, 0, len(options.Fields))
+		for name := range options.Fields {
+			fields = append(fields, name)
+		}
+		sort.Strings(fields)
+		return Sort{}, fmt.Errorf("sort must be one of %s", strings.Join(fields, ", "))
+	}
+
+	desc := options.DefaultDesc
+	switch strings.ToLower(c.Query("order")) {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		return Sort{}, fmt.Errorf("order must be asc or desc")
+	}
+	return Sort{Column: column, Desc: desc, Nullable: options.Nullable[field]}, nil
+}
+
+// Scope orders a query by the sort column and then by id. The zero Sort leaves the order of the
+// query unchanged.
+func (s Sort) Scope(db *gorm.DB) *gorm.DB {
+	if s.Column == "" {
+		return db
+	}
+	direction := " ASC"
+	if s.Desc {
+		direction = " DESC"
+	}
+	db = db.Order(s.Column + direction)
+	if s.Column != "id" {
+		db = db.Order("id" + direction)
+	}
+	return db
+}
+
+// CursorPaginatedResponse is the response of a list endpoint paged with a cursor. NextCursor is
+// empty on the last page.
+type CursorPaginatedResponse struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	PageSize   int    `json:"page_size"`
+	TotalItems int64  `json:"total_items"`
+}
+
+// UseCursor reports whether the request asked for cursor pagination, by passing a "cursor"
+// query parameter; it is empty for the first page.
+func UseCursor(c *gin.Context) bool {
+	_, ok := c.GetQuery("cursor")
+	return ok
+}
+
+// PaginateCursor pages a query after the row encoded in the "cursor" query parameter, ordered by
+// order, which must be on a column that is never NULL. Unlike offsets, cursors stay valid while
+// rows are added in front of them, and deep pages cost as much as the first one.
+func PaginateCursor(c *gin.Context, query *gorm.DB, dest any, order Sort) (*CursorPaginatedResponse, error) {
+	if order.Nullable {
+		return nil, ErrInvalidCursor
+	}
+	pageSize := parsePageSize(c)
+
+	var totalItems int64
+	if err := query.Count(&totalItems).Error; err != nil {
+		return nil, err
+	}
+
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(dest); err != nil {
+		return nil, err
+	}
+	sortField := stmt.Schema.LookUpField(order.Column)
+	idField := stmt.Schema.LookUpField("id")
+	if sortField == nil || idField == nil {
+		return nil, fmt.Errorf("cannot page by %s", order.Column)
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		value, id, err := decodeCursor(cursor, sortField, idField)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		op := ">"
+		if order.Desc {
+			op = "<"
+		}
+		query = query.Where(
+			fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", order.Column, op),
+			value, value, id,
+		)
+	}
+
+	if err := query.Scopes(order.Scope).Limit(pageSize + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	result := &CursorPaginatedResponse{Items: dest, PageSize: pageSize, TotalItems: totalItems}
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() > pageSize {
+		rows.SetLen(pageSize)
+		last := rows.Index(pageSize - 1)
+		value, _ := sortField.ValueOf(context.Background(), last)
+		id, _ := idField.ValueOf(context.Background(), last)
+		cursor, err := json.Marshal([]any{value, id})
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = base64.RawURLEncoding.EncodeToString(cursor)
+	}
+	return result, nil
+}
+
+// ErrInvalidCursor is returned for a cursor that was not produced by PaginateCursor for the same
+// sort column, or when the sort column is nullable.
+var ErrInvalidCursor = errors.New("invalid cursor, or cursor pagination is not supported for this sort")
+
+// decodeCursor decodes the sort value and id of a cursor into the types of their fields.
+func decodeCursor(cursor string, sortField, idField *schema.Field) (any, any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil || len(parts) != 2 {
+		return nil, nil, ErrInvalidCursor
+	}
+	value := reflect.New(sortField.FieldType)
+	if err := json.Unmarshal(parts[0], value.Interface()); err != nil {
+		return nil, nil, err
+	}
+	id := reflect.New(idField.FieldType)
+	if err := json.Unmarshal(parts[1], id.Interface()); err != nil {
+		return nil, nil, err
+	}
+	return value.Elem().Interface(), id.Elem().Interface(), nil
+}
@@ -20,6 +20,7 @@ type SuccessResponse struct {
 type ErrorResponse struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
 }
 
 // Success sends a standardized success response.
@@ -67,3 +68,13 @@ func ErrorI18nFromAPIError(c *gin.Context, apiErr *app_errors.APIError, msgID st
 		Message: message,
 	})
 }
+
+// ErrorI18nWithData sends a standardized error response with i18n message and details the client can act on.
+func ErrorI18nWithData(c *gin.Context, apiErr *app_errors.APIError, msgID string, data any, templateData ...map[string]any) {
+	message := i18n.Message(c, msgID, templateData...)
+	c.JSON(apiErr.HTTPStatus, ErrorResponse{
+		Code:    apiErr.Code,
+		Message: message,
+		Data:    data,
+	})
+}
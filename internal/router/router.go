@@ -2,15 +2,19 @@ package router
 
 import (
 	"embed"
+	"gpt-load/internal/accesslog"
 	"gpt-load/internal/handler"
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/plugin"
 	prommetrics "gpt-load/internal/prometheus"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
 	"io/fs"
 	"net/http"
+	"net/http/pprof"
 	"strings"
 	"time"
 
@@ -18,6 +22,7 @@ import (
 	"github.com/gin-contrib/static"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type embedFileSystem struct {
@@ -44,17 +49,30 @@ func NewRouter(
 	proxyServer *proxy.ProxyServer,
 	configManager types.ConfigManager,
 	groupManager *services.GroupManager,
+	userService *services.UserService,
+	oidcService *services.OIDCService,
+	teamService *services.TeamService,
+	accessLogger *accesslog.Logger,
+	pluginManager *plugin.Manager,
 	buildFS embed.FS,
 	indexPage []byte,
 ) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
+	// Client addresses (IP access policies, rate limits, logs) only come from forwarding headers
+	// when the request was sent by a configured reverse proxy.
+	trustedProxies := configManager.GetEffectiveServerConfig().TrustedProxies
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		logrus.WithError(err).Warn("Invalid TRUSTED_PROXIES, forwarding headers will be ignored")
+		_ = router.SetTrustedProxies(nil)
+	}
 
 	// 注册全局中间件
 	router.Use(middleware.Recovery())
 	router.Use(middleware.ErrorHandler())
 	router.Use(middleware.Logger(configManager.GetLogConfig()))
+	router.Use(middleware.AccessLog(accessLogger))
 	router.Use(middleware.CORS(configManager.GetCORSConfig()))
 	router.Use(middleware.RateLimiter(configManager.GetPerformanceConfig()))
 	router.Use(middleware.SecurityHeaders())
@@ -66,17 +84,23 @@ func NewRouter(
 	})
 
 	// 注册路由
-	registerSystemRoutes(router, serverHandler)
-	registerAPIRoutes(router, serverHandler, configManager)
-	registerProxyRoutes(router, proxyServer, groupManager, serverHandler)
+	registerSystemRoutes(router, serverHandler, proxyServer)
+	registerAPIRoutes(router, serverHandler, userService, oidcService, teamService)
+	if configManager.GetEffectiveServerConfig().EnablePprof {
+		registerPprofRoutes(router, userService, oidcService)
+	}
+	registerProxyRoutes(router, proxyServer, groupManager, pluginManager, serverHandler)
 	registerFrontendRoutes(router, buildFS, indexPage)
 
 	return router
 }
 
 // registerSystemRoutes 注册系统级路由
-func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server) {
+func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server, proxyServer *proxy.ProxyServer) {
 	router.GET("/health", serverHandler.Health)
+	router.GET("/ready", proxyServer.Ready)
+	router.GET("/healthz", serverHandler.Healthz)
+	router.GET("/readyz", serverHandler.Readyz)
 	router.GET("/metrics", prommetrics.Handler()) // Prometheus metrics endpoint
 }
 
@@ -84,31 +108,85 @@ func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server) {
 func registerAPIRoutes(
 	router *gin.Engine,
 	serverHandler *handler.Server,
-	configManager types.ConfigManager,
+	userService *services.UserService,
+	oidcService *services.OIDCService,
+	teamService *services.TeamService,
 ) {
 	api := router.Group("/api")
 	api.Use(i18n.Middleware())
 
-	authConfig := configManager.GetAuthConfig()
-
 	// 公开
-	registerPublicAPIRoutes(api, serverHandler)
+	registerPublicAPIRoutes(api, serverHandler, router.Routes)
 
 	// 认证
 	protectedAPI := api.Group("")
-	protectedAPI.Use(middleware.Auth(authConfig))
-	registerProtectedAPIRoutes(protectedAPI, serverHandler)
+	protectedAPI.Use(middleware.Auth(userService, oidcService))
+	registerAccountAPIRoutes(protectedAPI, serverHandler)
+	registerSharedAPIRoutes(protectedAPI, serverHandler)
+
+	// Viewers can only read; operators and admins can change things, limited to their teams' groups
+	managedAPI := protectedAPI.Group("")
+	managedAPI.Use(middleware.RequireRoleForWrites(models.RoleOperator))
+	managedAPI.Use(middleware.GroupScope(teamService))
+	registerProtectedAPIRoutes(managedAPI, serverHandler, middleware.RequireSecondFactor(userService))
 }
 
 // registerPublicAPIRoutes 公开API路由
-func registerPublicAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
+func registerPublicAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server, routes func() gin.RoutesInfo) {
 	api.POST("/auth/login", serverHandler.Login)
+	api.GET("/auth/oidc/config", serverHandler.GetOIDCConfig)
+	api.GET("/auth/oidc/login", serverHandler.OIDCLogin)
+	api.GET("/auth/oidc/callback", serverHandler.OIDCCallback)
 	api.GET("/integration/info", serverHandler.GetIntegrationInfo)
+
+	// OpenAPI 文档，列出的路径在文档中标记为无需认证
+	api.GET("/openapi.json", serverHandler.OpenAPISpec(routes,
+		"/api/auth/login",
+		"/api/auth/oidc/config",
+		"/api/auth/oidc/login",
+		"/api/auth/oidc/callback",
+		"/api/integration/info",
+	))
+}
+
+// registerAccountAPIRoutes 当前登录用户的账户路由，所有角色可用
+func registerAccountAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
+	api.GET("/auth/me", serverHandler.GetCurrentUser)
+	api.POST("/auth/logout", serverHandler.Logout)
+	api.PUT("/auth/password", serverHandler.ChangePassword)
+	api.POST("/auth/2fa/setup", serverHandler.SetupTOTP)
+	api.POST("/auth/2fa/enable", serverHandler.EnableTOTP)
+	api.POST("/auth/2fa/disable", serverHandler.DisableTOTP)
 }
 
-// registerProtectedAPIRoutes 认证API路由
-func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
+// registerSharedAPIRoutes 通知中心和 Playground 对话路由，viewer 也可以标记、清理通知和保存对话
+func registerSharedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
+	// 通知中心
+	notifications := api.Group("/notifications")
+	{
+		notifications.GET("", serverHandler.ListNotifications)
+		notifications.GET("/unread-count", serverHandler.GetUnreadNotificationCount)
+		notifications.POST("/read", serverHandler.MarkNotificationsRead)
+		notifications.POST("/read-all", serverHandler.MarkAllNotificationsRead)
+		notifications.DELETE("/read", serverHandler.ClearReadNotifications)
+		notifications.DELETE("/:id", serverHandler.DeleteNotification)
+	}
+
+	// Playground 对话
+	conversations := api.Group("/playground/conversations")
+	{
+		conversations.GET("", serverHandler.ListPlaygroundConversations)
+		conversations.POST("", serverHandler.CreatePlaygroundConversation)
+		conversations.GET("/:id", serverHandler.GetPlaygroundConversation)
+		conversations.PUT("/:id", serverHandler.UpdatePlaygroundConversation)
+		conversations.DELETE("/:id", serverHandler.DeletePlaygroundConversation)
+	}
+}
+
+// registerProtectedAPIRoutes 认证API路由，secondFactor 保护删除、清空、导出密钥等破坏性操作
+func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server, secondFactor gin.HandlerFunc) {
 	api.GET("/channel-types", serverHandler.CommonHandler.GetChannelTypes)
+	api.GET("/channel-presets", serverHandler.CommonHandler.GetChannelPresets)
 
 	groups := api.Group("/groups")
 	{
@@ -116,10 +194,18 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		groups.GET("", serverHandler.ListGroups)
 		groups.GET("/list", serverHandler.List)
 		groups.GET("/config-options", serverHandler.GetGroupConfigOptions)
+		groups.PUT("/bulk-config", serverHandler.BulkUpdateGroupConfig)
 		groups.PUT("/:id", serverHandler.UpdateGroup)
-		groups.DELETE("/:id", serverHandler.DeleteGroup)
+		groups.PATCH("/:id", serverHandler.PatchGroup)
+		groups.DELETE("/:id", secondFactor, serverHandler.DeleteGroup)
+		groups.PUT("/:id/team", middleware.RequireRole(models.RoleAdmin), serverHandler.AssignGroupTeam)
 		groups.GET("/:id/stats", serverHandler.GetGroupStats)
 		groups.POST("/:id/copy", serverHandler.CopyGroup)
+		groups.POST("/:id/sandbox-keys", serverHandler.CreateSandboxKey)
+		groups.POST("/:id/proxy-check", serverHandler.CheckGroupProxy)
+		groups.GET("/:id/ip-access", serverHandler.GetIPAccess)
+		groups.PUT("/:id/ip-access", serverHandler.UpdateIPAccess)
+		groups.DELETE("/:id/ip-access", serverHandler.DeleteIPAccess)
 
 		groups.GET("/:id/sub-groups", serverHandler.GetSubGroups)
 		groups.POST("/:id/sub-groups", serverHandler.AddSubGroups)
@@ -132,73 +218,211 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	keys := api.Group("/keys")
 	{
 		keys.GET("", serverHandler.ListKeysInGroup)
-		keys.GET("/export", serverHandler.ExportKeys)
+		keys.GET("/export", middleware.RequireRole(models.RoleOperator), secondFactor, serverHandler.ExportKeys)
 		keys.POST("/add-multiple", serverHandler.AddMultipleKeys)
 		keys.POST("/add-async", serverHandler.AddMultipleKeysAsync)
-		keys.POST("/delete-multiple", serverHandler.DeleteMultipleKeys)
-		keys.POST("/delete-async", serverHandler.DeleteMultipleKeysAsync)
+		keys.POST("/delete-multiple", secondFactor, serverHandler.DeleteMultipleKeys)
+		keys.POST("/delete-async", secondFactor, serverHandler.DeleteMultipleKeysAsync)
 		keys.POST("/restore-multiple", serverHandler.RestoreMultipleKeys)
 		keys.POST("/restore-all-invalid", serverHandler.RestoreAllInvalidKeys)
-		keys.POST("/clear-all-invalid", serverHandler.ClearAllInvalidKeys)
-		keys.POST("/clear-all", serverHandler.ClearAllKeys)
+		keys.POST("/set-tier", serverHandler.SetKeysTier)
+		keys.POST("/clear-all-invalid", secondFactor, serverHandler.ClearAllInvalidKeys)
+		keys.POST("/clear-all", secondFactor, serverHandler.ClearAllKeys)
 		keys.POST("/validate-group", serverHandler.ValidateGroupKeys)
 		keys.POST("/test-multiple", serverHandler.TestMultipleKeys)
+		keys.GET("/:id/history", serverHandler.GetKeyHistory)
 		keys.PUT("/:id/notes", serverHandler.UpdateKeyNotes)
+		keys.PUT("/:id/expiry", serverHandler.UpdateKeyExpiry)
+		keys.PUT("/:id/limits", serverHandler.UpdateKeyLimits)
+		keys.PUT("/:id/tier", serverHandler.UpdateKeyTier)
 	}
 
 	// Model Management Routes
-	models := api.Group("/models")
+	modelRoutes := api.Group("/models")
 	{
-		models.POST("/fetch", serverHandler.FetchModels)
-		models.GET("/group/:groupId", serverHandler.ListModels)
-		models.GET("/:modelId", serverHandler.GetModel)
-		models.PUT("/:modelId", serverHandler.UpdateModel)
-		models.DELETE("/:modelId", serverHandler.DeleteModel)
-		models.POST("/group/:groupId/refresh", serverHandler.RefreshModels)
+		modelRoutes.POST("/fetch", serverHandler.FetchModels)
+		modelRoutes.GET("/group/:groupId", serverHandler.ListModels)
+		modelRoutes.POST("/group/:groupId", serverHandler.CreateModel)
+		modelRoutes.PUT("/group/:groupId/bulk", serverHandler.BulkUpdateModels)
+		modelRoutes.GET("/:modelId", serverHandler.GetModel)
+		modelRoutes.PUT("/:modelId", serverHandler.UpdateModel)
+		modelRoutes.DELETE("/:modelId", serverHandler.DeleteModel)
+		modelRoutes.POST("/group/:groupId/refresh", serverHandler.RefreshModels)
+		modelRoutes.POST("/group/:groupId/refresh-async", serverHandler.RefreshModelsAsync)
+		modelRoutes.GET("/group/:groupId/aliases", serverHandler.ListModelAliases)
+		modelRoutes.PUT("/group/:groupId/aliases", serverHandler.UpdateModelAliases)
+		modelRoutes.GET("/group/:groupId/access", serverHandler.GetModelAccess)
+		modelRoutes.PUT("/group/:groupId/access", serverHandler.UpdateModelAccess)
+		modelRoutes.DELETE("/group/:groupId/access", serverHandler.DeleteModelAccess)
 	}
 
 	// Tasks
 	api.GET("/tasks/status", serverHandler.GetTaskStatus)
 
+	// Background jobs
+	jobs := api.Group("/jobs")
+	{
+		jobs.GET("", serverHandler.ListJobs)
+		jobs.GET("/:id", serverHandler.GetJob)
+	}
+
+	// Alert rules
+	alerts := api.Group("/alerts")
+	{
+		alerts.GET("", serverHandler.ListAlertRules)
+		alerts.POST("", serverHandler.CreateAlertRule)
+		alerts.PUT("/:id", serverHandler.UpdateAlertRule)
+		alerts.DELETE("/:id", serverHandler.DeleteAlertRule)
+		alerts.POST("/:id/test", serverHandler.TestAlertRule)
+	}
+
+	// Webhook 事件订阅
+	webhooks := api.Group("/webhooks")
+	webhooks.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		webhooks.GET("", serverHandler.ListWebhooks)
+		webhooks.POST("", serverHandler.CreateWebhook)
+		webhooks.PUT("/:id", serverHandler.UpdateWebhook)
+		webhooks.DELETE("/:id", serverHandler.DeleteWebhook)
+		webhooks.POST("/:id/test", serverHandler.TestWebhook)
+	}
+
 	// 仪表板和日志
 	dashboard := api.Group("/dashboard")
 	{
 		dashboard.GET("/stats", serverHandler.Stats)
 		dashboard.GET("/chart", serverHandler.Chart)
+		dashboard.GET("/usage", serverHandler.Usage)
 		dashboard.GET("/encryption-status", serverHandler.EncryptionStatus)
 	}
 
+	// Metrics snapshot for clients that cannot scrape /metrics
+	api.GET("/metrics/snapshot", serverHandler.GetMetricsSnapshot)
+
 	// 日志
 	logs := api.Group("/logs")
 	{
 		logs.GET("", serverHandler.GetLogs)
-		logs.GET("/export", serverHandler.ExportLogs)
+		logs.GET("/export", middleware.RequireRole(models.RoleOperator), serverHandler.ExportLogs)
+		logs.GET("/stream", serverHandler.StreamLogs)
+		logs.GET("/usage-export", serverHandler.ExportUsage)
+		logs.GET("/trace", serverHandler.GetRequestTrace)
+		logs.GET("/transcripts", serverHandler.ListStreamTranscripts)
+		logs.GET("/transcripts/:id", serverHandler.GetStreamTranscript)
 	}
 
 	// 设置
 	settings := api.Group("/settings")
 	{
 		settings.GET("", serverHandler.GetSettings)
-		settings.PUT("", serverHandler.UpdateSettings)
+		settings.PUT("", middleware.RequireRole(models.RoleAdmin), serverHandler.UpdateSettings)
+	}
+
+	// 配置版本
+	configVersions := api.Group("/config-versions")
+	{
+		configVersions.GET("", serverHandler.ListConfigVersions)
+		configVersions.GET("/:id", serverHandler.GetConfigVersion)
+		configVersions.GET("/:id/diff", serverHandler.DiffConfigVersions)
+		configVersions.POST("/:id/rollback", middleware.RequireRole(models.RoleAdmin), secondFactor, serverHandler.RollbackConfigVersion)
+	}
+
+	// 配置备份与恢复
+	backup := api.Group("/backup")
+	backup.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		backup.POST("/export", secondFactor, serverHandler.ExportConfig)
+		backup.POST("/import", secondFactor, serverHandler.ImportConfig)
+	}
+
+	// 加密密钥轮换
+	encryption := api.Group("/encryption")
+	encryption.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		encryption.GET("/rotation", serverHandler.GetEncryptionRotation)
+		encryption.POST("/rotation", serverHandler.StartEncryptionRotation)
+	}
+
+	// 用户管理
+	users := api.Group("/users")
+	users.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		users.GET("", serverHandler.ListUsers)
+		users.POST("", serverHandler.CreateUser)
+		users.PUT("/:id", serverHandler.UpdateUser)
+		users.DELETE("/:id", secondFactor, serverHandler.DeleteUser)
+		users.DELETE("/:id/2fa", secondFactor, serverHandler.ResetUserTOTP)
+	}
+
+	// 团队
+	teams := api.Group("/teams")
+	teams.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		teams.GET("", serverHandler.ListTeams)
+		teams.POST("", serverHandler.CreateTeam)
+		teams.PUT("/:id", serverHandler.UpdateTeam)
+		teams.DELETE("/:id", serverHandler.DeleteTeam)
+	}
+
+	// 运行时诊断
+	system := api.Group("/system")
+	system.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		system.GET("/runtime", serverHandler.GetRuntimeInfo)
+	}
+
+	// 配置诊断
+	admin := api.Group("/admin")
+	{
+		admin.GET("/advisor", serverHandler.GetAdvisorReport)
 	}
 
 	// 测试环境 (Playground)
 	playground := api.Group("/playground")
 	{
 		playground.POST("/chat", serverHandler.PlaygroundChat)
+		playground.POST("/embeddings", serverHandler.PlaygroundEmbeddings)
 	}
 }
 
+// registerPprofRoutes 注册 pprof 性能分析路由，仅管理员可访问
+func registerPprofRoutes(router *gin.Engine, userService *services.UserService, oidcService *services.OIDCService) {
+	debug := router.Group("/debug/pprof")
+	debug.Use(i18n.Middleware())
+	debug.Use(middleware.Auth(userService, oidcService))
+	debug.Use(middleware.RequireRole(models.RoleAdmin))
+
+	debug.GET("/*name", func(c *gin.Context) {
+		switch c.Param("name") {
+		case "/cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "/profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "/symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "/trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			// Index serves the named profiles (heap, goroutine, ...) and the profile list
+			pprof.Index(c.Writer, c.Request)
+		}
+	})
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+}
+
 // registerProxyRoutes 注册代理路由
 func registerProxyRoutes(
 	router *gin.Engine,
 	proxyServer *proxy.ProxyServer,
 	groupManager *services.GroupManager,
+	pluginManager *plugin.Manager,
 	serverHandler *handler.Server,
 ) {
 	proxyGroup := router.Group("/proxy/:group_name")
 
+	proxyGroup.Use(proxyServer.DrainGuard())
 	proxyGroup.Use(middleware.ProxyRouteDispatcher(serverHandler))
+	proxyGroup.Use(middleware.PluginPreAuth(pluginManager))
 	proxyGroup.Use(middleware.ProxyAuth(groupManager))
 
 	proxyGroup.Any("/*path", proxyServer.HandleProxy)
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// Advisor finding severities, ordered from most to least urgent.
+const (
+	AdvisorSeverityCritical = "critical"
+	AdvisorSeverityWarning  = "warning"
+	AdvisorSeverityInfo     = "info"
+)
+
+const (
+	// advisorHighTrafficRequests is the 24h request count above which a group counts as high-traffic.
+	advisorHighTrafficRequests = 1000
+	// advisorMaxRequestTimeout and advisorMaxConnectTimeout are the timeouts (seconds) above which
+	// stuck upstreams hold connections and queue slots for too long.
+	advisorMaxRequestTimeout = 1800
+	advisorMaxConnectTimeout = 60
+)
+
+var advisorSeverityRank = map[string]int{
+	AdvisorSeverityCritical: 0,
+	AdvisorSeverityWarning:  1,
+	AdvisorSeverityInfo:     2,
+}
+
+// AdvisorFinding is a single actionable issue found in the configuration. Code selects the
+// translated message and fix hint; Params fills their placeholders.
+type AdvisorFinding struct {
+	Code      string         `json:"code"`
+	Severity  string         `json:"severity"`
+	GroupID   uint           `json:"group_id,omitempty"`
+	GroupName string         `json:"group_name,omitempty"`
+	Params    map[string]any `json:"params,omitempty"`
+}
+
+// AdvisorService inspects groups and system settings for risky or ineffective configuration.
+type AdvisorService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	groupManager    *GroupManager
+}
+
+// NewAdvisorService creates a new AdvisorService.
+func NewAdvisorService(db *gorm.DB, settingsManager *config.SystemSettingsManager, groupManager *GroupManager) *AdvisorService {
+	return &AdvisorService{
+		db:              db,
+		settingsManager: settingsManager,
+		groupManager:    groupManager,
+	}
+}
+
+// advisorGroupFacts are the per-group numbers the checks need, loaded in bulk.
+type advisorGroupFacts struct {
+	activeKeys   map[uint]int64
+	requests24h  map[uint]int64
+	subGroupIDs  map[uint]bool
+	groupsByName map[string]bool
+}
+
+// Analyze runs every check and returns the findings ordered by severity and group.
+func (s *AdvisorService) Analyze(ctx context.Context) ([]AdvisorFinding, error) {
+	var groups []models.Group
+	if err := s.db.WithContext(ctx).Order("sort asc, id desc").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	facts, err := s.loadFacts(ctx, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := checkTimeouts(s.settingsManager.GetSettings(), nil)
+	for i := range groups {
+		group := &groups[i]
+		if cached, err := s.groupManager.GetGroupByName(group.Name); err == nil {
+			group = cached
+		}
+		if group.GroupType == "aggregate" {
+			findings = append(findings, checkAggregateGroup(group)...)
+		} else {
+			findings = append(findings, checkStandardGroup(group, facts)...)
+		}
+		findings = append(findings, checkGroupReferences(group, facts)...)
+		findings = append(findings, checkTimeouts(group.EffectiveConfig, group)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return advisorSeverityRank[findings[i].Severity] < advisorSeverityRank[findings[j].Severity]
+	})
+	return findings, nil
+}
+
+// loadFacts loads key counts, recent traffic and aggregate membership for all groups.
+func (s *AdvisorService) loadFacts(ctx context.Context, groups []models.Group) (*advisorGroupFacts, error) {
+	facts := &advisorGroupFacts{
+		activeKeys:   make(map[uint]int64),
+		requests24h:  make(map[uint]int64),
+		subGroupIDs:  make(map[uint]bool),
+		groupsByName: make(map[string]bool, len(groups)),
+	}
+	for _, group := range groups {
+		facts.groupsByName[group.Name] = true
+	}
+
+	var keyCounts []struct {
+		GroupID uint
+		Count   int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Select("group_id, COUNT(*) as count").
+		Where("status = ?", models.KeyStatusActive).
+		Group("group_id").
+		Scan(&keyCounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active keys: %w", err)
+	}
+	for _, row := range keyCounts {
+		facts.activeKeys[row.GroupID] = row.Count
+	}
+
+	var traffic []struct {
+		GroupID uint
+		Total   int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.GroupHourlyStat{}).
+		Select("group_id, SUM(success_count + failure_count) as total").
+		Where("time >= ?", time.Now().Add(-24*time.Hour)).
+		Group("group_id").
+		Scan(&traffic).Error; err != nil {
+		return nil, fmt.Errorf("failed to load request counts: %w", err)
+	}
+	for _, row := range traffic {
+		facts.requests24h[row.GroupID] = row.Total
+	}
+
+	var memberIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.GroupSubGroup{}).
+		Distinct("sub_group_id").
+		Pluck("sub_group_id", &memberIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load aggregate memberships: %w", err)
+	}
+	for _, id := range memberIDs {
+		facts.subGroupIDs[id] = true
+	}
+
+	return facts, nil
+}
+
+func newGroupFinding(group *models.Group, code, severity string, params map[string]any) AdvisorFinding {
+	return AdvisorFinding{
+		Code:      code,
+		Severity:  severity,
+		GroupID:   group.ID,
+		GroupName: group.Name,
+		Params:    params,
+	}
+}
+
+// checkStandardGroup covers keys, validation and failover of a group that talks to upstreams itself.
+func checkStandardGroup(group *models.Group, facts *advisorGroupFacts) []AdvisorFinding {
+	var findings []AdvisorFinding
+	cfg := group.EffectiveConfig
+
+	activeKeys := facts.activeKeys[group.ID]
+	requests := facts.requests24h[group.ID]
+	switch {
+	case activeKeys == 0:
+		findings = append(findings, newGroupFinding(group, "no_active_keys", AdvisorSeverityCritical, nil))
+	case activeKeys == 1 && requests >= advisorHighTrafficRequests:
+		findings = append(findings, newGroupFinding(group, "single_key_high_traffic", AdvisorSeverityWarning,
+			map[string]any{"requests": requests}))
+	}
+
+	if group.TestModel == "" {
+		findings = append(findings, newGroupFinding(group, "missing_test_model", AdvisorSeverityWarning, nil))
+	}
+
+	if cfg.BlacklistThreshold == 0 {
+		findings = append(findings, newGroupFinding(group, "validation_disabled", AdvisorSeverityWarning, nil))
+	}
+
+	var upstreams []models.UpstreamDefinition
+	_ = json.Unmarshal(group.Upstreams, &upstreams)
+	if len(upstreams) <= 1 && !facts.subGroupIDs[group.ID] {
+		findings = append(findings, newGroupFinding(group, "no_fallback", AdvisorSeverityInfo, nil))
+	}
+
+	return findings
+}
+
+// checkAggregateGroup flags aggregate groups that cannot route to any sub-group.
+func checkAggregateGroup(group *models.Group) []AdvisorFinding {
+	for _, sg := range group.SubGroups {
+		if sg.Weight > 0 {
+			return nil
+		}
+	}
+	return []AdvisorFinding{newGroupFinding(group, "aggregate_without_sub_groups", AdvisorSeverityCritical, nil)}
+}
+
+// checkGroupReferences flags fallback and canary groups that do not exist, and schedules without a fallback.
+func checkGroupReferences(group *models.Group, facts *advisorGroupFacts) []AdvisorFinding {
+	var findings []AdvisorFinding
+	cfg := group.EffectiveConfig
+
+	references := []struct{ setting, target string }{
+		{"traffic_schedule_fallback_group", cfg.TrafficScheduleFallbackGroup},
+		{"canary_group", cfg.CanaryGroup},
+	}
+	for _, ref := range references {
+		if ref.target != "" && !facts.groupsByName[ref.target] {
+			findings = append(findings, newGroupFinding(group, "missing_group_reference", AdvisorSeverityCritical,
+				map[string]any{"setting": ref.setting, "target": ref.target}))
+		}
+	}
+
+	if cfg.TrafficSchedule != "" && cfg.TrafficScheduleFallbackGroup == "" {
+		findings = append(findings, newGroupFinding(group, "schedule_without_fallback", AdvisorSeverityWarning, nil))
+	}
+
+	return findings
+}
+
+// checkTimeouts flags oversized timeouts in the system settings, or in a group's own overrides
+// when group is given, so that a system-wide value is reported once.
+func checkTimeouts(cfg types.SystemSettings, group *models.Group) []AdvisorFinding {
+	overridden := func(key string) bool {
+		if group == nil {
+			return true
+		}
+		_, ok := group.Config[key]
+		return ok
+	}
+
+	var findings []AdvisorFinding
+	add := func(setting string, value, limit int) {
+		finding := AdvisorFinding{
+			Code:     "oversized_timeout",
+			Severity: AdvisorSeverityInfo,
+			Params:   map[string]any{"setting": setting, "value": value, "limit": limit},
+		}
+		if group != nil {
+			finding.GroupID = group.ID
+			finding.GroupName = group.Name
+		}
+		findings = append(findings, finding)
+	}
+
+	if cfg.RequestTimeout > advisorMaxRequestTimeout && overridden("request_timeout") {
+		add("request_timeout", cfg.RequestTimeout, advisorMaxRequestTimeout)
+	}
+	if cfg.ConnectTimeout > advisorMaxConnectTimeout && overridden("connect_timeout") {
+		add("connect_timeout", cfg.ConnectTimeout, advisorMaxConnectTimeout)
+	}
+	if cfg.ResponseHeaderTimeout > cfg.RequestTimeout && overridden("response_header_timeout") {
+		add("response_header_timeout", cfg.ResponseHeaderTimeout, cfg.RequestTimeout)
+	}
+	return findings
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"gpt-load/internal/models"
+)
+
+// telegramAPIBase is the Telegram bot API endpoint.
+const telegramAPIBase = "https://api.telegram.org"
+
+// Message length limits of the chat services; longer alert texts are cut.
+const (
+	discordMaxContent = 2000
+	telegramMaxText   = 4096
+)
+
+// telegramBotTokenPattern matches the tokens issued by BotFather, e.g. 123456:ABC-DEF.
+var telegramBotTokenPattern = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]+$`)
+
+// AlertChannels lists the supported alert delivery channels.
+var AlertChannels = []string{
+	models.AlertChannelWebhook,
+	models.AlertChannelSlack,
+	models.AlertChannelDiscord,
+	models.AlertChannelTelegram,
+}
+
+// alertTargetConfigured reports whether a rule has somewhere to deliver its alerts.
+func alertTargetConfigured(rule *models.AlertRule) bool {
+	if rule.Channel == models.AlertChannelTelegram {
+		return rule.TelegramBotToken != "" && rule.TelegramChatID != ""
+	}
+	return rule.WebhookURL != ""
+}
+
+// alertText formats a payload as a short chat message.
+func alertText(payload AlertPayload) string {
+	title := fmt.Sprintf("[%s] %s", strings.ToUpper(payload.Status), payload.Rule)
+	if payload.Group != "" {
+		title += " · " + payload.Group
+	}
+	return title + "\n" + payload.Message
+}
+
+// truncateText cuts text to at most limit runes.
+func truncateText(text string, limit int) string {
+	if runes := []rune(text); len(runes) > limit {
+		return string(runes[:limit-1]) + "…"
+	}
+	return text
+}
+
+// renderAlertBody renders the JSON body a rule sends for a payload. Slack and Discord use the payload
+// template when one is set, so that richer messages can be built; Telegram always sends plain text.
+func renderAlertBody(rule *models.AlertRule, payload AlertPayload) ([]byte, error) {
+	switch rule.Channel {
+	case models.AlertChannelSlack:
+		if rule.PayloadTemplate == "" {
+			return json.Marshal(map[string]string{"text": alertText(payload)})
+		}
+	case models.AlertChannelDiscord:
+		if rule.PayloadTemplate == "" {
+			return json.Marshal(map[string]string{"content": truncateText(alertText(payload), discordMaxContent)})
+		}
+	case models.AlertChannelTelegram:
+		return json.Marshal(map[string]any{
+			"chat_id":                  rule.TelegramChatID,
+			"text":                     truncateText(alertText(payload), telegramMaxText),
+			"disable_web_page_preview": true,
+		})
+	}
+	return renderAlertPayload(rule.PayloadTemplate, payload)
+}
+
+// deliverAlert sends a payload through the rule's channel.
+func deliverAlert(ctx context.Context, client *http.Client, rule *models.AlertRule, payload AlertPayload) error {
+	body, err := renderAlertBody(rule, payload)
+	if err != nil {
+		return err
+	}
+	if rule.Channel == models.AlertChannelTelegram {
+		return sendTelegramMessage(ctx, client, rule.TelegramBotToken, body)
+	}
+	return postAlertWebhook(ctx, client, rule.WebhookURL, body)
+}
+
+// sendTelegramMessage calls the bot API's sendMessage. The token is part of the URL, so it is
+// removed from errors before they are logged or shown.
+func sendTelegramMessage(ctx context.Context, client *http.Client, token string, body []byte) error {
+	err := postAlertWebhook(ctx, client, telegramAPIBase+"/bot"+token+"/sendMessage", body)
+	if err != nil {
+		return errors.New(strings.ReplaceAll(err.Error(), token, "<token>"))
+	}
+	return nil
+}
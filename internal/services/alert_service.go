@@ -0,0 +1,591 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// alertEvaluationInterval is how often the enabled alert rules are evaluated.
+const alertEvaluationInterval = time.Minute
+
+// AlertRuleTypes lists the supported alert rule types.
+var AlertRuleTypes = []string{
+	models.AlertTypeErrorRate,
+	models.AlertTypeAllKeysInvalid,
+	models.AlertTypeQuotaExceeded,
+	models.AlertTypeUpstreamCircuitOpen,
+	models.AlertTypeKeyInvalidated,
+	models.AlertTypeKeyRecovered,
+}
+
+// alertKeyEvents maps the notification events of keys to the rule types watching them.
+var alertKeyEvents = map[string]string{
+	notification.EventKeyDisabled:  models.AlertTypeKeyInvalidated,
+	notification.EventKeyRecovered: models.AlertTypeKeyRecovered,
+	notification.EventKeyExpired:   models.AlertTypeKeyInvalidated,
+}
+
+// AlertRuleParams are the editable fields of an alert rule.
+type AlertRuleParams struct {
+	Name             string
+	Type             string
+	GroupID          *uint
+	Threshold        float64
+	WindowMinutes    int
+	MinRequests      int
+	RepeatMinutes    int
+	Channel          string
+	WebhookURL       string
+	TelegramBotToken string
+	TelegramChatID   string
+	PayloadTemplate  string
+	Enabled          bool
+}
+
+// alertStateKey identifies the evaluation of a rule for one group.
+type alertStateKey struct {
+	ruleID  uint
+	groupID uint
+}
+
+// alertState remembers whether an alert is firing, so that only changes are sent.
+type alertState struct {
+	firing   bool
+	lastSent time.Time
+}
+
+// alertCheck is the outcome of evaluating a rule for a group.
+type alertCheck struct {
+	firing  bool
+	value   float64
+	message string
+}
+
+// AlertService manages alert rules and, on the master node, evaluates them periodically. A rule
+// notifies its channel and the notification center when it starts firing for a group and when it
+// resolves. Alert states are kept in memory, so alerts still firing are sent again after a restart.
+// Key rules are not evaluated but follow the key notifications of every node as they are recorded.
+type AlertService struct {
+	db                  *gorm.DB
+	groupManager        *GroupManager
+	quotaService        *QuotaService
+	channelFactory      *channel.Factory
+	notificationService *notification.Service
+	client              *http.Client
+	states              map[alertStateKey]*alertState
+	stopCh              chan struct{}
+	wg                  sync.WaitGroup
+}
+
+// NewAlertService creates a new AlertService.
+func NewAlertService(
+	db *gorm.DB,
+	groupManager *GroupManager,
+	quotaService *QuotaService,
+	channelFactory *channel.Factory,
+	notificationService *notification.Service,
+) *AlertService {
+	s := &AlertService{
+		db:                  db,
+		groupManager:        groupManager,
+		quotaService:        quotaService,
+		channelFactory:      channelFactory,
+		notificationService: notificationService,
+		client:              &http.Client{},
+		states:              make(map[alertStateKey]*alertState),
+		stopCh:              make(chan struct{}),
+	}
+	notificationService.AddListener(s.handleEvent)
+	return s
+}
+
+// ListRules returns all alert rules.
+func (s *AlertService) ListRules() ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := s.db.Order("id asc").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreateRule validates and stores a new alert rule.
+func (s *AlertService) CreateRule(params AlertRuleParams) (*models.AlertRule, error) {
+	rule := &models.AlertRule{}
+	if err := s.applyRuleParams(rule, params); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return rule, nil
+}
+
+// UpdateRule validates and replaces the fields of an alert rule.
+func (s *AlertService) UpdateRule(id uint, params AlertRuleParams) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	if err := s.applyRuleParams(&rule, params); err != nil {
+		return nil, err
+	}
+	if err := s.db.Select("*").Omit("created_at").Save(&rule).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &rule, nil
+}
+
+// DeleteRule removes an alert rule.
+func (s *AlertService) DeleteRule(id uint) error {
+	result := s.db.Delete(&models.AlertRule{}, id)
+	if result.Error != nil {
+		return app_errors.ParseDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return app_errors.ErrResourceNotFound
+	}
+	return nil
+}
+
+// TestRule sends a test payload through the rule's channel and returns the delivery error, if any.
+func (s *AlertService) TestRule(ctx context.Context, id uint) error {
+	var rule models.AlertRule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	if !alertTargetConfigured(&rule) {
+		return NewI18nError(app_errors.ErrValidation, "validation.alert_target_required", nil)
+	}
+
+	payload := AlertPayload{
+		Rule:      rule.Name,
+		RuleID:    rule.ID,
+		Type:      rule.Type,
+		Status:    AlertStatusTest,
+		Threshold: rule.Threshold,
+		Message:   fmt.Sprintf("Test notification for alert rule '%s'", rule.Name),
+		Time:      time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := deliverAlert(ctx, s.client, &rule, payload); err != nil {
+		return NewI18nError(app_errors.ErrBadGateway, "alert.test_failed", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// applyRuleParams validates params and copies them onto rule.
+func (s *AlertService) applyRuleParams(rule *models.AlertRule, params AlertRuleParams) error {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return NewI18nError(app_errors.ErrValidation, "validation.alert_name_required", nil)
+	}
+	if !slices.Contains(AlertRuleTypes, params.Type) {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_alert_type", map[string]any{"types": strings.Join(AlertRuleTypes, ", ")})
+	}
+
+	switch params.Type {
+	case models.AlertTypeErrorRate:
+		if params.WindowMinutes < 1 || params.WindowMinutes > 1440 {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_alert_window", nil)
+		}
+		fallthrough
+	case models.AlertTypeQuotaExceeded:
+		if params.Threshold <= 0 || params.Threshold > 100 {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_alert_threshold", nil)
+		}
+	}
+	if params.MinRequests < 0 || params.RepeatMinutes < 0 {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_alert_limits", nil)
+	}
+
+	if params.GroupID != nil {
+		var count int64
+		if err := s.db.Model(&models.Group{}).Where("id = ?", *params.GroupID).Count(&count).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		if count == 0 {
+			return NewI18nError(app_errors.ErrValidation, "validation.group_not_found", nil)
+		}
+	}
+
+	deliveryChannel := params.Channel
+	if deliveryChannel == "" {
+		deliveryChannel = models.AlertChannelWebhook
+	}
+	if !slices.Contains(AlertChannels, deliveryChannel) {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_alert_channel", map[string]any{"channels": strings.Join(AlertChannels, ", ")})
+	}
+
+	// Only the fields of the selected channel are kept
+	var webhookURL, botToken, chatID, payloadTemplate string
+	if deliveryChannel == models.AlertChannelTelegram {
+		botToken = strings.TrimSpace(params.TelegramBotToken)
+		chatID = strings.TrimSpace(params.TelegramChatID)
+		if (botToken == "") != (chatID == "") || (botToken != "" && !telegramBotTokenPattern.MatchString(botToken)) {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_telegram_target", nil)
+		}
+	} else {
+		webhookURL = strings.TrimSpace(params.WebhookURL)
+		if webhookURL != "" {
+			parsed, err := url.Parse(webhookURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return NewI18nError(app_errors.ErrValidation, "validation.invalid_webhook_url", nil)
+			}
+		}
+		payloadTemplate = params.PayloadTemplate
+		if _, err := renderAlertPayload(payloadTemplate, AlertPayload{Status: AlertStatusTest}); err != nil {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_payload_template", map[string]any{"error": err.Error()})
+		}
+	}
+
+	// Key events only reach the notification center through their own notifications, so a key rule
+	// without a channel would do nothing
+	isKeyRule := params.Type == models.AlertTypeKeyInvalidated || params.Type == models.AlertTypeKeyRecovered
+	if isKeyRule && webhookURL == "" && botToken == "" {
+		return NewI18nError(app_errors.ErrValidation, "validation.alert_target_required", nil)
+	}
+
+	rule.Name = name
+	rule.Type = params.Type
+	rule.GroupID = params.GroupID
+	rule.Threshold = params.Threshold
+	rule.WindowMinutes = params.WindowMinutes
+	rule.MinRequests = params.MinRequests
+	rule.RepeatMinutes = params.RepeatMinutes
+	rule.Channel = deliveryChannel
+	rule.WebhookURL = webhookURL
+	rule.TelegramBotToken = botToken
+	rule.TelegramChatID = chatID
+	rule.PayloadTemplate = payloadTemplate
+	rule.Enabled = params.Enabled
+	return nil
+}
+
+// Start begins evaluating alert rules in the background.
+func (s *AlertService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Alert service started")
+}
+
+// Stop waits for the current evaluation to finish.
+func (s *AlertService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("AlertService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("AlertService stop timed out.")
+	}
+}
+
+func (s *AlertService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(alertEvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evaluate()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// evaluate checks every enabled rule against the groups it covers and notifies about changes.
+func (s *AlertService) evaluate() {
+	var rules []models.AlertRule
+	if err := s.db.Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		logrus.WithError(err).Error("Failed to load alert rules")
+		return
+	}
+	if len(rules) == 0 {
+		clear(s.states)
+		return
+	}
+
+	var names []string
+	if err := s.db.Model(&models.Group{}).Order("id asc").Pluck("name", &names).Error; err != nil {
+		logrus.WithError(err).Error("Failed to load groups for alert evaluation")
+		return
+	}
+	groups := make([]*models.Group, 0, len(names))
+	for _, name := range names {
+		if group, err := s.groupManager.GetGroupByName(name); err == nil {
+			groups = append(groups, group)
+		}
+	}
+
+	now := time.Now()
+	seen := make(map[alertStateKey]bool)
+	for i := range rules {
+		rule := &rules[i]
+		for _, group := range groups {
+			if rule.GroupID != nil && *rule.GroupID != group.ID {
+				continue
+			}
+			check, ok, err := s.check(rule, group, now)
+			if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"rule": rule.Name, "group": group.Name}).Warn("Failed to evaluate alert rule")
+				continue
+			}
+			if !ok {
+				continue
+			}
+			key := alertStateKey{ruleID: rule.ID, groupID: group.ID}
+			seen[key] = true
+			s.transition(key, rule, group, check, now)
+		}
+	}
+
+	// Rules that were deleted, disabled or no longer apply are forgotten without a resolved notice
+	for key := range s.states {
+		if !seen[key] {
+			delete(s.states, key)
+		}
+	}
+}
+
+// transition notifies when an alert starts firing, repeats while it fires, and resolves.
+func (s *AlertService) transition(key alertStateKey, rule *models.AlertRule, group *models.Group, check alertCheck, now time.Time) {
+	state, ok := s.states[key]
+	if !ok {
+		state = &alertState{}
+		s.states[key] = state
+	}
+
+	switch {
+	case check.firing && !state.firing:
+		state.firing = true
+	case check.firing && rule.RepeatMinutes > 0 && now.Sub(state.lastSent) >= time.Duration(rule.RepeatMinutes)*time.Minute:
+	case !check.firing && state.firing:
+		state.firing = false
+	default:
+		return
+	}
+	state.lastSent = now
+
+	status := AlertStatusResolved
+	if check.firing {
+		status = AlertStatusFiring
+	}
+	s.notify(rule, group, status, check, now)
+}
+
+// notify records the alert in the notification center and delivers it to the rule's channel.
+func (s *AlertService) notify(rule *models.AlertRule, group *models.Group, status string, check alertCheck, now time.Time) {
+	event := notification.EventAlertFiring
+	severity := models.NotificationSeverityWarning
+	if status == AlertStatusResolved {
+		event = notification.EventAlertResolved
+		severity = models.NotificationSeveritySuccess
+	}
+	s.notificationService.Notify(notification.Event{
+		Category: models.NotificationCategoryAlert,
+		Severity: severity,
+		Event:    event,
+		Message:  check.message,
+		Params: map[string]any{
+			"rule":      rule.Name,
+			"group":     group.Name,
+			"value":     check.value,
+			"threshold": rule.Threshold,
+		},
+		GroupID:   group.ID,
+		GroupName: group.Name,
+	})
+
+	s.deliver(rule, AlertPayload{
+		Rule:      rule.Name,
+		RuleID:    rule.ID,
+		Type:      rule.Type,
+		Status:    status,
+		Group:     group.Name,
+		GroupID:   group.ID,
+		Value:     check.value,
+		Threshold: rule.Threshold,
+		Message:   check.message,
+		Time:      now.UTC().Format(time.RFC3339),
+	})
+}
+
+// deliver sends a payload through the rule's channel in the background.
+func (s *AlertService) deliver(rule *models.AlertRule, payload AlertPayload) {
+	if !alertTargetConfigured(rule) {
+		return
+	}
+	target := *rule
+	go func() {
+		if err := deliverAlert(context.Background(), s.client, &target, payload); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"rule": target.Name, "channel": target.Channel}).Warn("Failed to deliver alert")
+		}
+	}()
+}
+
+// handleEvent forwards key notifications to the enabled key rules covering their group.
+func (s *AlertService) handleEvent(e notification.Event) {
+	ruleType, ok := alertKeyEvents[e.Event]
+	if !ok {
+		return
+	}
+	status := AlertStatusFiring
+	if ruleType == models.AlertTypeKeyRecovered {
+		status = AlertStatusResolved
+	}
+	now := time.Now()
+
+	// Listeners run on the producer's goroutine, so the rules are looked up in the background
+	go func() {
+		var rules []models.AlertRule
+		if err := s.db.Where("enabled = ? AND type = ?", true, ruleType).
+			Where("group_id IS NULL OR group_id = ?", e.GroupID).
+			Find(&rules).Error; err != nil {
+			logrus.WithError(err).Warn("Failed to load key alert rules")
+			return
+		}
+		for i := range rules {
+			s.deliver(&rules[i], AlertPayload{
+				Rule:    rules[i].Name,
+				RuleID:  rules[i].ID,
+				Type:    ruleType,
+				Status:  status,
+				Group:   e.GroupName,
+				GroupID: e.GroupID,
+				Message: e.Message,
+				Time:    now.UTC().Format(time.RFC3339),
+			})
+		}
+	}()
+}
+
+// check evaluates a rule for a group. ok is false when the rule does not apply to the group.
+func (s *AlertService) check(rule *models.AlertRule, group *models.Group, now time.Time) (alertCheck, bool, error) {
+	switch rule.Type {
+	case models.AlertTypeErrorRate:
+		return s.checkErrorRate(rule, group, now)
+	case models.AlertTypeAllKeysInvalid:
+		return s.checkAllKeysInvalid(group)
+	case models.AlertTypeQuotaExceeded:
+		return s.checkQuota(rule, group)
+	case models.AlertTypeUpstreamCircuitOpen:
+		return s.checkUpstreams(group)
+	}
+	return alertCheck{}, false, nil
+}
+
+// checkErrorRate compares the share of failed final requests in the window with the threshold.
+// Requests of an aggregate group are counted through its sub groups.
+func (s *AlertService) checkErrorRate(rule *models.AlertRule, group *models.Group, now time.Time) (alertCheck, bool, error) {
+	var result struct {
+		Total    int64
+		Failures int64
+	}
+	if err := s.db.Model(&models.RequestLog{}).
+		Select("COUNT(*) AS total, COUNT(CASE WHEN is_success = ? THEN 1 END) AS failures", false).
+		Where("request_type = ? AND timestamp >= ?", models.RequestTypeFinal, now.Add(-time.Duration(rule.WindowMinutes)*time.Minute)).
+		Where("group_id = ? OR parent_group_id = ?", group.ID, group.ID).
+		Scan(&result).Error; err != nil {
+		return alertCheck{}, false, err
+	}
+
+	var rate float64
+	if result.Total > 0 {
+		rate = float64(result.Failures) / float64(result.Total) * 100
+	}
+	return alertCheck{
+		firing: result.Total > 0 && result.Total >= int64(rule.MinRequests) && rate > rule.Threshold,
+		value:  rate,
+		message: fmt.Sprintf("Error rate of group '%s' is %.1f%% over the last %d minutes (%d of %d requests failed, threshold %.1f%%)",
+			group.Name, rate, rule.WindowMinutes, result.Failures, result.Total, rule.Threshold),
+	}, true, nil
+}
+
+// checkAllKeysInvalid fires when a standard group has keys but none of them is active.
+func (s *AlertService) checkAllKeysInvalid(group *models.Group) (alertCheck, bool, error) {
+	if group.GroupType == "aggregate" {
+		return alertCheck{}, false, nil
+	}
+	var total, active int64
+	if err := s.db.Model(&models.APIKey{}).Where("group_id = ?", group.ID).Count(&total).Error; err != nil {
+		return alertCheck{}, false, err
+	}
+	if err := s.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", group.ID, models.KeyStatusActive).Count(&active).Error; err != nil {
+		return alertCheck{}, false, err
+	}
+	return alertCheck{
+		firing:  total > 0 && active == 0,
+		value:   float64(total - active),
+		message: fmt.Sprintf("Group '%s' has %d active of %d keys", group.Name, active, total),
+	}, true, nil
+}
+
+// checkQuota compares the share of the current quota cycle used with the threshold. It only applies
+// to groups with a quota cycle.
+func (s *AlertService) checkQuota(rule *models.AlertRule, group *models.Group) (alertCheck, bool, error) {
+	var active int64
+	if err := s.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", group.ID, models.KeyStatusActive).Count(&active).Error; err != nil {
+		return alertCheck{}, false, err
+	}
+	report := s.quotaService.Report(group, active)
+	if report == nil || report.Limit <= 0 {
+		return alertCheck{}, false, nil
+	}
+	usedPercent := float64(report.Used) / float64(report.Limit) * 100
+	return alertCheck{
+		firing: usedPercent >= rule.Threshold,
+		value:  usedPercent,
+		message: fmt.Sprintf("Group '%s' used %d of its %d %s quota (%.1f%%, threshold %.1f%%), the cycle resets at %s",
+			group.Name, report.Used, report.Limit, report.Unit, usedPercent, rule.Threshold, report.CycleEnd.UTC().Format(time.RFC3339)),
+	}, true, nil
+}
+
+// checkUpstreams fires while any upstream of the group keeps failing, as seen by this node.
+func (s *AlertService) checkUpstreams(group *models.Group) (alertCheck, bool, error) {
+	if group.GroupType == "aggregate" {
+		return alertCheck{}, false, nil
+	}
+	var open []string
+	for _, stats := range s.channelFactory.UpstreamLatency(group.ID) {
+		if !stats.CircuitOpen {
+			continue
+		}
+		upstream := stats.Upstream
+		if parsed, err := url.Parse(upstream); err == nil && parsed.Host != "" {
+			upstream = parsed.Scheme + "://" + parsed.Host
+		}
+		if !slices.Contains(open, upstream) {
+			open = append(open, upstream)
+		}
+	}
+	message := fmt.Sprintf("All upstreams of group '%s' are healthy", group.Name)
+	if len(open) > 0 {
+		message = fmt.Sprintf("Upstreams of group '%s' keep failing: %s", group.Name, strings.Join(open, ", "))
+	}
+	return alertCheck{
+		firing:  len(open) > 0,
+		value:   float64(len(open)),
+		message: message,
+	}, true, nil
+}
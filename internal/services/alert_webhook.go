@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// alertWebhookTimeout bounds a single webhook delivery.
+const alertWebhookTimeout = 10 * time.Second
+
+// Alert statuses sent in webhook payloads.
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+	AlertStatusTest     = "test"
+)
+
+// DefaultAlertPayloadTemplate renders every field of an AlertPayload as a flat JSON object.
+const DefaultAlertPayloadTemplate = `{"rule":{{json .Rule}},"rule_id":{{.RuleID}},"type":{{json .Type}},"status":{{json .Status}},"group":{{json .Group}},"group_id":{{.GroupID}},"value":{{json .Value}},"threshold":{{json .Threshold}},"message":{{json .Message}},"time":{{json .Time}}}`
+
+// AlertPayload is the data available to webhook payload templates.
+type AlertPayload struct {
+	Rule      string
+	RuleID    uint
+	Type      string
+	Status    string
+	Group     string
+	GroupID   uint
+	Value     float64
+	Threshold float64
+	Message   string
+	Time      string
+}
+
+// alertTemplateFuncs are available in payload templates; json renders any value as a JSON literal,
+// so strings are quoted and escaped.
+var alertTemplateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// parseAlertTemplate parses a payload template, falling back to the default payload when empty.
+func parseAlertTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultAlertPayloadTemplate
+	}
+	return template.New("payload").Funcs(alertTemplateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// renderAlertPayload renders a payload and checks that the result is valid JSON.
+func renderAlertPayload(text string, payload AlertPayload) ([]byte, error) {
+	tmpl, err := parseAlertTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, payload); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("payload template does not render valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// postAlertWebhook delivers a rendered payload, treating any non-2xx response as a failure.
+func postAlertWebhook(ctx context.Context, client *http.Client, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, alertWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gpt-load-alerts")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook responded with %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+// Bulk item statuses.
+const (
+	BulkItemSucceeded = "succeeded"
+	BulkItemFailed    = "failed"
+	BulkItemSkipped   = "skipped"
+)
+
+// Reasons reported for skipped or failed bulk items.
+const (
+	BulkReasonInvalidFormat = "invalid_format"
+	BulkReasonDuplicate     = "duplicate_in_request"
+	BulkReasonAlreadyExists = "already_exists"
+	BulkReasonNotFound      = "not_found"
+	BulkReasonNotInvalid    = "not_invalid"
+	BulkReasonRolledBack    = "rolled_back"
+)
+
+// BulkItemResult reports the outcome of one item of a bulk operation.
+type BulkItemResult struct {
+	// Index is the item's position in the request.
+	Index  int    `json:"index"`
+	Item   string `json:"item"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	// Err keeps the original failure so handlers can translate it into Reason.
+	Err error `json:"-"`
+}
+
+// BulkResult holds the per-item outcome shared by all bulk operations.
+// In transactional mode any failure rolls back the whole batch; items that would
+// have succeeded are then reported as skipped with the rolled_back reason.
+type BulkResult struct {
+	FailedCount   int              `json:"failed_count"`
+	Transactional bool             `json:"transactional"`
+	RolledBack    bool             `json:"rolled_back"`
+	Items         []BulkItemResult `json:"items"`
+}
+
+// newBulkResult creates a result with one pending item per input.
+func newBulkResult(items []string, transactional bool) *BulkResult {
+	result := &BulkResult{
+		Transactional: transactional,
+		Items:         make([]BulkItemResult, len(items)),
+	}
+	for i, item := range items {
+		result.Items[i] = BulkItemResult{Index: i, Item: item}
+	}
+	return result
+}
+
+// fail marks an item as failed.
+func (r *BulkResult) fail(index int, err error) {
+	r.Items[index].Status = BulkItemFailed
+	r.Items[index].Reason = err.Error()
+	r.Items[index].Err = err
+}
+
+// skip marks an item as skipped.
+func (r *BulkResult) skip(index int, reason string) {
+	r.Items[index].Status = BulkItemSkipped
+	r.Items[index].Reason = reason
+}
+
+// succeed marks an item as succeeded.
+func (r *BulkResult) succeed(index int) {
+	r.Items[index].Status = BulkItemSucceeded
+	r.Items[index].Reason = ""
+}
+
+// hasFailures reports whether any item failed.
+func (r *BulkResult) hasFailures() bool {
+	for _, item := range r.Items {
+		if item.Status == BulkItemFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// rollBack turns every succeeded item into a skipped one after the batch was discarded.
+func (r *BulkResult) rollBack() {
+	r.RolledBack = true
+	for i := range r.Items {
+		if r.Items[i].Status == BulkItemSucceeded || r.Items[i].Status == "" {
+			r.skip(i, BulkReasonRolledBack)
+		}
+	}
+}
+
+// Counts returns the number of succeeded and skipped items and updates FailedCount.
+func (r *BulkResult) Counts() (succeeded, skipped int) {
+	r.FailedCount = 0
+	for _, item := range r.Items {
+		switch item.Status {
+		case BulkItemSucceeded:
+			succeeded++
+		case BulkItemSkipped:
+			skipped++
+		case BulkItemFailed:
+			r.FailedCount++
+		}
+	}
+	return succeeded, skipped
+}
+
+// jobFailures reports the failed items to the job that ran the bulk operation. Counts must have
+// been called first.
+func (r BulkResult) jobFailures() int {
+	return r.FailedCount
+}
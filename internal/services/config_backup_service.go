@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ConfigBundleVersion is the format version of exported configuration bundles. Imports reject
+// bundles written by a newer version.
+const ConfigBundleVersion = 1
+
+// ConfigBundle is a complete, portable copy of the configuration of an instance.
+type ConfigBundle struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Groups     []GroupBundle  `json:"groups"`
+	Settings   map[string]any `json:"settings,omitempty"`
+	// KeysIncluded is set when the groups carry their keys, encrypted with the export passphrase.
+	KeysIncluded bool `json:"keys_included"`
+}
+
+// GroupBundle is the configuration of one group. Sub-groups and model routing rules refer to
+// other groups by name, so bundles can be imported into instances with different group IDs.
+type GroupBundle struct {
+	GroupSnapshot
+	GroupType string           `json:"group_type"`
+	SubGroups []SubGroupBundle `json:"sub_groups,omitempty"`
+	Models    []ModelBundle    `json:"models,omitempty"`
+	Keys      []string         `json:"keys,omitempty"`
+}
+
+// SubGroupBundle is a member of an aggregate group.
+type SubGroupBundle struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// ModelBundle is the stored capabilities of a model of a group.
+type ModelBundle struct {
+	ModelID               string         `json:"model_id"`
+	ModelName             string         `json:"model_name"`
+	SupportsStreaming     bool           `json:"supports_streaming"`
+	SupportsVision        bool           `json:"supports_vision"`
+	SupportsFunctions     bool           `json:"supports_functions"`
+	SupportsRerank        bool           `json:"supports_rerank"`
+	MaxTokens             *int           `json:"max_tokens,omitempty"`
+	MaxInputTokens        *int           `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens       *int           `json:"max_output_tokens,omitempty"`
+	InputPricePerMillion  *float64       `json:"input_price_per_million,omitempty"`
+	OutputPricePerMillion *float64       `json:"output_price_per_million,omitempty"`
+	CustomCapabilities    datatypes.JSON `json:"custom_capabilities,omitempty"`
+	IsAutoFetched         bool           `json:"is_auto_fetched"`
+}
+
+// ConfigExportOptions selects what an export contains.
+type ConfigExportOptions struct {
+	IncludeSettings bool
+	// IncludeKeys exports the keys of every group, encrypted with Passphrase.
+	IncludeKeys bool
+	Passphrase  string
+}
+
+// ConfigImportResult summarizes an import.
+type ConfigImportResult struct {
+	GroupsCreated   int  `json:"groups_created"`
+	GroupsUpdated   int  `json:"groups_updated"`
+	ModelsImported  int  `json:"models_imported"`
+	KeysAdded       int  `json:"keys_added"`
+	KeysIgnored     int  `json:"keys_ignored"`
+	SettingsUpdated bool `json:"settings_updated"`
+}
+
+// ConfigBackupService exports the configuration of an instance as a single bundle and imports such
+// bundles, for migrations and disaster recovery.
+type ConfigBackupService struct {
+	db                    *gorm.DB
+	settingsManager       *config.SystemSettingsManager
+	groupService          *GroupService
+	aggregateGroupService *AggregateGroupService
+	keyService            *KeyService
+	configVersionService  *ConfigVersionService
+	encryptionSvc         encryption.Service
+}
+
+// NewConfigBackupService creates a new ConfigBackupService.
+func NewConfigBackupService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	groupService *GroupService,
+	aggregateGroupService *AggregateGroupService,
+	keyService *KeyService,
+	configVersionService *ConfigVersionService,
+	encryptionSvc encryption.Service,
+) *ConfigBackupService {
+	return &ConfigBackupService{
+		db:                    db,
+		settingsManager:       settingsManager,
+		groupService:          groupService,
+		aggregateGroupService: aggregateGroupService,
+		keyService:            keyService,
+		configVersionService:  configVersionService,
+		encryptionSvc:         encryptionSvc,
+	}
+}
+
+// Export builds a bundle of all groups and, optionally, the system settings and keys.
+func (s *ConfigBackupService) Export(ctx context.Context, opts ConfigExportOptions) (*ConfigBundle, error) {
+	var keyCipher encryption.Service
+	if opts.IncludeKeys {
+		cipher, err := newBundleCipher(opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		keyCipher = cipher
+	}
+
+	var groups []models.Group
+	if err := s.db.WithContext(ctx).Order("sort asc, id asc").Find(&groups).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	names := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		names[group.ID] = group.Name
+	}
+
+	bundle := &ConfigBundle{
+		Version:      ConfigBundleVersion,
+		ExportedAt:   time.Now(),
+		Groups:       make([]GroupBundle, 0, len(groups)),
+		KeysIncluded: opts.IncludeKeys,
+	}
+	for i := range groups {
+		entry, err := s.exportGroup(ctx, &groups[i], names, keyCipher)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Groups = append(bundle.Groups, *entry)
+	}
+
+	if opts.IncludeSettings {
+		data, err := json.Marshal(s.settingsManager.GetSettings())
+		if err != nil {
+			return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+		}
+		if err := json.Unmarshal(data, &bundle.Settings); err != nil {
+			return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+		}
+	}
+
+	return bundle, nil
+}
+
+func (s *ConfigBackupService) exportGroup(ctx context.Context, group *models.Group, names map[uint]string, keyCipher encryption.Service) (*GroupBundle, error) {
+	entry := &GroupBundle{
+		GroupSnapshot: NewGroupSnapshot(group),
+		GroupType:     group.GroupType,
+	}
+
+	var subGroups []models.GroupSubGroup
+	if err := s.db.WithContext(ctx).Where("group_id = ?", group.ID).Order("sub_group_id asc").Find(&subGroups).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	for _, sg := range subGroups {
+		if name, ok := names[sg.SubGroupID]; ok {
+			entry.SubGroups = append(entry.SubGroups, SubGroupBundle{Name: name, Weight: sg.Weight})
+		}
+	}
+
+	var capabilities []models.ModelCapabilities
+	if err := s.db.WithContext(ctx).Where("group_id = ?", group.ID).Order("model_id asc").Find(&capabilities).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	for _, m := range capabilities {
+		entry.Models = append(entry.Models, ModelBundle{
+			ModelID:               m.ModelID,
+			ModelName:             m.ModelName,
+			SupportsStreaming:     m.SupportsStreaming,
+			SupportsVision:        m.SupportsVision,
+			SupportsFunctions:     m.SupportsFunctions,
+			SupportsRerank:        m.SupportsRerank,
+			MaxTokens:             m.MaxTokens,
+			MaxInputTokens:        m.MaxInputTokens,
+			MaxOutputTokens:       m.MaxOutputTokens,
+			InputPricePerMillion:  m.InputPricePerMillion,
+			OutputPricePerMillion: m.OutputPricePerMillion,
+			CustomCapabilities:    m.CustomCapabilities,
+			IsAutoFetched:         m.IsAutoFetched,
+		})
+	}
+
+	if keyCipher != nil {
+		var keys []models.APIKey
+		if err := s.db.WithContext(ctx).Select("id", "key_value").Where("group_id = ?", group.ID).Order("id asc").Find(&keys).Error; err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+		for _, key := range keys {
+			plaintext, err := s.encryptionSvc.Decrypt(key.KeyValue)
+			if err != nil {
+				logrus.WithError(err).WithField("key_id", key.ID).Error("Failed to decrypt key for export, skipping")
+				continue
+			}
+			sealed, err := keyCipher.Encrypt(plaintext)
+			if err != nil {
+				return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+			}
+			entry.Keys = append(entry.Keys, sealed)
+		}
+	}
+
+	return entry, nil
+}
+
+// Import applies a bundle. Groups are matched by name: existing groups are updated and missing
+// ones created, so importing the same bundle twice leaves the instance unchanged. Keys already in
+// a group are ignored, and models are updated in place.
+func (s *ConfigBackupService) Import(ctx context.Context, bundle *ConfigBundle, passphrase string) (*ConfigImportResult, error) {
+	if bundle.Version < 1 || bundle.Version > ConfigBundleVersion {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.unsupported_backup_version", map[string]any{"version": bundle.Version})
+	}
+
+	var keyCipher encryption.Service
+	if bundle.KeysIncluded {
+		cipher, err := newBundleCipher(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		keyCipher = cipher
+	}
+
+	result := &ConfigImportResult{}
+	imported := make(map[string]*models.Group, len(bundle.Groups))
+
+	// Groups are created before routing rules and sub-groups are applied, since those may refer
+	// to groups that appear later in the bundle.
+	for i := range bundle.Groups {
+		entry := &bundle.Groups[i]
+		group, created, err := s.groupService.ImportGroup(ctx, entry.GroupSnapshot, entry.GroupType)
+		if err != nil {
+			return result, err
+		}
+		imported[group.Name] = group
+		if created {
+			result.GroupsCreated++
+		} else {
+			result.GroupsUpdated++
+		}
+	}
+
+	for i := range bundle.Groups {
+		entry := &bundle.Groups[i]
+		group := imported[strings.TrimSpace(entry.Name)]
+		groupID := group.ID
+
+		rules := entry.ModelRoutingRules
+		if rules == nil {
+			rules = []models.ModelRoutingRule{}
+		}
+		if len(rules) > 0 || !isEmptyJSONList(group.ModelRoutingRules) {
+			if _, err := s.groupService.updateGroup(ctx, groupID, GroupUpdateParams{ModelRoutingRules: &rules}, ConfigActionImport); err != nil {
+				return result, err
+			}
+		}
+
+		if entry.GroupType == "aggregate" {
+			if err := s.importSubGroups(ctx, groupID, entry.SubGroups); err != nil {
+				return result, err
+			}
+		}
+
+		modelCount, err := s.importModels(ctx, groupID, entry.Models)
+		if err != nil {
+			return result, err
+		}
+		result.ModelsImported += modelCount
+
+		if keyCipher != nil && len(entry.Keys) > 0 {
+			added, ignored, err := s.importKeys(groupID, entry.Keys, keyCipher)
+			if err != nil {
+				return result, err
+			}
+			result.KeysAdded += added
+			result.KeysIgnored += ignored
+		}
+	}
+
+	if len(bundle.Settings) > 0 {
+		previous := s.settingsManager.GetSettings()
+		if err := s.settingsManager.UpdateSettings(bundle.Settings); err != nil {
+			return result, app_errors.NewAPIError(app_errors.ErrValidation, err.Error())
+		}
+		if err := s.configVersionService.RecordSettings(previous, bundle.Settings, ConfigActionImport); err != nil {
+			logrus.WithError(err).Warn("Failed to record settings version")
+		}
+		result.SettingsUpdated = true
+	}
+
+	return result, nil
+}
+
+// importSubGroups makes the members of an aggregate group match the bundle: missing members are
+// added, weights updated and members not in the bundle removed.
+func (s *ConfigBackupService) importSubGroups(ctx context.Context, groupID uint, subGroups []SubGroupBundle) error {
+	var existing []models.GroupSubGroup
+	if err := s.db.WithContext(ctx).Where("group_id = ?", groupID).Find(&existing).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	weights := make(map[uint]int, len(existing))
+	for _, sg := range existing {
+		weights[sg.SubGroupID] = sg.Weight
+	}
+
+	var inputs []SubGroupInput
+	kept := make(map[uint]bool, len(subGroups))
+	for _, sg := range subGroups {
+		var member models.Group
+		if err := s.db.WithContext(ctx).Select("id").Where("name = ?", sg.Name).First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return NewI18nError(app_errors.ErrValidation, "validation.backup_sub_group_not_found", map[string]any{"name": sg.Name})
+			}
+			return app_errors.ParseDBError(err)
+		}
+
+		kept[member.ID] = true
+		weight, ok := weights[member.ID]
+		switch {
+		case !ok:
+			inputs = append(inputs, SubGroupInput{GroupID: member.ID, Weight: sg.Weight})
+		case weight != sg.Weight:
+			if err := s.aggregateGroupService.UpdateSubGroupWeight(ctx, groupID, member.ID, sg.Weight); err != nil {
+				return err
+			}
+		}
+	}
+
+	for subGroupID := range weights {
+		if kept[subGroupID] {
+			continue
+		}
+		if err := s.aggregateGroupService.DeleteSubGroup(ctx, groupID, subGroupID); err != nil {
+			return err
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil
+	}
+	return s.aggregateGroupService.AddSubGroups(ctx, groupID, inputs)
+}
+
+// importModels creates or updates the stored capabilities of the models of a group.
+func (s *ConfigBackupService) importModels(ctx context.Context, groupID uint, bundles []ModelBundle) (int, error) {
+	imported := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, m := range bundles {
+			if m.ModelID == "" {
+				continue
+			}
+			var capability models.ModelCapabilities
+			err := tx.Where("group_id = ? AND model_id = ?", groupID, m.ModelID).First(&capability).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return app_errors.ParseDBError(err)
+			}
+
+			capability.GroupID = groupID
+			capability.ModelID = m.ModelID
+			capability.ModelName = m.ModelName
+			capability.SupportsStreaming = m.SupportsStreaming
+			capability.SupportsVision = m.SupportsVision
+			capability.SupportsFunctions = m.SupportsFunctions
+			capability.SupportsRerank = m.SupportsRerank
+			capability.MaxTokens = m.MaxTokens
+			capability.MaxInputTokens = m.MaxInputTokens
+			capability.MaxOutputTokens = m.MaxOutputTokens
+			capability.InputPricePerMillion = m.InputPricePerMillion
+			capability.OutputPricePerMillion = m.OutputPricePerMillion
+			capability.CustomCapabilities = m.CustomCapabilities
+			capability.IsAutoFetched = m.IsAutoFetched
+			if err := tx.Save(&capability).Error; err != nil {
+				return app_errors.ParseDBError(err)
+			}
+			imported++
+		}
+		return nil
+	})
+	return imported, err
+}
+
+// importKeys decrypts the keys of a group with the bundle passphrase and adds the ones it does not
+// have yet.
+func (s *ConfigBackupService) importKeys(groupID uint, sealed []string, keyCipher encryption.Service) (int, int, error) {
+	keys := make([]string, 0, len(sealed))
+	for _, value := range sealed {
+		plaintext, err := keyCipher.Decrypt(value)
+		if err != nil {
+			return 0, 0, NewI18nError(app_errors.ErrValidation, "validation.backup_passphrase_invalid", nil)
+		}
+		keys = append(keys, plaintext)
+	}
+
+	added, ignored := 0, 0
+	for start := 0; start < len(keys); start += maxRequestKeys {
+		end := min(start+maxRequestKeys, len(keys))
+		bulk, err := s.keyService.processAndCreateKeys(groupID, keys[start:end], false, nil)
+		if err != nil {
+			return added, ignored, app_errors.ParseDBError(err)
+		}
+		a, i := bulk.Counts()
+		added += a
+		ignored += i
+	}
+	return added, ignored, nil
+}
+
+// isEmptyJSONList reports whether a stored JSON list is unset or has no elements.
+func isEmptyJSONList(value datatypes.JSON) bool {
+	var items []json.RawMessage
+	return len(value) == 0 || json.Unmarshal(value, &items) != nil || len(items) == 0
+}
+
+// newBundleCipher returns the cipher that protects the keys in a bundle. It depends only on the
+// passphrase, so keys can be restored on instances with a different ENCRYPTION_KEY.
+func newBundleCipher(passphrase string) (encryption.Service, error) {
+	if passphrase == "" {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.backup_passphrase_required", nil)
+	}
+	cipher, err := encryption.NewService(passphrase)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("failed to create bundle cipher: %v", err))
+	}
+	return cipher, nil
+}
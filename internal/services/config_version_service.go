@@ -0,0 +1,307 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"reflect"
+	"sort"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Config version resource types and actions.
+const (
+	ConfigResourceGroup    = "group"
+	ConfigResourceSettings = "settings"
+
+	ConfigActionBaseline = "baseline"
+	ConfigActionCreate   = "create"
+	ConfigActionUpdate   = "update"
+	ConfigActionRollback = "rollback"
+	ConfigActionImport   = "import"
+)
+
+// maxConfigVersions is the number of versions kept per resource.
+const maxConfigVersions = 100
+
+// GroupSnapshot is the restorable configuration of a group. Keys and sub-group membership are not included.
+type GroupSnapshot struct {
+	Name                string                    `json:"name"`
+	DisplayName         string                    `json:"display_name"`
+	Description         string                    `json:"description"`
+	Upstreams           datatypes.JSON            `json:"upstreams"`
+	ChannelType         string                    `json:"channel_type"`
+	Sort                int                       `json:"sort"`
+	TestModel           string                    `json:"test_model"`
+	ValidationEndpoint  string                    `json:"validation_endpoint"`
+	ParamOverrides      datatypes.JSONMap         `json:"param_overrides"`
+	Config              datatypes.JSONMap         `json:"config"`
+	HeaderRules         []models.HeaderRule       `json:"header_rules"`
+	BodyRules           []models.BodyRule         `json:"body_rules"`
+	ScriptRules         []models.ScriptRule       `json:"script_rules"`
+	ModelRedirectRules  datatypes.JSONMap         `json:"model_redirect_rules"`
+	ModelRedirectStrict bool                      `json:"model_redirect_strict"`
+	ModelRoutingRules   []models.ModelRoutingRule `json:"model_routing_rules"`
+	ModelAccess         *models.ModelAccessPolicy `json:"model_access,omitempty"`
+	IPAccess            *models.IPAccessPolicy    `json:"ip_access,omitempty"`
+	ProxyKeys           string                    `json:"proxy_keys"`
+}
+
+// NewGroupSnapshot captures the configuration of a group.
+func NewGroupSnapshot(group *models.Group) GroupSnapshot {
+	var headerRules []models.HeaderRule
+	if len(group.HeaderRules) > 0 {
+		_ = json.Unmarshal(group.HeaderRules, &headerRules)
+	}
+	var bodyRules []models.BodyRule
+	if len(group.BodyRules) > 0 {
+		_ = json.Unmarshal(group.BodyRules, &bodyRules)
+	}
+	var scriptRules []models.ScriptRule
+	if len(group.ScriptRules) > 0 {
+		_ = json.Unmarshal(group.ScriptRules, &scriptRules)
+	}
+	var routingRules []models.ModelRoutingRule
+	if len(group.ModelRoutingRules) > 0 {
+		_ = json.Unmarshal(group.ModelRoutingRules, &routingRules)
+	}
+	var modelAccess *models.ModelAccessPolicy
+	if len(group.ModelAccess) > 0 {
+		_ = json.Unmarshal(group.ModelAccess, &modelAccess)
+	}
+	var ipAccess *models.IPAccessPolicy
+	if len(group.IPAccess) > 0 {
+		_ = json.Unmarshal(group.IPAccess, &ipAccess)
+	}
+	return GroupSnapshot{
+		Name:                group.Name,
+		DisplayName:         group.DisplayName,
+		Description:         group.Description,
+		Upstreams:           group.Upstreams,
+		ChannelType:         group.ChannelType,
+		Sort:                group.Sort,
+		TestModel:           group.TestModel,
+		ValidationEndpoint:  group.ValidationEndpoint,
+		ParamOverrides:      group.ParamOverrides,
+		Config:              group.Config,
+		HeaderRules:         headerRules,
+		BodyRules:           bodyRules,
+		ScriptRules:         scriptRules,
+		ModelRedirectRules:  group.ModelRedirectRules,
+		ModelRedirectStrict: group.ModelRedirectStrict,
+		ModelRoutingRules:   routingRules,
+		ModelAccess:         modelAccess,
+		IPAccess:            ipAccess,
+		ProxyKeys:           group.ProxyKeys,
+	}
+}
+
+// ConfigFieldChange describes a single top-level field that differs between two versions.
+type ConfigFieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// ConfigVersionService keeps versioned snapshots of group and system settings changes.
+type ConfigVersionService struct {
+	db *gorm.DB
+}
+
+// NewConfigVersionService creates a new ConfigVersionService.
+func NewConfigVersionService(db *gorm.DB) *ConfigVersionService {
+	return &ConfigVersionService{db: db}
+}
+
+// Record stores a new version of a resource using the given transaction (or the service DB if nil)
+// and prunes versions beyond the retention limit.
+func (s *ConfigVersionService) Record(tx *gorm.DB, resourceType string, resourceID uint, action string, snapshot any) error {
+	if tx == nil {
+		tx = s.db
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config snapshot: %w", err)
+	}
+
+	latest, err := s.Latest(tx, resourceType, resourceID)
+	if err != nil {
+		return err
+	}
+
+	version := models.ConfigVersion{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Version:      latest + 1,
+		Action:       action,
+		Snapshot:     datatypes.JSON(data),
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return err
+	}
+
+	if version.Version > maxConfigVersions {
+		return tx.Where("resource_type = ? AND resource_id = ? AND version <= ?", resourceType, resourceID, version.Version-maxConfigVersions).
+			Delete(&models.ConfigVersion{}).Error
+	}
+	return nil
+}
+
+// HasVersions reports whether any version has been recorded for a resource.
+func (s *ConfigVersionService) HasVersions(tx *gorm.DB, resourceType string, resourceID uint) (bool, error) {
+	if tx == nil {
+		tx = s.db
+	}
+	var count int64
+	err := tx.Model(&models.ConfigVersion{}).
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Latest returns the newest version number of a resource, or 0 if none has been recorded.
+func (s *ConfigVersionService) Latest(tx *gorm.DB, resourceType string, resourceID uint) (int, error) {
+	if tx == nil {
+		tx = s.db
+	}
+	var latest int
+	err := tx.Model(&models.ConfigVersion{}).
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error
+	return latest, err
+}
+
+// FindByNumber returns the version of a resource with the given number, or nil if it was pruned or never recorded.
+func (s *ConfigVersionService) FindByNumber(resourceType string, resourceID uint, number int) (*models.ConfigVersion, error) {
+	return s.findOne(s.db.Where("resource_type = ? AND resource_id = ? AND version = ?", resourceType, resourceID, number))
+}
+
+// FirstSince returns the oldest version of a resource recorded at or after t, or nil if there is none.
+// Versions are recorded in the transaction that changes the resource, so this is the state the
+// resource had at t.
+func (s *ConfigVersionService) FirstSince(resourceType string, resourceID uint, t time.Time) (*models.ConfigVersion, error) {
+	return s.findOne(s.db.Where("resource_type = ? AND resource_id = ? AND created_at >= ?", resourceType, resourceID, t).
+		Order("version asc"))
+}
+
+func (s *ConfigVersionService) findOne(query *gorm.DB) (*models.ConfigVersion, error) {
+	var version models.ConfigVersion
+	err := query.First(&version).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// List returns the versions of a resource, newest first.
+func (s *ConfigVersionService) List(resourceType string, resourceID uint) ([]models.ConfigVersion, error) {
+	var versions []models.ConfigVersion
+	err := s.db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Order("version desc").
+		Find(&versions).Error
+	return versions, err
+}
+
+// Get returns a single version by ID.
+func (s *ConfigVersionService) Get(id uint) (*models.ConfigVersion, error) {
+	var version models.ConfigVersion
+	if err := s.db.First(&version, id).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Previous returns the version recorded before the given one, or nil if it is the first.
+func (s *ConfigVersionService) Previous(version *models.ConfigVersion) (*models.ConfigVersion, error) {
+	var previous models.ConfigVersion
+	err := s.db.Where("resource_type = ? AND resource_id = ? AND version < ?", version.ResourceType, version.ResourceID, version.Version).
+		Order("version desc").
+		First(&previous).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// Diff compares the top-level fields of two snapshots. A nil base is treated as empty.
+func (s *ConfigVersionService) Diff(base, target *models.ConfigVersion) ([]ConfigFieldChange, error) {
+	oldFields := map[string]any{}
+	if base != nil {
+		if err := json.Unmarshal(base.Snapshot, &oldFields); err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot of version %d: %w", base.Version, err)
+		}
+	}
+	newFields := map[string]any{}
+	if err := json.Unmarshal(target.Snapshot, &newFields); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot of version %d: %w", target.Version, err)
+	}
+
+	fields := make(map[string]struct{}, len(newFields))
+	for field := range oldFields {
+		fields[field] = struct{}{}
+	}
+	for field := range newFields {
+		fields[field] = struct{}{}
+	}
+
+	changes := make([]ConfigFieldChange, 0)
+	for field := range fields {
+		oldValue, newValue := oldFields[field], newFields[field]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, ConfigFieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	return changes, nil
+}
+
+// RecordSettings stores the system settings that result from applying changes on top of current.
+// The first recorded change also stores current as a baseline.
+func (s *ConfigVersionService) RecordSettings(current types.SystemSettings, changes map[string]any, action string) error {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	snapshot := map[string]any{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		hasVersions, err := s.HasVersions(tx, ConfigResourceSettings, 0)
+		if err != nil {
+			return err
+		}
+		if !hasVersions {
+			if err := s.Record(tx, ConfigResourceSettings, 0, ConfigActionBaseline, snapshot); err != nil {
+				return err
+			}
+		}
+
+		for key, value := range changes {
+			snapshot[key] = value
+		}
+		return s.Record(tx, ConfigResourceSettings, 0, action, snapshot)
+	})
+}
+
+// SettingsFromVersion returns the settings map stored in a settings version, in the same shape as an update request.
+func SettingsFromVersion(version *models.ConfigVersion) (map[string]any, error) {
+	var snapshot map[string]any
+	if err := json.Unmarshal(version.Snapshot, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	return snapshot, nil
+}
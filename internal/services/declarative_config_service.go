@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// DeclarativeConfig is the content of CONFIG_FILE. Groups use the same fields as a backup bundle;
+// keys are not managed by the file.
+type DeclarativeConfig struct {
+	// Prune deletes groups that are not declared in the file.
+	Prune  bool          `json:"prune"`
+	Groups []GroupBundle `json:"groups"`
+}
+
+// DeclarativeConfigService reconciles the groups in the database with CONFIG_FILE at startup and
+// whenever the process receives SIGHUP, so deployments can keep their groups in version control.
+type DeclarativeConfigService struct {
+	db            *gorm.DB
+	configManager types.ConfigManager
+	groupService  *GroupService
+	backupService *ConfigBackupService
+	signals       chan os.Signal
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+	mu            sync.Mutex
+}
+
+// NewDeclarativeConfigService creates a new DeclarativeConfigService.
+func NewDeclarativeConfigService(
+	db *gorm.DB,
+	configManager types.ConfigManager,
+	groupService *GroupService,
+	backupService *ConfigBackupService,
+) *DeclarativeConfigService {
+	return &DeclarativeConfigService{
+		db:            db,
+		configManager: configManager,
+		groupService:  groupService,
+		backupService: backupService,
+		signals:       make(chan os.Signal, 1),
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// Start applies the config file and reloads it on SIGHUP. It does nothing when CONFIG_FILE is unset.
+func (s *DeclarativeConfigService) Start() {
+	path := s.configManager.GetConfigFilePath()
+	if path == "" {
+		return
+	}
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		logrus.WithError(err).WithField("path", path).Error("Failed to apply config file")
+	}
+
+	signal.Notify(s.signals, syscall.SIGHUP)
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Declarative config service started")
+}
+
+// Stop stops listening for SIGHUP and waits for a running reconciliation to finish.
+func (s *DeclarativeConfigService) Stop(ctx context.Context) {
+	if s.configManager.GetConfigFilePath() == "" {
+		return
+	}
+	signal.Stop(s.signals)
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("DeclarativeConfigService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("DeclarativeConfigService stop timed out.")
+	}
+}
+
+func (s *DeclarativeConfigService) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.signals:
+			logrus.Info("SIGHUP received, reloading config file")
+			if err := s.Reconcile(context.Background()); err != nil {
+				logrus.WithError(err).Error("Failed to apply config file")
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Reconcile reads the config file and makes the database match it: declared groups are created or
+// updated, and with prune set, groups that are not declared are deleted.
+func (s *DeclarativeConfigService) Reconcile(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.configManager.GetConfigFilePath()
+	declared, err := loadDeclarativeConfig(path)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.backupService.Import(ctx, &ConfigBundle{Version: ConfigBundleVersion, Groups: declared.Groups}, "")
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	if declared.Prune {
+		deleted, err = s.pruneGroups(ctx, declared.Groups)
+		if err != nil {
+			return err
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"path":    path,
+		"created": result.GroupsCreated,
+		"updated": result.GroupsUpdated,
+		"deleted": deleted,
+	}).Info("Config file applied")
+	return nil
+}
+
+// pruneGroups deletes the groups that are not declared. Aggregate groups go first, so that their
+// members are no longer referenced when they are deleted.
+func (s *DeclarativeConfigService) pruneGroups(ctx context.Context, declared []GroupBundle) (int, error) {
+	names := make([]string, 0, len(declared))
+	for _, group := range declared {
+		names = append(names, strings.TrimSpace(group.Name))
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Group{})
+	if len(names) > 0 {
+		query = query.Where("name NOT IN ?", names)
+	}
+	var stale []models.Group
+	if err := query.Order("CASE WHEN group_type = 'aggregate' THEN 0 ELSE 1 END, id asc").Find(&stale).Error; err != nil {
+		return 0, err
+	}
+
+	for _, group := range stale {
+		if err := s.groupService.DeleteGroup(ctx, group.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete group %s: %w", group.Name, err)
+		}
+	}
+	return len(stale), nil
+}
+
+// loadDeclarativeConfig parses a JSON or YAML config file, chosen by its extension.
+func loadDeclarativeConfig(path string) (*DeclarativeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// YAML is converted to JSON so that both formats share the JSON field names of GroupBundle.
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML in config file: %w", err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML in config file: %w", err)
+		}
+	}
+
+	var declared DeclarativeConfig
+	if err := json.Unmarshal(data, &declared); err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+
+	seen := make(map[string]bool, len(declared.Groups))
+	for _, group := range declared.Groups {
+		name := strings.TrimSpace(group.Name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid config file: every group needs a name")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("invalid config file: group %s is declared twice", name)
+		}
+		seen[name] = true
+	}
+	return &declared, nil
+}
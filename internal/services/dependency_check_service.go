@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// Dependency check statuses.
+const (
+	DependencyStatusOK      = "ok"
+	DependencyStatusFailed  = "failed"
+	DependencyStatusSkipped = "skipped"
+)
+
+const (
+	// dependencyCheckTimeout bounds each dependency check, so that a hung dependency fails the
+	// probe instead of stalling it.
+	dependencyCheckTimeout = 3 * time.Second
+	// upstreamCheckTTL is how long the result of resolving the upstreams is reused, so that
+	// frequent readiness probes do not send a burst of DNS lookups each time.
+	upstreamCheckTTL = 15 * time.Second
+	// encryptionProbeText is encrypted and decrypted again to check the encryption service.
+	encryptionProbeText = "gpt-load-health-probe"
+)
+
+// DependencyCheck is the status of one dependency.
+type DependencyCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// DependencyReport is the status of every dependency checked by a probe. Status is failed when
+// any check failed.
+type DependencyReport struct {
+	Status    string            `json:"status"`
+	Timestamp time.Time         `json:"timestamp"`
+	Checks    []DependencyCheck `json:"checks"`
+}
+
+// GroupUpstreamStatus reports how many of a group's upstreams resolve.
+type GroupUpstreamStatus struct {
+	Group      string   `json:"group"`
+	Resolvable int      `json:"resolvable"`
+	Total      int      `json:"total"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// DependencyCheckService checks the dependencies the service needs to handle requests, for the
+// /healthz and /readyz probes.
+type DependencyCheckService struct {
+	db            *gorm.DB
+	store         store.Store
+	configManager types.ConfigManager
+	encryptionSvc encryption.Service
+	groupManager  *GroupManager
+
+	upstreamMu      sync.Mutex
+	upstreamCheck   DependencyCheck
+	upstreamChecked time.Time
+}
+
+// NewDependencyCheckService creates a new DependencyCheckService.
+func NewDependencyCheckService(
+	db *gorm.DB,
+	store store.Store,
+	configManager types.ConfigManager,
+	encryptionSvc encryption.Service,
+	groupManager *GroupManager,
+) *DependencyCheckService {
+	return &DependencyCheckService{
+		db:            db,
+		store:         store,
+		configManager: configManager,
+		encryptionSvc: encryptionSvc,
+		groupManager:  groupManager,
+	}
+}
+
+// Health checks the database, Redis (when configured) and the encryption service.
+func (s *DependencyCheckService) Health(ctx context.Context) *DependencyReport {
+	return newDependencyReport(
+		s.run("database", func() (any, error) { return nil, s.checkDatabase(ctx) }),
+		s.checkRedis(),
+		s.run("encryption", func() (any, error) { return nil, s.checkEncryption() }),
+	)
+}
+
+// Readiness runs the health checks and also checks that every standard group in use has at least
+// one upstream whose host resolves.
+func (s *DependencyCheckService) Readiness(ctx context.Context) *DependencyReport {
+	report := s.Health(ctx)
+	report.Checks = append(report.Checks, s.checkUpstreams(ctx))
+	report.Status = reportStatus(report.Checks)
+	return report
+}
+
+// run times a check and turns its error into a failed status.
+func (s *DependencyCheckService) run(name string, check func() (any, error)) DependencyCheck {
+	start := time.Now()
+	details, err := check()
+	result := DependencyCheck{
+		Name:      name,
+		Status:    DependencyStatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = DependencyStatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (s *DependencyCheckService) checkDatabase(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *DependencyCheckService) checkRedis() DependencyCheck {
+	if s.configManager.GetRedisDSN() == "" {
+		return DependencyCheck{Name: "redis", Status: DependencyStatusSkipped}
+	}
+	return s.run("redis", func() (any, error) {
+		_, err := s.store.Exists("health_probe")
+		return nil, err
+	})
+}
+
+// checkEncryption encrypts and decrypts a probe, and decrypts the newest stored key so that a
+// wrong ENCRYPTION_KEY is reported.
+func (s *DependencyCheckService) checkEncryption() error {
+	encrypted, err := s.encryptionSvc.Encrypt(encryptionProbeText)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	if decrypted, err := s.encryptionSvc.Decrypt(encrypted); err != nil || decrypted != encryptionProbeText {
+		return fmt.Errorf("decrypt: round trip failed")
+	}
+
+	var key models.APIKey
+	err = s.db.Select("id", "key_value").Order("id desc").Limit(1).Find(&key).Error
+	if err != nil {
+		return fmt.Errorf("read stored key: %w", err)
+	}
+	if key.ID != 0 {
+		if _, err := s.encryptionSvc.Decrypt(key.KeyValue); err != nil {
+			return fmt.Errorf("stored key %d cannot be decrypted with the configured key: %w", key.ID, err)
+		}
+	}
+	return nil
+}
+
+// checkUpstreams resolves the upstream hosts of the standard groups that have not expired, through
+// the group's proxy when it has one. Upstreams with weight 0 are not used and are left out.
+func (s *DependencyCheckService) checkUpstreams(ctx context.Context) DependencyCheck {
+	s.upstreamMu.Lock()
+	defer s.upstreamMu.Unlock()
+	if time.Since(s.upstreamChecked) < upstreamCheckTTL {
+		return s.upstreamCheck
+	}
+
+	s.upstreamCheck = s.run("upstreams", func() (any, error) {
+		var names []string
+		if err := s.db.Model(&models.Group{}).
+			Where("group_type <> ? OR group_type IS NULL", "aggregate").
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			Order("name").Pluck("name", &names).Error; err != nil {
+			return nil, fmt.Errorf("read groups: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+		defer cancel()
+		resolved := make(map[string]error)
+		var statuses []GroupUpstreamStatus
+		var unavailable []string
+		for _, name := range names {
+			group, err := s.groupManager.GetGroupByName(name)
+			if err != nil {
+				continue
+			}
+			status := s.resolveGroupUpstreams(ctx, group, resolved)
+			if status.Total > 0 && status.Resolvable == 0 {
+				unavailable = append(unavailable, name)
+			}
+			statuses = append(statuses, status)
+		}
+
+		if len(unavailable) > 0 {
+			return statuses, fmt.Errorf("no resolvable upstream in groups %v", unavailable)
+		}
+		return statuses, nil
+	})
+	s.upstreamChecked = time.Now()
+	return s.upstreamCheck
+}
+
+// resolveGroupUpstreams resolves the hosts of a group's upstreams, reusing the lookups in resolved.
+func (s *DependencyCheckService) resolveGroupUpstreams(ctx context.Context, group *models.Group, resolved map[string]error) GroupUpstreamStatus {
+	status := GroupUpstreamStatus{Group: group.Name}
+
+	var defs []models.UpstreamDefinition
+	if err := json.Unmarshal(group.Upstreams, &defs); err != nil {
+		status.Errors = append(status.Errors, fmt.Sprintf("invalid upstreams: %v", err))
+		return status
+	}
+
+	for _, def := range defs {
+		if def.Weight <= 0 {
+			continue
+		}
+		status.Total++
+
+		target := def.URL
+		if group.EffectiveConfig.ProxyURL != "" {
+			target = group.EffectiveConfig.ProxyURL
+		}
+		parsed, err := url.Parse(target)
+		if err != nil || parsed.Hostname() == "" {
+			status.Errors = append(status.Errors, fmt.Sprintf("%s: invalid URL", def.URL))
+			continue
+		}
+
+		host := parsed.Hostname()
+		lookupErr, ok := resolved[host]
+		if !ok {
+			if net.ParseIP(host) == nil {
+				_, lookupErr = net.DefaultResolver.LookupHost(ctx, host)
+			}
+			resolved[host] = lookupErr
+		}
+		if lookupErr != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("%s: %v", def.URL, lookupErr))
+			continue
+		}
+		status.Resolvable++
+	}
+	return status
+}
+
+func newDependencyReport(checks ...DependencyCheck) *DependencyReport {
+	return &DependencyReport{
+		Status:    reportStatus(checks),
+		Timestamp: time.Now().UTC(),
+		Checks:    checks,
+	}
+}
+
+func reportStatus(checks []DependencyCheck) string {
+	for _, check := range checks {
+		if check.Status == DependencyStatusFailed {
+			return DependencyStatusFailed
+		}
+	}
+	return DependencyStatusOK
+}
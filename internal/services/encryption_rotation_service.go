@@ -0,0 +1,562 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	encryptionRotationBatchSize = 200
+	encryptionRotationPause     = 200 * time.Millisecond
+)
+
+// RotationProgress counts the rows of one table handled by a rotation pass. Total is the number of
+// rows to scan when the pass started; rows already on the current key version are scanned but skipped.
+type RotationProgress struct {
+	Total    int64 `json:"total"`
+	Scanned  int64 `json:"scanned"`
+	Migrated int64 `json:"migrated"`
+	Failed   int64 `json:"failed"`
+}
+
+// EncryptionRotationStatus reports the current or last rotation pass of this node.
+type EncryptionRotationStatus struct {
+	// Enabled is true when previous key versions are configured, so there is something to rotate.
+	Enabled     bool             `json:"enabled"`
+	JobID       string           `json:"job_id,omitempty"`
+	Running     bool             `json:"running"`
+	Version     int              `json:"version"`
+	Keys        RotationProgress `json:"keys"`
+	RequestLogs RotationProgress `json:"request_logs"`
+	Users       RotationProgress `json:"users"`
+	Transcripts RotationProgress `json:"stream_transcripts"`
+	Webhooks    RotationProgress `json:"webhook_subscriptions"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	FinishedAt  *time.Time       `json:"finished_at,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// jobFailures reports the rows that could not be re-encrypted to the rotation's job.
+func (s EncryptionRotationStatus) jobFailures() int {
+	failed := s.Keys.Failed + s.RequestLogs.Failed
+	for _, table := range EncryptedTables {
+		failed += table.progress(&s).Failed
+	}
+	return int(failed)
+}
+
+// EncryptedTable is a table whose text columns are stored encrypted, apart from api_keys and
+// request_logs, whose key values come with a key hash.
+type EncryptedTable struct {
+	Table   string
+	Columns []string
+	// progress selects the counters of the table in a rotation status.
+	progress func(status *EncryptionRotationStatus) *RotationProgress
+}
+
+// EncryptedTables lists the tables the rotation service re-encrypts after the API keys and request logs.
+var EncryptedTables = []EncryptedTable{
+	{
+		Table:    "users",
+		Columns:  []string{"totp_secret", "recovery_codes"},
+		progress: func(status *EncryptionRotationStatus) *RotationProgress { return &status.Users },
+	},
+	{
+		Table:    "stream_transcripts",
+		Columns:  []string{"request_body", "content", "reasoning_content"},
+		progress: func(status *EncryptionRotationStatus) *RotationProgress { return &status.Transcripts },
+	},
+	{
+		Table:    "webhook_subscriptions",
+		Columns:  []string{"secret"},
+		progress: func(status *EncryptionRotationStatus) *RotationProgress { return &status.Webhooks },
+	},
+}
+
+// EncryptedRow is the ID and the encrypted column values of a row of an EncryptedTable. Values
+// are in the order of the table's columns; empty values are not encrypted.
+type EncryptedRow struct {
+	ID     uint
+	Values []string
+}
+
+// nonEmpty is the condition matching rows that hold at least one encrypted value.
+func (t EncryptedTable) nonEmpty() string {
+	conditions := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		conditions[i] = column + " <> ''"
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// Count returns the number of rows that hold at least one encrypted value.
+func (t EncryptedTable) Count(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Table(t.Table).Where(t.nonEmpty()).Count(&count).Error
+	return count, err
+}
+
+// EachBatch calls fn with the rows holding encrypted values in ID order, batchSize rows at a time,
+// and stops at the first error fn returns.
+func (t EncryptedTable) EachBatch(db *gorm.DB, batchSize int, fn func(rows []EncryptedRow) error) error {
+	lastID := uint(0)
+	for {
+		rows, err := db.Table(t.Table).
+			Select(append([]string{"id"}, t.Columns...)).
+			Where("id > ?", lastID).
+			Where(t.nonEmpty()).
+			Order("id").
+			Limit(batchSize).
+			Rows()
+		if err != nil {
+			return err
+		}
+		var batch []EncryptedRow
+		for rows.Next() {
+			var id uint
+			values := make([]sql.NullString, len(t.Columns))
+			dest := []any{&id}
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return err
+			}
+			row := EncryptedRow{ID: id, Values: make([]string, len(values))}
+			for i, value := range values {
+				row.Values[i] = value.String
+			}
+			batch = append(batch, row)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+}
+
+// EncryptionRotationService re-encrypts rows written with a previous encryption key version while the
+// server keeps serving traffic. Reads work throughout because every configured key version stays
+// readable, so the old key can be dropped from ENCRYPTION_PREVIOUS_KEYS once the pass reports no failures.
+// A pass only rewrites rows that are not on the current version yet, so an interrupted pass resumes
+// where it stopped when it is started again.
+type EncryptionRotationService struct {
+	db            *gorm.DB
+	encryptionSvc encryption.Service
+	configManager types.ConfigManager
+	notifier      *notification.Service
+	jobs          *JobService
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+
+	mu     sync.Mutex
+	status EncryptionRotationStatus
+}
+
+// NewEncryptionRotationService creates a new encryption rotation service
+func NewEncryptionRotationService(db *gorm.DB, encryptionSvc encryption.Service, configManager types.ConfigManager, notifier *notification.Service, jobs *JobService) *EncryptionRotationService {
+	return &EncryptionRotationService{
+		db:            db,
+		encryptionSvc: encryptionSvc,
+		configManager: configManager,
+		notifier:      notifier,
+		jobs:          jobs,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// Start migrates rows in the background when previous encryption keys are configured.
+func (s *EncryptionRotationService) Start() {
+	if !s.enabled() {
+		return
+	}
+	if s.begin() {
+		logrus.Debug("Encryption rotation service started")
+	}
+}
+
+// Trigger starts a rotation pass on demand, resuming the rows an earlier pass did not migrate.
+func (s *EncryptionRotationService) Trigger() error {
+	if !s.configManager.IsMaster() {
+		return app_errors.NewAPIError(app_errors.ErrBadRequest, "Encryption key rotation runs on the master node")
+	}
+	if !s.enabled() {
+		return app_errors.NewAPIError(app_errors.ErrBadRequest, "Encryption key rotation needs ENCRYPTION_KEY_VERSION and ENCRYPTION_PREVIOUS_KEYS to be configured")
+	}
+	select {
+	case <-s.stopCh:
+		return app_errors.NewAPIError(app_errors.ErrBadRequest, "The server is shutting down")
+	default:
+	}
+	if !s.begin() {
+		return app_errors.NewAPIError(app_errors.ErrTaskInProgress, "An encryption key rotation is already running")
+	}
+	return nil
+}
+
+// Status returns the progress of the current or last rotation pass.
+func (s *EncryptionRotationService) Status() EncryptionRotationStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Enabled = s.enabled()
+	if !status.Running && status.StartedAt == nil {
+		status.Version = s.encryptionSvc.CurrentVersion()
+	}
+	return status
+}
+
+func (s *EncryptionRotationService) enabled() bool {
+	return s.encryptionSvc.CurrentVersion() != 0 && len(s.configManager.GetEncryptionConfig().PreviousKeys) > 0
+}
+
+// begin starts a pass unless one is already running.
+func (s *EncryptionRotationService) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.Running {
+		return false
+	}
+	now := time.Now()
+	s.status = EncryptionRotationStatus{
+		Running:   true,
+		Version:   s.encryptionSvc.CurrentVersion(),
+		StartedAt: &now,
+	}
+	if job, err := s.jobs.Create(JobTypeEncryptionRotation, "", 0); err != nil {
+		logrus.WithError(err).Warn("Encryption key rotation runs without a job record")
+	} else {
+		s.status.JobID = job.ID
+	}
+	s.wg.Add(1)
+	go s.run()
+	return true
+}
+
+// finish records the end of a pass.
+func (s *EncryptionRotationService) finish(err error) {
+	s.mu.Lock()
+	now := time.Now()
+	s.status.Running = false
+	s.status.FinishedAt = &now
+	if err != nil {
+		s.status.Error = err.Error()
+	}
+	status := s.status
+	s.mu.Unlock()
+
+	if status.JobID == "" {
+		return
+	}
+	s.updateJob(status)
+	if jobErr := s.jobs.Finish(status.JobID, status, err); jobErr != nil {
+		logrus.WithError(jobErr).Warn("Failed to finish encryption rotation job")
+	}
+}
+
+// progress updates the counters of the running pass.
+func (s *EncryptionRotationService) progress(update func(status *EncryptionRotationStatus)) {
+	s.mu.Lock()
+	update(&s.status)
+	status := s.status
+	s.mu.Unlock()
+
+	if status.JobID != "" {
+		s.updateJob(status)
+	}
+}
+
+// updateJob copies the counters of all tables to the rotation's job.
+func (s *EncryptionRotationService) updateJob(status EncryptionRotationStatus) {
+	total := status.Keys.Total + status.RequestLogs.Total
+	scanned := status.Keys.Scanned + status.RequestLogs.Scanned
+	for _, table := range EncryptedTables {
+		total += table.progress(&status).Total
+		scanned += table.progress(&status).Scanned
+	}
+	if err := s.jobs.Progress(status.JobID, int(total), int(scanned), status.jobFailures()); err != nil {
+		logrus.WithError(err).Warn("Failed to update encryption rotation job")
+	}
+}
+
+// Stop interrupts a running migration; the next start resumes it.
+func (s *EncryptionRotationService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("EncryptionRotationService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("EncryptionRotationService stop timed out.")
+	}
+}
+
+func (s *EncryptionRotationService) run() {
+	defer s.wg.Done()
+
+	version := s.encryptionSvc.CurrentVersion()
+	start := time.Now()
+	logrus.Infof("Migrating encrypted data to encryption key version %d in the background...", version)
+
+	if err := s.countRows(); err != nil {
+		logrus.WithError(err).Error("Encryption key rotation could not count the rows to migrate")
+		s.finish(err)
+		return
+	}
+	keyStats, err := s.rotateKeys()
+	if err != nil {
+		logrus.WithError(err).Error("Encryption key rotation for API keys stopped")
+		s.finish(err)
+		return
+	}
+	logStats, err := s.rotateRequestLogs()
+	if err != nil {
+		logrus.WithError(err).Error("Encryption key rotation for request logs stopped")
+		s.finish(err)
+		return
+	}
+	fields := logrus.Fields{
+		"version":       version,
+		"keys_migrated": keyStats.Migrated,
+		"keys_failed":   keyStats.Failed,
+		"logs_migrated": logStats.Migrated,
+		"logs_failed":   logStats.Failed,
+	}
+	for _, table := range EncryptedTables {
+		stats, err := s.rotateTable(table)
+		if err != nil {
+			logrus.WithError(err).Errorf("Encryption key rotation for %s stopped", table.Table)
+			s.finish(err)
+			return
+		}
+		fields[table.Table+"_migrated"] = stats.Migrated
+		fields[table.Table+"_failed"] = stats.Failed
+	}
+	s.finish(nil)
+	fields["duration"] = time.Since(start).Round(time.Millisecond).String()
+
+	if keyStats.Failed > 0 {
+		logrus.WithFields(fields).Warn("Encryption key rotation finished with undecryptable API keys, keep ENCRYPTION_PREVIOUS_KEYS and run 'gpt-load db check'")
+		s.notifier.Notify(notification.Event{
+			Category: models.NotificationCategoryAlert,
+			Severity: models.NotificationSeverityError,
+			Event:    notification.EventKeyRotationFailed,
+			Message:  fmt.Sprintf("Encryption key rotation to version %d left %d API keys that cannot be decrypted", version, keyStats.Failed),
+			Params:   map[string]any{"version": version, "failed": keyStats.Failed},
+		})
+		return
+	}
+	logrus.WithFields(fields).Info("Encryption key rotation completed, ENCRYPTION_PREVIOUS_KEYS can be removed")
+}
+
+// countRows records how many rows the pass has to scan.
+func (s *EncryptionRotationService) countRows() error {
+	var keys, logs int64
+	if err := s.db.Model(&models.APIKey{}).Count(&keys).Error; err != nil {
+		return err
+	}
+	if err := s.db.Model(&models.RequestLog{}).Where("key_value <> ''").Count(&logs).Error; err != nil {
+		return err
+	}
+	totals := make([]int64, len(EncryptedTables))
+	for i, table := range EncryptedTables {
+		count, err := table.Count(s.db)
+		if err != nil {
+			return err
+		}
+		totals[i] = count
+	}
+	s.progress(func(status *EncryptionRotationStatus) {
+		status.Keys.Total = keys
+		status.RequestLogs.Total = logs
+		for i, table := range EncryptedTables {
+			table.progress(status).Total = totals[i]
+		}
+	})
+	return nil
+}
+
+// rotateKeys re-encrypts API keys and recomputes their hashes with the current key.
+func (s *EncryptionRotationService) rotateKeys() (RotationProgress, error) {
+	var stats RotationProgress
+	lastID := uint(0)
+	for {
+		var keys []models.APIKey
+		if err := s.db.Select("id, key_value").Where("id > ?", lastID).Order("id").Limit(encryptionRotationBatchSize).Find(&keys).Error; err != nil {
+			return stats, err
+		}
+		if len(keys) == 0 {
+			return stats, nil
+		}
+		lastID = keys[len(keys)-1].ID
+		stats.Scanned += int64(len(keys))
+
+		for _, key := range keys {
+			if !s.encryptionSvc.NeedsRotation(key.KeyValue) {
+				continue
+			}
+			updates, ok := s.reencrypt(key.KeyValue)
+			if !ok {
+				logrus.WithField("keyID", key.ID).Warn("API key cannot be decrypted with any configured encryption key")
+				stats.Failed++
+				continue
+			}
+			// The old value guards against overwriting a key changed since it was read.
+			result := s.db.Model(&models.APIKey{}).Where("id = ? AND key_value = ?", key.ID, key.KeyValue).UpdateColumns(updates)
+			if result.Error != nil {
+				return stats, result.Error
+			}
+			stats.Migrated += result.RowsAffected
+		}
+		s.progress(func(status *EncryptionRotationStatus) {
+			status.Keys.Scanned, status.Keys.Migrated, status.Keys.Failed = stats.Scanned, stats.Migrated, stats.Failed
+		})
+
+		if !s.pause() {
+			return stats, context.Canceled
+		}
+	}
+}
+
+// rotateRequestLogs re-encrypts the key values recorded in request logs.
+func (s *EncryptionRotationService) rotateRequestLogs() (RotationProgress, error) {
+	var stats RotationProgress
+	lastID := ""
+	for {
+		var logs []models.RequestLog
+		if err := s.db.Select("id, key_value").Where("id > ? AND key_value <> ''", lastID).Order("id").Limit(encryptionRotationBatchSize).Find(&logs).Error; err != nil {
+			return stats, err
+		}
+		if len(logs) == 0 {
+			return stats, nil
+		}
+		lastID = logs[len(logs)-1].ID
+		stats.Scanned += int64(len(logs))
+
+		for _, log := range logs {
+			if !s.encryptionSvc.NeedsRotation(log.KeyValue) {
+				continue
+			}
+			updates, ok := s.reencrypt(log.KeyValue)
+			if !ok {
+				stats.Failed++
+				continue
+			}
+			result := s.db.Model(&models.RequestLog{}).Where("id = ? AND key_value = ?", log.ID, log.KeyValue).UpdateColumns(updates)
+			if result.Error != nil {
+				return stats, result.Error
+			}
+			stats.Migrated += result.RowsAffected
+		}
+		s.progress(func(status *EncryptionRotationStatus) {
+			status.RequestLogs.Scanned, status.RequestLogs.Migrated, status.RequestLogs.Failed = stats.Scanned, stats.Migrated, stats.Failed
+		})
+
+		if !s.pause() {
+			return stats, context.Canceled
+		}
+	}
+}
+
+// rotateTable re-encrypts the encrypted columns of a table. A row counts as migrated when any of
+// its values was rewritten, and as failed when any of them cannot be decrypted.
+func (s *EncryptionRotationService) rotateTable(table EncryptedTable) (RotationProgress, error) {
+	var stats RotationProgress
+	err := table.EachBatch(s.db, encryptionRotationBatchSize, func(rows []EncryptedRow) error {
+		stats.Scanned += int64(len(rows))
+		for _, row := range rows {
+			migrated, failed := false, false
+			for i, column := range table.Columns {
+				value := row.Values[i]
+				if value == "" || !s.encryptionSvc.NeedsRotation(value) {
+					continue
+				}
+				plaintext, err := s.encryptionSvc.Decrypt(value)
+				if err != nil {
+					failed = true
+					continue
+				}
+				reencrypted, err := s.encryptionSvc.Encrypt(plaintext)
+				if err != nil {
+					failed = true
+					continue
+				}
+				// The old value guards against overwriting a value changed since it was read.
+				result := s.db.Table(table.Table).Where("id = ? AND "+column+" = ?", row.ID, value).UpdateColumn(column, reencrypted)
+				if result.Error != nil {
+					return result.Error
+				}
+				migrated = migrated || result.RowsAffected > 0
+			}
+			if failed {
+				logrus.WithField("id", row.ID).Warnf("A row of %s cannot be decrypted with any configured encryption key", table.Table)
+				stats.Failed++
+			} else if migrated {
+				stats.Migrated++
+			}
+		}
+		s.progress(func(status *EncryptionRotationStatus) {
+			progress := table.progress(status)
+			progress.Scanned, progress.Migrated, progress.Failed = stats.Scanned, stats.Migrated, stats.Failed
+		})
+
+		if !s.pause() {
+			return context.Canceled
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// reencrypt returns the key_value and key_hash columns of a value re-encrypted with the current key.
+func (s *EncryptionRotationService) reencrypt(ciphertext string) (map[string]any, bool) {
+	plaintext, err := s.encryptionSvc.Decrypt(ciphertext)
+	if err != nil {
+		return nil, false
+	}
+	reencrypted, err := s.encryptionSvc.Encrypt(plaintext)
+	if err != nil {
+		return nil, false
+	}
+	return map[string]any{
+		"key_value": reencrypted,
+		"key_hash":  s.encryptionSvc.Hash(plaintext),
+	}, true
+}
+
+// pause spreads the migration out between batches; it returns false once the service is stopping.
+func (s *EncryptionRotationService) pause() bool {
+	select {
+	case <-s.stopCh:
+		return false
+	case <-time.After(encryptionRotationPause):
+		return true
+	}
+}
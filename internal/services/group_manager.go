@@ -71,6 +71,7 @@ func (gm *GroupManager) Initialize() error {
 			g := *group
 			g.EffectiveConfig = gm.settingsManager.GetEffectiveConfig(g.Config)
 			g.ProxyKeysMap = utils.StringToSet(g.ProxyKeys, ",")
+			g.ProxyKeyExpiryMap = parseProxyKeyExpiry(g.ProxyKeyExpiry)
 
 			// Parse header rules with error handling
 			if len(group.HeaderRules) > 0 {
@@ -82,6 +83,20 @@ func (gm *GroupManager) Initialize() error {
 				g.HeaderRuleList = []models.HeaderRule{}
 			}
 
+			if len(group.BodyRules) > 0 {
+				if err := json.Unmarshal(group.BodyRules, &g.BodyRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse body rules for group")
+					g.BodyRuleList = nil
+				}
+			}
+
+			if len(group.ScriptRules) > 0 {
+				if err := json.Unmarshal(group.ScriptRules, &g.ScriptRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse script rules for group")
+					g.ScriptRuleList = nil
+				}
+			}
+
 			// Parse model redirect rules with error handling
 			g.ModelRedirectMap = make(map[string]string)
 			if len(group.ModelRedirectRules) > 0 {
@@ -104,6 +119,31 @@ func (gm *GroupManager) Initialize() error {
 				}
 			}
 
+			if len(group.ModelRoutingRules) > 0 {
+				if err := json.Unmarshal(group.ModelRoutingRules, &g.ModelRoutingList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse model routing rules for group")
+					g.ModelRoutingList = nil
+				}
+			}
+
+			if len(group.ModelAccess) > 0 {
+				var policy models.ModelAccessPolicy
+				if err := json.Unmarshal(group.ModelAccess, &policy); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse model access policy for group")
+				} else {
+					g.ModelAccessPolicy = &policy
+				}
+			}
+
+			if len(group.IPAccess) > 0 {
+				var policy models.IPAccessPolicy
+				if err := json.Unmarshal(group.IPAccess, &policy); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse IP access policy for group")
+				} else {
+					g.IPAccessPolicy = &policy
+				}
+			}
+
 			// Load sub-groups for aggregate groups
 			if g.GroupType == "aggregate" {
 				if subGroups, ok := subGroupsByAggregateID[g.ID]; ok {
@@ -3,11 +3,14 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"net/http"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -57,6 +60,11 @@ type GroupService struct {
 	keyImportSvc          *KeyImportService
 	encryptionSvc         encryption.Service
 	aggregateGroupService *AggregateGroupService
+	configVersionService  *ConfigVersionService
+	subGroupManager       *SubGroupManager
+	channelFactory        *channel.Factory
+	quotaService          *QuotaService
+	webhooks              *WebhookService
 	channelRegistry       []string
 }
 
@@ -69,6 +77,11 @@ func NewGroupService(
 	keyImportSvc *KeyImportService,
 	encryptionSvc encryption.Service,
 	aggregateGroupService *AggregateGroupService,
+	configVersionService *ConfigVersionService,
+	subGroupManager *SubGroupManager,
+	channelFactory *channel.Factory,
+	quotaService *QuotaService,
+	webhooks *WebhookService,
 ) *GroupService {
 	return &GroupService{
 		db:                    db,
@@ -78,6 +91,11 @@ func NewGroupService(
 		keyImportSvc:          keyImportSvc,
 		encryptionSvc:         encryptionSvc,
 		aggregateGroupService: aggregateGroupService,
+		configVersionService:  configVersionService,
+		subGroupManager:       subGroupManager,
+		channelFactory:        channelFactory,
+		quotaService:          quotaService,
+		webhooks:              webhooks,
 		channelRegistry:       channel.GetChannels(),
 	}
 }
@@ -96,10 +114,15 @@ type GroupCreateParams struct {
 	ParamOverrides      map[string]any
 	ModelRedirectRules  map[string]string
 	ModelRedirectStrict bool
+	ModelRoutingRules   []models.ModelRoutingRule
 	Config              map[string]any
 	HeaderRules         []models.HeaderRule
+	BodyRules           []models.BodyRule
+	ScriptRules         []models.ScriptRule
 	ProxyKeys           string
 	SubGroups           []SubGroupInput
+	// TTLHours creates a sandbox group that expires after the given hours; 0 creates a permanent group.
+	TTLHours int
 }
 
 // GroupUpdateParams captures updatable fields for a group.
@@ -118,10 +141,39 @@ type GroupUpdateParams struct {
 	ParamOverrides      map[string]any
 	ModelRedirectRules  map[string]string
 	ModelRedirectStrict *bool
+	ModelRoutingRules   *[]models.ModelRoutingRule
+	ModelAccess         *models.ModelAccessPolicy
+	IPAccess            *models.IPAccessPolicy
 	Config              map[string]any
 	HeaderRules         *[]models.HeaderRule
+	BodyRules           *[]models.BodyRule
+	ScriptRules         *[]models.ScriptRule
 	ProxyKeys           *string
 	SubGroups           *[]SubGroupInput
+	// TTLHours extends a sandbox group to expire the given hours from now; 0 makes it permanent.
+	TTLHours *int
+	// MergeConfig merges Config into the existing overrides, a nil value removing one, instead of replacing them.
+	MergeConfig  bool
+	Precondition *GroupPrecondition
+}
+
+// GroupPrecondition is the state of a group a client based its update on. The update is rejected
+// with a GroupConflictError if the group has changed since.
+type GroupPrecondition struct {
+	UpdatedAt *time.Time
+	Version   *int
+}
+
+// GroupConflictError reports a failed precondition. Changes lists what was modified since the
+// state the client saw, and is nil when that state is no longer stored.
+type GroupConflictError struct {
+	Current *models.Group
+	Changes []ConfigFieldChange
+}
+
+// Error implements the error interface.
+func (e *GroupConflictError) Error() string {
+	return fmt.Sprintf("group %s was modified by another request", e.Current.Name)
 }
 
 // KeyStats captures aggregated API key statistics for a group.
@@ -129,6 +181,16 @@ type KeyStats struct {
 	TotalKeys   int64 `json:"total_keys"`
 	ActiveKeys  int64 `json:"active_keys"`
 	InvalidKeys int64 `json:"invalid_keys"`
+	// Tiers breaks the keys down by primary and backup tier; only set when the group has backup keys.
+	Tiers []KeyTierStats `json:"tiers,omitempty"`
+}
+
+// KeyTierStats captures the keys of one tier and the requests they have served.
+type KeyTierStats struct {
+	Tier         string `json:"tier"`
+	ActiveKeys   int64  `json:"active_keys"`
+	InvalidKeys  int64  `json:"invalid_keys"`
+	RequestCount int64  `json:"request_count"`
 }
 
 // RequestStats captures request success and failure ratios over a time window.
@@ -144,6 +206,12 @@ type GroupStats struct {
 	Stats24Hour RequestStats `json:"stats_24_hour"`
 	Stats7Day   RequestStats `json:"stats_7_day"`
 	Stats30Day  RequestStats `json:"stats_30_day"`
+	// Experiment reports sub-group routing performance; only set for aggregate groups.
+	Experiment *ExperimentReport `json:"experiment,omitempty"`
+	// UpstreamLatency reports rolling upstream latency per model; only set for standard groups.
+	UpstreamLatency []channel.UpstreamLatencyStats `json:"upstream_latency,omitempty"`
+	// Quota reports the current quota cycle; only set for standard groups with quota_cycle.
+	Quota *QuotaReport `json:"quota,omitempty"`
 }
 
 // ConfigOption describes a configurable override exposed to clients.
@@ -189,7 +257,7 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		if testModel == "" {
 			return nil, NewI18nError(app_errors.ErrValidation, "validation.test_model_required", nil)
 		}
-		cleaned, err := s.validateAndCleanUpstreams(params.Upstreams)
+		cleaned, err := s.validateAndCleanUpstreams(ctx, params.Upstreams, channelType)
 		if err != nil {
 			return nil, err
 		}
@@ -214,6 +282,16 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		headerRulesJSON = datatypes.JSON("[]")
 	}
 
+	bodyRulesJSON, err := normalizeBodyRules(params.BodyRules)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptRulesJSON, err := s.normalizeScriptRules(ctx, name, params.ScriptRules)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate model redirect rules for aggregate groups
 	if groupType == "aggregate" && len(params.ModelRedirectRules) > 0 {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.aggregate_no_model_redirect", nil)
@@ -224,6 +302,20 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_redirect", map[string]any{"error": err.Error()})
 	}
 
+	modelRoutingJSON, err := s.normalizeModelRoutingRules(ctx, name, params.ModelRoutingRules)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if params.TTLHours != 0 {
+		expiry, err := s.sandboxExpiry(params.TTLHours)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt = &expiry
+	}
+
 	group := models.Group{
 		Name:                name,
 		DisplayName:         strings.TrimSpace(params.DisplayName),
@@ -237,9 +329,13 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		ParamOverrides:      params.ParamOverrides,
 		ModelRedirectRules:  convertToJSONMap(params.ModelRedirectRules),
 		ModelRedirectStrict: params.ModelRedirectStrict,
+		ModelRoutingRules:   modelRoutingJSON,
 		Config:              cleanedConfig,
 		HeaderRules:         headerRulesJSON,
+		BodyRules:           bodyRulesJSON,
+		ScriptRules:         scriptRulesJSON,
 		ProxyKeys:           strings.TrimSpace(params.ProxyKeys),
+		ExpiresAt:           expiresAt,
 	}
 
 	tx := s.db.WithContext(ctx).Begin()
@@ -252,6 +348,11 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		return nil, app_errors.ParseDBError(err)
 	}
 
+	if err := s.configVersionService.Record(tx, ConfigResourceGroup, group.ID, ConfigActionCreate, NewGroupSnapshot(&group)); err != nil {
+		tx.Rollback()
+		return nil, app_errors.ParseDBError(err)
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return nil, app_errors.ParseDBError(err)
 	}
@@ -260,6 +361,11 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
 	}
 
+	s.webhooks.Publish(WebhookEventGroupCreated, group.ID, group.Name, fmt.Sprintf("Group '%s' created", group.Name), map[string]any{
+		"group_type":   group.GroupType,
+		"channel_type": group.ChannelType,
+	})
+
 	return &group, nil
 }
 
@@ -275,10 +381,22 @@ func (s *GroupService) ListGroups(ctx context.Context) ([]models.Group, error) {
 
 // UpdateGroup validates and updates an existing group.
 func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpdateParams) (*models.Group, error) {
+	return s.updateGroup(ctx, id, params, ConfigActionUpdate)
+}
+
+// updateGroup applies params to a group and records the result as a config version with the given action.
+func (s *GroupService) updateGroup(ctx context.Context, id uint, params GroupUpdateParams, action string) (*models.Group, error) {
 	var group models.Group
 	if err := s.db.WithContext(ctx).First(&group, id).Error; err != nil {
 		return nil, app_errors.ParseDBError(err)
 	}
+	loadedUpdatedAt := group.UpdatedAt
+
+	if params.Precondition != nil {
+		if err := s.checkGroupPrecondition(ctx, &group, params.Precondition); err != nil {
+			return nil, err
+		}
+	}
 
 	tx := s.db.WithContext(ctx).Begin()
 	if err := tx.Error; err != nil {
@@ -286,6 +404,17 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	}
 	defer tx.Rollback()
 
+	// Groups created before versioning get their current state recorded first so it can be restored.
+	hasVersions, err := s.configVersionService.HasVersions(tx, ConfigResourceGroup, group.ID)
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	if !hasVersions {
+		if err := s.configVersionService.Record(tx, ConfigResourceGroup, group.ID, ConfigActionBaseline, NewGroupSnapshot(&group)); err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+	}
+
 	if params.Name != nil {
 		cleanedName := strings.TrimSpace(*params.Name)
 		if !isValidGroupName(cleanedName) {
@@ -303,7 +432,11 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	}
 
 	if params.HasUpstreams {
-		cleanedUpstreams, err := s.validateAndCleanUpstreams(params.Upstreams)
+		channelType := group.ChannelType
+		if params.ChannelType != nil {
+			channelType = strings.TrimSpace(*params.ChannelType)
+		}
+		cleanedUpstreams, err := s.validateAndCleanUpstreams(ctx, params.Upstreams, channelType)
 		if err != nil {
 			return nil, err
 		}
@@ -380,6 +513,30 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.ModelRedirectStrict = *params.ModelRedirectStrict
 	}
 
+	if params.ModelRoutingRules != nil {
+		modelRoutingJSON, err := s.normalizeModelRoutingRules(ctx, group.Name, *params.ModelRoutingRules)
+		if err != nil {
+			return nil, err
+		}
+		group.ModelRoutingRules = modelRoutingJSON
+	}
+
+	if params.ModelAccess != nil {
+		modelAccessJSON, err := normalizeModelAccess(*params.ModelAccess)
+		if err != nil {
+			return nil, err
+		}
+		group.ModelAccess = modelAccessJSON
+	}
+
+	if params.IPAccess != nil {
+		ipAccessJSON, err := normalizeIPAccess(*params.IPAccess)
+		if err != nil {
+			return nil, err
+		}
+		group.IPAccess = ipAccessJSON
+	}
+
 	if params.ValidationEndpoint != nil {
 		validationEndpoint := strings.TrimSpace(*params.ValidationEndpoint)
 		if !isValidValidationEndpoint(validationEndpoint) {
@@ -389,7 +546,11 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	}
 
 	if params.Config != nil {
-		cleanedConfig, err := s.validateAndCleanConfig(params.Config)
+		overrides := params.Config
+		if params.MergeConfig {
+			overrides = mergeConfigPatch(group.Config, params.Config)
+		}
+		cleanedConfig, err := s.validateAndCleanConfig(overrides)
 		if err != nil {
 			return nil, err
 		}
@@ -400,6 +561,17 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.ProxyKeys = strings.TrimSpace(*params.ProxyKeys)
 	}
 
+	if params.TTLHours != nil {
+		group.ExpiresAt = nil
+		if *params.TTLHours != 0 {
+			expiry, err := s.sandboxExpiry(*params.TTLHours)
+			if err != nil {
+				return nil, err
+			}
+			group.ExpiresAt = &expiry
+		}
+	}
+
 	if params.HeaderRules != nil {
 		headerRulesJSON, err := s.normalizeHeaderRules(*params.HeaderRules)
 		if err != nil {
@@ -411,7 +583,37 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.HeaderRules = headerRulesJSON
 	}
 
-	if err := tx.Save(&group).Error; err != nil {
+	if params.BodyRules != nil {
+		bodyRulesJSON, err := normalizeBodyRules(*params.BodyRules)
+		if err != nil {
+			return nil, err
+		}
+		group.BodyRules = bodyRulesJSON
+	}
+
+	if params.ScriptRules != nil {
+		scriptRulesJSON, err := s.normalizeScriptRules(ctx, group.Name, *params.ScriptRules)
+		if err != nil {
+			return nil, err
+		}
+		group.ScriptRules = scriptRulesJSON
+	}
+
+	if params.Precondition != nil {
+		// Only write if nobody else has changed the group since it was checked.
+		result := tx.Model(&group).Where("updated_at = ?", loadedUpdatedAt).Select("*").Updates(&group)
+		if result.Error != nil {
+			return nil, app_errors.ParseDBError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			return nil, s.newGroupConflict(ctx, id, params.Precondition)
+		}
+	} else if err := tx.Save(&group).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	if err := s.configVersionService.Record(tx, ConfigResourceGroup, group.ID, action, NewGroupSnapshot(&group)); err != nil {
 		return nil, app_errors.ParseDBError(err)
 	}
 
@@ -426,6 +628,347 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	return &group, nil
 }
 
+// checkGroupPrecondition returns a GroupConflictError if the group no longer matches the precondition.
+// UpdatedAt is compared to the millisecond since databases store timestamps with different precision.
+func (s *GroupService) checkGroupPrecondition(ctx context.Context, group *models.Group, precondition *GroupPrecondition) error {
+	if precondition.UpdatedAt != nil {
+		if diff := group.UpdatedAt.Sub(*precondition.UpdatedAt); diff >= time.Millisecond || diff <= -time.Millisecond {
+			return s.newGroupConflict(ctx, group.ID, precondition)
+		}
+	}
+	if precondition.Version != nil {
+		latest, err := s.configVersionService.Latest(s.db.WithContext(ctx), ConfigResourceGroup, group.ID)
+		if err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		if latest != *precondition.Version {
+			return s.newGroupConflict(ctx, group.ID, precondition)
+		}
+	}
+	return nil
+}
+
+// newGroupConflict loads the current group and diffs it against the state the precondition refers to.
+func (s *GroupService) newGroupConflict(ctx context.Context, id uint, precondition *GroupPrecondition) error {
+	var current models.Group
+	if err := s.db.WithContext(ctx).First(&current, id).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	conflict := &GroupConflictError{Current: &current}
+
+	var base *models.ConfigVersion
+	var err error
+	switch {
+	case precondition.Version != nil:
+		base, err = s.configVersionService.FindByNumber(ConfigResourceGroup, id, *precondition.Version)
+	case precondition.UpdatedAt != nil:
+		base, err = s.configVersionService.FirstSince(ConfigResourceGroup, id, precondition.UpdatedAt.Add(-time.Millisecond))
+	}
+	if err != nil || base == nil {
+		return conflict
+	}
+
+	snapshot, err := json.Marshal(NewGroupSnapshot(&current))
+	if err != nil {
+		return conflict
+	}
+	changes, err := s.configVersionService.Diff(base, &models.ConfigVersion{Snapshot: snapshot})
+	if err == nil {
+		conflict.Changes = changes
+	}
+	return conflict
+}
+
+// maxBulkConfigGroups limits how many groups a single bulk config update may touch.
+const maxBulkConfigGroups = 500
+
+// BulkConfigResult holds the result of updating the config of multiple groups.
+type BulkConfigResult struct {
+	UpdatedCount int `json:"updated_count"`
+	IgnoredCount int `json:"ignored_count"`
+	BulkResult
+}
+
+// BulkUpdateGroupConfig merges config overrides into several groups; a nil value removes the override.
+// Groups are updated independently unless transactional is set, in which case any failure leaves
+// every group unchanged.
+func (s *GroupService) BulkUpdateGroupConfig(ctx context.Context, groupIDs []uint, patch map[string]any, transactional bool) (*BulkConfigResult, error) {
+	if len(groupIDs) == 0 || len(patch) == 0 {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.bulk_config_empty", nil)
+	}
+	if len(groupIDs) > maxBulkConfigGroups {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.bulk_config_too_many", map[string]any{"max": maxBulkConfigGroups})
+	}
+
+	var groups []models.Group
+	if err := s.db.WithContext(ctx).Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	groupsByID := make(map[uint]*models.Group, len(groups))
+	for i := range groups {
+		groupsByID[groups[i].ID] = &groups[i]
+	}
+
+	labels := make([]string, len(groupIDs))
+	for i, id := range groupIDs {
+		if group, ok := groupsByID[id]; ok {
+			labels[i] = group.Name
+		} else {
+			labels[i] = strconv.FormatUint(uint64(id), 10)
+		}
+	}
+	result := newBulkResult(labels, transactional)
+
+	var tx *gorm.DB
+	if transactional {
+		tx = s.db.WithContext(ctx).Begin()
+		if tx.Error != nil {
+			return nil, app_errors.ErrDatabase
+		}
+		defer tx.Rollback()
+	}
+
+	applied := false
+	seen := make(map[uint]bool, len(groupIDs))
+	for i, id := range groupIDs {
+		group, ok := groupsByID[id]
+		if !ok {
+			result.skip(i, BulkReasonNotFound)
+			continue
+		}
+		if seen[id] {
+			result.skip(i, BulkReasonDuplicate)
+			continue
+		}
+		seen[id] = true
+
+		if transactional {
+			if err := s.applyGroupConfigPatch(tx, group, patch); err != nil {
+				result.fail(i, err)
+			} else {
+				result.succeed(i)
+			}
+			continue
+		}
+
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return s.applyGroupConfigPatch(tx, group, patch)
+		})
+		if err != nil {
+			result.fail(i, err)
+			continue
+		}
+		result.succeed(i)
+		applied = true
+	}
+
+	if transactional {
+		if result.hasFailures() {
+			result.rollBack()
+		} else if err := tx.Commit().Error; err != nil {
+			return nil, app_errors.ErrDatabase
+		} else {
+			applied = true
+		}
+	}
+
+	if applied {
+		if err := s.groupManager.Invalidate(); err != nil {
+			logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
+		}
+	}
+
+	updatedCount, ignoredCount := result.Counts()
+	return &BulkConfigResult{
+		UpdatedCount: updatedCount,
+		IgnoredCount: ignoredCount,
+		BulkResult:   *result,
+	}, nil
+}
+
+// applyGroupConfigPatch merges patch into the group's config overrides and records the new version.
+func (s *GroupService) applyGroupConfigPatch(tx *gorm.DB, group *models.Group, patch map[string]any) error {
+	cleanedConfig, err := s.validateAndCleanConfig(mergeConfigPatch(group.Config, patch))
+	if err != nil {
+		return err
+	}
+
+	hasVersions, err := s.configVersionService.HasVersions(tx, ConfigResourceGroup, group.ID)
+	if err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	if !hasVersions {
+		if err := s.configVersionService.Record(tx, ConfigResourceGroup, group.ID, ConfigActionBaseline, NewGroupSnapshot(group)); err != nil {
+			return app_errors.ParseDBError(err)
+		}
+	}
+
+	group.Config = cleanedConfig
+	if err := tx.Model(group).Update("config", group.Config).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+
+	if err := s.configVersionService.Record(tx, ConfigResourceGroup, group.ID, ConfigActionUpdate, NewGroupSnapshot(group)); err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
+
+// mergeConfigPatch returns config with patch applied on top; a nil value in patch removes the key.
+func mergeConfigPatch(config map[string]any, patch map[string]any) map[string]any {
+	merged := make(map[string]any, len(config)+len(patch))
+	maps.Copy(merged, config)
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// RollbackGroup restores a group's configuration from a recorded version.
+func (s *GroupService) RollbackGroup(ctx context.Context, version *models.ConfigVersion) (*models.Group, error) {
+	if version.ResourceType != ConfigResourceGroup {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.config_version_type_mismatch", nil)
+	}
+
+	var snapshot GroupSnapshot
+	if err := json.Unmarshal(version.Snapshot, &snapshot); err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("invalid snapshot: %v", err))
+	}
+
+	var current models.Group
+	if err := s.db.WithContext(ctx).First(&current, version.ResourceID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return s.updateGroup(ctx, version.ResourceID, snapshot.updateParams(current.GroupType), ConfigActionRollback)
+}
+
+// ImportGroup applies a snapshot to the group of the same name, creating the group if there is
+// none. Model routing rules are left unchanged, as they may refer to groups not imported yet.
+func (s *GroupService) ImportGroup(ctx context.Context, snapshot GroupSnapshot, groupType string) (*models.Group, bool, error) {
+	if groupType == "" {
+		groupType = "standard"
+	}
+
+	var existing models.Group
+	err := s.db.WithContext(ctx).Where("name = ?", strings.TrimSpace(snapshot.Name)).First(&existing).Error
+	if err == nil {
+		if existing.GroupType != groupType {
+			return nil, false, NewI18nError(app_errors.ErrValidation, "validation.backup_group_type_mismatch", map[string]any{"name": existing.Name})
+		}
+		params := snapshot.updateParams(existing.GroupType)
+		params.ModelRoutingRules = nil
+		group, err := s.updateGroup(ctx, existing.ID, params, ConfigActionImport)
+		return group, false, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, app_errors.ParseDBError(err)
+	}
+
+	params := snapshot.updateParams(groupType)
+	group, err := s.CreateGroup(ctx, GroupCreateParams{
+		Name:                snapshot.Name,
+		DisplayName:         snapshot.DisplayName,
+		Description:         snapshot.Description,
+		GroupType:           groupType,
+		Upstreams:           json.RawMessage(snapshot.Upstreams),
+		ChannelType:         snapshot.ChannelType,
+		Sort:                snapshot.Sort,
+		TestModel:           snapshot.TestModel,
+		ValidationEndpoint:  snapshot.ValidationEndpoint,
+		ParamOverrides:      params.ParamOverrides,
+		ModelRedirectRules:  params.ModelRedirectRules,
+		ModelRedirectStrict: snapshot.ModelRedirectStrict,
+		Config:              params.Config,
+		HeaderRules:         *params.HeaderRules,
+		BodyRules:           *params.BodyRules,
+		ScriptRules:         *params.ScriptRules,
+		ProxyKeys:           snapshot.ProxyKeys,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if snapshot.ModelAccess != nil || snapshot.IPAccess != nil {
+		group, err = s.updateGroup(ctx, group.ID, GroupUpdateParams{ModelAccess: snapshot.ModelAccess, IPAccess: snapshot.IPAccess}, ConfigActionImport)
+	}
+	return group, true, err
+}
+
+// updateParams returns the update that restores a group of the given type to the snapshot.
+// Aggregate groups have no upstreams, channel type or test model of their own.
+func (snapshot GroupSnapshot) updateParams(groupType string) GroupUpdateParams {
+	var configMap map[string]any
+	if snapshot.Config != nil {
+		configMap = map[string]any(snapshot.Config)
+	} else {
+		configMap = map[string]any{}
+	}
+	paramOverrides := map[string]any(snapshot.ParamOverrides)
+	if paramOverrides == nil {
+		paramOverrides = map[string]any{}
+	}
+	redirectRules := make(map[string]string, len(snapshot.ModelRedirectRules))
+	for from, to := range snapshot.ModelRedirectRules {
+		if target, ok := to.(string); ok {
+			redirectRules[from] = target
+		}
+	}
+	headerRules := snapshot.HeaderRules
+	if headerRules == nil {
+		headerRules = []models.HeaderRule{}
+	}
+	bodyRules := snapshot.BodyRules
+	if bodyRules == nil {
+		bodyRules = []models.BodyRule{}
+	}
+	scriptRules := snapshot.ScriptRules
+	if scriptRules == nil {
+		scriptRules = []models.ScriptRule{}
+	}
+	routingRules := snapshot.ModelRoutingRules
+	if routingRules == nil {
+		routingRules = []models.ModelRoutingRule{}
+	}
+	modelAccess := snapshot.ModelAccess
+	if modelAccess == nil {
+		modelAccess = &models.ModelAccessPolicy{}
+	}
+	ipAccess := snapshot.IPAccess
+	if ipAccess == nil {
+		ipAccess = &models.IPAccessPolicy{}
+	}
+
+	params := GroupUpdateParams{
+		Name:                &snapshot.Name,
+		DisplayName:         &snapshot.DisplayName,
+		Description:         &snapshot.Description,
+		Sort:                &snapshot.Sort,
+		ValidationEndpoint:  &snapshot.ValidationEndpoint,
+		ParamOverrides:      paramOverrides,
+		ModelRedirectRules:  redirectRules,
+		ModelRedirectStrict: &snapshot.ModelRedirectStrict,
+		ModelRoutingRules:   &routingRules,
+		ModelAccess:         modelAccess,
+		IPAccess:            ipAccess,
+		Config:              configMap,
+		HeaderRules:         &headerRules,
+		BodyRules:           &bodyRules,
+		ScriptRules:         &scriptRules,
+		ProxyKeys:           &snapshot.ProxyKeys,
+	}
+	if groupType != "aggregate" {
+		params.Upstreams = json.RawMessage(snapshot.Upstreams)
+		params.HasUpstreams = true
+		params.ChannelType = &snapshot.ChannelType
+		params.TestModel = snapshot.TestModel
+		params.HasTestModel = true
+	}
+	return params
+}
+
 // DeleteGroup removes a group and associated resources.
 func (s *GroupService) DeleteGroup(ctx context.Context, id uint) error {
 	var apiKeys []models.APIKey
@@ -558,7 +1101,7 @@ func (s *GroupService) CopyGroup(ctx context.Context, sourceGroupID uint, copyKe
 
 	if len(sourceKeyValues) > 0 {
 		keysText := strings.Join(sourceKeyValues, "\n")
-		if _, err := s.keyImportSvc.StartImportTask(&newGroup, keysText); err != nil {
+		if _, err := s.keyImportSvc.StartImportTask(&newGroup, keysText, false); err != nil {
 			logrus.WithContext(ctx).WithFields(logrus.Fields{
 				"groupId":  newGroup.ID,
 				"keyCount": len(sourceKeyValues),
@@ -583,10 +1126,18 @@ func (s *GroupService) GetGroupStats(ctx context.Context, groupID uint) (*GroupS
 
 	// 根据分组类型选择不同的统计逻辑
 	if group.GroupType == "aggregate" {
-		return s.getAggregateGroupStats(ctx, groupID)
+		return s.getAggregateGroupStats(ctx, &group)
 	}
 
-	return s.getStandardGroupStats(ctx, groupID)
+	stats, err := s.getStandardGroupStats(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	// Quota cycles are configured through the effective config of the cached group
+	if cached, err := s.groupManager.GetGroupByName(group.Name); err == nil {
+		stats.Quota = s.quotaService.Report(cached, stats.KeyStats.ActiveKeys)
+	}
+	return stats, nil
 }
 
 // queryGroupHourlyStats queries aggregated hourly statistics from group_hourly_stats table
@@ -627,11 +1178,45 @@ func (s *GroupService) fetchKeyStats(ctx context.Context, groupID uint) (KeyStat
 		return KeyStats{}, fmt.Errorf("failed to get active keys: %w", err)
 	}
 
-	return KeyStats{
+	var tierRows []struct {
+		Tier         string
+		Status       string
+		Count        int64
+		RequestCount int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Select("tier, status, COUNT(*) AS count, COALESCE(SUM(request_count), 0) AS request_count").
+		Where("group_id = ?", groupID).
+		Group("tier, status").
+		Scan(&tierRows).Error; err != nil {
+		return KeyStats{}, fmt.Errorf("failed to get key tier stats: %w", err)
+	}
+
+	stats := KeyStats{
 		TotalKeys:   totalKeys,
 		ActiveKeys:  activeKeys,
 		InvalidKeys: totalKeys - activeKeys,
-	}, nil
+	}
+	tiers := []KeyTierStats{{Tier: models.KeyTierPrimary}, {Tier: models.KeyTierBackup}}
+	hasBackup := false
+	for _, row := range tierRows {
+		tier := &tiers[0]
+		if row.Tier == models.KeyTierBackup {
+			tier = &tiers[1]
+			hasBackup = true
+		}
+		if row.Status == models.KeyStatusActive {
+			tier.ActiveKeys += row.Count
+		} else {
+			tier.InvalidKeys += row.Count
+		}
+		tier.RequestCount += row.RequestCount
+	}
+	if hasBackup {
+		stats.Tiers = tiers
+	}
+
+	return stats, nil
 }
 
 // fetchRequestStats retrieves request statistics for multiple time periods
@@ -676,7 +1261,7 @@ func (s *GroupService) fetchRequestStats(ctx context.Context, groupID uint, stat
 }
 
 func (s *GroupService) getStandardGroupStats(ctx context.Context, groupID uint) (*GroupStats, error) {
-	stats := &GroupStats{}
+	stats := &GroupStats{UpstreamLatency: s.channelFactory.UpstreamLatency(groupID)}
 	var allErrors []error
 
 	// Fetch key statistics (only for standard groups)
@@ -707,9 +1292,15 @@ func (s *GroupService) getStandardGroupStats(ctx context.Context, groupID uint)
 	return stats, nil
 }
 
-func (s *GroupService) getAggregateGroupStats(ctx context.Context, groupID uint) (*GroupStats, error) {
+func (s *GroupService) getAggregateGroupStats(ctx context.Context, group *models.Group) (*GroupStats, error) {
+	groupID := group.ID
 	stats := &GroupStats{}
 
+	// Routing statistics are kept in memory, keyed by the cached group with its effective config
+	if cached, err := s.groupManager.GetGroupByName(group.Name); err == nil {
+		stats.Experiment = s.subGroupManager.ExperimentReport(cached)
+	}
+
 	// Aggregate groups only need request statistics, not key statistics
 	if errs := s.fetchRequestStats(ctx, groupID, stats); len(errs) > 0 {
 		logrus.WithContext(ctx).WithError(errs[0]).Error("errors occurred while fetching aggregate group stats")
@@ -828,10 +1419,24 @@ func (s *GroupService) validateAndCleanConfig(configMap map[string]any) (map[str
 
 // normalizeHeaderRules deduplicates and normalises header rules.
 func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatypes.JSON, error) {
-	if len(rules) == 0 {
+	normalized, err := cleanHeaderRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	if len(normalized) == 0 {
 		return nil, nil
 	}
 
+	headerRulesBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, NewI18nError(app_errors.ErrInternalServer, "error.process_header_rules", map[string]any{"error": err.Error()})
+	}
+
+	return datatypes.JSON(headerRulesBytes), nil
+}
+
+// cleanHeaderRules canonicalizes header keys, drops empty ones and rejects duplicates.
+func cleanHeaderRules(rules []models.HeaderRule) ([]models.HeaderRule, error) {
 	normalized := make([]models.HeaderRule, 0, len(rules))
 	seenKeys := make(map[string]bool)
 
@@ -848,28 +1453,267 @@ func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatype
 		normalized = append(normalized, models.HeaderRule{Key: canonicalKey, Value: rule.Value, Action: rule.Action})
 	}
 
-	if len(normalized) == 0 {
+	return normalized, nil
+}
+
+// normalizeBodyRules trims body rule paths and checks their actions. No rules are stored as an empty list.
+func normalizeBodyRules(rules []models.BodyRule) (datatypes.JSON, error) {
+	normalized := make([]models.BodyRule, 0, len(rules))
+	for _, rule := range rules {
+		path := strings.TrimSpace(rule.Path)
+		if path == "" {
+			continue
+		}
+		for _, segment := range strings.Split(path, ".") {
+			if strings.TrimSpace(segment) == "" {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_body_rule", map[string]any{"error": fmt.Sprintf("invalid path %q", path)})
+			}
+		}
+
+		switch rule.Action {
+		case "set", "default":
+			if rule.Value == nil {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_body_rule", map[string]any{"error": fmt.Sprintf("%s of %q needs a value", rule.Action, path)})
+			}
+		case "remove":
+			rule.Value = nil
+		case "max":
+			if limit, ok := rule.Value.(float64); !ok || limit < 0 {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_body_rule", map[string]any{"error": fmt.Sprintf("max of %q needs a non-negative number", path)})
+			}
+		default:
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_body_rule", map[string]any{"error": fmt.Sprintf("unknown action %q", rule.Action)})
+		}
+		normalized = append(normalized, models.BodyRule{Path: path, Action: rule.Action, Value: rule.Value})
+	}
+
+	rulesBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+	}
+	return datatypes.JSON(rulesBytes), nil
+}
+
+// normalizeScriptRules trims script rules, compiles their expressions and checks that route rules
+// target another existing group. No rules are stored as an empty list.
+func (s *GroupService) normalizeScriptRules(ctx context.Context, groupName string, rules []models.ScriptRule) (datatypes.JSON, error) {
+	normalized := make([]models.ScriptRule, 0, len(rules))
+	for _, rule := range rules {
+		when := strings.TrimSpace(rule.When)
+		value := strings.TrimSpace(rule.Value)
+		if when == "" {
+			continue
+		}
+		if _, err := utils.CompileScriptRule(when); err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_script_rule", map[string]any{"error": err.Error()})
+		}
+
+		switch rule.Action {
+		case "reject":
+		case "route":
+			if value == "" || value == groupName {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_script_rule", map[string]any{"error": "route needs another group"})
+			}
+			var count int64
+			if err := s.db.WithContext(ctx).Model(&models.Group{}).Where("name = ?", value).Count(&count).Error; err != nil {
+				return nil, app_errors.ParseDBError(err)
+			}
+			if count == 0 {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_script_rule", map[string]any{"error": fmt.Sprintf("group %q not found", value)})
+			}
+		case "model":
+			if value == "" {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_script_rule", map[string]any{"error": "model needs a model name"})
+			}
+		default:
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_script_rule", map[string]any{"error": fmt.Sprintf("unknown action %q", rule.Action)})
+		}
+		normalized = append(normalized, models.ScriptRule{When: when, Action: rule.Action, Value: value})
+	}
+
+	rulesBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+	}
+	return datatypes.JSON(rulesBytes), nil
+}
+
+// normalizeModelRoutingRules trims routing rules and checks that each one targets another existing group.
+func (s *GroupService) normalizeModelRoutingRules(ctx context.Context, groupName string, rules []models.ModelRoutingRule) (datatypes.JSON, error) {
+	normalized := make([]models.ModelRoutingRule, 0, len(rules))
+	seenPatterns := make(map[string]bool)
+
+	for _, rule := range rules {
+		pattern := strings.TrimSpace(rule.Pattern)
+		target := strings.TrimSpace(rule.Group)
+		if pattern == "" && target == "" {
+			continue
+		}
+		if pattern == "" || target == "" {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_routing", map[string]any{"error": "pattern and group are required"})
+		}
+		if seenPatterns[strings.ToLower(pattern)] {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_routing", map[string]any{"error": fmt.Sprintf("duplicate pattern %q", pattern)})
+		}
+		if target == groupName {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_routing", map[string]any{"error": "a group cannot route to itself"})
+		}
+
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Group{}).Where("name = ?", target).Count(&count).Error; err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+		if count == 0 {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_routing", map[string]any{"error": fmt.Sprintf("group %q not found", target)})
+		}
+
+		seenPatterns[strings.ToLower(pattern)] = true
+		normalized = append(normalized, models.ModelRoutingRule{Pattern: pattern, Group: target})
+	}
+
+	rulesBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+	}
+	return datatypes.JSON(rulesBytes), nil
+}
+
+// normalizeModelAccess trims and de-duplicates the model lists of a policy. An empty policy is stored as NULL.
+func normalizeModelAccess(policy models.ModelAccessPolicy) (datatypes.JSON, error) {
+	normalized := models.ModelAccessPolicy{
+		Allowed: normalizeModelList(policy.Allowed),
+		Denied:  normalizeModelList(policy.Denied),
+	}
+
+	seenKeys := make(map[string]bool)
+	for _, rule := range policy.ProxyKeys {
+		proxyKey := strings.TrimSpace(rule.ProxyKey)
+		allowed := normalizeModelList(rule.Allowed)
+		denied := normalizeModelList(rule.Denied)
+		if proxyKey == "" {
+			if len(allowed) == 0 && len(denied) == 0 {
+				continue
+			}
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_access", map[string]any{"error": "proxy key is required"})
+		}
+		if seenKeys[proxyKey] {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_model_access", map[string]any{"error": "duplicate proxy key"})
+		}
+		seenKeys[proxyKey] = true
+		if len(allowed) == 0 && len(denied) == 0 {
+			continue
+		}
+		normalized.ProxyKeys = append(normalized.ProxyKeys, models.ProxyKeyModelAccess{ProxyKey: proxyKey, Allowed: allowed, Denied: denied})
+	}
+
+	if len(normalized.Allowed) == 0 && len(normalized.Denied) == 0 && len(normalized.ProxyKeys) == 0 {
 		return nil, nil
 	}
+	policyBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+	}
+	return datatypes.JSON(policyBytes), nil
+}
 
-	headerRulesBytes, err := json.Marshal(normalized)
+// normalizeIPAccess trims and de-duplicates the address lists of a policy and rejects entries that
+// are not IP addresses or CIDR ranges. An empty policy is stored as NULL.
+func normalizeIPAccess(policy models.IPAccessPolicy) (datatypes.JSON, error) {
+	allowed, err := normalizeIPList(policy.Allowed)
 	if err != nil {
-		return nil, NewI18nError(app_errors.ErrInternalServer, "error.process_header_rules", map[string]any{"error": err.Error()})
+		return nil, err
+	}
+	denied, err := normalizeIPList(policy.Denied)
+	if err != nil {
+		return nil, err
 	}
+	normalized := models.IPAccessPolicy{Allowed: allowed, Denied: denied}
 
-	return datatypes.JSON(headerRulesBytes), nil
+	seenKeys := make(map[string]bool)
+	for _, rule := range policy.ProxyKeys {
+		proxyKey := strings.TrimSpace(rule.ProxyKey)
+		allowed, err := normalizeIPList(rule.Allowed)
+		if err != nil {
+			return nil, err
+		}
+		denied, err := normalizeIPList(rule.Denied)
+		if err != nil {
+			return nil, err
+		}
+		if proxyKey == "" {
+			if len(allowed) == 0 && len(denied) == 0 {
+				continue
+			}
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_ip_access", map[string]any{"error": "proxy key is required"})
+		}
+		if seenKeys[proxyKey] {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_ip_access", map[string]any{"error": "duplicate proxy key"})
+		}
+		seenKeys[proxyKey] = true
+		if len(allowed) == 0 && len(denied) == 0 {
+			continue
+		}
+		normalized.ProxyKeys = append(normalized.ProxyKeys, models.ProxyKeyIPAccess{ProxyKey: proxyKey, Allowed: allowed, Denied: denied})
+	}
+
+	if len(normalized.Allowed) == 0 && len(normalized.Denied) == 0 && len(normalized.ProxyKeys) == 0 {
+		return nil, nil
+	}
+	policyBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+	}
+	return datatypes.JSON(policyBytes), nil
 }
 
-// validateAndCleanUpstreams validates upstream definitions.
-func (s *GroupService) validateAndCleanUpstreams(upstreams json.RawMessage) (datatypes.JSON, error) {
+// normalizeIPList canonicalizes IP addresses and CIDR ranges and drops blanks and duplicates.
+func normalizeIPList(entries []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := utils.ParseIPNetwork(entry)
+		if err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_ip_access", map[string]any{"error": err.Error()})
+		}
+		canonical := network.String()
+		if !strings.Contains(entry, "/") {
+			canonical = network.IP.String()
+		}
+		if seen[canonical] {
+			continue
+		}
+		seen[canonical] = true
+		normalized = append(normalized, canonical)
+	}
+	return normalized, nil
+}
+
+// normalizeModelList trims model names and patterns and drops blanks and case-insensitive duplicates.
+func normalizeModelList(names []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, model := range names {
+		model = strings.TrimSpace(model)
+		if model == "" || seen[strings.ToLower(model)] {
+			continue
+		}
+		seen[strings.ToLower(model)] = true
+		normalized = append(normalized, model)
+	}
+	return normalized
+}
+
+// validateAndCleanUpstreams validates upstream definitions and normalizes their URLs.
+func (s *GroupService) validateAndCleanUpstreams(ctx context.Context, upstreams json.RawMessage, channelType string) (datatypes.JSON, error) {
 	if len(upstreams) == 0 {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": "upstreams field is required"})
 	}
 
-	var defs []struct {
-		URL    string `json:"url"`
-		Weight int    `json:"weight"`
-	}
+	var defs []models.UpstreamDefinition
 	if err := json.Unmarshal(upstreams, &defs); err != nil {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": err.Error()})
 	}
@@ -893,12 +1737,24 @@ func (s *GroupService) validateAndCleanUpstreams(upstreams json.RawMessage) (dat
 		if defs[i].Weight > 0 {
 			hasActiveUpstream = true
 		}
+
+		rules, err := cleanHeaderRules(defs[i].HeaderRules)
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) == 0 {
+			rules = nil
+		}
+		defs[i].HeaderRules = rules
+		defs[i].APIKey = strings.TrimSpace(defs[i].APIKey)
 	}
 
 	if !hasActiveUpstream {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": "at least one upstream must have a weight greater than 0"})
 	}
 
+	s.normalizeUpstreams(ctx, channelType, defs)
+
 	cleanedUpstreams, err := json.Marshal(defs)
 	if err != nil {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": err.Error()})
@@ -1001,6 +1857,9 @@ func validateModelRedirectRules(rules map[string]string) error {
 		if strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
 			return fmt.Errorf("model name cannot be empty")
 		}
+		if utils.IsModelPattern(value) {
+			return fmt.Errorf("target model '%s' cannot contain wildcards", value)
+		}
 	}
 
 	return nil
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// jobStaleAfter is how long a running job may go without a progress update before it is
+	// reported as interrupted, for jobs whose node was restarted or crashed.
+	jobStaleAfter = 5 * time.Minute
+	// jobRetention is how long finished jobs are kept.
+	jobRetention = 7 * 24 * time.Hour
+	// jobSweepInterval is how often stale and expired jobs are cleaned up.
+	jobSweepInterval = time.Minute
+)
+
+// Job types besides the key tasks, which use the TaskType constants.
+const (
+	JobTypeEncryptionRotation = "ENCRYPTION_ROTATION"
+	JobTypeModelRefresh       = "MODEL_REFRESH"
+)
+
+// JobListParams filters the job list.
+type JobListParams struct {
+	Type      string
+	Status    string
+	GroupName string
+}
+
+// jobFailureCounter is implemented by job results that count the items that failed.
+type jobFailureCounter interface {
+	jobFailures() int
+}
+
+// JobService records the progress and results of long-running operations in the jobs table, so
+// that callers get a job ID back instead of waiting, and can follow the job from any node.
+type JobService struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewJobService creates a new JobService.
+func NewJobService(db *gorm.DB) *JobService {
+	return &JobService{db: db}
+}
+
+// Create records a new running job.
+func (s *JobService) Create(jobType, groupName string, total int) (*models.Job, error) {
+	job := &models.Job{
+		ID:        uuid.NewString(),
+		Type:      jobType,
+		Status:    models.JobStatusRunning,
+		GroupName: groupName,
+		Total:     total,
+		StartedAt: time.Now(),
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	return job, nil
+}
+
+// Progress updates the counters of a running job.
+func (s *JobService) Progress(id string, total, processed, failed int) error {
+	return s.db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusRunning).
+		Updates(map[string]any{
+			"total":      total,
+			"processed":  processed,
+			"failed":     failed,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// Finish marks a job as finished. A job fails when err is set, and is partial when its result
+// reports failed items.
+func (s *JobService) Finish(id string, result any, err error) error {
+	now := time.Now()
+	updates := map[string]any{
+		"status":      models.JobStatusSucceeded,
+		"finished_at": now,
+		"updated_at":  now,
+	}
+	if counter, ok := result.(jobFailureCounter); ok {
+		if failed := counter.jobFailures(); failed > 0 {
+			updates["failed"] = failed
+			updates["status"] = models.JobStatusPartial
+		}
+	}
+	if result != nil {
+		encoded, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			return fmt.Errorf("failed to serialize job result: %w", marshalErr)
+		}
+		updates["result"] = encoded
+	}
+	if err != nil {
+		updates["status"] = models.JobStatusFailed
+		updates["error"] = err.Error()
+	}
+	return s.db.Model(&models.Job{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Get returns a job by ID.
+func (s *JobService) Get(id string) (*models.Job, error) {
+	s.sweep()
+	var job models.Job
+	if err := s.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Query returns the filtered jobs, newest first.
+func (s *JobService) Query(params JobListParams) *gorm.DB {
+	s.sweep()
+	query := s.db.Model(&models.Job{})
+	if params.Type != "" {
+		query = query.Where("type = ?", params.Type)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if params.GroupName != "" {
+		query = query.Where("group_name = ?", params.GroupName)
+	}
+	return query.Order("started_at desc, id desc")
+}
+
+// sweep fails running jobs that stopped reporting progress and deletes finished jobs past the
+// retention period, at most once per sweep interval.
+func (s *JobService) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastSweep) < jobSweepInterval {
+		return
+	}
+	s.lastSweep = time.Now()
+
+	now := time.Now()
+	if err := s.db.Model(&models.Job{}).
+		Where("status = ? AND updated_at < ?", models.JobStatusRunning, now.Add(-jobStaleAfter)).
+		Updates(map[string]any{
+			"status":      models.JobStatusFailed,
+			"error":       "interrupted: no progress reported, the server running the job may have restarted",
+			"finished_at": now,
+		}).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to mark stale jobs")
+	}
+	if err := s.db.Where("finished_at < ?", now.Add(-jobRetention)).Delete(&models.Job{}).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to prune old jobs")
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// keyExpiryCheckInterval is how often keys are checked for reaching their expiry date.
+	keyExpiryCheckInterval = time.Minute
+	// keyExpiryBatchSize bounds how many keys are disabled per transaction.
+	keyExpiryBatchSize = 500
+)
+
+// KeyExpiryService disables keys once their expiry date is reached and warns about keys expiring
+// within key_expiry_warning_days.
+type KeyExpiryService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	keyService      *KeyService
+	notifier        *notification.Service
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewKeyExpiryService creates a new KeyExpiryService.
+func NewKeyExpiryService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	keyService *KeyService,
+	notifier *notification.Service,
+) *KeyExpiryService {
+	return &KeyExpiryService{
+		db:              db,
+		settingsManager: settingsManager,
+		keyService:      keyService,
+		notifier:        notifier,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start starts the periodic expiry check.
+func (s *KeyExpiryService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Key expiry service started")
+}
+
+// Stop stops the service.
+func (s *KeyExpiryService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("KeyExpiryService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("KeyExpiryService stop timed out.")
+	}
+}
+
+func (s *KeyExpiryService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(keyExpiryCheckInterval)
+	defer ticker.Stop()
+
+	s.check()
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *KeyExpiryService) check() {
+	now := time.Now()
+	s.expireKeys(now)
+	if days := s.settingsManager.GetSettings().KeyExpiryWarningDays; days > 0 {
+		s.warnExpiringKeys(now, days)
+	}
+}
+
+// expireKeys disables the active keys whose expiry date has passed.
+func (s *KeyExpiryService) expireKeys(now time.Time) {
+	expiredByGroup := make(map[uint]int)
+	for {
+		var keys []models.APIKey
+		if err := s.db.Select("id", "group_id").
+			Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", models.KeyStatusActive, now).
+			Limit(keyExpiryBatchSize).Find(&keys).Error; err != nil {
+			logrus.Errorf("KeyExpiryService: Failed to get expired keys: %v", err)
+			break
+		}
+		if len(keys) == 0 {
+			break
+		}
+		expired, err := s.keyService.KeyProvider.ExpireKeys(keys)
+		if err != nil {
+			logrus.Errorf("KeyExpiryService: Failed to disable expired keys: %v", err)
+			break
+		}
+		for _, key := range expired {
+			expiredByGroup[key.GroupID]++
+		}
+		if len(keys) < keyExpiryBatchSize {
+			break
+		}
+	}
+
+	for groupID, count := range expiredByGroup {
+		groupName := s.groupName(groupID)
+		logrus.Infof("KeyExpiryService: Disabled %d expired keys in group '%s'.", count, groupName)
+		s.notifier.Notify(notification.Event{
+			Category:  models.NotificationCategoryKey,
+			Severity:  models.NotificationSeverityWarning,
+			Event:     notification.EventKeyExpired,
+			Message:   fmt.Sprintf("%d keys in group '%s' expired and were disabled", count, groupName),
+			Params:    map[string]any{"count": count},
+			GroupID:   groupID,
+			GroupName: groupName,
+		})
+	}
+}
+
+// warnExpiringKeys sends one notification per group for the active keys expiring within days that
+// have not been warned about yet.
+func (s *KeyExpiryService) warnExpiringKeys(now time.Time, days int) {
+	var rows []struct {
+		GroupID uint
+		Count   int
+	}
+	query := s.db.Model(&models.APIKey{}).
+		Where("status = ? AND expires_at > ? AND expires_at <= ? AND expiry_warned_at IS NULL",
+			models.KeyStatusActive, now, now.AddDate(0, 0, days)).
+		Session(&gorm.Session{})
+	if err := query.Select("group_id, COUNT(*) AS count").
+		Group("group_id").Scan(&rows).Error; err != nil {
+		logrus.Errorf("KeyExpiryService: Failed to get expiring keys: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	if err := query.Update("expiry_warned_at", now).Error; err != nil {
+		logrus.Errorf("KeyExpiryService: Failed to mark expiring keys as warned: %v", err)
+		return
+	}
+
+	for _, row := range rows {
+		groupName := s.groupName(row.GroupID)
+		s.notifier.Notify(notification.Event{
+			Category:  models.NotificationCategoryKey,
+			Severity:  models.NotificationSeverityWarning,
+			Event:     notification.EventKeyExpiring,
+			Message:   fmt.Sprintf("%d keys in group '%s' expire within %d days", row.Count, groupName, days),
+			Params:    map[string]any{"count": row.Count, "days": days},
+			GroupID:   row.GroupID,
+			GroupName: groupName,
+		})
+	}
+}
+
+func (s *KeyExpiryService) groupName(groupID uint) string {
+	var group models.Group
+	if err := s.db.Select("name").First(&group, groupID).Error; err != nil {
+		return fmt.Sprintf("#%d", groupID)
+	}
+	return group.Name
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// keyHealthEventLimit bounds how many of the most recent health events a history returns.
+	keyHealthEventLimit = 200
+	// keyHealthHourlyMaxDays is the longest history reported in hourly buckets; longer ones use days.
+	keyHealthHourlyMaxDays = 7
+)
+
+// KeyHealthBucket captures the outcomes of a key's requests and validations in one time bucket.
+type KeyHealthBucket struct {
+	Time         time.Time `json:"time"`
+	SuccessCount int64     `json:"success_count"`
+	FailureCount int64     `json:"failure_count"`
+	// SuccessRate is between 0 and 1, or -1 when the key was not used in the bucket.
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// KeyHealthHistory is the health timeline of a key.
+type KeyHealthHistory struct {
+	KeyID    uint   `json:"key_id"`
+	Status   string `json:"status"`
+	Interval string `json:"interval"` // "hour" or "day"
+	// Buckets cover the whole period, oldest first.
+	Buckets []KeyHealthBucket `json:"buckets"`
+	// Events are the most recent validation results and proxy failures, newest first.
+	Events []models.KeyHealthEvent `json:"events"`
+}
+
+// KeyHealthService reports how the health of a key changed over time, from its recorded
+// validation results and proxy failures and from the request logs of the requests it served.
+type KeyHealthService struct {
+	db *gorm.DB
+}
+
+// NewKeyHealthService creates a new KeyHealthService.
+func NewKeyHealthService(db *gorm.DB) *KeyHealthService {
+	return &KeyHealthService{db: db}
+}
+
+// History returns the health timeline of a key over the last days. Success rates count every
+// request attempt made with the key, including retried ones, and every validation.
+func (s *KeyHealthService) History(key *models.APIKey, days int) (*KeyHealthHistory, error) {
+	interval, intervalName := time.Hour, "hour"
+	if days > keyHealthHourlyMaxDays {
+		interval, intervalName = 24*time.Hour, "day"
+	}
+	now := time.Now()
+	start := now.Add(-time.Duration(days) * 24 * time.Hour).UTC().Truncate(interval)
+
+	buckets := make(map[time.Time]*KeyHealthBucket)
+	var ordered []*KeyHealthBucket
+	for t := start; !t.After(now); t = t.Add(interval) {
+		bucket := &KeyHealthBucket{Time: t}
+		buckets[t] = bucket
+		ordered = append(ordered, bucket)
+	}
+	count := func(at time.Time, isSuccess bool) {
+		bucket, ok := buckets[at.UTC().Truncate(interval)]
+		if !ok {
+			return
+		}
+		if isSuccess {
+			bucket.SuccessCount++
+		} else {
+			bucket.FailureCount++
+		}
+	}
+
+	rows, err := s.db.Model(&models.RequestLog{}).
+		Select("timestamp, is_success").
+		Where("key_hash = ? AND timestamp >= ?", key.KeyHash, start).
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request logs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var log models.RequestLog
+		if err := s.db.ScanRows(rows, &log); err != nil {
+			return nil, fmt.Errorf("failed to scan request log: %w", err)
+		}
+		count(log.Timestamp, log.IsSuccess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read request logs: %w", err)
+	}
+
+	// Proxy failures are already counted from the request logs.
+	var validations []models.KeyHealthEvent
+	if err := s.db.Select("created_at, is_success").
+		Where("key_id = ? AND source = ? AND created_at >= ?", key.ID, models.KeyHealthSourceValidation, start).
+		Find(&validations).Error; err != nil {
+		return nil, fmt.Errorf("failed to read key validations: %w", err)
+	}
+	for _, event := range validations {
+		count(event.CreatedAt, event.IsSuccess)
+	}
+
+	history := &KeyHealthHistory{
+		KeyID:    key.ID,
+		Status:   key.Status,
+		Interval: intervalName,
+		Buckets:  make([]KeyHealthBucket, 0, len(ordered)),
+		Events:   []models.KeyHealthEvent{},
+	}
+	for _, bucket := range ordered {
+		bucket.SuccessRate = -1
+		if total := bucket.SuccessCount + bucket.FailureCount; total > 0 {
+			bucket.SuccessRate = float64(bucket.SuccessCount) / float64(total)
+		}
+		history.Buckets = append(history.Buckets, *bucket)
+	}
+
+	if err := s.db.Where("key_id = ? AND created_at >= ?", key.ID, start).
+		Order("created_at desc, id desc").Limit(keyHealthEventLimit).
+		Find(&history.Events).Error; err != nil {
+		return nil, fmt.Errorf("failed to read key health events: %w", err)
+	}
+
+	return history, nil
+}
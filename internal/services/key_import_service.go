@@ -11,24 +11,28 @@ import (
 type KeyImportResult struct {
 	AddedCount   int `json:"added_count"`
 	IgnoredCount int `json:"ignored_count"`
+	BulkResult
 }
 
 // KeyImportService handles the asynchronous import of a large number of keys.
 type KeyImportService struct {
-	TaskService *TaskService
-	KeyService  *KeyService
+	TaskService  *TaskService
+	KeyService   *KeyService
+	ModelService *ModelService
 }
 
 // NewKeyImportService creates a new KeyImportService.
-func NewKeyImportService(taskService *TaskService, keyService *KeyService) *KeyImportService {
+func NewKeyImportService(taskService *TaskService, keyService *KeyService, modelService *ModelService) *KeyImportService {
 	return &KeyImportService{
-		TaskService: taskService,
-		KeyService:  keyService,
+		TaskService:  taskService,
+		KeyService:   keyService,
+		ModelService: modelService,
 	}
 }
 
 // StartImportTask initiates a new asynchronous key import task.
-func (s *KeyImportService) StartImportTask(group *models.Group, keysText string) (*TaskStatus, error) {
+// With transactional set, no key is imported unless every key can be imported.
+func (s *KeyImportService) StartImportTask(group *models.Group, keysText string, transactional bool) (*TaskStatus, error) {
 	keys := s.KeyService.ParseKeysFromText(keysText)
 	if len(keys) == 0 {
 		return nil, fmt.Errorf("no valid keys found in the input text")
@@ -39,19 +43,19 @@ func (s *KeyImportService) StartImportTask(group *models.Group, keysText string)
 		return nil, err
 	}
 
-	go s.runImport(group, keys)
+	go s.runImport(group, keys, transactional)
 
 	return initialStatus, nil
 }
 
-func (s *KeyImportService) runImport(group *models.Group, keys []string) {
+func (s *KeyImportService) runImport(group *models.Group, keys []string, transactional bool) {
 	progressCallback := func(processed int) {
 		if err := s.TaskService.UpdateProgress(processed); err != nil {
 			logrus.Warnf("Failed to update task progress for group %d: %v", group.ID, err)
 		}
 	}
 
-	addedCount, ignoredCount, err := s.KeyService.processAndCreateKeys(group.ID, keys, progressCallback)
+	bulk, err := s.KeyService.processAndCreateKeys(group.ID, keys, transactional, progressCallback)
 	if err != nil {
 		if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
 			logrus.Errorf("Failed to end task with error for group %d: %v (original error: %v)", group.ID, endErr, err)
@@ -59,12 +63,18 @@ func (s *KeyImportService) runImport(group *models.Group, keys []string) {
 		return
 	}
 
+	addedCount, ignoredCount := bulk.Counts()
 	result := KeyImportResult{
 		AddedCount:   addedCount,
 		IgnoredCount: ignoredCount,
+		BulkResult:   *bulk,
 	}
 
 	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
 		logrus.Errorf("Failed to end task with success result for group %d: %v", group.ID, endErr)
 	}
+
+	if addedCount > 0 {
+		s.ModelService.WarmUpModels(group.ID)
+	}
 }
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// keySpendScale stores spend in micro-dollars so that it can be counted with HIncrBy.
+const keySpendScale = 1e6
+
+// KeyLimitService enforces the rpm_limit, tpm_limit and daily_spend_limit of individual keys, so
+// that keys of a lower vendor tier are passed over when they are at their limit instead of being
+// sent requests that end in 429s. Requests and tokens are counted per minute and spend per UTC
+// day in the shared store.
+type KeyLimitService struct {
+	store    store.Store
+	webhooks *WebhookService
+	// windows remembers the last counter key seen per key and window so that the previous
+	// window's counters are dropped.
+	windows sync.Map
+}
+
+// NewKeyLimitService creates a new KeyLimitService.
+func NewKeyLimitService(store store.Store, webhooks *WebhookService) *KeyLimitService {
+	return &KeyLimitService{store: store, webhooks: webhooks}
+}
+
+// hasLimits reports whether a key has any limit of its own.
+func hasLimits(key *models.APIKey) bool {
+	return key.RPMLimit > 0 || key.TPMLimit > 0 || key.DailySpendLimit > 0
+}
+
+// rateCounterKey returns the counter of the key's requests and tokens in the minute of now.
+func rateCounterKey(keyID uint, now time.Time) string {
+	return fmt.Sprintf("key_rate:%d:%d", keyID, now.Truncate(time.Minute).Unix())
+}
+
+// spendCounterKey returns the counter of the key's spend on the UTC day of now.
+func spendCounterKey(keyID uint, now time.Time) string {
+	return fmt.Sprintf("key_spend:%d:%s", keyID, now.UTC().Format("20060102"))
+}
+
+// Limited reports whether a key has reached one of its limits, and how long until the window
+// that limits it resets.
+func (s *KeyLimitService) Limited(key *models.APIKey) (time.Duration, bool) {
+	if !hasLimits(key) {
+		return 0, false
+	}
+	now := time.Now()
+
+	if key.RPMLimit > 0 || key.TPMLimit > 0 {
+		usage, err := s.store.HGetAll(rateCounterKey(key.ID, now))
+		if err == nil {
+			requests, _ := strconv.ParseInt(usage["requests"], 10, 64)
+			tokens, _ := strconv.ParseInt(usage["tokens"], 10, 64)
+			if (key.RPMLimit > 0 && requests >= int64(key.RPMLimit)) || (key.TPMLimit > 0 && tokens >= int64(key.TPMLimit)) {
+				return now.Truncate(time.Minute).Add(time.Minute).Sub(now), true
+			}
+		}
+	}
+
+	if key.DailySpendLimit > 0 {
+		usage, err := s.store.HGetAll(spendCounterKey(key.ID, now))
+		if err == nil {
+			spend, _ := strconv.ParseInt(usage["spend"], 10, 64)
+			if float64(spend) >= key.DailySpendLimit*keySpendScale {
+				day := now.UTC().Truncate(24 * time.Hour)
+				return day.Add(24 * time.Hour).Sub(now), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// CountRequest counts a request sent with the key against its rpm_limit.
+func (s *KeyLimitService) CountRequest(key *models.APIKey) {
+	if key.RPMLimit <= 0 {
+		return
+	}
+	s.incr(key.ID, "rate", rateCounterKey(key.ID, time.Now()), "requests", 1)
+}
+
+// RecordUsage counts the tokens and the cost in USD of a successful request against the key's
+// tpm_limit and daily_spend_limit. The request that takes the spend past the limit publishes a
+// budget.exceeded event; the shared counter makes it the only one across instances.
+func (s *KeyLimitService) RecordUsage(key *models.APIKey, totalTokens int, cost float64) {
+	now := time.Now()
+	if key.TPMLimit > 0 && totalTokens > 0 {
+		s.incr(key.ID, "rate", rateCounterKey(key.ID, now), "tokens", int64(totalTokens))
+	}
+	if key.DailySpendLimit > 0 && cost > 0 {
+		amount := int64(cost * keySpendScale)
+		spend, ok := s.incr(key.ID, "spend", spendCounterKey(key.ID, now), "spend", amount)
+		limit := key.DailySpendLimit * keySpendScale
+		if ok && float64(spend) >= limit && float64(spend-amount) < limit {
+			s.webhooks.Publish(WebhookEventBudgetExceeded, key.GroupID, "",
+				fmt.Sprintf("Key %s reached its daily spend limit of $%.2f", utils.MaskAPIKey(key.KeyValue), key.DailySpendLimit),
+				map[string]any{
+					"key_id":      key.ID,
+					"key":         utils.MaskAPIKey(key.KeyValue),
+					"limit_usd":   key.DailySpendLimit,
+					"spend_usd":   float64(spend) / keySpendScale,
+					"resets_at":   now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour).Format(time.RFC3339),
+					"limit_scope": "key_daily_spend",
+				})
+		}
+	}
+}
+
+// incr adds to a counter field, dropping the key's counter of the previous window. It returns the
+// new value of the field, and false when it could not be updated.
+func (s *KeyLimitService) incr(keyID uint, window, counterKey, field string, amount int64) (int64, bool) {
+	if previous, loaded := s.windows.Swap(fmt.Sprintf("%s:%d", window, keyID), counterKey); loaded && previous != counterKey {
+		if err := s.store.Delete(previous.(string)); err != nil {
+			logrus.WithError(err).Debug("Failed to drop previous key limit counters")
+		}
+	}
+	value, err := s.store.HIncrBy(counterKey, field, amount)
+	if err != nil {
+		logrus.WithError(err).WithField("keyID", keyID).Warn("Failed to record key limit usage")
+		return 0, false
+	}
+	return value, true
+}
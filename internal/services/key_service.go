@@ -6,9 +6,12 @@ import (
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 	"io"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -24,6 +27,7 @@ type AddKeysResult struct {
 	AddedCount   int   `json:"added_count"`
 	IgnoredCount int   `json:"ignored_count"`
 	TotalInGroup int64 `json:"total_in_group"`
+	BulkResult
 }
 
 // DeleteKeysResult holds the result of deleting multiple keys.
@@ -31,6 +35,7 @@ type DeleteKeysResult struct {
 	DeletedCount int   `json:"deleted_count"`
 	IgnoredCount int   `json:"ignored_count"`
 	TotalInGroup int64 `json:"total_in_group"`
+	BulkResult
 }
 
 // RestoreKeysResult holds the result of restoring multiple keys.
@@ -38,6 +43,14 @@ type RestoreKeysResult struct {
 	RestoredCount int   `json:"restored_count"`
 	IgnoredCount  int   `json:"ignored_count"`
 	TotalInGroup  int64 `json:"total_in_group"`
+	BulkResult
+}
+
+// SetKeysTierResult holds the result of moving multiple keys to a tier.
+type SetKeysTierResult struct {
+	UpdatedCount int `json:"updated_count"`
+	IgnoredCount int `json:"ignored_count"`
+	BulkResult
 }
 
 // KeyService provides services related to API keys.
@@ -59,8 +72,9 @@ func NewKeyService(db *gorm.DB, keyProvider *keypool.KeyProvider, keyValidator *
 }
 
 // AddMultipleKeys handles the business logic of creating new keys from a text block.
+// With transactional set, no key is added unless every key can be added.
 // deprecated: use KeyImportService for large imports
-func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysResult, error) {
+func (s *KeyService) AddMultipleKeys(groupID uint, keysText string, transactional bool) (*AddKeysResult, error) {
 	keys := s.ParseKeysFromText(keysText)
 	if len(keys) > maxRequestKeys {
 		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keys))
@@ -69,10 +83,11 @@ func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysRes
 		return nil, fmt.Errorf("no valid keys found in the input text")
 	}
 
-	addedCount, ignoredCount, err := s.processAndCreateKeys(groupID, keys, nil)
+	bulk, err := s.processAndCreateKeys(groupID, keys, transactional, nil)
 	if err != nil {
 		return nil, err
 	}
+	addedCount, ignoredCount := bulk.Counts()
 
 	var totalInGroup int64
 	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Count(&totalInGroup).Error; err != nil {
@@ -83,78 +98,192 @@ func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysRes
 		AddedCount:   addedCount,
 		IgnoredCount: ignoredCount,
 		TotalInGroup: totalInGroup,
+		BulkResult:   *bulk,
 	}, nil
 }
 
 // processAndCreateKeys is the lowest-level reusable function for adding keys.
+// It reports the outcome of every input key; the error is only set when nothing could be attempted.
 func (s *KeyService) processAndCreateKeys(
 	groupID uint,
 	keys []string,
+	transactional bool,
 	progressCallback func(processed int),
-) (addedCount int, ignoredCount int, err error) {
+) (*BulkResult, error) {
+	result := newBulkResult(maskKeys(keys), transactional)
+
 	// 1. Get existing key hashes in the group for deduplication
 	var existingHashes []string
 	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("key_hash", &existingHashes).Error; err != nil {
-		return 0, 0, err
+		return nil, err
 	}
 	existingHashMap := make(map[string]bool)
 	for _, h := range existingHashes {
 		existingHashMap[h] = true
 	}
 
-	// 2. Prepare new keys for creation
+	// 2. Prepare new keys for creation, remembering each key's position in the input
 	var newKeysToCreate []models.APIKey
+	var newKeyIndexes []int
 	uniqueNewKeys := make(map[string]bool)
 
-	for _, keyVal := range keys {
+	for i, keyVal := range keys {
 		trimmedKey := strings.TrimSpace(keyVal)
-		if trimmedKey == "" || uniqueNewKeys[trimmedKey] || !s.isValidKeyFormat(trimmedKey) {
+		if trimmedKey == "" || !s.isValidKeyFormat(trimmedKey) {
+			result.skip(i, BulkReasonInvalidFormat)
+			continue
+		}
+		if uniqueNewKeys[trimmedKey] {
+			result.skip(i, BulkReasonDuplicate)
 			continue
 		}
+		uniqueNewKeys[trimmedKey] = true
 
-		// Generate hash for deduplication check
+		// Generate hash for deduplication check, including hashes of keys not yet migrated to the current encryption key
 		keyHash := s.EncryptionSvc.Hash(trimmedKey)
-		if existingHashMap[keyHash] {
+		if slices.ContainsFunc(s.EncryptionSvc.LookupHashes(trimmedKey), func(h string) bool { return existingHashMap[h] }) {
+			result.skip(i, BulkReasonAlreadyExists)
 			continue
 		}
 
 		encryptedKey, err := s.EncryptionSvc.Encrypt(trimmedKey)
 		if err != nil {
-			logrus.WithError(err).WithField("key", trimmedKey).Error("Failed to encrypt key, skipping")
+			logrus.WithError(err).WithField("key", utils.MaskAPIKey(trimmedKey)).Error("Failed to encrypt key")
+			result.fail(i, fmt.Errorf("failed to encrypt key: %w", err))
 			continue
 		}
 
-		uniqueNewKeys[trimmedKey] = true
 		newKeysToCreate = append(newKeysToCreate, models.APIKey{
 			GroupID:  groupID,
 			KeyValue: encryptedKey,
 			KeyHash:  keyHash,
 			Status:   models.KeyStatusActive,
 		})
+		newKeyIndexes = append(newKeyIndexes, i)
 	}
 
-	if len(newKeysToCreate) == 0 {
-		return 0, len(keys), nil
+	if transactional {
+		if result.hasFailures() {
+			result.rollBack()
+			return result, nil
+		}
+		if err := s.KeyProvider.AddKeys(groupID, newKeysToCreate); err != nil {
+			for _, idx := range newKeyIndexes {
+				result.fail(idx, err)
+			}
+			result.rollBack()
+			return result, nil
+		}
+		for _, idx := range newKeyIndexes {
+			result.succeed(idx)
+		}
+		if progressCallback != nil {
+			progressCallback(len(keys))
+		}
+		return result, nil
 	}
 
-	// 3. Use KeyProvider to add keys in chunks
+	// 3. Use KeyProvider to add keys in chunks; a failed chunk is retried key by key
+	// so that one bad key does not take the rest of its chunk down with it.
 	for i := 0; i < len(newKeysToCreate); i += chunkSize {
-		end := i + chunkSize
-		if end > len(newKeysToCreate) {
-			end = len(newKeysToCreate)
-		}
+		end := min(i+chunkSize, len(newKeysToCreate))
 		chunk := newKeysToCreate[i:end]
 		if err := s.KeyProvider.AddKeys(groupID, chunk); err != nil {
-			return addedCount, len(keys) - addedCount, err
+			logrus.WithError(err).WithField("groupID", groupID).Warn("Failed to add key chunk, retrying keys individually")
+			for j := range chunk {
+				chunk[j].ID = 0
+				if err := s.KeyProvider.AddKeys(groupID, chunk[j:j+1]); err != nil {
+					result.fail(newKeyIndexes[i+j], err)
+				} else {
+					result.succeed(newKeyIndexes[i+j])
+				}
+			}
+		} else {
+			for _, idx := range newKeyIndexes[i:end] {
+				result.succeed(idx)
+			}
 		}
-		addedCount += len(chunk)
 
 		if progressCallback != nil {
 			progressCallback(i + len(chunk))
 		}
 	}
 
-	return addedCount, len(keys) - addedCount, nil
+	return result, nil
+}
+
+// applyToExistingKeys runs a key provider operation over the keys in chunks of size and reports,
+// per input key, whether the operation matched it. Keys the operation did not touch are skipped
+// with missReason. With transactional set, the operation runs once over all keys.
+func (s *KeyService) applyToExistingKeys(
+	keys []string,
+	transactional bool,
+	size int,
+	missReason string,
+	operation func(keyValues []string) ([]models.APIKey, error),
+	progressCallback func(processed int),
+) *BulkResult {
+	result := newBulkResult(maskKeys(keys), transactional)
+
+	var uniqueKeys []string
+	var uniqueIndexes []int
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		if seen[key] {
+			result.skip(i, BulkReasonDuplicate)
+			continue
+		}
+		seen[key] = true
+		uniqueKeys = append(uniqueKeys, key)
+		uniqueIndexes = append(uniqueIndexes, i)
+	}
+
+	if transactional {
+		size = len(uniqueKeys)
+	}
+
+	for i := 0; i < len(uniqueKeys); i += size {
+		end := min(i+size, len(uniqueKeys))
+		chunk := uniqueKeys[i:end]
+
+		matched, err := operation(chunk)
+		if err != nil {
+			for _, idx := range uniqueIndexes[i:end] {
+				result.fail(idx, err)
+			}
+			if transactional {
+				result.rollBack()
+				return result
+			}
+		} else {
+			matchedHashes := make(map[string]bool, len(matched))
+			for _, key := range matched {
+				matchedHashes[key.KeyHash] = true
+			}
+			for j, key := range chunk {
+				if slices.ContainsFunc(s.EncryptionSvc.LookupHashes(key), func(h string) bool { return matchedHashes[h] }) {
+					result.succeed(uniqueIndexes[i+j])
+				} else {
+					result.skip(uniqueIndexes[i+j], missReason)
+				}
+			}
+		}
+
+		if progressCallback != nil {
+			progressCallback(uniqueIndexes[end-1] + 1)
+		}
+	}
+
+	return result
+}
+
+// maskKeys masks key values for reporting them in bulk results.
+func maskKeys(keys []string) []string {
+	masked := make([]string, len(keys))
+	for i, key := range keys {
+		masked[i] = utils.MaskAPIKey(strings.TrimSpace(key))
+	}
+	return masked
 }
 
 // ParseKeysFromText parses a string of keys from various formats into a string slice.
@@ -199,7 +328,7 @@ func (s *KeyService) isValidKeyFormat(key string) bool {
 }
 
 // RestoreMultipleKeys handles the business logic of restoring keys from a text block.
-func (s *KeyService) RestoreMultipleKeys(groupID uint, keysText string) (*RestoreKeysResult, error) {
+func (s *KeyService) RestoreMultipleKeys(groupID uint, keysText string, transactional bool) (*RestoreKeysResult, error) {
 	keysToRestore := s.ParseKeysFromText(keysText)
 	if len(keysToRestore) > maxRequestKeys {
 		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keysToRestore))
@@ -208,21 +337,10 @@ func (s *KeyService) RestoreMultipleKeys(groupID uint, keysText string) (*Restor
 		return nil, fmt.Errorf("no valid keys found in the input text")
 	}
 
-	var totalRestoredCount int64
-	for i := 0; i < len(keysToRestore); i += chunkSize {
-		end := i + chunkSize
-		if end > len(keysToRestore) {
-			end = len(keysToRestore)
-		}
-		chunk := keysToRestore[i:end]
-		restoredCount, err := s.KeyProvider.RestoreMultipleKeys(groupID, chunk)
-		if err != nil {
-			return nil, err
-		}
-		totalRestoredCount += restoredCount
-	}
-
-	ignoredCount := len(keysToRestore) - int(totalRestoredCount)
+	bulk := s.applyToExistingKeys(keysToRestore, transactional, chunkSize, BulkReasonNotInvalid, func(keyValues []string) ([]models.APIKey, error) {
+		return s.KeyProvider.RestoreMultipleKeys(groupID, keyValues)
+	}, nil)
+	restoredCount, ignoredCount := bulk.Counts()
 
 	var totalInGroup int64
 	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Count(&totalInGroup).Error; err != nil {
@@ -230,9 +348,32 @@ func (s *KeyService) RestoreMultipleKeys(groupID uint, keysText string) (*Restor
 	}
 
 	return &RestoreKeysResult{
-		RestoredCount: int(totalRestoredCount),
+		RestoredCount: restoredCount,
 		IgnoredCount:  ignoredCount,
 		TotalInGroup:  totalInGroup,
+		BulkResult:    *bulk,
+	}, nil
+}
+
+// SetKeysTier handles the business logic of moving keys from a text block to the primary or backup tier.
+func (s *KeyService) SetKeysTier(groupID uint, keysText string, tier string, transactional bool) (*SetKeysTierResult, error) {
+	keysToUpdate := s.ParseKeysFromText(keysText)
+	if len(keysToUpdate) > maxRequestKeys {
+		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keysToUpdate))
+	}
+	if len(keysToUpdate) == 0 {
+		return nil, fmt.Errorf("no valid keys found in the input text")
+	}
+
+	bulk := s.applyToExistingKeys(keysToUpdate, transactional, chunkSize, BulkReasonNotFound, func(keyValues []string) ([]models.APIKey, error) {
+		return s.KeyProvider.SetKeysTier(groupID, keyValues, tier)
+	}, nil)
+	updatedCount, ignoredCount := bulk.Counts()
+
+	return &SetKeysTierResult{
+		UpdatedCount: updatedCount,
+		IgnoredCount: ignoredCount,
+		BulkResult:   *bulk,
 	}, nil
 }
 
@@ -252,7 +393,7 @@ func (s *KeyService) ClearAllKeys(groupID uint) (int64, error) {
 }
 
 // DeleteMultipleKeys handles the business logic of deleting keys from a text block.
-func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string) (*DeleteKeysResult, error) {
+func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string, transactional bool) (*DeleteKeysResult, error) {
 	keysToDelete := s.ParseKeysFromText(keysText)
 	if len(keysToDelete) > maxRequestKeys {
 		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keysToDelete))
@@ -261,21 +402,10 @@ func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string) (*DeleteK
 		return nil, fmt.Errorf("no valid keys found in the input text")
 	}
 
-	var totalDeletedCount int64
-	for i := 0; i < len(keysToDelete); i += chunkSize {
-		end := i + chunkSize
-		if end > len(keysToDelete) {
-			end = len(keysToDelete)
-		}
-		chunk := keysToDelete[i:end]
-		deletedCount, err := s.KeyProvider.RemoveKeys(groupID, chunk)
-		if err != nil {
-			return nil, err
-		}
-		totalDeletedCount += deletedCount
-	}
-
-	ignoredCount := len(keysToDelete) - int(totalDeletedCount)
+	bulk := s.applyToExistingKeys(keysToDelete, transactional, chunkSize, BulkReasonNotFound, func(keyValues []string) ([]models.APIKey, error) {
+		return s.KeyProvider.RemoveKeys(groupID, keyValues)
+	}, nil)
+	deletedCount, ignoredCount := bulk.Counts()
 
 	var totalInGroup int64
 	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Count(&totalInGroup).Error; err != nil {
@@ -283,29 +413,84 @@ func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string) (*DeleteK
 	}
 
 	return &DeleteKeysResult{
-		DeletedCount: int(totalDeletedCount),
+		DeletedCount: deletedCount,
 		IgnoredCount: ignoredCount,
 		TotalInGroup: totalInGroup,
+		BulkResult:   *bulk,
 	}, nil
 }
 
 // ListKeysInGroupQuery builds a query to list all keys within a specific group, filtered by status.
-func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHash string) *gorm.DB {
+// With expiresBefore set, only keys with an expiry date up to it are listed, the soonest first,
+// and other lists put the most recently used keys first, unless sorted is set because the caller
+// orders the query itself.
+func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHashes []string, expiresBefore *time.Time, sorted bool) *gorm.DB {
 	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID)
 
 	if statusFilter != "" {
 		query = query.Where("status = ?", statusFilter)
 	}
 
-	if searchHash != "" {
-		query = query.Where("key_hash = ?", searchHash)
+	if len(searchHashes) > 0 {
+		query = query.Where("key_hash IN ?", searchHashes)
 	}
 
-	query = query.Order("last_used_at desc, updated_at desc")
+	if expiresBefore != nil {
+		query = query.Where("expires_at IS NOT NULL AND expires_at <= ?", *expiresBefore)
+		if !sorted {
+			query = query.Order("expires_at asc")
+		}
+		return query
+	}
+
+	if !sorted {
+		query = query.Order("last_used_at desc, updated_at desc")
+	}
 
 	return query
 }
 
+// SetKeyLimits sets the requests per minute, tokens per minute and daily spend (USD) limits of a
+// key, 0 meaning no limit, and makes them effective for key selection right away.
+func (s *KeyService) SetKeyLimits(key *models.APIKey, rpmLimit, tpmLimit int, dailySpendLimit float64) error {
+	if err := s.DB.Model(key).Updates(map[string]any{
+		"rpm_limit":         rpmLimit,
+		"tpm_limit":         tpmLimit,
+		"daily_spend_limit": dailySpendLimit,
+	}).Error; err != nil {
+		return err
+	}
+	key.RPMLimit, key.TPMLimit, key.DailySpendLimit = rpmLimit, tpmLimit, dailySpendLimit
+	return s.KeyProvider.UpdateKeyLimits(key)
+}
+
+// SetKeyTier moves a key to the primary or backup tier, which decides whether it is selected before
+// or after the other keys of its group.
+func (s *KeyService) SetKeyTier(key *models.APIKey, tier string) error {
+	return s.KeyProvider.SetKeyTier(key, tier)
+}
+
+// SetKeyExpiry sets the expiry date of a key, or clears it when expiresAt is nil. A key given a date
+// that has already passed is disabled right away.
+func (s *KeyService) SetKeyExpiry(key *models.APIKey, expiresAt *time.Time) error {
+	if err := s.DB.Model(key).Updates(map[string]any{"expires_at": expiresAt, "expiry_warned_at": nil}).Error; err != nil {
+		return err
+	}
+	key.ExpiresAt = expiresAt
+
+	if key.Status != models.KeyStatusActive || expiresAt == nil || time.Now().Before(*expiresAt) {
+		return nil
+	}
+	expired, err := s.KeyProvider.ExpireKeys([]models.APIKey{*key})
+	if err != nil {
+		return err
+	}
+	if len(expired) > 0 {
+		key.Status = models.KeyStatusInvalid
+	}
+	return nil
+}
+
 // TestMultipleKeys handles a one-off validation test for multiple keys.
 func (s *KeyService) TestMultipleKeys(group *models.Group, keysText string) ([]keypool.KeyTestResult, error) {
 	keysToTest := s.ParseKeysFromText(keysText)
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/types"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// keyTopUpCheckInterval is how often groups are checked for a low number of active keys.
+	keyTopUpCheckInterval = time.Minute
+	// keyTopUpHookTimeout bounds a single webhook call or script run.
+	keyTopUpHookTimeout = time.Minute
+	// maxKeyTopUpOutputBytes bounds how much of a hook's output is read.
+	maxKeyTopUpOutputBytes = 1 << 20
+)
+
+// KeyTopUpEvent is the group context passed to top-up webhooks and scripts.
+type KeyTopUpEvent struct {
+	Event       string    `json:"event"`
+	GroupID     uint      `json:"group_id"`
+	GroupName   string    `json:"group_name"`
+	DisplayName string    `json:"display_name"`
+	ChannelType string    `json:"channel_type"`
+	ActiveKeys  int64     `json:"active_keys"`
+	InvalidKeys int64     `json:"invalid_keys"`
+	Threshold   int       `json:"threshold"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// KeyTopUpService calls a group's top-up webhook and script when its active keys drop below the
+// configured threshold, and imports the keys they return.
+type KeyTopUpService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	configManager   types.ConfigManager
+	keyService      *KeyService
+	notifier        *notification.Service
+	client          *http.Client
+	lastAttempt     map[uint]time.Time
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewKeyTopUpService creates a new KeyTopUpService.
+func NewKeyTopUpService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	configManager types.ConfigManager,
+	keyService *KeyService,
+	notifier *notification.Service,
+) *KeyTopUpService {
+	return &KeyTopUpService{
+		db:              db,
+		settingsManager: settingsManager,
+		configManager:   configManager,
+		keyService:      keyService,
+		notifier:        notifier,
+		client:          &http.Client{Timeout: keyTopUpHookTimeout},
+		lastAttempt:     make(map[uint]time.Time),
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start starts the periodic top-up check.
+func (s *KeyTopUpService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Key top-up service started")
+}
+
+// Stop stops the service, waiting for running hooks until ctx expires.
+func (s *KeyTopUpService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("KeyTopUpService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("KeyTopUpService stop timed out.")
+	}
+}
+
+func (s *KeyTopUpService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(keyTopUpCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkGroups()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// checkGroups triggers the hooks of every group that is below its threshold and out of cooldown.
+func (s *KeyTopUpService) checkGroups() {
+	var groups []models.Group
+	if err := s.db.Where("group_type != ? OR group_type IS NULL", "aggregate").Find(&groups).Error; err != nil {
+		logrus.Errorf("KeyTopUpService: Failed to get groups: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := range groups {
+		group := &groups[i]
+		cfg := s.settingsManager.GetEffectiveConfig(group.Config)
+		if cfg.KeyTopUpThreshold <= 0 || (cfg.KeyTopUpWebhookURL == "" && cfg.KeyTopUpScript == "") {
+			continue
+		}
+		cooldown := time.Duration(cfg.KeyTopUpCooldownMinutes) * time.Minute
+		if last, ok := s.lastAttempt[group.ID]; ok && now.Sub(last) < cooldown {
+			continue
+		}
+
+		var activeKeys int64
+		if err := s.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", group.ID, models.KeyStatusActive).Count(&activeKeys).Error; err != nil {
+			logrus.Errorf("KeyTopUpService: Failed to count keys for group %s: %v", group.Name, err)
+			continue
+		}
+		if activeKeys >= int64(cfg.KeyTopUpThreshold) {
+			continue
+		}
+
+		s.lastAttempt[group.ID] = now
+		wg.Add(1)
+		go func(cfg types.SystemSettings) {
+			defer wg.Done()
+			s.topUpGroup(group, cfg, activeKeys)
+		}(cfg)
+	}
+	wg.Wait()
+}
+
+// topUpGroup runs the group's hooks and imports the keys they return.
+func (s *KeyTopUpService) topUpGroup(group *models.Group, cfg types.SystemSettings, activeKeys int64) {
+	var invalidKeys int64
+	s.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", group.ID, models.KeyStatusInvalid).Count(&invalidKeys)
+
+	event := KeyTopUpEvent{
+		Event:       "keys.low",
+		GroupID:     group.ID,
+		GroupName:   group.Name,
+		DisplayName: group.DisplayName,
+		ChannelType: group.ChannelType,
+		ActiveKeys:  activeKeys,
+		InvalidKeys: invalidKeys,
+		Threshold:   cfg.KeyTopUpThreshold,
+		Timestamp:   time.Now(),
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logrus.Errorf("KeyTopUpService: Failed to encode event for group %s: %v", group.Name, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyTopUpHookTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var keys []string
+	if cfg.KeyTopUpWebhookURL != "" {
+		output, err := s.callWebhook(ctx, cfg.KeyTopUpWebhookURL, payload)
+		if err != nil {
+			logrus.Warnf("KeyTopUpService: Webhook for group %s failed: %v", group.Name, err)
+		} else {
+			keys = append(keys, s.parseHookKeys(output)...)
+		}
+	}
+	if cfg.KeyTopUpScript != "" {
+		output, err := s.runScript(ctx, cfg.KeyTopUpScript, payload)
+		if err != nil {
+			logrus.Warnf("KeyTopUpService: Script for group %s failed: %v", group.Name, err)
+		} else {
+			keys = append(keys, s.parseHookKeys(output)...)
+		}
+	}
+
+	if len(keys) == 0 {
+		logrus.Infof("KeyTopUpService: Group '%s' has %d active keys (threshold %d), hooks returned no keys.", group.Name, activeKeys, cfg.KeyTopUpThreshold)
+		s.notifyKeysLow(group, activeKeys, cfg.KeyTopUpThreshold, 0)
+		return
+	}
+	if len(keys) > maxRequestKeys {
+		keys = keys[:maxRequestKeys]
+	}
+
+	bulk, err := s.keyService.processAndCreateKeys(group.ID, keys, false, nil)
+	if err != nil {
+		logrus.Errorf("KeyTopUpService: Failed to import keys for group %s: %v", group.Name, err)
+		s.notifyKeysLow(group, activeKeys, cfg.KeyTopUpThreshold, 0)
+		return
+	}
+	added, ignored := bulk.Counts()
+	logrus.Infof("KeyTopUpService: Group '%s' topped up with %d new keys (%d ignored, %d failed).", group.Name, added, ignored, bulk.FailedCount)
+	s.notifyKeysLow(group, activeKeys, cfg.KeyTopUpThreshold, added)
+}
+
+// notifyKeysLow reports a group that fell below its top-up threshold and how many keys were added.
+func (s *KeyTopUpService) notifyKeysLow(group *models.Group, activeKeys int64, threshold, added int) {
+	severity := models.NotificationSeverityWarning
+	message := fmt.Sprintf("Group '%s' has %d active keys (threshold %d) and top-up added no keys", group.Name, activeKeys, threshold)
+	if added > 0 {
+		severity = models.NotificationSeverityInfo
+		message = fmt.Sprintf("Group '%s' had %d active keys (threshold %d), top-up added %d keys", group.Name, activeKeys, threshold, added)
+	}
+	s.notifier.Notify(notification.Event{
+		Category:  models.NotificationCategoryAlert,
+		Severity:  severity,
+		Event:     notification.EventKeysLow,
+		Message:   message,
+		Params:    map[string]any{"active_keys": activeKeys, "threshold": threshold, "added": added},
+		GroupID:   group.ID,
+		GroupName: group.Name,
+	})
+}
+
+// callWebhook posts the event to url and returns the response body.
+func (s *KeyTopUpService) callWebhook(ctx context.Context, url string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxKeyTopUpOutputBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// runScript runs a script from the hook directory with the event on stdin and returns its stdout.
+// Only bare file names are accepted, so groups cannot run arbitrary commands.
+func (s *KeyTopUpService) runScript(ctx context.Context, name string, payload []byte) ([]byte, error) {
+	if filepath.Base(name) != name || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid script name %q", name)
+	}
+	dir, err := filepath.Abs(s.configManager.GetHookScriptDir())
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, filepath.Join(dir, name))
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: maxKeyTopUpOutputBytes}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: maxKeyTopUpOutputBytes}
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseHookKeys accepts {"keys": [...]} or any format supported by the key import text box.
+func (s *KeyTopUpService) parseHookKeys(output []byte) []string {
+	var response struct {
+		Keys []string `json:"keys"`
+	}
+	if json.Unmarshal(output, &response) == nil && response.Keys != nil {
+		return s.keyService.filterValidKeys(response.Keys)
+	}
+	if len(bytes.TrimSpace(output)) == 0 || bytes.HasPrefix(bytes.TrimSpace(output), []byte("{")) {
+		return nil
+	}
+	return s.keyService.ParseKeysFromText(string(output))
+}
+
+// limitedBuffer discards writes beyond limit instead of failing the command.
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -15,15 +15,17 @@ import (
 type LogCleanupService struct {
 	db              *gorm.DB
 	settingsManager *config.SystemSettingsManager
+	usageRollups    *UsageRollupService
 	stopCh          chan struct{}
 	wg              sync.WaitGroup
 }
 
 // NewLogCleanupService 创建新的日志清理服务
-func NewLogCleanupService(db *gorm.DB, settingsManager *config.SystemSettingsManager) *LogCleanupService {
+func NewLogCleanupService(db *gorm.DB, settingsManager *config.SystemSettingsManager, usageRollups *UsageRollupService) *LogCleanupService {
 	return &LogCleanupService{
 		db:              db,
 		settingsManager: settingsManager,
+		usageRollups:    usageRollups,
 		stopCh:          make(chan struct{}),
 	}
 }
@@ -61,11 +63,15 @@ func (s *LogCleanupService) run() {
 
 	// 启动时先执行一次清理
 	s.cleanupExpiredLogs()
+	s.cleanupExpiredTranscripts()
+	s.cleanupExpiredKeyHealthEvents()
 
 	for {
 		select {
 		case <-ticker.C:
 			s.cleanupExpiredLogs()
+			s.cleanupExpiredTranscripts()
+			s.cleanupExpiredKeyHealthEvents()
 		case <-s.stopCh:
 			return
 		}
@@ -86,6 +92,20 @@ func (s *LogCleanupService) cleanupExpiredLogs() {
 	// 计算过期时间点
 	cutoffTime := time.Now().AddDate(0, 0, -retentionDays).UTC()
 
+	// 尚未计入用量汇总的日志不清理
+	rolledUpUntil, err := s.usageRollups.RolledUpUntil()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check usage rollup progress, skipping log cleanup")
+		return
+	}
+	if rolledUpUntil.Before(cutoffTime) {
+		if rolledUpUntil.IsZero() {
+			logrus.Debug("Usage has not been rolled up yet, skipping log cleanup")
+			return
+		}
+		cutoffTime = rolledUpUntil
+	}
+
 	// 执行删除操作
 	result := s.db.Where("timestamp < ?", cutoffTime).Delete(&models.RequestLog{})
 	if result.Error != nil {
@@ -103,3 +123,45 @@ func (s *LogCleanupService) cleanupExpiredLogs() {
 		logrus.Debug("No expired request logs found to cleanup")
 	}
 }
+
+// cleanupExpiredTranscripts 清理超过保留天数的流式对话记录
+func (s *LogCleanupService) cleanupExpiredTranscripts() {
+	retentionDays := s.settingsManager.GetSettings().TranscriptRetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+
+	cutoffTime := time.Now().AddDate(0, 0, -retentionDays).UTC()
+	result := s.db.Where("created_at < ?", cutoffTime).Delete(&models.StreamTranscript{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to cleanup expired stream transcripts")
+		return
+	}
+	if result.RowsAffected > 0 {
+		logrus.WithFields(logrus.Fields{
+			"deleted_count":  result.RowsAffected,
+			"retention_days": retentionDays,
+		}).Info("Successfully cleaned up expired stream transcripts")
+	}
+}
+
+// cleanupExpiredKeyHealthEvents 清理超过请求日志保留天数的 Key 健康事件
+func (s *LogCleanupService) cleanupExpiredKeyHealthEvents() {
+	retentionDays := s.settingsManager.GetSettings().RequestLogRetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+
+	cutoffTime := time.Now().AddDate(0, 0, -retentionDays).UTC()
+	result := s.db.Where("created_at < ?", cutoffTime).Delete(&models.KeyHealthEvent{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to cleanup expired key health events")
+		return
+	}
+	if result.RowsAffected > 0 {
+		logrus.WithFields(logrus.Fields{
+			"deleted_count":  result.RowsAffected,
+			"retention_days": retentionDays,
+		}).Info("Successfully cleaned up expired key health events")
+	}
+}
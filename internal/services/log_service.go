@@ -2,11 +2,14 @@ package services
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -35,9 +38,11 @@ func NewLogService(db *gorm.DB, encryptionSvc encryption.Service) *LogService {
 	}
 }
 
-// logFiltersScope returns a GORM scope function that applies filters from the Gin context.
+// logFiltersScope returns a GORM scope function that applies filters from the Gin context, limited
+// to the groups in the request's group scope.
 func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(GroupScopeFromContext(c).ByGroupID("group_id"))
 		if parentGroupName := c.Query("parent_group_name"); parentGroupName != "" {
 			db = db.Where("parent_group_name LIKE ?", "%"+parentGroupName+"%")
 		}
@@ -45,8 +50,7 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 			db = db.Where("group_name LIKE ?", "%"+groupName+"%")
 		}
 		if keyValue := c.Query("key_value"); keyValue != "" {
-			keyHash := s.EncryptionSvc.Hash(keyValue)
-			db = db.Where("key_hash = ?", keyHash)
+			db = db.Where("key_hash IN ?", s.EncryptionSvc.LookupHashes(keyValue))
 		}
 		if model := c.Query("model"); model != "" {
 			db = db.Where("model LIKE ?", "%"+model+"%")
@@ -59,11 +63,25 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 		if requestType := c.Query("request_type"); requestType != "" {
 			db = db.Where("request_type = ?", requestType)
 		}
+		if requestID := c.Query("request_id"); requestID != "" {
+			db = db.Where("request_id = ?", requestID)
+		}
+		if finishReason := c.Query("finish_reason"); finishReason != "" {
+			db = db.Where("finish_reason = ?", finishReason)
+		}
+		for _, filter := range c.QueryArray("metadata") {
+			if pattern, ok := metadataLikePattern(filter); ok {
+				db = db.Where("metadata LIKE ?", pattern)
+			}
+		}
 		if statusCodeStr := c.Query("status_code"); statusCodeStr != "" {
 			if statusCode, err := strconv.Atoi(statusCodeStr); err == nil {
 				db = db.Where("status_code = ?", statusCode)
 			}
 		}
+		if statusClass, ok := ParseStatusClass(c.Query("status_class")); ok && statusClass != 0 {
+			db = db.Where("status_code >= ? AND status_code < ?", statusClass*100, (statusClass+1)*100)
+		}
 		if sourceIP := c.Query("source_ip"); sourceIP != "" {
 			db = db.Where("source_ip = ?", sourceIP)
 		}
@@ -90,7 +108,8 @@ func (s *LogService) GetLogsQuery(c *gin.Context) *gorm.DB {
 }
 
 // StreamLogKeysToCSV fetches unique keys from logs based on filters and streams them as a CSV.
-func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error {
+// A non-nil maskKey is applied to every decrypted key.
+func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer, maskKey func(string) string) error {
 	// Create a CSV writer
 	csvWriter := csv.NewWriter(writer)
 	defer csvWriter.Flush()
@@ -136,6 +155,8 @@ func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error
 			if decrypted, err := s.EncryptionSvc.Decrypt(record.KeyValue); err != nil {
 				logrus.WithError(err).WithField("key_value", record.KeyValue).Error("Failed to decrypt key for CSV export")
 				decryptedKey = "failed-to-decrypt"
+			} else if maskKey != nil {
+				decryptedKey = maskKey(decrypted)
 			} else {
 				decryptedKey = decrypted
 			}
@@ -153,3 +174,240 @@ func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error
 
 	return nil
 }
+
+// metadataLikePattern turns a "field:value" metadata filter into a LIKE pattern matching the
+// compact JSON that request logs store.
+func metadataLikePattern(filter string) (string, bool) {
+	name, value, ok := strings.Cut(filter, ":")
+	if !ok || name == "" {
+		return "", false
+	}
+	encoded, err := json.Marshal(map[string]string{name: value})
+	if err != nil {
+		return "", false
+	}
+	return "%" + strings.TrimSuffix(strings.TrimPrefix(string(encoded), "{"), "}") + "%", true
+}
+
+// usageExportColumns are the fixed columns of the usage export, followed by one column per metadata field.
+var usageExportColumns = []string{
+	"timestamp", "request_id", "group_name", "parent_group_name", "model", "status_code", "is_success",
+	"prompt_tokens", "completion_tokens", "total_tokens", "cache_creation_tokens", "cache_read_tokens",
+}
+
+// StreamUsageToCSV streams the token usage of filtered final requests as CSV, with a column for every
+// proxy key metadata field that occurs in the result so that usage can be charged back.
+func (s *LogService) StreamUsageToCSV(c *gin.Context, writer io.Writer) error {
+	baseQuery := func() *gorm.DB {
+		return s.DB.Model(&models.RequestLog{}).Scopes(s.logFiltersScope(c)).Where("request_type = ?", models.RequestTypeFinal)
+	}
+
+	var metadataValues []string
+	if err := baseQuery().Where("metadata IS NOT NULL AND metadata != ''").Distinct("metadata").Pluck("metadata", &metadataValues).Error; err != nil {
+		return fmt.Errorf("failed to collect metadata fields: %w", err)
+	}
+	fieldSet := make(map[string]struct{})
+	for _, value := range metadataValues {
+		var metadata map[string]string
+		if json.Unmarshal([]byte(value), &metadata) != nil {
+			continue
+		}
+		for field := range metadata {
+			fieldSet[field] = struct{}{}
+		}
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	csvWriter := csv.NewWriter(writer)
+	defer csvWriter.Flush()
+
+	header := append([]string{}, usageExportColumns...)
+	for _, field := range fields {
+		header = append(header, "metadata."+field)
+	}
+	if err := csvWriter.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	rows, err := baseQuery().Order("timestamp asc").Rows()
+	if err != nil {
+		return fmt.Errorf("failed to fetch usage records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.RequestLog
+		if err := s.DB.ScanRows(rows, &entry); err != nil {
+			return fmt.Errorf("failed to read usage record: %w", err)
+		}
+
+		var metadata map[string]string
+		if len(entry.Metadata) > 0 {
+			_ = json.Unmarshal(entry.Metadata, &metadata)
+		}
+
+		record := []string{
+			entry.Timestamp.UTC().Format(time.RFC3339),
+			entry.RequestID,
+			entry.GroupName,
+			entry.ParentGroupName,
+			entry.Model,
+			strconv.Itoa(entry.StatusCode),
+			strconv.FormatBool(entry.IsSuccess),
+			strconv.Itoa(entry.PromptTokens),
+			strconv.Itoa(entry.CompletionTokens),
+			strconv.Itoa(entry.TotalTokens),
+			strconv.Itoa(entry.CacheCreationTokens),
+			strconv.Itoa(entry.CacheReadTokens),
+		}
+		for _, field := range fields {
+			record = append(record, metadata[field])
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
+// RequestTraceAttempt is a single upstream attempt within a traced request.
+type RequestTraceAttempt struct {
+	LogID        string    `json:"log_id"`
+	Timestamp    time.Time `json:"timestamp"`
+	RequestType  string    `json:"request_type"`
+	GroupName    string    `json:"group_name"`
+	KeyID        uint      `json:"key_id,omitempty"`
+	KeyValue     string    `json:"key_value"`
+	KeyHash      string    `json:"key_hash"`
+	UpstreamAddr string    `json:"upstream_addr"`
+	StatusCode   int       `json:"status_code"`
+	IsSuccess    bool      `json:"is_success"`
+	DurationMs   int64     `json:"duration_ms"`
+	ErrorMessage string    `json:"error_message"`
+}
+
+// RequestTrace describes which keys and upstreams served a proxied request.
+type RequestTrace struct {
+	RequestID       string                `json:"request_id"`
+	ParentGroupName string                `json:"parent_group_name,omitempty"`
+	GroupName       string                `json:"group_name"`
+	Model           string                `json:"model"`
+	SourceIP        string                `json:"source_ip"`
+	RequestPath     string                `json:"request_path"`
+	FinalStatus     int                   `json:"final_status"`
+	IsSuccess       bool                  `json:"is_success"`
+	Attempts        []RequestTraceAttempt `json:"attempts"`
+}
+
+// TraceRequestByID returns the trace of a request, looked up by request ID or by the ID of any of its log entries.
+func (s *LogService) TraceRequestByID(id string) (*RequestTrace, error) {
+	var entry models.RequestLog
+	if err := s.DB.Where("request_id = ? OR id = ?", id, id).Order("timestamp desc").First(&entry).Error; err != nil {
+		return nil, err
+	}
+
+	if entry.RequestID == "" {
+		// Entries logged before request IDs were introduced have no retry chain.
+		return s.buildTrace(entry.ID, []models.RequestLog{entry})
+	}
+
+	var entries []models.RequestLog
+	if err := s.DB.Where("request_id = ?", entry.RequestID).Order("timestamp asc").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return s.buildTrace(entry.RequestID, entries)
+}
+
+// TraceRequestsAt returns traces of the final requests for a group within window of the given time.
+func (s *LogService) TraceRequestsAt(groupName string, at time.Time, window time.Duration, limit int) ([]*RequestTrace, error) {
+	var finals []models.RequestLog
+	err := s.DB.
+		Where("(group_name = ? OR parent_group_name = ?)", groupName, groupName).
+		Where("request_type = ?", models.RequestTypeFinal).
+		Where("timestamp BETWEEN ? AND ?", at.Add(-window), at.Add(window)).
+		Order("timestamp asc").
+		Limit(limit).
+		Find(&finals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	traces := make([]*RequestTrace, 0, len(finals))
+	for _, final := range finals {
+		var trace *RequestTrace
+		if final.RequestID != "" {
+			trace, err = s.TraceRequestByID(final.RequestID)
+		} else {
+			trace, err = s.buildTrace(final.ID, []models.RequestLog{final})
+		}
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// buildTrace assembles a trace from log entries ordered by time, decrypting keys and resolving key IDs.
+func (s *LogService) buildTrace(requestID string, entries []models.RequestLog) (*RequestTrace, error) {
+	trace := &RequestTrace{
+		RequestID: requestID,
+		Attempts:  make([]RequestTraceAttempt, 0, len(entries)),
+	}
+
+	keyIDs := make(map[string]uint)
+	for _, entry := range entries {
+		attempt := RequestTraceAttempt{
+			LogID:        entry.ID,
+			Timestamp:    entry.Timestamp,
+			RequestType:  entry.RequestType,
+			GroupName:    entry.GroupName,
+			KeyHash:      entry.KeyHash,
+			UpstreamAddr: entry.UpstreamAddr,
+			StatusCode:   entry.StatusCode,
+			IsSuccess:    entry.IsSuccess,
+			DurationMs:   entry.Duration,
+			ErrorMessage: entry.ErrorMessage,
+		}
+
+		if entry.KeyValue != "" {
+			if decrypted, err := s.EncryptionSvc.Decrypt(entry.KeyValue); err != nil {
+				logrus.WithError(err).WithField("log_id", entry.ID).Error("Failed to decrypt log key value")
+				attempt.KeyValue = "failed-to-decrypt"
+			} else {
+				attempt.KeyValue = decrypted
+			}
+		}
+
+		if entry.KeyHash != "" {
+			id, ok := keyIDs[entry.KeyHash]
+			if !ok {
+				var key models.APIKey
+				if err := s.DB.Select("id").Where("key_hash = ? AND group_id = ?", entry.KeyHash, entry.GroupID).First(&key).Error; err == nil {
+					id = key.ID
+				}
+				keyIDs[entry.KeyHash] = id
+			}
+			attempt.KeyID = id
+		}
+
+		trace.Attempts = append(trace.Attempts, attempt)
+
+		// The final entry carries the request-level outcome.
+		if entry.RequestType == models.RequestTypeFinal || trace.GroupName == "" {
+			trace.ParentGroupName = entry.ParentGroupName
+			trace.GroupName = entry.GroupName
+			trace.Model = entry.Model
+			trace.SourceIP = entry.SourceIP
+			trace.RequestPath = entry.RequestPath
+			trace.FinalStatus = entry.StatusCode
+			trace.IsSuccess = entry.IsSuccess
+		}
+	}
+
+	return trace, nil
+}
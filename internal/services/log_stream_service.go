@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestLogStreamChannel carries request logs as they are recorded, so that a stream opened on any
+// node also sees the requests served by the other nodes.
+const RequestLogStreamChannel = "request_log_stream"
+
+// LogStreamFilter selects the request logs a stream receives. Empty fields match everything.
+type LogStreamFilter struct {
+	ParentGroupName string
+	GroupName       string
+	Model           string
+	// StatusClass is the first digit of the status code, e.g. 4 for 4xx.
+	StatusClass int
+	RequestType string
+	// GroupIDs, when not nil, limits the stream to the logs of these groups.
+	GroupIDs map[uint]bool
+}
+
+// Match reports whether a log passes the filter, using the same partial matching on names as the
+// log list.
+func (f LogStreamFilter) Match(log *models.RequestLog) bool {
+	if f.ParentGroupName != "" && !strings.Contains(log.ParentGroupName, f.ParentGroupName) {
+		return false
+	}
+	if f.GroupName != "" && !strings.Contains(log.GroupName, f.GroupName) {
+		return false
+	}
+	if f.Model != "" && !strings.Contains(log.Model, f.Model) {
+		return false
+	}
+	if f.StatusClass != 0 && log.StatusCode/100 != f.StatusClass {
+		return false
+	}
+	if f.RequestType != "" && log.RequestType != f.RequestType {
+		return false
+	}
+	if f.GroupIDs != nil && !f.GroupIDs[log.GroupID] {
+		return false
+	}
+	return true
+}
+
+// ParseStatusClass parses a status class such as 4xx into its first digit. An empty value gives 0
+// and ok.
+func ParseStatusClass(value string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+	if len(value) != 3 || value[0] < '1' || value[0] > '5' || !strings.EqualFold(value[1:], "xx") {
+		return 0, false
+	}
+	return int(value[0] - '0'), true
+}
+
+// LogStreamService publishes recorded request logs and delivers them to live streams.
+type LogStreamService struct {
+	store         store.Store
+	encryptionSvc encryption.Service
+}
+
+// NewLogStreamService creates a new LogStreamService.
+func NewLogStreamService(store store.Store, encryptionSvc encryption.Service) *LogStreamService {
+	return &LogStreamService{
+		store:         store,
+		encryptionSvc: encryptionSvc,
+	}
+}
+
+// Publish sends a recorded log to the open streams. The request body is left out to keep the
+// messages small.
+func (s *LogStreamService) Publish(log *models.RequestLog) {
+	entry := *log
+	entry.RequestBody = ""
+	payload, err := json.Marshal(&entry)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to marshal request log for streaming")
+		return
+	}
+	if err := s.store.Publish(RequestLogStreamChannel, payload); err != nil {
+		logrus.WithError(err).Debug("Failed to publish request log")
+	}
+}
+
+// Subscribe streams the logs matching filter, with their keys decrypted, until ctx is done.
+func (s *LogStreamService) Subscribe(ctx context.Context, filter LogStreamFilter) (<-chan *models.RequestLog, error) {
+	subscription, err := s.store.Subscribe(RequestLogStreamChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make(chan *models.RequestLog, 64)
+	go func() {
+		defer close(logs)
+		defer subscription.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-subscription.Channel():
+				if !ok {
+					return
+				}
+				var log models.RequestLog
+				if err := json.Unmarshal(msg.Payload, &log); err != nil || !filter.Match(&log) {
+					continue
+				}
+				if log.KeyValue != "" {
+					if decrypted, err := s.encryptionSvc.Decrypt(log.KeyValue); err == nil {
+						log.KeyValue = decrypted
+					} else {
+						log.KeyValue = "failed-to-decrypt"
+					}
+				}
+				select {
+				case logs <- &log:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return logs, nil
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// modelMetadataTTL is how long a downloaded metadata document is reused.
+	modelMetadataTTL = 24 * time.Hour
+	// modelMetadataTimeout bounds the download of the metadata document.
+	modelMetadataTimeout = 30 * time.Second
+	// modelMetadataMaxSize caps the size of the metadata document.
+	modelMetadataMaxSize = 32 << 20
+)
+
+// modelMetadata is one entry of a community model metadata document, in the format of LiteLLM's
+// model_prices_and_context_window.json. Costs are in USD per token.
+type modelMetadata struct {
+	MaxTokens               *int     `json:"max_tokens"`
+	MaxInputTokens          *int     `json:"max_input_tokens"`
+	MaxOutputTokens         *int     `json:"max_output_tokens"`
+	InputCostPerToken       *float64 `json:"input_cost_per_token"`
+	OutputCostPerToken      *float64 `json:"output_cost_per_token"`
+	Mode                    string   `json:"mode"`
+	SupportsVision          bool     `json:"supports_vision"`
+	SupportsFunctionCalling bool     `json:"supports_function_calling"`
+}
+
+// modelMetadataRegistry downloads and caches the metadata document set by model_metadata_url.
+type modelMetadataRegistry struct {
+	client    *http.Client
+	mu        sync.Mutex
+	url       string
+	entries   map[string]modelMetadata
+	fetchedAt time.Time
+}
+
+func newModelMetadataRegistry() *modelMetadataRegistry {
+	return &modelMetadataRegistry{client: &http.Client{Timeout: modelMetadataTimeout}}
+}
+
+// load returns the entries of the document at url, downloading it when the cached copy is older
+// than modelMetadataTTL. A stale copy is kept when the download fails.
+func (r *modelMetadataRegistry) load(ctx context.Context, url string) (map[string]modelMetadata, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.url == url && time.Since(r.fetchedAt) < modelMetadataTTL {
+		return r.entries, nil
+	}
+
+	entries, err := r.download(ctx, url)
+	if err != nil {
+		if r.url == url && r.entries != nil {
+			logrus.WithError(err).Warn("Failed to refresh model metadata, using the cached copy")
+			return r.entries, nil
+		}
+		return nil, err
+	}
+
+	r.url = url
+	r.entries = entries
+	r.fetchedAt = time.Now()
+	return entries, nil
+}
+
+func (r *modelMetadataRegistry) download(ctx context.Context, url string) (map[string]modelMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model metadata source returned %s", resp.Status)
+	}
+
+	// Entries that do not describe a model, such as LiteLLM's sample_spec, are skipped
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, modelMetadataMaxSize)).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid model metadata document: %w", err)
+	}
+	entries := make(map[string]modelMetadata, len(raw))
+	for key, value := range raw {
+		var entry modelMetadata
+		if err := json.Unmarshal(value, &entry); err != nil {
+			continue
+		}
+		entries[strings.ToLower(key)] = entry
+	}
+	return entries, nil
+}
+
+// lookupModelMetadata finds the entry of a model ID, trying the ID as is, then without a provider
+// prefix such as "models/" or "openai/", then any entry whose key ends with "/<id>".
+func lookupModelMetadata(entries map[string]modelMetadata, modelID string) (modelMetadata, bool) {
+	id := strings.ToLower(modelID)
+	if entry, ok := entries[id]; ok {
+		return entry, true
+	}
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+		if entry, ok := entries[id]; ok {
+			return entry, true
+		}
+	}
+	for key, entry := range entries {
+		if strings.HasSuffix(key, "/"+id) {
+			return entry, true
+		}
+	}
+	return modelMetadata{}, false
+}
+
+// enrichModels fills the context window and pricing of the given models of a group from the
+// metadata source set by model_metadata_url. Only empty columns are filled, so values the provider
+// reported or an administrator entered are kept; capabilities the source reports are turned on.
+func (s *ModelService) enrichModels(ctx context.Context, groupID uint, modelIDs []string) {
+	url := strings.TrimSpace(s.settingsManager.GetSettings().ModelMetadataURL)
+	if url == "" || len(modelIDs) == 0 {
+		return
+	}
+
+	entries, err := s.metadata.load(ctx, url)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load model metadata")
+		return
+	}
+
+	var capabilities []models.ModelCapabilities
+	if err := s.db.Where("group_id = ? AND model_id IN ?", groupID, modelIDs).Find(&capabilities).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to load models for metadata enrichment")
+		return
+	}
+
+	enriched := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, capability := range capabilities {
+			entry, ok := lookupModelMetadata(entries, capability.ModelID)
+			if !ok {
+				continue
+			}
+			updates := metadataUpdates(&capability, entry)
+			if len(updates) == 0 {
+				continue
+			}
+			if err := tx.Model(&capability).Updates(updates).Error; err != nil {
+				return err
+			}
+			enriched++
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to store model metadata")
+		return
+	}
+	if enriched > 0 {
+		logrus.WithFields(logrus.Fields{"group_id": groupID, "models": enriched}).Info("Model metadata enriched")
+	}
+}
+
+// metadataUpdates returns the columns of capability that entry can fill.
+func metadataUpdates(capability *models.ModelCapabilities, entry modelMetadata) map[string]interface{} {
+	updates := make(map[string]interface{})
+	if capability.MaxTokens == nil && entry.MaxTokens != nil {
+		updates["max_tokens"] = *entry.MaxTokens
+	}
+	if capability.MaxInputTokens == nil && entry.MaxInputTokens != nil {
+		updates["max_input_tokens"] = *entry.MaxInputTokens
+	}
+	if capability.MaxOutputTokens == nil && entry.MaxOutputTokens != nil {
+		updates["max_output_tokens"] = *entry.MaxOutputTokens
+	}
+	if capability.InputPricePerMillion == nil && entry.InputCostPerToken != nil {
+		updates["input_price_per_million"] = perMillion(*entry.InputCostPerToken)
+	}
+	if capability.OutputPricePerMillion == nil && entry.OutputCostPerToken != nil {
+		updates["output_price_per_million"] = perMillion(*entry.OutputCostPerToken)
+	}
+	if !capability.SupportsVision && entry.SupportsVision {
+		updates["supports_vision"] = true
+	}
+	if !capability.SupportsFunctions && entry.SupportsFunctionCalling {
+		updates["supports_functions"] = true
+	}
+	if !capability.SupportsRerank && entry.Mode == "rerank" {
+		updates["supports_rerank"] = true
+	}
+	return updates
+}
+
+// perMillion converts a per-token cost to a per-million price, rounded to drop float noise.
+func perMillion(costPerToken float64) float64 {
+	return math.Round(costPerToken*1e12) / 1e6
+}
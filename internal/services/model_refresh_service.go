@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// modelRefreshCheckInterval is how often groups are checked for a due model refresh.
+const modelRefreshCheckInterval = 10 * time.Minute
+
+// errModelRefreshNoActiveKey is returned when a group has no active key to list models with.
+var errModelRefreshNoActiveKey = errors.New("no active keys in group")
+
+// ModelRefreshService refreshes the model list of every standard group on the interval set by
+// model_refresh_interval_hours, so models the provider adds or retires show up without a manual
+// refresh. Groups without active keys are skipped.
+type ModelRefreshService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	modelService    *ModelService
+	encryptionSvc   encryption.Service
+	jobs            *JobService
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewModelRefreshService creates a new ModelRefreshService.
+func NewModelRefreshService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	modelService *ModelService,
+	encryptionSvc encryption.Service,
+	jobs *JobService,
+) *ModelRefreshService {
+	return &ModelRefreshService{
+		db:              db,
+		settingsManager: settingsManager,
+		modelService:    modelService,
+		encryptionSvc:   encryptionSvc,
+		jobs:            jobs,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start begins refreshing model lists in the background.
+func (s *ModelRefreshService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Model refresh service started")
+}
+
+// Stop waits for the current refresh to finish.
+func (s *ModelRefreshService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("ModelRefreshService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("ModelRefreshService stop timed out.")
+	}
+}
+
+func (s *ModelRefreshService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(modelRefreshCheckInterval)
+	defer ticker.Stop()
+
+	s.refreshDueGroups()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.refreshDueGroups()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// refreshDueGroups refreshes, one after the other, the groups whose interval has elapsed.
+func (s *ModelRefreshService) refreshDueGroups() {
+	var groups []models.Group
+	if err := s.db.Where("group_type != ? OR group_type IS NULL", "aggregate").Find(&groups).Error; err != nil {
+		logrus.WithError(err).Error("Failed to load groups for model refresh")
+		return
+	}
+
+	now := time.Now()
+	for i := range groups {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		group := &groups[i]
+		group.EffectiveConfig = s.settingsManager.GetEffectiveConfig(group.Config)
+		interval := time.Duration(group.EffectiveConfig.ModelRefreshIntervalHours) * time.Hour
+		if interval <= 0 || (group.ModelsRefreshedAt != nil && now.Sub(*group.ModelsRefreshedAt) < interval) {
+			continue
+		}
+		s.refreshGroup(group)
+	}
+}
+
+// StartRefreshJob refreshes the model list of a group in the background, like the scheduled
+// refresh, and returns the job that tracks it.
+func (s *ModelRefreshService) StartRefreshJob(group *models.Group) (*models.Job, error) {
+	job, err := s.jobs.Create(JobTypeModelRefresh, group.Name, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		var result any
+		changes, err := s.refreshGroup(group)
+		if err == nil {
+			result = changes
+			if progressErr := s.jobs.Progress(job.ID, 1, 1, 0); progressErr != nil {
+				logrus.WithError(progressErr).Warn("Failed to update model refresh job")
+			}
+		}
+		if finishErr := s.jobs.Finish(job.ID, result, err); finishErr != nil {
+			logrus.WithError(finishErr).Warn("Failed to finish model refresh job")
+		}
+	}()
+	return job, nil
+}
+
+func (s *ModelRefreshService) refreshGroup(group *models.Group) (*ModelChanges, error) {
+	var apiKey models.APIKey
+	if err := s.db.Where("group_id = ? AND status = ?", group.ID, models.KeyStatusActive).First(&apiKey).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithError(err).WithField("group", group.Name).Error("Failed to load key for model refresh")
+			return nil, err
+		}
+		return nil, errModelRefreshNoActiveKey
+	}
+
+	// The attempt is recorded even when it fails, so a broken upstream is retried on the next
+	// interval rather than on every check.
+	if err := s.db.Model(&models.Group{}).Where("id = ?", group.ID).
+		UpdateColumn("models_refreshed_at", time.Now()).Error; err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Error("Failed to record model refresh")
+		return nil, err
+	}
+
+	decrypted, err := s.encryptionSvc.Decrypt(apiKey.KeyValue)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Model refresh skipped: failed to decrypt key")
+		return nil, err
+	}
+	apiKey.KeyValue = decrypted
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelWarmupTimeout)
+	defer cancel()
+	changes, err := s.modelService.SyncModels(ctx, group, &apiKey)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Model refresh failed")
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"group":   group.Name,
+		"added":   len(changes.Added),
+		"removed": len(changes.Removed),
+	}).Info("Model list refreshed")
+	return changes, nil
+}
@@ -2,50 +2,181 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gpt-load/internal/channel"
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/utils"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// modelWarmupTimeout bounds a background model list warm-up.
+const modelWarmupTimeout = 60 * time.Second
+
+// maxNotifiedModels caps how many new model names a notification lists.
+const maxNotifiedModels = 50
+
+var (
+	// ErrModelExists is returned when a model is added by hand to a group that already has it.
+	ErrModelExists = errors.New("model already exists in group")
+	// ErrModelNotInGroup is returned when a bulk update selects models of another group.
+	ErrModelNotInGroup = errors.New("model does not belong to group")
+)
+
 // ModelService handles model-related operations
 type ModelService struct {
-	db             *gorm.DB
-	channelFactory *channel.Factory
+	db              *gorm.DB
+	channelFactory  *channel.Factory
+	settingsManager *config.SystemSettingsManager
+	encryptionSvc   encryption.Service
+	notifier        *notification.Service
+	metadata        *modelMetadataRegistry
 }
 
 // NewModelService creates a new ModelService instance
-func NewModelService(db *gorm.DB, channelFactory *channel.Factory) *ModelService {
+func NewModelService(
+	db *gorm.DB,
+	channelFactory *channel.Factory,
+	settingsManager *config.SystemSettingsManager,
+	encryptionSvc encryption.Service,
+	notifier *notification.Service,
+) *ModelService {
 	return &ModelService{
-		db:             db,
-		channelFactory: channelFactory,
+		db:              db,
+		channelFactory:  channelFactory,
+		settingsManager: settingsManager,
+		encryptionSvc:   encryptionSvc,
+		notifier:        notifier,
+		metadata:        newModelMetadataRegistry(),
+	}
+}
+
+// WarmUpModels fetches the model list in the background for a standard group that has keys but
+// no models yet. Progress is recorded in the group's model_warmup_* columns.
+func (s *ModelService) WarmUpModels(groupID uint) {
+	var group models.Group
+	if err := s.db.First(&group, groupID).Error; err != nil {
+		logrus.WithError(err).WithField("group_id", groupID).Warn("Model warm-up skipped: group not found")
+		return
+	}
+	if group.GroupType == "aggregate" || group.ModelWarmupStatus == models.ModelWarmupRunning {
+		return
+	}
+
+	var modelCount int64
+	if err := s.db.Model(&models.ModelCapabilities{}).Where("group_id = ?", groupID).Count(&modelCount).Error; err != nil || modelCount > 0 {
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := s.db.Where("group_id = ? AND status = ?", groupID, models.KeyStatusActive).First(&apiKey).Error; err != nil {
+		return
+	}
+
+	// Claim the warm-up so concurrent key imports don't start a second one.
+	result := s.db.Model(&models.Group{}).
+		Where("id = ? AND (model_warmup_status IS NULL OR model_warmup_status <> ?)", groupID, models.ModelWarmupRunning).
+		UpdateColumns(map[string]any{"model_warmup_status": models.ModelWarmupRunning, "model_warmup_error": ""})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+
+	go s.runModelWarmup(group, apiKey)
+}
+
+func (s *ModelService) runModelWarmup(group models.Group, apiKey models.APIKey) {
+	group.EffectiveConfig = s.settingsManager.GetEffectiveConfig(group.Config)
+
+	err := func() error {
+		decrypted, err := s.encryptionSvc.Decrypt(apiKey.KeyValue)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt key: %w", err)
+		}
+		apiKey.KeyValue = decrypted
+
+		ctx, cancel := context.WithTimeout(context.Background(), modelWarmupTimeout)
+		defer cancel()
+		return s.FetchAndStoreModels(ctx, &group, &apiKey)
+	}()
+
+	now := time.Now()
+	updates := map[string]any{
+		"model_warmup_status": models.ModelWarmupSuccess,
+		"model_warmup_error":  "",
+		"model_warmup_at":     now,
+	}
+	if err != nil {
+		updates["model_warmup_status"] = models.ModelWarmupFailed
+		updates["model_warmup_error"] = utils.TruncateString(err.Error(), 512)
+		logrus.WithError(err).WithField("group", group.Name).Warn("Model list warm-up failed")
+	} else {
+		logrus.WithField("group", group.Name).Info("Model list warm-up completed")
+	}
+
+	if err := s.db.Model(&models.Group{}).Where("id = ?", group.ID).UpdateColumns(updates).Error; err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Error("Failed to record model warm-up status")
 	}
 }
 
+// ModelChanges lists the models that appeared and disappeared in a group when it was refreshed.
+type ModelChanges struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
 // FetchAndStoreModels fetches models from the provider and stores them in the database
 func (s *ModelService) FetchAndStoreModels(ctx context.Context, group *models.Group, apiKey *models.APIKey) error {
+	_, err := s.fetchAndStoreModels(ctx, group, apiKey, false)
+	return err
+}
+
+// SyncModels fetches models from the provider like FetchAndStoreModels and also deletes the
+// auto-fetched models the provider no longer lists. Added and removed models are notified.
+func (s *ModelService) SyncModels(ctx context.Context, group *models.Group, apiKey *models.APIKey) (*ModelChanges, error) {
+	return s.fetchAndStoreModels(ctx, group, apiKey, true)
+}
+
+func (s *ModelService) fetchAndStoreModels(ctx context.Context, group *models.Group, apiKey *models.APIKey, prune bool) (*ModelChanges, error) {
 	// Get the channel for this group
 	ch, err := s.channelFactory.GetChannel(group)
 	if err != nil {
-		return fmt.Errorf("failed to get channel: %w", err)
+		return nil, fmt.Errorf("failed to get channel: %w", err)
 	}
 
 	// Fetch models from the provider
 	capabilities, err := ch.FetchModels(ctx, apiKey, group)
 	if err != nil {
-		return fmt.Errorf("failed to fetch models: %w", err)
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
 	}
 
+	changes := &ModelChanges{}
 	if len(capabilities) == 0 {
 		logrus.WithField("group_id", group.ID).Warn("No models returned from provider")
-		return nil // Not an error, just log and continue
+		return changes, nil // Not an error, just log and continue
+	}
+
+	// Models found on a later fetch are reported as new; the first fetch of a group is not
+	var knownModels int64
+	if err := s.db.Model(&models.ModelCapabilities{}).Where("group_id = ?", group.ID).Count(&knownModels).Error; err != nil {
+		return nil, err
 	}
 
 	// Store or update models in database
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	var newModels, removedModels []string
+	fetched := make([]string, 0, len(capabilities))
+	for _, capability := range capabilities {
+		fetched = append(fetched, capability.ModelID)
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		newModels = newModels[:0]
+		removedModels = removedModels[:0]
 		for _, capability := range capabilities {
 			var existing models.ModelCapabilities
 			result := tx.Where("group_id = ? AND model_id = ?", capability.GroupID, capability.ModelID).First(&existing)
@@ -60,6 +191,7 @@ func (s *ModelService) FetchAndStoreModels(ctx context.Context, group *models.Gr
 					}).Error("Failed to create model capability")
 					return err
 				}
+				newModels = append(newModels, capability.ModelID)
 			} else if result.Error != nil {
 				return result.Error
 			} else {
@@ -69,6 +201,7 @@ func (s *ModelService) FetchAndStoreModels(ctx context.Context, group *models.Gr
 					"supports_streaming": capability.SupportsStreaming,
 					"supports_vision":    capability.SupportsVision,
 					"supports_functions": capability.SupportsFunctions,
+					"supports_rerank":    capability.SupportsRerank,
 					"is_auto_fetched":    capability.IsAutoFetched,
 					"last_fetched_at":    capability.LastFetchedAt,
 				}
@@ -82,6 +215,12 @@ func (s *ModelService) FetchAndStoreModels(ctx context.Context, group *models.Gr
 				if capability.MaxOutputTokens != nil {
 					updates["max_output_tokens"] = *capability.MaxOutputTokens
 				}
+				if capability.InputPricePerMillion != nil {
+					updates["input_price_per_million"] = *capability.InputPricePerMillion
+				}
+				if capability.OutputPricePerMillion != nil {
+					updates["output_price_per_million"] = *capability.OutputPricePerMillion
+				}
 
 				if err := tx.Model(&existing).Updates(updates).Error; err != nil {
 					logrus.WithFields(logrus.Fields{
@@ -93,8 +232,110 @@ func (s *ModelService) FetchAndStoreModels(ctx context.Context, group *models.Gr
 				}
 			}
 		}
+
+		if !prune {
+			return nil
+		}
+		// Only auto-fetched models are removed; models added or edited by hand are kept
+		var stale []models.ModelCapabilities
+		if err := tx.Where("group_id = ? AND is_auto_fetched = ? AND model_id NOT IN ?", group.ID, true, fetched).
+			Find(&stale).Error; err != nil {
+			return err
+		}
+		for _, capability := range stale {
+			if err := tx.Delete(&capability).Error; err != nil {
+				return err
+			}
+			removedModels = append(removedModels, capability.ModelID)
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	s.enrichModels(ctx, group.ID, fetched)
+
+	changes.Removed = removedModels
+	if knownModels > 0 {
+		changes.Added = newModels
+	}
+
+	if len(changes.Removed) > 0 {
+		listed := changes.Removed
+		if len(listed) > maxNotifiedModels {
+			listed = listed[:maxNotifiedModels]
+		}
+		s.notifier.Notify(notification.Event{
+			Category:  models.NotificationCategoryModel,
+			Severity:  models.NotificationSeverityWarning,
+			Event:     notification.EventModelsRemoved,
+			Message:   fmt.Sprintf("%d models no longer offered in group '%s': %s", len(changes.Removed), group.Name, utils.TruncateString(strings.Join(listed, ", "), 500)),
+			Params:    map[string]any{"count": len(changes.Removed), "models": listed},
+			GroupID:   group.ID,
+			GroupName: group.Name,
+		})
+	}
+
+	if knownModels > 0 && len(newModels) > 0 {
+		listed := newModels
+		if len(listed) > maxNotifiedModels {
+			listed = listed[:maxNotifiedModels]
+		}
+		s.notifier.Notify(notification.Event{
+			Category:  models.NotificationCategoryModel,
+			Severity:  models.NotificationSeverityInfo,
+			Event:     notification.EventModelsDetected,
+			Message:   fmt.Sprintf("%d new models detected in group '%s': %s", len(newModels), group.Name, utils.TruncateString(strings.Join(listed, ", "), 500)),
+			Params:    map[string]any{"count": len(newModels), "models": listed},
+			GroupID:   group.ID,
+			GroupName: group.Name,
+		})
+	}
+	return changes, nil
+}
+
+// FindGroupModel looks up a model of a group by model ID, falling back to the display name.
+func (s *ModelService) FindGroupModel(groupID uint, model string) (*models.ModelCapabilities, error) {
+	var capability models.ModelCapabilities
+	err := s.db.Where("group_id = ? AND (model_id = ? OR model_name = ?)", groupID, model, model).
+		Order(gorm.Expr("CASE WHEN model_id = ? THEN 0 ELSE 1 END", model)).
+		First(&capability).Error
+	if err != nil {
+		return nil, err
+	}
+	return &capability, nil
+}
+
+// ModelFilter narrows the model list of a group. Search matches the model ID or name; nil flags
+// match both values.
+type ModelFilter struct {
+	Search            string
+	SupportsStreaming *bool
+	SupportsVision    *bool
+	SupportsFunctions *bool
+	SupportsRerank    *bool
+	IsAutoFetched     *bool
+}
+
+// ModelsQuery builds a query for the models of a group that match filter, without an order.
+func (s *ModelService) ModelsQuery(groupID uint, filter ModelFilter) *gorm.DB {
+	query := s.db.Model(&models.ModelCapabilities{}).Where("group_id = ?", groupID)
+	if filter.Search != "" {
+		pattern := "%" + filter.Search + "%"
+		query = query.Where("model_id LIKE ? OR model_name LIKE ?", pattern, pattern)
+	}
+	for column, value := range map[string]*bool{
+		"supports_streaming": filter.SupportsStreaming,
+		"supports_vision":    filter.SupportsVision,
+		"supports_functions": filter.SupportsFunctions,
+		"supports_rerank":    filter.SupportsRerank,
+		"is_auto_fetched":    filter.IsAutoFetched,
+	} {
+		if value != nil {
+			query = query.Where(column+" = ?", *value)
+		}
+	}
+	return query
 }
 
 // GetModels retrieves all models for a group
@@ -104,6 +345,67 @@ func (s *ModelService) GetModels(groupID uint) ([]models.ModelCapabilities, erro
 	return capabilities, err
 }
 
+// CreateModel stores a model added by hand, for providers without a model list API. The name
+// defaults to the model ID; updates holds the capability columns to set.
+func (s *ModelService) CreateModel(groupID uint, modelID, modelName string, updates map[string]interface{}) (*models.ModelCapabilities, error) {
+	if modelName == "" {
+		modelName = modelID
+	}
+
+	var capability models.ModelCapabilities
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.ModelCapabilities{}).Where("group_id = ? AND model_id = ?", groupID, modelID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrModelExists
+		}
+
+		capability = models.ModelCapabilities{GroupID: groupID, ModelID: modelID, ModelName: modelName}
+		if err := tx.Create(&capability).Error; err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&capability).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetModelByID(capability.ID)
+}
+
+// BulkUpdateModelCapabilities applies the same updates to several models of a group. Either all
+// models are updated or, when one of them does not belong to the group, none is.
+func (s *ModelService) BulkUpdateModelCapabilities(groupID uint, ids []uint, updates map[string]interface{}) ([]models.ModelCapabilities, error) {
+	var updated []models.ModelCapabilities
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.ModelCapabilities{}).Where("group_id = ? AND id IN ?", groupID, ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(uniqueIDs(ids)) {
+			return ErrModelNotInGroup
+		}
+
+		if err := tx.Model(&models.ModelCapabilities{}).Where("group_id = ? AND id IN ?", groupID, ids).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Where("group_id = ? AND id IN ?", groupID, ids).Order("model_id ASC").Find(&updated).Error
+	})
+	return updated, err
+}
+
+func uniqueIDs(ids []uint) map[uint]struct{} {
+	unique := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		unique[id] = struct{}{}
+	}
+	return unique
+}
+
 // GetModelByID retrieves a specific model capability
 func (s *ModelService) GetModelByID(id uint) (*models.ModelCapabilities, error) {
 	var capability models.ModelCapabilities
@@ -0,0 +1,604 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OIDCCallbackPath is where the identity provider sends users back to; register AppUrl plus this
+// path as the redirect URI of the client.
+const OIDCCallbackPath = "/api/auth/oidc/callback"
+
+const (
+	// oidcStateKeyPrefix namespaces pending logins in the store, so that any node can finish them.
+	oidcStateKeyPrefix = "oidc_state:"
+	// oidcStateTTL is how long a user has to sign in at the identity provider.
+	oidcStateTTL = 10 * time.Minute
+	// oidcDiscoveryTTL is how long the provider metadata and signing keys are cached.
+	oidcDiscoveryTTL = time.Hour
+	// oidcKeysMinRefresh limits how often an unknown key ID triggers a refetch of the signing keys.
+	oidcKeysMinRefresh = time.Minute
+	// oidcClockSkew is the clock difference tolerated when checking token lifetimes.
+	oidcClockSkew = time.Minute
+	// oidcMaxResponseSize caps the provider responses that are read.
+	oidcMaxResponseSize = 1 << 20
+)
+
+// oidcDiscovery is the part of the provider metadata that the login flow uses.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcState is kept for a login between the redirect to the provider and the callback.
+type oidcState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// jwtHashes maps the supported signing algorithms to their hash functions.
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// jsonWebKey is a public key of a JWKS document.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCService signs admins in through an OpenID Connect provider such as Google, Azure AD or
+// Keycloak. It runs the authorization code flow with PKCE, verifies ID tokens against the
+// provider's published keys and maps the groups in the token to gpt-load roles.
+type OIDCService struct {
+	settingsManager *config.SystemSettingsManager
+	users           *UserService
+	store           store.Store
+	client          *http.Client
+
+	mu           sync.Mutex
+	issuer       string
+	discovery    *oidcDiscovery
+	discoveredAt time.Time
+	keys         map[string]crypto.PublicKey
+	keysAt       time.Time
+}
+
+// NewOIDCService creates a new OIDCService.
+func NewOIDCService(settingsManager *config.SystemSettingsManager, users *UserService, store store.Store) *OIDCService {
+	return &OIDCService{
+		settingsManager: settingsManager,
+		users:           users,
+		store:           store,
+		client:          &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Enabled reports whether single sign-on is switched on and configured.
+func (s *OIDCService) Enabled() bool {
+	settings := s.settingsManager.GetSettings()
+	return settings.OIDCEnabled && settings.OIDCIssuerURL != "" && settings.OIDCClientID != ""
+}
+
+// AuthorizationURL starts a login and returns the provider URL to send the user to.
+func (s *OIDCService) AuthorizationURL(ctx context.Context) (string, error) {
+	if !s.Enabled() {
+		return "", NewI18nError(app_errors.ErrBadRequest, "auth.oidc_disabled", nil)
+	}
+	settings := s.settingsManager.GetSettings()
+	discovery, err := s.discover(ctx, settings.OIDCIssuerURL)
+	if err != nil {
+		return "", err
+	}
+
+	state := oidcState{Nonce: randomToken(), Verifier: randomToken()}
+	stateID := randomToken()
+	data, _ := json.Marshal(state)
+	if err := s.store.Set(oidcStateKeyPrefix+stateID, data, oidcStateTTL); err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {settings.OIDCClientID},
+		"redirect_uri":          {s.redirectURI()},
+		"scope":                 {settings.OIDCScopes},
+		"state":                 {stateID},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Callback finishes a login: it redeems the authorization code, verifies the ID token and starts a
+// session for the mapped role.
+func (s *OIDCService) Callback(ctx context.Context, code, stateID string) (string, *Principal, error) {
+	if !s.Enabled() {
+		return "", nil, NewI18nError(app_errors.ErrBadRequest, "auth.oidc_disabled", nil)
+	}
+	key := oidcStateKeyPrefix + stateID
+	data, err := s.store.Get(key)
+	if stateID == "" || err != nil {
+		return "", nil, NewI18nError(app_errors.ErrBadRequest, "auth.oidc_invalid_state", nil)
+	}
+	_ = s.store.Delete(key)
+	var state oidcState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return "", nil, NewI18nError(app_errors.ErrBadRequest, "auth.oidc_invalid_state", nil)
+	}
+
+	settings := s.settingsManager.GetSettings()
+	rawIDToken, err := s.exchangeCode(ctx, settings, code, state.Verifier)
+	if err != nil {
+		return "", nil, err
+	}
+	claims, err := s.verifyIDToken(ctx, settings, rawIDToken, state.Nonce)
+	if err != nil {
+		return "", nil, err
+	}
+	principal, err := oidcPrincipal(settings, claims)
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := s.users.LoginExternal(principal)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, principal, nil
+}
+
+// Authenticate accepts an ID token issued by the provider for this client as a bearer token, so
+// that scripts can call the admin API with tokens from the provider directly.
+func (s *OIDCService) Authenticate(ctx context.Context, token string) (*Principal, bool) {
+	if strings.Count(token, ".") != 2 || !s.Enabled() {
+		return nil, false
+	}
+	settings := s.settingsManager.GetSettings()
+	claims, err := s.verifyIDToken(ctx, settings, token, "")
+	if err != nil {
+		logrus.Debugf("Rejected OIDC bearer token: %v", err)
+		return nil, false
+	}
+	principal, err := oidcPrincipal(settings, claims)
+	if err != nil {
+		return nil, false
+	}
+	return principal, true
+}
+
+// redirectURI is the callback URL sent to the provider.
+func (s *OIDCService) redirectURI() string {
+	return strings.TrimRight(s.settingsManager.GetAppUrl(), "/") + OIDCCallbackPath
+}
+
+// exchangeCode redeems an authorization code at the token endpoint and returns the ID token.
+func (s *OIDCService) exchangeCode(ctx context.Context, settings types.SystemSettings, code, verifier string) (string, error) {
+	discovery, err := s.discover(ctx, settings.OIDCIssuerURL)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.redirectURI()},
+		"client_id":     {settings.OIDCClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if settings.OIDCClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(settings.OIDCClientID), url.QueryEscape(settings.OIDCClientSecret))
+	}
+
+	var result struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := s.doJSON(req, &result); err != nil {
+		if result.Error != "" {
+			return "", fmt.Errorf("token endpoint: %s", strings.TrimSpace(result.Error+" "+result.ErrorDescription))
+		}
+		return "", err
+	}
+	if result.IDToken == "" {
+		return "", errors.New("token endpoint returned no id_token; is the openid scope requested?")
+	}
+	return result.IDToken, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and, when nonce is set, the nonce
+// of an ID token and returns its claims.
+func (s *OIDCService) verifyIDToken(ctx context.Context, settings types.SystemSettings, raw, nonce string) (map[string]any, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	var claims map[string]any
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+
+	discovery, err := s.discover(ctx, settings.OIDCIssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.signingKey(ctx, discovery, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != discovery.Issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if !slices.Contains(claimStrings(claims["aud"]), settings.OIDCClientID) {
+		return nil, errors.New("token was not issued for this client")
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != settings.OIDCClientID {
+		return nil, errors.New("token was not issued for this client")
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return nil, errors.New("token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(oidcClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if nonce != "" {
+		if got, _ := claims["nonce"].(string); got != nonce {
+			return nil, errors.New("nonce mismatch")
+		}
+	}
+	return claims, nil
+}
+
+// discover returns the provider metadata of issuer, fetching it when it is not cached.
+func (s *OIDCService) discover(ctx context.Context, issuer string) (*oidcDiscovery, error) {
+	issuer = strings.TrimRight(issuer, "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.discovery != nil && s.issuer == issuer && time.Since(s.discoveredAt) < oidcDiscoveryTTL {
+		return s.discovery, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var discovery oidcDiscovery
+	if err := s.doJSON(req, &discovery); err != nil {
+		return nil, fmt.Errorf("fetch provider metadata: %w", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("provider reports issuer %q instead of %q", discovery.Issuer, issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("provider metadata is missing endpoints")
+	}
+
+	if s.issuer != issuer {
+		s.keys = nil
+		s.keysAt = time.Time{}
+	}
+	s.issuer = issuer
+	s.discovery = &discovery
+	s.discoveredAt = time.Now()
+	return s.discovery, nil
+}
+
+// signingKey returns the provider key with the given ID, refetching the key set when the key is
+// unknown, e.g. after the provider rotated its keys.
+func (s *OIDCService) signingKey(ctx context.Context, discovery *oidcDiscovery, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key := lookupSigningKey(s.keys, kid); key != nil && time.Since(s.keysAt) < oidcDiscoveryTTL {
+		return key, nil
+	}
+	if s.keys != nil && time.Since(s.keysAt) < oidcKeysMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("fetch signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			logrus.Warnf("Skipping OIDC signing key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	s.keys = keys
+	s.keysAt = time.Now()
+
+	if key := lookupSigningKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// doJSON sends req and decodes the JSON response into out. Error responses are decoded as well,
+// so that callers can read OAuth error fields.
+func (s *OIDCService) doJSON(req *http.Request, out any) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseSize))
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, out)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return decodeErr
+}
+
+// lookupSigningKey finds a key by ID. Tokens without a key ID are accepted when the provider
+// publishes a single key.
+func lookupSigningKey(keys map[string]crypto.PublicKey, kid string) crypto.PublicKey {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// parseJSONWebKey converts an RSA or EC JSON web key to a public key.
+func parseJSONWebKey(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// verifyJWTSignature checks a JWS signature made with one of the RS, PS or ES algorithms. Symmetric
+// and unsigned tokens are never accepted.
+func verifyJWTSignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	hash, ok := jwtHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("signing key does not match the token algorithm")
+		}
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(rsaKey, hash, digest, signature, nil)
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+	case strings.HasPrefix(alg, "ES"):
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("signing key does not match the token algorithm")
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		sig := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+// oidcPrincipal maps verified claims to a principal. The highest role mapped from the user's groups
+// wins; users in no mapped group get the default role, or are rejected when there is none.
+func oidcPrincipal(settings types.SystemSettings, claims map[string]any) (*Principal, error) {
+	email, _ := claims["email"].(string)
+	if domains := utils.SplitAndTrim(settings.OIDCAllowedEmailDomains, ","); len(domains) > 0 {
+		verified, hasVerified := claims["email_verified"].(bool)
+		at := strings.LastIndex(email, "@")
+		if at < 0 || (hasVerified && !verified) || !slices.ContainsFunc(domains, func(domain string) bool {
+			return strings.EqualFold(domain, email[at+1:])
+		}) {
+			return nil, NewI18nError(app_errors.ErrForbidden, "auth.oidc_domain_not_allowed", nil)
+		}
+	}
+
+	role := settings.OIDCDefaultRole
+	if settings.OIDCRoleMapping != "" {
+		mapping, err := utils.ParseOIDCRoleMapping(settings.OIDCRoleMapping)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range claimStrings(lookupClaim(claims, settings.OIDCGroupsClaim)) {
+			if mapped, ok := mapping[group]; ok && (role == "" || RoleAllows(mapped, role)) {
+				role = mapped
+			}
+		}
+	}
+	if role == "" {
+		return nil, NewI18nError(app_errors.ErrForbidden, "auth.oidc_no_role", nil)
+	}
+
+	username, _ := claims["preferred_username"].(string)
+	if username == "" {
+		username = email
+	}
+	if username == "" {
+		username, _ = claims["sub"].(string)
+	}
+	return &Principal{Username: username, Role: role}, nil
+}
+
+// lookupClaim returns a claim by name, or by a dotted path into nested objects such as Keycloak's
+// realm_access.roles.
+func lookupClaim(claims map[string]any, path string) any {
+	if value, ok := claims[path]; ok {
+		return value
+	}
+	var current any = claims
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// claimStrings reads a claim that holds a string or a list of strings.
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// decodeJWTPart decodes a base64url JSON segment of a token.
+func decodeJWTPart(part string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// randomToken returns 32 random bytes, base64url encoded.
+func randomToken() string {
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
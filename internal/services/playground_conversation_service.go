@@ -0,0 +1,97 @@
+package services
+
+import (
+	"gpt-load/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxPlaygroundMessages bounds the messages stored for a single conversation.
+const maxPlaygroundMessages = 500
+
+// PlaygroundConversationService persists playground conversations so that multi-turn context survives page reloads.
+type PlaygroundConversationService struct {
+	db *gorm.DB
+}
+
+// NewPlaygroundConversationService creates a new PlaygroundConversationService.
+func NewPlaygroundConversationService(db *gorm.DB) *PlaygroundConversationService {
+	return &PlaygroundConversationService{db: db}
+}
+
+// Query returns conversations without their messages, most recently updated first.
+func (s *PlaygroundConversationService) Query() *gorm.DB {
+	return s.db.Model(&models.PlaygroundConversation{}).Order("updated_at desc, id desc")
+}
+
+// Get loads a conversation with its messages in order.
+func (s *PlaygroundConversationService) Get(id uint) (*models.PlaygroundConversation, error) {
+	var conversation models.PlaygroundConversation
+	err := s.db.Preload("Messages", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position asc")
+	}).First(&conversation, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// Save creates the conversation when its ID is zero, or updates it otherwise, replacing all stored
+// messages with the given ones. Only the most recent maxPlaygroundMessages messages are kept.
+func (s *PlaygroundConversationService) Save(conversation *models.PlaygroundConversation, messages []models.PlaygroundMessage) error {
+	if len(messages) > maxPlaygroundMessages {
+		messages = messages[len(messages)-maxPlaygroundMessages:]
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if conversation.ID == 0 {
+			if err := tx.Omit("Messages").Create(conversation).Error; err != nil {
+				return err
+			}
+		} else {
+			var existing models.PlaygroundConversation
+			if err := tx.Select("id", "created_at").First(&existing, conversation.ID).Error; err != nil {
+				return err
+			}
+			conversation.CreatedAt = existing.CreatedAt
+			if err := tx.Model(conversation).
+				Select("title", "group_name", "model", "temperature", "updated_at").
+				Updates(conversation).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("conversation_id = ?", conversation.ID).Delete(&models.PlaygroundMessage{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range messages {
+			messages[i].ID = 0
+			messages[i].ConversationID = conversation.ID
+			messages[i].Position = i
+		}
+		if len(messages) > 0 {
+			if err := tx.CreateInBatches(messages, 100).Error; err != nil {
+				return err
+			}
+		}
+		conversation.Messages = messages
+		return nil
+	})
+}
+
+// Delete removes a conversation and its messages.
+func (s *PlaygroundConversationService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", id).Delete(&models.PlaygroundMessage{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.PlaygroundConversation{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
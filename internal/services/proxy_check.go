@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+)
+
+// proxyCheckTimeout bounds the connectivity check of each upstream.
+const proxyCheckTimeout = 15 * time.Second
+
+// ProxyCheckResult reports whether the upstreams of a group can be reached through its outbound
+// proxy.
+type ProxyCheckResult struct {
+	// Proxy is the group's proxy_url with the password redacted; empty when the environment's
+	// proxy settings or a direct connection are used.
+	Proxy     string                `json:"proxy"`
+	Upstreams []UpstreamCheckResult `json:"upstreams"`
+}
+
+// UpstreamCheckResult is the outcome of reaching one upstream. Any HTTP response counts as
+// reachable, since the check sends no credentials.
+type UpstreamCheckResult struct {
+	URL        string `json:"url"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CheckProxy requests the model list of every upstream of a standard group through the same HTTP
+// client, and so the same outbound proxy, that forwards its requests.
+func (s *GroupService) CheckProxy(ctx context.Context, groupID uint) (*ProxyCheckResult, error) {
+	var stored models.Group
+	if err := s.db.WithContext(ctx).Select("id", "name").First(&stored, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	group, err := s.groupManager.GetGroupByName(stored.Name)
+	if err != nil {
+		return nil, app_errors.ErrResourceNotFound
+	}
+
+	result := &ProxyCheckResult{Upstreams: []UpstreamCheckResult{}}
+	if proxyURL, err := url.Parse(group.EffectiveConfig.ProxyURL); err == nil && proxyURL.Host != "" {
+		result.Proxy = proxyURL.Redacted()
+	}
+	if group.GroupType == "aggregate" {
+		return result, nil
+	}
+
+	var defs []models.UpstreamDefinition
+	if err := json.Unmarshal(group.Upstreams, &defs); err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, "failed to parse upstreams")
+	}
+	channelHandler, err := s.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error())
+	}
+	client := channelHandler.GetHTTPClient()
+	versionPrefix := utils.APIVersionPrefix(group.ChannelType)
+
+	result.Upstreams = make([]UpstreamCheckResult, len(defs))
+	var wg sync.WaitGroup
+	for i, def := range defs {
+		wg.Add(1)
+		go func(i int, def models.UpstreamDefinition) {
+			defer wg.Done()
+			result.Upstreams[i] = checkUpstream(ctx, client, def, versionPrefix)
+		}(i, def)
+	}
+	wg.Wait()
+	return result, nil
+}
+
+// checkUpstream requests the model list of one upstream without credentials.
+func checkUpstream(ctx context.Context, client *http.Client, def models.UpstreamDefinition, versionPrefix string) UpstreamCheckResult {
+	check := UpstreamCheckResult{URL: def.URL}
+	u, err := url.Parse(def.URL)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	u.Path = utils.JoinUpstreamPath(u.Path, versionPrefix+"/models", versionPrefix, def.VersionedBase)
+
+	ctx, cancel := context.WithTimeout(ctx, proxyCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	check.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	resp.Body.Close()
+	check.Reachable = true
+	check.StatusCode = resp.StatusCode
+	return check
+}
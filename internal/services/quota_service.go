@@ -0,0 +1,242 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Quota scopes and units, configured per group via quota_scope and quota_unit.
+const (
+	QuotaScopeKey   = "key"
+	QuotaScopeGroup = "group"
+
+	QuotaUnitRequests = "requests"
+	QuotaUnitTokens   = "tokens"
+)
+
+// quotaPacingBurst is the share of the limit usable ahead of the pro-rata pace, so that a fresh
+// cycle is not throttled from its first request.
+const quotaPacingBurst = 1.0 / 24
+
+// maxQuotaReportKeys bounds the per-key usage returned in a quota report.
+const maxQuotaReportKeys = 20
+
+// QuotaService tracks usage against provider quota cycles, such as a free tier that resets daily
+// at 00:00 UTC or monthly credits, and paces requests so that the quota lasts the whole cycle.
+// Usage is counted in the shared store per group and per key for the current cycle.
+type QuotaService struct {
+	store store.Store
+	// cycles remembers the last cycle seen per group so that the previous cycle's counters are dropped.
+	cycles sync.Map
+}
+
+// NewQuotaService creates a new QuotaService.
+func NewQuotaService(store store.Store) *QuotaService {
+	return &QuotaService{store: store}
+}
+
+// QuotaKeyUsage is the usage of a single key in the current cycle.
+type QuotaKeyUsage struct {
+	KeyID                 uint       `json:"key_id"`
+	Used                  int64      `json:"used"`
+	ProjectedExhaustionAt *time.Time `json:"projected_exhaustion_at,omitempty"`
+}
+
+// QuotaReport describes the current quota cycle of a group. Limit is the total for the group,
+// which in key scope is LimitPerKey times the active keys. PacedUsage is how much of the limit
+// may be used by now for it to last until CycleEnd. ProjectedExhaustionAt is set when the usage
+// rate so far would exhaust the limit before the cycle resets.
+type QuotaReport struct {
+	Cycle                 string          `json:"cycle"`
+	Scope                 string          `json:"scope"`
+	Unit                  string          `json:"unit"`
+	CycleStart            time.Time       `json:"cycle_start"`
+	CycleEnd              time.Time       `json:"cycle_end"`
+	Limit                 int64           `json:"limit"`
+	LimitPerKey           int64           `json:"limit_per_key,omitempty"`
+	Used                  int64           `json:"used"`
+	Remaining             int64           `json:"remaining"`
+	PacedUsage            int64           `json:"paced_usage"`
+	Pacing                bool            `json:"pacing"`
+	ProjectedExhaustionAt *time.Time      `json:"projected_exhaustion_at,omitempty"`
+	Keys                  []QuotaKeyUsage `json:"keys,omitempty"`
+}
+
+// quotaCycle returns the bounds and counter key of the group's current cycle.
+func (s *QuotaService) quotaCycle(cfg types.SystemSettings, groupID uint, now time.Time) (start, end time.Time, counterKey string, ok bool) {
+	start, end, ok = utils.QuotaCycleBounds(cfg.QuotaCycle, cfg.QuotaResetTime, cfg.QuotaResetDay, now)
+	if !ok {
+		return start, end, "", false
+	}
+	return start, end, fmt.Sprintf("quota_usage:%d:%d", groupID, start.Unix()), true
+}
+
+// Record counts a successful upstream request against the group's current cycle, as one request
+// or as its total tokens depending on quota_unit.
+func (s *QuotaService) Record(group *models.Group, keyID uint, totalTokens int) {
+	cfg := group.EffectiveConfig
+	_, _, counterKey, ok := s.quotaCycle(cfg, group.ID, time.Now())
+	if !ok {
+		return
+	}
+	amount := int64(1)
+	if cfg.QuotaUnit == QuotaUnitTokens {
+		amount = int64(totalTokens)
+	}
+	if amount <= 0 {
+		return
+	}
+
+	if previous, loaded := s.cycles.Swap(group.ID, counterKey); loaded && previous != counterKey {
+		if err := s.store.Delete(previous.(string)); err != nil {
+			logrus.WithError(err).Debug("Failed to drop previous quota cycle counters")
+		}
+	}
+	if _, err := s.store.HIncrBy(counterKey, "group", amount); err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to record quota usage")
+		return
+	}
+	if _, err := s.store.HIncrBy(counterKey, "key:"+strconv.FormatUint(uint64(keyID), 10), amount); err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to record key quota usage")
+	}
+}
+
+// GroupPaced reports whether a group with quota_scope=group is ahead of its pace, and how long
+// until the next request fits.
+func (s *QuotaService) GroupPaced(group *models.Group) (time.Duration, bool) {
+	if group.EffectiveConfig.QuotaScope != QuotaScopeGroup {
+		return 0, false
+	}
+	return s.paced(group, "group")
+}
+
+// KeyPaced reports whether a key of a group with quota_scope=key is ahead of its pace, and how
+// long until its next request fits.
+func (s *QuotaService) KeyPaced(group *models.Group, keyID uint) (time.Duration, bool) {
+	if group.EffectiveConfig.QuotaScope != QuotaScopeKey {
+		return 0, false
+	}
+	return s.paced(group, "key:"+strconv.FormatUint(uint64(keyID), 10))
+}
+
+// paced compares the counter field against the pro-rata share of the limit for the elapsed part
+// of the cycle, plus quotaPacingBurst.
+func (s *QuotaService) paced(group *models.Group, field string) (time.Duration, bool) {
+	cfg := group.EffectiveConfig
+	if !cfg.QuotaPacing || cfg.QuotaLimit <= 0 {
+		return 0, false
+	}
+	now := time.Now()
+	start, end, counterKey, ok := s.quotaCycle(cfg, group.ID, now)
+	if !ok {
+		return 0, false
+	}
+	usage, err := s.store.HGetAll(counterKey)
+	if err != nil {
+		return 0, false
+	}
+	used, _ := strconv.ParseInt(usage[field], 10, 64)
+
+	limit := float64(cfg.QuotaLimit)
+	length := end.Sub(start).Seconds()
+	allowance := min(limit*(now.Sub(start).Seconds()/length+quotaPacingBurst), limit)
+	if float64(used) < allowance {
+		return 0, false
+	}
+	if float64(used) >= limit {
+		return end.Sub(now), true
+	}
+	// The pace reaches the current usage at this point of the cycle.
+	catchUp := start.Add(time.Duration((float64(used)/limit - quotaPacingBurst) * length * float64(time.Second)))
+	return max(catchUp.Sub(now), time.Second), true
+}
+
+// Report returns the current quota cycle of a group, or nil when the group has no quota cycle.
+// activeKeys is used to derive the group total in key scope.
+func (s *QuotaService) Report(group *models.Group, activeKeys int64) *QuotaReport {
+	cfg := group.EffectiveConfig
+	now := time.Now()
+	start, end, counterKey, ok := s.quotaCycle(cfg, group.ID, now)
+	if !ok {
+		return nil
+	}
+
+	report := &QuotaReport{
+		Cycle:      cfg.QuotaCycle,
+		Scope:      cfg.QuotaScope,
+		Unit:       cfg.QuotaUnit,
+		CycleStart: start,
+		CycleEnd:   end,
+		Limit:      int64(cfg.QuotaLimit),
+		Pacing:     cfg.QuotaPacing,
+	}
+	if cfg.QuotaScope == QuotaScopeKey {
+		report.LimitPerKey = int64(cfg.QuotaLimit)
+		report.Limit = report.LimitPerKey * activeKeys
+	}
+
+	usage, err := s.store.HGetAll(counterKey)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to load quota usage")
+	}
+	report.Used, _ = strconv.ParseInt(usage["group"], 10, 64)
+	if report.Limit > 0 {
+		report.Remaining = max(report.Limit-report.Used, 0)
+		report.PacedUsage = int64(float64(report.Limit) * now.Sub(start).Seconds() / end.Sub(start).Seconds())
+	}
+	report.ProjectedExhaustionAt = projectExhaustion(report.Used, report.Limit, start, end, now)
+
+	if cfg.QuotaScope == QuotaScopeKey {
+		for field, value := range usage {
+			idStr, isKey := strings.CutPrefix(field, "key:")
+			keyID, err := strconv.ParseUint(idStr, 10, 64)
+			if !isKey || err != nil {
+				continue
+			}
+			used, _ := strconv.ParseInt(value, 10, 64)
+			report.Keys = append(report.Keys, QuotaKeyUsage{
+				KeyID:                 uint(keyID),
+				Used:                  used,
+				ProjectedExhaustionAt: projectExhaustion(used, report.LimitPerKey, start, end, now),
+			})
+		}
+		sort.Slice(report.Keys, func(i, j int) bool {
+			if report.Keys[i].Used != report.Keys[j].Used {
+				return report.Keys[i].Used > report.Keys[j].Used
+			}
+			return report.Keys[i].KeyID < report.Keys[j].KeyID
+		})
+		if len(report.Keys) > maxQuotaReportKeys {
+			report.Keys = report.Keys[:maxQuotaReportKeys]
+		}
+	}
+	return report
+}
+
+// projectExhaustion extrapolates the usage rate since the cycle start and returns when the limit
+// would be reached, or nil when that is after the cycle ends.
+func projectExhaustion(used, limit int64, start, end, now time.Time) *time.Time {
+	if limit <= 0 || used <= 0 {
+		return nil
+	}
+	if used >= limit {
+		return &now
+	}
+	elapsed := now.Sub(start)
+	at := start.Add(time.Duration(float64(elapsed) * float64(limit) / float64(used)))
+	if !at.Before(end) {
+		return nil
+	}
+	return &at
+}
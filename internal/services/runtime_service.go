@@ -0,0 +1,143 @@
+package services
+
+import (
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/version"
+)
+
+// RuntimeService reports the state of the Go runtime and the HTTP server, for troubleshooting
+// production instances.
+type RuntimeService struct {
+	startedAt   time.Time
+	openConns   atomic.Int64
+	activeConns atomic.Int64
+}
+
+// RuntimeInfo is a snapshot of the runtime state of the instance.
+type RuntimeInfo struct {
+	Timestamp     time.Time          `json:"timestamp"`
+	UptimeSeconds int64              `json:"uptime_seconds"`
+	Goroutines    int                `json:"goroutines"`
+	CPUs          int                `json:"cpus"`
+	GOMAXPROCS    int                `json:"gomaxprocs"`
+	Memory        RuntimeMemory      `json:"memory"`
+	Connections   RuntimeConnections `json:"connections"`
+	Build         RuntimeBuild       `json:"build"`
+}
+
+// RuntimeMemory holds the heap and garbage collector statistics.
+type RuntimeMemory struct {
+	HeapAllocBytes    uint64     `json:"heap_alloc_bytes"`
+	HeapInuseBytes    uint64     `json:"heap_inuse_bytes"`
+	HeapIdleBytes     uint64     `json:"heap_idle_bytes"`
+	HeapReleasedBytes uint64     `json:"heap_released_bytes"`
+	HeapObjects       uint64     `json:"heap_objects"`
+	StackInuseBytes   uint64     `json:"stack_inuse_bytes"`
+	SysBytes          uint64     `json:"sys_bytes"`
+	TotalAllocBytes   uint64     `json:"total_alloc_bytes"`
+	NumGC             uint32     `json:"num_gc"`
+	LastGC            *time.Time `json:"last_gc,omitempty"`
+	GCPauseTotalMs    float64    `json:"gc_pause_total_ms"`
+}
+
+// RuntimeConnections counts the client connections of the HTTP server. Active connections are
+// reading or serving a request; the others are idle keep-alive connections.
+type RuntimeConnections struct {
+	Open   int64 `json:"open"`
+	Active int64 `json:"active"`
+}
+
+// RuntimeBuild describes the running binary.
+type RuntimeBuild struct {
+	Version     string `json:"version"`
+	GoVersion   string `json:"go_version"`
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	VCSRevision string `json:"vcs_revision,omitempty"`
+	VCSTime     string `json:"vcs_time,omitempty"`
+	VCSModified bool   `json:"vcs_modified,omitempty"`
+}
+
+// NewRuntimeService creates a new RuntimeService.
+func NewRuntimeService() *RuntimeService {
+	return &RuntimeService{startedAt: time.Now()}
+}
+
+// TrackConnState counts the connections of the HTTP server; it is set as http.Server.ConnState.
+func (s *RuntimeService) TrackConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		s.openConns.Add(1)
+	case http.StateActive:
+		s.activeConns.Add(1)
+	case http.StateIdle:
+		s.activeConns.Add(-1)
+	case http.StateHijacked, http.StateClosed:
+		s.openConns.Add(-1)
+	}
+}
+
+// Info returns a snapshot of the runtime state.
+func (s *RuntimeService) Info() *RuntimeInfo {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	info := &RuntimeInfo{
+		Timestamp:     time.Now().UTC(),
+		UptimeSeconds: int64(time.Since(s.startedAt).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		CPUs:          runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Memory: RuntimeMemory{
+			HeapAllocBytes:    mem.HeapAlloc,
+			HeapInuseBytes:    mem.HeapInuse,
+			HeapIdleBytes:     mem.HeapIdle,
+			HeapReleasedBytes: mem.HeapReleased,
+			HeapObjects:       mem.HeapObjects,
+			StackInuseBytes:   mem.StackInuse,
+			SysBytes:          mem.Sys,
+			TotalAllocBytes:   mem.TotalAlloc,
+			NumGC:             mem.NumGC,
+			GCPauseTotalMs:    float64(mem.PauseTotalNs) / float64(time.Millisecond),
+		},
+		Connections: RuntimeConnections{
+			Open:   s.openConns.Load(),
+			Active: s.activeConns.Load(),
+		},
+		Build: runtimeBuild(),
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		info.Memory.LastGC = &lastGC
+	}
+	return info
+}
+
+// runtimeBuild reads the version and the VCS information stamped into the binary.
+func runtimeBuild() RuntimeBuild {
+	build := RuntimeBuild{
+		Version:   version.Version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				build.VCSRevision = setting.Value
+			case "vcs.time":
+				build.VCSTime = setting.Value
+			case "vcs.modified":
+				build.VCSModified = setting.Value == "true"
+			}
+		}
+	}
+	return build
+}
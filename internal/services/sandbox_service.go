@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// sandboxCheckInterval is how often expired sandbox groups and proxy keys are cleaned up.
+const sandboxCheckInterval = time.Minute
+
+// sandboxKeyPrefix marks proxy keys generated for sandboxes.
+const sandboxKeyPrefix = "sk-sandbox-"
+
+// SandboxService cleans up developer sandboxes: groups created with a TTL stop proxying when they
+// expire and are deleted with their keys once sandbox_retention_hours have passed, and sandbox
+// proxy keys are removed from their group when they expire.
+type SandboxService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	groupManager    *GroupManager
+	groupService    *GroupService
+	notifier        *notification.Service
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewSandboxService creates a new SandboxService.
+func NewSandboxService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	groupManager *GroupManager,
+	groupService *GroupService,
+	notifier *notification.Service,
+) *SandboxService {
+	return &SandboxService{
+		db:              db,
+		settingsManager: settingsManager,
+		groupManager:    groupManager,
+		groupService:    groupService,
+		notifier:        notifier,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start starts the cleanup loop.
+func (s *SandboxService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Sandbox service started")
+}
+
+// Stop stops the cleanup loop.
+func (s *SandboxService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("SandboxService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("SandboxService stop timed out.")
+	}
+}
+
+func (s *SandboxService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(sandboxCheckInterval)
+	defer ticker.Stop()
+
+	s.cleanup()
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanup()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *SandboxService) cleanup() {
+	now := time.Now()
+	s.removeExpiredProxyKeys(now)
+	s.deleteExpiredGroups(now)
+}
+
+// removeExpiredProxyKeys drops expired sandbox proxy keys from their groups.
+func (s *SandboxService) removeExpiredProxyKeys(now time.Time) {
+	var groups []models.Group
+	if err := s.db.Select("id", "name", "proxy_keys", "proxy_key_expiry").Find(&groups).Error; err != nil {
+		logrus.WithError(err).Error("SandboxService: failed to load groups with sandbox proxy keys")
+		return
+	}
+
+	changed := false
+	for _, group := range groups {
+		expired := make(map[string]struct{})
+		for key, expiresAt := range parseProxyKeyExpiry(group.ProxyKeyExpiry) {
+			if !now.Before(expiresAt) {
+				expired[key] = struct{}{}
+			}
+		}
+		if len(expired) == 0 {
+			continue
+		}
+
+		var remaining []string
+		for _, key := range utils.SplitAndTrim(group.ProxyKeys, ",") {
+			if _, ok := expired[key]; !ok {
+				remaining = append(remaining, key)
+			}
+		}
+		var expiry datatypes.JSONMap
+		for key, value := range group.ProxyKeyExpiry {
+			if _, ok := expired[key]; !ok {
+				if expiry == nil {
+					expiry = datatypes.JSONMap{}
+				}
+				expiry[key] = value
+			}
+		}
+
+		if err := s.db.Model(&models.Group{}).Where("id = ?", group.ID).Updates(map[string]any{
+			"proxy_keys":       strings.Join(remaining, ","),
+			"proxy_key_expiry": expiry,
+		}).Error; err != nil {
+			logrus.WithError(err).WithField("group", group.Name).Error("SandboxService: failed to remove expired proxy keys")
+			continue
+		}
+		logrus.Infof("SandboxService: removed %d expired sandbox proxy keys from group '%s'.", len(expired), group.Name)
+		changed = true
+	}
+
+	if changed {
+		if err := s.groupManager.Invalidate(); err != nil {
+			logrus.WithError(err).Error("SandboxService: failed to invalidate group cache")
+		}
+	}
+}
+
+// deleteExpiredGroups deletes sandbox groups whose retention after expiry has passed.
+func (s *SandboxService) deleteExpiredGroups(now time.Time) {
+	retention := time.Duration(s.settingsManager.GetSettings().SandboxRetentionHours) * time.Hour
+
+	var groups []models.Group
+	if err := s.db.Select("id", "name", "expires_at").
+		Where("expires_at IS NOT NULL AND expires_at < ?", now.Add(-retention)).Find(&groups).Error; err != nil {
+		logrus.WithError(err).Error("SandboxService: failed to load expired sandbox groups")
+		return
+	}
+
+	for _, group := range groups {
+		if err := s.groupService.DeleteGroup(context.Background(), group.ID); err != nil {
+			logrus.WithError(err).WithField("group", group.Name).Error("SandboxService: failed to delete expired sandbox group")
+			continue
+		}
+		logrus.Infof("SandboxService: deleted sandbox group '%s' which expired at %s.", group.Name, group.ExpiresAt.Format(time.RFC3339))
+		s.notifier.Notify(notification.Event{
+			Category:  models.NotificationCategoryAlert,
+			Severity:  models.NotificationSeverityInfo,
+			Event:     notification.EventSandboxDeleted,
+			Message:   fmt.Sprintf("Sandbox group '%s' expired and was deleted", group.Name),
+			Params:    map[string]any{"expired_at": group.ExpiresAt.Format(time.RFC3339)},
+			GroupID:   group.ID,
+			GroupName: group.Name,
+		})
+	}
+}
+
+// parseProxyKeyExpiry reads the expiry of a group's sandbox proxy keys, skipping invalid entries.
+func parseProxyKeyExpiry(expiry datatypes.JSONMap) map[string]time.Time {
+	if len(expiry) == 0 {
+		return nil
+	}
+	parsed := make(map[string]time.Time, len(expiry))
+	for key, value := range expiry {
+		text, _ := value.(string)
+		expiresAt, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			logrus.WithField("value", value).Warn("Invalid sandbox proxy key expiry, skipping it")
+			continue
+		}
+		parsed[key] = expiresAt
+	}
+	return parsed
+}
+
+// sandboxExpiry validates a sandbox TTL against sandbox_max_ttl_hours and returns the expiry.
+func (s *GroupService) sandboxExpiry(ttlHours int) (time.Time, error) {
+	maxHours := s.settingsManager.GetSettings().SandboxMaxTTLHours
+	if ttlHours <= 0 || ttlHours > maxHours {
+		return time.Time{}, NewI18nError(app_errors.ErrValidation, "validation.invalid_sandbox_ttl", map[string]any{"max": maxHours})
+	}
+	return time.Now().Add(time.Duration(ttlHours) * time.Hour).UTC().Truncate(time.Second), nil
+}
+
+// SandboxProxyKey is a generated proxy key with a limited lifetime.
+type SandboxProxyKey struct {
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateSandboxProxyKey generates a proxy key for the group that is removed after ttlHours, for
+// handing out trial or demo access without leaving credentials behind.
+func (s *GroupService) CreateSandboxProxyKey(ctx context.Context, groupID uint, ttlHours int) (*SandboxProxyKey, error) {
+	expiresAt, err := s.sandboxExpiry(ttlHours)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, app_errors.ErrInternalServer
+	}
+	key := sandboxKeyPrefix + hex.EncodeToString(secret)
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var group models.Group
+		if err := tx.Select("id", "proxy_keys", "proxy_key_expiry").First(&group, groupID).Error; err != nil {
+			return err
+		}
+		keys := utils.SplitAndTrim(group.ProxyKeys, ",")
+		expiry := group.ProxyKeyExpiry
+		if expiry == nil {
+			expiry = datatypes.JSONMap{}
+		}
+		expiry[key] = expiresAt.Format(time.RFC3339)
+		return tx.Model(&group).Updates(map[string]any{
+			"proxy_keys":       strings.Join(append(keys, key), ","),
+			"proxy_key_expiry": expiry,
+		}).Error
+	})
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	if err := s.groupManager.Invalidate(); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
+	}
+	return &SandboxProxyKey{Key: key, ExpiresAt: expiresAt}, nil
+}
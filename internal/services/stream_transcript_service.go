@@ -0,0 +1,69 @@
+package services
+
+import (
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// StreamTranscriptService stores captured streaming transcripts, encrypting the request and
+// assembled response so that conversation content is never kept in plain text.
+type StreamTranscriptService struct {
+	db            *gorm.DB
+	encryptionSvc encryption.Service
+}
+
+// NewStreamTranscriptService creates a new StreamTranscriptService.
+func NewStreamTranscriptService(db *gorm.DB, encryptionSvc encryption.Service) *StreamTranscriptService {
+	return &StreamTranscriptService{db: db, encryptionSvc: encryptionSvc}
+}
+
+// Save encrypts the content fields of the transcript and stores it.
+func (s *StreamTranscriptService) Save(transcript *models.StreamTranscript) error {
+	for _, field := range []*string{&transcript.RequestBody, &transcript.Content, &transcript.ReasoningContent} {
+		if *field == "" {
+			continue
+		}
+		encrypted, err := s.encryptionSvc.Encrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = encrypted
+	}
+	return s.db.Create(transcript).Error
+}
+
+// Query returns transcripts newest first without their encrypted content, optionally filtered
+// by group name and request ID.
+func (s *StreamTranscriptService) Query(groupName, requestID string) *gorm.DB {
+	query := s.db.Model(&models.StreamTranscript{}).
+		Omit("request_body", "content", "reasoning_content").
+		Order("created_at desc, id desc")
+	if groupName != "" {
+		query = query.Where("group_name = ?", groupName)
+	}
+	if requestID != "" {
+		query = query.Where("request_id = ?", requestID)
+	}
+	return query
+}
+
+// Get loads a transcript and decrypts its content.
+func (s *StreamTranscriptService) Get(id uint) (*models.StreamTranscript, error) {
+	var transcript models.StreamTranscript
+	if err := s.db.First(&transcript, id).Error; err != nil {
+		return nil, err
+	}
+	for _, field := range []*string{&transcript.RequestBody, &transcript.Content, &transcript.ReasoningContent} {
+		if *field == "" {
+			continue
+		}
+		decrypted, err := s.encryptionSvc.Decrypt(*field)
+		if err != nil {
+			return nil, err
+		}
+		*field = decrypted
+	}
+	return &transcript, nil
+}
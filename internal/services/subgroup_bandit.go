@@ -0,0 +1,249 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// SubGroupRoutingWeighted uses smooth weighted round-robin over the static weights.
+	SubGroupRoutingWeighted = "weighted"
+	// SubGroupRoutingBandit shifts traffic toward the best-scoring sub-group.
+	SubGroupRoutingBandit = "bandit"
+
+	// banditDecay discounts older outcomes so scores follow recent behaviour
+	// (an effective window of roughly 200 requests per sub-group).
+	banditDecay = 0.995
+	// banditMinSamples is the number of outcomes each sub-group needs before it is judged on its score.
+	banditMinSamples = 10
+	// banditLatencyExponent and banditCostExponent set how strongly relative latency and cost
+	// reduce a sub-group's score; the success rate is always weighted fully.
+	banditLatencyExponent = 0.5
+	banditCostExponent    = 0.5
+)
+
+// banditArm holds the observed outcomes of one sub-group. Weighted* fields are exponentially decayed.
+type banditArm struct {
+	selections int64
+	requests   int64
+	successes  int64
+
+	weightedRequests  float64
+	weightedSuccesses float64
+	weightedLatencyMs float64
+	weightedCost      float64
+	weightedCostCount float64
+}
+
+// banditState tracks all sub-groups of one aggregate group.
+type banditState struct {
+	mu   sync.Mutex
+	arms map[uint]*banditArm
+}
+
+func newBanditState() *banditState {
+	return &banditState{arms: make(map[uint]*banditArm)}
+}
+
+// arm returns the arm for a sub-group, creating it if needed. Callers must hold mu.
+func (b *banditState) arm(subGroupID uint) *banditArm {
+	a, ok := b.arms[subGroupID]
+	if !ok {
+		a = &banditArm{}
+		b.arms[subGroupID] = a
+	}
+	return a
+}
+
+func (b *banditState) recordSelection(subGroupID uint) {
+	b.mu.Lock()
+	b.arm(subGroupID).selections++
+	b.mu.Unlock()
+}
+
+func (b *banditState) recordOutcome(subGroupID uint, success bool, latency time.Duration, cost *float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a := b.arm(subGroupID)
+	a.requests++
+	a.weightedRequests = a.weightedRequests*banditDecay + 1
+	a.weightedSuccesses *= banditDecay
+	a.weightedLatencyMs *= banditDecay
+	if success {
+		a.successes++
+		a.weightedSuccesses++
+	}
+	a.weightedLatencyMs += float64(latency.Milliseconds())
+	if cost != nil {
+		a.weightedCost = a.weightedCost*banditDecay + *cost
+		a.weightedCostCount = a.weightedCostCount*banditDecay + 1
+	}
+}
+
+func (a *banditArm) successRate() float64 {
+	if a.weightedRequests == 0 {
+		return 0
+	}
+	return a.weightedSuccesses / a.weightedRequests
+}
+
+func (a *banditArm) avgLatencyMs() float64 {
+	if a.weightedRequests == 0 {
+		return 0
+	}
+	return a.weightedLatencyMs / a.weightedRequests
+}
+
+// avgCost returns the mean cost per priced request, or 0 if no request could be priced.
+func (a *banditArm) avgCost() float64 {
+	if a.weightedCostCount == 0 {
+		return 0
+	}
+	return a.weightedCost / a.weightedCostCount
+}
+
+// scores computes the score of each candidate: success rate, scaled down by latency and cost
+// relative to the best candidate. Callers must hold mu.
+func (b *banditState) scores(candidates []*subGroupItem) map[uint]float64 {
+	minLatency, minCost := math.MaxFloat64, math.MaxFloat64
+	for _, item := range candidates {
+		a := b.arm(item.subGroupID)
+		if l := a.avgLatencyMs(); l > 0 && l < minLatency {
+			minLatency = l
+		}
+		if c := a.avgCost(); c > 0 && c < minCost {
+			minCost = c
+		}
+	}
+
+	scores := make(map[uint]float64, len(candidates))
+	for _, item := range candidates {
+		a := b.arm(item.subGroupID)
+		score := a.successRate()
+		if l := a.avgLatencyMs(); l > 0 && minLatency < math.MaxFloat64 {
+			score *= math.Pow(minLatency/l, banditLatencyExponent)
+		}
+		if c := a.avgCost(); c > 0 && minCost < math.MaxFloat64 {
+			score *= math.Pow(minCost/c, banditCostExponent)
+		}
+		scores[item.subGroupID] = score
+	}
+	return scores
+}
+
+// pick chooses among the candidates: under-sampled sub-groups first, then a weighted-random
+// sub-group with the exploration probability, otherwise the best-scoring one.
+func (b *banditState) pick(candidates []*subGroupItem, explorationRate int) *subGroupItem {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var leastSampled *subGroupItem
+	for _, item := range candidates {
+		a := b.arm(item.subGroupID)
+		if a.requests < banditMinSamples && (leastSampled == nil || a.requests < b.arm(leastSampled.subGroupID).requests) {
+			leastSampled = item
+		}
+	}
+	if leastSampled != nil {
+		return leastSampled
+	}
+
+	if rand.Intn(100) < explorationRate {
+		return pickWeightedRandom(candidates)
+	}
+
+	scores := b.scores(candidates)
+	best := candidates[0]
+	for _, item := range candidates[1:] {
+		if scores[item.subGroupID] > scores[best.subGroupID] {
+			best = item
+		}
+	}
+	return best
+}
+
+func pickWeightedRandom(candidates []*subGroupItem) *subGroupItem {
+	total := 0
+	for _, item := range candidates {
+		total += item.weight
+	}
+	if total <= 0 {
+		return candidates[rand.Intn(len(candidates))]
+	}
+	n := rand.Intn(total)
+	for _, item := range candidates {
+		n -= item.weight
+		if n < 0 {
+			return item
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// ExperimentVariant reports the observed performance of one sub-group.
+type ExperimentVariant struct {
+	SubGroupID   uint    `json:"sub_group_id"`
+	SubGroupName string  `json:"sub_group_name"`
+	Weight       int     `json:"weight"`
+	Selections   int64   `json:"selections"`
+	TrafficShare float64 `json:"traffic_share"`
+	Requests     int64   `json:"requests"`
+	Successes    int64   `json:"successes"`
+	// SuccessRate, AvgLatencyMs and AvgCost favour recent requests.
+	SuccessRate  float64 `json:"success_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	AvgCost      float64 `json:"avg_cost"`
+	Score        float64 `json:"score"`
+}
+
+// ExperimentReport summarizes sub-group routing for an aggregate group since this instance started.
+type ExperimentReport struct {
+	Routing         string              `json:"routing"`
+	ExplorationRate int                 `json:"exploration_rate"`
+	Leader          string              `json:"leader"`
+	Variants        []ExperimentVariant `json:"variants"`
+}
+
+func (b *banditState) report(items []subGroupItem) []ExperimentVariant {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	candidates := make([]*subGroupItem, len(items))
+	var totalSelections int64
+	for i := range items {
+		candidates[i] = &items[i]
+		totalSelections += b.arm(items[i].subGroupID).selections
+	}
+	scores := b.scores(candidates)
+
+	variants := make([]ExperimentVariant, 0, len(items))
+	for _, item := range items {
+		a := b.arm(item.subGroupID)
+		variant := ExperimentVariant{
+			SubGroupID:   item.subGroupID,
+			SubGroupName: item.name,
+			Weight:       item.weight,
+			Selections:   a.selections,
+			Requests:     a.requests,
+			Successes:    a.successes,
+			SuccessRate:  a.successRate(),
+			AvgLatencyMs: a.avgLatencyMs(),
+			AvgCost:      a.avgCost(),
+			Score:        scores[item.subGroupID],
+		}
+		if totalSelections > 0 {
+			variant.TrafficShare = float64(a.selections) / float64(totalSelections)
+		}
+		variants = append(variants, variant)
+	}
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Score > variants[j].Score })
+	return variants
+}
@@ -5,15 +5,19 @@ import (
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-// SubGroupManager manages weighted round-robin selection for all aggregate groups
+// SubGroupManager manages weighted round-robin or bandit selection for all aggregate groups
 type SubGroupManager struct {
 	store     store.Store
 	selectors map[uint]*selector
 	mu        sync.RWMutex
+	// bandits holds per-aggregate outcome statistics. It survives selector rebuilds so that
+	// configuration changes do not reset what has been learned.
+	bandits sync.Map // map[uint]*banditState
 }
 
 // subGroupItem represents a sub-group with its weight and current weight for round-robin
@@ -43,7 +47,17 @@ func (m *SubGroupManager) SelectSubGroup(group *models.Group) (string, error) {
 		return "", fmt.Errorf("no valid sub-groups available for aggregate group '%s'", group.Name)
 	}
 
-	selectedName := selector.selectNext()
+	var selectedName string
+	if group.EffectiveConfig.SubGroupRouting == SubGroupRoutingBandit {
+		rate := min(max(group.EffectiveConfig.BanditExplorationRate, 0), 100)
+		selectedName = selector.selectNext(func(attempted map[uint]bool) *subGroupItem {
+			return selector.bandit.pick(selector.candidates(attempted), rate)
+		})
+	} else {
+		selectedName = selector.selectNext(func(map[uint]bool) *subGroupItem {
+			return selector.selectByWeight()
+		})
+	}
 	if selectedName == "" {
 		return "", fmt.Errorf("no sub-groups with active keys for aggregate group '%s'", group.Name)
 	}
@@ -129,20 +143,60 @@ func (m *SubGroupManager) createSelector(group *models.Group) *selector {
 		groupName: group.Name,
 		subGroups: items,
 		store:     m.store,
+		bandit:    m.banditFor(group.ID),
+	}
+}
+
+// banditFor returns the outcome statistics of an aggregate group.
+func (m *SubGroupManager) banditFor(groupID uint) *banditState {
+	state, _ := m.bandits.LoadOrStore(groupID, newBanditState())
+	return state.(*banditState)
+}
+
+// RecordOutcome feeds the final result of a request routed through an aggregate group back into
+// its routing statistics. cost is nil when the request could not be priced.
+func (m *SubGroupManager) RecordOutcome(aggregateGroupID, subGroupID uint, success bool, latency time.Duration, cost *float64) {
+	m.banditFor(aggregateGroupID).recordOutcome(subGroupID, success, latency, cost)
+}
+
+// ExperimentReport returns the routing statistics of an aggregate group, or nil for other groups.
+func (m *SubGroupManager) ExperimentReport(group *models.Group) *ExperimentReport {
+	sel := m.getSelector(group)
+	if sel == nil {
+		return nil
+	}
+
+	sel.mu.Lock()
+	items := make([]subGroupItem, len(sel.subGroups))
+	copy(items, sel.subGroups)
+	sel.mu.Unlock()
+
+	report := &ExperimentReport{
+		Routing:         group.EffectiveConfig.SubGroupRouting,
+		ExplorationRate: group.EffectiveConfig.BanditExplorationRate,
+		Variants:        sel.bandit.report(items),
+	}
+	if report.Routing == "" {
+		report.Routing = SubGroupRoutingWeighted
 	}
+	if len(report.Variants) > 0 && report.Variants[0].Requests > 0 {
+		report.Leader = report.Variants[0].SubGroupName
+	}
+	return report
 }
 
-// selector encapsulates the weighted round-robin algorithm for a single aggregate group
+// selector encapsulates the sub-group selection algorithms for a single aggregate group
 type selector struct {
 	groupID   uint
 	groupName string
 	subGroups []subGroupItem
 	store     store.Store
+	bandit    *banditState
 	mu        sync.Mutex
 }
 
-// selectNext uses weighted round-robin algorithm to select a sub-group with active keys
-func (s *selector) selectNext() string {
+// selectNext selects a sub-group with active keys, trying the sub-groups returned by pick in turn
+func (s *selector) selectNext(pick func(attempted map[uint]bool) *subGroupItem) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -152,6 +206,7 @@ func (s *selector) selectNext() string {
 
 	if len(s.subGroups) == 1 {
 		if s.hasActiveKeys(s.subGroups[0].subGroupID) {
+			s.bandit.recordSelection(s.subGroups[0].subGroupID)
 			return s.subGroups[0].name
 		}
 		logrus.WithFields(logrus.Fields{
@@ -163,7 +218,7 @@ func (s *selector) selectNext() string {
 
 	attempted := make(map[uint]bool)
 	for len(attempted) < len(s.subGroups) {
-		item := s.selectByWeight()
+		item := pick(attempted)
 		if item == nil {
 			break
 		}
@@ -174,6 +229,7 @@ func (s *selector) selectNext() string {
 		attempted[item.subGroupID] = true
 
 		if s.hasActiveKeys(item.subGroupID) {
+			s.bandit.recordSelection(item.subGroupID)
 			logrus.WithFields(logrus.Fields{
 				"aggregate_group": s.groupName,
 				"selected_group":  item.name,
@@ -197,6 +253,18 @@ func (s *selector) selectNext() string {
 	return ""
 }
 
+// candidates returns the sub-groups with a positive weight that have not been attempted yet
+func (s *selector) candidates(attempted map[uint]bool) []*subGroupItem {
+	var items []*subGroupItem
+	for i := range s.subGroups {
+		item := &s.subGroups[i]
+		if item.weight > 0 && !attempted[item.subGroupID] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // selectByWeight implements smooth weighted round-robin algorithm
 func (s *selector) selectByWeight() *subGroupItem {
 	totalWeight := 0
@@ -220,16 +288,23 @@ func (s *selector) selectByWeight() *subGroupItem {
 	return best
 }
 
-// hasActiveKeys checks if a sub-group has available API keys
+// hasActiveKeys checks if a sub-group has available API keys in either its primary or backup tier
 func (s *selector) hasActiveKeys(groupID uint) bool {
-	key := fmt.Sprintf("group:%d:active_keys", groupID)
-	length, err := s.store.LLen(key)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"group_id": groupID,
-			"error":    err,
-		}).Debug("Error checking active keys, assuming available")
-		return true
+	for _, key := range []string{
+		fmt.Sprintf("group:%d:active_keys", groupID),
+		fmt.Sprintf("group:%d:backup_keys", groupID),
+	} {
+		length, err := s.store.LLen(key)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"group_id": groupID,
+				"error":    err,
+			}).Debug("Error checking active keys, assuming available")
+			return true
+		}
+		if length > 0 {
+			return true
+		}
 	}
-	return length > 0
+	return false
 }
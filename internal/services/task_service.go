@@ -4,13 +4,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
 	"gpt-load/internal/store"
+	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
 	globalTaskKey = "global_task"
 	ResultTTL     = 60 * time.Minute
+	// taskJobUpdateInterval throttles the progress written to the task's job record, since
+	// imports report progress after every key.
+	taskJobUpdateInterval = time.Second
 )
 
 const (
@@ -21,6 +29,7 @@ const (
 
 // TaskStatus represents the full lifecycle of a long-running task.
 type TaskStatus struct {
+	JobID           string     `json:"job_id,omitempty"`
 	TaskType        string     `json:"task_type"`
 	IsRunning       bool       `json:"is_running"`
 	GroupName       string     `json:"group_name,omitempty"`
@@ -34,14 +43,22 @@ type TaskStatus struct {
 }
 
 // TaskService manages the state of a single, global, long-running task using the store interface.
+// Each task is also recorded as a job, which keeps its result after the global task is replaced.
 type TaskService struct {
-	store store.Store
+	store    store.Store
+	notifier *notification.Service
+	jobs     *JobService
+
+	jobMu         sync.Mutex
+	lastJobUpdate time.Time
 }
 
 // NewTaskService creates a new TaskService.
-func NewTaskService(store store.Store) *TaskService {
+func NewTaskService(store store.Store, notifier *notification.Service, jobs *JobService) *TaskService {
 	return &TaskService{
-		store: store,
+		store:    store,
+		notifier: notifier,
+		jobs:     jobs,
 	}
 }
 
@@ -56,7 +73,13 @@ func (s *TaskService) StartTask(taskType, groupName string, total int) (*TaskSta
 		return nil, errors.New("a task is already running, please wait")
 	}
 
+	job, err := s.jobs.Create(taskType, groupName, total)
+	if err != nil {
+		return nil, err
+	}
+
 	status := &TaskStatus{
+		JobID:     job.ID,
 		TaskType:  taskType,
 		IsRunning: true,
 		GroupName: groupName,
@@ -114,7 +137,29 @@ func (s *TaskService) UpdateProgress(processed int) error {
 		return fmt.Errorf("failed to serialize updated status: %w", err)
 	}
 
-	return s.store.Set(globalTaskKey, statusBytes, ResultTTL)
+	if err := s.store.Set(globalTaskKey, statusBytes, ResultTTL); err != nil {
+		return err
+	}
+	s.updateJobProgress(status)
+	return nil
+}
+
+// updateJobProgress copies the task progress to its job, at most once per update interval.
+func (s *TaskService) updateJobProgress(status *TaskStatus) {
+	if status.JobID == "" {
+		return
+	}
+	s.jobMu.Lock()
+	if time.Since(s.lastJobUpdate) < taskJobUpdateInterval && status.Processed < status.Total {
+		s.jobMu.Unlock()
+		return
+	}
+	s.lastJobUpdate = time.Now()
+	s.jobMu.Unlock()
+
+	if err := s.jobs.Progress(status.JobID, status.Total, status.Processed, 0); err != nil {
+		logrus.Warnf("Failed to update job progress: %v", err)
+	}
 }
 
 // EndTask marks the current task as finished and stores its final result.
@@ -142,5 +187,47 @@ func (s *TaskService) EndTask(resultData any, taskErr error) error {
 		return fmt.Errorf("failed to serialize final task status: %w", err)
 	}
 
-	return s.store.Set(globalTaskKey, updatedTaskBytes, ResultTTL)
+	if err := s.store.Set(globalTaskKey, updatedTaskBytes, ResultTTL); err != nil {
+		return err
+	}
+	if status.JobID != "" {
+		// A task that ran to the end handled every item, including those it skipped without
+		// reporting progress.
+		processed := status.Processed
+		if taskErr == nil {
+			processed = status.Total
+		}
+		if err := s.jobs.Progress(status.JobID, status.Total, processed, 0); err != nil {
+			logrus.Warnf("Failed to update job progress: %v", err)
+		}
+		if err := s.jobs.Finish(status.JobID, status.Result, taskErr); err != nil {
+			logrus.Warnf("Failed to finish job %s: %v", status.JobID, err)
+		}
+	}
+	s.notifyTaskFinished(status)
+	return nil
+}
+
+// notifyTaskFinished adds a notification for a finished task, so results are not lost once the
+// progress bar is dismissed.
+func (s *TaskService) notifyTaskFinished(status *TaskStatus) {
+	event := notification.Event{
+		Category:  models.NotificationCategoryJob,
+		Severity:  models.NotificationSeveritySuccess,
+		Event:     notification.EventTaskCompleted,
+		Message:   fmt.Sprintf("Task %s for group '%s' completed in %.1fs", status.TaskType, status.GroupName, status.DurationSeconds),
+		GroupName: status.GroupName,
+		Params: map[string]any{
+			"task_type": status.TaskType,
+			"duration":  status.DurationSeconds,
+			"result":    status.Result,
+		},
+	}
+	if status.Error != "" {
+		event.Severity = models.NotificationSeverityError
+		event.Event = notification.EventTaskFailed
+		event.Message = fmt.Sprintf("Task %s for group '%s' failed: %s", status.TaskType, status.GroupName, status.Error)
+		event.Params["error"] = status.Error
+	}
+	s.notifier.Notify(event)
 }
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ContextKeyGroupScope holds the *GroupScope of an admin API request.
+const ContextKeyGroupScope = "group_scope"
+
+// GroupScope limits the groups a principal can see and manage to the groups of its teams and the
+// groups that belong to no team. A nil scope is unrestricted.
+type GroupScope struct {
+	teamIDs []uint
+}
+
+// GroupScopeFromContext returns the scope the request was authorized with, or nil if it is
+// unrestricted.
+func GroupScopeFromContext(c *gin.Context) *GroupScope {
+	if value, ok := c.Get(ContextKeyGroupScope); ok {
+		if scope, ok := value.(*GroupScope); ok {
+			return scope
+		}
+	}
+	return nil
+}
+
+// AllowsTeam reports whether groups owned by teamID are in scope.
+func (sc *GroupScope) AllowsTeam(teamID *uint) bool {
+	return sc == nil || teamID == nil || slices.Contains(sc.teamIDs, *teamID)
+}
+
+// Allows reports whether a group is in scope.
+func (sc *GroupScope) Allows(group *models.Group) bool {
+	return sc.AllowsTeam(group.TeamID)
+}
+
+// DefaultTeamID is the team that groups created within the scope are assigned to.
+func (sc *GroupScope) DefaultTeamID() *uint {
+	if sc == nil || len(sc.teamIDs) == 0 {
+		return nil
+	}
+	id := sc.teamIDs[0]
+	return &id
+}
+
+// Groups restricts a query on the groups table.
+func (sc *GroupScope) Groups(db *gorm.DB) *gorm.DB {
+	if sc == nil {
+		return db
+	}
+	if len(sc.teamIDs) == 0 {
+		return db.Where("team_id IS NULL")
+	}
+	return db.Where("team_id IS NULL OR team_id IN ?", sc.teamIDs)
+}
+
+// ByGroupID restricts a query on a table whose column references groups, e.g. keys or logs.
+func (sc *GroupScope) ByGroupID(column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if sc == nil {
+			return db
+		}
+		visible := db.Session(&gorm.Session{NewDB: true}).Model(&models.Group{}).Select("id").Scopes(sc.Groups)
+		return db.Where(column+" IN (?)", visible)
+	}
+}
+
+// TeamParams is the full state of a team.
+type TeamParams struct {
+	Name        string
+	Description string
+	UserIDs     []uint
+}
+
+// TeamService manages teams, the users in them and which team owns each group.
+type TeamService struct {
+	db *gorm.DB
+}
+
+// NewTeamService creates a new TeamService.
+func NewTeamService(db *gorm.DB) *TeamService {
+	return &TeamService{db: db}
+}
+
+// ScopeFor returns the groups a principal may access. Admins see every group; everyone else sees
+// the groups of their teams and groups without a team. Single sign-on principals have no account
+// and therefore no teams.
+func (s *TeamService) ScopeFor(principal *Principal) (*GroupScope, error) {
+	if principal.Role == models.RoleAdmin {
+		return nil, nil
+	}
+	scope := &GroupScope{teamIDs: []uint{}}
+	if principal.UserID == 0 {
+		return scope, nil
+	}
+	err := s.db.Model(&models.TeamMember{}).
+		Where("user_id = ?", principal.UserID).
+		Order("team_id asc").
+		Pluck("team_id", &scope.teamIDs).Error
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return scope, nil
+}
+
+// ListTeams returns all teams with their members.
+func (s *TeamService) ListTeams() ([]models.Team, error) {
+	var teams []models.Team
+	if err := s.db.Order("name asc").Find(&teams).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	var members []models.TeamMember
+	if err := s.db.Order("user_id asc").Find(&members).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	byTeam := make(map[uint][]uint, len(teams))
+	for _, member := range members {
+		byTeam[member.TeamID] = append(byTeam[member.TeamID], member.UserID)
+	}
+	for i := range teams {
+		teams[i].UserIDs = byTeam[teams[i].ID]
+		if teams[i].UserIDs == nil {
+			teams[i].UserIDs = []uint{}
+		}
+	}
+	return teams, nil
+}
+
+// CreateTeam validates and stores a new team.
+func (s *TeamService) CreateTeam(params TeamParams) (*models.Team, error) {
+	team := &models.Team{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := applyTeamParams(tx, team, params); err != nil {
+			return err
+		}
+		if err := tx.Create(team).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		return replaceTeamMembers(tx, team)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// UpdateTeam validates and replaces the name, description and members of a team.
+func (s *TeamService) UpdateTeam(id uint, params TeamParams) (*models.Team, error) {
+	var team models.Team
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&team, id).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		if err := applyTeamParams(tx, &team, params); err != nil {
+			return err
+		}
+		if err := tx.Select("name", "description").Updates(&team).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		return replaceTeamMembers(tx, &team)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// DeleteTeam removes a team. Teams that still own groups cannot be deleted, as their groups would
+// become visible to everyone.
+func (s *TeamService) DeleteTeam(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var owned int64
+		if err := tx.Model(&models.Group{}).Where("team_id = ?", id).Count(&owned).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		if owned > 0 {
+			return NewI18nError(app_errors.ErrValidation, "validation.team_has_groups", map[string]any{"count": owned})
+		}
+
+		result := tx.Delete(&models.Team{}, id)
+		if result.Error != nil {
+			return app_errors.ParseDBError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return app_errors.ErrResourceNotFound
+		}
+		if err := tx.Where("team_id = ?", id).Delete(&models.TeamMember{}).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		return nil
+	})
+}
+
+// AssignGroup makes a team the owner of a group; a nil team shares the group with everyone.
+func (s *TeamService) AssignGroup(groupID uint, teamID *uint) error {
+	if teamID != nil {
+		if err := s.db.First(&models.Team{}, *teamID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return NewI18nError(app_errors.ErrValidation, "validation.team_not_found", nil)
+			}
+			return app_errors.ParseDBError(err)
+		}
+	}
+
+	result := s.db.Model(&models.Group{}).Where("id = ?", groupID).UpdateColumn("team_id", teamID)
+	if result.Error != nil {
+		return app_errors.ParseDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return app_errors.ErrResourceNotFound
+	}
+	return nil
+}
+
+// RemoveUser drops a deleted user from all teams.
+func (s *TeamService) RemoveUser(userID uint) error {
+	if err := s.db.Where("user_id = ?", userID).Delete(&models.TeamMember{}).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
+
+// applyTeamParams validates params and copies them onto team.
+func applyTeamParams(tx *gorm.DB, team *models.Team, params TeamParams) error {
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > 64 {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_team_name", nil)
+	}
+
+	var existing models.Team
+	err := tx.Where("name = ? AND id <> ?", name, team.ID).First(&existing).Error
+	if err == nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.team_name_taken", nil)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return app_errors.ParseDBError(err)
+	}
+
+	userIDs := slices.Clone(params.UserIDs)
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+	if len(userIDs) > 0 {
+		var found int64
+		if err := tx.Model(&models.User{}).Where("id IN ?", userIDs).Count(&found).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		if int(found) != len(userIDs) {
+			return NewI18nError(app_errors.ErrValidation, "validation.team_user_not_found", nil)
+		}
+	}
+
+	team.Name = name
+	team.Description = strings.TrimSpace(params.Description)
+	team.UserIDs = userIDs
+	return nil
+}
+
+// replaceTeamMembers stores team.UserIDs as the members of the team.
+func replaceTeamMembers(tx *gorm.DB, team *models.Team) error {
+	if err := tx.Where("team_id = ?", team.ID).Delete(&models.TeamMember{}).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	if len(team.UserIDs) == 0 {
+		team.UserIDs = []uint{}
+		return nil
+	}
+	members := make([]models.TeamMember, 0, len(team.UserIDs))
+	for _, userID := range team.UserIDs {
+		members = append(members, models.TeamMember{TeamID: team.ID, UserID: userID})
+	}
+	if err := tx.Create(&members).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
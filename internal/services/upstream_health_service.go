@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/prometheus"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// upstreamProbeIdleInterval is how often the interval setting is checked while active probes are
+// disabled.
+const upstreamProbeIdleInterval = time.Minute
+
+// UpstreamHealthService actively probes the upstreams of every standard group on the interval set
+// by upstream_probe_interval_seconds. The results are combined with the passive circuit state of
+// each upstream to leave unhealthy upstreams out of upstream selection and are exported as
+// gpt_load_upstream_healthy. An upstream found unhealthy publishes an upstream.down event. Every
+// instance probes on its own, since reachability depends on where it runs.
+type UpstreamHealthService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	groupManager    *GroupManager
+	channelFactory  *channel.Factory
+	webhooks        *WebhookService
+	// healthy remembers the last health of each group and upstream, so that upstream.down is only
+	// published when an upstream goes down. Only the probe goroutine uses it.
+	healthy map[[2]string]bool
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewUpstreamHealthService creates a new UpstreamHealthService.
+func NewUpstreamHealthService(
+	db *gorm.DB,
+	settingsManager *config.SystemSettingsManager,
+	groupManager *GroupManager,
+	channelFactory *channel.Factory,
+	webhooks *WebhookService,
+) *UpstreamHealthService {
+	return &UpstreamHealthService{
+		db:              db,
+		settingsManager: settingsManager,
+		groupManager:    groupManager,
+		channelFactory:  channelFactory,
+		webhooks:        webhooks,
+		healthy:         make(map[[2]string]bool),
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start begins probing upstreams in the background.
+func (s *UpstreamHealthService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Upstream health service started")
+}
+
+// Stop waits for the current probe round to finish.
+func (s *UpstreamHealthService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("UpstreamHealthService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("UpstreamHealthService stop timed out.")
+	}
+}
+
+func (s *UpstreamHealthService) run() {
+	defer s.wg.Done()
+
+	for {
+		interval := time.Duration(s.settingsManager.GetSettings().UpstreamProbeIntervalSeconds) * time.Second
+		wait := interval
+		if interval > 0 {
+			s.probeAll()
+		} else {
+			wait = upstreamProbeIdleInterval
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// probeAll probes the upstreams of the standard groups that have not expired and publishes their
+// health.
+func (s *UpstreamHealthService) probeAll() {
+	var names []string
+	if err := s.db.Model(&models.Group{}).
+		Where("group_type <> ? OR group_type IS NULL", "aggregate").
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Pluck("name", &names).Error; err != nil {
+		logrus.WithError(err).Error("Failed to load groups for upstream probes")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	health := make(map[[2]string]bool)
+	for _, name := range names {
+		group, err := s.groupManager.GetGroupByName(name)
+		if err != nil {
+			continue
+		}
+		channelHandler, err := s.channelFactory.GetChannel(group)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"group": name, "error": err}).Debug("Failed to get channel for upstream probes")
+			continue
+		}
+		for _, upstream := range channelHandler.ProbeUpstreams(ctx) {
+			if !upstream.Healthy {
+				logrus.WithFields(logrus.Fields{
+					"group":        name,
+					"upstream":     upstream.Upstream,
+					"probe_error":  upstream.LastProbeError,
+					"circuit_open": upstream.CircuitOpen,
+				}).Warn("Upstream is unhealthy")
+			}
+			key := [2]string{name, upstream.Upstream}
+			if wasHealthy, seen := s.healthy[key]; !upstream.Healthy && (wasHealthy || !seen) {
+				s.webhooks.Publish(WebhookEventUpstreamDown, group.ID, group.Name,
+					fmt.Sprintf("Upstream %s of group '%s' is unhealthy", upstream.Upstream, group.Name),
+					map[string]any{
+						"upstream":     upstream.Upstream,
+						"probe_error":  upstream.LastProbeError,
+						"circuit_open": upstream.CircuitOpen,
+						"instance":     instanceName(),
+					})
+			}
+			s.healthy[key] = upstream.Healthy
+			health[key] = upstream.Healthy
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	prometheus.SetUpstreamHealth(health)
+}
+
+// instanceName names the instance that observed an upstream going down.
+func instanceName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
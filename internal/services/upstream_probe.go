@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// upstreamProbeTimeout bounds each probe so that saving a group stays responsive when an
+// upstream is slow or unreachable.
+const upstreamProbeTimeout = 3 * time.Second
+
+// upstreamProbeResult is the outcome of probing one path layout of an upstream.
+type upstreamProbeResult int
+
+const (
+	probeInconclusive upstreamProbeResult = iota
+	probeFound
+	probeNotFound
+)
+
+// normalizeUpstreams rewrites each upstream URL for clean path joining and decides whether it is a
+// versioned base. The static guess is checked against the upstream's model list endpoint, which
+// answers 401/403 rather than 404 even without credentials when the path layout is right.
+func (s *GroupService) normalizeUpstreams(ctx context.Context, channelType string, defs []models.UpstreamDefinition) {
+	versionPrefix := utils.APIVersionPrefix(channelType)
+	client := &http.Client{Timeout: upstreamProbeTimeout}
+
+	var wg sync.WaitGroup
+	for i := range defs {
+		normalized, versioned := utils.NormalizeUpstreamURL(defs[i].URL, channelType)
+		defs[i].URL = normalized
+		defs[i].VersionedBase = versioned
+
+		wg.Add(1)
+		go func(def *models.UpstreamDefinition) {
+			defer wg.Done()
+			probe := func(versioned bool) upstreamProbeResult {
+				return probeUpstreamLayout(ctx, client, def.URL, versionPrefix, versioned)
+			}
+
+			switch {
+			case def.VersionedBase:
+				if probe(true) == probeNotFound && probe(false) == probeFound {
+					def.VersionedBase = false
+				}
+			case probe(false) == probeNotFound:
+				if probe(true) == probeFound {
+					def.VersionedBase = true
+				}
+			}
+		}(&defs[i])
+	}
+	wg.Wait()
+
+	for _, def := range defs {
+		if def.VersionedBase {
+			logrus.Infof("Upstream %s includes its own API version; the %s prefix will be dropped from request paths", def.URL, versionPrefix)
+		}
+	}
+}
+
+// probeUpstreamLayout requests the model list under the given path layout without credentials.
+func probeUpstreamLayout(ctx context.Context, client *http.Client, baseURL, versionPrefix string, versioned bool) upstreamProbeResult {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return probeInconclusive
+	}
+	u.Path = utils.JoinUpstreamPath(u.Path, versionPrefix+"/models", versionPrefix, versioned)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return probeInconclusive
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		logrus.Debugf("Upstream probe %s failed: %v", u.String(), err)
+		return probeInconclusive
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return probeNotFound
+	case resp.StatusCode < http.StatusInternalServerError:
+		return probeFound
+	default:
+		return probeInconclusive
+	}
+}
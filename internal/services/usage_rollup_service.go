@@ -0,0 +1,346 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// usageRollupInterval is how often completed hours are rolled up.
+	usageRollupInterval = 10 * time.Minute
+	// usageRollupBatch bounds the span of raw logs read by a single query.
+	usageRollupBatch = 24 * time.Hour
+)
+
+// Usage rollup granularities accepted by QueryUsage.
+const (
+	UsageGranularityHour = "hour"
+	UsageGranularityDay  = "day"
+)
+
+// usageRollupColumns are the metric columns rewritten when a bucket is rolled up again.
+var usageRollupColumns = []string{
+	"request_count", "success_count", "failure_count", "prompt_tokens", "completion_tokens",
+	"total_tokens", "cache_creation_tokens", "cache_read_tokens", "duration_ms",
+}
+
+type usageRollupKey struct {
+	time          time.Time
+	groupID       uint
+	parentGroupID uint
+	model         string
+}
+
+// UsageRollupService rolls final request logs up into hourly and daily usage summaries, so usage
+// statistics do not have to scan the raw logs and survive their cleanup.
+type UsageRollupService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewUsageRollupService creates a new UsageRollupService.
+func NewUsageRollupService(db *gorm.DB, settingsManager *config.SystemSettingsManager) *UsageRollupService {
+	return &UsageRollupService{
+		db:              db,
+		settingsManager: settingsManager,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start begins rolling up usage in the background.
+func (s *UsageRollupService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Usage rollup service started")
+}
+
+// Stop waits for the current rollup to finish.
+func (s *UsageRollupService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("UsageRollupService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("UsageRollupService stop timed out.")
+	}
+}
+
+func (s *UsageRollupService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(usageRollupInterval)
+	defer ticker.Stop()
+
+	s.rollup()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.rollup()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// rollup aggregates every completed hour that has not been rolled up yet, then prunes expired
+// hourly rollups.
+func (s *UsageRollupService) rollup() {
+	cutoff := s.rollupCutoff()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		start, found, err := s.nextPendingHour(cutoff)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to find request logs to roll up")
+			return
+		}
+		if !found {
+			break
+		}
+		end := start.Add(usageRollupBatch)
+		if end.After(cutoff) {
+			end = cutoff
+		}
+		if err := s.rollupRange(start, end); err != nil {
+			logrus.WithError(err).Error("Failed to roll up request logs")
+			return
+		}
+	}
+
+	s.pruneHourlyRollups()
+}
+
+// rollupCutoff is the end of the last hour whose logs have all been written. Buffered logs reach
+// the database up to one write interval late.
+func (s *UsageRollupService) rollupCutoff() time.Time {
+	lag := time.Duration(s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes)*time.Minute + time.Minute
+	return time.Now().Add(-lag).UTC().Truncate(time.Hour)
+}
+
+// RolledUpUntil returns the time up to which request logs are included in the rollups, or the
+// zero time when nothing has been rolled up yet.
+func (s *UsageRollupService) RolledUpUntil() (time.Time, error) {
+	var hourly models.UsageHourlyRollup
+	result := s.db.Order("time desc").Limit(1).Find(&hourly)
+	if result.Error != nil {
+		return time.Time{}, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return hourly.Time.UTC().Add(time.Hour), nil
+	}
+
+	// Every hourly rollup may have been pruned after a long idle period
+	var daily models.UsageDailyRollup
+	result = s.db.Order("time desc").Limit(1).Find(&daily)
+	if result.Error != nil {
+		return time.Time{}, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return daily.Time.UTC().Add(24 * time.Hour), nil
+	}
+	return time.Time{}, nil
+}
+
+// nextPendingHour returns the hour of the oldest final log that is not rolled up yet and ends
+// before cutoff, skipping hours without traffic.
+func (s *UsageRollupService) nextPendingHour(cutoff time.Time) (time.Time, bool, error) {
+	from, err := s.RolledUpUntil()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	// Request log timestamps are stored in local time
+	var logs []models.RequestLog
+	if err := s.db.Select("timestamp").
+		Where("request_type = ? AND timestamp >= ? AND timestamp < ?", models.RequestTypeFinal, from.Local(), cutoff.Local()).
+		Order("timestamp asc").Limit(1).Find(&logs).Error; err != nil {
+		return time.Time{}, false, err
+	}
+	if len(logs) == 0 {
+		return time.Time{}, false, nil
+	}
+	return logs[0].Timestamp.UTC().Truncate(time.Hour), true, nil
+}
+
+// rollupRange aggregates the final logs in [start, end) into hourly rollups and recomputes the
+// daily rollups of the days involved. Rolling up the same range again gives the same result.
+func (s *UsageRollupService) rollupRange(start, end time.Time) error {
+	rows, err := s.db.Model(&models.RequestLog{}).
+		Select("timestamp, group_id, parent_group_id, model, is_success, prompt_tokens, completion_tokens, total_tokens, cache_creation_tokens, cache_read_tokens, duration").
+		Where("request_type = ? AND timestamp >= ? AND timestamp < ?", models.RequestTypeFinal, start.Local(), end.Local()).
+		Rows()
+	if err != nil {
+		return fmt.Errorf("failed to read request logs: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make(map[usageRollupKey]*models.UsageHourlyRollup)
+	days := make(map[time.Time]struct{})
+	for rows.Next() {
+		var log models.RequestLog
+		if err := s.db.ScanRows(rows, &log); err != nil {
+			return fmt.Errorf("failed to scan request log: %w", err)
+		}
+		hour := log.Timestamp.UTC().Truncate(time.Hour)
+		key := usageRollupKey{time: hour, groupID: log.GroupID, parentGroupID: log.ParentGroupID, model: log.Model}
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &models.UsageHourlyRollup{UsageRollup: models.UsageRollup{
+				Time:          hour,
+				GroupID:       log.GroupID,
+				ParentGroupID: log.ParentGroupID,
+				Model:         log.Model,
+			}}
+			buckets[key] = bucket
+			days[startOfUTCDay(hour)] = struct{}{}
+		}
+		bucket.RequestCount++
+		if log.IsSuccess {
+			bucket.SuccessCount++
+		} else {
+			bucket.FailureCount++
+		}
+		bucket.PromptTokens += int64(log.PromptTokens)
+		bucket.CompletionTokens += int64(log.CompletionTokens)
+		bucket.TotalTokens += int64(log.TotalTokens)
+		bucket.CacheCreationTokens += int64(log.CacheCreationTokens)
+		bucket.CacheReadTokens += int64(log.CacheReadTokens)
+		bucket.DurationMs += log.Duration
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read request logs: %w", err)
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	hourly := make([]*models.UsageHourlyRollup, 0, len(buckets))
+	for _, bucket := range buckets {
+		hourly = append(hourly, bucket)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(upsertUsageRollup()).CreateInBatches(hourly, 100).Error; err != nil {
+			return fmt.Errorf("failed to save hourly usage rollups: %w", err)
+		}
+		for day := range days {
+			if err := rollupDay(tx, day); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"start":   start.Format(time.RFC3339),
+		"end":     end.Format(time.RFC3339),
+		"buckets": len(hourly),
+	}).Debug("Rolled up request logs")
+	return nil
+}
+
+// rollupDay recomputes the daily rollups of a day from its hourly rollups.
+func rollupDay(tx *gorm.DB, day time.Time) error {
+	var sums []models.UsageRollup
+	if err := tx.Model(&models.UsageHourlyRollup{}).
+		Select("group_id, parent_group_id, model, SUM(request_count) AS request_count, SUM(success_count) AS success_count, SUM(failure_count) AS failure_count, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, SUM(total_tokens) AS total_tokens, SUM(cache_creation_tokens) AS cache_creation_tokens, SUM(cache_read_tokens) AS cache_read_tokens, SUM(duration_ms) AS duration_ms").
+		Where("time >= ? AND time < ?", day, day.Add(24*time.Hour)).
+		Group("group_id, parent_group_id, model").
+		Scan(&sums).Error; err != nil {
+		return fmt.Errorf("failed to sum hourly usage rollups: %w", err)
+	}
+	if len(sums) == 0 {
+		return nil
+	}
+
+	daily := make([]*models.UsageDailyRollup, len(sums))
+	for i := range sums {
+		sums[i].Time = day
+		daily[i] = &models.UsageDailyRollup{UsageRollup: sums[i]}
+	}
+	if err := tx.Clauses(upsertUsageRollup()).CreateInBatches(daily, 100).Error; err != nil {
+		return fmt.Errorf("failed to save daily usage rollups: %w", err)
+	}
+	return nil
+}
+
+func upsertUsageRollup() clause.OnConflict {
+	return clause.OnConflict{
+		Columns:   []clause.Column{{Name: "time"}, {Name: "group_id"}, {Name: "parent_group_id"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns(usageRollupColumns),
+	}
+}
+
+// pruneHourlyRollups removes hourly rollups older than the retention period, whole days at a
+// time so the daily rollups never have to be recomputed from a partial day.
+func (s *UsageRollupService) pruneHourlyRollups() {
+	retentionDays := s.settingsManager.GetSettings().UsageRollupRetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+
+	cutoff := startOfUTCDay(time.Now().AddDate(0, 0, -retentionDays))
+	result := s.db.Where("time < ?", cutoff).Delete(&models.UsageHourlyRollup{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to prune hourly usage rollups")
+		return
+	}
+	if result.RowsAffected > 0 {
+		logrus.WithFields(logrus.Fields{
+			"deleted_count":  result.RowsAffected,
+			"retention_days": retentionDays,
+		}).Info("Pruned expired hourly usage rollups")
+	}
+}
+
+// QueryUsage returns the rollups of a granularity in [start, end) within a group scope, optionally
+// limited to a group (including the requests it forwarded to its sub groups) and a model.
+func (s *UsageRollupService) QueryUsage(scope *GroupScope, granularity string, start, end time.Time, groupID uint, model string) ([]models.UsageRollup, error) {
+	query := s.db.Model(&models.UsageDailyRollup{})
+	if granularity == UsageGranularityHour {
+		query = s.db.Model(&models.UsageHourlyRollup{})
+	}
+	query = query.Scopes(scope.ByGroupID("group_id")).Where("time >= ? AND time < ?", start.UTC(), end.UTC())
+	if groupID != 0 {
+		query = query.Where("group_id = ? OR parent_group_id = ?", groupID, groupID)
+	}
+	if model != "" {
+		query = query.Where("model = ?", model)
+	}
+
+	var rollups []models.UsageRollup
+	if err := query.Order("time asc, group_id asc, model asc").Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+func startOfUTCDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// sessionTTL is how long a login stays valid.
+	sessionTTL = 24 * time.Hour
+	// sessionKeyPrefix namespaces sessions in the store, which is shared by all nodes.
+	sessionKeyPrefix = "session:"
+	// minPasswordLength is the shortest password accepted for users.
+	minPasswordLength = 8
+)
+
+// AuthKeyUsername is the name shown for requests authenticated with AUTH_KEY, which always act as
+// an administrator.
+const AuthKeyUsername = "AUTH_KEY"
+
+// Roles lists the user roles from the least to the most privileged.
+var Roles = []string{models.RoleViewer, models.RoleOperator, models.RoleAdmin}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// dummyPasswordHash is compared against when a username does not exist, so that failed logins take
+// the same time whether or not the user exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("gpt-load-dummy-password"), bcrypt.DefaultCost)
+
+// Login errors. ErrTOTPRequired asks the client for a second factor and retry.
+var (
+	ErrLoginFailed  = errors.New("invalid username or password")
+	ErrTOTPRequired = errors.New("two-factor code required")
+)
+
+// Principal is who an admin API request is authenticated as.
+type Principal struct {
+	UserID      uint   `json:"id"`
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+}
+
+// RoleAllows reports whether role grants at least the permissions of required.
+func RoleAllows(role, required string) bool {
+	rank := slices.Index(Roles, role)
+	return rank >= 0 && rank >= slices.Index(Roles, required)
+}
+
+// UserParams are the editable fields of a user. An empty password keeps the current one on update.
+type UserParams struct {
+	Username string
+	Password string
+	Role     string
+	Disabled bool
+}
+
+// session is what the store keeps for a login token. Sessions of single sign-on logins have no user
+// and carry the username and role granted by the identity provider instead.
+type session struct {
+	UserID   uint   `json:"user_id"`
+	Version  int    `json:"version"`
+	Username string `json:"username,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+// UserService manages the users of the admin interface and their sessions. AUTH_KEY keeps working
+// as the credential of a built-in administrator, so the first users can be created with it.
+type UserService struct {
+	db              *gorm.DB
+	store           store.Store
+	configManager   types.ConfigManager
+	settingsManager *config.SystemSettingsManager
+	encryptionSvc   encryption.Service
+}
+
+// NewUserService creates a new UserService.
+func NewUserService(
+	db *gorm.DB,
+	store store.Store,
+	configManager types.ConfigManager,
+	settingsManager *config.SystemSettingsManager,
+	encryptionSvc encryption.Service,
+) *UserService {
+	return &UserService{
+		db:              db,
+		store:           store,
+		configManager:   configManager,
+		settingsManager: settingsManager,
+		encryptionSvc:   encryptionSvc,
+	}
+}
+
+// Authenticate resolves a bearer token, either AUTH_KEY or a session token, to its principal.
+func (s *UserService) Authenticate(token string) (*Principal, bool) {
+	if token == "" {
+		return nil, false
+	}
+	if s.isAuthKey(token) {
+		return &Principal{Username: AuthKeyUsername, Role: models.RoleAdmin}, true
+	}
+
+	data, err := s.store.Get(sessionKey(token))
+	if err != nil {
+		return nil, false
+	}
+	var sess session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, false
+	}
+	if sess.UserID == 0 {
+		if !slices.Contains(Roles, sess.Role) {
+			return nil, false
+		}
+		return &Principal{Username: sess.Username, Role: sess.Role}, true
+	}
+	var user models.User
+	if err := s.db.First(&user, sess.UserID).Error; err != nil {
+		return nil, false
+	}
+	if user.Disabled || user.SessionVersion != sess.Version {
+		return nil, false
+	}
+	return userPrincipal(&user), true
+}
+
+// Login checks a username and password, and the TOTP or recovery code of users with two-factor
+// authentication, and returns a new session token. An empty username logs in with AUTH_KEY as the
+// password, and the key itself is the token.
+func (s *UserService) Login(username, password, totpCode string) (string, *Principal, error) {
+	if username == "" {
+		if !s.isAuthKey(password) {
+			return "", nil, ErrLoginFailed
+		}
+		return password, &Principal{Username: AuthKeyUsername, Role: models.RoleAdmin}, nil
+	}
+
+	var user models.User
+	err := s.db.Where("username = ?", username).First(&user).Error
+	if err != nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return "", nil, ErrLoginFailed
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil || user.Disabled {
+		return "", nil, ErrLoginFailed
+	}
+	if user.TOTPEnabled {
+		if strings.TrimSpace(totpCode) == "" {
+			return "", nil, ErrTOTPRequired
+		}
+		if err := s.verifySecondFactor(&user, totpCode); err != nil {
+			return "", nil, err
+		}
+	}
+
+	token, err := s.createSession(session{UserID: user.ID, Version: user.SessionVersion})
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	s.db.Model(&user).UpdateColumn("last_login_at", now)
+	return token, userPrincipal(&user), nil
+}
+
+// LoginExternal starts a session for a principal authenticated by an identity provider.
+func (s *UserService) LoginExternal(principal *Principal) (string, error) {
+	return s.createSession(session{Username: principal.Username, Role: principal.Role})
+}
+
+// createSession stores sess under a new random token.
+func (s *UserService) createSession(sess session) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	data, _ := json.Marshal(sess)
+	if err := s.store.Set(sessionKey(token), data, sessionTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Logout ends a session. AUTH_KEY cannot be signed out.
+func (s *UserService) Logout(token string) error {
+	if token == "" || s.isAuthKey(token) {
+		return nil
+	}
+	return s.store.Delete(sessionKey(token))
+}
+
+// ListUsers returns all users.
+func (s *UserService) ListUsers() ([]models.User, error) {
+	var users []models.User
+	if err := s.db.Order("id asc").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CreateUser validates and stores a new user.
+func (s *UserService) CreateUser(params UserParams) (*models.User, error) {
+	if params.Password == "" {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.password_too_short", map[string]any{"min": minPasswordLength})
+	}
+	user := &models.User{}
+	if err := s.applyUserParams(user, params); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return user, nil
+}
+
+// UpdateUser validates and replaces the fields of a user. Changing the password or disabling the
+// user signs them out of every session.
+func (s *UserService) UpdateUser(id uint, params UserParams) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	wasDisabled := user.Disabled
+	if err := s.applyUserParams(&user, params); err != nil {
+		return nil, err
+	}
+	if params.Password != "" || (user.Disabled && !wasDisabled) {
+		user.SessionVersion++
+	}
+	if err := s.db.Select("*").Omit("created_at", "last_login_at").Save(&user).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &user, nil
+}
+
+// DeleteUser removes a user; their sessions stop working with the next request.
+func (s *UserService) DeleteUser(id uint) error {
+	result := s.db.Delete(&models.User{}, id)
+	if result.Error != nil {
+		return app_errors.ParseDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return app_errors.ErrResourceNotFound
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of the signed-in user after checking the current one, and
+// signs out their other sessions. currentToken stays valid.
+func (s *UserService) ChangePassword(principal *Principal, currentToken, currentPassword, newPassword string) error {
+	if principal.UserID == 0 {
+		return NewI18nError(app_errors.ErrValidation, "validation.password_change_unavailable", nil)
+	}
+	var user models.User
+	if err := s.db.First(&user, principal.UserID).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)) != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.current_password_incorrect", nil)
+	}
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	user.SessionVersion++
+	if err := s.db.Model(&user).Select("password_hash", "session_version").Updates(&user).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	data, _ := json.Marshal(session{UserID: user.ID, Version: user.SessionVersion})
+	return s.store.Set(sessionKey(currentToken), data, sessionTTL)
+}
+
+// applyUserParams validates params and copies them onto user.
+func (s *UserService) applyUserParams(user *models.User, params UserParams) error {
+	username := strings.TrimSpace(params.Username)
+	if !usernamePattern.MatchString(username) || strings.EqualFold(username, AuthKeyUsername) {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_username", nil)
+	}
+	if !slices.Contains(Roles, params.Role) {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_role", map[string]any{"roles": strings.Join(Roles, ", ")})
+	}
+
+	var existing models.User
+	err := s.db.Where("username = ? AND id <> ?", username, user.ID).First(&existing).Error
+	if err == nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.username_taken", nil)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return app_errors.ParseDBError(err)
+	}
+
+	if params.Password != "" {
+		hash, err := hashPassword(params.Password)
+		if err != nil {
+			return err
+		}
+		user.PasswordHash = hash
+	}
+	user.Username = username
+	user.Role = params.Role
+	user.Disabled = params.Disabled
+	return nil
+}
+
+func (s *UserService) isAuthKey(token string) bool {
+	key := s.configManager.GetAuthConfig().Key
+	return key != "" && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1
+}
+
+// userPrincipal returns the principal of a user.
+func userPrincipal(user *models.User) *Principal {
+	return &Principal{UserID: user.ID, Username: user.Username, Role: user.Role, TOTPEnabled: user.TOTPEnabled}
+}
+
+// hashPassword checks the password policy and hashes the password with bcrypt.
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", NewI18nError(app_errors.ErrValidation, "validation.password_too_short", map[string]any{"min": minPasswordLength})
+	}
+	// bcrypt ignores everything past 72 bytes
+	if len(password) > 72 {
+		return "", NewI18nError(app_errors.ErrValidation, "validation.password_too_long", map[string]any{"max": 72})
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// sessionKey stores sessions under a hash of their token, so that the store never holds a usable
+// token.
+func sessionKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return sessionKeyPrefix + hex.EncodeToString(sum[:])
+}
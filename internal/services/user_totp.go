@@ -0,0 +1,272 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// totpIssuer is the account issuer shown in authenticator apps.
+	totpIssuer = "GPT-Load"
+	// recoveryCodeCount is how many one-time recovery codes are issued on enrollment.
+	recoveryCodeCount = 10
+	// totpMaxFailures wrong codes in a row lock two-factor verification of a user for
+	// totpLockout, so that codes cannot be guessed.
+	totpMaxFailures = 5
+	totpLockout     = 15 * time.Minute
+	// totpUsedKeyPrefix remembers accepted time steps so that an observed code cannot be replayed.
+	totpUsedKeyPrefix     = "totp_used:"
+	totpFailuresKeyPrefix = "totp_failures:"
+)
+
+// TOTPEnrollment is returned when a user starts enrolling an authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// SetupTOTP generates a new TOTP secret for the signed-in user. The secret only takes effect once
+// EnableTOTP confirms a code from the authenticator app.
+func (s *UserService) SetupTOTP(principal *Principal) (*TOTPEnrollment, error) {
+	user, err := s.totpUser(principal)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.two_factor_already_enabled", nil)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptionSvc.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).UpdateColumn("totp_secret", encrypted).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &TOTPEnrollment{Secret: secret, URI: utils.TOTPURI(totpIssuer, user.Username, secret)}, nil
+}
+
+// EnableTOTP confirms enrollment with a code from the authenticator app and returns the recovery
+// codes, which are only shown this once.
+func (s *UserService) EnableTOTP(principal *Principal, code string) ([]string, error) {
+	user, err := s.totpUser(principal)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.two_factor_already_enabled", nil)
+	}
+	if user.TOTPSecret == "" {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.two_factor_not_set_up", nil)
+	}
+	if err := s.verifyTOTPCode(user, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		encoded := hex.EncodeToString(buf)
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+	}
+	if err := s.saveRecoveryCodes(user, codes); err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).UpdateColumn("totp_enabled", true).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off after checking a TOTP or recovery code.
+func (s *UserService) DisableTOTP(principal *Principal, code string) error {
+	user, err := s.totpUser(principal)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return NewI18nError(app_errors.ErrValidation, "validation.two_factor_not_enabled", nil)
+	}
+	if err := s.verifySecondFactor(user, code); err != nil {
+		return err
+	}
+	return s.ResetTOTP(user.ID)
+}
+
+// ResetTOTP removes the second factor of a user, e.g. when an admin helps someone who lost their
+// device.
+func (s *UserService) ResetTOTP(id uint) error {
+	result := s.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]any{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"recovery_codes": "",
+	})
+	if result.Error != nil {
+		return app_errors.ParseDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return app_errors.ErrResourceNotFound
+	}
+	return nil
+}
+
+// SecondFactorEnforced reports whether destructive operations require a fresh TOTP code.
+func (s *UserService) SecondFactorEnforced() bool {
+	return s.settingsManager.GetSettings().Require2FAForDestructive
+}
+
+// CheckSecondFactor guards destructive operations while enforcement is on: users must have
+// two-factor authentication and send a current code. AUTH_KEY and single sign-on principals have no
+// second factor here, so they are refused and these operations need a user account.
+func (s *UserService) CheckSecondFactor(principal *Principal, code string) error {
+	if !s.SecondFactorEnforced() {
+		return nil
+	}
+	if principal.UserID == 0 {
+		return NewI18nError(app_errors.ErrForbidden, "auth.two_factor_account_required", nil)
+	}
+	var user models.User
+	if err := s.db.First(&user, principal.UserID).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	if !user.TOTPEnabled {
+		return NewI18nError(app_errors.ErrForbidden, "auth.two_factor_enrollment_required", nil)
+	}
+	if strings.TrimSpace(code) == "" {
+		return NewI18nError(app_errors.ErrTOTPRequired, "auth.two_factor_required", nil)
+	}
+	return s.verifySecondFactor(&user, code)
+}
+
+// totpUser loads the user behind a principal. AUTH_KEY and single sign-on logins have no account to
+// enroll.
+func (s *UserService) totpUser(principal *Principal) (*models.User, error) {
+	if principal.UserID == 0 {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.two_factor_unavailable", nil)
+	}
+	var user models.User
+	if err := s.db.First(&user, principal.UserID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &user, nil
+}
+
+// verifySecondFactor accepts a TOTP code, or else consumes a recovery code.
+func (s *UserService) verifySecondFactor(user *models.User, code string) error {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	if _, err := strconv.Atoi(code); err == nil && len(code) < 10 {
+		return s.verifyTOTPCode(user, code)
+	}
+	if s.totpLocked(user.ID) {
+		return NewI18nError(app_errors.ErrTOTPRequired, "auth.two_factor_locked", nil)
+	}
+
+	codes, err := s.recoveryCodes(user)
+	if err != nil {
+		return err
+	}
+	normalized := strings.ReplaceAll(code, "-", "")
+	for i, candidate := range codes {
+		if subtle.ConstantTimeCompare([]byte(strings.ReplaceAll(candidate, "-", "")), []byte(normalized)) == 1 {
+			remaining := append(codes[:i:i], codes[i+1:]...)
+			if err := s.saveRecoveryCodes(user, remaining); err != nil {
+				return err
+			}
+			logrus.Infof("User %s used a recovery code, %d left", user.Username, len(remaining))
+			return nil
+		}
+	}
+	s.recordTOTPFailure(user.ID)
+	return NewI18nError(app_errors.ErrTOTPRequired, "auth.two_factor_invalid", nil)
+}
+
+// verifyTOTPCode checks a code from the authenticator app. Each time step is accepted once.
+func (s *UserService) verifyTOTPCode(user *models.User, code string) error {
+	if s.totpLocked(user.ID) {
+		return NewI18nError(app_errors.ErrTOTPRequired, "auth.two_factor_locked", nil)
+	}
+	secret, err := s.encryptionSvc.Decrypt(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := utils.VerifyTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		s.recordTOTPFailure(user.ID)
+		return NewI18nError(app_errors.ErrTOTPRequired, "auth.two_factor_invalid", nil)
+	}
+	fresh, err := s.store.SetNX(fmt.Sprintf("%s%d:%d", totpUsedKeyPrefix, user.ID, step), []byte("1"), 2*time.Minute)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return NewI18nError(app_errors.ErrTOTPRequired, "auth.two_factor_reused", nil)
+	}
+	_ = s.store.Delete(fmt.Sprintf("%s%d", totpFailuresKeyPrefix, user.ID))
+	return nil
+}
+
+func (s *UserService) totpLocked(userID uint) bool {
+	data, err := s.store.Get(fmt.Sprintf("%s%d", totpFailuresKeyPrefix, userID))
+	if err != nil {
+		return false
+	}
+	failures, _ := strconv.Atoi(string(data))
+	return failures >= totpMaxFailures
+}
+
+// recordTOTPFailure counts a wrong code. The count expires totpLockout after the last failure.
+func (s *UserService) recordTOTPFailure(userID uint) {
+	key := fmt.Sprintf("%s%d", totpFailuresKeyPrefix, userID)
+	failures := 0
+	if data, err := s.store.Get(key); err == nil {
+		failures, _ = strconv.Atoi(string(data))
+	}
+	_ = s.store.Set(key, []byte(strconv.Itoa(failures+1)), totpLockout)
+}
+
+func (s *UserService) recoveryCodes(user *models.User) ([]string, error) {
+	if user.RecoveryCodes == "" {
+		return nil, nil
+	}
+	plaintext, err := s.encryptionSvc.Decrypt(user.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	if err := json.Unmarshal([]byte(plaintext), &codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *UserService) saveRecoveryCodes(user *models.User, codes []string) error {
+	data, _ := json.Marshal(codes)
+	encrypted, err := s.encryptionSvc.Encrypt(string(data))
+	if err != nil {
+		return err
+	}
+	if err := s.db.Model(user).UpdateColumn("recovery_codes", encrypted).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	user.RecoveryCodes = encrypted
+	return nil
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/notification"
+	"gpt-load/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Webhook event types.
+const (
+	WebhookEventKeyInvalidated = "key.invalidated"
+	WebhookEventKeyRecovered   = "key.recovered"
+	WebhookEventGroupCreated   = "group.created"
+	WebhookEventBudgetExceeded = "budget.exceeded"
+	WebhookEventUpstreamDown   = "upstream.down"
+	WebhookEventModelAdded     = "model.added"
+	// WebhookEventPing is only sent by the test endpoint.
+	WebhookEventPing = "ping"
+)
+
+// WebhookEventTypes lists the event types subscriptions can select.
+var WebhookEventTypes = []string{
+	WebhookEventKeyInvalidated,
+	WebhookEventKeyRecovered,
+	WebhookEventGroupCreated,
+	WebhookEventBudgetExceeded,
+	WebhookEventUpstreamDown,
+	WebhookEventModelAdded,
+}
+
+// webhookNotificationEvents maps the notification events forwarded to webhooks to their event type.
+var webhookNotificationEvents = map[string]string{
+	notification.EventKeyDisabled:    WebhookEventKeyInvalidated,
+	notification.EventKeyExpired:     WebhookEventKeyInvalidated,
+	notification.EventKeyRecovered:   WebhookEventKeyRecovered,
+	notification.EventModelsDetected: WebhookEventModelAdded,
+}
+
+// Headers of webhook deliveries. The signature is the hex HMAC-SHA256 of the timestamp, a dot and
+// the body, keyed with the subscription's secret, so receivers can reject replayed deliveries.
+const (
+	WebhookHeaderEvent     = "X-GPT-Load-Event"
+	WebhookHeaderDelivery  = "X-GPT-Load-Delivery"
+	WebhookHeaderTimestamp = "X-GPT-Load-Timestamp"
+	WebhookHeaderSignature = "X-GPT-Load-Signature"
+)
+
+const (
+	// webhookQueueSize bounds the events waiting to be dispatched; events beyond it are dropped.
+	webhookQueueSize = 1000
+	// webhookMaxAttempts is how often a delivery is tried before it is given up.
+	webhookMaxAttempts = 5
+	// webhookInitialBackoff is the wait before the first retry; it doubles with every retry.
+	webhookInitialBackoff = 2 * time.Second
+	// webhookTimeout bounds a single delivery attempt.
+	webhookTimeout = 10 * time.Second
+)
+
+// WebhookEvent is the JSON body posted to subscriptions.
+type WebhookEvent struct {
+	ID      string         `json:"id"`
+	Type    string         `json:"type"`
+	Time    string         `json:"time"`
+	GroupID uint           `json:"group_id,omitempty"`
+	Group   string         `json:"group,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// WebhookSubscriptionParams are the editable fields of a webhook subscription. An empty Secret
+// keeps the current secret unless ClearSecret is set.
+type WebhookSubscriptionParams struct {
+	Name        string
+	URL         string
+	Secret      string
+	ClearSecret bool
+	Events      []string
+	GroupID     *uint
+	Enabled     bool
+}
+
+// webhookDeliveryError is a failed attempt; retry is false when trying again cannot help.
+type webhookDeliveryError struct {
+	err   error
+	retry bool
+}
+
+func (e *webhookDeliveryError) Error() string { return e.err.Error() }
+
+// WebhookService delivers lifecycle events to the webhook subscriptions. Events are queued and
+// delivered in the background, retrying failed deliveries with exponential backoff. Each instance
+// delivers the events it observes, and deliveries still being retried are dropped on shutdown.
+type WebhookService struct {
+	db            *gorm.DB
+	encryptionSvc encryption.Service
+	client        *http.Client
+	queue         chan WebhookEvent
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+}
+
+// NewWebhookService creates a new WebhookService and subscribes it to the notifications that
+// become webhook events.
+func NewWebhookService(db *gorm.DB, encryptionSvc encryption.Service, notificationService *notification.Service) *WebhookService {
+	s := &WebhookService{
+		db:            db,
+		encryptionSvc: encryptionSvc,
+		client:        &http.Client{Timeout: webhookTimeout},
+		queue:         make(chan WebhookEvent, webhookQueueSize),
+		stopCh:        make(chan struct{}),
+	}
+	notificationService.AddListener(s.handleNotification)
+	return s
+}
+
+// Start begins dispatching queued events.
+func (s *WebhookService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Webhook service started")
+}
+
+// Stop stops dispatching and waits for the deliveries in progress.
+func (s *WebhookService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("WebhookService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("WebhookService stop timed out.")
+	}
+}
+
+// Publish queues an event for the subscriptions to it. It never blocks; events are dropped with a
+// warning when the queue is full.
+func (s *WebhookService) Publish(eventType string, groupID uint, groupName, message string, data map[string]any) {
+	event := WebhookEvent{
+		ID:      uuid.NewString(),
+		Type:    eventType,
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		GroupID: groupID,
+		Group:   groupName,
+		Message: message,
+		Data:    data,
+	}
+	select {
+	case s.queue <- event:
+	default:
+		logrus.WithField("event", eventType).Warn("Webhook queue is full, event dropped")
+	}
+}
+
+// handleNotification forwards key and model notifications as webhook events.
+func (s *WebhookService) handleNotification(e notification.Event) {
+	eventType, ok := webhookNotificationEvents[e.Event]
+	if !ok {
+		return
+	}
+	s.Publish(eventType, e.GroupID, e.GroupName, e.Message, e.Params)
+}
+
+func (s *WebhookService) run() {
+	defer s.wg.Done()
+	for {
+		select {
+		case event := <-s.queue:
+			s.dispatch(event)
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// dispatch starts a delivery to every enabled subscription of the event.
+func (s *WebhookService) dispatch(event WebhookEvent) {
+	query := s.db.Where("enabled = ?", true)
+	if event.GroupID != 0 {
+		query = query.Where("group_id IS NULL OR group_id = ?", event.GroupID)
+	} else {
+		query = query.Where("group_id IS NULL")
+	}
+	var subscriptions []models.WebhookSubscription
+	if err := query.Find(&subscriptions).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to load webhook subscriptions")
+		return
+	}
+
+	if event.Group == "" && event.GroupID != 0 {
+		s.db.Model(&models.Group{}).Where("id = ?", event.GroupID).Pluck("name", &event.Group)
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logrus.WithError(err).WithField("event", event.Type).Error("Failed to serialize webhook event")
+		return
+	}
+
+	for i := range subscriptions {
+		if !webhookSubscribed(&subscriptions[i], event.Type) {
+			continue
+		}
+		s.wg.Add(1)
+		go func(subscription models.WebhookSubscription) {
+			defer s.wg.Done()
+			s.deliverWithRetry(&subscription, event, body)
+		}(subscriptions[i])
+	}
+}
+
+// deliverWithRetry tries a delivery until it succeeds, fails permanently, runs out of attempts or
+// the service stops, and records the outcome on the subscription.
+func (s *WebhookService) deliverWithRetry(subscription *models.WebhookSubscription, event WebhookEvent, body []byte) {
+	backoff := webhookInitialBackoff
+	var err error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		err = s.deliver(context.Background(), subscription, event, body)
+		if err == nil {
+			break
+		}
+		deliveryErr, ok := err.(*webhookDeliveryError)
+		if (ok && !deliveryErr.retry) || attempt == webhookMaxAttempts {
+			break
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"webhook": subscription.Name,
+			"event":   event.Type,
+			"attempt": attempt,
+		}).Debug("Webhook delivery failed, retrying")
+
+		select {
+		case <-time.After(backoff):
+		case <-s.stopCh:
+			err = fmt.Errorf("delivery abandoned on shutdown: %w", err)
+			s.recordDelivery(subscription.ID, err)
+			return
+		}
+		backoff *= 2
+	}
+
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"webhook": subscription.Name, "event": event.Type}).Warn("Failed to deliver webhook event")
+	}
+	s.recordDelivery(subscription.ID, err)
+}
+
+// deliver posts a signed event to a subscription once. Network errors, 429 and 5xx responses can
+// be retried; other non-2xx responses cannot.
+func (s *WebhookService) deliver(ctx context.Context, subscription *models.WebhookSubscription, event WebhookEvent, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return &webhookDeliveryError{err: err}
+	}
+	secret := ""
+	if subscription.Secret != "" {
+		if secret, err = s.encryptionSvc.Decrypt(subscription.Secret); err != nil {
+			return &webhookDeliveryError{err: fmt.Errorf("failed to decrypt the webhook secret: %w", err)}
+		}
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gpt-load-webhooks")
+	req.Header.Set(WebhookHeaderEvent, event.Type)
+	req.Header.Set(WebhookHeaderDelivery, event.ID)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	if secret != "" {
+		req.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhookPayload(secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &webhookDeliveryError{err: err, retry: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &webhookDeliveryError{
+			err:   fmt.Errorf("webhook responded with %d: %s", resp.StatusCode, bytes.TrimSpace(snippet)),
+			retry: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		}
+	}
+	return nil
+}
+
+// recordDelivery stores the outcome of the last delivery on the subscription.
+func (s *WebhookService) recordDelivery(id uint, err error) {
+	updates := map[string]any{
+		"last_delivery_at":     time.Now(),
+		"last_delivery_status": models.WebhookDeliveryDelivered,
+		"last_delivery_error":  "",
+	}
+	if err != nil {
+		updates["last_delivery_status"] = models.WebhookDeliveryFailed
+		updates["last_delivery_error"] = utils.TruncateString(err.Error(), 500)
+	}
+	if dbErr := s.db.Model(&models.WebhookSubscription{}).Where("id = ?", id).UpdateColumns(updates).Error; dbErr != nil {
+		logrus.WithError(dbErr).Warn("Failed to record webhook delivery")
+	}
+}
+
+// EncryptPlaintextSecrets encrypts the secrets of subscriptions saved before secrets were encrypted.
+// Values that already have the shape of ciphertext are left alone, even when they were encrypted
+// with another key.
+func (s *WebhookService) EncryptPlaintextSecrets() error {
+	var subscriptions []models.WebhookSubscription
+	if err := s.db.Select("id, secret").Where("secret <> ''").Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	encrypted := 0
+	for _, subscription := range subscriptions {
+		if _, err := s.encryptionSvc.Decrypt(subscription.Secret); err == nil || encryption.LooksEncrypted(subscription.Secret) {
+			continue
+		}
+		secret, err := s.encryptionSvc.Encrypt(subscription.Secret)
+		if err != nil {
+			return err
+		}
+		// The old value guards against overwriting a secret changed since it was read.
+		result := s.db.Model(&models.WebhookSubscription{}).
+			Where("id = ? AND secret = ?", subscription.ID, subscription.Secret).
+			UpdateColumn("secret", secret)
+		if result.Error != nil {
+			return result.Error
+		}
+		encrypted += int(result.RowsAffected)
+	}
+	if encrypted > 0 {
+		logrus.Infof("Encrypted the secrets of %d webhook subscriptions", encrypted)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 signature of a delivery.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookSubscribed reports whether a subscription selected an event type.
+func webhookSubscribed(subscription *models.WebhookSubscription, eventType string) bool {
+	var events []string
+	if len(subscription.Events) > 0 {
+		if err := json.Unmarshal(subscription.Events, &events); err != nil {
+			return false
+		}
+	}
+	return len(events) == 0 || slices.Contains(events, eventType)
+}
+
+// ListSubscriptions returns all webhook subscriptions.
+func (s *WebhookService) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := s.db.Order("id asc").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		subscriptions[i].SecretSet = subscriptions[i].Secret != ""
+	}
+	return subscriptions, nil
+}
+
+// CreateSubscription validates and stores a new webhook subscription.
+func (s *WebhookService) CreateSubscription(params WebhookSubscriptionParams) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{}
+	if err := s.applySubscriptionParams(subscription, params); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(subscription).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	subscription.SecretSet = subscription.Secret != ""
+	return subscription, nil
+}
+
+// UpdateSubscription validates and replaces the fields of a webhook subscription.
+func (s *WebhookService) UpdateSubscription(id uint, params WebhookSubscriptionParams) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := s.db.First(&subscription, id).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	if err := s.applySubscriptionParams(&subscription, params); err != nil {
+		return nil, err
+	}
+	if err := s.db.Select("*").Omit("created_at").Save(&subscription).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	subscription.SecretSet = subscription.Secret != ""
+	return &subscription, nil
+}
+
+// DeleteSubscription removes a webhook subscription.
+func (s *WebhookService) DeleteSubscription(id uint) error {
+	result := s.db.Delete(&models.WebhookSubscription{}, id)
+	if result.Error != nil {
+		return app_errors.ParseDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return app_errors.ErrResourceNotFound
+	}
+	return nil
+}
+
+// TestSubscription sends a ping event to a subscription once, without retries, and returns the
+// delivery error, if any.
+func (s *WebhookService) TestSubscription(ctx context.Context, id uint) error {
+	var subscription models.WebhookSubscription
+	if err := s.db.First(&subscription, id).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+
+	event := WebhookEvent{
+		ID:      uuid.NewString(),
+		Type:    WebhookEventPing,
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Message: fmt.Sprintf("Test event for webhook '%s'", subscription.Name),
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	err = s.deliver(ctx, &subscription, event, body)
+	s.recordDelivery(subscription.ID, err)
+	if err != nil {
+		return NewI18nError(app_errors.ErrBadGateway, "webhook.test_failed", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
+// applySubscriptionParams validates params and copies them onto subscription.
+func (s *WebhookService) applySubscriptionParams(subscription *models.WebhookSubscription, params WebhookSubscriptionParams) error {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return NewI18nError(app_errors.ErrValidation, "validation.webhook_name_required", nil)
+	}
+	webhookURL := strings.TrimSpace(params.URL)
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_webhook_url", nil)
+	}
+
+	events := make([]string, 0, len(params.Events))
+	for _, event := range params.Events {
+		if !slices.Contains(WebhookEventTypes, event) {
+			return NewI18nError(app_errors.ErrValidation, "validation.invalid_webhook_events", map[string]any{"events": strings.Join(WebhookEventTypes, ", ")})
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	encodedEvents, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	if params.GroupID != nil {
+		var count int64
+		if err := s.db.Model(&models.Group{}).Where("id = ?", *params.GroupID).Count(&count).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		if count == 0 {
+			return NewI18nError(app_errors.ErrValidation, "validation.group_not_found", nil)
+		}
+	}
+
+	subscription.Name = name
+	subscription.URL = webhookURL
+	switch {
+	case params.ClearSecret:
+		subscription.Secret = ""
+	case params.Secret != "":
+		secret, err := s.encryptionSvc.Encrypt(params.Secret)
+		if err != nil {
+			return app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to encrypt the webhook secret")
+		}
+		subscription.Secret = secret
+	}
+	subscription.Events = datatypes.JSON(encodedEvents)
+	subscription.GroupID = params.GroupID
+	subscription.Enabled = params.Enabled
+	return nil
+}
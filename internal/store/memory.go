@@ -18,14 +18,14 @@ type MemoryStore struct {
 	mu            sync.RWMutex
 	data          map[string]any
 	muSubscribers sync.RWMutex
-	subscribers   map[string]map[chan *Message]struct{}
+	subscribers   map[string]map[*memorySubscription]struct{}
 }
 
 // NewMemoryStore creates and returns a new MemoryStore instance.
 func NewMemoryStore() *MemoryStore {
 	s := &MemoryStore{
 		data:        make(map[string]any),
-		subscribers: make(map[string]map[chan *Message]struct{}),
+		subscribers: make(map[string]map[*memorySubscription]struct{}),
 	}
 	return s
 }
@@ -384,6 +384,10 @@ type memorySubscription struct {
 	store   *MemoryStore
 	channel string
 	msgChan chan *Message
+	// done stops pending deliveries, and senders tracks them so msgChan is only closed once
+	// nothing can send on it anymore
+	done    chan struct{}
+	senders sync.WaitGroup
 }
 
 // Channel returns the message channel for the subscription.
@@ -394,14 +398,19 @@ func (ms *memorySubscription) Channel() <-chan *Message {
 // Close removes the subscription from the store.
 func (ms *memorySubscription) Close() error {
 	ms.store.muSubscribers.Lock()
-	defer ms.store.muSubscribers.Unlock()
-
-	if subs, ok := ms.store.subscribers[ms.channel]; ok {
-		delete(subs, ms.msgChan)
-		if len(subs) == 0 {
-			delete(ms.store.subscribers, ms.channel)
-		}
+	subs, ok := ms.store.subscribers[ms.channel]
+	if _, subscribed := subs[ms]; !ok || !subscribed {
+		ms.store.muSubscribers.Unlock()
+		return nil
 	}
+	delete(subs, ms)
+	if len(subs) == 0 {
+		delete(ms.store.subscribers, ms.channel)
+	}
+	ms.store.muSubscribers.Unlock()
+
+	close(ms.done)
+	ms.senders.Wait()
 	close(ms.msgChan)
 	return nil
 }
@@ -417,13 +426,16 @@ func (s *MemoryStore) Publish(channel string, message []byte) error {
 	}
 
 	if subs, ok := s.subscribers[channel]; ok {
-		for subCh := range subs {
-			go func(c chan *Message) {
+		for sub := range subs {
+			sub.senders.Add(1)
+			go func(sub *memorySubscription) {
+				defer sub.senders.Done()
 				select {
-				case c <- msg:
+				case sub.msgChan <- msg:
+				case <-sub.done:
 				case <-time.After(1 * time.Second):
 				}
-			}(subCh)
+			}(sub)
 		}
 	}
 	return nil
@@ -434,18 +446,17 @@ func (s *MemoryStore) Subscribe(channel string) (Subscription, error) {
 	s.muSubscribers.Lock()
 	defer s.muSubscribers.Unlock()
 
-	msgChan := make(chan *Message, 10) // Buffered channel
-
-	if _, ok := s.subscribers[channel]; !ok {
-		s.subscribers[channel] = make(map[chan *Message]struct{})
-	}
-	s.subscribers[channel][msgChan] = struct{}{}
-
 	sub := &memorySubscription{
 		store:   s,
 		channel: channel,
-		msgChan: msgChan,
+		msgChan: make(chan *Message, 10), // Buffered channel
+		done:    make(chan struct{}),
+	}
+
+	if _, ok := s.subscribers[channel]; !ok {
+		s.subscribers[channel] = make(map[*memorySubscription]struct{})
 	}
+	s.subscribers[channel][sub] = struct{}{}
 
 	return sub, nil
 }
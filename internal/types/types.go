@@ -7,10 +7,15 @@ type ConfigManager interface {
 	GetCORSConfig() CORSConfig
 	GetPerformanceConfig() PerformanceConfig
 	GetLogConfig() LogConfig
+	GetAccessLogConfig() AccessLogConfig
 	GetDatabaseConfig() DatabaseConfig
 	GetEncryptionKey() string
+	GetEncryptionConfig() EncryptionConfig
 	GetEffectiveServerConfig() ServerConfig
 	GetRedisDSN() string
+	GetHookScriptDir() string
+	GetConfigFilePath() string
+	GetPlugins() []string
 	Validate() error
 	DisplayServerConfig()
 	ReloadConfig() error
@@ -21,28 +26,113 @@ type SystemSettings struct {
 	// 基础参数
 	AppUrl                         string `json:"app_url" default:"http://localhost:3001" name:"config.app_url" category:"config.category.basic" desc:"config.app_url_desc" validate:"required"`
 	ProxyKeys                      string `json:"proxy_keys" name:"config.proxy_keys" category:"config.category.basic" desc:"config.proxy_keys_desc" validate:"required"`
+	ProxyKeyMetadata               string `json:"proxy_key_metadata" name:"config.proxy_key_metadata" category:"config.category.basic" desc:"config.proxy_key_metadata_desc" validate:"proxy_key_metadata"`
 	RequestLogRetentionDays        int    `json:"request_log_retention_days" default:"7" name:"config.log_retention_days" category:"config.category.basic" desc:"config.log_retention_days_desc" validate:"required,min=0"`
+	UsageRollupRetentionDays       int    `json:"usage_rollup_retention_days" default:"90" name:"config.usage_rollup_retention_days" category:"config.category.basic" desc:"config.usage_rollup_retention_days_desc" validate:"min=0"`
 	RequestLogWriteIntervalMinutes int    `json:"request_log_write_interval_minutes" default:"1" name:"config.log_write_interval" category:"config.category.basic" desc:"config.log_write_interval_desc" validate:"required,min=0"`
 	EnableRequestBodyLogging       bool   `json:"enable_request_body_logging" default:"false" name:"config.enable_request_body_logging" category:"config.category.basic" desc:"config.enable_request_body_logging_desc"`
+	TranscriptProxyKeys            string `json:"transcript_proxy_keys" name:"config.transcript_proxy_keys" category:"config.category.basic" desc:"config.transcript_proxy_keys_desc"`
+	TranscriptSamplePercent        int    `json:"transcript_sample_percent" default:"0" name:"config.transcript_sample_percent" category:"config.category.basic" desc:"config.transcript_sample_percent_desc" validate:"min=0,max=100"`
+	TranscriptRetentionDays        int    `json:"transcript_retention_days" default:"7" name:"config.transcript_retention_days" category:"config.category.basic" desc:"config.transcript_retention_days_desc" validate:"required,min=1"`
+	SandboxMaxTTLHours             int    `json:"sandbox_max_ttl_hours" default:"720" name:"config.sandbox_max_ttl_hours" category:"config.category.basic" desc:"config.sandbox_max_ttl_hours_desc" validate:"required,min=1"`
+	SandboxRetentionHours          int    `json:"sandbox_retention_hours" default:"24" name:"config.sandbox_retention_hours" category:"config.category.basic" desc:"config.sandbox_retention_hours_desc" validate:"min=0"`
+	KeyExpiryWarningDays           int    `json:"key_expiry_warning_days" default:"7" name:"config.key_expiry_warning_days" category:"config.category.basic" desc:"config.key_expiry_warning_days_desc" validate:"min=0"`
+	ModelRefreshIntervalHours      int    `json:"model_refresh_interval_hours" default:"0" name:"config.model_refresh_interval" category:"config.category.basic" desc:"config.model_refresh_interval_desc" validate:"min=0"`
+	ModelMetadataURL               string `json:"model_metadata_url" name:"config.model_metadata_url" category:"config.category.basic" desc:"config.model_metadata_url_desc" validate:"http_url"`
+	UpstreamProbeIntervalSeconds   int    `json:"upstream_probe_interval_seconds" default:"60" name:"config.upstream_probe_interval" category:"config.category.basic" desc:"config.upstream_probe_interval_desc" validate:"min=0"`
+	MetricsModelAllowlist          string `json:"metrics_model_allowlist" name:"config.metrics_model_allowlist" category:"config.category.basic" desc:"config.metrics_model_allowlist_desc"`
 
 	// 请求设置
-	RequestTimeout        int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
-	ConnectTimeout        int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
-	IdleConnTimeout       int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
-	ResponseHeaderTimeout int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
-	MaxIdleConns          int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
-	MaxIdleConnsPerHost   int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
-	ProxyURL              string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	RequestTimeout               int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
+	ConnectTimeout               int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
+	IdleConnTimeout              int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
+	ResponseHeaderTimeout        int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
+	StreamIdleTimeout            int    `json:"stream_idle_timeout" default:"300" name:"config.stream_idle_timeout" category:"config.category.request" desc:"config.stream_idle_timeout_desc" validate:"min=0"`
+	MaxIdleConns                 int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
+	MaxIdleConnsPerHost          int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
+	TLSHandshakeTimeout          int    `json:"tls_handshake_timeout" default:"15" name:"config.tls_handshake_timeout" category:"config.category.request" desc:"config.tls_handshake_timeout_desc" validate:"required,min=1"`
+	DisableHTTP2                 bool   `json:"disable_http2" default:"false" name:"config.disable_http2" category:"config.category.request" desc:"config.disable_http2_desc"`
+	DNSResolver                  string `json:"dns_resolver" name:"config.dns_resolver" category:"config.category.request" desc:"config.dns_resolver_desc" validate:"dns_resolver"`
+	ProxyURL                     string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc" validate:"proxy_url"`
+	EgressIPFamily               string `json:"egress_ip_family" default:"auto" name:"config.egress_ip_family" category:"config.category.request" desc:"config.egress_ip_family_desc" validate:"required,oneof=auto ipv4 ipv6 prefer_ipv4 prefer_ipv6"`
+	EgressSourceAddress          string `json:"egress_source_address" name:"config.egress_source_address" category:"config.category.request" desc:"config.egress_source_address_desc" validate:"ip_address"`
+	ContentFilterPolicy          string `json:"content_filter_policy" default:"passthrough" name:"config.content_filter_policy" category:"config.category.request" desc:"config.content_filter_policy_desc" validate:"required,oneof=passthrough normalize retry"`
+	GeminiSafetySettings         string `json:"gemini_safety_settings" name:"config.gemini_safety_settings" category:"config.category.request" desc:"config.gemini_safety_settings_desc" validate:"gemini_safety"`
+	TrafficSchedule              string `json:"traffic_schedule" name:"config.traffic_schedule" category:"config.category.request" desc:"config.traffic_schedule_desc" validate:"traffic_schedule"`
+	TrafficScheduleFallbackGroup string `json:"traffic_schedule_fallback_group" name:"config.traffic_schedule_fallback_group" category:"config.category.request" desc:"config.traffic_schedule_fallback_group_desc"`
+	CanaryGroup                  string `json:"canary_group" name:"config.canary_group" category:"config.category.request" desc:"config.canary_group_desc"`
+	CanaryPercent                int    `json:"canary_percent" default:"0" name:"config.canary_percent" category:"config.category.request" desc:"config.canary_percent_desc" validate:"min=0,max=100"`
+	CanaryStickyHeader           string `json:"canary_sticky_header" name:"config.canary_sticky_header" category:"config.category.request" desc:"config.canary_sticky_header_desc"`
+	SubGroupRouting              string `json:"sub_group_routing" default:"weighted" name:"config.sub_group_routing" category:"config.category.request" desc:"config.sub_group_routing_desc" validate:"required,oneof=weighted bandit"`
+	BanditExplorationRate        int    `json:"bandit_exploration_rate" default:"10" name:"config.bandit_exploration_rate" category:"config.category.request" desc:"config.bandit_exploration_rate_desc" validate:"min=0"`
+	UpstreamSelection            string `json:"upstream_selection" default:"weighted" name:"config.upstream_selection" category:"config.category.request" desc:"config.upstream_selection_desc" validate:"required,oneof=weighted latency"`
+	ReasoningContentMode         string `json:"reasoning_content_mode" default:"passthrough" name:"config.reasoning_content_mode" category:"config.category.request" desc:"config.reasoning_content_mode_desc" validate:"required,oneof=passthrough strip inline"`
+	StreamUsageOptions           string `json:"stream_usage_options" default:"auto" name:"config.stream_usage_options" category:"config.category.request" desc:"config.stream_usage_options_desc" validate:"required,oneof=auto off strip"`
+	TranslateLegacyCompletions   bool   `json:"translate_legacy_completions" default:"false" name:"config.translate_legacy_completions" category:"config.category.request" desc:"config.translate_legacy_completions_desc"`
+	OpenRouterReferer            string `json:"openrouter_referer" name:"config.openrouter_referer" category:"config.category.request" desc:"config.openrouter_referer_desc"`
+	OpenRouterTitle              string `json:"openrouter_title" name:"config.openrouter_title" category:"config.category.request" desc:"config.openrouter_title_desc"`
+	ResponseMetadataEnabled      bool   `json:"response_metadata_enabled" default:"false" name:"config.response_metadata_enabled" category:"config.category.request" desc:"config.response_metadata_enabled_desc"`
+	ResponseMetadataKey          string `json:"response_metadata_key" default:"_gateway" name:"config.response_metadata_key" category:"config.category.request" desc:"config.response_metadata_key_desc" validate:"required,json_key"`
+	MaxRequestBodyKB             int    `json:"max_request_body_kb" default:"0" name:"config.max_request_body_kb" category:"config.category.request" desc:"config.max_request_body_kb_desc" validate:"min=0"`
+	ResponseCompressionMinKB     int    `json:"response_compression_min_kb" default:"8" name:"config.response_compression_min_kb" category:"config.category.request" desc:"config.response_compression_min_kb_desc" validate:"min=0"`
+	ResponseBufferMaxKB          int    `json:"response_buffer_max_kb" default:"8192" name:"config.response_buffer_max_kb" category:"config.category.request" desc:"config.response_buffer_max_kb_desc" validate:"required,min=64"`
+	DiagnosticsHeaders           bool   `json:"diagnostics_headers" default:"false" name:"config.diagnostics_headers" category:"config.category.request" desc:"config.diagnostics_headers_desc"`
+	MaxConcurrency               int    `json:"max_concurrency" default:"0" name:"config.max_concurrency" category:"config.category.request" desc:"config.max_concurrency_desc" validate:"min=0"`
+	MaxQueueDepth                int    `json:"max_queue_depth" default:"100" name:"config.max_queue_depth" category:"config.category.request" desc:"config.max_queue_depth_desc" validate:"min=0"`
+	QueueTimeoutSeconds          int    `json:"queue_timeout_seconds" default:"30" name:"config.queue_timeout" category:"config.category.request" desc:"config.queue_timeout_desc" validate:"required,min=1"`
+	DuplicateRequestProtection   string `json:"duplicate_request_protection" default:"off" name:"config.duplicate_request_protection" category:"config.category.request" desc:"config.duplicate_request_protection_desc" validate:"required,oneof=off collapse throttle"`
+	DuplicateRequestWindow       int    `json:"duplicate_request_window_seconds" default:"10" name:"config.duplicate_request_window" category:"config.category.request" desc:"config.duplicate_request_window_desc" validate:"required,min=1"`
+	DuplicateRequestLimit        int    `json:"duplicate_request_limit" default:"3" name:"config.duplicate_request_limit" category:"config.category.request" desc:"config.duplicate_request_limit_desc" validate:"required,min=1"`
+	StickySessionMode            string `json:"sticky_session_mode" default:"off" name:"config.sticky_session_mode" category:"config.category.request" desc:"config.sticky_session_mode_desc" validate:"required,oneof=off header first_message"`
+	StickySessionHeader          string `json:"sticky_session_header" default:"X-Session-Id" name:"config.sticky_session_header" category:"config.category.request" desc:"config.sticky_session_header_desc" validate:"required"`
+	StickySessionTTLSeconds      int    `json:"sticky_session_ttl_seconds" default:"3600" name:"config.sticky_session_ttl" category:"config.category.request" desc:"config.sticky_session_ttl_desc" validate:"required,min=1"`
+	ContextWindowPolicy          string `json:"context_window_policy" default:"off" name:"config.context_window_policy" category:"config.category.request" desc:"config.context_window_policy_desc" validate:"required,oneof=off reject truncate"`
+	AutoMaxTokens                bool   `json:"auto_max_tokens" default:"false" name:"config.auto_max_tokens" category:"config.category.request" desc:"config.auto_max_tokens_desc"`
+	PIIRedaction                 string `json:"pii_redaction" default:"off" name:"config.pii_redaction" category:"config.category.request" desc:"config.pii_redaction_desc" validate:"required,oneof=off mask reject"`
+	PIIRedactionTypes            string `json:"pii_redaction_types" default:"email,phone,credit_card" name:"config.pii_redaction_types" category:"config.category.request" desc:"config.pii_redaction_types_desc" validate:"pii_types"`
+	PIIRedactionPatterns         string `json:"pii_redaction_patterns" name:"config.pii_redaction_patterns" category:"config.category.request" desc:"config.pii_redaction_patterns_desc" validate:"pii_patterns"`
+	Moderation                   string `json:"moderation" default:"off" name:"config.moderation" category:"config.category.request" desc:"config.moderation_desc" validate:"required,oneof=off flag block"`
+	ModerationEndpoint           string `json:"moderation_endpoint" default:"https://api.openai.com/v1/moderations" name:"config.moderation_endpoint" category:"config.category.request" desc:"config.moderation_endpoint_desc" validate:"required,http_url"`
+	ModerationAPIKey             string `json:"moderation_api_key" name:"config.moderation_api_key" category:"config.category.request" desc:"config.moderation_api_key_desc"`
+	ModerationModel              string `json:"moderation_model" default:"omni-moderation-latest" name:"config.moderation_model" category:"config.category.request" desc:"config.moderation_model_desc"`
 
 	// 密钥配置
-	MaxRetries                   int `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
-	BlacklistThreshold           int `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
-	KeyValidationIntervalMinutes int `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
-	KeyValidationConcurrency     int `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
-	KeyValidationTimeoutSeconds  int `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
+	MaxRetries                   int    `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
+	BlacklistThreshold           int    `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
+	KeyValidationIntervalMinutes int    `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
+	KeyValidationConcurrency     int    `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
+	KeyValidationTimeoutSeconds  int    `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
+	KeyTopUpThreshold            int    `json:"key_topup_threshold" default:"0" name:"config.key_topup_threshold" category:"config.category.key" desc:"config.key_topup_threshold_desc" validate:"min=0"`
+	KeyTopUpWebhookURL           string `json:"key_topup_webhook_url" name:"config.key_topup_webhook_url" category:"config.category.key" desc:"config.key_topup_webhook_url_desc" validate:"http_url"`
+	KeyTopUpScript               string `json:"key_topup_script" name:"config.key_topup_script" category:"config.category.key" desc:"config.key_topup_script_desc" validate:"file_name"`
+	KeyTopUpCooldownMinutes      int    `json:"key_topup_cooldown_minutes" default:"30" name:"config.key_topup_cooldown" category:"config.category.key" desc:"config.key_topup_cooldown_desc" validate:"required,min=1"`
+	QuotaCycle                   string `json:"quota_cycle" default:"off" name:"config.quota_cycle" category:"config.category.key" desc:"config.quota_cycle_desc" validate:"required,oneof=off daily weekly monthly"`
+	QuotaResetTime               string `json:"quota_reset_time" default:"00:00" name:"config.quota_reset_time" category:"config.category.key" desc:"config.quota_reset_time_desc" validate:"required,clock_time"`
+	QuotaResetDay                int    `json:"quota_reset_day" default:"1" name:"config.quota_reset_day" category:"config.category.key" desc:"config.quota_reset_day_desc" validate:"required,min=1,max=28"`
+	QuotaScope                   string `json:"quota_scope" default:"key" name:"config.quota_scope" category:"config.category.key" desc:"config.quota_scope_desc" validate:"required,oneof=key group"`
+	QuotaUnit                    string `json:"quota_unit" default:"requests" name:"config.quota_unit" category:"config.category.key" desc:"config.quota_unit_desc" validate:"required,oneof=requests tokens"`
+	QuotaLimit                   int    `json:"quota_limit" default:"0" name:"config.quota_limit" category:"config.category.key" desc:"config.quota_limit_desc" validate:"min=0"`
+	QuotaPacing                  bool   `json:"quota_pacing" default:"false" name:"config.quota_pacing" category:"config.category.key" desc:"config.quota_pacing_desc"`
+
+	// 响应缓存
+	EnableResponseCache     bool `json:"enable_response_cache" default:"false" name:"config.enable_response_cache" category:"config.category.cache" desc:"config.enable_response_cache_desc"`
+	ResponseCacheTTLSeconds int  `json:"response_cache_ttl_seconds" default:"300" name:"config.response_cache_ttl" category:"config.category.cache" desc:"config.response_cache_ttl_desc" validate:"required,min=1"`
+
+	// 认证
+	OIDCEnabled              bool   `json:"oidc_enabled" default:"false" name:"config.oidc_enabled" category:"config.category.auth" desc:"config.oidc_enabled_desc"`
+	OIDCIssuerURL            string `json:"oidc_issuer_url" name:"config.oidc_issuer_url" category:"config.category.auth" desc:"config.oidc_issuer_url_desc" validate:"http_url"`
+	OIDCClientID             string `json:"oidc_client_id" name:"config.oidc_client_id" category:"config.category.auth" desc:"config.oidc_client_id_desc"`
+	OIDCClientSecret         string `json:"oidc_client_secret" name:"config.oidc_client_secret" category:"config.category.auth" desc:"config.oidc_client_secret_desc"`
+	OIDCScopes               string `json:"oidc_scopes" default:"openid profile email" name:"config.oidc_scopes" category:"config.category.auth" desc:"config.oidc_scopes_desc" validate:"required"`
+	OIDCGroupsClaim          string `json:"oidc_groups_claim" default:"groups" name:"config.oidc_groups_claim" category:"config.category.auth" desc:"config.oidc_groups_claim_desc" validate:"required"`
+	OIDCRoleMapping          string `json:"oidc_role_mapping" name:"config.oidc_role_mapping" category:"config.category.auth" desc:"config.oidc_role_mapping_desc" validate:"oidc_role_mapping"`
+	OIDCDefaultRole          string `json:"oidc_default_role" name:"config.oidc_default_role" category:"config.category.auth" desc:"config.oidc_default_role_desc" validate:"oneof=viewer operator admin"`
+	OIDCAllowedEmailDomains  string `json:"oidc_allowed_email_domains" name:"config.oidc_allowed_email_domains" category:"config.category.auth" desc:"config.oidc_allowed_email_domains_desc"`
+	Require2FAForDestructive bool   `json:"require_2fa_for_destructive" default:"false" name:"config.require_2fa_for_destructive" category:"config.category.auth" desc:"config.require_2fa_for_destructive_desc"`
 
 	// For cache
-	ProxyKeysMap map[string]struct{} `json:"-"`
+	ProxyKeysMap        map[string]struct{}          `json:"-"`
+	ProxyKeyMetadataMap map[string]map[string]string `json:"-"`
 }
 
 // ServerConfig represents server configuration
@@ -54,6 +144,14 @@ type ServerConfig struct {
 	WriteTimeout            int    `json:"write_timeout"`
 	IdleTimeout             int    `json:"idle_timeout"`
 	GracefulShutdownTimeout int    `json:"graceful_shutdown_timeout"`
+	// DrainTimeout is how long in-flight proxy requests may finish on shutdown; 0 leaves them
+	// the graceful shutdown timeout minus the time reserved for background services.
+	DrainTimeout int `json:"drain_timeout"`
+	// EnablePprof serves the net/http/pprof profiles on /debug/pprof to admins.
+	EnablePprof bool `json:"enable_pprof"`
+	// TrustedProxies lists the reverse proxies (IPs or CIDR ranges) whose X-Forwarded-For and
+	// X-Real-IP headers are used as the client address. Without any, the socket peer is the client.
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 // AuthConfig represents authentication configuration
@@ -83,11 +181,42 @@ type LogConfig struct {
 	FilePath   string `json:"file_path"`
 }
 
+// AccessLogConfig represents the access log configuration, kept apart from application logs
+type AccessLogConfig struct {
+	Sinks          []string `json:"sinks"`
+	Fields         []string `json:"fields"`
+	FilePath       string   `json:"file_path"`
+	FileMaxSizeMB  int      `json:"file_max_size_mb"`
+	FileMaxBackups int      `json:"file_max_backups"`
+	SyslogNetwork  string   `json:"syslog_network"`
+	SyslogAddress  string   `json:"syslog_address"`
+	SyslogTag      string   `json:"syslog_tag"`
+}
+
 // DatabaseConfig represents database configuration
 type DatabaseConfig struct {
 	DSN string `json:"dsn"`
 }
 
+// EncryptionConfig represents the versioned encryption keys
+type EncryptionConfig struct {
+	Key          string
+	KeyVersion   int
+	PreviousKeys map[int]string
+	// KMSProvider is the KMS that unwrapped Key, empty when Key is the static ENCRYPTION_KEY
+	KMSProvider string
+}
+
+// KMSConfig selects the KMS that wraps the data encryption key
+type KMSConfig struct {
+	// Provider is aws, gcp or vault
+	Provider string
+	// KeyID is the AWS key ID, ARN or alias, the GCP CryptoKey resource name or the Vault Transit key name
+	KeyID string
+	// WrappedKey is the data encryption key as encrypted by the KMS
+	WrappedKey string
+}
+
 type RetryError struct {
 	StatusCode         int    `json:"status_code"`
 	ErrorMessage       string `json:"error_message"`
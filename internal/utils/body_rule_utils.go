@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"gpt-load/internal/models"
+	"strings"
+)
+
+// ApplyBodyRules applies body rules to a decoded JSON request body and reports whether it changed.
+// String values may use the same variables as header rules.
+func ApplyBodyRules(body map[string]any, rules []models.BodyRule, ctx *HeaderVariableContext) bool {
+	changed := false
+	for _, rule := range rules {
+		segments := strings.Split(rule.Path, ".")
+		parent, key := bodyRuleParent(body, segments, rule.Action == "set" || rule.Action == "default")
+		if parent == nil {
+			continue
+		}
+		current, exists := parent[key]
+
+		switch rule.Action {
+		case "set":
+			parent[key] = resolveBodyRuleValue(rule.Value, ctx)
+			changed = true
+		case "default":
+			if !exists || current == nil {
+				parent[key] = resolveBodyRuleValue(rule.Value, ctx)
+				changed = true
+			}
+		case "remove":
+			if exists {
+				delete(parent, key)
+				changed = true
+			}
+		case "max":
+			value, isNumber := current.(float64)
+			limit, hasLimit := rule.Value.(float64)
+			if isNumber && hasLimit && value > limit {
+				parent[key] = limit
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// bodyRuleParent walks to the object holding the last path segment, creating missing objects
+// when create is set. It returns nil when the path runs into a non-object value.
+func bodyRuleParent(body map[string]any, segments []string, create bool) (map[string]any, string) {
+	current := body
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := current[segment].(map[string]any)
+		if !ok {
+			if _, exists := current[segment]; exists || !create {
+				return nil, ""
+			}
+			next = make(map[string]any)
+			current[segment] = next
+		}
+		current = next
+	}
+	return current, segments[len(segments)-1]
+}
+
+func resolveBodyRuleValue(value any, ctx *HeaderVariableContext) any {
+	if s, ok := value.(string); ok {
+		return ResolveHeaderVariables(s, ctx)
+	}
+	return value
+}
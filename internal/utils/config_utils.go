@@ -179,7 +179,7 @@ func GetValidationEndpoint(group *models.Group) string {
 
 	// Return default validation endpoint based on channel type
 	switch group.ChannelType {
-	case "openai":
+	case "openai", "ollama", "mistral", "deepseek", "openrouter", "openai-compatible", "dashscope", "zhipu", "moonshot":
 		return "/v1/chat/completions"
 	case "anthropic":
 		return "/v1/messages"
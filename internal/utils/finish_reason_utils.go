@@ -0,0 +1,52 @@
+package utils
+
+import "strings"
+
+// Normalized finish reasons shared by all providers.
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonContentFilter = "content_filter"
+	FinishReasonOther         = "other"
+)
+
+// finishReasonAliases maps provider-specific stop reasons to the normalized set.
+var finishReasonAliases = map[string]string{
+	// OpenAI
+	"stop":           FinishReasonStop,
+	"length":         FinishReasonLength,
+	"tool_calls":     FinishReasonToolCalls,
+	"function_call":  FinishReasonToolCalls,
+	"content_filter": FinishReasonContentFilter,
+
+	// Anthropic
+	"end_turn":      FinishReasonStop,
+	"stop_sequence": FinishReasonStop,
+	"pause_turn":    FinishReasonStop,
+	"max_tokens":    FinishReasonLength,
+	"tool_use":      FinishReasonToolCalls,
+	"refusal":       FinishReasonContentFilter,
+
+	// Gemini (matched case-insensitively)
+	"finish_reason_unspecified": "",
+	"safety":                    FinishReasonContentFilter,
+	"recitation":                FinishReasonContentFilter,
+	"blocklist":                 FinishReasonContentFilter,
+	"prohibited_content":        FinishReasonContentFilter,
+	"spii":                      FinishReasonContentFilter,
+	"image_safety":              FinishReasonContentFilter,
+}
+
+// NormalizeFinishReason converts an OpenAI, Anthropic or Gemini finish reason into one of
+// stop, length, tool_calls, content_filter or other. An empty input yields an empty result.
+func NormalizeFinishReason(reason string) string {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ""
+	}
+	if normalized, ok := finishReasonAliases[strings.ToLower(reason)]; ok {
+		return normalized
+	}
+	return FinishReasonOther
+}
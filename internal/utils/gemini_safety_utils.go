@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// geminiHarmCategories maps short category aliases to Gemini harm categories.
+var geminiHarmCategories = map[string]string{
+	"harassment":        "HARM_CATEGORY_HARASSMENT",
+	"hate_speech":       "HARM_CATEGORY_HATE_SPEECH",
+	"sexually_explicit": "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"dangerous_content": "HARM_CATEGORY_DANGEROUS_CONTENT",
+	"civic_integrity":   "HARM_CATEGORY_CIVIC_INTEGRITY",
+}
+
+var geminiHarmThresholds = map[string]bool{
+	"HARM_BLOCK_THRESHOLD_UNSPECIFIED": true,
+	"BLOCK_LOW_AND_ABOVE":              true,
+	"BLOCK_MEDIUM_AND_ABOVE":           true,
+	"BLOCK_ONLY_HIGH":                  true,
+	"BLOCK_NONE":                       true,
+	"OFF":                              true,
+}
+
+// GeminiSafetySetting is a single entry of a Gemini safetySettings array.
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// ParseGeminiSafetySettings parses a comma-separated "category:threshold" list.
+// Categories may be given in full (HARM_CATEGORY_HARASSMENT) or short (harassment) form,
+// and "*" applies the threshold to every category.
+func ParseGeminiSafetySettings(value string) ([]GeminiSafetySetting, error) {
+	thresholds := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		category, threshold, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid safety setting %q: expected category:threshold", item)
+		}
+		category = strings.TrimSpace(category)
+		threshold = strings.ToUpper(strings.TrimSpace(threshold))
+		if !geminiHarmThresholds[threshold] {
+			return nil, fmt.Errorf("invalid safety threshold %q", threshold)
+		}
+
+		if category == "*" {
+			for _, full := range geminiHarmCategories {
+				thresholds[full] = threshold
+			}
+			continue
+		}
+		full, err := normalizeHarmCategory(category)
+		if err != nil {
+			return nil, err
+		}
+		thresholds[full] = threshold
+	}
+
+	settings := make([]GeminiSafetySetting, 0, len(thresholds))
+	for category, threshold := range thresholds {
+		settings = append(settings, GeminiSafetySetting{Category: category, Threshold: threshold})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Category < settings[j].Category })
+	return settings, nil
+}
+
+func normalizeHarmCategory(category string) (string, error) {
+	if full, ok := geminiHarmCategories[strings.ToLower(category)]; ok {
+		return full, nil
+	}
+	upper := strings.ToUpper(category)
+	for _, full := range geminiHarmCategories {
+		if upper == full {
+			return full, nil
+		}
+	}
+	return "", fmt.Errorf("unknown harm category %q", category)
+}
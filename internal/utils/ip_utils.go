@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ipNetworkCache sync.Map
+
+// ParseIPNetwork parses an IP address or CIDR range. A single address becomes a /32 or /128 range.
+func ParseIPNetwork(entry string) (*net.IPNet, error) {
+	entry = strings.TrimSpace(entry)
+	if !strings.Contains(entry, "/") {
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR range %q", entry)
+		}
+		if ip.To4() != nil {
+			return &net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, network, err := net.ParseCIDR(entry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP address or CIDR range %q", entry)
+	}
+	return network, nil
+}
+
+// IPInList reports whether ip is in one of the addresses and ranges of entries. Invalid entries
+// never match.
+func IPInList(ip net.IP, entries []string) bool {
+	for _, entry := range entries {
+		var network *net.IPNet
+		if cached, ok := ipNetworkCache.Load(entry); ok {
+			network = cached.(*net.IPNet)
+		} else {
+			parsed, err := ParseIPNetwork(entry)
+			if err != nil {
+				continue
+			}
+			ipNetworkCache.Store(entry, parsed)
+			network = parsed
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPAllowed applies a denylist and then, when it is not empty, an allowlist to ip.
+func IPAllowed(ip net.IP, allowed, denied []string) bool {
+	if IPInList(ip, denied) {
+		return false
+	}
+	return len(allowed) == 0 || IPInList(ip, allowed)
+}
+
+// ParseDNSResolver parses a DNS server given as an IP address or IP:port and returns it as
+// host:port, defaulting to port 53.
+func ParseDNSResolver(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if ip := net.ParseIP(strings.Trim(value, "[]")); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("must be an IP address or IP:port such as 1.1.1.1 or [2606:4700::1111]:53")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxMetadataFields bounds the metadata attached to a single proxy key.
+	maxMetadataFields = 16
+	// maxMetadataValueLength bounds a single metadata value.
+	maxMetadataValueLength = 128
+)
+
+// metadataFieldPattern restricts metadata field names to identifiers usable as CSV columns and filters.
+var metadataFieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,63}$`)
+
+// ParseProxyKeyMetadata parses a JSON object that maps proxy keys to flat string metadata, e.g.
+// {"sk-team-a": {"team": "platform", "cost_center": "CC-42"}}.
+func ParseProxyKeyMetadata(value string) (map[string]map[string]string, error) {
+	var parsed map[string]map[string]string
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("must be a JSON object mapping proxy keys to objects of string values: %w", err)
+	}
+
+	result := make(map[string]map[string]string, len(parsed))
+	for proxyKey, fields := range parsed {
+		proxyKey = strings.TrimSpace(proxyKey)
+		if proxyKey == "" {
+			return nil, fmt.Errorf("proxy key must not be empty")
+		}
+		if len(fields) > maxMetadataFields {
+			return nil, fmt.Errorf("proxy key %s has more than %d metadata fields", MaskAPIKey(proxyKey), maxMetadataFields)
+		}
+		for name, fieldValue := range fields {
+			if !metadataFieldPattern.MatchString(name) {
+				return nil, fmt.Errorf("invalid metadata field %q: must start with a letter or underscore and contain only letters, digits, '_', '-' or '.'", name)
+			}
+			if len(fieldValue) > maxMetadataValueLength {
+				return nil, fmt.Errorf("metadata field %q exceeds %d characters", name, maxMetadataValueLength)
+			}
+		}
+		if len(fields) > 0 {
+			result[proxyKey] = fields
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package utils
+
+import "strings"
+
+// IsModelPattern reports whether pattern contains glob wildcards.
+func IsModelPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
+
+// MatchModelPattern matches a model name against an exact name or a glob pattern, ignoring case.
+// "*" matches any run of characters (including "/", so "meta-llama/*" works) and "?" a single one.
+func MatchModelPattern(pattern, model string) bool {
+	pattern = strings.ToLower(pattern)
+	model = strings.ToLower(model)
+	if !IsModelPattern(pattern) {
+		return pattern == model
+	}
+
+	p, m := 0, 0
+	starP, starM := -1, 0
+	for m < len(model) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == model[m]):
+			p++
+			m++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starM = p, m
+			p++
+		case starP >= 0:
+			starM++
+			p, m = starP+1, starM
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
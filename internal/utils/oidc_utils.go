@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gpt-load/internal/models"
+)
+
+// ParseOIDCRoleMapping parses a JSON object that maps identity provider groups to gpt-load roles,
+// e.g. {"gpt-load-admins": "admin", "platform-team": "operator"}.
+func ParseOIDCRoleMapping(value string) (map[string]string, error) {
+	var parsed map[string]string
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, fmt.Errorf("must be a JSON object mapping groups to roles: %w", err)
+	}
+
+	result := make(map[string]string, len(parsed))
+	for group, role := range parsed {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			return nil, fmt.Errorf("group must not be empty")
+		}
+		switch role {
+		case models.RoleViewer, models.RoleOperator, models.RoleAdmin:
+		default:
+			return nil, fmt.Errorf("invalid role %q for group %q: must be viewer, operator or admin", role, group)
+		}
+		result[group] = role
+	}
+	return result, nil
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Built-in PII types for pii_redaction_types.
+const (
+	PIITypeEmail      = "email"
+	PIITypePhone      = "phone"
+	PIITypeCreditCard = "credit_card"
+)
+
+// piiRule finds one type of personal data. valid, when set, rejects matches that only look like it.
+type piiRule struct {
+	name    string
+	pattern *regexp.Regexp
+	mask    string
+	valid   func(text string, start, end int) bool
+}
+
+// Built-in rules, in the order they are applied: card numbers go before phone numbers so that
+// their digits are not taken for a phone number.
+var builtinPIIRules = []piiRule{
+	{
+		name:    PIITypeEmail,
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+		mask:    "[EMAIL]",
+	},
+	{
+		name:    PIITypeCreditCard,
+		pattern: regexp.MustCompile(`\d(?:[ -]?\d){12,18}`),
+		mask:    "[CREDIT_CARD]",
+		valid: func(text string, start, end int) bool {
+			return standsAlone(text, start, end) && luhnValid(text[start:end])
+		},
+	},
+	{
+		name:    PIITypePhone,
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){2,4}`),
+		mask:    "[PHONE]",
+		valid: func(text string, start, end int) bool {
+			digits := countDigits(text[start:end])
+			return standsAlone(text, start, end) && digits >= 9 && digits <= 15
+		},
+	},
+}
+
+// piiPromptFields are the request fields that carry prompt text in the supported API formats.
+var piiPromptFields = []string{"messages", "contents", "systemInstruction", "system_instruction", "system", "instructions", "input", "prompt", "query", "documents"}
+
+// piiSkipFields hold identifiers and binary data rather than text, and are left alone.
+var piiSkipFields = map[string]bool{
+	"role": true, "type": true, "id": true, "name": true, "tool_call_id": true, "tool_use_id": true, "call_id": true,
+	"image_url": true, "url": true, "source": true, "data": true, "inline_data": true, "inlineData": true,
+	"file_data": true, "fileData": true, "mime_type": true, "mimeType": true, "signature": true, "thoughtSignature": true,
+}
+
+// PIIDetector finds and masks personal data in prompt text.
+type PIIDetector struct {
+	rules []piiRule
+}
+
+var piiDetectorCache sync.Map
+
+// NewPIIDetector builds a detector for a comma-separated list of built-in types and a JSON object of
+// custom patterns, e.g. {"employee_id": "EMP-\\d{6}"}. Custom matches are masked as [EMPLOYEE_ID].
+func NewPIIDetector(types, patterns string) (*PIIDetector, error) {
+	cacheKey := types + "\x00" + patterns
+	if cached, ok := piiDetectorCache.Load(cacheKey); ok {
+		return cached.(*PIIDetector), nil
+	}
+
+	enabled, err := ParsePIITypes(types)
+	if err != nil {
+		return nil, err
+	}
+	custom, err := ParsePIIPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	detector := &PIIDetector{}
+	for _, rule := range builtinPIIRules {
+		if enabled[rule.name] {
+			detector.rules = append(detector.rules, rule)
+		}
+	}
+	detector.rules = append(detector.rules, custom...)
+
+	piiDetectorCache.Store(cacheKey, detector)
+	return detector, nil
+}
+
+// ParsePIITypes parses a comma-separated list of built-in PII types.
+func ParsePIITypes(value string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for _, name := range SplitAndTrim(value, ",") {
+		switch name {
+		case PIITypeEmail, PIITypePhone, PIITypeCreditCard:
+			enabled[name] = true
+		default:
+			return nil, fmt.Errorf("unknown PII type %q, expected %s, %s or %s", name, PIITypeEmail, PIITypePhone, PIITypeCreditCard)
+		}
+	}
+	return enabled, nil
+}
+
+// ParsePIIPatterns parses a JSON object of custom PII types and their regular expressions. The
+// expressions use Go's RE2 syntax, which runs in linear time.
+func ParsePIIPatterns(value string) ([]piiRule, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of names and regular expressions: %w", err)
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make([]piiRule, 0, len(raw))
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" || raw[name] == "" {
+			return nil, fmt.Errorf("custom PII patterns need a name and an expression")
+		}
+		pattern, err := regexp.Compile(raw[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for %q: %w", name, err)
+		}
+		rules = append(rules, piiRule{
+			name:    name,
+			pattern: pattern,
+			mask:    "[" + strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(name), " ", "_")) + "]",
+		})
+	}
+	return rules, nil
+}
+
+// Redact masks the personal data in text and adds the number of matches per type to counts.
+func (d *PIIDetector) Redact(text string, counts map[string]int) string {
+	for _, rule := range d.rules {
+		matches := rule.pattern.FindAllStringIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			if m[0] == m[1] || (rule.valid != nil && !rule.valid(text, m[0], m[1])) {
+				continue
+			}
+			b.WriteString(text[last:m[0]])
+			b.WriteString(rule.mask)
+			last = m[1]
+			counts[rule.name]++
+		}
+		if last > 0 {
+			b.WriteString(text[last:])
+			text = b.String()
+		}
+	}
+	return text
+}
+
+// RedactBody masks the personal data in the prompt fields of a decoded JSON request body in place
+// and returns the number of matches per type.
+func (d *PIIDetector) RedactBody(body map[string]any) map[string]int {
+	counts := make(map[string]int)
+	for _, field := range piiPromptFields {
+		if value, ok := body[field]; ok {
+			body[field] = d.redactValue(value, counts)
+		}
+	}
+	return counts
+}
+
+func (d *PIIDetector) redactValue(value any, counts map[string]int) any {
+	switch v := value.(type) {
+	case string:
+		return d.Redact(v, counts)
+	case []any:
+		for i := range v {
+			v[i] = d.redactValue(v[i], counts)
+		}
+	case map[string]any:
+		for key, item := range v {
+			if !piiSkipFields[key] {
+				v[key] = d.redactValue(item, counts)
+			}
+		}
+	}
+	return value
+}
+
+// standsAlone reports whether text[start:end] is not part of a longer word or number.
+func standsAlone(text string, start, end int) bool {
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		if r, _ := utf8.DecodeRuneInString(text[end:]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhnValid checks the Luhn checksum of the digits in s.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
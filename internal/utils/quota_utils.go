@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// Quota cycles, configured per group via quota_cycle.
+const (
+	QuotaCycleOff     = "off"
+	QuotaCycleDaily   = "daily"
+	QuotaCycleWeekly  = "weekly"
+	QuotaCycleMonthly = "monthly"
+)
+
+// ParseClockTime parses a "HH:MM" time of day.
+func ParseClockTime(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("must be a time of day in HH:MM format")
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// QuotaCycleBounds returns the start and end of the quota cycle containing now. Cycles reset at
+// resetTime (HH:MM, UTC) every day, every week on resetDay (1 = Monday ... 7 = Sunday) or every
+// month on day resetDay (1-28). ok is false when the cycle is off or misconfigured.
+func QuotaCycleBounds(cycle, resetTime string, resetDay int, now time.Time) (start, end time.Time, ok bool) {
+	hour, minute, err := ParseClockTime(resetTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	now = now.UTC()
+
+	switch cycle {
+	case QuotaCycleDaily:
+		start = time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+		if start.After(now) {
+			start = start.AddDate(0, 0, -1)
+		}
+		return start, start.AddDate(0, 0, 1), true
+	case QuotaCycleWeekly:
+		if resetDay < 1 || resetDay > 7 {
+			return time.Time{}, time.Time{}, false
+		}
+		target := time.Weekday(resetDay % 7)
+		start = time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+		start = start.AddDate(0, 0, -((int(now.Weekday()) - int(target) + 7) % 7))
+		if start.After(now) {
+			start = start.AddDate(0, 0, -7)
+		}
+		return start, start.AddDate(0, 0, 7), true
+	case QuotaCycleMonthly:
+		if resetDay < 1 || resetDay > 28 {
+			return time.Time{}, time.Time{}, false
+		}
+		start = time.Date(now.Year(), now.Month(), resetDay, hour, minute, 0, 0, time.UTC)
+		if start.After(now) {
+			start = start.AddDate(0, -1, 0)
+		}
+		return start, start.AddDate(0, 1, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TrafficSchedule is a union of cron-style windows. A point in time is inside the schedule
+// when it matches any of the windows.
+type TrafficSchedule struct {
+	windows []cronWindow
+}
+
+type cronWindow struct {
+	location *time.Location
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	domStar  bool
+	dowStar  bool
+}
+
+var trafficScheduleCache sync.Map
+
+// ParseTrafficSchedule parses semicolon-separated 5-field cron expressions
+// ("minute hour day-of-month month day-of-week"), each optionally prefixed with
+// CRON_TZ=<zone>. For example "* 0-6 * * *; * * * * 0,6" opens the window
+// from 00:00 to 06:59 every day and all day at weekends.
+func ParseTrafficSchedule(expr string) (*TrafficSchedule, error) {
+	if cached, ok := trafficScheduleCache.Load(expr); ok {
+		return cached.(*TrafficSchedule), nil
+	}
+
+	schedule := &TrafficSchedule{}
+	for _, part := range strings.Split(expr, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		window, err := parseCronWindow(part)
+		if err != nil {
+			return nil, err
+		}
+		schedule.windows = append(schedule.windows, window)
+	}
+	if len(schedule.windows) == 0 {
+		return nil, fmt.Errorf("schedule is empty")
+	}
+
+	trafficScheduleCache.Store(expr, schedule)
+	return schedule, nil
+}
+
+// Contains reports whether t falls inside one of the schedule's windows.
+func (s *TrafficSchedule) Contains(t time.Time) bool {
+	for _, w := range s.windows {
+		if w.matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCronWindow(expr string) (cronWindow, error) {
+	w := cronWindow{location: time.Local}
+
+	fields := strings.Fields(expr)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "CRON_TZ=") {
+		loc, err := time.LoadLocation(strings.TrimPrefix(fields[0], "CRON_TZ="))
+		if err != nil {
+			return w, fmt.Errorf("invalid time zone in %q: %w", expr, err)
+		}
+		w.location = loc
+		fields = fields[1:]
+	}
+	if len(fields) != 5 {
+		return w, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day month weekday)", expr)
+	}
+
+	var err error
+	if w.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return w, err
+	}
+	if w.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return w, err
+	}
+	if w.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return w, err
+	}
+	if w.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return w, err
+	}
+	if w.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return w, err
+	}
+	// Both 0 and 7 mean Sunday.
+	if w.weekdays[7] {
+		w.weekdays[0] = true
+	}
+	w.domStar = fields[2] == "*"
+	w.dowStar = fields[4] == "*"
+
+	return w, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps (e.g. "*/15", "1-5", "0,30").
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", field)
+			}
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			loStr, hiStr, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return nil, fmt.Errorf("invalid value in %q", field)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return nil, fmt.Errorf("invalid range in %q", field)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("value out of range [%d-%d] in %q", min, max, field)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (w cronWindow) matches(t time.Time) bool {
+	t = t.In(w.location)
+	if !w.minutes[t.Minute()] || !w.hours[t.Hour()] || !w.months[int(t.Month())] {
+		return false
+	}
+
+	dayMatch := w.days[t.Day()]
+	weekdayMatch := w.weekdays[int(t.Weekday())]
+	// Standard cron semantics: when both day fields are restricted, either may match.
+	if !w.domStar && !w.dowStar {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+const (
+	// scriptRuleMaxNodes bounds the size of a script rule expression.
+	scriptRuleMaxNodes = 1000
+	// ScriptRuleMaxSteps bounds how many predicate iterations, e.g. of `any(body.messages, ...)`, one
+	// evaluation may run. Together with the node limit it bounds the work of an expression.
+	ScriptRuleMaxSteps = 100000
+)
+
+// ErrScriptRuleTooComplex is returned when a script rule iterates more than ScriptRuleMaxSteps times.
+var ErrScriptRuleTooComplex = errors.New("script rule exceeded its step limit")
+
+// ScriptSteps counts the predicate iterations of one evaluation.
+type ScriptSteps struct {
+	count int
+}
+
+// ScriptEnv is what a script rule expression can inspect, e.g.
+// `model startsWith "gpt-4" && headers["x-team"] != "research"`.
+type ScriptEnv struct {
+	Model        string            `expr:"model"`
+	ProxyKey     string            `expr:"proxy_key"`
+	Headers      map[string]string `expr:"headers"` // lower-case names
+	Body         map[string]any    `expr:"body"`
+	Path         string            `expr:"path"`
+	Method       string            `expr:"method"`
+	ClientIP     string            `expr:"client_ip"`
+	Group        string            `expr:"group"`
+	PromptTokens int               `expr:"prompt_tokens"`
+	// Steps is set by EvalScriptRule for every evaluation.
+	Steps *ScriptSteps `expr:"__steps"`
+}
+
+var scriptRulePrograms sync.Map
+
+// scriptStepCounter makes every predicate body count a step before it runs.
+type scriptStepCounter struct{}
+
+func (scriptStepCounter) Visit(node *ast.Node) {
+	if predicate, ok := (*node).(*ast.PredicateNode); ok {
+		predicate.Node = &ast.SequenceNode{Nodes: []ast.Node{
+			&ast.CallNode{
+				Callee:    &ast.IdentifierNode{Value: "__step"},
+				Arguments: []ast.Node{&ast.IdentifierNode{Value: "__steps"}},
+			},
+			predicate.Node,
+		}}
+	}
+}
+
+// scriptStep counts a predicate iteration and stops the expression at the step limit.
+func scriptStep(params ...any) (any, error) {
+	steps, _ := params[0].(*ScriptSteps)
+	if steps == nil {
+		return true, nil
+	}
+	steps.count++
+	if steps.count > ScriptRuleMaxSteps {
+		return nil, ErrScriptRuleTooComplex
+	}
+	return true, nil
+}
+
+// CompileScriptRule compiles a script rule expression, which must evaluate to a boolean. Compiled
+// programs are cached by expression.
+func CompileScriptRule(expression string) (*vm.Program, error) {
+	if program, ok := scriptRulePrograms.Load(expression); ok {
+		return program.(*vm.Program), nil
+	}
+	program, err := expr.Compile(expression,
+		expr.Env(ScriptEnv{}),
+		expr.AsBool(),
+		expr.MaxNodes(scriptRuleMaxNodes),
+		expr.Function("__step", scriptStep, new(func(*ScriptSteps) bool)),
+		expr.Patch(scriptStepCounter{}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	scriptRulePrograms.Store(expression, program)
+	return program, nil
+}
+
+// EvalScriptRule runs a script rule expression against env. Expressions cannot call out of the
+// sandbox, are limited in memory by expr, and fail with ErrScriptRuleTooComplex once their loops
+// over lists run more than ScriptRuleMaxSteps iterations.
+func EvalScriptRule(expression string, env *ScriptEnv) (bool, error) {
+	program, err := CompileScriptRule(expression)
+	if err != nil {
+		return false, err
+	}
+
+	env.Steps = &ScriptSteps{}
+	out, err := expr.Run(program, env)
+	if errors.Is(err, ErrScriptRuleTooComplex) {
+		return false, ErrScriptRuleTooComplex
+	}
+	if err != nil {
+		return false, err
+	}
+	matched, _ := out.(bool)
+	return matched, nil
+}
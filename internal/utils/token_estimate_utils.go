@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// perMessageTokenOverhead approximates the role and separator tokens added around each chat message.
+const perMessageTokenOverhead = 4
+
+// promptContentKeys are the request fields that carry prompt text in the OpenAI, Anthropic,
+// Gemini and Ollama formats.
+var promptContentKeys = []string{
+	"messages", "system", "prompt", "input", "contents",
+	"systemInstruction", "system_instruction", "tools", "functions",
+}
+
+// nonTextKeys hold metadata or binary payloads that are not counted as prompt text.
+var nonTextKeys = map[string]bool{
+	"role": true, "type": true, "url": true, "data": true, "detail": true,
+	"mime_type": true, "mimeType": true, "media_type": true, "image_url": true, "images": true,
+	"inline_data": true, "inlineData": true, "source": true, "cache_control": true,
+}
+
+// EstimateTokens approximates the token count of text without a model-specific tokenizer:
+// about four characters per token for Latin scripts and one token per CJK character.
+func EstimateTokens(text string) int {
+	latin, wide := 0, 0
+	for _, r := range text {
+		if r > unicode.MaxLatin1 && (unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)) {
+			wide++
+		} else {
+			latin++
+		}
+	}
+	return wide + (latin+3)/4
+}
+
+// EstimatePromptTokens approximates the prompt tokens of a decoded JSON request body.
+func EstimatePromptTokens(body map[string]any) int {
+	total := 0
+	for _, key := range promptContentKeys {
+		value, ok := body[key]
+		if !ok {
+			continue
+		}
+		total += estimateValueTokens(value)
+		if list, ok := value.([]any); ok && (key == "messages" || key == "contents") {
+			total += len(list) * perMessageTokenOverhead
+		}
+	}
+	return total
+}
+
+func estimateValueTokens(value any) int {
+	switch v := value.(type) {
+	case string:
+		return EstimateTokens(v)
+	case []any:
+		total := 0
+		for _, item := range v {
+			total += estimateValueTokens(item)
+		}
+		return total
+	case map[string]any:
+		total := 0
+		for key, item := range v {
+			if nonTextKeys[key] {
+				continue
+			}
+			total += estimateValueTokens(item)
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// PromptText joins the prompt text of a decoded JSON request body, one part per line. Tool and
+// function definitions are left out.
+func PromptText(body map[string]any) string {
+	var parts []string
+	for _, key := range promptContentKeys {
+		if key == "tools" || key == "functions" {
+			continue
+		}
+		if value, ok := body[key]; ok {
+			parts = collectText(value, parts)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func collectText(value any, parts []string) []string {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			parts = append(parts, v)
+		}
+	case []any:
+		for _, item := range v {
+			parts = collectText(item, parts)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if !nonTextKeys[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			parts = collectText(v[key], parts)
+		}
+	}
+	return parts
+}
+
+// EstimateMessageTokens approximates the tokens of one chat message, including its overhead.
+func EstimateMessageTokens(message any) int {
+	return estimateValueTokens(message) + perMessageTokenOverhead
+}
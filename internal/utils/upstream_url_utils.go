@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// versionSegmentPattern matches a trailing API version segment such as /v1, /v4 or /v1beta.
+var versionSegmentPattern = regexp.MustCompile(`/v\d+[a-z0-9]*$`)
+
+// APIVersionPrefix returns the version prefix that clients of a channel put in front of request
+// paths, e.g. /v1/chat/completions.
+func APIVersionPrefix(channelType string) string {
+	if channelType == "gemini" {
+		return "/v1beta"
+	}
+	return "/v1"
+}
+
+// NormalizeUpstreamURL cleans an upstream base URL for path joining. A trailing version segment
+// that clients also send (such as /v1 on an OpenAI-compatible upstream) is removed, so requests do
+// not end up at /v1/v1/.... Any other trailing version segment is kept, and versioned reports that
+// the client's own version prefix has to be dropped instead.
+func NormalizeUpstreamURL(rawURL, channelType string) (normalized string, versioned bool) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return strings.TrimSpace(rawURL), false
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+	prefixes := []string{APIVersionPrefix(channelType)}
+	if channelType == "gemini" {
+		prefixes = append(prefixes, "/v1")
+	}
+	for _, prefix := range prefixes {
+		if strings.HasSuffix(path, prefix) {
+			path = strings.TrimSuffix(path, prefix)
+			break
+		}
+	}
+	u.Path = path
+	u.RawPath = ""
+
+	return u.String(), versionSegmentPattern.MatchString(path)
+}
+
+// JoinUpstreamPath appends apiPath to an upstream base path. For a versioned base, the version
+// prefix of apiPath is dropped first, e.g. /v1/chat/completions becomes /chat/completions.
+func JoinUpstreamPath(basePath, apiPath, versionPrefix string, versioned bool) string {
+	if versioned && versionPrefix != "" &&
+		(apiPath == versionPrefix || strings.HasPrefix(apiPath, versionPrefix+"/")) {
+		apiPath = strings.TrimPrefix(apiPath, versionPrefix)
+	}
+	return strings.TrimRight(basePath, "/") + apiPath
+}
@@ -15,6 +15,7 @@ import (
 	"gpt-load/internal/container"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
+	_ "gpt-load/plugins"
 
 	"github.com/sirupsen/logrus"
 )
@@ -41,6 +42,18 @@ func runCommand() {
 	switch command {
 	case "migrate-keys":
 		commands.RunMigrateKeys(args)
+	case "db":
+		commands.RunDBMaintenance(args)
+	case "groups":
+		commands.RunGroups(args)
+	case "keys":
+		commands.RunKeys(args)
+	case "config":
+		commands.RunConfig(args)
+	case "logs":
+		commands.RunLogs(args)
+	case "encryption":
+		commands.RunEncryption(args)
 	case "help", "-h", "--help":
 		printHelp()
 	default:
@@ -60,6 +73,12 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("Available Commands:")
 	fmt.Println("  migrate-keys    Migrate encryption keys")
+	fmt.Println("  db              Check, repair, reindex or optimize the database")
+	fmt.Println("  groups          List or create groups on a running server")
+	fmt.Println("  keys            Import or validate the keys of a group on a running server")
+	fmt.Println("  config          Export or import a configuration backup of a running server")
+	fmt.Println("  logs            Follow the request logs of a running server")
+	fmt.Println("  encryption      Rotate stored keys to the current encryption key, or wrap it with a KMS")
 	fmt.Println("  help            Display this help message")
 	fmt.Println()
 	fmt.Println("Use 'gpt-load <command> --help' for more information about a command.")
@@ -99,9 +118,10 @@ func runServer() {
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 
-		// Create a context with timeout for shutdown
+		// Create a context with timeout for shutdown, extended by the time given to drain in-flight requests
 		serverConfig := configManager.GetEffectiveServerConfig()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(serverConfig.GracefulShutdownTimeout)*time.Second)
+		shutdownTimeout := time.Duration(serverConfig.GracefulShutdownTimeout+serverConfig.DrainTimeout) * time.Second
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 
 		// Perform graceful shutdown
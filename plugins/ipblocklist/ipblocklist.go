@@ -0,0 +1,50 @@
+// Package ipblocklist rejects proxy requests from the client IPs and ranges listed in
+// PLUGIN_IP_BLOCKLIST before their proxy key is checked.
+package ipblocklist
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/plugin"
+	"gpt-load/internal/utils"
+)
+
+func init() {
+	plugin.Register(&ipBlocklist{})
+}
+
+type ipBlocklist struct {
+	networks []*net.IPNet
+}
+
+func (p *ipBlocklist) Name() string {
+	return "ip_blocklist"
+}
+
+// Init parses PLUGIN_IP_BLOCKLIST, a comma-separated list of IP addresses and CIDR ranges.
+func (p *ipBlocklist) Init() error {
+	for _, entry := range utils.ParseArray(os.Getenv("PLUGIN_IP_BLOCKLIST"), nil) {
+		network, err := utils.ParseIPNetwork(entry)
+		if err != nil {
+			return fmt.Errorf("invalid PLUGIN_IP_BLOCKLIST entry %q", entry)
+		}
+		p.networks = append(p.networks, network)
+	}
+	return nil
+}
+
+func (p *ipBlocklist) PreAuth(ctx *plugin.Context) error {
+	ip := net.ParseIP(ctx.Gin.ClientIP())
+	if ip == nil {
+		return nil
+	}
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return app_errors.NewAPIError(app_errors.ErrForbidden, "Requests from this address are not allowed")
+		}
+	}
+	return nil
+}
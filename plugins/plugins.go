@@ -0,0 +1,10 @@
+// Package plugins compiles the in-tree proxy plugins into the binary.
+//
+// To add your own plugin, put its package in this directory, register it from an init function
+// with plugin.Register and import it below. It is enabled by listing its name in PLUGINS.
+// See docs/PLUGINS.md.
+package plugins
+
+import (
+	_ "gpt-load/plugins/ipblocklist"
+)
@@ -0,0 +1,29 @@
+import type { AlertRule, AlertRuleForm, AlertRulesResponse, ApiResponse } from "@/types/models";
+import http from "@/utils/http";
+
+export const alertsApi = {
+  // 获取告警规则
+  list: (): Promise<ApiResponse<AlertRulesResponse>> => {
+    return http.get("/alerts");
+  },
+
+  // 创建告警规则
+  create: (data: AlertRuleForm): Promise<ApiResponse<AlertRule>> => {
+    return http.post("/alerts", data);
+  },
+
+  // 更新告警规则
+  update: (id: number, data: AlertRuleForm): Promise<ApiResponse<AlertRule>> => {
+    return http.put(`/alerts/${id}`, data);
+  },
+
+  // 删除告警规则
+  delete: (id: number) => {
+    return http.delete(`/alerts/${id}`);
+  },
+
+  // 发送测试通知
+  test: (id: number) => {
+    return http.post(`/alerts/${id}/test`);
+  },
+};
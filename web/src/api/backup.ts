@@ -0,0 +1,23 @@
+import type { ApiResponse, ConfigBundle, ConfigImportResult } from "@/types/models";
+import http from "@/utils/http";
+
+export interface ConfigExportOptions {
+  include_settings: boolean;
+  include_keys: boolean;
+  passphrase: string;
+}
+
+export const backupApi = {
+  // 导出全部分组配置，可选包含系统设置和以密码加密的密钥
+  exportConfig: (options: ConfigExportOptions): Promise<ConfigBundle> => {
+    return http.post("/backup/export", options, { hideMessage: true });
+  },
+
+  // 导入备份，按分组名称更新已有分组或创建新分组
+  importConfig: (
+    bundle: ConfigBundle,
+    passphrase: string
+  ): Promise<ApiResponse<ConfigImportResult>> => {
+    return http.post("/backup/import", { bundle, passphrase });
+  },
+};
@@ -1,10 +1,13 @@
 import i18n from "@/locales";
+import { useAuthService } from "@/services/auth";
 import type {
   APIKey,
   Group,
   GroupConfigOption,
   GroupStatsResponse,
+  KeyHealthHistory,
   KeyStatus,
+  KeyTier,
   ParentAggregateGroup,
   TaskInfo,
 } from "@/types/models";
@@ -29,6 +32,16 @@ export const keysApi = {
     return res.data;
   },
 
+  // 增量更新分组，分组在 updatedAt 之后被他人修改时返回 409
+  async patchGroup(
+    groupId: number,
+    group: Partial<Omit<Group, "config">> & { config?: Record<string, unknown> },
+    updatedAt: string
+  ): Promise<Group> {
+    const res = await http.patch(`/groups/${groupId}`, { ...group, updated_at: updatedAt });
+    return res.data;
+  },
+
   // 删除分组
   deleteGroup(groupId: number): Promise<void> {
     return http.delete(`/groups/${groupId}`);
@@ -46,6 +59,15 @@ export const keysApi = {
     return res.data || [];
   },
 
+  // 生成到期后自动移除的沙盒代理密钥
+  async createSandboxKey(
+    groupId: number,
+    ttlHours: number
+  ): Promise<{ key: string; expires_at: string }> {
+    const res = await http.post(`/groups/${groupId}/sandbox-keys`, { ttl_hours: ttlHours });
+    return res.data;
+  },
+
   // 复制分组
   async copyGroup(
     groupId: number,
@@ -74,6 +96,7 @@ export const keysApi = {
     page_size: number;
     key_value?: string;
     status?: KeyStatus;
+    expires_within_days?: number;
   }): Promise<{
     items: APIKey[];
     pagination: {
@@ -121,6 +144,34 @@ export const keysApi = {
     await http.put(`/keys/${keyId}/notes`, { notes }, { hideMessage: true });
   },
 
+  // 设置或清除密钥到期时间
+  async updateKeyExpiry(
+    keyId: number,
+    expires_at: string | null
+  ): Promise<{ expires_at: string | null; status: KeyStatus }> {
+    const res = await http.put(`/keys/${keyId}/expiry`, { expires_at }, { hideMessage: true });
+    return res.data;
+  },
+
+  // 设置密钥的 RPM/TPM/每日消费上限，0 表示不限制
+  async updateKeyLimits(
+    keyId: number,
+    limits: { rpm_limit: number; tpm_limit: number; daily_spend_limit: number }
+  ): Promise<void> {
+    await http.put(`/keys/${keyId}/limits`, limits, { hideMessage: true });
+  },
+
+  // 获取密钥健康历史
+  async getKeyHistory(keyId: number, days: number): Promise<KeyHealthHistory> {
+    const res = await http.get(`/keys/${keyId}/history`, { params: { days } });
+    return res.data;
+  },
+
+  // 设置密钥层级
+  async updateKeyTier(keyId: number, tier: KeyTier): Promise<void> {
+    await http.put(`/keys/${keyId}/tier`, { tier }, { hideMessage: true });
+  },
+
   // 测试密钥
   async testKeys(
     group_id: number,
@@ -225,6 +276,20 @@ export const keysApi = {
       params.append("status", status);
     }
 
+    // 下载链接无法设置请求头，验证码通过查询参数传递
+    const { authUser } = useAuthService();
+    if (authUser.value?.two_factor_enforced && authUser.value.id) {
+      if (!authUser.value.totp_enabled) {
+        window.$message.error(i18n.global.t("users.twoFactorEnrollmentRequired"));
+        return;
+      }
+      const code = window.prompt(i18n.global.t("login.totpPrompt"))?.trim();
+      if (!code) {
+        return;
+      }
+      params.append("totp_code", code);
+    }
+
     const url = `${http.defaults.baseURL}/keys/export?${params.toString()}`;
 
     const link = document.createElement("a");
@@ -1,7 +1,16 @@
 import i18n from "@/locales";
-import type { ApiResponse, Group, LogFilter, LogsResponse } from "@/types/models";
+import type {
+  ApiResponse,
+  Group,
+  LogFilter,
+  LogStreamFilter,
+  LogsResponse,
+  RequestLog,
+} from "@/types/models";
 import http from "@/utils/http";
 
+type ExportFilter = Omit<LogFilter, "page" | "page_size">;
+
 export const logApi = {
   // 获取日志列表
   getLogs: (params: LogFilter): Promise<ApiResponse<LogsResponse>> => {
@@ -14,33 +23,90 @@ export const logApi = {
   },
 
   // 导出日志
-  exportLogs: (params: Omit<LogFilter, "page" | "page_size">) => {
+  exportLogs: (params: ExportFilter) => {
+    downloadCSV("/logs/export", params, `logs-${Date.now()}.csv`);
+  },
+
+  // Export token usage with proxy key metadata for chargeback
+  exportUsage: (params: ExportFilter) => {
+    downloadCSV("/logs/usage-export", params, `usage-${Date.now()}.csv`);
+  },
+
+  // Live tail: axios cannot read the response incrementally, so the events are read with fetch
+  streamLogs: async (
+    params: LogStreamFilter,
+    onLog: (log: RequestLog) => void,
+    signal: AbortSignal
+  ): Promise<void> => {
+    const headers: Record<string, string> = {
+      "Accept-Language": localStorage.getItem("locale") || "zh-CN",
+    };
     const authKey = localStorage.getItem("authKey");
-    if (!authKey) {
-      window.$message.error(i18n.global.t("auth.noAuthKeyFound"));
-      return;
+    if (authKey) {
+      headers.Authorization = `Bearer ${authKey}`;
+    }
+
+    const query = new URLSearchParams();
+    Object.entries(params).forEach(([key, value]) => {
+      if (value) {
+        query.append(key, String(value));
+      }
+    });
+    const response = await fetch(`${http.defaults.baseURL}/logs/stream?${query.toString()}`, {
+      headers,
+      signal,
+    });
+    if (!response.ok || !response.body) {
+      const body = await response.json().catch(() => null);
+      throw new Error(body?.message || `HTTP ${response.status}`);
     }
 
-    const queryParams = new URLSearchParams(
-      Object.entries(params).reduce(
-        (acc, [key, value]) => {
-          if (value !== undefined && value !== null && value !== "") {
-            acc[key] = String(value);
-          }
-          return acc;
-        },
-        {} as Record<string, string>
-      )
-    );
-    queryParams.append("key", authKey);
-
-    const url = `${http.defaults.baseURL}/logs/export?${queryParams.toString()}`;
-
-    const link = document.createElement("a");
-    link.href = url;
-    link.setAttribute("download", `logs-${Date.now()}.csv`);
-    document.body.appendChild(link);
-    link.click();
-    document.body.removeChild(link);
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = "";
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) {
+        break;
+      }
+      buffer += decoder.decode(value, { stream: true });
+      const lines = buffer.split("\n");
+      buffer = lines.pop() || "";
+      for (const line of lines) {
+        if (line.startsWith("data: ")) {
+          onLog(JSON.parse(line.slice(6)) as RequestLog);
+        }
+      }
+    }
   },
 };
+
+function downloadCSV(path: string, params: ExportFilter, filename: string) {
+  const authKey = localStorage.getItem("authKey");
+  if (!authKey) {
+    window.$message.error(i18n.global.t("auth.noAuthKeyFound"));
+    return;
+  }
+
+  const queryParams = new URLSearchParams(
+    Object.entries(params).reduce(
+      (acc, [key, value]) => {
+        if (value !== undefined && value !== null && value !== "") {
+          acc[key] = String(value);
+        }
+        return acc;
+      },
+      {} as Record<string, string>
+    )
+  );
+  queryParams.append("key", authKey);
+
+  const url = `${http.defaults.baseURL}${path}?${queryParams.toString()}`;
+
+  const link = document.createElement("a");
+  link.href = url;
+  link.setAttribute("download", filename);
+  document.body.appendChild(link);
+  link.click();
+  document.body.removeChild(link);
+}
@@ -1,6 +1,19 @@
-import type { ModelCapability } from "@/types/models";
+import type { ModelAccessPolicy, ModelAliases, ModelCapability } from "@/types/models";
 import http from "@/utils/http";
 
+export interface ModelCapabilityUpdate {
+  supports_streaming?: boolean;
+  supports_vision?: boolean;
+  supports_functions?: boolean;
+  supports_rerank?: boolean;
+  max_tokens?: number;
+  max_input_tokens?: number;
+  max_output_tokens?: number;
+  input_price_per_million?: number;
+  output_price_per_million?: number;
+  custom_capabilities?: Record<string, unknown>;
+}
+
 export const modelsApi = {
   // Fetch models from provider
   async fetchModels(groupId: number): Promise<{ models: ModelCapability[]; count: number }> {
@@ -20,23 +33,31 @@ export const modelsApi = {
     return res.data;
   },
 
-  // Update model capabilities
-  async updateModel(
-    modelId: number,
-    data: {
-      supports_streaming?: boolean;
-      supports_vision?: boolean;
-      supports_functions?: boolean;
-      max_tokens?: number;
-      max_input_tokens?: number;
-      max_output_tokens?: number;
-      custom_capabilities?: Record<string, unknown>;
-    }
+  // Add a model by hand, for providers without a model list API
+  async createModel(
+    groupId: number,
+    data: ModelCapabilityUpdate & { model_id: string; model_name?: string }
   ): Promise<ModelCapability> {
+    const res = await http.post(`/models/group/${groupId}`, data);
+    return res.data;
+  },
+
+  // Update model capabilities
+  async updateModel(modelId: number, data: ModelCapabilityUpdate): Promise<ModelCapability> {
     const res = await http.put(`/models/${modelId}`, data);
     return res.data;
   },
 
+  // Apply the same capabilities to several models of a group
+  async bulkUpdateModels(
+    groupId: number,
+    modelIds: number[],
+    data: ModelCapabilityUpdate
+  ): Promise<{ models: ModelCapability[]; count: number }> {
+    const res = await http.put(`/models/group/${groupId}/bulk`, { model_ids: modelIds, ...data });
+    return res.data;
+  },
+
   // Delete model
   async deleteModel(modelId: number): Promise<void> {
     await http.delete(`/models/${modelId}`);
@@ -51,4 +72,28 @@ export const modelsApi = {
     const res = await http.post(`/models/group/${groupId}/refresh${params}`);
     return res.data;
   },
+
+  // List model aliases for a group
+  async getAliases(groupId: number): Promise<ModelAliases> {
+    const res = await http.get(`/models/group/${groupId}/aliases`);
+    return res.data;
+  },
+
+  // Replace model aliases for a group
+  async updateAliases(groupId: number, data: ModelAliases): Promise<ModelAliases> {
+    const res = await http.put(`/models/group/${groupId}/aliases`, data);
+    return res.data;
+  },
+
+  // Get the model allowlist and denylist of a group
+  async getAccess(groupId: number): Promise<ModelAccessPolicy> {
+    const res = await http.get(`/models/group/${groupId}/access`);
+    return res.data;
+  },
+
+  // Replace the model allowlist and denylist of a group
+  async updateAccess(groupId: number, data: ModelAccessPolicy): Promise<ModelAccessPolicy> {
+    const res = await http.put(`/models/group/${groupId}/access`, data);
+    return res.data;
+  },
 };
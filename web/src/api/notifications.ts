@@ -0,0 +1,43 @@
+import type {
+  ApiResponse,
+  NotificationCategory,
+  NotificationSeverity,
+  NotificationsResponse,
+  NotificationUnreadCounts,
+} from "@/types/models";
+import http from "@/utils/http";
+
+export interface NotificationFilter {
+  page?: number;
+  page_size?: number;
+  unread?: boolean;
+  category?: NotificationCategory;
+  severity?: NotificationSeverity;
+}
+
+export const notificationsApi = {
+  // 获取通知列表
+  list: (params: NotificationFilter): Promise<ApiResponse<NotificationsResponse>> => {
+    return http.get("/notifications", { params });
+  },
+
+  // 获取未读数量
+  getUnreadCount: (): Promise<ApiResponse<NotificationUnreadCounts>> => {
+    return http.get("/notifications/unread-count");
+  },
+
+  // 标记为已读
+  markRead: (ids: number[]) => {
+    return http.post("/notifications/read", { ids }, { hideMessage: true });
+  },
+
+  // 全部标记为已读
+  markAllRead: () => {
+    return http.post("/notifications/read-all", {}, { hideMessage: true });
+  },
+
+  // 清除已读通知
+  clearRead: () => {
+    return http.delete("/notifications/read", { hideMessage: true });
+  },
+};
@@ -0,0 +1,90 @@
+import type {
+  ApiResponse,
+  PlaygroundConversation,
+  PlaygroundConversationMessage,
+  PlaygroundConversationsResponse,
+  PlaygroundStreamEvent,
+} from "@/types/models";
+import http from "@/utils/http";
+
+export interface SavePlaygroundConversationRequest {
+  title?: string;
+  group_name: string;
+  model: string;
+  temperature: number;
+  messages: PlaygroundConversationMessage[];
+}
+
+export const playgroundApi = {
+  // 获取对话列表
+  listConversations: (params: {
+    page?: number;
+    page_size?: number;
+  }): Promise<ApiResponse<PlaygroundConversationsResponse>> => {
+    return http.get("/playground/conversations", { params });
+  },
+
+  // 获取对话及其消息
+  getConversation: (id: number): Promise<ApiResponse<PlaygroundConversation>> => {
+    return http.get(`/playground/conversations/${id}`);
+  },
+
+  // 保存对话，未指定 id 时新建
+  saveConversation: (
+    data: SavePlaygroundConversationRequest,
+    id?: number | null
+  ): Promise<ApiResponse<PlaygroundConversation>> => {
+    if (id) {
+      return http.put(`/playground/conversations/${id}`, data, { hideMessage: true });
+    }
+    return http.post("/playground/conversations", data, { hideMessage: true });
+  },
+
+  // 删除对话
+  deleteConversation: (id: number) => {
+    return http.delete(`/playground/conversations/${id}`, { hideMessage: true });
+  },
+
+  // 流式对话：axios 无法逐块读取响应，因此使用 fetch 读取服务端事件
+  streamChat: async (
+    data: Record<string, unknown>,
+    onEvent: (event: PlaygroundStreamEvent) => void
+  ): Promise<void> => {
+    const headers: Record<string, string> = {
+      "Content-Type": "application/json",
+      "Accept-Language": localStorage.getItem("locale") || "zh-CN",
+    };
+    const authKey = localStorage.getItem("authKey");
+    if (authKey) {
+      headers.Authorization = `Bearer ${authKey}`;
+    }
+
+    const response = await fetch("/api/playground/chat", {
+      method: "POST",
+      headers,
+      body: JSON.stringify({ ...data, stream: true }),
+    });
+    if (!response.ok || !response.body) {
+      const body = await response.json().catch(() => null);
+      throw new Error(body?.message || `HTTP ${response.status}`);
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = "";
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) {
+        break;
+      }
+      buffer += decoder.decode(value, { stream: true });
+      const lines = buffer.split("\n");
+      buffer = lines.pop() || "";
+      for (const line of lines) {
+        if (line.startsWith("data: ")) {
+          onEvent(JSON.parse(line.slice(6)) as PlaygroundStreamEvent);
+        }
+      }
+    }
+  },
+};
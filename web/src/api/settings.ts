@@ -15,6 +15,15 @@ export interface SettingCategory {
   settings: Setting[];
 }
 
+export interface ChannelPreset {
+  id: string;
+  name: string;
+  channel_type: string;
+  upstream: string;
+  validation_endpoint: string;
+  test_model: string;
+}
+
 export type SettingsUpdatePayload = Record<string, string | number | boolean>;
 
 export const settingsApi = {
@@ -29,4 +38,8 @@ export const settingsApi = {
     const response = await http.get("/channel-types");
     return response.data || [];
   },
+  async getChannelPresets(): Promise<ChannelPreset[]> {
+    const response = await http.get("/channel-presets");
+    return response.data || [];
+  },
 };
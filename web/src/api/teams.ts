@@ -0,0 +1,29 @@
+import type { ApiResponse, Team, TeamForm } from "@/types/models";
+import http from "@/utils/http";
+
+export const teamsApi = {
+  // 获取团队列表及成员
+  list: (): Promise<ApiResponse<Team[]>> => {
+    return http.get("/teams");
+  },
+
+  // 创建团队
+  create: (data: TeamForm): Promise<ApiResponse<Team>> => {
+    return http.post("/teams", data);
+  },
+
+  // 更新团队名称、描述和成员
+  update: (id: number, data: TeamForm): Promise<ApiResponse<Team>> => {
+    return http.put(`/teams/${id}`, data);
+  },
+
+  // 删除团队，仍拥有分组的团队无法删除
+  delete: (id: number) => {
+    return http.delete(`/teams/${id}`);
+  },
+
+  // 设置分组所属团队，传 null 表示所有用户可见
+  assignGroup: (groupId: number, teamId: number | null) => {
+    return http.put(`/groups/${groupId}/team`, { team_id: teamId });
+  },
+};
@@ -0,0 +1,58 @@
+import type {
+  ApiResponse,
+  TOTPEnrollment,
+  User,
+  UserForm,
+  UsersResponse,
+} from "@/types/models";
+import http from "@/utils/http";
+
+export const usersApi = {
+  // 获取用户列表
+  list: (): Promise<ApiResponse<UsersResponse>> => {
+    return http.get("/users");
+  },
+
+  // 创建用户
+  create: (data: UserForm): Promise<ApiResponse<User>> => {
+    return http.post("/users", data);
+  },
+
+  // 更新用户，密码留空则保持不变
+  update: (id: number, data: UserForm): Promise<ApiResponse<User>> => {
+    return http.put(`/users/${id}`, data);
+  },
+
+  // 删除用户
+  delete: (id: number) => {
+    return http.delete(`/users/${id}`);
+  },
+
+  // 重置用户的两步验证，用于丢失验证器的用户
+  resetTOTP: (id: number) => {
+    return http.delete(`/users/${id}/2fa`);
+  },
+
+  // 为当前用户生成新的 TOTP 密钥
+  setupTOTP: (): Promise<ApiResponse<TOTPEnrollment>> => {
+    return http.post("/auth/2fa/setup", {}, { hideMessage: true });
+  },
+
+  // 用验证器中的验证码确认启用，返回仅显示一次的恢复码
+  enableTOTP: (code: string): Promise<ApiResponse<{ recovery_codes: string[] }>> => {
+    return http.post("/auth/2fa/enable", { code });
+  },
+
+  // 用验证码或恢复码关闭两步验证
+  disableTOTP: (code: string) => {
+    return http.post("/auth/2fa/disable", { code });
+  },
+
+  // 修改当前用户的密码
+  changePassword: (currentPassword: string, newPassword: string) => {
+    return http.put("/auth/password", {
+      current_password: currentPassword,
+      new_password: newPassword,
+    });
+  },
+};
@@ -63,12 +63,19 @@ export default {
     title: "GPT Load",
     subtitle: "Intelligent Load Balancing Platform",
     welcome: "Welcome Back",
-    welcomeDesc: "Please enter your auth key to continue",
+    welcomeDesc: "Sign in with your account, or leave the username empty to use the auth key",
     authKey: "Auth Key",
     authKeyPlaceholder: "Enter auth key",
     loginButton: "Login",
+    ssoButton: "Sign in with SSO",
     loginSuccess: "Login successful",
     authKeyRequired: "Please enter auth key",
+    usernamePlaceholder: "Username (optional)",
+    passwordPlaceholder: "Enter password",
+    passwordRequired: "Please enter password",
+    totpPlaceholder: "Two-factor or recovery code",
+    totpRequired: "Please enter the code from your authenticator app",
+    totpPrompt: "Enter the code from your authenticator app",
   },
   nav: {
     dashboard: "Dashboard",
@@ -77,6 +84,8 @@ export default {
     logs: "Logs",
     settings: "Settings",
     playground: "Playground",
+    alerts: "Alerts",
+    users: "Users",
     logout: "Logout",
   },
   dashboard: {
@@ -226,6 +235,28 @@ export default {
     stats30Day: "30d Requests",
     stats30DayFailed: "30d Failed Requests",
     stats30DayFailureRate: "30d Failure Rate",
+    routingExperiment: "Sub-group Routing",
+    experimentSubGroup: "Sub-group",
+    trafficShare: "Traffic Share",
+    experimentRequests: "Requests",
+    successRate: "Success Rate",
+    avgLatency: "Avg Latency",
+    avgCost: "Avg Cost",
+    score: "Score",
+    leader: "Leader",
+    upstreamLatency: "Upstream Latency",
+    latencyModel: "Model",
+    latencySamples: "Samples",
+    upstreamHealth: "Status",
+    upstreamHealthy: "Healthy",
+    upstreamPaused: "Paused",
+    quotaCycle: "Quota Cycle",
+    quotaPacing: "Paced",
+    quotaUsed: "Used",
+    quotaLimit: "Limit",
+    quotaPacedUsage: "Pace",
+    quotaResetsAt: "Resets At",
+    quotaProjectedExhaustion: "Projected Exhaustion",
     detailInfo: "Detailed Information",
     basicInfo: "Basic Information",
     displayName: "Display Name",
@@ -282,6 +313,8 @@ export default {
     dontCopyKeys: "Don't copy keys",
     confirmCopy: "Confirm Copy",
     upstreamAddresses: "Upstream Addresses",
+    versionedBase: "Versioned URL",
+    versionedBaseTooltip: "This URL already includes the provider's API version, so the client's version prefix (e.g. /v1) is dropped from request paths.",
     upstream: "Upstream",
     weight: "Weight",
     advancedConfig: "Advanced Configuration",
@@ -308,6 +341,10 @@ export default {
       "Friendly name displayed in the UI, can contain Chinese and special characters. If not filled, group name will be used as display name",
     channelTypeTooltip:
       "Select API provider type, determines request format and authentication method. Supports major AI providers like OpenAI, Gemini, Anthropic",
+    channelPreset: "Provider Preset",
+    selectChannelPreset: "Select provider",
+    channelPresetTooltip:
+      "Fills in the upstream URL, test model and test path for a known OpenAI-compatible provider such as Groq, Together AI or Fireworks AI",
     sortOrderTooltip:
       "Determines display order in the list, smaller numbers appear first. Recommend using intervals like 10, 20, 30 for easy adjustment",
     sortValue: "Sort value",
@@ -320,11 +357,27 @@ export default {
     proxyKeysTooltip:
       "Group-specific proxy keys for accessing this group's proxy endpoint. Separate multiple keys with commas.",
     proxyKeysCopied: "Proxy keys copied to clipboard",
+    sandbox: "Sandbox",
+    sandboxExpired: "Sandbox expired",
+    sandboxTTL: "Sandbox TTL (hours)",
+    sandboxTTLTooltip:
+      "Creates a sandbox group that stops proxying after the given hours and is deleted with its keys after the sandbox retention period. Leave empty for a permanent group; when editing, enter 0 to make the group permanent or a number of hours to extend it from now.",
+    sandboxTTLPlaceholder: "Empty for a permanent group",
+    sandboxExpiresAt: "Expires at {time}, leave empty to keep",
+    sandboxTTLHours: "Valid for hours",
+    createSandboxKey: "Create sandbox proxy key",
+    createSandboxKeyHint:
+      "Generates a proxy key for this group that is removed automatically once it expires.",
+    sandboxKeyCreated: "Sandbox proxy key created, valid until {time}",
+    sandboxKeyCreatedCopied: "Sandbox proxy key created and copied, valid until {time}",
     multiKeysPlaceholder: "Separate multiple keys with commas",
     descriptionTooltip:
       "Detailed description of the group to help team members understand its purpose and features. Supports multi-line text",
     upstreamTooltip:
       "Complete URL of the API server. Multiple upstreams enable load balancing and failover for high availability",
+    upstreamApiKeyPlaceholder: "Optional: API key for this upstream (overrides pooled keys)",
+    upstreamHeadersPlaceholder:
+      'Optional headers for this upstream, one per line: "Header: value", or "-Header" to remove',
     weightTooltip:
       "Load balancing weight configuration. Weight determines traffic distribution ratio - higher values receive more traffic. Weight 0 disables the upstream (no requests). Example: Weight 2:1 means the first receives ~67% of traffic",
     addUpstream: "Add Upstream",
@@ -356,9 +409,48 @@ export default {
     removeToggleTooltip:
       "Enable remove switch to delete this header, disable to add or override this header",
     addHeader: "Add Header",
+    bodyRules: "Body Rules",
+    bodyRulesTooltip:
+      "Modify the JSON body of requests before they are forwarded. Paths are dot-separated, e.g. metadata.user. Set always writes the value, Default only fills a missing field, Remove strips the field and Max caps a number such as max_tokens. Values are JSON (plain text is sent as a string) and strings support the header variables.",
+    bodyRule: "Rule",
+    bodyRulePathPlaceholder: "Field path, e.g. metadata.user",
+    bodyRuleValuePlaceholder: "JSON value, e.g. 0.7 or true",
+    bodyRuleActions: {
+      set: "Set",
+      default: "Default",
+      remove: "Remove",
+      max: "Max",
+    },
+    addBodyRule: "Add Body Rule",
+    scriptRules: "Script Rules",
+    scriptRulesTooltip:
+      'Expressions evaluated in order for every request, with model, proxy_key, headers (lower-case names), body, path, method, client_ip, group and prompt_tokens, e.g. model startsWith "gpt-4" && prompt_tokens > 8000. When one is true, Reject refuses the request, Route sends it to another group and Model replaces the requested model before checking the next rule. Rules that fail or take longer than 50 ms are skipped.',
+    scriptRule: "Rule",
+    scriptRuleWhenPlaceholder: 'Condition, e.g. headers["x-team"] == "research"',
+    scriptRuleMessagePlaceholder: "Message returned to the client (optional)",
+    scriptRuleModelPlaceholder: "Model to use instead",
+    scriptRuleActions: {
+      reject: "Reject",
+      route: "Route",
+      model: "Model",
+    },
+    addScriptRule: "Add Script Rule",
+    modelRouting: "Model Routing",
+    modelRoutingTooltip:
+      "Send requests for matching models to another group, so this endpoint can serve models from several providers. Rules are checked in order; the first match wins. Patterns are exact names or globs such as claude-*. Requests with no matching rule are served by this group.",
+    modelRoutingRule: "Rule",
+    modelRoutingPatternPlaceholder: "Model name or pattern, e.g. claude-*",
+    modelRoutingGroupPlaceholder: "Target group",
+    addModelRoutingRule: "Add Routing Rule",
     paramOverridesTooltip:
       "Define the API request parameters to be overridden using JSON format. These parameters will be merged with the original parameters when sending the request.",
     modelRedirectPolicy: "Unconfigured Model Policy",
+    modelWarmup: "Model List Warm-up",
+    modelWarmupStatus: {
+      running: "Fetching",
+      success: "Ready",
+      failed: "Failed",
+    },
     modelRedirectPolicyTooltip:
       "Choose how to handle requests for models not configured in redirect rules",
     modelRedirectStrictMode: "Strict Mode: Reject unconfigured model requests (return 404)",
@@ -371,7 +463,7 @@ export default {
     modelRedirectRulesTooltip:
       "Configure model redirect rules, key is the model name requested by user, value is the actual model name sent to upstream",
     modelRedirectRulesDescription:
-      "Configure model redirect rules, key is the model name requested by user, value is the actual model name sent to upstream",
+      "Configure model redirect rules, key is the model name requested by user, value is the actual model name sent to upstream. Keys may be glob patterns such as gpt-4*",
     modelRedirectInvalidJson: "Invalid JSON format for model redirect rules",
     modelRedirectInvalidFormat: "Model redirect rule keys and values must all be strings",
     modelRedirectEmptyModel: "Model name cannot be empty",
@@ -424,6 +516,40 @@ export default {
     notes: "Notes",
     editKeyNotes: "Edit key notes",
     enterNotes: "Enter notes...",
+    expiringSoon: "Expiring soon",
+    editExpiry: "Edit expiry",
+    editKeyExpiry: "Edit key expiry",
+    noExpiry: "No expiry",
+    expiryUpdated: "Expiry updated",
+    expired: "Expired",
+    expiresInDays: "Expires in {days}d",
+    expiresInHours: "Expires in {hours}h",
+    editLimits: "Edit limits",
+    editKeyLimits: "Edit key limits",
+    rpmLimit: "Requests per minute",
+    tpmLimit: "Tokens per minute",
+    dailySpendLimit: "Daily spend (USD)",
+    limitsHint: "0 means no limit. A key at its limit is skipped until the minute or UTC day resets.",
+    limitsUpdated: "Limits updated",
+    backupTier: "Backup",
+    moveToBackup: "Move to backup tier",
+    moveToPrimary: "Move to primary tier",
+    movedToBackup: "Key moved to the backup tier, used once primary keys run out",
+    movedToPrimary: "Key moved to the primary tier",
+    primaryTier: "Primary",
+    tierUsage: "{tier}: {active} active, {invalid} invalid, {requests} requests",
+    viewHistory: "Health history",
+    keyHistory: "Health history of {key}",
+    historyDays: "{days}d",
+    historyBucket: "{time}: {rate} ({success} succeeded, {failure} failed)",
+    noHealthEvents: "No validations or failures in this period",
+    sourceValidation: "Validation",
+    sourceProxy: "Request",
+    healthOk: "Passed",
+    healthFailed: "Failed",
+    healthDisabled: "Disabled",
+    coolingDownSeconds: "Cooling down {seconds}s",
+    coolingDownMinutes: "Cooling down {minutes}m",
   },
   subGroups: {
     addSubGroup: "Add Sub Group",
@@ -461,6 +587,7 @@ export default {
     stream: "Stream",
     nonStream: "Non-Stream",
     statusCode: "Status Code",
+    statusClass: "Status Class",
     duration: "Duration(ms)",
     model: "Model",
     sourceIP: "Source IP",
@@ -500,9 +627,17 @@ export default {
     hideDetails: "Hide Details",
     requestInfo: "Request Information",
     upstreamAddress: "Upstream Address",
+    requestId: "Request ID",
     requestContent: "Request Content",
     errorInfo: "Error Information",
     customColumns: "Custom Columns",
+    metadata: "Metadata",
+    metadataFilter: "Metadata (field:value)",
+    moderationFlags: "Moderation Flags",
+    exportUsage: "Export Usage",
+    liveStart: "Live tail: show new requests as they arrive",
+    liveStop: "Stop live tail",
+    liveFailed: "Live log stream disconnected",
   },
   settings: {
     title: "Settings",
@@ -544,6 +679,19 @@ export default {
     dangerZone: "Danger Zone",
     clearAllData: "Clear All Data",
     confirmClearData: "This will delete all data and cannot be undone. Continue?",
+    backup: "Backup & Restore",
+    backupDescription:
+      "Export all groups, upstreams, header rules and models as a single file to migrate or " +
+      "restore another instance. Importing updates groups with the same name and creates the others.",
+    backupIncludeSettings: "Include system settings",
+    backupIncludeKeys: "Include keys",
+    backupPassphrase: "Passphrase",
+    backupPassphraseHint: "Encrypts the exported keys; needed again to import them",
+    backupExport: "Export",
+    backupImport: "Import",
+    backupImportResult:
+      "{created} groups created, {updated} updated, {models} models and {keys} new keys imported",
+    backupInvalidFile: "Not a valid backup file",
   },
   footer: {
     checking: "Checking...",
@@ -586,6 +734,8 @@ export default {
       "Key validation completed, processed {total} keys, {valid} successful, {invalid} failed. Note: Failed validations do not immediately blacklist keys - failure count must reach threshold to blacklist.",
     importCompleted: "Key import completed, added {added} keys, ignored {ignored}.",
     deleteCompleted: "Key deletion completed, deleted {deleted} keys, ignored {ignored}.",
+    failedItems: "{failed} failed.",
+    rolledBack: "Some keys failed, so no changes were applied.",
   },
   theme: {
     auto: "Auto Mode",
@@ -637,19 +787,34 @@ export default {
     streaming: "Streaming",
     vision: "Vision",
     functions: "Functions",
+    rerank: "Rerank",
     auto_fetched: "Auto Fetched",
     manual: "Manual",
     fetch_models: "Fetch Models",
     refresh_models: "Refresh Models",
     edit_model: "Edit Model",
+    add_model: "Add Model",
+    model_id_placeholder: "Model ID as sent to the provider",
+    model_id_required: "Model ID is required",
+    create_success: "Model added",
+    create_failed: "Failed to add model",
+    bulk_edit: "Edit {count} selected",
+    bulk_edit_title: "Edit {count} Models",
+    bulk_edit_hint:
+      "The switches below are applied to every selected model. Limits and prices are only changed when filled in.",
+    bulk_update_success: "Updated {count} models",
     delete_model: "Delete Model",
     select_group_hint: "Please select a group to view models",
     supports_streaming: "Supports Streaming",
     supports_vision: "Supports Vision",
     supports_functions: "Supports Functions",
+    supports_rerank: "Supports Rerank",
     max_tokens: "Max Tokens",
     max_input_tokens: "Max Input Tokens",
     max_output_tokens: "Max Output Tokens",
+    pricing: "Price (input / output per 1M tokens, USD)",
+    input_price_per_million: "Input Price (USD / 1M tokens)",
+    output_price_per_million: "Output Price (USD / 1M tokens)",
     fetch_success: "Successfully fetched {count} models",
     fetch_failed: "Failed to fetch models",
     refresh_success: "Successfully refreshed {count} models",
@@ -659,15 +824,178 @@ export default {
     update_failed: "Failed to update model",
     delete_success: "Model deleted successfully",
     delete_failed: "Failed to delete model",
+    aliases_title: "Model Aliases",
+    aliases_description: "Map a requested model name or glob pattern (e.g. gpt-4*) to the model sent upstream. Exact aliases take precedence over patterns. Aliases are shared with the group's model redirect rules.",
+    add_alias: "Add Alias",
+    alias_placeholder: "Alias or pattern, e.g. gpt-4",
+    alias_target_placeholder: "Upstream model, e.g. gpt-4o-2024-08-06",
+    no_aliases: "No aliases configured",
+    aliases_strict: "Reject models without an alias (strict mode)",
+    alias_incomplete: "Each alias needs both a name and a target model",
+    aliases_saved: "Model aliases saved",
+    aliases_save_failed: "Failed to save model aliases",
+    access_title: "Model Access",
+    access_description: "Restrict which models can be called through this group. Entries are model names or glob patterns (e.g. gpt-4*). Denied models always win; when the allowlist is not empty, only listed models are accepted. Rules for a proxy key apply on top of the group rules. Disallowed requests are rejected with 403 before a key is used.",
+    access_allowed: "Allowed",
+    access_denied: "Denied",
+    add_key_access: "Add Proxy Key Rule",
+    access_key_placeholder: "Proxy key",
+    access_key_required: "Each proxy key rule needs a proxy key",
+    access_saved: "Model access rules saved",
+    access_save_failed: "Failed to save model access rules",
     delete_confirm_title: "Delete Model",
     delete_confirm_content: "Are you sure you want to delete model '{modelName}'?",
   },
+  notifications: {
+    title: "Notifications",
+    mark_all_read: "Mark all read",
+    clear_read: "Clear read",
+    empty: "No notifications",
+    categories: {
+      key: "Key",
+      job: "Task",
+      alert: "Alert",
+      model: "Model",
+    },
+    events: {
+      key_disabled: "Key {key} in group {group} was disabled after {failures} failures",
+      key_recovered: "Key {key} in group {group} recovered and is active again",
+      key_expired: "{count} keys in group {group} expired and were disabled",
+      key_expiring: "{count} keys in group {group} expire within {days} days",
+      task_completed: "Task {task_type} for group {group} completed",
+      task_failed: "Task {task_type} for group {group} failed: {error}",
+      keys_low: "Group {group} dropped to {active_keys} active keys (threshold {threshold}), top-up added {added}",
+      key_rotation_failed: "Encryption key rotation to version {version} left {failed} keys undecryptable",
+      models_detected: "{count} new models detected in group {group}",
+      models_removed: "{count} models are no longer offered in group {group} and were removed",
+      sandbox_deleted: "Sandbox group {group} expired and was deleted",
+      alert_firing: "Alert {rule} is firing for group {group}",
+      alert_resolved: "Alert {rule} for group {group} resolved",
+    },
+  },
+  alerts: {
+    title: "Alert Rules",
+    description:
+      "Rules are checked every minute on the master node. A notification is sent when a rule " +
+      "starts firing for a group and when it resolves.",
+    add: "Add Rule",
+    edit: "Edit Rule",
+    name: "Name",
+    type: "Type",
+    condition: "Condition",
+    types: {
+      error_rate: "Error rate above threshold",
+      all_keys_invalid: "All keys invalid",
+      quota_exceeded: "Quota usage above threshold",
+      upstream_circuit_open: "Upstream circuit open",
+      key_invalidated: "Key invalidated",
+      key_recovered: "Key recovered",
+    },
+    group: "Group",
+    allGroups: "All groups",
+    threshold: "Threshold (%)",
+    window: "Window (minutes)",
+    minRequests: "Minimum requests",
+    minRequestsHint: "The error rate is only checked once the window has this many requests",
+    repeat: "Repeat every (minutes)",
+    repeatHint: "Send the alert again while it keeps firing, 0 sends it once",
+    channel: "Channel",
+    channels: {
+      webhook: "Webhook",
+      slack: "Slack",
+      discord: "Discord",
+      telegram: "Telegram",
+    },
+    telegramBotToken: "Bot token",
+    telegramChatId: "Chat ID",
+    webhookUrl: "Webhook URL",
+    webhookHint:
+      "Leave empty to only record alerts in the notification center. Key rules need a channel.",
+    payloadTemplate: "Payload template",
+    payloadTemplateHint:
+      "Go template rendering a JSON body. Fields: .Rule .RuleID .Type .Status .Group .GroupID " +
+      ".Value .Threshold .Message .Time; the json function quotes a value. Leave empty for the " +
+      "default payload, or the plain message on Slack and Discord.",
+    enabled: "Enabled",
+    test: "Send test",
+    conditionErrorRate: "> {threshold}% in {window} min",
+    conditionQuota: "≥ {threshold}% of quota",
+    deleteConfirm: "Delete alert rule {name}?",
+    loadFailed: "Failed to load alert rules",
+    empty: "No alert rules",
+  },
+  users: {
+    title: "Users",
+    description:
+      "Users sign in with a username and password. The auth key keeps working as a built-in " +
+      "administrator.",
+    add: "Add User",
+    edit: "Edit User",
+    username: "Username",
+    password: "Password",
+    passwordHint: "At least 8 characters",
+    passwordKeepHint: "Leave empty to keep the current password",
+    role: "Role",
+    roles: {
+      viewer: "Viewer",
+      operator: "Operator",
+      admin: "Admin",
+    },
+    roleHints: {
+      viewer: "Read-only access; cannot export keys or logs",
+      operator: "Can manage groups, keys and alerts",
+      admin: "Full access, including users, settings and config rollbacks",
+    },
+    status: "Status",
+    active: "Active",
+    disabled: "Disabled",
+    lastLogin: "Last login",
+    changePassword: "Change Password",
+    currentPassword: "Current password",
+    newPassword: "New password",
+    deleteConfirm: "Delete user {name}?",
+    loadFailed: "Failed to load users",
+    empty: "No users",
+    twoFactor: "Two-Factor Auth",
+    twoFactorOn: "On",
+    twoFactorOff: "Off",
+    enableTwoFactor: "Enable",
+    disableTwoFactor: "Disable",
+    disableTwoFactorHint: "Enter a code from your authenticator app or a recovery code to disable",
+    resetTwoFactor: "Reset two-factor auth",
+    resetTwoFactorConfirm: "Remove the two-factor authentication of {name}?",
+    totpSecret: "Secret",
+    totpSecretHint: "Add this secret to your authenticator app, or import the URI below",
+    totpURI: "URI",
+    totpCode: "Code",
+    recoveryCodesHint:
+      "Save these recovery codes somewhere safe. Each works once if you lose your device, " +
+      "and they will not be shown again.",
+    twoFactorEnrollmentRequired: "Enable two-factor authentication to perform this operation",
+    teams: "Teams",
+    teamsDescription:
+      "Groups owned by a team are only visible to its members and admins. Groups without a team " +
+      "are visible to everyone.",
+    addTeam: "Add Team",
+    editTeam: "Edit Team",
+    teamName: "Name",
+    teamDescription: "Description",
+    teamMembers: "Members",
+    teamsEmpty: "No teams",
+    deleteTeamConfirm: "Delete team {name}?",
+    team: "Team",
+    noTeam: "Shared with everyone",
+  },
   playground: {
     title: "LLM Playground",
     selectGroup: "Select Group",
     modelName: "Model Name",
     temperature: "Temperature",
-    clearChat: "Clear Chat",
+    newChat: "New Chat",
+    history: "Conversation history",
+    untitledConversation: "Untitled",
+    failedToSaveConversation: "Failed to save conversation",
+    confirmDeleteConversation: "Delete this conversation?",
     startConversation: "Start a conversation with the LLM",
     enterMessage: "Enter your message...",
     send: "Send",
@@ -679,5 +1007,33 @@ export default {
     failedToLoadGroups: "Failed to load groups",
     failedToSendMessage: "Failed to send message",
     invalidTemperature: "Temperature must be between 0 and 2",
+    choiceCount: "Choices (n)",
+    showReasoning: "Reasoning shown",
+    hideReasoning: "Reasoning hidden",
+    reasoning: "Reasoning",
+    candidate: "Candidate {index}",
+    chatTab: "Chat",
+    embeddingsTab: "Embeddings",
+    embeddingInputHint: "One input per line",
+    createEmbeddings: "Create Embeddings",
+    embeddingTokens: "Tokens used: {tokens}",
+    dimensions: "{count} dimensions",
+    attach: "Attach",
+    attachedImage: "Attached image",
+    fileTooLarge: "{name} is too large",
+    failedToReadFile: "Failed to read {name}",
+    streamOn: "Streaming",
+    streamOff: "Not streaming",
+    tools: "Tools",
+    toolsHint: "JSON array of OpenAI style function tools (type, function.name, function.parameters)",
+    toolChoice: "Tool choice",
+    toolChoiceAuto: "auto",
+    toolChoiceNone: "none",
+    toolChoiceRequired: "required",
+    invalidTools: "Tools must be a JSON array of tool definitions",
+    validJson: "Valid JSON",
+    invalidJson: "Invalid JSON",
+    replyToToolCall: "Reply with result",
+    replyingTo: "Replying to {name} ({id})",
   },
 };
@@ -63,12 +63,19 @@ export default {
     title: "GPT Load",
     subtitle: "インテリジェント負荷分散管理プラットフォーム",
     welcome: "おかえりなさい",
-    welcomeDesc: "続行するには認証キーを入力してください",
+    welcomeDesc: "アカウントでログインするか、ユーザー名を空にして認証キーでログインしてください",
     authKey: "認証キー",
     authKeyPlaceholder: "認証キーを入力",
     loginButton: "ログイン",
+    ssoButton: "SSO でログイン",
     loginSuccess: "ログイン成功",
     authKeyRequired: "認証キーを入力してください",
+    usernamePlaceholder: "ユーザー名（任意）",
+    passwordPlaceholder: "パスワードを入力",
+    passwordRequired: "パスワードを入力してください",
+    totpPlaceholder: "二要素認証コードまたはリカバリーコード",
+    totpRequired: "認証アプリのコードを入力してください",
+    totpPrompt: "認証アプリのコードを入力してください",
   },
   nav: {
     dashboard: "ダッシュボード",
@@ -77,6 +84,8 @@ export default {
     logs: "ログ",
     settings: "システム設定",
     playground: "プレイグラウンド",
+    alerts: "アラート",
+    users: "ユーザー",
     logout: "ログアウト",
   },
   dashboard: {
@@ -225,6 +234,28 @@ export default {
     stats30Day: "30日間リクエスト",
     stats30DayFailed: "30日間失敗リクエスト",
     stats30DayFailureRate: "30日間失敗率",
+    routingExperiment: "サブグループルーティング",
+    experimentSubGroup: "サブグループ",
+    trafficShare: "トラフィック比率",
+    experimentRequests: "リクエスト数",
+    successRate: "成功率",
+    avgLatency: "平均レイテンシ",
+    avgCost: "平均コスト",
+    score: "スコア",
+    leader: "トップ",
+    upstreamLatency: "アップストリームのレイテンシ",
+    latencyModel: "モデル",
+    latencySamples: "サンプル数",
+    upstreamHealth: "状態",
+    upstreamHealthy: "正常",
+    upstreamPaused: "一時停止中",
+    quotaCycle: "クォータ周期",
+    quotaPacing: "ペース配分",
+    quotaUsed: "使用量",
+    quotaLimit: "上限",
+    quotaPacedUsage: "ペース",
+    quotaResetsAt: "リセット日時",
+    quotaProjectedExhaustion: "枯渇予測",
     detailInfo: "詳細情報",
     basicInfo: "基本情報",
     displayName: "表示名",
@@ -281,6 +312,8 @@ export default {
     dontCopyKeys: "キーをコピーしない",
     confirmCopy: "コピーを確認",
     upstreamAddresses: "アップストリームアドレス",
+    versionedBase: "バージョン付きURL",
+    versionedBaseTooltip: "このURLにはプロバイダー独自のAPIバージョンが含まれているため、リクエストパスのクライアント側バージョン接頭辞（/v1 など）は除去されます。",
     upstream: "アップストリーム",
     weight: "ウェイト",
     advancedConfig: "詳細設定",
@@ -307,6 +340,10 @@ export default {
       "UIに表示されるフレンドリーな名前、中国語や特殊文字を含むことができます。未入力の場合、グループ名が表示名として使用されます",
     channelTypeTooltip:
       "APIプロバイダータイプを選択、リクエスト形式と認証方法を決定します。OpenAI、Gemini、Anthropicなどの主要AIプロバイダーをサポート",
+    channelPreset: "プロバイダープリセット",
+    selectChannelPreset: "プロバイダーを選択",
+    channelPresetTooltip:
+      "Groq、Together AI、Fireworks AIなどの既知のOpenAI互換プロバイダーの上流URL、テストモデル、テストパスを自動入力します",
     sortOrderTooltip:
       "リスト内の表示順序を決定、数値が小さいほど前に表示されます。10、20、30のような間隔での設定を推奨",
     sortValue: "ソート値",
@@ -319,11 +356,27 @@ export default {
     proxyKeysTooltip:
       "このグループのプロキシエンドポイントにアクセスするためのグループ固有のプロキシキー。複数のキーはカンマで区切ってください。",
     proxyKeysCopied: "プロキシキーがクリップボードにコピーされました",
+    sandbox: "サンドボックス",
+    sandboxExpired: "サンドボックス期限切れ",
+    sandboxTTL: "サンドボックス有効期間（時間）",
+    sandboxTTLTooltip:
+      "指定した時間の経過後にプロキシを停止し、サンドボックス保持期間の後にキーとともに削除されるサンドボックスグループを作成します。空欄にすると無期限のグループになります。編集時は 0 で無期限に、時間数を入力すると現在から延長します。",
+    sandboxTTLPlaceholder: "空欄で無期限",
+    sandboxExpiresAt: "{time} に期限切れ、空欄で変更なし",
+    sandboxTTLHours: "有効時間",
+    createSandboxKey: "サンドボックスプロキシキーを作成",
+    createSandboxKeyHint:
+      "期限切れ後に自動的に削除される、このグループ用のプロキシキーを生成します。",
+    sandboxKeyCreated: "サンドボックスプロキシキーを作成しました。有効期限: {time}",
+    sandboxKeyCreatedCopied: "サンドボックスプロキシキーを作成してコピーしました。有効期限: {time}",
     multiKeysPlaceholder: "複数のキーはカンマで区切ってください",
     descriptionTooltip:
       "チームメンバーがその目的と特徴を理解できるようにするグループの詳細説明。複数行テキストをサポート",
     upstreamTooltip:
       "APIサーバーの完全なURL。複数のアップストリームにより高可用性のためのロードバランシングとフェイルオーバーが可能",
+    upstreamApiKeyPlaceholder: "任意：このアップストリーム専用の API キー（プールのキーを上書き）",
+    upstreamHeadersPlaceholder:
+      "任意：このアップストリームのヘッダー。1行に1つ「Header: value」、「-Header」で削除",
     weightTooltip:
       "ロードバランシング重み設定。重みはトラフィック分配比率を決定し、値が高いほど多くのトラフィックを受信します。重み0はアップストリームを無効化（リクエストなし）。例：重み2:1は前者が約67%のトラフィックを受信",
     addUpstream: "アップストリーム追加",
@@ -355,9 +408,48 @@ export default {
     removeToggleTooltip:
       "削除スイッチを有効にするとこのヘッダーを削除、無効にするとこのヘッダーを追加または上書き",
     addHeader: "ヘッダー追加",
+    bodyRules: "ボディルール",
+    bodyRulesTooltip:
+      "転送前にリクエストの JSON ボディを変更します。パスはドット区切りです（例: metadata.user）。設定は常に値を書き込み、デフォルトは未指定の場合のみ補完し、削除はフィールドを取り除き、上限は max_tokens などの数値を制限します。値は JSON として解釈され（プレーンテキストは文字列として送信）、文字列ではヘッダー変数を使えます。",
+    bodyRule: "ルール",
+    bodyRulePathPlaceholder: "フィールドパス（例: metadata.user）",
+    bodyRuleValuePlaceholder: "JSON 値（例: 0.7 または true）",
+    bodyRuleActions: {
+      set: "設定",
+      default: "デフォルト",
+      remove: "削除",
+      max: "上限",
+    },
+    addBodyRule: "ボディルール追加",
+    scriptRules: "スクリプトルール",
+    scriptRulesTooltip:
+      '各リクエストに対して順番に評価される式です。model、proxy_key、headers（小文字の名前）、body、path、method、client_ip、group、prompt_tokens を参照できます（例: model startsWith "gpt-4" && prompt_tokens > 8000）。式が真の場合、拒否はリクエストを拒否し、ルーティングは別のグループに転送し、モデルは要求モデルを置き換えて次のルールへ進みます。失敗したルールや 50 ms を超えたルールはスキップされます。',
+    scriptRule: "ルール",
+    scriptRuleWhenPlaceholder: '条件（例: headers["x-team"] == "research"）',
+    scriptRuleMessagePlaceholder: "クライアントに返すメッセージ（任意）",
+    scriptRuleModelPlaceholder: "代わりに使用するモデル",
+    scriptRuleActions: {
+      reject: "拒否",
+      route: "ルーティング",
+      model: "モデル",
+    },
+    addScriptRule: "スクリプトルール追加",
+    modelRouting: "モデルルーティング",
+    modelRoutingTooltip:
+      "一致するモデルへのリクエストを別のグループに転送し、1つのエンドポイントで複数プロバイダーのモデルを提供できます。ルールは順番に評価され、最初に一致したものが適用されます。パターンは完全なモデル名または claude-* のようなワイルドカードです。一致しないリクエストはこのグループで処理されます。",
+    modelRoutingRule: "ルール",
+    modelRoutingPatternPlaceholder: "モデル名またはパターン（例: claude-*）",
+    modelRoutingGroupPlaceholder: "転送先グループ",
+    addModelRoutingRule: "ルーティングルール追加",
     paramOverridesTooltip:
       "JSON形式を使用して、上書きするAPIリクエストパラメータを定義します。これらのパラメータは、リクエスト送信時に元のパラメータにマージされます。",
     modelRedirectPolicy: "未設定モデルポリシー",
+    modelWarmup: "モデルリストのウォームアップ",
+    modelWarmupStatus: {
+      running: "取得中",
+      success: "準備完了",
+      failed: "失敗",
+    },
     modelRedirectPolicyTooltip:
       "リダイレクトルールで設定されていないモデルのリクエストをどう処理するか選択",
     modelRedirectStrictMode: "厳格モード：未設定モデルのリクエストを拒否（404を返す）",
@@ -370,7 +462,7 @@ export default {
     modelRedirectRulesTooltip:
       "モデルリダイレクトルールを設定。キーはユーザーがリクエストするモデル名、値はアップストリームに送信する実際のモデル名",
     modelRedirectRulesDescription:
-      "モデルリダイレクトルールを設定。キーはユーザーがリクエストするモデル名、値はアップストリームに送信する実際のモデル名",
+      "モデルリダイレクトルールを設定。キーはユーザーがリクエストするモデル名、値はアップストリームに送信する実際のモデル名。キーには gpt-4* のようなワイルドカードパターンも使用できます",
     modelRedirectInvalidJson: "モデルリダイレクトルールのJSON形式が無効です",
     modelRedirectInvalidFormat:
       "モデルリダイレクトルールのキーと値はすべて文字列である必要があります",
@@ -424,6 +516,40 @@ export default {
     notes: "備考",
     editKeyNotes: "キー備考を編集",
     enterNotes: "備考を入力してください...",
+    expiringSoon: "まもなく期限切れ",
+    editExpiry: "有効期限を編集",
+    editKeyExpiry: "キーの有効期限を編集",
+    noExpiry: "無期限",
+    expiryUpdated: "有効期限を更新しました",
+    expired: "期限切れ",
+    expiresInDays: "{days} 日後に期限切れ",
+    expiresInHours: "{hours} 時間後に期限切れ",
+    editLimits: "上限を編集",
+    editKeyLimits: "キーの上限を編集",
+    rpmLimit: "1 分あたりのリクエスト数",
+    tpmLimit: "1 分あたりのトークン数",
+    dailySpendLimit: "1 日の利用額（USD）",
+    limitsHint: "0 は無制限です。上限に達したキーは、その分または UTC の日がリセットされるまでスキップされます。",
+    limitsUpdated: "上限を更新しました",
+    backupTier: "予備",
+    moveToBackup: "予備層に移動",
+    moveToPrimary: "主層に移動",
+    movedToBackup: "キーを予備層に移動しました。主層のキーを使い切った後に使用されます",
+    movedToPrimary: "キーを主層に移動しました",
+    primaryTier: "主層",
+    tierUsage: "{tier}：有効 {active}、無効 {invalid}、リクエスト {requests}",
+    viewHistory: "ヘルス履歴",
+    keyHistory: "{key} のヘルス履歴",
+    historyDays: "{days} 日",
+    historyBucket: "{time}：{rate}（成功 {success}、失敗 {failure}）",
+    noHealthEvents: "この期間に検証や失敗の記録はありません",
+    sourceValidation: "検証",
+    sourceProxy: "リクエスト",
+    healthOk: "成功",
+    healthFailed: "失敗",
+    healthDisabled: "無効化",
+    coolingDownSeconds: "クールダウン中 {seconds} 秒",
+    coolingDownMinutes: "クールダウン中 {minutes} 分",
   },
   subGroups: {
     addSubGroup: "サブグループを追加",
@@ -462,6 +588,7 @@ export default {
     stream: "ストリーム",
     nonStream: "非ストリーム",
     statusCode: "ステータスコード",
+    statusClass: "ステータスクラス",
     duration: "所要時間(ms)",
     model: "モデル",
     sourceIP: "ソースIP",
@@ -501,9 +628,17 @@ export default {
     hideDetails: "詳細非表示",
     requestInfo: "リクエスト情報",
     upstreamAddress: "アップストリームアドレス",
+    requestId: "リクエスト ID",
     requestContent: "リクエスト内容",
     errorInfo: "エラー情報",
     customColumns: "カラムのカスタマイズ",
+    metadata: "メタデータ",
+    metadataFilter: "メタデータ（フィールド:値）",
+    moderationFlags: "モデレーションフラグ",
+    exportUsage: "使用量をエクスポート",
+    liveStart: "ライブテール：新しいリクエストを到着時に表示",
+    liveStop: "ライブテールを停止",
+    liveFailed: "ライブログストリームが切断されました",
   },
   settings: {
     title: "システム設定",
@@ -545,6 +680,18 @@ export default {
     dangerZone: "危険ゾーン",
     clearAllData: "すべてのデータをクリア",
     confirmClearData: "この操作はすべてのデータを削除し、元に戻すことはできません。続行しますか？",
+    backup: "バックアップと復元",
+    backupDescription:
+      "すべてのグループ、アップストリーム、ヘッダールール、モデルを 1 つのファイルにエクスポートし、別のインスタンスの移行や復元に使用します。インポートでは同名のグループを更新し、それ以外を作成します。",
+    backupIncludeSettings: "システム設定を含める",
+    backupIncludeKeys: "キーを含める",
+    backupPassphrase: "パスフレーズ",
+    backupPassphraseHint: "エクスポートするキーを暗号化します。インポート時にも必要です",
+    backupExport: "エクスポート",
+    backupImport: "インポート",
+    backupImportResult:
+      "{created} 個のグループを作成、{updated} 個を更新、{models} 個のモデルと {keys} 個の新しいキーをインポートしました",
+    backupInvalidFile: "有効なバックアップファイルではありません",
   },
   footer: {
     checking: "確認中...",
@@ -587,6 +734,8 @@ export default {
       "キー検証完了、{total}個のキーを処理、{valid}個成功、{invalid}個失敗。注意：検証失敗でもすぐにブラックリストに追加されるわけではありません。失敗回数が闾値に達する必要があります。",
     importCompleted: "キーインポート完了、{added}個追加、{ignored}個無視。",
     deleteCompleted: "キー削除完了、{deleted}個削除、{ignored}個無視。",
+    failedItems: "{failed}個失敗。",
+    rolledBack: "一部のキーが失敗したため、変更は適用されませんでした。",
   },
   theme: {
     auto: "自動モード",
@@ -638,19 +787,34 @@ export default {
     streaming: "ストリーミング",
     vision: "ビジョン",
     functions: "関数呼び出し",
+    rerank: "リランク",
     auto_fetched: "自動取得",
     manual: "手動",
     fetch_models: "モデル取得",
     refresh_models: "モデル更新",
     edit_model: "モデル編集",
+    add_model: "モデル追加",
+    model_id_placeholder: "プロバイダーに送信するモデルID",
+    model_id_required: "モデルIDは必須です",
+    create_success: "モデルを追加しました",
+    create_failed: "モデルの追加に失敗しました",
+    bulk_edit: "選択した{count}件を編集",
+    bulk_edit_title: "{count}件のモデルを編集",
+    bulk_edit_hint:
+      "以下のスイッチは選択したすべてのモデルに適用されます。トークン制限と価格は入力した場合のみ更新されます。",
+    bulk_update_success: "{count}件のモデルを更新しました",
     delete_model: "モデル削除",
     select_group_hint: "グループを選択してモデルを表示してください",
     supports_streaming: "ストリーミング対応",
     supports_vision: "ビジョン対応",
     supports_functions: "関数呼び出し対応",
+    supports_rerank: "リランク対応",
     max_tokens: "最大トークン数",
     max_input_tokens: "最大入力トークン数",
     max_output_tokens: "最大出力トークン数",
+    pricing: "価格（入力 / 出力、100万トークンあたりUSD）",
+    input_price_per_million: "入力価格（USD / 100万トークン）",
+    output_price_per_million: "出力価格（USD / 100万トークン）",
     fetch_success: "{count}個のモデルを取得しました",
     fetch_failed: "モデルの取得に失敗しました",
     refresh_success: "{count}個のモデルを更新しました",
@@ -660,15 +824,174 @@ export default {
     update_failed: "モデルの更新に失敗しました",
     delete_success: "モデルを削除しました",
     delete_failed: "モデルの削除に失敗しました",
+    aliases_title: "モデルエイリアス",
+    aliases_description: "リクエストされたモデル名またはワイルドカードパターン（例: gpt-4*）を上流に送信するモデルに対応付けます。完全一致のエイリアスがパターンより優先されます。エイリアスはグループのモデルリダイレクトルールと共有されます。",
+    add_alias: "エイリアスを追加",
+    alias_placeholder: "エイリアスまたはパターン（例: gpt-4）",
+    alias_target_placeholder: "上流モデル（例: gpt-4o-2024-08-06）",
+    no_aliases: "エイリアスは設定されていません",
+    aliases_strict: "エイリアスのないモデルを拒否する（厳格モード）",
+    alias_incomplete: "各エイリアスには名前とターゲットモデルの両方が必要です",
+    aliases_saved: "モデルエイリアスを保存しました",
+    aliases_save_failed: "モデルエイリアスの保存に失敗しました",
+    access_title: "モデルアクセス制御",
+    access_description: "このグループで呼び出せるモデルを制限します。エントリはモデル名またはワイルドカード（例: gpt-4*）です。拒否リストが優先され、許可リストが空でない場合は一覧のモデルのみ受け付けます。プロキシキーのルールはグループのルールに加えて適用されます。許可されないリクエストはキーを使用する前に 403 で拒否されます。",
+    access_allowed: "許可",
+    access_denied: "拒否",
+    add_key_access: "プロキシキールールを追加",
+    access_key_placeholder: "プロキシキー",
+    access_key_required: "各プロキシキールールにはプロキシキーが必要です",
+    access_saved: "モデルアクセスルールを保存しました",
+    access_save_failed: "モデルアクセスルールの保存に失敗しました",
     delete_confirm_title: "モデル削除",
     delete_confirm_content: "モデル '{modelName}' を削除してもよろしいですか？",
   },
+  notifications: {
+    title: "通知",
+    mark_all_read: "すべて既読にする",
+    clear_read: "既読を削除",
+    empty: "通知はありません",
+    categories: {
+      key: "キー",
+      job: "タスク",
+      alert: "アラート",
+      model: "モデル",
+    },
+    events: {
+      key_disabled: "グループ {group} のキー {key} が {failures} 回の失敗後に無効化されました",
+      key_recovered: "グループ {group} のキー {key} が復旧し、再び有効になりました",
+      key_expired: "グループ {group} のキー {count} 個が期限切れになり、無効化されました",
+      key_expiring: "グループ {group} のキー {count} 個が {days} 日以内に期限切れになります",
+      task_completed: "グループ {group} のタスク {task_type} が完了しました",
+      task_failed: "グループ {group} のタスク {task_type} が失敗しました: {error}",
+      keys_low: "グループ {group} の有効なキーが {active_keys} 個に減少しました（しきい値 {threshold}）。補充されたキー: {added} 個",
+      key_rotation_failed: "暗号化キーのバージョン {version} へのローテーション後、{failed} 個のキーが復号できません",
+      models_detected: "グループ {group} で {count} 個の新しいモデルが検出されました",
+      models_removed: "グループ {group} で提供されなくなった {count} 個のモデルを削除しました",
+      sandbox_deleted: "サンドボックスグループ {group} は期限切れのため削除されました",
+      alert_firing: "アラート {rule} がグループ {group} で発生しています",
+      alert_resolved: "グループ {group} のアラート {rule} が解消しました",
+    },
+  },
+  alerts: {
+    title: "アラートルール",
+    description:
+      "ルールはマスターノードで毎分チェックされます。グループでルールが発生したときと解消したときに" +
+      "通知が送信されます。",
+    add: "ルールを追加",
+    edit: "ルールを編集",
+    name: "名前",
+    type: "タイプ",
+    condition: "条件",
+    types: {
+      error_rate: "エラー率がしきい値を超過",
+      all_keys_invalid: "すべてのキーが無効",
+      quota_exceeded: "クォータ使用率がしきい値を超過",
+      upstream_circuit_open: "上流のサーキットオープン",
+      key_invalidated: "キーの無効化",
+      key_recovered: "キーの復旧",
+    },
+    group: "グループ",
+    allGroups: "すべてのグループ",
+    threshold: "しきい値 (%)",
+    window: "ウィンドウ（分）",
+    minRequests: "最小リクエスト数",
+    minRequestsHint: "ウィンドウ内のリクエスト数がこの値に達してからエラー率をチェックします",
+    repeat: "再通知間隔（分）",
+    repeatHint: "発生し続けている間アラートを再送します。0 は一度だけ送信します",
+    channel: "チャネル",
+    channels: {
+      webhook: "Webhook",
+      slack: "Slack",
+      discord: "Discord",
+      telegram: "Telegram",
+    },
+    telegramBotToken: "ボットトークン",
+    telegramChatId: "チャット ID",
+    webhookUrl: "Webhook URL",
+    webhookHint: "空の場合は通知センターにのみ記録します。キーのルールにはチャネルが必要です",
+    payloadTemplate: "ペイロードテンプレート",
+    payloadTemplateHint:
+      "JSON ボディを生成する Go テンプレート。フィールド: .Rule .RuleID .Type .Status .Group " +
+      ".GroupID .Value .Threshold .Message .Time。json 関数で値を引用します。空の場合はデフォルトまたは Slack・Discord のテキストメッセージ。",
+    enabled: "有効",
+    test: "テスト送信",
+    conditionErrorRate: "{window} 分間で > {threshold}%",
+    conditionQuota: "クォータの ≥ {threshold}%",
+    deleteConfirm: "アラートルール {name} を削除しますか？",
+    loadFailed: "アラートルールの読み込みに失敗しました",
+    empty: "アラートルールはありません",
+  },
+  users: {
+    title: "ユーザー",
+    description:
+      "ユーザーはユーザー名とパスワードでログインします。認証キーは組み込みの管理者として引き続き使用できます。",
+    add: "ユーザーを追加",
+    edit: "ユーザーを編集",
+    username: "ユーザー名",
+    password: "パスワード",
+    passwordHint: "8 文字以上",
+    passwordKeepHint: "空のままにすると現在のパスワードを維持します",
+    role: "ロール",
+    roles: {
+      viewer: "閲覧者",
+      operator: "オペレーター",
+      admin: "管理者",
+    },
+    roleHints: {
+      viewer: "読み取り専用。キーやログはエクスポートできません",
+      operator: "グループ、キー、アラートを管理できます",
+      admin: "ユーザー、設定、設定のロールバックを含むすべての権限",
+    },
+    status: "状態",
+    active: "有効",
+    disabled: "無効",
+    lastLogin: "最終ログイン",
+    changePassword: "パスワード変更",
+    currentPassword: "現在のパスワード",
+    newPassword: "新しいパスワード",
+    deleteConfirm: "ユーザー {name} を削除しますか？",
+    loadFailed: "ユーザーの読み込みに失敗しました",
+    empty: "ユーザーがいません",
+    twoFactor: "二要素認証",
+    twoFactorOn: "有効",
+    twoFactorOff: "無効",
+    enableTwoFactor: "有効にする",
+    disableTwoFactor: "無効にする",
+    disableTwoFactorHint: "無効にするには認証アプリのコードまたはリカバリーコードを入力してください",
+    resetTwoFactor: "二要素認証をリセット",
+    resetTwoFactorConfirm: "{name} の二要素認証を削除しますか？",
+    totpSecret: "シークレット",
+    totpSecretHint: "このシークレットを認証アプリに追加するか、下の URI をインポートしてください",
+    totpURI: "URI",
+    totpCode: "コード",
+    recoveryCodesHint:
+      "リカバリーコードを安全な場所に保存してください。デバイスを紛失した場合に各コードを一度だけ使用でき、" +
+      "再表示されません。",
+    twoFactorEnrollmentRequired: "この操作を行うには二要素認証を有効にしてください",
+    teams: "チーム",
+    teamsDescription:
+      "チームに属するグループはメンバーと管理者のみに表示されます。チームに属さないグループは全員に表示されます。",
+    addTeam: "チームを追加",
+    editTeam: "チームを編集",
+    teamName: "名前",
+    teamDescription: "説明",
+    teamMembers: "メンバー",
+    teamsEmpty: "チームがありません",
+    deleteTeamConfirm: "チーム {name} を削除しますか？",
+    team: "所属チーム",
+    noTeam: "全員に公開",
+  },
   playground: {
     title: "LLM プレイグラウンド",
     selectGroup: "グループを選択",
     modelName: "モデル名",
     temperature: "Temperature",
-    clearChat: "チャットをクリア",
+    newChat: "新しいチャット",
+    history: "会話履歴",
+    untitledConversation: "無題",
+    failedToSaveConversation: "会話の保存に失敗しました",
+    confirmDeleteConversation: "この会話を削除しますか？",
     startConversation: "LLMとの会話を開始",
     enterMessage: "メッセージを入力...",
     send: "送信",
@@ -680,5 +1003,33 @@ export default {
     failedToLoadGroups: "グループの読み込みに失敗しました",
     failedToSendMessage: "メッセージの送信に失敗しました",
     invalidTemperature: "Temperatureは0から2の間である必要があります",
+    choiceCount: "候補数 (n)",
+    showReasoning: "推論を表示",
+    hideReasoning: "推論を非表示",
+    reasoning: "推論過程",
+    candidate: "候補 {index}",
+    chatTab: "チャット",
+    embeddingsTab: "埋め込み",
+    embeddingInputHint: "1行に1つの入力",
+    createEmbeddings: "埋め込みを生成",
+    embeddingTokens: "使用トークン：{tokens}",
+    dimensions: "{count} 次元",
+    attach: "添付",
+    attachedImage: "添付画像",
+    fileTooLarge: "{name} は大きすぎます",
+    failedToReadFile: "{name} の読み込みに失敗しました",
+    streamOn: "ストリーミング",
+    streamOff: "非ストリーミング",
+    tools: "ツール",
+    toolsHint: "OpenAI 形式の関数ツールの JSON 配列（type、function.name、function.parameters）",
+    toolChoice: "ツール選択",
+    toolChoiceAuto: "auto",
+    toolChoiceNone: "none",
+    toolChoiceRequired: "required",
+    invalidTools: "ツールはツール定義の JSON 配列である必要があります",
+    validJson: "有効な JSON",
+    invalidJson: "無効な JSON",
+    replyToToolCall: "結果を返信",
+    replyingTo: "{name}（{id}）に返信中",
   },
 };
@@ -63,12 +63,19 @@ export default {
     title: "GPT Load",
     subtitle: "智能负载均衡管理平台",
     welcome: "欢迎回来",
-    welcomeDesc: "请输入您的授权密钥以继续",
+    welcomeDesc: "使用账号登录，或留空用户名以授权密钥登录",
     authKey: "授权密钥",
     authKeyPlaceholder: "请输入授权密钥",
     loginButton: "登录",
+    ssoButton: "使用单点登录",
     loginSuccess: "登录成功",
     authKeyRequired: "请输入授权密钥",
+    usernamePlaceholder: "用户名（可选）",
+    passwordPlaceholder: "请输入密码",
+    passwordRequired: "请输入密码",
+    totpPlaceholder: "两步验证码或恢复码",
+    totpRequired: "请输入验证器中的验证码",
+    totpPrompt: "请输入验证器中的验证码",
   },
   nav: {
     dashboard: "仪表盘",
@@ -77,6 +84,8 @@ export default {
     logs: "日志",
     settings: "系统设置",
     playground: "测试环境",
+    alerts: "告警",
+    users: "用户",
     logout: "退出登录",
   },
   dashboard: {
@@ -222,6 +231,28 @@ export default {
     stats30Day: "30天请求",
     stats30DayFailed: "30天失败请求",
     stats30DayFailureRate: "30天失败率",
+    routingExperiment: "子分组路由",
+    experimentSubGroup: "子分组",
+    trafficShare: "流量占比",
+    experimentRequests: "请求数",
+    successRate: "成功率",
+    avgLatency: "平均延迟",
+    avgCost: "平均成本",
+    score: "得分",
+    leader: "领先",
+    upstreamLatency: "上游延迟",
+    latencyModel: "模型",
+    latencySamples: "样本数",
+    upstreamHealth: "状态",
+    upstreamHealthy: "正常",
+    upstreamPaused: "已暂停",
+    quotaCycle: "配额周期",
+    quotaPacing: "匀速",
+    quotaUsed: "已用",
+    quotaLimit: "上限",
+    quotaPacedUsage: "匀速进度",
+    quotaResetsAt: "重置时间",
+    quotaProjectedExhaustion: "预计耗尽",
     detailInfo: "详细信息",
     basicInfo: "基础信息",
     displayName: "显示名称",
@@ -277,6 +308,8 @@ export default {
     dontCopyKeys: "不复制密钥",
     confirmCopy: "确认复制",
     upstreamAddresses: "上游地址",
+    versionedBase: "含版本路径",
+    versionedBaseTooltip: "该地址已包含服务商自己的 API 版本，转发时会去掉客户端请求路径中的版本前缀（如 /v1）。",
     upstream: "上游",
     weight: "权重",
     advancedConfig: "高级配置",
@@ -303,6 +336,10 @@ export default {
       "用于在界面上显示的友好名称，可以包含中文和特殊字符。如果不填写，将使用分组名称作为显示名称",
     channelTypeTooltip:
       "选择API提供商类型，决定了请求格式和认证方式。支持OpenAI、Gemini、Anthropic等主流AI服务商",
+    channelPreset: "服务商预设",
+    selectChannelPreset: "选择服务商",
+    channelPresetTooltip:
+      "为 Groq、Together AI、Fireworks AI 等已知的 OpenAI 兼容服务商自动填写上游地址、测试模型和测试路径",
     sortOrderTooltip:
       "决定分组在列表中的显示顺序，数字越小越靠前。建议使用10、20、30这样的间隔数字，便于后续调整",
     sortValue: "排序值",
@@ -313,9 +350,24 @@ export default {
     optionalCustomValidationPath: "可选，自定义用于验证key的API路径",
     proxyKeysTooltip: "分组专用代理密钥，用于访问此分组的代理端点。多个密钥请用逗号分隔。",
     proxyKeysCopied: "代理密钥已复制到剪贴板",
+    sandbox: "沙盒",
+    sandboxExpired: "沙盒已到期",
+    sandboxTTL: "沙盒有效期（小时）",
+    sandboxTTLTooltip:
+      "创建沙盒分组，到期后停止代理，并在沙盒保留时长结束后连同密钥一并删除。留空表示长期有效；编辑时输入 0 转为长期分组，输入小时数则从现在起延长有效期。",
+    sandboxTTLPlaceholder: "留空表示长期有效",
+    sandboxExpiresAt: "将于 {time} 到期，留空保持不变",
+    sandboxTTLHours: "有效小时数",
+    createSandboxKey: "创建沙盒代理密钥",
+    createSandboxKeyHint: "为此分组生成一个代理密钥，到期后自动移除。",
+    sandboxKeyCreated: "沙盒代理密钥已创建，有效期至 {time}",
+    sandboxKeyCreatedCopied: "沙盒代理密钥已创建并复制，有效期至 {time}",
     multiKeysPlaceholder: "多个密钥请用英文逗号 , 分隔",
     descriptionTooltip: "分组的详细说明，帮助团队成员了解该分组的用途和特点。支持多行文本",
     upstreamTooltip: "API服务器的完整URL地址。多个上游可以实现负载均衡和故障转移，提高服务可用性",
+    upstreamApiKeyPlaceholder: "可选：该上游专用的 API 密钥（覆盖密钥池中的密钥）",
+    upstreamHeadersPlaceholder:
+      '可选：该上游的请求头，每行一条："Header: value"，"-Header" 表示删除',
     weightTooltip:
       "负载均衡权重配置。权重决定流量分配比例，数值越大获得的流量越多。权重为0时禁用该上游（不接收任何请求）。示例：权重2:1表示前者获得约67%的流量",
     addUpstream: "添加上游地址",
@@ -343,9 +395,48 @@ export default {
     willRemoveFromRequest: "将从请求中移除",
     removeToggleTooltip: "开启移除开关将删除此请求头，关闭则添加或覆盖此请求头",
     addHeader: "添加请求头",
+    bodyRules: "请求体规则",
+    bodyRulesTooltip:
+      "在转发前修改请求的 JSON 请求体。路径以点分隔，如 metadata.user。设置：始终写入该值；默认：仅在字段缺失时填充；删除：移除该字段；上限：限制数值字段（如 max_tokens）的最大值。值按 JSON 解析（普通文本作为字符串发送），字符串支持请求头变量。",
+    bodyRule: "规则",
+    bodyRulePathPlaceholder: "字段路径，如 metadata.user",
+    bodyRuleValuePlaceholder: "JSON 值，如 0.7 或 true",
+    bodyRuleActions: {
+      set: "设置",
+      default: "默认",
+      remove: "删除",
+      max: "上限",
+    },
+    addBodyRule: "添加请求体规则",
+    scriptRules: "脚本规则",
+    scriptRulesTooltip:
+      '对每个请求按顺序求值的表达式，可使用 model、proxy_key、headers（小写名称）、body、path、method、client_ip、group 和 prompt_tokens，如 model startsWith "gpt-4" && prompt_tokens > 8000。表达式为真时：拒绝将拒绝该请求；路由将其转发到其他分组；模型替换请求的模型后继续检查下一条规则。执行失败或超过 50 毫秒的规则会被跳过。',
+    scriptRule: "规则",
+    scriptRuleWhenPlaceholder: '条件，如 headers["x-team"] == "research"',
+    scriptRuleMessagePlaceholder: "返回给客户端的消息（可选）",
+    scriptRuleModelPlaceholder: "替换使用的模型",
+    scriptRuleActions: {
+      reject: "拒绝",
+      route: "路由",
+      model: "模型",
+    },
+    addScriptRule: "添加脚本规则",
+    modelRouting: "模型路由",
+    modelRoutingTooltip:
+      "将匹配的模型请求转发到其他分组，使同一个端点可以服务多个服务商的模型。按顺序匹配，命中第一条即生效。规则可以是精确模型名或通配符，如 claude-*。未命中任何规则的请求由本分组处理。",
+    modelRoutingRule: "规则",
+    modelRoutingPatternPlaceholder: "模型名或通配符，如 claude-*",
+    modelRoutingGroupPlaceholder: "目标分组",
+    addModelRoutingRule: "添加路由规则",
     paramOverridesTooltip:
       "使用JSON格式定义要覆盖的API请求参数。这些参数会在发送请求时合并到原始参数中",
     modelRedirectPolicy: "未配置模型策略",
+    modelWarmup: "模型列表预热",
+    modelWarmupStatus: {
+      running: "获取中",
+      success: "已就绪",
+      failed: "失败",
+    },
     modelRedirectPolicyTooltip: "选择如何处理未在重定向规则中配置的模型请求",
     modelRedirectStrictMode: "严格模式：拒绝未配置的模型请求（返回404）",
     modelRedirectLooseMode: "宽松模式：透传未配置的模型请求",
@@ -355,7 +446,7 @@ export default {
     modelRedirectRules: "模型重定向规则",
     modelRedirectRulesTooltip: "配置模型重定向规则，键为用户请求的模型名，值为实际请求上游的模型名",
     modelRedirectRulesDescription:
-      "配置模型重定向规则，键为用户请求的模型名，值为实际请求上游的模型名",
+      "配置模型重定向规则，键为用户请求的模型名，值为实际请求上游的模型名。键支持 gpt-4* 这样的通配符模式",
     modelRedirectInvalidJson: "模型重定向规则 JSON 格式错误",
     modelRedirectInvalidFormat: "模型重定向规则的键值必须都是字符串",
     modelRedirectEmptyModel: "模型名称不能为空",
@@ -408,6 +499,40 @@ export default {
     notes: "备注",
     editKeyNotes: "编辑密钥备注",
     enterNotes: "请输入备注...",
+    expiringSoon: "即将到期",
+    editExpiry: "编辑到期时间",
+    editKeyExpiry: "编辑密钥到期时间",
+    noExpiry: "永不过期",
+    expiryUpdated: "到期时间已更新",
+    expired: "已到期",
+    expiresInDays: "{days} 天后到期",
+    expiresInHours: "{hours} 小时后到期",
+    editLimits: "编辑限额",
+    editKeyLimits: "编辑密钥限额",
+    rpmLimit: "每分钟请求数",
+    tpmLimit: "每分钟 Token 数",
+    dailySpendLimit: "每日消费（美元）",
+    limitsHint: "0 表示不限制。达到限额的密钥会被跳过，直到当前分钟或 UTC 日重置。",
+    limitsUpdated: "限额已更新",
+    backupTier: "备用",
+    moveToBackup: "移到备用层",
+    moveToPrimary: "移到主层",
+    movedToBackup: "密钥已移到备用层，主层密钥用尽后才会使用",
+    movedToPrimary: "密钥已移到主层",
+    primaryTier: "主层",
+    tierUsage: "{tier}：有效 {active}，无效 {invalid}，请求 {requests}",
+    viewHistory: "健康历史",
+    keyHistory: "{key} 的健康历史",
+    historyDays: "{days} 天",
+    historyBucket: "{time}：{rate}（成功 {success}，失败 {failure}）",
+    noHealthEvents: "该时间段内没有验证或失败记录",
+    sourceValidation: "验证",
+    sourceProxy: "请求",
+    healthOk: "通过",
+    healthFailed: "失败",
+    healthDisabled: "已停用",
+    coolingDownSeconds: "冷却中 {seconds} 秒",
+    coolingDownMinutes: "冷却中 {minutes} 分钟",
   },
   subGroups: {
     addSubGroup: "添加子分组",
@@ -445,6 +570,7 @@ export default {
     stream: "流式",
     nonStream: "非流",
     statusCode: "状态码",
+    statusClass: "状态码类别",
     duration: "耗时(ms)",
     model: "模型",
     sourceIP: "源IP",
@@ -484,9 +610,17 @@ export default {
     hideDetails: "隐藏详情",
     requestInfo: "请求信息",
     upstreamAddress: "上游地址",
+    requestId: "请求 ID",
     requestContent: "请求内容",
     errorInfo: "错误信息",
     customColumns: "自定义列",
+    metadata: "元数据",
+    metadataFilter: "元数据（字段:值）",
+    moderationFlags: "内容审核标记",
+    exportUsage: "导出用量",
+    liveStart: "实时跟踪：新请求到达时立即显示",
+    liveStop: "停止实时跟踪",
+    liveFailed: "实时日志流已断开",
   },
   settings: {
     title: "系统设置",
@@ -528,6 +662,17 @@ export default {
     dangerZone: "危险区域",
     clearAllData: "清除所有数据",
     confirmClearData: "此操作将删除所有数据且不可恢复，确认继续？",
+    backup: "备份与恢复",
+    backupDescription:
+      "将全部分组、上游、请求头规则和模型导出为单个文件，用于迁移或恢复其他实例。导入时更新同名分组并创建其余分组。",
+    backupIncludeSettings: "包含系统设置",
+    backupIncludeKeys: "包含密钥",
+    backupPassphrase: "密码",
+    backupPassphraseHint: "用于加密导出的密钥，导入时需要提供相同的密码",
+    backupExport: "导出",
+    backupImport: "导入",
+    backupImportResult: "新建 {created} 个分组，更新 {updated} 个，导入 {models} 个模型和 {keys} 个新密钥",
+    backupInvalidFile: "无效的备份文件",
   },
   footer: {
     checking: "检查中...",
@@ -569,6 +714,8 @@ export default {
       "密钥验证完成，处理了 {total} 个密钥，其中 {valid} 个成功，{invalid} 个失败。请注意：验证失败并不一定拉黑该密钥，需要失败次数达到阈值才会拉黑。",
     importCompleted: "密钥导入完成，成功添加 {added} 个密钥，忽略了 {ignored} 个。",
     deleteCompleted: "密钥删除完成，成功删除 {deleted} 个密钥，忽略了 {ignored} 个。",
+    failedItems: "失败 {failed} 个。",
+    rolledBack: "部分密钥处理失败，所有更改已回滚。",
   },
   theme: {
     auto: "自动模式",
@@ -620,19 +767,33 @@ export default {
     streaming: "流式输出",
     vision: "视觉",
     functions: "函数调用",
+    rerank: "重排序",
     auto_fetched: "自动获取",
     manual: "手动",
     fetch_models: "获取模型",
     refresh_models: "刷新模型",
     edit_model: "编辑模型",
+    add_model: "添加模型",
+    model_id_placeholder: "发送给上游的模型ID",
+    model_id_required: "模型ID不能为空",
+    create_success: "模型已添加",
+    create_failed: "添加模型失败",
+    bulk_edit: "编辑所选 {count} 项",
+    bulk_edit_title: "批量编辑 {count} 个模型",
+    bulk_edit_hint: "下方开关将应用到所有所选模型，令牌限制和价格仅在填写时更新。",
+    bulk_update_success: "已更新 {count} 个模型",
     delete_model: "删除模型",
     select_group_hint: "请选择一个分组以查看模型",
     supports_streaming: "支持流式输出",
     supports_vision: "支持视觉",
     supports_functions: "支持函数调用",
+    supports_rerank: "支持重排序",
     max_tokens: "最大令牌数",
     max_input_tokens: "最大输入令牌数",
     max_output_tokens: "最大输出令牌数",
+    pricing: "价格（输入 / 输出，美元每百万 Token）",
+    input_price_per_million: "输入价格（美元 / 百万 Token）",
+    output_price_per_million: "输出价格（美元 / 百万 Token）",
     fetch_success: "成功获取 {count} 个模型",
     fetch_failed: "获取模型失败",
     refresh_success: "成功刷新 {count} 个模型",
@@ -642,15 +803,168 @@ export default {
     update_failed: "更新模型失败",
     delete_success: "模型删除成功",
     delete_failed: "删除模型失败",
+    aliases_title: "模型别名",
+    aliases_description: "将请求的模型名称或通配符模式（如 gpt-4*）映射为发送到上游的模型。精确别名优先于模式匹配。别名与分组的模型重定向规则共用。",
+    add_alias: "添加别名",
+    alias_placeholder: "别名或模式，如 gpt-4",
+    alias_target_placeholder: "上游模型，如 gpt-4o-2024-08-06",
+    no_aliases: "暂未配置别名",
+    aliases_strict: "拒绝未配置别名的模型（严格模式）",
+    alias_incomplete: "每个别名都需要填写名称和目标模型",
+    aliases_saved: "模型别名已保存",
+    aliases_save_failed: "保存模型别名失败",
+    access_title: "模型访问控制",
+    access_description: "限制可通过此分组调用的模型。条目为模型名称或通配符（如 gpt-4*）。拒绝列表优先；允许列表不为空时，仅接受列出的模型。代理密钥规则在分组规则之上额外生效。不允许的请求会在使用密钥前以 403 拒绝。",
+    access_allowed: "允许",
+    access_denied: "拒绝",
+    add_key_access: "添加代理密钥规则",
+    access_key_placeholder: "代理密钥",
+    access_key_required: "每条代理密钥规则都需要填写代理密钥",
+    access_saved: "模型访问规则已保存",
+    access_save_failed: "保存模型访问规则失败",
     delete_confirm_title: "删除模型",
     delete_confirm_content: "确定要删除模型 '{modelName}' 吗？",
   },
+  notifications: {
+    title: "通知",
+    mark_all_read: "全部已读",
+    clear_read: "清除已读",
+    empty: "暂无通知",
+    categories: {
+      key: "密钥",
+      job: "任务",
+      alert: "告警",
+      model: "模型",
+    },
+    events: {
+      key_disabled: "分组 {group} 的密钥 {key} 在失败 {failures} 次后已被禁用",
+      key_recovered: "分组 {group} 中的密钥 {key} 已恢复可用",
+      key_expired: "分组 {group} 的 {count} 个密钥已到期并被停用",
+      key_expiring: "分组 {group} 的 {count} 个密钥将在 {days} 天内到期",
+      task_completed: "分组 {group} 的任务 {task_type} 已完成",
+      task_failed: "分组 {group} 的任务 {task_type} 失败: {error}",
+      keys_low: "分组 {group} 的有效密钥降至 {active_keys} 个（阈值 {threshold}），自动补充了 {added} 个",
+      key_rotation_failed: "加密密钥轮换到版本 {version} 后仍有 {failed} 个密钥无法解密",
+      models_detected: "分组 {group} 检测到 {count} 个新模型",
+      models_removed: "分组 {group} 中 {count} 个模型已不再提供并被移除",
+      sandbox_deleted: "沙盒分组 {group} 已到期并被删除",
+      alert_firing: "告警 {rule} 在分组 {group} 触发",
+      alert_resolved: "分组 {group} 的告警 {rule} 已恢复",
+    },
+  },
+  alerts: {
+    title: "告警规则",
+    description: "主节点每分钟检查一次规则。规则在某个分组开始触发以及恢复时都会发送通知。",
+    add: "添加规则",
+    edit: "编辑规则",
+    name: "名称",
+    type: "类型",
+    condition: "条件",
+    types: {
+      error_rate: "错误率超过阈值",
+      all_keys_invalid: "所有密钥失效",
+      quota_exceeded: "配额使用超过阈值",
+      upstream_circuit_open: "上游熔断",
+      key_invalidated: "密钥失效",
+      key_recovered: "密钥恢复",
+    },
+    group: "分组",
+    allGroups: "所有分组",
+    threshold: "阈值 (%)",
+    window: "时间窗口（分钟）",
+    minRequests: "最少请求数",
+    minRequestsHint: "时间窗口内的请求数达到该值后才检查错误率",
+    repeat: "重复间隔（分钟）",
+    repeatHint: "告警持续触发时重复发送，0 表示只发送一次",
+    channel: "渠道",
+    channels: {
+      webhook: "Webhook",
+      slack: "Slack",
+      discord: "Discord",
+      telegram: "Telegram",
+    },
+    telegramBotToken: "Bot Token",
+    telegramChatId: "Chat ID",
+    webhookUrl: "Webhook URL",
+    webhookHint: "留空则只在通知中心记录告警，密钥类规则必须配置渠道",
+    payloadTemplate: "消息模板",
+    payloadTemplateHint:
+      "渲染 JSON 请求体的 Go 模板。可用字段：.Rule .RuleID .Type .Status .Group .GroupID " +
+      ".Value .Threshold .Message .Time；json 函数输出带引号的值。留空使用默认模板或 Slack、Discord 的纯文本消息。",
+    enabled: "启用",
+    test: "发送测试",
+    conditionErrorRate: "{window} 分钟内 > {threshold}%",
+    conditionQuota: "配额使用 ≥ {threshold}%",
+    deleteConfirm: "确定删除告警规则 {name} 吗？",
+    loadFailed: "加载告警规则失败",
+    empty: "暂无告警规则",
+  },
+  users: {
+    title: "用户",
+    description: "用户使用用户名和密码登录。授权密钥仍可作为内置管理员使用。",
+    add: "添加用户",
+    edit: "编辑用户",
+    username: "用户名",
+    password: "密码",
+    passwordHint: "至少 8 个字符",
+    passwordKeepHint: "留空则保持当前密码",
+    role: "角色",
+    roles: {
+      viewer: "只读",
+      operator: "运维",
+      admin: "管理员",
+    },
+    roleHints: {
+      viewer: "只读访问，不能导出密钥或日志",
+      operator: "可管理分组、密钥和告警",
+      admin: "完全访问，包括用户、系统设置和配置回滚",
+    },
+    status: "状态",
+    active: "启用",
+    disabled: "已禁用",
+    lastLogin: "最后登录",
+    changePassword: "修改密码",
+    currentPassword: "当前密码",
+    newPassword: "新密码",
+    deleteConfirm: "确定删除用户 {name} 吗？",
+    loadFailed: "加载用户失败",
+    empty: "暂无用户",
+    twoFactor: "两步验证",
+    twoFactorOn: "已开启",
+    twoFactorOff: "未开启",
+    enableTwoFactor: "启用",
+    disableTwoFactor: "关闭",
+    disableTwoFactorHint: "输入验证器中的验证码或恢复码以关闭",
+    resetTwoFactor: "重置两步验证",
+    resetTwoFactorConfirm: "移除用户 {name} 的两步验证？",
+    totpSecret: "密钥",
+    totpSecretHint: "将此密钥添加到验证器应用，或导入下方的 URI",
+    totpURI: "URI",
+    totpCode: "验证码",
+    recoveryCodesHint: "请妥善保存这些恢复码。丢失设备时每个恢复码可使用一次，且不会再次显示。",
+    twoFactorEnrollmentRequired: "请先开启两步验证再执行此操作",
+    teams: "团队",
+    teamsDescription: "归属团队的分组仅对团队成员和管理员可见，未归属团队的分组所有用户可见。",
+    addTeam: "添加团队",
+    editTeam: "编辑团队",
+    teamName: "名称",
+    teamDescription: "描述",
+    teamMembers: "成员",
+    teamsEmpty: "暂无团队",
+    deleteTeamConfirm: "确定删除团队 {name} 吗？",
+    team: "所属团队",
+    noTeam: "所有用户可见",
+  },
   playground: {
     title: "LLM 测试环境",
     selectGroup: "选择分组",
     modelName: "模型名称",
     temperature: "温度",
-    clearChat: "清空对话",
+    newChat: "新对话",
+    history: "历史对话",
+    untitledConversation: "未命名",
+    failedToSaveConversation: "保存对话失败",
+    confirmDeleteConversation: "确定删除该对话吗？",
     startConversation: "开始与 LLM 对话",
     enterMessage: "输入您的消息...",
     send: "发送",
@@ -662,5 +976,33 @@ export default {
     failedToLoadGroups: "加载分组失败",
     failedToSendMessage: "发送消息失败",
     invalidTemperature: "温度必须在 0 到 2 之间",
+    choiceCount: "候选数 (n)",
+    showReasoning: "显示推理",
+    hideReasoning: "隐藏推理",
+    reasoning: "推理过程",
+    candidate: "候选 {index}",
+    chatTab: "对话",
+    embeddingsTab: "向量嵌入",
+    embeddingInputHint: "每行一条输入",
+    createEmbeddings: "生成向量",
+    embeddingTokens: "消耗 Token：{tokens}",
+    dimensions: "{count} 维",
+    attach: "附件",
+    attachedImage: "附加的图片",
+    fileTooLarge: "{name} 文件过大",
+    failedToReadFile: "读取 {name} 失败",
+    streamOn: "流式",
+    streamOff: "非流式",
+    tools: "工具",
+    toolsHint: "OpenAI 格式的函数工具 JSON 数组（type、function.name、function.parameters）",
+    toolChoice: "工具选择",
+    toolChoiceAuto: "auto",
+    toolChoiceNone: "none",
+    toolChoiceRequired: "required",
+    invalidTools: "工具必须是由工具定义组成的 JSON 数组",
+    validJson: "JSON 有效",
+    invalidJson: "JSON 无效",
+    replyToToolCall: "回复结果",
+    replyingTo: "正在回复 {name}（{id}）",
   },
 };
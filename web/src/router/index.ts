@@ -1,4 +1,5 @@
 import { useAuthService } from "@/services/auth";
+import type { UserRole } from "@/types/models";
 import { createRouter, createWebHistory, type RouteRecordRaw } from "vue-router";
 import Layout from "@/components/Layout.vue";
 
@@ -27,6 +28,17 @@ const routes: Array<RouteRecordRaw> = [
         name: "models",
         component: () => import("@/views/Models.vue"),
       },
+      {
+        path: "alerts",
+        name: "alerts",
+        component: () => import("@/views/Alerts.vue"),
+      },
+      {
+        path: "users",
+        name: "users",
+        component: () => import("@/views/Users.vue"),
+        meta: { role: "admin" },
+      },
       {
         path: "settings",
         name: "settings",
@@ -51,7 +63,7 @@ const router = createRouter({
   routes,
 });
 
-const { checkLogin } = useAuthService();
+const { checkLogin, hasRole } = useAuthService();
 
 router.beforeEach((to, _from, next) => {
   const loggedIn = checkLogin();
@@ -63,6 +75,12 @@ router.beforeEach((to, _from, next) => {
     return next({ path: "/" });
   }
 
+  // 角色不足的页面回到首页
+  const role = to.meta.role as UserRole | undefined;
+  if (role && !hasRole(role)) {
+    return next({ path: "/" });
+  }
+
   next();
 });
 
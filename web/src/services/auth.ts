@@ -1,30 +1,99 @@
+import type { AuthUser, UserRole } from "@/types/models";
 import http from "@/utils/http";
 import { useState } from "@/utils/state";
 
 const AUTH_KEY = "authKey";
+const AUTH_USER = "authUser";
+
+// 角色从低到高
+const ROLES: UserRole[] = ["viewer", "operator", "admin"];
 
 export const useAuthKey = () => {
   return useState<string | null>(AUTH_KEY, () => null);
 };
 
+export const useAuthUser = () => {
+  return useState<AuthUser | null>(AUTH_USER, () => {
+    const raw = localStorage.getItem(AUTH_USER);
+    return raw ? (JSON.parse(raw) as AuthUser) : null;
+  });
+};
+
+interface LoginResponse {
+  success: boolean;
+  token: string;
+  user: AuthUser;
+}
+
+// totp_required 表示账号开启了两步验证，需要带上验证码重新登录
+export type LoginResult = "success" | "totp_required" | "failed";
+
 export function useAuthService() {
   const authKey = useAuthKey();
+  const authUser = useAuthUser();
 
-  const login = async (key: string): Promise<boolean> => {
+  // 用户名为空时以 AUTH_KEY 作为密码登录
+  const login = async (username: string, password: string, totpCode = ""): Promise<LoginResult> => {
     try {
-      await http.post("/auth/login", { auth_key: key });
-      localStorage.setItem(AUTH_KEY, key);
-      authKey.value = key;
+      const res = (await http.post("/auth/login", {
+        username,
+        password,
+        totp_code: totpCode,
+      })) as LoginResponse;
+      localStorage.setItem(AUTH_KEY, res.token);
+      localStorage.setItem(AUTH_USER, JSON.stringify(res.user));
+      authKey.value = res.token;
+      authUser.value = res.user;
+      await refreshUser();
+      return "success";
+    } catch (error) {
+      // 错误已记录
+      const data = (error as { response?: { data?: { totp_required?: boolean } } }).response?.data;
+      return data?.totp_required ? "totp_required" : "failed";
+    }
+  };
+
+  // 重新获取当前用户，包括两步验证的状态
+  const refreshUser = async (): Promise<void> => {
+    try {
+      const res = (await http.get("/auth/me")) as { data: AuthUser };
+      localStorage.setItem(AUTH_USER, JSON.stringify(res.data));
+      authUser.value = res.data;
+    } catch (_error) {
+      // 保留登录时返回的用户信息
+    }
+  };
+
+  // 单点登录回调返回的会话令牌
+  const loginWithToken = async (token: string): Promise<boolean> => {
+    localStorage.setItem(AUTH_KEY, token);
+    authKey.value = token;
+    try {
+      const res = (await http.get("/auth/me")) as { data: AuthUser };
+      localStorage.setItem(AUTH_USER, JSON.stringify(res.data));
+      authUser.value = res.data;
       return true;
     } catch (_error) {
-      // 错误已记录
+      logout();
       return false;
     }
   };
 
   const logout = (): void => {
     localStorage.removeItem(AUTH_KEY);
+    localStorage.removeItem(AUTH_USER);
     authKey.value = null;
+    authUser.value = null;
+  };
+
+  // 结束服务端会话后退出
+  const signOut = async (): Promise<void> => {
+    try {
+      await http.post("/auth/logout", {}, { hideMessage: true });
+    } catch (_error) {
+      // 会话可能已失效
+    }
+    logout();
   };
 
   const checkLogin = (): boolean => {
@@ -39,9 +108,20 @@ export function useAuthService() {
     return !!authKey.value;
   };
 
+  // 当前用户的角色是否不低于 role；旧版登录没有用户信息，视为管理员
+  const hasRole = (role: UserRole): boolean => {
+    const current = authUser.value?.role ?? "admin";
+    return ROLES.indexOf(current) >= ROLES.indexOf(role);
+  };
+
   return {
     login,
+    loginWithToken,
+    refreshUser,
     logout,
+    signOut,
     checkLogin,
+    hasRole,
+    authUser,
   };
 }
@@ -12,7 +12,10 @@ export type KeyStatus = "active" | "invalid" | undefined;
 export type GroupType = "standard" | "aggregate";
 
 // 渠道类型
-export type ChannelType = "openai" | "gemini" | "anthropic";
+export type ChannelType = "openai" | "gemini" | "anthropic" | "ollama" | "mistral" | "deepseek" | "openrouter" | "openai-compatible" | "dashscope" | "zhipu" | "moonshot";
+
+// 密钥层级，primary 层的密钥用尽后才会使用 backup 层
+export type KeyTier = "primary" | "backup";
 
 // 数据模型定义
 export interface APIKey {
@@ -21,6 +24,12 @@ export interface APIKey {
   key_value: string;
   notes?: string;
   status: KeyStatus;
+  tier: KeyTier;
+  expires_at?: string | null;
+  rpm_limit: number;
+  tpm_limit: number;
+  daily_spend_limit: number;
+  cooldown_until?: string | null;
   request_count: number;
   failure_count: number;
   last_used_at?: string;
@@ -28,9 +37,40 @@ export interface APIKey {
   updated_at: string;
 }
 
+// KeyHealthEvent is a recorded validation result or proxy failure of a key.
+export interface KeyHealthEvent {
+  id: number;
+  key_id: number;
+  group_id: number;
+  source: "validation" | "proxy";
+  is_success: boolean;
+  error_message?: string;
+  disabled: boolean;
+  created_at: string;
+}
+
+// KeyHealthBucket captures a key's successes and failures in one hour or day.
+export interface KeyHealthBucket {
+  time: string;
+  success_count: number;
+  failure_count: number;
+  success_rate: number; // -1 when the key was not used
+}
+
+export interface KeyHealthHistory {
+  key_id: number;
+  status: KeyStatus;
+  interval: "hour" | "day";
+  buckets: KeyHealthBucket[];
+  events: KeyHealthEvent[];
+}
+
 export interface UpstreamInfo {
   url: string;
   weight: number;
+  header_rules?: HeaderRule[];
+  api_key?: string;
+  versioned_base?: boolean;
 }
 
 export interface HeaderRule {
@@ -39,6 +79,26 @@ export interface HeaderRule {
   action: "set" | "remove";
 }
 
+// 请求体规则：按点分隔的字段路径修改转发前的请求体
+export interface BodyRule {
+  path: string;
+  action: "set" | "default" | "remove" | "max";
+  value?: unknown;
+}
+
+// 脚本规则：when 表达式为真时拒绝请求、转发到 value 分组或将模型改为 value
+export interface ScriptRule {
+  when: string;
+  action: "reject" | "route" | "model";
+  value?: string;
+}
+
+// 模型路由规则：匹配 pattern 的模型请求转发到 group 分组
+export interface ModelRoutingRule {
+  pattern: string;
+  group: string;
+}
+
 // 子分组配置（创建/更新时使用）
 export interface SubGroupConfig {
   group_id: number;
@@ -79,10 +139,20 @@ export interface Group {
   model_redirect_rules: Record<string, string>;
   model_redirect_strict: boolean;
   header_rules?: HeaderRule[];
+  body_rules?: BodyRule[];
+  script_rules?: ScriptRule[];
+  model_routing_rules?: ModelRoutingRule[];
   proxy_keys: string;
+  proxy_key_expiry?: Record<string, string> | null; // 沙盒代理密钥 -> 到期时间
+  expires_at?: string | null; // 沙盒分组的到期时间
+  ttl_hours?: number; // 提交时设置沙盒有效期，0 表示长期有效
   group_type?: GroupType;
+  team_id?: number | null; // 所属团队，为空表示所有用户可见
   sub_groups?: SubGroupInfo[]; // 子分组列表（仅聚合分组）
   sub_group_ids?: number[]; // 子分组ID列表
+  model_warmup_status?: "" | "running" | "success" | "failed";
+  model_warmup_error?: string;
+  model_warmup_at?: string;
   created_at?: string;
   updated_at?: string;
 }
@@ -96,9 +166,12 @@ export interface ModelCapability {
   supports_streaming: boolean;
   supports_vision: boolean;
   supports_functions: boolean;
+  supports_rerank: boolean;
   max_tokens?: number;
   max_input_tokens?: number;
   max_output_tokens?: number;
+  input_price_per_million?: number | null;
+  output_price_per_million?: number | null;
   custom_capabilities?: Record<string, unknown>;
   is_auto_fetched: boolean;
   last_fetched_at?: string;
@@ -106,6 +179,30 @@ export interface ModelCapability {
   updated_at: string;
 }
 
+// Model alias: a requested model name or glob pattern mapped to the upstream model
+export interface ModelAlias {
+  alias: string;
+  target: string;
+}
+
+export interface ModelAliases {
+  aliases: ModelAlias[];
+  strict: boolean;
+}
+
+// Model access policy: allowlist and denylist for a group, optionally narrowed per proxy key
+export interface ProxyKeyModelAccess {
+  proxy_key: string;
+  allowed: string[];
+  denied: string[];
+}
+
+export interface ModelAccessPolicy {
+  allowed: string[];
+  denied: string[];
+  proxy_keys: ProxyKeyModelAccess[];
+}
+
 export interface GroupConfigOption {
   key: string;
   name: string;
@@ -119,6 +216,67 @@ export interface GroupStatsResponse {
   stats_24_hour: RequestStats;
   stats_7_day: RequestStats;
   stats_30_day: RequestStats;
+  experiment?: ExperimentReport;
+  upstream_latency?: UpstreamLatencyStats[];
+  quota?: QuotaReport;
+}
+
+// QuotaKeyUsage is the usage of one key in the current quota cycle.
+export interface QuotaKeyUsage {
+  key_id: number;
+  used: number;
+  projected_exhaustion_at?: string;
+}
+
+// QuotaReport describes the current provider quota cycle of a group.
+export interface QuotaReport {
+  cycle: "daily" | "weekly" | "monthly";
+  scope: "key" | "group";
+  unit: "requests" | "tokens";
+  cycle_start: string;
+  cycle_end: string;
+  limit: number;
+  limit_per_key?: number;
+  used: number;
+  remaining: number;
+  paced_usage: number;
+  pacing: boolean;
+  projected_exhaustion_at?: string;
+  keys?: QuotaKeyUsage[];
+}
+
+// UpstreamLatencyStats is the rolling latency of one upstream for one model.
+export interface UpstreamLatencyStats {
+  upstream: string;
+  model: string;
+  samples: number;
+  p50_ms: number;
+  p95_ms: number;
+  healthy: boolean;
+  consecutive_failures: number;
+}
+
+// ExperimentVariant is the observed routing performance of one sub-group.
+export interface ExperimentVariant {
+  sub_group_id: number;
+  sub_group_name: string;
+  weight: number;
+  selections: number;
+  traffic_share: number;
+  requests: number;
+  successes: number;
+  success_rate: number;
+  avg_latency_ms: number;
+  avg_cost: number;
+  score: number;
+}
+
+// ExperimentReport summarizes sub-group routing of an aggregate group.
+export interface ExperimentReport {
+  routing: "weighted" | "bandit";
+  exploration_rate: number;
+  leader: string;
+  variants: ExperimentVariant[];
 }
 
 // KeyStats defines the statistics for API keys in a group.
@@ -126,6 +284,15 @@ export interface KeyStats {
   total_keys: number;
   active_keys: number;
   invalid_keys: number;
+  tiers?: KeyTierStats[];
+}
+
+// KeyTierStats defines the keys of one tier and the requests they have served.
+export interface KeyTierStats {
+  tier: KeyTier;
+  active_keys: number;
+  invalid_keys: number;
+  request_count: number;
 }
 
 // RequestStats defines the statistics for requests over a period.
@@ -143,12 +310,29 @@ export interface KeyValidationResult {
   valid_keys: number;
 }
 
-export interface KeyImportResult {
+export type BulkItemStatus = "succeeded" | "failed" | "skipped";
+
+export interface BulkItemResult {
+  index: number;
+  item: string;
+  status: BulkItemStatus;
+  reason?: string;
+}
+
+// 批量操作的逐项结果
+export interface BulkResult {
+  failed_count: number;
+  transactional: boolean;
+  rolled_back: boolean;
+  items: BulkItemResult[];
+}
+
+export interface KeyImportResult extends Partial<BulkResult> {
   added_count: number;
   ignored_count: number;
 }
 
-export interface KeyDeleteResult {
+export interface KeyDeleteResult extends Partial<BulkResult> {
   deleted_count: number;
   ignored_count: number;
 }
@@ -186,6 +370,9 @@ export interface RequestLog {
   upstream_addr: string;
   is_stream: boolean;
   request_body?: string;
+  request_id?: string;
+  metadata?: Record<string, string>;
+  moderation_flags?: string;
 }
 
 export interface Pagination {
@@ -195,6 +382,210 @@ export interface Pagination {
   total_pages: number;
 }
 
+// In-app notification shown in the notification center
+export type NotificationCategory = "key" | "job" | "alert" | "model";
+export type NotificationSeverity = "info" | "success" | "warning" | "error";
+
+export interface AppNotification {
+  id: number;
+  category: NotificationCategory;
+  severity: NotificationSeverity;
+  event: string;
+  message: string;
+  params: Record<string, unknown> | null;
+  group_id: number | null;
+  group_name: string;
+  count: number;
+  read_at: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface NotificationsResponse {
+  items: AppNotification[];
+  pagination: Pagination;
+}
+
+export interface NotificationUnreadCounts {
+  total: number;
+  by_severity: Partial<Record<NotificationSeverity, number>>;
+}
+
+export type PlaygroundMessageRole = "system" | "user" | "assistant" | "tool" | "error";
+
+// 模型请求的工具调用，arguments 为模型生成的原始 JSON 文本
+export interface PlaygroundToolCall {
+  id: string;
+  name: string;
+  arguments: string;
+  valid_json: boolean;
+}
+
+export interface PlaygroundConversationMessage {
+  role: PlaygroundMessageRole;
+  content: string;
+  reasoning_content?: string;
+  tool_calls?: PlaygroundToolCall[];
+  tool_call_id?: string;
+}
+
+export interface PlaygroundChoice {
+  index: number;
+  content: string;
+  reasoning_content?: string;
+  tool_calls?: PlaygroundToolCall[];
+  finish_reason?: string;
+}
+
+export interface PlaygroundChatResponse {
+  content: string;
+  model: string;
+  choices: PlaygroundChoice[];
+  usage: {
+    prompt_tokens: number;
+    completion_tokens: number;
+    total_tokens: number;
+  };
+}
+
+// 流式 playground 对话的事件
+export interface PlaygroundStreamEvent {
+  type: "delta" | "tool_call_delta" | "done" | "error";
+  index: number;
+  content?: string;
+  reasoning_content?: string;
+  tool_index: number;
+  id?: string;
+  name?: string;
+  arguments?: string;
+  response?: PlaygroundChatResponse;
+  message?: string;
+}
+
+export type AlertRuleType =
+  | "error_rate"
+  | "all_keys_invalid"
+  | "quota_exceeded"
+  | "upstream_circuit_open"
+  | "key_invalidated"
+  | "key_recovered";
+
+export type AlertChannel = "webhook" | "slack" | "discord" | "telegram";
+
+export interface AlertRule {
+  id: number;
+  name: string;
+  type: AlertRuleType;
+  group_id: number | null;
+  threshold: number;
+  window_minutes: number;
+  min_requests: number;
+  repeat_minutes: number;
+  channel: AlertChannel;
+  webhook_url: string;
+  telegram_bot_token: string;
+  telegram_chat_id: string;
+  payload_template: string;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export type AlertRuleForm = Omit<AlertRule, "id" | "created_at" | "updated_at">;
+
+export interface AlertRulesResponse {
+  rules: AlertRule[];
+  types: AlertRuleType[];
+  channels: AlertChannel[];
+  default_template: string;
+}
+
+export type UserRole = "viewer" | "operator" | "admin";
+
+export interface AuthUser {
+  id: number;
+  username: string;
+  role: UserRole;
+  totp_enabled: boolean;
+  two_factor_enforced?: boolean;
+}
+
+export interface TOTPEnrollment {
+  secret: string;
+  uri: string;
+}
+
+export interface User {
+  id: number;
+  username: string;
+  role: UserRole;
+  disabled: boolean;
+  totp_enabled: boolean;
+  last_login_at: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface UserForm {
+  username: string;
+  password: string;
+  role: UserRole;
+  disabled: boolean;
+}
+
+export interface UsersResponse {
+  users: User[];
+  roles: UserRole[];
+}
+
+export interface Team {
+  id: number;
+  name: string;
+  description: string;
+  user_ids: number[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TeamForm {
+  name: string;
+  description: string;
+  user_ids: number[];
+}
+
+export interface ConfigBundle {
+  version: number;
+  exported_at: string;
+  groups: Record<string, unknown>[];
+  settings?: Record<string, unknown>;
+  keys_included: boolean;
+}
+
+export interface ConfigImportResult {
+  groups_created: number;
+  groups_updated: number;
+  models_imported: number;
+  keys_added: number;
+  keys_ignored: number;
+  settings_updated: boolean;
+}
+
+export interface PlaygroundConversation {
+  id: number;
+  title: string;
+  group_name: string;
+  model: string;
+  temperature: number;
+  messages?: PlaygroundConversationMessage[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PlaygroundConversationsResponse {
+  items: PlaygroundConversation[];
+  pagination: Pagination;
+}
+
 export interface LogsResponse {
   items: RequestLog[];
   pagination: Pagination;
@@ -211,11 +602,18 @@ export interface LogFilter {
   status_code?: number | null;
   source_ip?: string;
   error_contains?: string;
+  metadata?: string;
   start_time?: string | null;
   end_time?: string | null;
   request_type?: "retry" | "final";
+  status_class?: string;
 }
 
+export type LogStreamFilter = Pick<
+  LogFilter,
+  "parent_group_name" | "group_name" | "model" | "request_type" | "status_class"
+>;
+
 export interface DashboardStats {
   total_requests: number;
   success_requests: number;
@@ -4,7 +4,7 @@ import axios from "axios";
 import { appState } from "./app-state";
 
 // 定义不需要显示 loading 的 API 地址列表
-const noLoadingUrls = ["/tasks/status"];
+const noLoadingUrls = ["/tasks/status", "/notifications/unread-count"];
 
 declare module "axios" {
   interface AxiosRequestConfig {
@@ -43,8 +43,18 @@ http.interceptors.response.use(
     }
     return response.data;
   },
-  error => {
+  async error => {
     appState.loading = false;
+    // 开启两步验证强制后，删除、清空等操作需要当前的验证码，输入后重试一次
+    const config = error.config;
+    const totpRequired = error.response?.data?.code === "TOTP_REQUIRED";
+    if (totpRequired && config && !config.headers["X-TOTP-Code"]) {
+      const code = window.prompt(i18n.global.t("login.totpPrompt"))?.trim();
+      if (code) {
+        config.headers["X-TOTP-Code"] = code;
+        return http.request(config);
+      }
+    }
     if (error.response) {
       if (error.response.status === 401) {
         if (window.location.pathname !== "/login") {